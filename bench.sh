@@ -0,0 +1,96 @@
+#!/bin/bash
+
+# Velocity Benchmark Script
+# Runs the API and storage benchmarks and compares them against a baseline
+#
+#   ./bench.sh baseline   Record the baseline (e.g. on main)
+#   ./bench.sh            Run and compare against the baseline
+#
+# Environment:
+#   BENCH      Benchmarks to run (default: .)
+#   COUNT      Runs per benchmark, averaged (default: 5)
+#   BENCHTIME  Duration or iterations per run (default: 1s)
+#   THRESHOLD  Percent slowdown (ns/op or allocs/op) that fails (default: 20)
+#   BASELINE   Baseline file (default: .bench/baseline.txt)
+
+set -e
+
+# Colors for output
+RED='\033[0;31m'
+GREEN='\033[0;32m'
+YELLOW='\033[1;33m'
+BLUE='\033[0;34m'
+NC='\033[0m' # No Color
+
+BENCH="${BENCH:-.}"
+COUNT="${COUNT:-5}"
+BENCHTIME="${BENCHTIME:-1s}"
+THRESHOLD="${THRESHOLD:-20}"
+BENCH_DIR=".bench"
+BASELINE="${BASELINE:-$BENCH_DIR/baseline.txt}"
+PACKAGES="./velocitytest ./internal/storage"
+
+echo "=============================================="
+echo -e "${YELLOW}Velocity Benchmarks${NC}"
+echo "=============================================="
+echo ""
+
+mkdir -p "$BENCH_DIR"
+OUTPUT="$BENCH_DIR/current.txt"
+if [ "$1" == "baseline" ]; then
+    OUTPUT="$BASELINE"
+    mkdir -p "$(dirname "$BASELINE")"
+fi
+
+echo -e "${BLUE}Running benchmarks (count=${COUNT}, benchtime=${BENCHTIME})...${NC}"
+go test -run='^$' -bench="$BENCH" -benchmem -count="$COUNT" -benchtime="$BENCHTIME" $PACKAGES | tee "$OUTPUT"
+echo ""
+
+if [ "$1" == "baseline" ]; then
+    echo -e "${GREEN}Baseline saved to ${OUTPUT}${NC}"
+    exit 0
+fi
+
+if [ ! -f "$BASELINE" ]; then
+    echo -e "${YELLOW}No baseline at ${BASELINE}; run './bench.sh baseline' (or 'make bench-baseline') first${NC}"
+    exit 0
+fi
+
+# benchstat gives a fuller comparison with significance when it's installed
+if command -v benchstat >/dev/null 2>&1; then
+    echo -e "${BLUE}benchstat:${NC}"
+    benchstat "$BASELINE" "$OUTPUT" || true
+    echo ""
+fi
+
+echo -e "${BLUE}Comparing against ${BASELINE} (threshold ${THRESHOLD}%)...${NC}"
+
+# Average ns/op and allocs/op per benchmark (the -N GOMAXPROCS suffix is
+# dropped, so baselines from other machines still line up), then compare
+summarize() {
+    awk '/^Benchmark/ {
+        name = $1; sub(/-[0-9]+$/, "", name)
+        for (i = 3; i < NF; i++) {
+            if ($(i+1) == "ns/op") { ns[name] += $i; runs[name]++ }
+            if ($(i+1) == "allocs/op") { allocs[name] += $i }
+        }
+    }
+    END { for (name in ns) printf "%s %f %f\n", name, ns[name] / runs[name], allocs[name] / runs[name] }' "$1" | sort
+}
+
+REGRESSIONS=$(join <(summarize "$BASELINE") <(summarize "$OUTPUT") | awk -v threshold="$THRESHOLD" '
+    function change(old, new) { return old > 0 ? (new - old) * 100 / old : 0 }
+    {
+        ns = change($2, $4); allocs = change($3, $5)
+        status = "ok"
+        if (ns > threshold || allocs > threshold) { status = "REGRESSION"; failed++ }
+        printf "%-32s %14.0f -> %14.0f ns/op (%+6.1f%%) %10.0f -> %10.0f allocs/op (%+6.1f%%)  %s\n", $1, $2, $4, ns, $3, $5, allocs, status > "/dev/stderr"
+    }
+    END { print failed + 0 }')
+
+echo ""
+if [ "$REGRESSIONS" -gt 0 ]; then
+    echo -e "${RED}${REGRESSIONS} benchmark(s) regressed by more than ${THRESHOLD}%${NC}"
+    exit 1
+fi
+echo -e "${GREEN}No regressions${NC}"
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"velocity/internal/ui"
+)
+
+var (
+	applyFile   string
+	applyDryRun bool
+)
+
+// defaultWebhookEvents are the events a webhook gets when none are listed
+// (matching the server's default)
+var defaultWebhookEvents = []string{"create", "update", "delete", "publish"}
+
+// applyConfig is a declarative tenant configuration. Each section that is
+// present is reconciled exactly: items missing from it are deleted. Sections
+// that are left out are not touched.
+type applyConfig struct {
+	// Tenant overrides --tenant
+	Tenant string `yaml:"tenant"`
+
+	// Schemas maps a schema name to the schema, inline or as a path to a JSON
+	// or YAML file (relative to the config file)
+	Schemas map[string]interface{} `yaml:"schemas"`
+
+	// Webhooks maps a webhook ID to its definition
+	Webhooks map[string]applyWebhook `yaml:"webhooks"`
+
+	// Settings are the tenant settings; omitted fields are reset to defaults
+	Settings map[string]interface{} `yaml:"settings"`
+}
+
+// applyWebhook is a webhook in the config file
+type applyWebhook struct {
+	URL    string   `yaml:"url" json:"url"`
+	Events []string `yaml:"events" json:"events"`
+}
+
+// applyChange is one step of the plan
+type applyChange struct {
+	Action string      `json:"action"` // create, update, or delete
+	Kind   string      `json:"kind"`   // schema, webhook, or settings
+	Name   string      `json:"name"`
+	body   interface{} // request body for create and update
+}
+
+func runApply(cmd *cobra.Command, args []string) {
+	config, err := loadApplyConfig(applyFile)
+	if err != nil {
+		ui.PrintError("%v", err)
+		os.Exit(1)
+	}
+
+	client := newClient()
+	if config.Tenant != "" {
+		client.tenant = config.Tenant
+	}
+
+	plan, err := planApply(client, config)
+	if err != nil {
+		ui.PrintError("Failed to plan changes: %v", err)
+		os.Exit(1)
+	}
+
+	if !applyDryRun {
+		for _, change := range plan {
+			if err := client.apply(change); err != nil {
+				ui.PrintError("Failed to %s %s %s: %v", change.Action, change.Kind, change.Name, err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if outputFmt == "json" {
+		printJSON(map[string]interface{}{
+			"tenant":  client.tenant,
+			"dry_run": applyDryRun,
+			"changes": plan,
+		})
+		return
+	}
+
+	if len(plan) == 0 {
+		ui.PrintSuccess("Tenant %s already matches %s", client.tenant, applyFile)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tKIND\tNAME")
+	for _, change := range plan {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", change.Action, change.Kind, change.Name)
+	}
+	w.Flush()
+	fmt.Println()
+
+	if applyDryRun {
+		ui.PrintInfo("Dry run: %d change(s) not applied", len(plan))
+	} else {
+		ui.PrintSuccess("Applied %d change(s) to tenant %s", len(plan), client.tenant)
+	}
+}
+
+// loadApplyConfig reads a config file, resolving schema file references
+func loadApplyConfig(file string) (*applyConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	config := &applyConfig{}
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", file, err)
+	}
+
+	for name, schema := range config.Schemas {
+		path, ok := schema.(string)
+		if !ok {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+		schemaData, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema %s: %w", name, err)
+		}
+		var parsed map[string]interface{}
+		if err := yaml.Unmarshal(schemaData, &parsed); err != nil {
+			return nil, fmt.Errorf("invalid schema %s (%s): %w", name, path, err)
+		}
+		config.Schemas[name] = parsed
+	}
+
+	for id, webhook := range config.Webhooks {
+		if webhook.URL == "" {
+			return nil, fmt.Errorf("webhook %s has no url", id)
+		}
+		if len(webhook.Events) == 0 {
+			webhook.Events = defaultWebhookEvents
+			config.Webhooks[id] = webhook
+		}
+	}
+
+	return config, nil
+}
+
+// planApply compares the config with the tenant and returns the changes that
+// make the tenant match it
+func planApply(c *client, config *applyConfig) ([]applyChange, error) {
+	var plan []applyChange
+
+	if config.Schemas != nil {
+		current, err := c.listTenantSchemas()
+		if err != nil {
+			return nil, err
+		}
+		existing := make(map[string]bool)
+		for _, name := range current {
+			existing[name] = true
+			if _, ok := config.Schemas[name]; !ok {
+				plan = append(plan, applyChange{Action: "delete", Kind: "schema", Name: name})
+			}
+		}
+		for _, name := range sortedKeys(config.Schemas) {
+			desired := config.Schemas[name]
+			if !existing[name] {
+				plan = append(plan, applyChange{Action: "create", Kind: "schema", Name: name, body: desired})
+				continue
+			}
+			data, err := c.request("GET", "/api/tenant/schemas/"+url.PathEscape(name), nil)
+			if err != nil {
+				return nil, err
+			}
+			if !sameJSON(data, desired) {
+				plan = append(plan, applyChange{Action: "update", Kind: "schema", Name: name, body: desired})
+			}
+		}
+	}
+
+	if config.Webhooks != nil {
+		data, err := c.request("GET", "/api/webhooks", nil)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Webhooks []struct {
+				ID string `json:"id"`
+				applyWebhook
+			} `json:"webhooks"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, err
+		}
+		existing := make(map[string]applyWebhook)
+		for _, webhook := range result.Webhooks {
+			existing[webhook.ID] = webhook.applyWebhook
+			if _, ok := config.Webhooks[webhook.ID]; !ok {
+				plan = append(plan, applyChange{Action: "delete", Kind: "webhook", Name: webhook.ID})
+			}
+		}
+		for _, id := range sortedKeys(config.Webhooks) {
+			desired := config.Webhooks[id]
+			current, ok := existing[id]
+			switch {
+			case !ok:
+				plan = append(plan, applyChange{Action: "create", Kind: "webhook", Name: id, body: desired})
+			case current.URL != desired.URL || !sameSet(current.Events, desired.Events):
+				plan = append(plan, applyChange{Action: "update", Kind: "webhook", Name: id, body: desired})
+			}
+		}
+	}
+
+	if config.Settings != nil {
+		data, err := c.request("GET", "/api/tenant/settings", nil)
+		if err != nil {
+			return nil, err
+		}
+		var current map[string]interface{}
+		if err := json.Unmarshal(data, &current); err != nil {
+			return nil, err
+		}
+
+		// Fields left out of the config go back to their defaults
+		desired := make(map[string]interface{}, len(current))
+		for key, value := range current {
+			desired[key] = zeroJSON(value)
+		}
+		for key, value := range config.Settings {
+			desired[key] = value
+		}
+		if !sameJSON(data, desired) {
+			plan = append(plan, applyChange{Action: "update", Kind: "settings", Name: "tenant", body: desired})
+		}
+	}
+
+	return plan, nil
+}
+
+// apply carries out one change
+func (c *client) apply(change applyChange) error {
+	var path string
+	switch change.Kind {
+	case "schema":
+		path = "/api/tenant/schemas/" + url.PathEscape(change.Name)
+	case "webhook":
+		path = "/api/webhooks/" + url.PathEscape(change.Name)
+	case "settings":
+		path = "/api/tenant/settings"
+	}
+
+	method := "PUT"
+	if change.Action == "delete" {
+		method = "DELETE"
+	}
+	_, err := c.request(method, path, change.body)
+	return err
+}
+
+func (c *client) listTenantSchemas() ([]string, error) {
+	data, err := c.request("GET", "/api/tenant/schemas", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Schemas []string `json:"schemas"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result.Schemas, nil
+}
+
+// sameJSON reports whether a JSON document equals a value once both are
+// normalized through JSON
+func sameJSON(data []byte, value interface{}) bool {
+	var a, b interface{}
+	if err := json.Unmarshal(data, &a); err != nil {
+		return false
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(encoded, &b); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// zeroJSON returns the zero value for a decoded JSON value's type
+func zeroJSON(value interface{}) interface{} {
+	switch value.(type) {
+	case bool:
+		return false
+	case string:
+		return ""
+	case float64:
+		return 0
+	default:
+		return nil
+	}
+}
+
+// sameSet reports whether two lists hold the same strings, in any order
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int, len(a))
+	for _, s := range a {
+		counts[s]++
+	}
+	for _, s := range b {
+		counts[s]--
+		if counts[s] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedKeys returns a map's keys in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
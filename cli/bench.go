@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"velocity/internal/ui"
+)
+
+var (
+	benchConcurrency int
+	benchDuration    time.Duration
+	benchMix         string
+	benchType        string
+	benchItems       int
+)
+
+// benchOps are the operations a mix can weight, with the route each exercises
+var benchOps = map[string]string{
+	"read":  "GET /api/content/{type}/{id}",
+	"write": "PUT /api/content/{type}/{id}",
+	"list":  "GET /api/content/{type}",
+}
+
+// benchWeight is one operation's share of the mix
+type benchWeight struct {
+	op     string
+	weight int
+}
+
+// parseMix parses a mix such as "read=80,write=20"
+func parseMix(mix string) ([]benchWeight, int, error) {
+	var weights []benchWeight
+	total := 0
+	for _, part := range strings.Split(mix, ",") {
+		op, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, 0, fmt.Errorf("invalid mix entry %q (expected op=weight)", part)
+		}
+		if _, known := benchOps[op]; !known {
+			return nil, 0, fmt.Errorf("unknown operation %q (expected read, write, or list)", op)
+		}
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return nil, 0, fmt.Errorf("invalid weight for %s: %s", op, value)
+		}
+		weights = append(weights, benchWeight{op: op, weight: weight})
+		total += weight
+	}
+	if total == 0 {
+		return nil, 0, fmt.Errorf("mix has no weight")
+	}
+	return weights, total, nil
+}
+
+// benchStats collects results for one route
+type benchStats struct {
+	latencies []time.Duration
+	errors    int
+}
+
+// benchResult is the summary printed for one route
+type benchResult struct {
+	Route      string  `json:"route"`
+	Requests   int     `json:"requests"`
+	Errors     int     `json:"errors"`
+	ErrorRate  float64 `json:"error_rate"`
+	Throughput float64 `json:"requests_per_second"`
+	P50        float64 `json:"p50_ms"`
+	P90        float64 `json:"p90_ms"`
+	P99        float64 `json:"p99_ms"`
+	Max        float64 `json:"max_ms"`
+}
+
+func runBench(cmd *cobra.Command, args []string) {
+	weights, total, err := parseMix(benchMix)
+	if err != nil {
+		ui.PrintError("%v", err)
+		os.Exit(1)
+	}
+	if benchConcurrency < 1 || benchItems < 1 {
+		ui.PrintError("--concurrency and --items must be at least 1")
+		os.Exit(1)
+	}
+
+	client := newClient()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = benchConcurrency
+	client.httpClient.Transport.(*cliTransport).base = transport
+
+	// Seed the items reads will fetch
+	ui.PrintInfo("Seeding %d %s items...", benchItems, benchType)
+	for i := 0; i < benchItems; i++ {
+		status, err := client.benchRequest("PUT", benchPath(i), benchBody(i))
+		if err != nil || status >= 400 {
+			ui.PrintError("Failed to seed content (status %d): %v", status, err)
+			os.Exit(1)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	ctx, cancelTimeout := context.WithTimeout(ctx, benchDuration)
+	defer cancelTimeout()
+
+	ui.PrintInfo("Running %d workers for %s (%s)...", benchConcurrency, benchDuration, benchMix)
+
+	// Each worker records into its own stats; they are merged at the end
+	start := time.Now()
+	results := make([]map[string]*benchStats, benchConcurrency)
+	var wg sync.WaitGroup
+	for w := 0; w < benchConcurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(w)))
+			stats := make(map[string]*benchStats)
+			results[w] = stats
+
+			for ctx.Err() == nil {
+				op := pickOp(rng, weights, total)
+				i := rng.Intn(benchItems)
+
+				var status int
+				var err error
+				began := time.Now()
+				switch op {
+				case "read":
+					status, err = client.benchRequest("GET", benchPath(i), nil)
+				case "write":
+					status, err = client.benchRequest("PUT", benchPath(i), benchBody(i))
+				case "list":
+					status, err = client.benchRequest("GET", "/api/content/"+benchType, nil)
+				}
+				elapsed := time.Since(began)
+
+				// Requests cut off by the end of the run aren't counted
+				if ctx.Err() != nil {
+					break
+				}
+
+				s, ok := stats[op]
+				if !ok {
+					s = &benchStats{}
+					stats[op] = s
+				}
+				s.latencies = append(s.latencies, elapsed)
+				if err != nil || status >= 400 {
+					s.errors++
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	merged := make(map[string]*benchStats)
+	for _, stats := range results {
+		for op, s := range stats {
+			m, ok := merged[op]
+			if !ok {
+				m = &benchStats{}
+				merged[op] = m
+			}
+			m.latencies = append(m.latencies, s.latencies...)
+			m.errors += s.errors
+		}
+	}
+
+	var summary []benchResult
+	all := &benchStats{}
+	for _, w := range weights {
+		if s, ok := merged[w.op]; ok {
+			summary = append(summary, summarize(benchOps[w.op], s, elapsed))
+			all.latencies = append(all.latencies, s.latencies...)
+			all.errors += s.errors
+		}
+	}
+	summary = append(summary, summarize("TOTAL", all, elapsed))
+
+	if outputFmt == "json" {
+		printJSON(map[string]interface{}{
+			"concurrency": benchConcurrency,
+			"duration":    elapsed.Round(time.Millisecond).String(),
+			"mix":         benchMix,
+			"routes":      summary,
+		})
+		return
+	}
+
+	fmt.Println(ui.Header("Benchmark"))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  ROUTE\tREQUESTS\tREQ/S\tERRORS\tP50\tP90\tP99\tMAX")
+	fmt.Fprintln(w, "  -----\t--------\t-----\t------\t---\t---\t---\t---")
+	for _, r := range summary {
+		fmt.Fprintf(w, "  %s\t%d\t%.1f\t%d (%.1f%%)\t%.1fms\t%.1fms\t%.1fms\t%.1fms\n",
+			r.Route, r.Requests, r.Throughput, r.Errors, r.ErrorRate*100, r.P50, r.P90, r.P99, r.Max)
+	}
+	w.Flush()
+}
+
+func pickOp(rng *rand.Rand, weights []benchWeight, total int) string {
+	n := rng.Intn(total)
+	for _, w := range weights {
+		if n < w.weight {
+			return w.op
+		}
+		n -= w.weight
+	}
+	return weights[len(weights)-1].op
+}
+
+func summarize(route string, s *benchStats, elapsed time.Duration) benchResult {
+	r := benchResult{Route: route, Requests: len(s.latencies), Errors: s.errors}
+	if r.Requests == 0 {
+		return r
+	}
+
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	r.ErrorRate = float64(s.errors) / float64(r.Requests)
+	r.Throughput = float64(r.Requests) / elapsed.Seconds()
+	r.P50 = percentile(s.latencies, 0.50)
+	r.P90 = percentile(s.latencies, 0.90)
+	r.P99 = percentile(s.latencies, 0.99)
+	r.Max = milliseconds(s.latencies[len(s.latencies)-1])
+	return r
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies, in milliseconds
+func percentile(sorted []time.Duration, p float64) float64 {
+	i := int(float64(len(sorted))*p+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return milliseconds(sorted[i])
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func benchPath(i int) string {
+	return fmt.Sprintf("/api/content/%s/bench-%05d", benchType, i)
+}
+
+func benchBody(i int) []byte {
+	return []byte(fmt.Sprintf(`{"title":"Benchmark item %d","body":"%s","updated_at":"%s"}`,
+		i, strings.Repeat("lorem ipsum ", 40), time.Now().UTC().Format(time.RFC3339Nano)))
+}
+
+// benchRequest sends a request and returns the status code, discarding the body
+func (c *client) benchRequest(method, path string, body []byte) (int, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, bodyReader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.tenant != "" {
+		req.Header.Set("X-Tenant", c.tenant)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, err
+}
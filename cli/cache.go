@@ -0,0 +1,290 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"velocity/internal/ui"
+)
+
+var (
+	cachedFlag  bool
+	refreshFlag bool
+)
+
+// Cache modes
+const (
+	cacheRevalidate = iota // ask the server if the cached copy is current
+	cacheOnly              // use the cached copy without asking (--cached)
+	cacheRefresh           // fetch again, ignoring the cached copy (--refresh)
+)
+
+// cacheEntry describes a cached response; its body is kept beside it
+type cacheEntry struct {
+	URL      string      `json:"url"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	StoredAt time.Time   `json:"stored_at"`
+}
+
+// cacheTransport keeps the responses of get and list in ~/.velocity/cache,
+// revalidates them with If-None-Match, and serves them when the server can't
+// be reached
+type cacheTransport struct {
+	base http.RoundTripper
+	dir  string
+	mode int
+}
+
+// cacheDir returns where the CLI keeps cached responses: VELOCITY_CACHE_DIR,
+// or ~/.velocity/cache
+func cacheDir() (string, error) {
+	if dir := os.Getenv("VELOCITY_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".velocity", "cache"), nil
+}
+
+// useCache reads the client's GETs through the local cache, per --cached and
+// --refresh. The cache is skipped when it has no home.
+func (c *client) useCache() {
+	if cachedFlag && refreshFlag {
+		ui.PrintError("--cached and --refresh can't be used together")
+		os.Exit(1)
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		return
+	}
+	mode := cacheRevalidate
+	switch {
+	case cachedFlag:
+		mode = cacheOnly
+	case refreshFlag:
+		mode = cacheRefresh
+	}
+	c.httpClient.Transport = &cacheTransport{base: c.httpClient.Transport, dir: dir, mode: mode}
+}
+
+// path returns where a request's response is cached. Requests differ by
+// endpoint, URL, tenant, representation, and credentials, so one API key's
+// cached content isn't served to another.
+func (t *cacheTransport) path(req *http.Request) string {
+	h := sha256.New()
+	for _, part := range []string{req.URL.String(), req.Header.Get("X-Tenant"), req.Header.Get("Accept"), req.Header.Get("X-API-Key")} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	return filepath.Join(t.dir, sum[:2], sum)
+}
+
+// load returns a request's cached entry, if there is one
+func (t *cacheTransport) load(path string) (*cacheEntry, bool) {
+	data, err := os.ReadFile(path + ".json")
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if _, err := os.Stat(path + ".body"); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// serve answers a request from its cached entry
+func (t *cacheTransport) serve(req *http.Request, path string, entry *cacheEntry) (*http.Response, error) {
+	body, err := os.Open(path + ".body")
+	if err != nil {
+		return nil, err
+	}
+	size := int64(-1)
+	if info, err := body.Stat(); err == nil {
+		size = info.Size()
+	}
+	header := entry.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("X-Velocity-Cache", "hit")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.Status, http.StatusText(entry.Status)),
+		StatusCode:    entry.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          body,
+		ContentLength: size,
+		Request:       req,
+	}, nil
+}
+
+// RoundTrip answers GETs from the cache as the mode allows, and caches
+// successful responses as they're read
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+	path := t.path(req)
+	entry, cached := t.load(path)
+	if cached && t.mode == cacheOnly {
+		return t.serve(req, path, entry)
+	}
+
+	if cached && t.mode == cacheRevalidate {
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			req = req.Clone(req.Context())
+			req.Header.Set("If-None-Match", etag)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if cached && offline(resp, err) {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		printWarning("Server unreachable; showing the copy cached %s", entry.StoredAt.Local().Format(time.RFC1123))
+		return t.serve(req, path, entry)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached:
+		resp.Body.Close()
+		return t.serve(req, path, entry)
+	case resp.StatusCode == http.StatusOK:
+		if err := os.MkdirAll(t.dir, 0700); err != nil {
+			return resp, nil
+		}
+		tmp, err := os.CreateTemp(t.dir, "response-*")
+		if err != nil {
+			return resp, nil
+		}
+		resp.Body = &cachingBody{
+			ReadCloser: resp.Body,
+			tmp:        tmp,
+			path:       path,
+			entry:      &cacheEntry{URL: req.URL.String(), Status: resp.StatusCode, Header: resp.Header.Clone(), StoredAt: time.Now()},
+		}
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		// Deleted on the server, so not worth keeping
+		os.Remove(path + ".json")
+		os.Remove(path + ".body")
+	}
+	return resp, nil
+}
+
+// offline reports whether an attempt failed in a way a cached copy can stand
+// in for: no connection, or a gateway that couldn't reach the server
+func offline(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// cachingBody copies a response body to the cache as it's read, and keeps the
+// copy once the body has been read to the end
+type cachingBody struct {
+	io.ReadCloser
+	tmp   *os.File
+	path  string
+	entry *cacheEntry
+	done  bool
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && b.tmp != nil {
+		if _, werr := b.tmp.Write(p[:n]); werr != nil {
+			b.discard()
+		}
+	}
+	if err == io.EOF {
+		b.done = true
+	}
+	return n, err
+}
+
+func (b *cachingBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.tmp == nil {
+		return err
+	}
+	if !b.done {
+		b.discard()
+		return err
+	}
+	b.tmp.Close()
+	data, _ := json.Marshal(b.entry)
+	if os.MkdirAll(filepath.Dir(b.path), 0700) == nil &&
+		os.Rename(b.tmp.Name(), b.path+".body") == nil {
+		os.WriteFile(b.path+".json", data, 0600)
+	} else {
+		os.Remove(b.tmp.Name())
+	}
+	b.tmp = nil
+	return err
+}
+
+// discard stops copying the body and removes what was copied
+func (b *cachingBody) discard() {
+	if b.tmp == nil {
+		return
+	}
+	b.tmp.Close()
+	os.Remove(b.tmp.Name())
+	b.tmp = nil
+}
+
+// =============================================================================
+// Cache command
+// =============================================================================
+
+func runCacheClear(cmd *cobra.Command, args []string) {
+	dir, err := cacheDir()
+	if err != nil {
+		ui.PrintError("Failed to find the cache: %v", err)
+		os.Exit(1)
+	}
+	var count int
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+			if strings.HasSuffix(path, ".json") {
+				count++
+			}
+		}
+		return nil
+	})
+	if err := os.RemoveAll(dir); err != nil {
+		ui.PrintError("Failed to clear the cache: %v", err)
+		os.Exit(1)
+	}
+	ui.PrintSuccess("Cleared %s (%d responses, %s)", dir, count, formatBytes(size))
+}
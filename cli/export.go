@@ -0,0 +1,323 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"velocity/internal/ui"
+)
+
+var (
+	exportParallel int
+	exportVerify   bool
+)
+
+// errChecksumMismatch is returned for content that doesn't match the SHA-256
+// the server has for it
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+// exportFile is an item and the local file it's saved to
+type exportFile struct {
+	ID       string `json:"id"`
+	Path     string `json:"file"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256,omitempty"`
+	Verified bool   `json:"verified"`
+	Error    string `json:"error,omitempty"`
+
+	mimeType string
+}
+
+// downloadFile saves a response body to path, hashing it as it's written. The
+// body goes to a temporary file beside path, which is renamed into place only
+// if the content matches the server's checksum, so a failed or corrupted
+// download never leaves a partial file. Content the server has no checksum
+// for is saved unverified.
+func (c *client) downloadFile(resp *http.Response, contentType, id, path string, p *progress) (n int64, sum string, verified bool, err error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return 0, "", false, err
+		}
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.part")
+	if err != nil {
+		return 0, "", false, err
+	}
+	defer os.Remove(f.Name())
+
+	hash := sha256.New()
+	n, err = io.Copy(io.MultiWriter(f, hash), p.reader(resp.Body))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, "", false, err
+	}
+	sum = hex.EncodeToString(hash.Sum(nil))
+
+	expected, err := c.expectedChecksum(resp, contentType, id)
+	if err != nil {
+		return n, sum, false, err
+	}
+	if expected != "" && expected != sum {
+		return n, sum, false, fmt.Errorf("%w: got %s, expected %s", errChecksumMismatch, sum, expected)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return n, sum, false, err
+	}
+	return n, sum, expected != "", nil
+}
+
+// expectedChecksum returns the SHA-256 a downloaded item should have: the
+// response's Repr-Digest, or else the checksum in the item's metadata if it's
+// of the object that was downloaded. "" means it can't be checked, say for
+// content a render plugin changed.
+func (c *client) expectedChecksum(resp *http.Response, contentType, id string) (string, error) {
+	if sum := reprDigest(resp.Header.Get("Repr-Digest")); sum != "" {
+		return sum, nil
+	}
+	sum, etag, err := c.getChecksum(contentType, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get checksum: %w", err)
+	}
+	if etag != "" && etag != resp.Header.Get("ETag") {
+		return "", nil
+	}
+	return sum, nil
+}
+
+// reprDigest returns the hex SHA-256 in a Repr-Digest header (RFC 9530), or
+// "" if it has none
+func reprDigest(header string) string {
+	for _, entry := range strings.Split(header, ",") {
+		algorithm, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || !strings.EqualFold(algorithm, "sha-256") {
+			continue
+		}
+		sum, err := base64.StdEncoding.DecodeString(strings.Trim(value, ":"))
+		if err == nil && len(sum) == sha256.Size {
+			return hex.EncodeToString(sum)
+		}
+	}
+	return ""
+}
+
+// getChecksum returns the SHA-256 in an item's metadata and the ETag of the
+// object it's of
+func (c *client) getChecksum(contentType, id string) (string, string, error) {
+	req, err := http.NewRequest("GET", c.baseURL+"/api/content/"+contentType+"/"+id+"/metadata", nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.tenant != "" {
+		req.Header.Set("X-Tenant", c.tenant)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return "", "", fmt.Errorf("API error (%d): %s", resp.StatusCode, string(data))
+	}
+
+	var metadata map[string]string
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return "", "", err
+	}
+	return metadata["sha256"], resp.Header.Get("ETag"), nil
+}
+
+// fileChecksum returns the hex SHA-256 of a local file
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func runExport(cmd *cobra.Command, args []string) {
+	contentType := args[0]
+	dir := args[1]
+	if exportParallel < 1 {
+		exportParallel = 1
+	}
+
+	client := newClient()
+	items, err := client.listContent(contentType)
+	if err != nil {
+		ui.PrintError("Failed to list content: %v", err)
+		os.Exit(1)
+	}
+	if len(items) == 0 {
+		ui.PrintWarning("No %s to export", contentType)
+		return
+	}
+
+	files := make([]*exportFile, 0, len(items))
+	var total int64
+	for _, item := range items {
+		id := getField(item, "id")
+		name := id
+		if ext := getField(item, "ext"); ext != "" {
+			name += "." + ext
+		}
+		size, _ := item["size"].(float64)
+		files = append(files, &exportFile{
+			ID:       id,
+			Path:     filepath.Join(dir, filepath.FromSlash(name)),
+			Size:     int64(size),
+			mimeType: getField(item, "content_type"),
+		})
+		total += int64(size)
+	}
+
+	verb := "Exporting"
+	if exportVerify {
+		verb = "Verifying"
+	}
+	if outputFmt != "json" {
+		ui.PrintInfo("%s %d %s items (%s) in %s", verb, len(files), contentType, formatBytes(total), dir)
+	}
+
+	var p *progress
+	if !exportVerify {
+		p = newProgress(total, len(files))
+	}
+	start := time.Now()
+
+	var mu sync.Mutex
+	failed, unverified := 0, 0
+	queue := make(chan *exportFile)
+	var wg sync.WaitGroup
+	for w := 0; w < exportParallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range queue {
+				var err error
+				if exportVerify {
+					err = client.verifyFile(contentType, f)
+				} else {
+					err = client.exportFile(contentType, f, p)
+				}
+				p.fileDone()
+				mu.Lock()
+				if err != nil {
+					f.Error = err.Error()
+					failed++
+				} else if !f.Verified {
+					unverified++
+				}
+				mu.Unlock()
+				if outputFmt == "json" {
+					continue
+				}
+				p.print(func() {
+					switch {
+					case err != nil:
+						ui.PrintError("%s: %v", f.ID, err)
+					case !f.Verified:
+						ui.PrintWarning("%s: no checksum to verify against", f.ID)
+					default:
+						ui.PrintSuccess("%s %s", f.ID, ui.Muted("("+formatBytes(f.Size)+", sha256 "+f.SHA256[:12]+")"))
+					}
+				})
+			}
+		}()
+	}
+	for _, f := range files {
+		queue <- f
+	}
+	close(queue)
+	wg.Wait()
+	p.finish()
+
+	elapsed := time.Since(start)
+	if outputFmt == "json" {
+		printJSON(map[string]interface{}{
+			"type":       contentType,
+			"dir":        dir,
+			"files":      files,
+			"verified":   len(files) - failed - unverified,
+			"unverified": unverified,
+			"failed":     failed,
+			"bytes":      total,
+			"seconds":    elapsed.Seconds(),
+		})
+	} else if failed == 0 && exportVerify {
+		ui.PrintSuccess("Verified %d files (%s) in %s", len(files)-unverified, formatBytes(total), elapsed.Round(time.Millisecond))
+	} else if failed == 0 {
+		ui.PrintSuccess("Exported %d files (%s) in %s, %s/s", len(files), formatBytes(total), elapsed.Round(time.Millisecond), formatBytes(int64(float64(total)/elapsed.Seconds())))
+	} else {
+		ui.PrintWarning("%d of %d files failed", failed, len(files))
+	}
+	if unverified > 0 && outputFmt != "json" {
+		ui.PrintWarning("%d files have no checksum on the server and weren't verified", unverified)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// exportFile downloads an item to its file, verifying it
+func (c *client) exportFile(contentType string, f *exportFile, p *progress) error {
+	resp, err := c.getContentResponse(contentType, f.ID, f.mimeType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	n, sum, verified, err := c.downloadFile(resp, contentType, f.ID, f.Path, p)
+	f.Size, f.SHA256, f.Verified = n, sum, verified
+	return err
+}
+
+// verifyFile checks an item's local file against the server's checksum
+func (c *client) verifyFile(contentType string, f *exportFile) error {
+	sum, err := fileChecksum(f.Path)
+	if err != nil {
+		return err
+	}
+	f.SHA256 = sum
+	expected, _, err := c.getChecksum(contentType, f.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get checksum: %w", err)
+	}
+	if expected == "" {
+		return nil
+	}
+	if expected != sum {
+		return fmt.Errorf("%w: got %s, expected %s", errChecksumMismatch, sum, expected)
+	}
+	f.Verified = true
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"velocity/internal/ui"
+)
+
+var (
+	importPrefix   string
+	importParallel int
+)
+
+// importFile is a local file to upload and the item it becomes
+type importFile struct {
+	Path  string `json:"file"`
+	ID    string `json:"id"`
+	Size  int64  `json:"size"`
+	Error string `json:"error,omitempty"`
+}
+
+// uploadWithProgress uploads one file, showing its progress
+func uploadWithProgress(c *client, contentType, id, filePath string, metadata map[string]string) (map[string]interface{}, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	p := newProgress(info.Size(), 0)
+	defer p.finish()
+	return c.uploadFile(contentType, id, filePath, metadata, p)
+}
+
+func runImport(cmd *cobra.Command, args []string) {
+	contentType := args[0]
+	if importParallel < 1 {
+		importParallel = 1
+	}
+
+	metadata, err := parseMetadata()
+	if err != nil {
+		ui.PrintError("Failed to parse metadata: %v", err)
+		os.Exit(1)
+	}
+
+	files, err := collectImportFiles(args[1:], importPrefix)
+	if err != nil {
+		ui.PrintError("%v", err)
+		os.Exit(1)
+	}
+	if len(files) == 0 {
+		ui.PrintWarning("No files to import")
+		return
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	if outputFmt != "json" {
+		ui.PrintInfo("Importing %d files (%s) into %s", len(files), formatBytes(total), contentType)
+	}
+
+	client := newClient()
+	p := newProgress(total, len(files))
+	start := time.Now()
+
+	var mu sync.Mutex
+	failed := 0
+	queue := make(chan *importFile)
+	var wg sync.WaitGroup
+	for w := 0; w < importParallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range queue {
+				_, err := client.uploadFile(contentType, f.ID, f.Path, metadata, p)
+				p.fileDone()
+				if err != nil {
+					f.Error = err.Error()
+					mu.Lock()
+					failed++
+					mu.Unlock()
+				}
+				if outputFmt == "json" {
+					continue
+				}
+				p.print(func() {
+					if err != nil {
+						ui.PrintError("%s: %v", f.ID, err)
+					} else {
+						ui.PrintSuccess("%s %s", f.ID, ui.Muted("("+formatBytes(f.Size)+")"))
+					}
+				})
+			}
+		}()
+	}
+	for _, f := range files {
+		queue <- f
+	}
+	close(queue)
+	wg.Wait()
+	p.finish()
+
+	elapsed := time.Since(start)
+	if outputFmt == "json" {
+		printJSON(map[string]interface{}{
+			"type":     contentType,
+			"files":    files,
+			"imported": len(files) - failed,
+			"failed":   failed,
+			"bytes":    total,
+			"seconds":  elapsed.Seconds(),
+		})
+	} else if failed == 0 {
+		ui.PrintSuccess("Imported %d files (%s) in %s, %s/s", len(files), formatBytes(total), elapsed.Round(time.Millisecond), formatBytes(int64(float64(total)/elapsed.Seconds())))
+	} else {
+		ui.PrintWarning("Imported %d of %d files (%d failed)", len(files)-failed, len(files), failed)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// collectImportFiles lists the files to upload: each file argument becomes an
+// item named after it, and each directory is walked, with paths below it
+// becoming item IDs. Hidden files and directories are skipped.
+func collectImportFiles(args []string, prefix string) ([]*importFile, error) {
+	var files []*importFile
+	add := func(filePath, id string, size int64) {
+		if prefix != "" {
+			id = path.Join(prefix, id)
+		}
+		files = append(files, &importFile{Path: filePath, ID: id, Size: size})
+	}
+
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", arg, err)
+		}
+		if !info.IsDir() {
+			add(arg, filepath.Base(arg), info.Size())
+			continue
+		}
+
+		err = filepath.WalkDir(arg, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if filePath != arg && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(arg, filePath)
+			if err != nil {
+				return err
+			}
+			add(filePath, filepath.ToSlash(rel), info.Size())
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", arg, err)
+		}
+	}
+	return files, nil
+}
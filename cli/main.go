@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -9,9 +10,11 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
+	"unicode/utf8"
 
 	"github.com/spf13/cobra"
 
@@ -22,11 +25,19 @@ import (
 var (
 	endpoint     string
 	apiKey       string
+	session      string
 	tenant       string
 	outputFmt    string
 	dataFlag     string
 	fileFlag     string
+	acceptFlag   string
 	metadataFlag string
+
+	incrementalFlag bool
+	pruneFlag       bool
+	repairFlag      bool
+	detailsFlag     bool
+	pageSizeFlag    int
 )
 
 var rootCmd = &cobra.Command{
@@ -102,8 +113,12 @@ func init() {
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 	rootCmd.PersistentFlags().StringVar(&endpoint, "endpoint", getEnv("VELOCITY_ENDPOINT", "http://localhost:8080"), "API endpoint URL")
 	rootCmd.PersistentFlags().StringVar(&apiKey, "api-key", getEnv("VELOCITY_API_KEY", ""), "API key for authentication")
+	rootCmd.PersistentFlags().StringVar(&session, "session", getEnv("VELOCITY_SESSION", ""), "Operator session token, required by backup, fsck, and jobs (from POST /api/login)")
 	rootCmd.PersistentFlags().StringVar(&tenant, "tenant", getEnv("VELOCITY_TENANT", "demo"), "Tenant identifier")
 	rootCmd.PersistentFlags().StringVarP(&outputFmt, "output", "o", "table", "Output format (table, json)")
+	rootCmd.PersistentFlags().DurationVar(&timeoutFlag, "timeout", getEnvDuration("VELOCITY_TIMEOUT", 30*time.Second), "Timeout for each request attempt (0 for none)")
+	rootCmd.PersistentFlags().IntVar(&retriesFlag, "retries", getEnvInt("VELOCITY_RETRIES", 0), "Retries for failed connections and 429/502/503/504 responses")
+	rootCmd.PersistentFlags().BoolVarP(&verboseFlag, "verbose", "v", false, "Print request and response headers to stderr (API keys redacted)")
 
 	// Version command
 	rootCmd.AddCommand(&cobra.Command{
@@ -115,11 +130,14 @@ func init() {
 	})
 
 	// Types command
-	rootCmd.AddCommand(&cobra.Command{
+	typesCmd := &cobra.Command{
 		Use:   "types",
 		Short: "List available content types",
 		Run:   runTypes,
-	})
+	}
+	typesCmd.Flags().BoolVar(&detailsFlag, "details", false, "Show each type's schema, item counts, and last change")
+	typesCmd.Flags().IntVar(&pageSizeFlag, "page-size", 100, "Types fetched per request with --details")
+	rootCmd.AddCommand(typesCmd)
 
 	// Content command group
 	contentCmd := &cobra.Command{
@@ -133,13 +151,20 @@ func init() {
 		Args:  cobra.ExactArgs(1),
 		Run:   runList,
 	}
+	listCmd.Flags().BoolVar(&cachedFlag, "cached", false, "Use the locally cached list without asking the server")
+	listCmd.Flags().BoolVar(&refreshFlag, "refresh", false, "Fetch the list again, ignoring the local cache")
 
 	getCmd := &cobra.Command{
-		Use:   "get <type> <id>",
-		Short: "Get a content item",
-		Args:  cobra.ExactArgs(2),
-		Run:   runGet,
+		Use:     "get <type> <id>",
+		Aliases: []string{"download"},
+		Short:   "Get a content item",
+		Args:    cobra.ExactArgs(2),
+		Run:     runGet,
 	}
+	getCmd.Flags().StringVarP(&fileFlag, "file", "f", "", "Save the content to a file, verifying its SHA-256")
+	getCmd.Flags().StringVar(&acceptFlag, "accept", "", "Media type to request, for items stored in several formats (e.g. text/markdown)")
+	getCmd.Flags().BoolVar(&cachedFlag, "cached", false, "Use the locally cached copy without asking the server")
+	getCmd.Flags().BoolVar(&refreshFlag, "refresh", false, "Fetch the item again, ignoring the local cache")
 
 	createCmd := &cobra.Command{
 		Use:   "create <type> <id>",
@@ -207,6 +232,180 @@ func init() {
 	metadataCmd.AddCommand(metadataGetCmd, metadataSetCmd, metadataUpdateCmd, metadataRemoveCmd)
 	contentCmd.AddCommand(listCmd, getCmd, createCmd, updateCmd, deleteCmd, metadataCmd)
 	rootCmd.AddCommand(contentCmd)
+
+	// Backup command group
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Manage content backups",
+	}
+
+	backupCreateCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Start a backup of the tenant's content",
+		Args:  cobra.NoArgs,
+		Run:   runBackupCreate,
+	}
+	backupCreateCmd.Flags().BoolVar(&incrementalFlag, "incremental", false, "Only copy objects changed since the last backup")
+
+	backupListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List backup manifests",
+		Args:  cobra.NoArgs,
+		Run:   runBackupList,
+	}
+
+	backupRestoreCmd := &cobra.Command{
+		Use:   "restore <manifest>",
+		Short: "Restore content from a backup manifest",
+		Args:  cobra.ExactArgs(1),
+		Run:   runBackupRestore,
+	}
+	backupRestoreCmd.Flags().BoolVar(&pruneFlag, "prune", false, "Delete content that is not in the backup")
+
+	backupCmd.AddCommand(backupCreateCmd, backupListCmd, backupRestoreCmd)
+	rootCmd.AddCommand(backupCmd)
+
+	// Admin command group
+	adminCmd := &cobra.Command{
+		Use:   "admin",
+		Short: "Administrative tools",
+	}
+
+	fsckCmd := &cobra.Command{
+		Use:   "fsck",
+		Short: "Check the tenant's storage for inconsistencies",
+		Args:  cobra.NoArgs,
+		Run:   runFsck,
+	}
+	fsckCmd.Flags().BoolVar(&repairFlag, "repair", false, "Repair orphaned comments, history records, and index entries")
+
+	jobsCmd := &cobra.Command{
+		Use:   "jobs",
+		Short: "List background jobs",
+		Args:  cobra.NoArgs,
+		Run:   runJobsList,
+	}
+
+	jobsCancelCmd := &cobra.Command{
+		Use:   "cancel <id>",
+		Short: "Cancel a running background job",
+		Args:  cobra.ExactArgs(1),
+		Run:   runJobsCancel,
+	}
+
+	jobsCmd.AddCommand(jobsCancelCmd)
+	adminCmd.AddCommand(fsckCmd, jobsCmd)
+	rootCmd.AddCommand(adminCmd)
+
+	// Bench command
+	benchCmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Load test the API and report latency per route",
+		Args:  cobra.NoArgs,
+		Run:   runBench,
+	}
+	benchCmd.Flags().IntVar(&benchConcurrency, "concurrency", 10, "Number of concurrent workers")
+	benchCmd.Flags().DurationVar(&benchDuration, "duration", 30*time.Second, "How long to run")
+	benchCmd.Flags().StringVar(&benchMix, "mix", "read=80,write=20", "Operation weights (read, write, list)")
+	benchCmd.Flags().StringVar(&benchType, "type", "bench", "Content type to read and write")
+	benchCmd.Flags().IntVar(&benchItems, "items", 100, "Number of items to seed and spread requests across")
+	rootCmd.AddCommand(benchCmd)
+
+	// Seed command
+	seedCmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Generate fake content for demos and testing",
+		Args:  cobra.NoArgs,
+		Run:   runSeed,
+	}
+	seedCmd.Flags().StringVar(&seedType, "type", "", "Content type to create (required)")
+	seedCmd.Flags().IntVar(&seedCount, "count", 10, "Number of items to create")
+	seedCmd.Flags().StringVar(&seedSchema, "schema", "", "Schema file describing the fields (default: the type's schema on the server)")
+	seedCmd.Flags().BoolVar(&seedImages, "images", true, "Upload generated images for image fields")
+	seedCmd.Flags().StringVar(&seedImageType, "image-type", "images", "Content type for generated images")
+	seedCmd.Flags().StringVar(&seedState, "state", "live", "State to create content in (draft, pending, live)")
+	seedCmd.Flags().IntVar(&seedConcurrency, "concurrency", 8, "Number of concurrent requests")
+	seedCmd.Flags().Int64Var(&seedRandom, "seed", 0, "Random seed for reproducible content (default: random)")
+	rootCmd.AddCommand(seedCmd)
+
+	// Metadata command (bulk operations; per-item metadata is under content)
+	bulkMetadataCmd := &cobra.Command{
+		Use:   "metadata",
+		Short: "Manage metadata across many items",
+	}
+	metadataApplyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Set metadata on many items from a CSV file",
+		Args:  cobra.NoArgs,
+		Run:   runMetadataApply,
+	}
+	metadataApplyCmd.Flags().StringVar(&metaApplyFile, "from-file", "", "CSV file with an id column and a column per key (required)")
+	metadataApplyCmd.Flags().StringVar(&metaApplyType, "type", "", "Content type for rows without a type column")
+	metadataApplyCmd.Flags().StringVar(&metaApplyState, "state", "live", "State whose metadata to set (draft, pending, live)")
+	metadataApplyCmd.Flags().BoolVar(&metaApplyReplace, "replace", false, "Replace each item's metadata instead of merging into it")
+	metadataApplyCmd.Flags().BoolVar(&metaApplyDryRun, "dry-run", false, "Show what would change without changing it")
+	metadataApplyCmd.Flags().IntVar(&metaApplyParallel, "parallel", 4, "Number of items to update at once")
+	bulkMetadataCmd.AddCommand(metadataApplyCmd)
+	rootCmd.AddCommand(bulkMetadataCmd)
+
+	// Import command
+	importCmd := &cobra.Command{
+		Use:   "import <type> <path>...",
+		Short: "Upload local files and folders as content items",
+		Args:  cobra.MinimumNArgs(2),
+		Run:   runImport,
+	}
+	importCmd.Flags().StringVar(&importPrefix, "prefix", "", "Folder to store the items under")
+	importCmd.Flags().IntVar(&importParallel, "parallel", 4, "Number of files to upload at once")
+	importCmd.Flags().StringVarP(&metadataFlag, "metadata", "m", "", "Metadata for every item, as JSON or key:value,key:value format")
+	rootCmd.AddCommand(importCmd)
+
+	// Export command
+	exportCmd := &cobra.Command{
+		Use:   "export <type> <dir>",
+		Short: "Download a type's items to a local folder, verifying each",
+		Args:  cobra.ExactArgs(2),
+		Run:   runExport,
+	}
+	exportCmd.Flags().IntVar(&exportParallel, "parallel", 4, "Number of files to download at once")
+	exportCmd.Flags().BoolVar(&exportVerify, "verify", false, "Check the files already in the folder against the server's checksums instead of downloading")
+	rootCmd.AddCommand(exportCmd)
+
+	// Cache command
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the local cache of fetched content",
+	}
+	cacheCmd.AddCommand(&cobra.Command{
+		Use:   "clear",
+		Short: "Remove everything from the local cache",
+		Args:  cobra.NoArgs,
+		Run:   runCacheClear,
+	})
+	rootCmd.AddCommand(cacheCmd)
+
+	// Apply command
+	applyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Reconcile schemas, webhooks, and settings with a config file",
+		Args:  cobra.NoArgs,
+		Run:   runApply,
+	}
+	applyCmd.Flags().StringVarP(&applyFile, "file", "f", "velocity.yaml", "Config file to apply")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Show the changes without making them")
+	rootCmd.AddCommand(applyCmd)
+
+	// Mount command
+	mountCmd := &cobra.Command{
+		Use:   "mount <type> <dir>",
+		Short: "Mount a content type as a local folder (over WebDAV)",
+		Args:  cobra.ExactArgs(2),
+		Run:   runMount,
+	}
+	mountCmd.Flags().IntVar(&mountPort, "port", 0, "Local WebDAV port (default: any free port)")
+	mountCmd.Flags().StringVar(&mountState, "state", "live", "State to mount (draft, pending, live)")
+	mountCmd.Flags().BoolVar(&mountServeOnly, "serve-only", false, "Only serve WebDAV; don't mount it")
+	rootCmd.AddCommand(mountCmd)
 }
 
 func getEnv(key, defaultValue string) string {
@@ -216,10 +415,29 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
 // Command handlers
 
 func runTypes(cmd *cobra.Command, args []string) {
 	client := newClient()
+	if detailsFlag {
+		runTypeDetails(client)
+		return
+	}
+
 	types, err := client.listTypes()
 	if err != nil {
 		ui.PrintError("Failed to list types: %v", err)
@@ -237,9 +455,43 @@ func runTypes(cmd *cobra.Command, args []string) {
 	}
 }
 
+func runTypeDetails(client *client) {
+	types, err := client.listTypeDetails(pageSizeFlag)
+	if err != nil {
+		ui.PrintError("Failed to list types: %v", err)
+		os.Exit(1)
+	}
+
+	if outputFmt == "json" {
+		printJSON(types)
+		return
+	}
+
+	fmt.Println(ui.Header("Content Types"))
+
+	if len(types) == 0 {
+		fmt.Println("  No types found")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tSCHEMA\tEXT\tLIVE\tDRAFT\tPENDING\tLAST MODIFIED\tDESCRIPTION")
+	fmt.Fprintln(w, "  ----\t------\t---\t----\t-----\t-------\t-------------\t-----------")
+
+	for _, t := range types {
+		counts, _ := t["counts"].(map[string]interface{})
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			getField(t, "name"), getField(t, "schema"), getField(t, "extension"),
+			getField(counts, "live"), getField(counts, "draft"), getField(counts, "pending"),
+			getField(t, "last_modified"), getField(t, "description"))
+	}
+	w.Flush()
+}
+
 func runList(cmd *cobra.Command, args []string) {
 	contentType := args[0]
 	client := newClient()
+	client.useCache()
 
 	items, err := client.listContent(contentType)
 	if err != nil {
@@ -276,20 +528,89 @@ func runGet(cmd *cobra.Command, args []string) {
 	contentType := args[0]
 	id := args[1]
 	client := newClient()
+	client.useCache()
 
-	item, err := client.getContent(contentType, id)
+	resp, err := client.getContentResponse(contentType, id, acceptFlag)
 	if err != nil {
 		ui.PrintError("Failed to get content: %v", err)
 		os.Exit(1)
 	}
+	defer resp.Body.Close()
 
-	if outputFmt == "json" {
-		printJSON(item)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	// Anything can be saved to a file as is, once it matches the server's checksum
+	if fileFlag != "" {
+		n, sum, verified, err := client.downloadFile(resp, contentType, id, fileFlag, nil)
+		if err != nil {
+			ui.PrintError("Failed to save content: %v", err)
+			os.Exit(1)
+		}
+		ui.PrintSuccess("Saved %s to %s (%s, %s)", id, fileFlag, mediaType, formatBytes(n))
+		if verified {
+			ui.PrintInfo("Verified sha256 %s", sum)
+		} else {
+			ui.PrintWarning("No checksum to verify against (sha256 %s)", sum)
+		}
+		return
+	}
+
+	if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+		var item map[string]interface{}
+		data, err := io.ReadAll(resp.Body)
+		if err == nil {
+			err = json.Unmarshal(data, &item)
+		}
+		if err != nil {
+			// Not an object (say, a JSON array), so show it as it is
+			os.Stdout.Write(data)
+			return
+		}
+		if outputFmt == "json" {
+			printJSON(item)
+			return
+		}
+		fmt.Println(ui.Header(fmt.Sprintf("%s: %s", strings.Title(contentType), id)))
+		printFields(item, "  ")
 		return
 	}
 
-	fmt.Println(ui.Header(fmt.Sprintf("%s: %s", strings.Title(contentType), id)))
-	printFields(item, "  ")
+	body := bufio.NewReader(resp.Body)
+	if !isTextContent(mediaType, body) && isTerminal(os.Stdout) {
+		ui.PrintError("%s is binary (%s); use -f <file> to save it, or redirect the output", id, mediaType)
+		os.Exit(1)
+	}
+	io.Copy(os.Stdout, body)
+}
+
+// isTextContent reports whether content is safe to print: a text media type,
+// or (for types that don't say) a start that is valid UTF-8 without NULs
+func isTextContent(mediaType string, body *bufio.Reader) bool {
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/xml",
+		mediaType == "application/javascript",
+		mediaType == "application/yaml",
+		mediaType == "application/x-yaml":
+		return true
+	case mediaType != "" && mediaType != "application/octet-stream":
+		return false
+	}
+	start, _ := body.Peek(512)
+	if len(start) == 512 {
+		// A multi-byte character may be cut off at the end
+		for i := 1; i < utf8.UTFMax && !utf8.Valid(start); i++ {
+			start = start[:len(start)-1]
+		}
+	}
+	return utf8.Valid(start) && !bytes.Contains(start, []byte{0})
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
 }
 
 func runCreate(cmd *cobra.Command, args []string) {
@@ -306,7 +627,7 @@ func runCreate(cmd *cobra.Command, args []string) {
 	var result map[string]interface{}
 
 	if fileFlag != "" {
-		result, err = client.uploadFile(contentType, id, fileFlag, metadata)
+		result, err = uploadWithProgress(client, contentType, id, fileFlag, metadata)
 	} else {
 		var data map[string]interface{}
 		data, err = parseData()
@@ -342,7 +663,7 @@ func runUpdate(cmd *cobra.Command, args []string) {
 	}
 
 	if fileFlag != "" {
-		_, err = client.uploadFile(contentType, id, fileFlag, metadata)
+		_, err = uploadWithProgress(client, contentType, id, fileFlag, metadata)
 	} else {
 		var data map[string]interface{}
 		data, err = parseData()
@@ -464,6 +785,187 @@ func runMetadataRemove(cmd *cobra.Command, args []string) {
 
 // Helpers
 
+func runBackupCreate(cmd *cobra.Command, args []string) {
+	client := newClient()
+
+	result, err := client.createBackup(incrementalFlag)
+	if err != nil {
+		ui.PrintError("Failed to start backup: %v", err)
+		os.Exit(1)
+	}
+
+	if outputFmt == "json" {
+		printJSON(result)
+		return
+	}
+
+	kind := "Full"
+	if incrementalFlag {
+		kind = "Incremental"
+	}
+	job, _ := result["job"].(map[string]interface{})
+	ui.PrintSuccess("%s backup started (job %s)", kind, getField(job, "id"))
+}
+
+func runBackupList(cmd *cobra.Command, args []string) {
+	client := newClient()
+
+	backups, err := client.listBackups()
+	if err != nil {
+		ui.PrintError("Failed to list backups: %v", err)
+		os.Exit(1)
+	}
+
+	if outputFmt == "json" {
+		printJSON(backups)
+		return
+	}
+
+	fmt.Println(ui.Header("Backups"))
+
+	if len(backups) == 0 {
+		fmt.Println("  No backups found")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  MANIFEST\tROOT\tINCREMENTAL\tOBJECTS\tCOPIED")
+	fmt.Fprintln(w, "  --------\t----\t-----------\t-------\t------")
+
+	for _, backup := range backups {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n",
+			getField(backup, "id"), getField(backup, "root"), getField(backup, "incremental"),
+			getField(backup, "objects"), getField(backup, "copied"))
+	}
+	w.Flush()
+}
+
+func runBackupRestore(cmd *cobra.Command, args []string) {
+	manifest := args[0]
+	client := newClient()
+
+	result, err := client.restoreBackup(manifest, pruneFlag)
+	if err != nil {
+		ui.PrintError("Failed to start restore: %v", err)
+		os.Exit(1)
+	}
+
+	if outputFmt == "json" {
+		printJSON(result)
+		return
+	}
+
+	job, _ := result["job"].(map[string]interface{})
+	ui.PrintSuccess("Restore of %s started (job %s)", manifest, getField(job, "id"))
+}
+
+func runFsck(cmd *cobra.Command, args []string) {
+	client := newClient()
+
+	if _, err := client.startFsck(repairFlag); err != nil {
+		ui.PrintError("Failed to start consistency check: %v", err)
+		os.Exit(1)
+	}
+
+	// Wait for the check to finish
+	var report map[string]interface{}
+	for {
+		result, err := client.fsckReport()
+		if err != nil {
+			ui.PrintError("Failed to get consistency report: %v", err)
+			os.Exit(1)
+		}
+		if job, _ := result["job"].(map[string]interface{}); job == nil {
+			report, _ = result["report"].(map[string]interface{})
+			break
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	if outputFmt == "json" {
+		printJSON(report)
+		return
+	}
+
+	fmt.Println(ui.Header("Consistency Check"))
+
+	issues, _ := report["issues"].([]interface{})
+	if len(issues) == 0 {
+		ui.PrintSuccess("No issues found (%s items checked)", getField(report, "checked"))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  KIND\tTYPE\tID\tSTATE\tDETAIL\tREPAIRED")
+	fmt.Fprintln(w, "  ----\t----\t--\t-----\t------\t--------")
+
+	for _, v := range issues {
+		issue, _ := v.(map[string]interface{})
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\n",
+			getField(issue, "kind"), getField(issue, "type"), getField(issue, "id"),
+			getField(issue, "state"), getField(issue, "detail"), getField(issue, "repaired"))
+	}
+	w.Flush()
+
+	ui.PrintWarning("%d issues found, %s repaired", len(issues), getField(report, "repaired"))
+}
+
+func runJobsList(cmd *cobra.Command, args []string) {
+	client := newClient()
+
+	jobs, err := client.listJobs()
+	if err != nil {
+		ui.PrintError("Failed to list jobs: %v", err)
+		os.Exit(1)
+	}
+
+	if outputFmt == "json" {
+		printJSON(jobs)
+		return
+	}
+
+	fmt.Println(ui.Header("Jobs"))
+
+	if len(jobs) == 0 {
+		fmt.Println("  No jobs found")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  ID\tKIND\tTENANT\tSTATUS\tPROGRESS\tMESSAGE")
+	fmt.Fprintln(w, "  --\t----\t------\t------\t--------\t-------")
+
+	for _, job := range jobs {
+		progress, _ := job["progress"].(float64)
+		message := getField(job, "message")
+		if errMsg := getField(job, "error"); errMsg != "" {
+			message = errMsg
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%.0f%%\t%s\n",
+			getField(job, "id"), getField(job, "kind"), getField(job, "tenant"),
+			getField(job, "status"), progress, message)
+	}
+	w.Flush()
+}
+
+func runJobsCancel(cmd *cobra.Command, args []string) {
+	id := args[0]
+	client := newClient()
+
+	result, err := client.cancelJob(id)
+	if err != nil {
+		ui.PrintError("Failed to cancel job: %v", err)
+		os.Exit(1)
+	}
+
+	if outputFmt == "json" {
+		printJSON(result)
+		return
+	}
+
+	ui.PrintSuccess("Cancellation requested for job %s", id)
+}
+
 func parseData() (map[string]interface{}, error) {
 	var jsonData string
 
@@ -565,6 +1067,7 @@ func getField(item map[string]interface{}, keys ...string) string {
 type client struct {
 	baseURL    string
 	apiKey     string
+	session    string
 	tenant     string
 	httpClient *http.Client
 }
@@ -573,9 +1076,10 @@ func newClient() *client {
 	return &client{
 		baseURL: endpoint,
 		apiKey:  apiKey,
+		session: session,
 		tenant:  tenant,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Transport: newTransport(),
 		},
 	}
 }
@@ -599,6 +1103,9 @@ func (c *client) request(method, path string, body interface{}) ([]byte, error)
 	if c.apiKey != "" {
 		req.Header.Set("X-API-Key", c.apiKey)
 	}
+	if c.session != "" {
+		req.Header.Set("Authorization", "Bearer "+c.session)
+	}
 	if c.tenant != "" {
 		req.Header.Set("X-Tenant", c.tenant)
 	}
@@ -637,6 +1144,30 @@ func (c *client) listTypes() ([]string, error) {
 	return result.Types, nil
 }
 
+// listTypeDetails fetches the detailed type catalog a page at a time
+func (c *client) listTypeDetails(pageSize int) ([]map[string]interface{}, error) {
+	types := []map[string]interface{}{}
+	for offset := 0; ; {
+		data, err := c.request("GET", fmt.Sprintf("/api/types?details=true&limit=%d&offset=%d", pageSize, offset), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Types      []map[string]interface{} `json:"types"`
+			NextOffset *int                     `json:"next_offset"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, err
+		}
+		types = append(types, result.Types...)
+		if result.NextOffset == nil {
+			return types, nil
+		}
+		offset = *result.NextOffset
+	}
+}
+
 func (c *client) listContent(contentType string) ([]map[string]interface{}, error) {
 	data, err := c.request("GET", "/api/content/"+contentType, nil)
 	if err != nil {
@@ -667,6 +1198,39 @@ func (c *client) getContent(contentType, id string) (map[string]interface{}, err
 	return result, nil
 }
 
+// getContentResponse fetches an item as it is stored, asking for the
+// representation named by accept when given. The caller closes the body.
+func (c *client) getContentResponse(contentType, id, accept string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", c.baseURL+"/api/content/"+contentType+"/"+id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	req.Header.Set("Want-Repr-Digest", "sha-256=1")
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.session != "" {
+		req.Header.Set("Authorization", "Bearer "+c.session)
+	}
+	if c.tenant != "" {
+		req.Header.Set("X-Tenant", c.tenant)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(data))
+	}
+	return resp, nil
+}
+
 func (c *client) createContent(contentType, id string, body map[string]interface{}) (map[string]interface{}, error) {
 	return c.createContentWithMetadata(contentType, id, body, nil)
 }
@@ -686,6 +1250,9 @@ func (c *client) createContentWithMetadata(contentType, id string, body map[stri
 	if c.apiKey != "" {
 		req.Header.Set("X-API-Key", c.apiKey)
 	}
+	if c.session != "" {
+		req.Header.Set("Authorization", "Bearer "+c.session)
+	}
 	if c.tenant != "" {
 		req.Header.Set("X-Tenant", c.tenant)
 	}
@@ -718,12 +1285,15 @@ func (c *client) createContentWithMetadata(contentType, id string, body map[stri
 	return result, nil
 }
 
-func (c *client) uploadFile(contentType, id, filePath string, metadata map[string]string) (map[string]interface{}, error) {
+// uploadFile sends a file as an item, counting the bytes sent toward p (which
+// may be nil)
+func (c *client) uploadFile(contentType, id, filePath string, metadata map[string]string, p *progress) (map[string]interface{}, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
-	defer file.Close()
+	body := p.reader(file)
+	defer func() { body.Close() }()
 
 	// Detect content type from file extension
 	ext := filepath.Ext(filePath)
@@ -732,15 +1302,31 @@ func (c *client) uploadFile(contentType, id, filePath string, metadata map[strin
 		mimeType = "application/octet-stream"
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+"/api/content/"+contentType+"/"+id, file)
+	req, err := http.NewRequest("POST", c.baseURL+"/api/content/"+contentType+"/"+id, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if info, err := file.Stat(); err == nil {
+		req.ContentLength = info.Size()
+	}
+	// Reopen the file if the upload has to be retried, starting its count over
+	req.GetBody = func() (io.ReadCloser, error) {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, err
+		}
+		body.rewind()
+		body = p.reader(file)
+		return body, nil
+	}
 
 	req.Header.Set("Content-Type", mimeType)
 	if c.apiKey != "" {
 		req.Header.Set("X-API-Key", c.apiKey)
 	}
+	if c.session != "" {
+		req.Header.Set("Authorization", "Bearer "+c.session)
+	}
 	if c.tenant != "" {
 		req.Header.Set("X-Tenant", c.tenant)
 	}
@@ -792,6 +1378,9 @@ func (c *client) updateContentWithMetadata(contentType, id string, body map[stri
 	if c.apiKey != "" {
 		req.Header.Set("X-API-Key", c.apiKey)
 	}
+	if c.session != "" {
+		req.Header.Set("Authorization", "Bearer "+c.session)
+	}
 	if c.tenant != "" {
 		req.Header.Set("X-Tenant", c.tenant)
 	}
@@ -858,3 +1447,110 @@ func (c *client) removeMetadata(contentType, id string, keys []string) error {
 	_, err := c.request("DELETE", "/api/content/"+contentType+"/"+id+"/metadata", body)
 	return err
 }
+
+func (c *client) createBackup(incremental bool) (map[string]interface{}, error) {
+	path := "/api/admin/backups"
+	if incremental {
+		path += "?incremental=true"
+	}
+	data, err := c.request("POST", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *client) listBackups() ([]map[string]interface{}, error) {
+	data, err := c.request("GET", "/api/admin/backups", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Backups []map[string]interface{} `json:"backups"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result.Backups, nil
+}
+
+func (c *client) restoreBackup(manifest string, prune bool) (map[string]interface{}, error) {
+	path := "/api/admin/backups/" + manifest + "/restore"
+	if prune {
+		path += "?prune=true"
+	}
+	data, err := c.request("POST", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *client) startFsck(repair bool) (map[string]interface{}, error) {
+	path := "/api/admin/fsck"
+	if repair {
+		path += "?repair=true"
+	}
+	data, err := c.request("POST", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *client) fsckReport() (map[string]interface{}, error) {
+	data, err := c.request("GET", "/api/admin/fsck", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *client) listJobs() ([]map[string]interface{}, error) {
+	data, err := c.request("GET", "/api/admin/jobs", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Jobs []map[string]interface{} `json:"jobs"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result.Jobs, nil
+}
+
+func (c *client) cancelJob(id string) (map[string]interface{}, error) {
+	data, err := c.request("POST", "/api/admin/jobs/"+id+"/cancel", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"velocity/internal/ui"
+)
+
+var (
+	metaApplyFile     string
+	metaApplyType     string
+	metaApplyState    string
+	metaApplyReplace  bool
+	metaApplyDryRun   bool
+	metaApplyParallel int
+)
+
+// metadataRow is one CSV row: the item and the metadata to give it
+type metadataRow struct {
+	Row      int               `json:"row"`
+	Type     string            `json:"type"`
+	ID       string            `json:"id"`
+	Metadata map[string]string `json:"metadata"`
+	Result   string            `json:"result"` // updated, unchanged, would update, or failed
+	Changes  []string          `json:"changes,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+func runMetadataApply(cmd *cobra.Command, args []string) {
+	if metaApplyFile == "" {
+		ui.PrintError("--from-file is required")
+		os.Exit(1)
+	}
+	if metaApplyParallel < 1 {
+		metaApplyParallel = 1
+	}
+
+	rows, err := readMetadataCSV(metaApplyFile, metaApplyType)
+	if err != nil {
+		ui.PrintError("%v", err)
+		os.Exit(1)
+	}
+	if len(rows) == 0 {
+		ui.PrintWarning("No rows in %s", metaApplyFile)
+		return
+	}
+
+	action := "Applying"
+	if metaApplyDryRun {
+		action = "Checking"
+	}
+	if outputFmt != "json" {
+		ui.PrintInfo("%s metadata for %d items from %s", action, len(rows), metaApplyFile)
+	}
+
+	client := newClient()
+	queue := make(chan *metadataRow)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for w := 0; w < metaApplyParallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for row := range queue {
+				applyMetadataRow(client, row)
+				if outputFmt == "json" {
+					continue
+				}
+				mu.Lock()
+				printMetadataRow(row)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, row := range rows {
+		queue <- row
+	}
+	close(queue)
+	wg.Wait()
+
+	counts := make(map[string]int)
+	for _, row := range rows {
+		counts[row.Result]++
+	}
+
+	if outputFmt == "json" {
+		printJSON(map[string]interface{}{
+			"file":    metaApplyFile,
+			"dry_run": metaApplyDryRun,
+			"rows":    rows,
+			"counts":  counts,
+		})
+	} else if metaApplyDryRun {
+		ui.PrintSuccess("Dry run: %d would change, %d unchanged, %d failed", counts["would update"], counts["unchanged"], counts["failed"])
+	} else if counts["failed"] == 0 {
+		ui.PrintSuccess("Updated metadata on %d items", counts["updated"])
+	} else {
+		ui.PrintWarning("Updated metadata on %d of %d items (%d failed)", counts["updated"], len(rows), counts["failed"])
+	}
+	if counts["failed"] > 0 {
+		os.Exit(1)
+	}
+}
+
+// applyMetadataRow writes one row's metadata, or with --dry-run compares it
+// with what the item has
+func applyMetadataRow(c *client, row *metadataRow) {
+	path := "/api/content/" + row.Type + "/" + row.ID
+	if metaApplyState != "" && metaApplyState != "live" {
+		path += "/" + metaApplyState
+	}
+	path += "/metadata"
+
+	if metaApplyDryRun {
+		var current map[string]string
+		data, err := c.request("GET", path, nil)
+		if err == nil {
+			err = json.Unmarshal(data, &current)
+		}
+		if err != nil {
+			row.Result, row.Error = "failed", err.Error()
+			return
+		}
+		row.Changes = metadataChanges(current, row.Metadata, metaApplyReplace)
+		row.Result = "unchanged"
+		if len(row.Changes) > 0 {
+			row.Result = "would update"
+		}
+		return
+	}
+
+	method := "PATCH"
+	if metaApplyReplace {
+		method = "PUT"
+	}
+	if _, err := c.request(method, path, row.Metadata); err != nil {
+		row.Result, row.Error = "failed", err.Error()
+		return
+	}
+	row.Result = "updated"
+}
+
+// metadataChanges lists how applying metadata would change current: keys set
+// or changed, and with replace, keys removed
+func metadataChanges(current, metadata map[string]string, replace bool) []string {
+	var changes []string
+	for key, value := range metadata {
+		if old, ok := current[key]; !ok {
+			changes = append(changes, fmt.Sprintf("+%s=%s", key, value))
+		} else if old != value {
+			changes = append(changes, fmt.Sprintf("~%s=%s (was %s)", key, value, old))
+		}
+	}
+	if replace {
+		for key := range current {
+			if _, ok := metadata[key]; !ok {
+				changes = append(changes, "-"+key)
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i][1:] < changes[j][1:] })
+	return changes
+}
+
+func printMetadataRow(row *metadataRow) {
+	item := fmt.Sprintf("row %d %s/%s", row.Row, row.Type, row.ID)
+	switch row.Result {
+	case "failed":
+		ui.PrintError("%s: %s", item, row.Error)
+	case "unchanged":
+		fmt.Printf("  %s %s\n", ui.Muted("="), ui.Muted(item+" unchanged"))
+	case "would update":
+		fmt.Printf("  %s %s %s\n", ui.Highlight("~"), item, ui.Muted(strings.Join(row.Changes, " ")))
+	default:
+		ui.PrintSuccess("%s", item)
+	}
+}
+
+// readMetadataCSV reads the rows of a metadata file. The header names the
+// columns: id (required), type (optional, defaulting to defaultType), and a
+// column per metadata key. Empty cells leave the key alone.
+func readMetadataCSV(path, defaultType string) ([]*metadataRow, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	idCol, typeCol := -1, -1
+	seen := make(map[string]bool)
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		header[i] = name
+		if name == "" {
+			return nil, fmt.Errorf("%s: column %d has no name", path, i+1)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s: column %q appears twice", path, name)
+		}
+		seen[name] = true
+		switch name {
+		case "id":
+			idCol = i
+		case "type":
+			typeCol = i
+		}
+	}
+	if idCol < 0 {
+		return nil, fmt.Errorf("%s: no id column", path)
+	}
+	if typeCol < 0 && defaultType == "" {
+		return nil, fmt.Errorf("%s: no type column; use --type", path)
+	}
+
+	var rows []*metadataRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		row := &metadataRow{Row: line, Type: defaultType, Metadata: make(map[string]string)}
+		for i, value := range record {
+			if i >= len(header) {
+				return nil, fmt.Errorf("%s: row %d has more cells than the header", path, line)
+			}
+			value = strings.TrimSpace(value)
+			switch i {
+			case idCol:
+				row.ID = value
+			case typeCol:
+				if value != "" {
+					row.Type = value
+				}
+			default:
+				if value != "" {
+					row.Metadata[header[i]] = value
+				}
+			}
+		}
+		if row.ID == "" {
+			return nil, fmt.Errorf("%s: row %d has no id", path, line)
+		}
+		if row.Type == "" {
+			return nil, fmt.Errorf("%s: row %d has no type", path, line)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
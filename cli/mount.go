@@ -0,0 +1,840 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/webdav"
+
+	"velocity/internal/ui"
+)
+
+var (
+	mountPort      int
+	mountState     string
+	mountServeOnly bool
+)
+
+// mountListingTTL is how long a directory listing is reused before it is
+// fetched again; writes through the mount invalidate it immediately
+const mountListingTTL = 2 * time.Second
+
+// mountRemovedTTL is how long the metadata of a removed file is kept, so an
+// editor that saves by deleting (or renaming away) the original and writing a
+// new file doesn't drop it
+const mountRemovedTTL = time.Minute
+
+// mountExtensions maps the content types reported by listings to the file
+// extension shown in the mount (the reverse of the server's mimeFromExt)
+var mountExtensions = map[string]string{
+	"application/json":       ".json",
+	"image/png":              ".png",
+	"image/jpeg":             ".jpg",
+	"image/gif":              ".gif",
+	"image/svg+xml":          ".svg",
+	"image/webp":             ".webp",
+	"text/html":              ".html",
+	"text/css":               ".css",
+	"application/javascript": ".js",
+	"text/plain":             ".txt",
+	"text/markdown":          ".md",
+	"application/xml":        ".xml",
+	"application/pdf":        ".pdf",
+	"video/mp4":              ".mp4",
+	"audio/mpeg":             ".mp3",
+}
+
+func runMount(cmd *cobra.Command, args []string) {
+	contentType, dir := args[0], args[1]
+	switch mountState {
+	case "draft", "pending", "live":
+	default:
+		ui.PrintError("Invalid --state %q (expected draft, pending, or live)", mountState)
+		os.Exit(1)
+	}
+
+	fs := newMountFS(newClient(), contentType, mountState)
+	if _, err := fs.list(context.Background(), ""); err != nil {
+		ui.PrintError("Failed to list %s: %v", contentType, err)
+		os.Exit(1)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", mountPort))
+	if err != nil {
+		ui.PrintError("Failed to listen: %v", err)
+		os.Exit(1)
+	}
+	davURL := "http://" + listener.Addr().String() + "/"
+
+	server := &http.Server{
+		Handler: &webdav.Handler{
+			FileSystem: fs,
+			LockSystem: webdav.NewMemLS(),
+			Logger: func(r *http.Request, err error) {
+				if err != nil && !errors.Is(err, os.ErrNotExist) {
+					ui.PrintWarning("%s %s: %v", r.Method, r.URL.Path, err)
+				}
+			},
+		},
+	}
+	go server.Serve(listener)
+
+	mounted := false
+	if !mountServeOnly {
+		if err := mountWebDAV(davURL, dir, contentType); err != nil {
+			ui.PrintWarning("Could not mount %s: %v", dir, err)
+			ui.PrintInfo("Connect a WebDAV client to %s instead", davURL)
+		} else {
+			mounted = true
+			ui.PrintSuccess("Mounted %s (%s) at %s", contentType, mountState, dir)
+		}
+	}
+	ui.PrintKeyValue("WebDAV", davURL)
+	ui.PrintInfo("Press Ctrl+C to unmount")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	if mounted {
+		if err := unmountWebDAV(dir); err != nil {
+			ui.PrintWarning("Could not unmount %s: %v", dir, err)
+		}
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(shutdownCtx)
+}
+
+// mountWebDAV mounts the WebDAV server at dir with the platform's client
+func mountWebDAV(davURL, dir, name string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("mount_webdav", "-S", "-v", name, davURL, dir)
+	case "linux":
+		cmd = exec.Command("mount", "-t", "davfs", davURL, dir)
+	case "windows":
+		cmd = exec.Command("net", "use", dir, davURL)
+	default:
+		return fmt.Errorf("mounting is not supported on %s", runtime.GOOS)
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// unmountWebDAV undoes mountWebDAV
+func unmountWebDAV(dir string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("net", "use", dir, "/delete", "/y")
+	} else {
+		cmd = exec.Command("umount", dir)
+	}
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// isScratchName reports whether a file is an editor or OS temp file (hidden
+// files, backups, swap files, AppleDouble files). Scratch files live in memory
+// and are never uploaded.
+func isScratchName(name string) bool {
+	if name == "" {
+		return false
+	}
+	base := path.Base(name)
+	switch {
+	case strings.HasPrefix(base, "."), strings.HasSuffix(base, "~"), strings.HasSuffix(base, ".tmp"):
+		return true
+	case base == "4913", base == "Thumbs.db", base == "desktop.ini": // 4913 is vim's write test
+		return true
+	}
+	return false
+}
+
+// mountFS is a webdav.FileSystem over one content type and state, backed by
+// the content API. Files are named {id}.{ext}; folders are id prefixes.
+type mountFS struct {
+	client      *client
+	contentType string
+	state       string
+	scratch     webdav.FileSystem
+
+	mu       sync.Mutex
+	listings map[string]*mountListing
+	removed  map[string]mountRemoved
+}
+
+// mountListing is a cached directory listing
+type mountListing struct {
+	entries []*mountEntry
+	fetched time.Time
+}
+
+// mountRemoved is the metadata of a recently removed file
+type mountRemoved struct {
+	metadata map[string]string
+	at       time.Time
+}
+
+func newMountFS(c *client, contentType, state string) *mountFS {
+	return &mountFS{
+		client:      c,
+		contentType: contentType,
+		state:       state,
+		scratch:     webdav.NewMemFS(),
+		listings:    make(map[string]*mountListing),
+		removed:     make(map[string]mountRemoved),
+	}
+}
+
+// mountEntry is a file or folder in the mount; it implements os.FileInfo
+type mountEntry struct {
+	name        string
+	id          string // content ID, without extension
+	contentType string
+	size        int64
+	modTime     time.Time
+	dir         bool
+}
+
+func (e *mountEntry) Name() string       { return e.name }
+func (e *mountEntry) Size() int64        { return e.size }
+func (e *mountEntry) ModTime() time.Time { return e.modTime }
+func (e *mountEntry) IsDir() bool        { return e.dir }
+func (e *mountEntry) Sys() interface{}   { return nil }
+
+func (e *mountEntry) Mode() os.FileMode {
+	if e.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+// relPath turns a WebDAV name into a path relative to the content type
+func relPath(name string) string {
+	return strings.Trim(path.Clean("/"+name), "/")
+}
+
+// escapePath escapes each segment of a relative path for use in a URL
+func escapePath(rel string) string {
+	segments := strings.Split(rel, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// do sends a request to the API and returns the response body; a 404 is
+// reported as os.ErrNotExist
+func (fs *mountFS) do(ctx context.Context, method, apiPath string, body []byte, header http.Header) ([]byte, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fs.client.baseURL+apiPath, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if fs.client.apiKey != "" {
+		req.Header.Set("X-API-Key", fs.client.apiKey)
+	}
+	if fs.client.tenant != "" {
+		req.Header.Set("X-Tenant", fs.client.tenant)
+	}
+
+	resp, err := fs.client.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, os.ErrNotExist
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// contentPath is the API path of a file in the mount's state
+func (fs *mountFS) contentPath(name string) string {
+	return "/api/content/" + url.PathEscape(fs.contentType) + "/" + escapePath(name) + "/" + fs.state
+}
+
+// list returns the entries of a directory, from cache when fresh
+func (fs *mountFS) list(ctx context.Context, dir string) ([]*mountEntry, error) {
+	fs.mu.Lock()
+	if listing, ok := fs.listings[dir]; ok && time.Since(listing.fetched) < mountListingTTL {
+		fs.mu.Unlock()
+		return listing.entries, nil
+	}
+	fs.mu.Unlock()
+
+	query := url.Values{"prefix": {dir}, "state": {fs.state}}
+	data, err := fs.do(ctx, "GET", "/api/content/"+url.PathEscape(fs.contentType)+"?"+query.Encode(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Folders []string `json:"folders"`
+		Items   []struct {
+			ID           string    `json:"id"`
+			ContentType  string    `json:"content_type"`
+			Size         int64     `json:"size"`
+			LastModified time.Time `json:"last_modified"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	var entries []*mountEntry
+	for _, folder := range result.Folders {
+		entries = append(entries, &mountEntry{name: folder, dir: true, modTime: time.Now()})
+	}
+	for _, item := range result.Items {
+		entries = append(entries, &mountEntry{
+			name:        path.Base(item.ID) + mountExtensions[item.ContentType],
+			id:          item.ID,
+			contentType: item.ContentType,
+			size:        item.Size,
+			modTime:     item.LastModified,
+		})
+	}
+
+	fs.mu.Lock()
+	fs.listings[dir] = &mountListing{entries: entries, fetched: time.Now()}
+	fs.mu.Unlock()
+	return entries, nil
+}
+
+// invalidate drops the cached listing of the directory containing rel
+func (fs *mountFS) invalidate(rel string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	delete(fs.listings, parentDir(rel))
+}
+
+// parentDir returns the directory of a relative path ("" for the root)
+func parentDir(rel string) string {
+	dir := path.Dir(rel)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// lookup finds the entry for a relative path
+func (fs *mountFS) lookup(ctx context.Context, rel string) (*mountEntry, error) {
+	if rel == "" {
+		return &mountEntry{name: "/", dir: true, modTime: time.Now()}, nil
+	}
+	entries, err := fs.list(ctx, parentDir(rel))
+	if err != nil {
+		return nil, err
+	}
+	base := path.Base(rel)
+	for _, entry := range entries {
+		if entry.name == base {
+			return entry, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+// read fetches a file's content. Files are read by name, then by ID for items
+// whose extension isn't the one the mount shows (such as .jpeg).
+func (fs *mountFS) read(ctx context.Context, rel string, entry *mountEntry) ([]byte, error) {
+	data, err := fs.do(ctx, "GET", fs.contentPath(rel), nil, nil)
+	if errors.Is(err, os.ErrNotExist) && entry != nil && entry.id != "" {
+		header := http.Header{"Accept": {entry.contentType}}
+		data, err = fs.do(ctx, "GET", fs.contentPath(entry.id), nil, header)
+	}
+	return data, err
+}
+
+// metadata fetches a file's metadata
+func (fs *mountFS) metadata(ctx context.Context, rel string) (map[string]string, error) {
+	data, err := fs.do(ctx, "GET", fs.contentPath(rel)+"?attribute=metadata", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result.Metadata, nil
+}
+
+// write uploads a file. A PUT replaces metadata, so nil metadata means the
+// file's current metadata, or that of a file recently removed at the same path.
+func (fs *mountFS) write(ctx context.Context, rel string, data []byte, metadata map[string]string) error {
+	if metadata == nil {
+		fs.mu.Lock()
+		if removed, ok := fs.removed[rel]; ok && time.Since(removed.at) < mountRemovedTTL {
+			metadata = removed.metadata
+		}
+		delete(fs.removed, rel)
+		fs.mu.Unlock()
+	}
+	if metadata == nil {
+		metadata, _ = fs.metadata(ctx, rel)
+	}
+
+	mimeType := mime.TypeByExtension(path.Ext(rel))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	header := http.Header{"Content-Type": {mimeType}}
+	for key, value := range metadata {
+		header.Set("X-Meta-"+key, value)
+	}
+
+	_, err := fs.do(ctx, "PUT", fs.contentPath(rel), data, header)
+	fs.invalidate(rel)
+	return err
+}
+
+// remove deletes a file, keeping its metadata for mountRemovedTTL
+func (fs *mountFS) remove(ctx context.Context, rel string) error {
+	metadata, _ := fs.metadata(ctx, rel)
+
+	_, err := fs.do(ctx, "DELETE", fs.contentPath(rel), nil, nil)
+	fs.invalidate(rel)
+	if err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	for name, removed := range fs.removed {
+		if time.Since(removed.at) >= mountRemovedTTL {
+			delete(fs.removed, name)
+		}
+	}
+	fs.removed[rel] = mountRemoved{metadata: metadata, at: time.Now()}
+	fs.mu.Unlock()
+	return nil
+}
+
+// walk returns the relative paths of all files under a directory
+func (fs *mountFS) walk(ctx context.Context, dir string) ([]string, error) {
+	entries, err := fs.list(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		rel := path.Join(dir, entry.name)
+		if !entry.dir {
+			files = append(files, rel)
+			continue
+		}
+		nested, err := fs.walk(ctx, rel)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, nested...)
+	}
+	return files, nil
+}
+
+// ensureScratchDirs creates the parents of a scratch file in memory
+func (fs *mountFS) ensureScratchDirs(ctx context.Context, rel string) {
+	dir := ""
+	for _, segment := range strings.Split(parentDir(rel), "/") {
+		if segment == "" {
+			continue
+		}
+		dir += "/" + segment
+		fs.scratch.Mkdir(ctx, dir, 0755)
+	}
+}
+
+// Mkdir creates a folder
+func (fs *mountFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	rel := relPath(name)
+	if isScratchName(rel) {
+		fs.ensureScratchDirs(ctx, rel)
+		return fs.scratch.Mkdir(ctx, "/"+rel, perm)
+	}
+	if rel == "" {
+		return os.ErrExist
+	}
+	if _, err := fs.lookup(ctx, rel); err == nil {
+		return os.ErrExist
+	}
+	if parent := parentDir(rel); parent != "" {
+		if entry, err := fs.lookup(ctx, parent); err != nil || !entry.dir {
+			return os.ErrNotExist
+		}
+	}
+
+	body, _ := json.Marshal(map[string]string{"name": path.Base(rel)})
+	query := url.Values{"prefix": {parentDir(rel)}}
+	_, err := fs.do(ctx, "POST", "/api/content/"+url.PathEscape(fs.contentType)+"/_mkdir?"+query.Encode(), body, nil)
+	fs.invalidate(rel)
+	return err
+}
+
+// OpenFile opens a file or folder. Writable files are buffered in memory and
+// uploaded when closed.
+func (fs *mountFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	rel := relPath(name)
+	if isScratchName(rel) {
+		fs.ensureScratchDirs(ctx, rel)
+		return fs.scratch.OpenFile(ctx, "/"+rel, flag, perm)
+	}
+
+	entry, err := fs.lookup(ctx, rel)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if entry != nil && entry.dir {
+		if flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+			return nil, os.ErrPermission
+		}
+		return &mountFile{fs: fs, rel: rel, info: entry, dir: true}, nil
+	}
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if entry == nil {
+		if !writable || flag&os.O_CREATE == 0 {
+			return nil, os.ErrNotExist
+		}
+		if parent := parentDir(rel); parent != "" {
+			if parentEntry, err := fs.lookup(ctx, parent); err != nil || !parentEntry.dir {
+				return nil, os.ErrNotExist
+			}
+		}
+		info := &mountEntry{name: path.Base(rel), modTime: time.Now()}
+		return &mountFile{fs: fs, rel: rel, info: info, writable: true, dirty: true}, nil
+	}
+	if writable && flag&os.O_EXCL != 0 {
+		return nil, os.ErrExist
+	}
+
+	file := &mountFile{fs: fs, rel: rel, info: entry, writable: writable}
+	if writable && flag&os.O_TRUNC != 0 {
+		file.dirty = true
+		return file, nil
+	}
+	file.data, err = fs.read(ctx, rel, entry)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// RemoveAll deletes a file, or every file in a folder (the folder itself
+// remains, as the API has no way to remove it)
+func (fs *mountFS) RemoveAll(ctx context.Context, name string) error {
+	rel := relPath(name)
+	if isScratchName(rel) {
+		return fs.scratch.RemoveAll(ctx, "/"+rel)
+	}
+	if rel == "" {
+		return os.ErrPermission
+	}
+
+	entry, err := fs.lookup(ctx, rel)
+	if err != nil {
+		return err
+	}
+	if !entry.dir {
+		return fs.remove(ctx, rel)
+	}
+
+	files, err := fs.walk(ctx, rel)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := fs.remove(ctx, file); err != nil {
+			return err
+		}
+	}
+	fs.scratch.RemoveAll(ctx, "/"+rel)
+	return nil
+}
+
+// Rename moves a file or folder by copying it (with its metadata) and
+// removing the original
+func (fs *mountFS) Rename(ctx context.Context, oldName, newName string) error {
+	oldRel, newRel := relPath(oldName), relPath(newName)
+	oldScratch, newScratch := isScratchName(oldRel), isScratchName(newRel)
+	if oldScratch && newScratch {
+		fs.ensureScratchDirs(ctx, newRel)
+		return fs.scratch.Rename(ctx, "/"+oldRel, "/"+newRel)
+	}
+	if oldRel == "" || newRel == "" {
+		return os.ErrPermission
+	}
+
+	// Scratch file saved over a real one (editors writing a temp file first)
+	if oldScratch {
+		data, err := readScratch(ctx, fs.scratch, "/"+oldRel)
+		if err != nil {
+			return err
+		}
+		if err := fs.write(ctx, newRel, data, nil); err != nil {
+			return err
+		}
+		return fs.scratch.RemoveAll(ctx, "/"+oldRel)
+	}
+
+	entry, err := fs.lookup(ctx, oldRel)
+	if err != nil {
+		return err
+	}
+
+	// Real file moved aside (editors keeping a backup): the original is
+	// removed, and its metadata restored when the new version is written
+	if newScratch {
+		if entry.dir {
+			return os.ErrPermission
+		}
+		data, err := fs.read(ctx, oldRel, entry)
+		if err != nil {
+			return err
+		}
+		fs.ensureScratchDirs(ctx, newRel)
+		if err := writeScratch(ctx, fs.scratch, "/"+newRel, data); err != nil {
+			return err
+		}
+		return fs.remove(ctx, oldRel)
+	}
+
+	if !entry.dir {
+		return fs.move(ctx, oldRel, newRel, entry)
+	}
+
+	if err := fs.Mkdir(ctx, newRel, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	files, err := fs.walk(ctx, oldRel)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		target := path.Join(newRel, strings.TrimPrefix(file, oldRel+"/"))
+		if parent := parentDir(target); parent != newRel {
+			if err := fs.Mkdir(ctx, parent, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+				return err
+			}
+		}
+		fileEntry, err := fs.lookup(ctx, file)
+		if err != nil {
+			return err
+		}
+		if err := fs.move(ctx, file, target, fileEntry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// move copies a file with its metadata and removes the original
+func (fs *mountFS) move(ctx context.Context, oldRel, newRel string, entry *mountEntry) error {
+	data, err := fs.read(ctx, oldRel, entry)
+	if err != nil {
+		return err
+	}
+	metadata, err := fs.metadata(ctx, oldRel)
+	if err != nil {
+		return err
+	}
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	if err := fs.write(ctx, newRel, data, metadata); err != nil {
+		return err
+	}
+	return fs.remove(ctx, oldRel)
+}
+
+// Stat describes a file or folder
+func (fs *mountFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	rel := relPath(name)
+	if isScratchName(rel) {
+		return fs.scratch.Stat(ctx, "/"+rel)
+	}
+	return fs.lookup(ctx, rel)
+}
+
+// readScratch reads a file from the scratch file system
+func readScratch(ctx context.Context, scratch webdav.FileSystem, name string) ([]byte, error) {
+	f, err := scratch.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// writeScratch writes a file to the scratch file system
+func writeScratch(ctx context.Context, scratch webdav.FileSystem, name string, data []byte) error {
+	f, err := scratch.OpenFile(ctx, name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// mountFile is an open file or folder in the mount
+type mountFile struct {
+	fs       *mountFS
+	rel      string
+	info     *mountEntry
+	dir      bool
+	data     []byte
+	offset   int64
+	writable bool
+	dirty    bool
+	listed   []os.FileInfo
+	listPos  int
+}
+
+func (f *mountFile) Read(p []byte) (int, error) {
+	if f.dir {
+		return 0, os.ErrInvalid
+	}
+	if f.offset >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+func (f *mountFile) Write(p []byte) (int, error) {
+	if !f.writable {
+		return 0, os.ErrPermission
+	}
+	end := f.offset + int64(len(p))
+	if end > int64(len(f.data)) {
+		grown := make([]byte, end)
+		copy(grown, f.data)
+		f.data = grown
+	}
+	copy(f.data[f.offset:], p)
+	f.offset = end
+	f.dirty = true
+	return len(p), nil
+}
+
+func (f *mountFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.data))
+	default:
+		return 0, os.ErrInvalid
+	}
+	if offset < 0 {
+		return 0, os.ErrInvalid
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Readdir lists a folder, including any scratch files in it
+func (f *mountFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !f.dir {
+		return nil, os.ErrInvalid
+	}
+	if f.listed == nil {
+		ctx := context.Background()
+		entries, err := f.fs.list(ctx, f.rel)
+		if err != nil {
+			return nil, err
+		}
+		f.listed = []os.FileInfo{}
+		for _, entry := range entries {
+			f.listed = append(f.listed, entry)
+		}
+		if dir, err := f.fs.scratch.OpenFile(ctx, "/"+f.rel, os.O_RDONLY, 0); err == nil {
+			scratch, _ := dir.Readdir(0)
+			dir.Close()
+			for _, info := range scratch {
+				if isScratchName(info.Name()) {
+					f.listed = append(f.listed, info)
+				}
+			}
+		}
+	}
+
+	remaining := f.listed[f.listPos:]
+	if count <= 0 {
+		f.listPos = len(f.listed)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+	f.listPos += count
+	return remaining[:count], nil
+}
+
+func (f *mountFile) Stat() (os.FileInfo, error) {
+	if f.dirty {
+		return &mountEntry{name: f.info.name, size: int64(len(f.data)), modTime: time.Now()}, nil
+	}
+	return f.info, nil
+}
+
+// Close uploads the file if it was written
+func (f *mountFile) Close() error {
+	if !f.dirty {
+		return nil
+	}
+	f.dirty = false
+	return f.fs.write(context.Background(), f.rel, f.data, nil)
+}
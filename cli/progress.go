@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"velocity/internal/ui"
+)
+
+// progressWidth is the width of the bar, in cells
+const progressWidth = 30
+
+var progressBarStyle = lipgloss.NewStyle().Foreground(ui.Primary)
+
+// progress shows how far a set of uploads has got: a bar with the bytes sent,
+// throughput, and time left, redrawn on stderr while it's a terminal. Uploads
+// from several goroutines can share one.
+type progress struct {
+	total int64 // bytes to send
+	files int   // files to send; 0 for a single upload
+	sent  atomic.Int64
+	done  atomic.Int64
+	start time.Time
+
+	tty  bool
+	mu   sync.Mutex // serializes drawing
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newProgress starts showing progress toward total bytes across files
+func newProgress(total int64, files int) *progress {
+	p := &progress{total: total, files: files, start: time.Now(), stop: make(chan struct{})}
+	if stat, err := os.Stderr.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		p.tty = true
+		p.wg.Add(1)
+		go p.run()
+	}
+	return p
+}
+
+func (p *progress) run() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.draw()
+			p.mu.Unlock()
+		}
+	}
+}
+
+// draw rewrites the progress line; the caller holds mu
+func (p *progress) draw() {
+	sent := p.sent.Load()
+	elapsed := time.Since(p.start)
+	rate := float64(sent) / elapsed.Seconds()
+
+	fraction := 1.0
+	if p.total > 0 {
+		fraction = float64(sent) / float64(p.total)
+		if fraction > 1 {
+			fraction = 1
+		}
+	}
+	filled := int(fraction * progressWidth)
+	bar := progressBarStyle.Render(strings.Repeat("█", filled)) + ui.Muted(strings.Repeat("░", progressWidth-filled))
+
+	eta := "--"
+	if rate > 0 && sent < p.total {
+		eta = formatETA(time.Duration(float64(p.total-sent) / rate * float64(time.Second)))
+	}
+
+	line := fmt.Sprintf("  %s %3.0f%%  %s / %s  %s/s  ETA %s", bar, fraction*100, formatBytes(sent), formatBytes(p.total), formatBytes(int64(rate)), eta)
+	if p.files > 0 {
+		line += ui.Muted(fmt.Sprintf("  %d/%d files", p.done.Load(), p.files))
+	}
+	fmt.Fprint(os.Stderr, "\r\033[K"+line)
+}
+
+// clear removes the progress line; the caller holds mu
+func (p *progress) clear() {
+	fmt.Fprint(os.Stderr, "\r\033[K")
+}
+
+// print runs fn, which writes a line of output, above the progress line
+func (p *progress) print(fn func()) {
+	if p == nil || !p.tty {
+		fn()
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	fn()
+	p.draw()
+}
+
+// fileDone counts a finished file
+func (p *progress) fileDone() {
+	if p != nil {
+		p.done.Add(1)
+	}
+}
+
+// finish stops redrawing and removes the progress line
+func (p *progress) finish() {
+	if p == nil || !p.tty {
+		return
+	}
+	close(p.stop)
+	p.wg.Wait()
+	p.clear()
+}
+
+// reader counts what's read from r toward the progress. A nil progress
+// counts nothing.
+func (p *progress) reader(r io.ReadCloser) *progressReader {
+	return &progressReader{ReadCloser: r, progress: p}
+}
+
+// progressReader is an upload body that reports the bytes sent
+type progressReader struct {
+	io.ReadCloser
+	progress *progress
+	n        atomic.Int64
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	if n > 0 && r.progress != nil {
+		r.n.Add(int64(n))
+		r.progress.sent.Add(int64(n))
+	}
+	return n, err
+}
+
+// rewind takes back what was counted, for a body that is being sent again
+func (r *progressReader) rewind() {
+	if r.progress != nil {
+		r.progress.sent.Add(-r.n.Swap(0))
+	}
+}
+
+// formatBytes renders a byte count for people: 512 B, 12.3 MB
+func formatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
+
+// formatETA renders a remaining time as 45s, 3m12s, or 1h04m
+func formatETA(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
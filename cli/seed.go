@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"velocity/internal/models"
+	"velocity/internal/ui"
+)
+
+var (
+	seedType        string
+	seedCount       int
+	seedSchema      string
+	seedImages      bool
+	seedImageType   string
+	seedState       string
+	seedConcurrency int
+	seedRandom      int64
+)
+
+// defaultSeedSchema is used when no schema is given and the server has none
+// for the type
+var defaultSeedSchema = models.Schema{
+	Fields: map[string]models.FieldDef{
+		"title":        {Type: "string", Required: true},
+		"slug":         {Type: "string"},
+		"author":       {Type: "string"},
+		"summary":      {Type: "string"},
+		"body":         {Type: "string"},
+		"tags":         {Type: "array", Items: "string"},
+		"published_at": {Type: "string"},
+		"featured":     {Type: "boolean"},
+		"image":        {Type: "image"},
+	},
+}
+
+func runSeed(cmd *cobra.Command, args []string) {
+	if seedType == "" {
+		ui.PrintError("--type is required")
+		os.Exit(1)
+	}
+	if seedConcurrency < 1 {
+		seedConcurrency = 1
+	}
+
+	client := newClient()
+
+	schema, source, err := loadSeedSchema(client)
+	if err != nil {
+		ui.PrintError("%v", err)
+		os.Exit(1)
+	}
+	ui.PrintInfo("Generating %d %s items from %s", seedCount, seedType, source)
+
+	if seedRandom == 0 {
+		seedRandom = time.Now().UnixNano()
+	}
+
+	var created, failed, images atomic.Int64
+	var mu sync.Mutex
+	var firstErr error
+	recordErr := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < seedConcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				// Each item has its own generator, so a given --seed always
+				// produces the same content regardless of concurrency
+				f := newFaker(seedRandom + int64(i))
+				doc := f.document(schema.Fields, func(field string) interface{} {
+					if !seedImages {
+						return f.imageURL(field)
+					}
+					ref, err := client.uploadSeedImage(f, i, field)
+					if err != nil {
+						recordErr(err)
+						return f.imageURL(field)
+					}
+					images.Add(1)
+					return ref
+				})
+
+				id := fmt.Sprintf("seed-%05d", i+1)
+				path := fmt.Sprintf("/api/content/%s/%s/%s", seedType, id, seedState)
+				if _, err := client.request("PUT", path, doc); err != nil {
+					failed.Add(1)
+					recordErr(err)
+					continue
+				}
+				if n := created.Add(1); n%100 == 0 {
+					ui.PrintInfo("%d/%d created", n, seedCount)
+				}
+			}
+		}()
+	}
+	for i := 0; i < seedCount; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if outputFmt == "json" {
+		result := map[string]interface{}{
+			"type":    seedType,
+			"created": created.Load(),
+			"failed":  failed.Load(),
+			"images":  images.Load(),
+			"seed":    seedRandom,
+		}
+		if firstErr != nil {
+			result["error"] = firstErr.Error()
+		}
+		printJSON(result)
+		return
+	}
+
+	if firstErr != nil {
+		ui.PrintWarning("First error: %v", firstErr)
+	}
+	if failed.Load() > 0 {
+		ui.PrintWarning("Created %d of %d %s items (%d failed)", created.Load(), seedCount, seedType, failed.Load())
+		os.Exit(1)
+	}
+	ui.PrintSuccess("Created %d %s items and %d images (seed %d)", created.Load(), seedType, images.Load(), seedRandom)
+}
+
+// loadSeedSchema reads --schema, or the type's schema from the server (tenant
+// then global), falling back to a generic article
+func loadSeedSchema(c *client) (*models.Schema, string, error) {
+	if seedSchema != "" {
+		data, err := os.ReadFile(seedSchema)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read schema: %w", err)
+		}
+		var schema models.Schema
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return nil, "", fmt.Errorf("invalid schema %s: %w", seedSchema, err)
+		}
+		return &schema, seedSchema, nil
+	}
+
+	for _, path := range []string{"/api/tenant/schemas/" + seedType, "/api/schemas/" + seedType} {
+		data, err := c.request("GET", path, nil)
+		if err != nil {
+			continue
+		}
+		var schema models.Schema
+		if json.Unmarshal(data, &schema) == nil && len(schema.Fields) > 0 {
+			return &schema, "the server schema", nil
+		}
+	}
+	return &defaultSeedSchema, "the default article schema", nil
+}
+
+// uploadSeedImage uploads a generated PNG and returns a reference to it
+func (c *client) uploadSeedImage(f *faker, i int, field string) (map[string]interface{}, error) {
+	id := fmt.Sprintf("seed-%s-%05d-%s.png", seedType, i+1, strings.ReplaceAll(field, ".", "-"))
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, f.image(640, 360)); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	alt := f.sentence(6)
+	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/api/content/%s/%s", c.baseURL, seedImageType, id), &buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "image/png")
+	req.Header.Set("X-Meta-Alt_text", alt)
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.tenant != "" {
+		req.Header.Set("X-Tenant", c.tenant)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(data))
+	}
+
+	return map[string]interface{}{
+		"url": fmt.Sprintf("/content/%s/%s/%s", c.tenant, seedImageType, id),
+		"alt": alt,
+	}, nil
+}
+
+// =============================================================================
+// Fake Data
+// =============================================================================
+
+var (
+	fakeFirstNames = []string{"Ada", "Alan", "Grace", "Linus", "Margaret", "Dennis", "Barbara", "Ken", "Radia", "Tim", "Frances", "John", "Katherine", "Edsger", "Hedy", "Guido", "Sophie", "Yukihiro", "Anita", "Bjarne"}
+	fakeLastNames  = []string{"Lovelace", "Turing", "Hopper", "Torvalds", "Hamilton", "Ritchie", "Liskov", "Thompson", "Perlman", "Berners-Lee", "Allen", "McCarthy", "Johnson", "Dijkstra", "Lamarr", "van Rossum", "Wilson", "Matsumoto", "Borg", "Stroustrup"}
+	fakeWords      = []string{"cloud", "design", "content", "garden", "coffee", "travel", "city", "music", "ocean", "market", "studio", "river", "kitchen", "summer", "project", "future", "library", "mountain", "journey", "pattern", "signal", "harbor", "color", "story", "craft", "light", "network", "season", "team", "launch", "guide", "winter", "review", "recipe", "update", "field", "native", "simple", "quiet", "bright"}
+	fakeVerbs      = []string{"builds", "explores", "shapes", "changes", "finds", "shares", "improves", "connects", "reveals", "celebrates"}
+	fakeDomains    = []string{"example.com", "example.org", "example.net"}
+	fakeCities     = []string{"Lisbon", "Toronto", "Nairobi", "Osaka", "Melbourne", "Bogotá", "Oslo", "Austin", "Seoul", "Edinburgh"}
+)
+
+// faker generates realistic-looking values from field names and types
+type faker struct {
+	rng *rand.Rand
+}
+
+func newFaker(seed int64) *faker {
+	return &faker{rng: rand.New(rand.NewSource(seed))}
+}
+
+func (f *faker) pick(values []string) string {
+	return values[f.rng.Intn(len(values))]
+}
+
+func (f *faker) name() string {
+	return f.pick(fakeFirstNames) + " " + f.pick(fakeLastNames)
+}
+
+func (f *faker) title() string {
+	words := []string{f.pick(fakeWords), f.pick(fakeVerbs), "the", f.pick(fakeWords), f.pick(fakeWords)}
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+	return strings.Join(words, " ")
+}
+
+func (f *faker) sentence(n int) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = f.pick(fakeWords)
+	}
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+	return strings.Join(words, " ") + "."
+}
+
+func (f *faker) paragraphs(n int) string {
+	paragraphs := make([]string, n)
+	for i := range paragraphs {
+		sentences := make([]string, 3+f.rng.Intn(4))
+		for j := range sentences {
+			sentences[j] = f.sentence(6 + f.rng.Intn(10))
+		}
+		paragraphs[i] = strings.Join(sentences, " ")
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func (f *faker) slug(s string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return '-'
+	}, s), "-")
+}
+
+func (f *faker) date() string {
+	return time.Now().Add(-time.Duration(f.rng.Intn(365*24)) * time.Hour).UTC().Format(time.RFC3339)
+}
+
+func (f *faker) imageURL(field string) string {
+	return fmt.Sprintf("https://%s/images/%s-%d.png", f.pick(fakeDomains), f.slug(field), f.rng.Intn(10000))
+}
+
+// image draws a gradient between two random colors
+func (f *faker) image(width, height int) image.Image {
+	from := color.RGBA{uint8(f.rng.Intn(256)), uint8(f.rng.Intn(256)), uint8(f.rng.Intn(256)), 255}
+	to := color.RGBA{uint8(f.rng.Intn(256)), uint8(f.rng.Intn(256)), uint8(f.rng.Intn(256)), 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		t := float64(x) / float64(width-1)
+		c := color.RGBA{
+			uint8(float64(from.R)*(1-t) + float64(to.R)*t),
+			uint8(float64(from.G)*(1-t) + float64(to.G)*t),
+			uint8(float64(from.B)*(1-t) + float64(to.B)*t),
+			255,
+		}
+		for y := 0; y < height; y++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// document generates a document for schema fields. image is called for image
+// and file fields.
+func (f *faker) document(fields map[string]models.FieldDef, image func(field string) interface{}) map[string]interface{} {
+	// Generate in a fixed order so a seed is reproducible
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	doc := make(map[string]interface{})
+	for _, name := range names {
+		def := fields[name]
+		// Leave out some optional fields, as real content would
+		if !def.Required && f.rng.Intn(10) == 0 {
+			continue
+		}
+		doc[name] = f.value(name, def, image)
+	}
+
+	// Keep slugs consistent with titles
+	if title, ok := doc["title"].(string); ok {
+		if _, ok := doc["slug"].(string); ok {
+			doc["slug"] = f.slug(title)
+		}
+	}
+	return doc
+}
+
+// value generates a value for one field, guided by its name
+func (f *faker) value(name string, def models.FieldDef, image func(field string) interface{}) interface{} {
+	if values, ok := def.Options["enum"].([]interface{}); ok && len(values) > 0 {
+		return values[f.rng.Intn(len(values))]
+	}
+
+	lower := strings.ToLower(name)
+	switch def.Type {
+	case "number", "integer":
+		min, max := 0.0, 1000.0
+		if v, ok := def.Options["min"].(float64); ok {
+			min = v
+		}
+		if v, ok := def.Options["max"].(float64); ok {
+			max = v
+		}
+		n := min + f.rng.Float64()*(max-min)
+		if def.Type == "integer" || !strings.Contains(lower, "price") {
+			return float64(int64(n))
+		}
+		return float64(int64(n*100)) / 100
+	case "boolean":
+		return f.rng.Intn(2) == 0
+	case "image", "file":
+		return image(name)
+	case "object":
+		return f.document(def.Properties, image)
+	case "array":
+		items := make([]interface{}, 1+f.rng.Intn(4))
+		for i := range items {
+			switch def.Items {
+			case "number", "integer":
+				items[i] = float64(f.rng.Intn(100))
+			case "boolean":
+				items[i] = f.rng.Intn(2) == 0
+			case "image", "file":
+				items[i] = image(fmt.Sprintf("%s.%d", name, i))
+			default:
+				items[i] = f.pick(fakeWords)
+			}
+		}
+		return items
+	}
+
+	switch {
+	case strings.Contains(lower, "title") || strings.Contains(lower, "headline"):
+		return f.title()
+	case strings.Contains(lower, "slug"):
+		return f.slug(f.title())
+	case strings.Contains(lower, "email"):
+		return strings.ToLower(strings.ReplaceAll(f.name(), " ", ".")) + "@" + f.pick(fakeDomains)
+	case strings.Contains(lower, "author") || strings.Contains(lower, "name"):
+		return f.name()
+	case strings.Contains(lower, "url") || strings.Contains(lower, "link") || strings.Contains(lower, "website"):
+		return fmt.Sprintf("https://%s/%s", f.pick(fakeDomains), f.slug(f.title()))
+	case strings.Contains(lower, "date") || strings.HasSuffix(lower, "_at"):
+		return f.date()
+	case strings.Contains(lower, "city") || strings.Contains(lower, "location"):
+		return f.pick(fakeCities)
+	case strings.Contains(lower, "phone"):
+		return fmt.Sprintf("+1-555-%03d-%04d", f.rng.Intn(1000), f.rng.Intn(10000))
+	case strings.Contains(lower, "color") || strings.Contains(lower, "colour"):
+		return fmt.Sprintf("#%06x", f.rng.Intn(1<<24))
+	case strings.Contains(lower, "body") || strings.Contains(lower, "content") || strings.Contains(lower, "text"):
+		return f.paragraphs(2 + f.rng.Intn(4))
+	case strings.Contains(lower, "summary") || strings.Contains(lower, "description") || strings.Contains(lower, "excerpt"):
+		return f.sentence(12 + f.rng.Intn(12))
+	}
+	return f.sentence(3 + f.rng.Intn(5))
+}
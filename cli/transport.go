@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"velocity/internal/ui"
+	"velocity/internal/version"
+)
+
+var (
+	timeoutFlag time.Duration
+	retriesFlag int
+	verboseFlag bool
+)
+
+// retryBackoff is the wait before the first retry; each later retry doubles it
+const retryBackoff = 500 * time.Millisecond
+
+// redactedHeaders hold credentials, so --verbose never prints their values
+var redactedHeaders = map[string]bool{
+	"X-Api-Key":     true,
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// cliTransport adds the global --timeout, --retries, and --verbose behaviour
+// to every request the CLI makes, identifies the CLI's version, and warns
+// about servers it may not work with
+type cliTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration // per attempt, including reading the body; 0 for none
+	retries int
+	verbose bool
+
+	compatOnce sync.Once
+	deprecated sync.Map // routes already warned about
+}
+
+func newTransport() *cliTransport {
+	return &cliTransport{
+		base:    http.DefaultTransport,
+		timeout: timeoutFlag,
+		retries: retriesFlag,
+		verbose: verboseFlag,
+	}
+}
+
+// cancelBody releases an attempt's timeout once its response is read
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// attempt sends a request once, under its own timeout
+func (t *cliTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", t.timeout)
+		}
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// RoundTrip sends a request, retrying connection failures and 429/502/503/504
+// responses. Only requests that can be sent again safely are retried: bodies
+// must be replayable, and POSTs get an Idempotency-Key so the server applies
+// them once.
+func (t *cliTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", "velocity-cli/"+version.GetVersion())
+	req.Header.Set("X-Client-Version", version.GetVersion())
+
+	retries := t.retries
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		retries = 0
+	}
+	if retries > 0 && req.Method == http.MethodPost && req.Header.Get("Idempotency-Key") == "" {
+		req.Header.Set("Idempotency-Key", uuid.New().String())
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		t.dumpRequest(req, attempt)
+		start := time.Now()
+		resp, err := t.attempt(req)
+		t.dumpResponse(resp, err, time.Since(start))
+
+		if attempt >= retries || !retryable(resp, err) {
+			if resp != nil {
+				t.checkCompatibility(resp)
+			}
+			return resp, err
+		}
+
+		wait := retryBackoff << attempt
+		if resp != nil {
+			if after := retryAfter(resp); after > 0 {
+				wait = after
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if t.verbose {
+			fmt.Fprintln(os.Stderr, ui.Muted(fmt.Sprintf("* Retrying in %s (%d of %d)", wait, attempt+1, retries)))
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// checkCompatibility warns, once per command, when the server doesn't support
+// this CLI's version, and once per route when a route is deprecated
+func (t *cliTransport) checkCompatibility(resp *http.Response) {
+	min, max := resp.Header.Get("X-Min-Client-Version"), resp.Header.Get("X-Max-Client-Version")
+	if min != "" && max != "" {
+		t.compatOnce.Do(func() {
+			cli, server := version.GetVersion(), resp.Header.Get("X-Server-Version")
+			switch {
+			case version.Compare(cli, min) < 0:
+				printWarning("This CLI (%s) is older than server %s supports (%s or newer); upgrade the CLI", cli, server, min)
+			case !version.Within(cli, min, max):
+				printWarning("This CLI (%s) is newer than server %s; commands using newer features may fail", cli, server)
+			}
+		})
+	}
+
+	if notice := resp.Header.Get("X-Deprecated"); notice != "" {
+		if _, warned := t.deprecated.LoadOrStore(notice, true); !warned {
+			printWarning("%s", notice)
+		}
+	}
+}
+
+// printWarning prints a warning to stderr, leaving stdout to the output
+func printWarning(format string, a ...interface{}) {
+	fmt.Fprintln(os.Stderr, ui.WarningStyle.Render("⚠ "+fmt.Sprintf(format, a...)))
+}
+
+// retryable reports whether a failed attempt is worth repeating: the server
+// couldn't be reached, or answered that it is busy or briefly unavailable
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the wait a Retry-After header asks for, in seconds or as
+// an HTTP date, or zero when there is none
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// dumpRequest prints a request line and headers to stderr with --verbose
+func (t *cliTransport) dumpRequest(req *http.Request, attempt int) {
+	if !t.verbose {
+		return
+	}
+	if attempt > 0 {
+		fmt.Fprintln(os.Stderr, ui.Muted(fmt.Sprintf("* Attempt %d", attempt+1)))
+	}
+	fmt.Fprintf(os.Stderr, "> %s %s\n", req.Method, req.URL.String())
+	dumpHeaders(">", req.Header)
+}
+
+// dumpResponse prints a response status and headers to stderr with --verbose
+func (t *cliTransport) dumpResponse(resp *http.Response, err error, elapsed time.Duration) {
+	if !t.verbose {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, ui.Muted(fmt.Sprintf("* Failed after %s: %v", elapsed.Round(time.Millisecond), err)))
+		return
+	}
+	fmt.Fprintf(os.Stderr, "< %s %s %s\n", resp.Proto, resp.Status, ui.Muted("("+elapsed.Round(time.Millisecond).String()+")"))
+	dumpHeaders("<", resp.Header)
+	fmt.Fprintln(os.Stderr)
+}
+
+// dumpHeaders prints headers in name order, hiding credentials
+func dumpHeaders(prefix string, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			value = "[redacted]"
+		}
+		fmt.Fprintf(os.Stderr, "%s %s: %s\n", prefix, name, value)
+	}
+}
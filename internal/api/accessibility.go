@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"velocity/internal/storage"
+)
+
+// Image metadata keys checked for accessibility
+const (
+	altTextKey = "alt_text"
+	captionKey = "caption"
+)
+
+// isImageContent checks if a MIME type is an image
+func isImageContent(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/")
+}
+
+// requiredImageMetadata returns the metadata keys images of a content type must carry
+// before going live. alt_text is required by the tenant setting require_alt_text or the
+// schema setting of the same name; caption by the schema setting require_caption.
+func (s *Server) requiredImageMetadata(ctx context.Context, tenant, contentType string) []string {
+	var keys []string
+	var settings map[string]interface{}
+	if schema := s.loadSchema(ctx, tenant, contentType); schema != nil {
+		settings = schema.Settings
+	}
+
+	if s.settings.get(ctx, tenant).RequireAltText || schemaFlag(settings, "require_alt_text") {
+		keys = append(keys, altTextKey)
+	}
+	if schemaFlag(settings, "require_caption") {
+		keys = append(keys, captionKey)
+	}
+	return keys
+}
+
+// schemaFlag reads a boolean from schema settings
+func schemaFlag(settings map[string]interface{}, name string) bool {
+	value, _ := settings[name].(bool)
+	return value
+}
+
+// missingMetadata returns the required keys that are absent or blank
+func missingMetadata(metadata map[string]string, required []string) []string {
+	var missing []string
+	for _, key := range required {
+		if strings.TrimSpace(metadata[key]) == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// checkImageMetadata returns an error message if image content in the given state
+// lacks metadata required to go live
+func (s *Server) checkImageMetadata(ctx context.Context, tenant, contentType, id string, state storage.State) string {
+	required := s.requiredImageMetadata(ctx, tenant, contentType)
+	if len(required) == 0 {
+		return ""
+	}
+
+	stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, "", state)
+	if err != nil {
+		return ""
+	}
+	stream.Body.Close()
+
+	if !isImageContent(stream.ContentType) {
+		return ""
+	}
+	if missing := missingMetadata(stream.Metadata, required); len(missing) > 0 {
+		return fmt.Sprintf("image is missing required metadata: %s", strings.Join(missing, ", "))
+	}
+	return ""
+}
+
+// =============================================================================
+// Accessibility Report Handlers
+// =============================================================================
+
+// altTextIssue describes an image lacking accessibility metadata
+type altTextIssue struct {
+	Type     string   `json:"type"`
+	ID       string   `json:"id"`
+	State    string   `json:"state"`
+	Key      string   `json:"key"`
+	Missing  []string `json:"missing"`
+	Required bool     `json:"required"` // true if policy blocks this image from going live
+}
+
+// altTextReportHandler handles GET /api/reports/alt-text
+// Lists images missing alt_text (and caption where required). Supports ?type= and ?state= (default live).
+func (s *Server) altTextReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenant := s.getTenant(r)
+
+	state := storage.StateLive
+	if v := r.URL.Query().Get("state"); v != "" {
+		if !storage.ValidState(v) {
+			writeError(w, http.StatusBadRequest, "invalid_state", fmt.Sprintf("Invalid state: %s", v))
+			return
+		}
+		state = storage.State(v)
+	}
+
+	types := splitList(r.URL.Query().Get("type"))
+	if len(types) == 0 {
+		var err error
+		if types, err = s.storage.ListContentTypes(ctx, tenant); err != nil {
+			writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+			return
+		}
+	}
+
+	issues := []altTextIssue{}
+	scanned := 0
+	for _, contentType := range types {
+		required := s.requiredImageMetadata(ctx, tenant, contentType)
+		checked := required
+		if !containsString(checked, altTextKey) {
+			checked = append([]string{altTextKey}, checked...)
+		}
+
+		items, err := s.storage.List(ctx, tenant, contentType, state)
+		if err != nil {
+			continue
+		}
+		for _, item := range items {
+			if !isImageContent(mimeFromExt(strings.ToLower(filepath.Ext(item.Key)))) {
+				continue
+			}
+			scanned++
+
+			id, ext := extractIDAndExt(item.Key, contentType, state)
+			metadata, err := s.storage.GetMetadata(ctx, tenant, contentType, id, ext, state)
+			if err != nil {
+				continue
+			}
+			if missing := missingMetadata(metadata, checked); len(missing) > 0 {
+				issues = append(issues, altTextIssue{
+					Type:     contentType,
+					ID:       id,
+					State:    string(state),
+					Key:      item.Key,
+					Missing:  missing,
+					Required: len(missingMetadata(metadata, required)) > 0,
+				})
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"state":     state,
+		"images":    scanned,
+		"issues":    issues,
+		"count":     len(issues),
+		"compliant": scanned - len(issues),
+	})
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"velocity/internal/storage"
+)
+
+// errNotLiveAsOf is returned when content had no live version at a time
+var errNotLiveAsOf = errors.New("content was not live at that time")
+
+// parseAsOf reads the ?as-of= of a read: RFC 3339 or Unix seconds. ok is false
+// when the read has none.
+func parseAsOf(r *http.Request) (at time.Time, ok bool, err error) {
+	if !r.URL.Query().Has("as-of") {
+		return time.Time{}, false, nil
+	}
+	at, err = parseSince(r.URL.Query().Get("as-of"))
+	return at, true, err
+}
+
+// asOfVersion returns the version of an item that was live at a time, and the
+// extension it's stored under: the newest version written at or before it.
+// Content deleted at or before the time wasn't live, but only its latest
+// deletion is known, so a version that was deleted and later written again
+// reads as live in between.
+func (s *Server) asOfVersion(ctx context.Context, tenant, contentType, id string, at time.Time) (string, *storage.ContentVersion, error) {
+	ext := s.getExtensionFromSchema(ctx, contentType)
+	deleted := true
+	if stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, "", storage.StateLive); err == nil {
+		stream.Body.Close()
+		_, ext = extractIDAndExt(stream.Key, contentType, storage.StateLive)
+		deleted = false
+	}
+
+	versions, err := s.storage.ListVersions(ctx, tenant, contentType, id, ext)
+	if err != nil {
+		return "", nil, err
+	}
+	var found *storage.ContentVersion
+	for _, v := range versions {
+		if !v.LastModified.After(at) && (found == nil || v.LastModified.After(found.LastModified)) {
+			found = v
+		}
+	}
+	if found == nil {
+		return "", nil, errNotLiveAsOf
+	}
+
+	if deleted {
+		deletedAt, ok, err := s.storage.ContentDeletedAt(ctx, tenant, contentType, id)
+		if err != nil {
+			return "", nil, err
+		}
+		if ok && !deletedAt.IsZero() && !deletedAt.After(at) {
+			return "", nil, errNotLiveAsOf
+		}
+	}
+	return ext, found, nil
+}
+
+// asOfHandler answers a read of live content with ?as-of=: the version that was
+// live at that time, for viewing the site as it was. Versions pruned by
+// retention can't be read back.
+func (s *Server) asOfHandler(w http.ResponseWriter, r *http.Request, tenant, contentType, id string, state storage.State) {
+	if state != storage.StateLive {
+		writeError(w, http.StatusBadRequest, "invalid_state", "Reads with as-of resolve live content only")
+		return
+	}
+	at, _, err := parseAsOf(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_as_of", err.Error())
+		return
+	}
+
+	ext, version, err := s.asOfVersion(r.Context(), tenant, contentType, id, at)
+	if errors.Is(err, errNotLiveAsOf) {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Content '%s' was not live at %s", id, at.UTC().Format(time.RFC3339)))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	stream, err := s.storage.GetVersionStream(r.Context(), tenant, contentType, id, ext, version.VersionID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	defer stream.Body.Close()
+
+	if checkNotModified(r, stream.ETag, stream.LastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// A later write can change what was live at a time still to come, so the
+	// response is revalidated rather than cached as immutable
+	w.Header().Set("ETag", stream.ETag)
+	w.Header().Set("Last-Modified", stream.LastModified.UTC().Format(time.RFC1123))
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Version-ID", stream.VersionID)
+	w.Header().Set("X-Content-State", string(storage.StateLive))
+	w.Header().Set("X-As-Of", at.UTC().Format(time.RFC3339))
+	w.Header().Set("Content-Type", stream.ContentType)
+	if stream.Size > 0 {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", stream.Size))
+	}
+	w.WriteHeader(http.StatusOK)
+	copyBuffered(w, stream.Body)
+}
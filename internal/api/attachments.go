@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+// maxAttachmentNameLength limits attachment names (they end up in storage keys)
+const maxAttachmentNameLength = 255
+
+// validAttachmentName checks an attachment name: one path segment that
+// doesn't start with "." or "_"
+func validAttachmentName(name string) bool {
+	return name != "" && len(name) <= maxAttachmentNameLength && !strings.ContainsAny(name, "/\\") &&
+		!strings.HasPrefix(name, ".") && !strings.HasPrefix(name, "_") && storage.ValidKeyPath(name)
+}
+
+// attachmentType returns the MIME type of an attachment, from its name
+// when the storage listing doesn't carry one
+func attachmentType(a *storage.Attachment) string {
+	if a.ContentType != "" {
+		return a.ContentType
+	}
+	return mimeFromExt(path.Ext(a.Name))
+}
+
+// listAttachments returns an item's attachments with their MIME types
+func (s *Server) listAttachments(ctx context.Context, tenant, contentType, id string) ([]*storage.Attachment, error) {
+	attachments, err := s.storage.ListAttachments(ctx, tenant, contentType, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range attachments {
+		a.ContentType = attachmentType(a)
+	}
+	return attachments, nil
+}
+
+// deleteOrphans removes an item's attachments and renditions once it no
+// longer exists in any state
+func (s *Server) deleteOrphans(ctx context.Context, tenant, contentType, id string) {
+	if s.contentExists(ctx, tenant, contentType, id) {
+		return
+	}
+	if err := s.storage.DeleteAllAttachments(ctx, tenant, contentType, id); err != nil {
+		log.Error("Failed to delete attachments of %s/%s: %v", contentType, id, err)
+	}
+	if _, err := s.deleteRenditions(ctx, tenant, contentType, id, false); err != nil {
+		log.Error("Failed to delete renditions of %s/%s: %v", contentType, id, err)
+	}
+}
+
+// =============================================================================
+// Attachment Handlers
+// =============================================================================
+
+// listAttachmentsHandler lists the attachments of a content item
+func (s *Server) listAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id := vars["type"], vars["id"]
+	tenant := s.getTenant(r)
+
+	attachments, err := s.listAttachments(r.Context(), tenant, contentType, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":          id,
+		"attachments": attachments,
+		"count":       len(attachments),
+	})
+}
+
+// getAttachmentHandler serves an attachment
+func (s *Server) getAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id, name := vars["type"], vars["id"], vars["name"]
+	tenant := s.getTenant(r)
+
+	stream, err := s.storage.GetAttachment(r.Context(), tenant, contentType, id, name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Attachment '%s' of '%s' not found", name, id))
+		return
+	}
+	defer stream.Body.Close()
+
+	w.Header().Set("Content-Type", stream.ContentType)
+	if stream.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(stream.Size, 10))
+	}
+	if stream.ETag != "" {
+		w.Header().Set("ETag", stream.ETag)
+	}
+	copyBuffered(w, stream.Body)
+}
+
+// putAttachmentHandler creates or replaces an attachment of an existing item
+func (s *Server) putAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id, name := vars["type"], vars["id"], vars["name"]
+	tenant := s.getTenant(r)
+	defer r.Body.Close()
+
+	if !validAttachmentName(name) {
+		writeError(w, http.StatusBadRequest, "invalid_name", "Attachment names are a single path segment that doesn't start with '.' or '_'")
+		return
+	}
+	if !s.contentExists(r.Context(), tenant, contentType, id) {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Content '%s' not found", id))
+		return
+	}
+
+	mimeType := r.Header.Get("Content-Type")
+	if mimeType == "" {
+		mimeType = mimeFromExt(path.Ext(name))
+	}
+
+	attachment, err := s.storage.PutAttachment(r.Context(), tenant, contentType, id, name, r.Body, r.ContentLength, mimeType)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	log.Debug("Stored attachment %s of %s/%s (%s, %d bytes)", name, contentType, id, mimeType, attachment.Size)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":         id,
+		"attachment": attachment,
+		"message":    "Attachment stored successfully",
+	})
+}
+
+// deleteAttachmentHandler removes an attachment
+func (s *Server) deleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id, name := vars["type"], vars["id"], vars["name"]
+	tenant := s.getTenant(r)
+
+	stream, err := s.storage.GetAttachment(r.Context(), tenant, contentType, id, name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Attachment '%s' of '%s' not found", name, id))
+		return
+	}
+	stream.Body.Close()
+
+	if err := s.storage.DeleteAttachment(r.Context(), tenant, contentType, id, name); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      id,
+		"name":    name,
+		"message": "Attachment deleted successfully",
+	})
+}
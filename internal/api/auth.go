@@ -32,12 +32,14 @@ type sessionStore struct {
 	mu      sync.RWMutex
 	cache   map[string]*cachedSession
 	storage storage.Storage
+	leader  *leaderElector // only the leader sweeps S3
 }
 
-func newSessionStore(s storage.Storage) *sessionStore {
+func newSessionStore(s storage.Storage, leader *leaderElector) *sessionStore {
 	ss := &sessionStore{
 		cache:   make(map[string]*cachedSession),
 		storage: s,
+		leader:  leader,
 	}
 	go ss.sweepLoop()
 	return ss
@@ -141,7 +143,10 @@ func (ss *sessionStore) sweep() {
 	}
 	ss.mu.Unlock()
 
-	// Clean S3
+	// Clean S3 (once across the cluster)
+	if !ss.leader.isLeader() {
+		return
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 	deleted, err := ss.storage.DeleteExpiredSessions(ctx)
@@ -179,20 +184,25 @@ func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "session_error", "Failed to create session")
 		return
 	}
+	setSessionCookie(w, r, token)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"token":   token,
+		"message": "Login successful",
+	})
+}
 
+// setSessionCookie gives the browser a session
+func setSessionCookie(w http.ResponseWriter, r *http.Request, token string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     sessionCookieName,
 		Value:    token,
 		Path:     "/",
 		HttpOnly: true,
+		Secure:   isSecureRequest(r),
 		SameSite: http.SameSiteLaxMode,
 		MaxAge:   int(sessionDuration.Seconds()),
 	})
-
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"token":   token,
-		"message": "Login successful",
-	})
 }
 
 // logoutHandler handles POST /api/logout
@@ -216,12 +226,14 @@ func (s *Server) logoutHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // sessionHandler handles GET /api/session
+// Also says whether single sign-on is available, for the login page.
 func (s *Server) sessionHandler(w http.ResponseWriter, r *http.Request) {
 	token := extractToken(r)
 	valid := token != "" && s.sessions.validate(token)
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"valid": valid,
+		"oidc":  s.oidc != nil,
 	})
 }
 
@@ -237,6 +249,18 @@ func (s *Server) adminAuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// requireSession rejects API requests without a valid session
+func (s *Server) requireSession(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := extractToken(r)
+		if token == "" || !s.sessions.validate(token) {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "A valid session is required")
+			return
+		}
+		next(w, r)
+	}
+}
+
 // extractToken gets the session token from cookie or Authorization header
 func extractToken(r *http.Request) string {
 	if cookie, err := r.Cookie(sessionCookieName); err == nil && cookie.Value != "" {
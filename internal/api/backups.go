@@ -0,0 +1,360 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/storage"
+)
+
+const (
+	backupsCollection = "backups"
+	backupJobKind     = "backup"
+	restoreJobKind    = "restore"
+	backupIDFormat    = "20060102T150405Z"
+)
+
+// contentStates lists every content state, e.g. for tenant-wide scans
+var contentStates = []storage.State{storage.StateLive, storage.StateDraft, storage.StatePending}
+
+// backupEntry is a content object recorded in a backup manifest
+type backupEntry struct {
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	Ext    string `json:"ext"`
+	State  string `json:"state"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+	Object string `json:"object"` // backup object holding the content
+}
+
+// key identifies the content object an entry was taken from
+func (e *backupEntry) key() string {
+	return e.State + "/" + e.Type + "/" + e.ID + "." + e.Ext
+}
+
+// backupManifest lists every content object of a root at a point in time.
+// Each manifest is complete on its own; incremental backups only copy the
+// objects that changed since the base manifest and reference the rest.
+type backupManifest struct {
+	ID          string        `json:"id"`
+	Root        string        `json:"root"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Incremental bool          `json:"incremental"`
+	Base        string        `json:"base,omitempty"` // manifest this backup was compared against
+	Objects     int           `json:"objects"`
+	Copied      int           `json:"copied"`
+	Reused      int           `json:"reused"`
+	BytesCopied int64         `json:"bytes_copied"`
+	Size        int64         `json:"size"`
+	Entries     []backupEntry `json:"entries,omitempty"`
+}
+
+// backupObjectID returns the backup object ID for a content item: its ETag,
+// so identical content shares one backup object
+func backupObjectID(item *storage.ContentItem) string {
+	if etag := strings.Trim(item.ETag, `"`); etag != "" {
+		return etag
+	}
+	sum := sha256.Sum256([]byte(item.Key + "\x00" + item.LastModified.String()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// loadManifest reads a backup manifest
+func (s *Server) loadManifest(ctx context.Context, tenant, id string) (*backupManifest, error) {
+	data, err := s.storage.GetDocument(ctx, tenant, backupsCollection, id)
+	if err != nil {
+		return nil, err
+	}
+	var manifest backupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// latestManifest returns the most recent manifest taken from a root, if any
+func (s *Server) latestManifest(ctx context.Context, tenant, root string) *backupManifest {
+	ids, err := s.storage.ListDocuments(ctx, tenant, backupsCollection)
+	if err != nil {
+		return nil
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	for _, id := range ids {
+		if manifest, err := s.loadManifest(ctx, tenant, id); err == nil && manifest.Root == root {
+			return manifest
+		}
+	}
+	return nil
+}
+
+// createBackup snapshots a tenant's content root, copying only objects that
+// changed since the last manifest when incremental is set
+func (s *Server) createBackup(ctx context.Context, j *job, tenant, root string, incremental bool) (*backupManifest, error) {
+	ctx = storage.WithRoot(ctx, root)
+	manifest := &backupManifest{
+		ID:          time.Now().UTC().Format(backupIDFormat),
+		Root:        root,
+		Incremental: incremental,
+		Entries:     []backupEntry{},
+	}
+
+	previous := make(map[string]backupEntry)
+	if incremental {
+		if base := s.latestManifest(ctx, tenant, root); base != nil {
+			manifest.Base = base.ID
+			for _, entry := range base.Entries {
+				previous[entry.key()] = entry
+			}
+		}
+	}
+
+	types, err := s.storage.ListContentTypes(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list content types: %w", err)
+	}
+
+	// Collect items up front so progress is meaningful
+	var entries []backupEntry
+	for _, contentType := range types {
+		for _, state := range contentStates {
+			items, err := s.storage.List(ctx, tenant, contentType, state)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %s (%s): %w", contentType, state, err)
+			}
+			for _, item := range items {
+				id, ext := extractIDAndExt(item.Key, contentType, state)
+				entries = append(entries, backupEntry{
+					Type:   contentType,
+					ID:     id,
+					Ext:    ext,
+					State:  string(state),
+					ETag:   item.ETag,
+					Size:   item.Size,
+					Object: backupObjectID(item),
+				})
+			}
+		}
+	}
+
+	for i, entry := range entries {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		j.setProgress(i, len(entries))
+
+		if prev, ok := previous[entry.key()]; ok && prev.ETag == entry.ETag {
+			entry.Object = prev.Object
+			manifest.Reused++
+		} else {
+			if err := s.storage.BackupObject(ctx, tenant, entry.Type, entry.ID, entry.Ext, storage.State(entry.State), entry.Object); err != nil {
+				return nil, fmt.Errorf("failed to back up %s: %w", entry.key(), err)
+			}
+			manifest.Copied++
+			manifest.BytesCopied += entry.Size
+		}
+		manifest.Size += entry.Size
+		manifest.Entries = append(manifest.Entries, entry)
+	}
+
+	manifest.Objects = len(manifest.Entries)
+	manifest.CreatedAt = time.Now().UTC()
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := s.storage.PutDocument(ctx, tenant, backupsCollection, manifest.ID, data); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// restoreBackup copies every object of a manifest back into its root. With
+// prune set, content that is not in the manifest is deleted.
+func (s *Server) restoreBackup(ctx context.Context, j *job, tenant string, manifest *backupManifest, prune bool) (map[string]int, error) {
+	ctx = storage.WithRoot(ctx, manifest.Root)
+	result := map[string]int{"restored": 0, "unchanged": 0, "deleted": 0}
+
+	// Index current content so unchanged objects are skipped
+	current := make(map[string]string)
+	types, err := s.storage.ListContentTypes(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list content types: %w", err)
+	}
+	for _, contentType := range types {
+		for _, state := range contentStates {
+			items, err := s.storage.List(ctx, tenant, contentType, state)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %s (%s): %w", contentType, state, err)
+			}
+			for _, item := range items {
+				id, ext := extractIDAndExt(item.Key, contentType, state)
+				entry := backupEntry{Type: contentType, ID: id, Ext: ext, State: string(state)}
+				current[entry.key()] = item.ETag
+			}
+		}
+	}
+
+	for i, entry := range manifest.Entries {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		j.setProgress(i, len(manifest.Entries))
+
+		etag, exists := current[entry.key()]
+		delete(current, entry.key())
+		if exists && etag == entry.ETag {
+			result["unchanged"]++
+			continue
+		}
+		if _, err := s.storage.RestoreObject(ctx, tenant, entry.Type, entry.ID, entry.Ext, storage.State(entry.State), entry.Object); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", entry.key(), err)
+		}
+		result["restored"]++
+	}
+
+	if prune {
+		for key := range current {
+			// key format: {state}/{type}/{id}.{ext}
+			parts := strings.SplitN(key, "/", 3)
+			id, ext := parts[2], ""
+			if dot := strings.LastIndex(id, "."); dot != -1 {
+				id, ext = id[:dot], id[dot+1:]
+			}
+			if err := s.storage.Delete(ctx, tenant, parts[1], id, ext, storage.State(parts[0])); err != nil {
+				return nil, fmt.Errorf("failed to delete %s: %w", key, err)
+			}
+			result["deleted"]++
+		}
+	}
+
+	return result, nil
+}
+
+// =============================================================================
+// Backup Handlers
+// =============================================================================
+
+// listBackupsHandler handles GET /api/admin/backups
+func (s *Server) listBackupsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenant := s.getTenant(r)
+
+	ids, err := s.storage.ListDocuments(ctx, tenant, backupsCollection)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+	backups := make([]*backupManifest, 0, len(ids))
+	for _, id := range ids {
+		manifest, err := s.loadManifest(ctx, tenant, id)
+		if err != nil {
+			continue
+		}
+		manifest.Entries = nil
+		backups = append(backups, manifest)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"backups": backups,
+		"count":   len(backups),
+	})
+}
+
+// getBackupHandler handles GET /api/admin/backups/{id}
+func (s *Server) getBackupHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	manifest, err := s.loadManifest(r.Context(), s.getTenant(r), id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Backup '%s' not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, manifest)
+}
+
+// createBackupHandler handles POST /api/admin/backups
+// Backs up the active root (or X-Content-Root) in the background;
+// ?incremental=true copies only objects changed since the last backup.
+func (s *Server) createBackupHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	incremental := r.URL.Query().Get("incremental") == "true"
+
+	root := storage.RootFromContext(r.Context())
+	if root == "" {
+		root = s.roots.ActiveRoot(r.Context(), tenant).Active
+	}
+
+	j, err := s.jobs.start(tenant, backupJobKind, func(ctx context.Context, j *job) (interface{}, error) {
+		manifest, err := s.createBackup(ctx, j, tenant, root, incremental)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"manifest":     manifest.ID,
+			"objects":      manifest.Objects,
+			"copied":       manifest.Copied,
+			"reused":       manifest.Reused,
+			"bytes_copied": manifest.BytesCopied,
+		}, nil
+	})
+	if err == errJobRunning {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":   "job_running",
+			"message": "A backup is already running",
+			"job":     j.info(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"job":     j.info(),
+		"root":    root,
+		"message": "Backup started",
+	})
+}
+
+// restoreBackupHandler handles POST /api/admin/backups/{id}/restore
+// Restores the manifest's objects into the root they were taken from;
+// ?prune=true also deletes content that is not in the manifest.
+func (s *Server) restoreBackupHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	id := mux.Vars(r)["id"]
+	prune := r.URL.Query().Get("prune") == "true"
+
+	manifest, err := s.loadManifest(r.Context(), tenant, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Backup '%s' not found", id))
+		return
+	}
+
+	j, err := s.jobs.start(tenant, restoreJobKind, func(ctx context.Context, j *job) (interface{}, error) {
+		return s.restoreBackup(ctx, j, tenant, manifest, prune)
+	})
+	if err == errJobRunning {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":   "job_running",
+			"message": "A restore is already running",
+			"job":     j.info(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"job":     j.info(),
+		"root":    manifest.Root,
+		"message": fmt.Sprintf("Restore of backup '%s' started", id),
+	})
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+// Metadata keys recording where a branched draft came from
+const (
+	branchedFromKey        = "branched_from"         // the state copied (live)
+	branchedFromVersionKey = "branched_from_version" // the live version copied
+	branchedAtKey          = "branched_at"           // RFC 3339
+	branchedByKey          = "branched_by"           // author, when given
+)
+
+// =============================================================================
+// Branch Handlers
+// =============================================================================
+
+// branchHandler handles POST /api/content/{type}/{id}/branch
+// Copies the live content into draft server-side, so the bytes, MIME type, and
+// metadata are kept exactly, and records the live version it was copied from
+// in the draft's metadata. An existing draft is only replaced with
+// ?overwrite=true. The body may name an author: {"author": "..."}.
+func (s *Server) branchHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenant := s.getTenant(r)
+	vars := mux.Vars(r)
+	contentType := vars["type"]
+	id := vars["id"]
+
+	var req struct {
+		Author string `json:"author,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	live, err := s.storage.FindContentStream(ctx, tenant, contentType, id, "", storage.StateLive)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Content '%s' has no live version to branch from", id))
+		return
+	}
+	live.Body.Close()
+	_, ext := extractIDAndExt(live.Key, contentType, storage.StateLive)
+
+	unlock := lockWrite(tenant + "/" + contentType + "/" + id + "/" + string(storage.StateDraft))
+	defer unlock()
+
+	status := http.StatusCreated
+	if draft, err := s.storage.FindContentStream(ctx, tenant, contentType, id, ext, storage.StateDraft); err == nil {
+		draft.Body.Close()
+		if r.URL.Query().Get("overwrite") != "true" {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{
+				"error":   "already_exists",
+				"message": fmt.Sprintf("Content '%s' already has a draft; pass ?overwrite=true to replace it", id),
+				"etag":    draft.ETag,
+				"version": draft.VersionID,
+			})
+			return
+		}
+		status = http.StatusOK
+	}
+
+	provenance := map[string]string{
+		branchedFromKey:        string(storage.StateLive),
+		branchedFromVersionKey: live.VersionID,
+		branchedAtKey:          time.Now().UTC().Format(time.RFC3339),
+	}
+	if req.Author != "" {
+		provenance[branchedByKey] = req.Author
+	}
+
+	item, err := s.storage.CopyContent(ctx, tenant, contentType, id, ext, storage.StateLive, storage.StateDraft, provenance)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	log.Info("Branched %s/%s for tenant %s from live version %s", contentType, id, tenant, live.VersionID)
+
+	event := "create"
+	if status == http.StatusOK {
+		event = "update"
+	}
+	s.triggerWebhooks(tenant, event, contentType, id, filepath.Base(item.Key), item.ContentType)
+	s.indexContent(ctx, tenant, contentType, id, ext, storage.StateDraft, item.ContentType)
+
+	s.writeWriteResult(w, r, status, tenant, contentType, id, ext, storage.StateDraft, item, "Draft branched from live")
+}
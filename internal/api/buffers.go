@@ -0,0 +1,58 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+const (
+	// copyBufferSize is the buffer content is streamed through: twice
+	// io.Copy's default, so large bodies take half the writes
+	copyBufferSize = 64 << 10
+
+	// maxPooledBuffer keeps unusually large bodies from pinning memory in the pool
+	maxPooledBuffer = 4 << 20
+)
+
+// copyBuffers recycles the buffers content is streamed through
+var copyBuffers = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, copyBufferSize)
+		return &buf
+	},
+}
+
+// byteBuffers recycles the buffers bodies are read into
+var byteBuffers = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// copyBuffered copies src to dst through a pooled buffer, rather than one
+// allocated per copy; it is used wherever content is streamed to clients.
+// Responses are wrapped for logging, so io.Copy would otherwise allocate.
+func copyBuffered(dst io.Writer, src io.Reader) (int64, error) {
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+	return io.CopyBuffer(dst, src, *buf)
+}
+
+// readPooled reads r into a pooled buffer, sized up front when the length is
+// known. The bytes are only valid until release is called.
+func readPooled(r io.Reader, size int64) (data []byte, release func(), err error) {
+	b := byteBuffers.Get().(*bytes.Buffer)
+	b.Reset()
+	release = func() {
+		if b.Cap() <= maxPooledBuffer {
+			byteBuffers.Put(b)
+		}
+	}
+	if size > 0 && size <= maxPooledBuffer {
+		b.Grow(int(size) + bytes.MinRead)
+	}
+	if _, err := b.ReadFrom(r); err != nil {
+		release()
+		return nil, func() {}, err
+	}
+	return b.Bytes(), release, nil
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+// changesCollection records content changes for the changes feed, as
+// documents named {yyyy-mm-dd}/{unix nanos}-{random} so names sort in the
+// order changes were made and each day can be listed on its own
+const changesCollection = "changes"
+
+// Changes feed settings. A change is recorded before the write that made it
+// returns, within changesRecordTimeout of its time, so once changesSettle has
+// passed it's either stored or was never recorded; the rest of the settle
+// time allows for clocks of different nodes being that far apart.
+const (
+	changesRecordTimeout = 5 * time.Second
+	changesSettle        = 15 * time.Second // changes younger than this aren't served yet
+	defaultChangesLimit  = 1000
+	maxChangesLimit      = 10000
+)
+
+// change is one entry of the changes feed
+type change struct {
+	Type    string    `json:"type"`
+	ID      string    `json:"id"`
+	Event   string    `json:"event"`
+	State   string    `json:"state,omitempty"`
+	Author  string    `json:"author,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// recordChange appends a content event to the changes feed. It's called
+// before the write returns, so the change is stored before changesSettle
+// passes (see changesHandler).
+func (s *Server) recordChange(payload storage.WebhookEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), changesRecordTimeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	state := payload.State
+	if payload.Event == "publish" {
+		state = string(storage.StateLive)
+	}
+	data, _ := json.Marshal(&change{
+		Type:    payload.Type,
+		ID:      payload.ID,
+		Event:   payload.Event,
+		State:   state,
+		Author:  payload.Author,
+		Message: payload.Message,
+		Time:    now,
+	})
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	name := fmt.Sprintf("%019d-%s", now.UnixNano(), hex.EncodeToString(suffix))
+	if err := s.storage.PutDocument(ctx, payload.Tenant, changesDay(now), name, data); err != nil {
+		log.Error("Failed to record change to %s/%s: %v", payload.Type, payload.ID, err)
+	}
+}
+
+// encodeChangesToken makes the sync token for a position in the feed: the
+// name of the last change read
+func encodeChangesToken(docID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(docID))
+}
+
+// decodeChangesToken reads a sync token, returning the change it follows and
+// when that change was made
+func decodeChangesToken(token string) (string, time.Time, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid sync token")
+	}
+	at, ok := changeTime(string(data))
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("invalid sync token")
+	}
+	return string(data), at, nil
+}
+
+// changesPosition is a position in the feed after every change recorded up
+// to a time
+func changesPosition(t time.Time) string {
+	return fmt.Sprintf("%s/%019d-~", t.UTC().Format("2006-01-02"), t.UnixNano())
+}
+
+// changesDay returns the collection of a day's changes
+func changesDay(t time.Time) string {
+	return changesCollection + "/" + t.UTC().Format("2006-01-02")
+}
+
+// changeTime reads when a change was recorded from its document name
+func changeTime(docID string) (time.Time, bool) {
+	_, name, ok := strings.Cut(docID, "/")
+	nanos, _, _ := strings.Cut(name, "-")
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if !ok || err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, n), true
+}
+
+// =============================================================================
+// Changes Feed Handlers
+// =============================================================================
+
+// changesHandler handles GET /api/changes
+// Returns the tenant's content changes (writes, publishes, metadata updates,
+// approval requests, and deletions in any state) after ?since=, a sync token
+// from an earlier response, oldest first. Without ?since= the feed starts at
+// the oldest change still kept. ?type= limits it to one content type and
+// ?limit= (default 1000, at most 10000) caps the page; has_more says to call
+// again at once with next_token.
+func (s *Server) changesHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	query := r.URL.Query()
+
+	var after string
+	if token := query.Get("since"); token != "" {
+		docID, at, err := decodeChangesToken(token)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_token", "Invalid sync token")
+			return
+		}
+		if time.Since(at) > s.gcRetention() {
+			writeError(w, http.StatusGone, "expired_token", "Sync token is older than the changes kept; sync in full and start again without since")
+			return
+		}
+		after = docID
+	}
+
+	limit := defaultChangesLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "invalid_limit", "Limit must be a positive number")
+			return
+		}
+		limit = min(n, maxChangesLimit)
+	}
+	contentType := query.Get("type")
+
+	// Changes are recorded as they happen on every node; leaving the newest
+	// for the next call means one recorded a moment late isn't skipped
+	settled := time.Now().Add(-changesSettle)
+	changes := []*change{}
+	next := after
+	hasMore := false
+
+	// Days are listed one at a time from the token's, so a poll only reads
+	// what's new
+	day := time.Now().Add(-s.gcRetention())
+	if after != "" {
+		day, _ = changeTime(after)
+	}
+	day = day.UTC().Truncate(24 * time.Hour)
+days:
+	for ; !day.After(settled); day = day.Add(24 * time.Hour) {
+		collection := changesDay(day)
+		ids, err := s.storage.ListDocuments(r.Context(), tenant, collection)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+			return
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			name := strings.TrimPrefix(collection, changesCollection+"/") + "/" + id
+			if name <= after {
+				continue
+			}
+			at, ok := changeTime(name)
+			if !ok {
+				continue
+			}
+			if at.After(settled) {
+				break days
+			}
+			if len(changes) == limit {
+				hasMore = true
+				break days
+			}
+			data, err := s.storage.GetDocument(r.Context(), tenant, collection, id)
+			if err != nil && !storage.IsNotFound(err) {
+				// Stop short of a change that couldn't be read, so the next
+				// call reads it rather than skipping it
+				log.Error("Failed to read change %s of tenant %s: %v", name, tenant, err)
+				hasMore = true
+				break days
+			}
+			next = name
+			if err != nil {
+				continue // Removed by garbage collection
+			}
+			var c change
+			if err := json.Unmarshal(data, &c); err != nil || (contentType != "" && c.Type != contentType) {
+				continue
+			}
+			changes = append(changes, &c)
+		}
+	}
+
+	if hasMore && next == "" {
+		writeError(w, http.StatusServiceUnavailable, "storage_error", "Failed to read the changes feed")
+		return
+	}
+
+	result := map[string]interface{}{
+		"tenant":   tenant,
+		"changes":  changes,
+		"count":    len(changes),
+		"has_more": hasMore,
+	}
+	if hasMore {
+		result["next_token"] = encodeChangesToken(next)
+	} else {
+		// Everything up to the settled time has been read, so the token moves
+		// there and stays fresh while the tenant is quiet
+		result["next_token"] = encodeChangesToken(changesPosition(settled))
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"velocity/internal/storage"
+	"velocity/internal/storage/storagetest"
+)
+
+// flakyStorage fails reads of one document until it's told to stop
+type flakyStorage struct {
+	storage.Storage
+	failing string
+}
+
+func (f *flakyStorage) GetDocument(ctx context.Context, tenant, collection, id string) ([]byte, error) {
+	if id == f.failing {
+		return nil, errors.New("connection reset")
+	}
+	return f.Storage.GetDocument(ctx, tenant, collection, id)
+}
+
+// putChange stores a change made at a time, returning its document name
+func putChange(t *testing.T, s storage.Storage, tenant, id string, at time.Time) string {
+	t.Helper()
+	name := fmt.Sprintf("%019d-%s", at.UnixNano(), id)
+	data, _ := json.Marshal(&change{Type: "pages", ID: id, Event: "update", Time: at})
+	if err := s.PutDocument(context.Background(), tenant, changesDay(at), name, data); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+// changesPage is a response of the changes feed
+type changesPage struct {
+	Changes   []*change `json:"changes"`
+	HasMore   bool      `json:"has_more"`
+	NextToken string    `json:"next_token"`
+}
+
+// readChanges reads a page of the changes feed
+func readChanges(t *testing.T, s *Server, query url.Values) changesPage {
+	t.Helper()
+	w := serve(s, "acme", "", "GET", "/api/changes?"+query.Encode(), "")
+	requireStatus(t, w, http.StatusOK, "GET /api/changes")
+	var page changesPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+// changeIDs returns the item IDs of changes
+func changeIDs(changes []*change) []string {
+	ids := []string{}
+	for _, c := range changes {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func TestChangesFeedPagesAcrossDays(t *testing.T) {
+	backend, _ := storagetest.NewS3Storage(10)
+	s := NewServer(backend, &ServerConfig{}, embed.FS{})
+
+	now := time.Now()
+	putChange(t, backend, "acme", "a", now.Add(-50*time.Hour))
+	putChange(t, backend, "acme", "b", now.Add(-26*time.Hour))
+	putChange(t, backend, "acme", "c", now.Add(-time.Minute))
+	putChange(t, backend, "acme", "unsettled", now)
+
+	var ids []string
+	query := url.Values{"limit": {"1"}}
+	for i := 0; ; i++ {
+		if i > 5 {
+			t.Fatal("feed didn't end")
+		}
+		page := readChanges(t, s, query)
+		ids = append(ids, changeIDs(page.Changes)...)
+		query.Set("since", page.NextToken)
+		if !page.HasMore {
+			break
+		}
+	}
+	if fmt.Sprint(ids) != "[a b c]" {
+		t.Errorf("changes = %v, want [a b c]", ids)
+	}
+}
+
+func TestChangesFeedStopsAtUnreadableChanges(t *testing.T) {
+	backend, _ := storagetest.NewS3Storage(10)
+	flaky := &flakyStorage{Storage: backend}
+	s := NewServer(flaky, &ServerConfig{}, embed.FS{})
+
+	now := time.Now()
+	putChange(t, backend, "acme", "a", now.Add(-3*time.Minute))
+	flaky.failing = putChange(t, backend, "acme", "b", now.Add(-2*time.Minute))
+	putChange(t, backend, "acme", "c", now.Add(-time.Minute))
+
+	page := readChanges(t, s, url.Values{})
+	if got := fmt.Sprint(changeIDs(page.Changes)); got != "[a]" || !page.HasMore {
+		t.Fatalf("changes = %s (has_more %v), want [a] and more", got, page.HasMore)
+	}
+
+	// Once it can be read, the next call starts with it
+	flaky.failing = ""
+	page = readChanges(t, s, url.Values{"since": {page.NextToken}})
+	if got := fmt.Sprint(changeIDs(page.Changes)); got != "[b c]" {
+		t.Errorf("changes after recovery = %s, want [b c]", got)
+	}
+}
+
+func TestChangesFeedRecordsWritesBeforeTheyReturn(t *testing.T) {
+	backend, _ := storagetest.NewS3Storage(10)
+	s := NewServer(backend, &ServerConfig{}, embed.FS{})
+
+	before := time.Now()
+	requireStatus(t, serve(s, "acme", "", "PUT", "/api/content/pages/home", `{"title":"Home"}`), http.StatusOK, "PUT content")
+
+	// The write may have crossed midnight
+	days := []string{changesDay(before)}
+	if day := changesDay(time.Now()); day != days[0] {
+		days = append(days, day)
+	}
+	var ids []string
+	for _, day := range days {
+		found, err := backend.ListDocuments(context.Background(), "acme", day)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, found...)
+	}
+	if len(ids) != 1 {
+		t.Errorf("changes recorded = %v, want one", ids)
+	}
+}
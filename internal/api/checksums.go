@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+// checksumMetadata is the metadata key an item's SHA-256 is shown under. It
+// isn't stored on the object: it's kept with the item's fingerprint (see
+// fingerprints.go), so it can't be changed through the metadata endpoints.
+const checksumMetadata = "sha256"
+
+// sha256Hex returns the hex SHA-256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// contentChecksum returns the SHA-256 of an item's content: the one recorded
+// when it was written, or, for content written before checksums were
+// recorded or changed out of band, one computed by reading the stream, which
+// is recorded for next time. The stream's body is consumed in that case.
+func (s *Server) contentChecksum(ctx context.Context, tenant, contentType, id string, state storage.State, stream *storage.ContentStream) (string, error) {
+	if record := s.getFingerprint(ctx, tenant, contentType, id, state, stream.ETag); record != nil && record.SHA256 != "" {
+		return record.SHA256, nil
+	}
+
+	body, fp, err := fingerprintBody(stream.Body, stream.ContentType)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		return "", err
+	}
+	s.storeFingerprint(tenant, contentType, id, state, &storage.ContentItem{ETag: stream.ETag, Size: stream.Size}, fp)
+	return fp.checksum(), nil
+}
+
+// withChecksum returns a copy of an item's metadata with its SHA-256 added,
+// computing it if needed. A failure is logged and leaves the checksum out.
+func (s *Server) withChecksum(ctx context.Context, tenant, contentType, id string, state storage.State, stream *storage.ContentStream) map[string]string {
+	metadata := make(map[string]string, len(stream.Metadata)+1)
+	for key, value := range stream.Metadata {
+		metadata[key] = value
+	}
+	delete(metadata, checksumMetadata)
+
+	sum, err := s.contentChecksum(ctx, tenant, contentType, id, state, stream)
+	if err != nil {
+		log.Error("Failed to compute the checksum of %s/%s: %v", contentType, id, err)
+		return metadata
+	}
+	metadata[checksumMetadata] = sum
+	return metadata
+}
+
+// wantsDigest reports whether a read asks for the content's SHA-256 with
+// Want-Repr-Digest (RFC 9530)
+func wantsDigest(r *http.Request) bool {
+	for _, want := range r.Header.Values("Want-Repr-Digest") {
+		for _, entry := range strings.Split(want, ",") {
+			algorithm, preference, _ := strings.Cut(strings.TrimSpace(entry), "=")
+			if strings.EqualFold(algorithm, "sha-256") && strings.TrimSpace(preference) != "0" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setReprDigest sets the Repr-Digest header of a read that asks for it, when
+// the checksum recorded for the item matches the object being served. It's
+// left out rather than computed, which would mean reading the content twice;
+// clients fall back to the metadata endpoint, which computes it.
+func (s *Server) setReprDigest(w http.ResponseWriter, r *http.Request, tenant, contentType, id string, state storage.State, etag string) {
+	if !wantsDigest(r) || etag == "" {
+		return
+	}
+	record := s.getFingerprint(r.Context(), tenant, contentType, id, state, etag)
+	if record == nil || record.SHA256 == "" {
+		return
+	}
+	sum, err := hex.DecodeString(record.SHA256)
+	if err != nil {
+		return
+	}
+	w.Header().Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum)+":")
+}
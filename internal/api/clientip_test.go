@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &ServerConfig{TrustedProxies: proxies}}
+
+	for _, tc := range []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{"direct", "203.0.113.7:1234", nil, "203.0.113.7"},
+		{"untrusted peer's header is ignored", "203.0.113.7:1234", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:1234", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"spoofed hops left of the client", "10.1.2.3:1234", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.1.2.3:1234", []string{"198.51.100.1, 192.168.1.10", "10.9.9.9"}, "198.51.100.1"},
+		{"invalid hop", "10.1.2.3:1234", []string{"198.51.100.1, garbage"}, "10.1.2.3"},
+		{"IPv4-mapped proxy", "[::ffff:10.1.2.3]:1234", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"only trusted hops", "10.1.2.3:1234", []string{"10.4.5.6"}, "10.4.5.6"},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tc.remoteAddr
+		for _, header := range tc.forwardedFor {
+			r.Header.Add("X-Forwarded-For", header)
+		}
+		if got := s.clientIP(r); got != tc.want {
+			t.Errorf("%s: clientIP = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseTrustedProxiesRejectsInvalidEntries(t *testing.T) {
+	if _, err := ParseTrustedProxies("10.0.0.0/8,proxy.internal"); err == nil {
+		t.Error("ParseTrustedProxies accepted a host name")
+	}
+}
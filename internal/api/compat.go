@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/version"
+)
+
+const (
+	// clientVersionHeader carries the version of the client making a request
+	clientVersionHeader = "X-Client-Version"
+
+	// minClientVersion is the oldest client this server works with; raise it
+	// when a release removes something older clients depend on
+	minClientVersion = "0.1.0"
+)
+
+// routeSunset schedules the removal of a route that has a replacement
+type routeSunset struct {
+	Sunset      time.Time // when the route will be removed
+	Replacement string    // path of the route to use instead
+	Message     string
+}
+
+// sunsetRoutes lists deprecated routes by method and path template (e.g.
+// "GET /api/content/{type}"). Routes are listed here, with a replacement, at
+// least one release before they're removed.
+var sunsetRoutes = map[string]routeSunset{}
+
+// maxClientVersion is the newest client this server works with: clients from
+// its own release, which use nothing the server doesn't have
+func maxClientVersion() string {
+	return version.GetVersion()
+}
+
+// compatibilityHandler tells clients which versions the server works with,
+// warns clients outside that range, and marks deprecated routes with the date
+// they'll be removed
+func (s *Server) compatibilityHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Server-Version", version.GetVersion())
+		w.Header().Set("X-Min-Client-Version", minClientVersion)
+		w.Header().Set("X-Max-Client-Version", maxClientVersion())
+
+		if client := r.Header.Get(clientVersionHeader); client != "" && !version.Within(client, minClientVersion, maxClientVersion()) {
+			notice := fmt.Sprintf("Client version %s is not supported by server %s (supported: %s to %s)", client, version.GetVersion(), minClientVersion, maxClientVersion())
+			w.Header().Set("Warning", fmt.Sprintf("299 velocity %q", notice))
+			log.Debug("%s: %s %s", notice, r.Method, r.URL.Path)
+		}
+
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				if sunset, ok := sunsetRoutes[r.Method+" "+template]; ok {
+					notice := fmt.Sprintf("%s %s is deprecated and will be removed on %s", r.Method, template, sunset.Sunset.Format("2006-01-02"))
+					if sunset.Replacement != "" {
+						notice += "; use " + sunset.Replacement + " instead"
+					}
+					if sunset.Message != "" {
+						notice += ": " + sunset.Message
+					}
+					w.Header().Set("Deprecation", "true")
+					w.Header().Set("Sunset", sunset.Sunset.UTC().Format(http.TimeFormat))
+					w.Header().Set("X-Deprecated", notice)
+					if sunset.Replacement != "" {
+						w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, sunset.Replacement))
+					}
+				}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"velocity/internal/storage"
+)
+
+const (
+	// maxContentSetItems bounds the items one ?ids= or ?id= fetch returns
+	maxContentSetItems = 100
+
+	// contentSetWorkers bounds how many items of a set are read at once
+	contentSetWorkers = 8
+)
+
+// isContentGlob reports whether an ID is a glob pattern (press-*, news/202?-*)
+func isContentGlob(id string) bool {
+	return strings.ContainsAny(id, "*?[")
+}
+
+// contentSetIDs returns the IDs a fetch names: ?ids=home,about,pricing lists
+// them, and each ?id= is an ID or a glob pattern matched against the type's
+// listing. Listed IDs keep their order; matches follow, sorted. truncated is
+// set when the patterns matched more than maxContentSetItems.
+func (s *Server) contentSetIDs(ctx context.Context, tenant, contentType string, state storage.State, query map[string][]string) (ids []string, truncated bool, err error) {
+	seen := make(map[string]bool)
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var patterns []string
+	for _, list := range query["ids"] {
+		for _, id := range strings.Split(list, ",") {
+			add(strings.TrimSpace(id))
+		}
+	}
+	for _, id := range query["id"] {
+		if !isContentGlob(id) {
+			add(id)
+			continue
+		}
+		if _, err := path.Match(id, ""); err != nil {
+			return nil, false, fmt.Errorf("Invalid pattern: %q", id)
+		}
+		patterns = append(patterns, id)
+	}
+	if len(ids) > maxContentSetItems {
+		return nil, false, fmt.Errorf("Too many IDs: %d (at most %d)", len(ids), maxContentSetItems)
+	}
+	if len(patterns) == 0 {
+		return ids, false, nil
+	}
+
+	// One listing of the type resolves every pattern
+	items, err := s.storage.List(ctx, tenant, contentType, state)
+	if err != nil {
+		return nil, false, err
+	}
+	var matches []string
+	for _, item := range items {
+		id, _ := extractIDAndExt(item.Key, contentType, state)
+		if seen[id] {
+			continue
+		}
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, id); ok {
+				seen[id] = true
+				matches = append(matches, id)
+				break
+			}
+		}
+	}
+	sort.Strings(matches)
+	if len(ids)+len(matches) > maxContentSetItems {
+		matches = matches[:maxContentSetItems-len(ids)]
+		truncated = true
+	}
+	return append(ids, matches...), truncated, nil
+}
+
+// contentSetHandler answers GET /api/content/{type} with ?ids= or ?id=: the
+// named items with their content and metadata, in one response, the way a
+// bulk get would return them. IDs that aren't found are listed in missing.
+// With ?as-of=, each item is the version that was live at that time.
+func (s *Server) contentSetHandler(w http.ResponseWriter, r *http.Request, tenant, contentType string, state storage.State) {
+	asOf, hasAsOf, err := parseAsOf(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_as_of", err.Error())
+		return
+	}
+	if hasAsOf && state != storage.StateLive {
+		writeError(w, http.StatusBadRequest, "invalid_state", "Reads with as-of resolve live content only")
+		return
+	}
+
+	ids, truncated, err := s.contentSetIDs(r.Context(), tenant, contentType, state, r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_ids", err.Error())
+		return
+	}
+	for _, id := range ids {
+		if !storage.ValidKeyPath(id) {
+			writeError(w, http.StatusBadRequest, "invalid_path", fmt.Sprintf("Invalid id: %q", id))
+			return
+		}
+	}
+
+	results := make([]map[string]interface{}, len(ids))
+	sem := make(chan struct{}, contentSetWorkers)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if hasAsOf {
+				results[i] = s.contentSetItemAsOf(r.Context(), tenant, contentType, id, asOf)
+			} else {
+				results[i] = s.contentSetItem(r.Context(), tenant, contentType, id, state)
+			}
+		}(i, id)
+	}
+	wg.Wait()
+
+	items := make([]map[string]interface{}, 0, len(ids))
+	missing := []string{}
+	for i, data := range results {
+		if data == nil {
+			missing = append(missing, ids[i])
+			continue
+		}
+		items = append(items, data)
+	}
+
+	response := map[string]interface{}{
+		"items":     items,
+		"count":     len(items),
+		"missing":   missing,
+		"truncated": truncated,
+	}
+	if hasAsOf {
+		response["as_of"] = asOf.UTC().Format(time.RFC3339)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// contentSetItem reads one item of a set, or returns nil if it isn't found
+func (s *Server) contentSetItem(ctx context.Context, tenant, contentType, id string, state storage.State) map[string]interface{} {
+	stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, "", state)
+	if err != nil {
+		return nil
+	}
+	return contentSetData(contentType, id, stream)
+}
+
+// contentSetItemAsOf reads the version of an item that was live at a time, or
+// returns nil if it wasn't live then
+func (s *Server) contentSetItemAsOf(ctx context.Context, tenant, contentType, id string, at time.Time) map[string]interface{} {
+	ext, version, err := s.asOfVersion(ctx, tenant, contentType, id, at)
+	if err != nil {
+		return nil
+	}
+	stream, err := s.storage.GetVersionStream(ctx, tenant, contentType, id, ext, version.VersionID)
+	if err != nil {
+		return nil
+	}
+	return contentSetData(contentType, id, stream)
+}
+
+// contentSetData reads and closes a stream into an item of a set, or returns
+// nil if it can't be read
+func contentSetData(contentType, id string, stream *storage.ContentStream) map[string]interface{} {
+	content, release, err := readPooled(stream.Body, stream.Size)
+	stream.Body.Close()
+	defer release()
+	if err != nil {
+		return nil
+	}
+
+	metadata := stream.Metadata
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	return map[string]interface{}{
+		"type":          contentType,
+		"id":            id,
+		"content-type":  stream.ContentType,
+		"version":       stream.VersionID,
+		"last_modified": stream.LastModified.UTC().Format(time.RFC3339),
+		"metadata":      metadata,
+		"content":       bulkContent(stream.ContentType, content),
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"velocity/internal/storage"
+)
+
+// createOnly reports whether a create must not replace an existing item:
+// If-None-Match: * asks for it, and ?fail-if-exists= overrides the tenant's
+// fail_if_exists default
+func (s *Server) createOnly(r *http.Request, tenant string) bool {
+	if strings.TrimSpace(r.Header.Get("If-None-Match")) == "*" {
+		return true
+	}
+	switch r.URL.Query().Get("fail-if-exists") {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return s.settings.get(r.Context(), tenant).FailIfExists
+}
+
+// existingItem returns the ETag and version of an item that already exists
+// in a state, under any extension, or ok false when there's none
+func (s *Server) existingItem(ctx context.Context, tenant, contentType, id, ext string, state storage.State) (etag, version string, ok bool) {
+	stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, ext, state)
+	if err != nil {
+		return "", "", false
+	}
+	stream.Body.Close()
+	return stream.ETag, stream.VersionID, true
+}
+
+// checkIfNoneMatch enforces create-only semantics on a write. It returns
+// false after writing a 409 response when the item already exists in the
+// state being written, under any extension.
+func (s *Server) checkIfNoneMatch(w http.ResponseWriter, r *http.Request, tenant, contentType, id, ext string, state storage.State) bool {
+	etag, version, exists := s.existingItem(r.Context(), tenant, contentType, id, ext, state)
+	if !exists {
+		return true
+	}
+
+	writeJSON(w, http.StatusConflict, map[string]interface{}{
+		"error":   "already_exists",
+		"message": fmt.Sprintf("Content '%s' already exists", id),
+		"etag":    etag,
+		"version": version,
+	})
+	return false
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+)
+
+// =============================================================================
+// Debug Handlers
+// =============================================================================
+
+// getLogLevelHandler handles GET /api/admin/log-level
+func (s *Server) getLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"level": strings.ToLower(log.GetLevel().String()),
+	})
+}
+
+// setLogLevelHandler handles PUT /api/admin/log-level
+// Body: {"level": "debug"}. The change applies to this node until restart.
+func (s *Server) setLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Level string `json:"level"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+	if !log.ValidLevel(req.Level) {
+		writeError(w, http.StatusBadRequest, "invalid_level", fmt.Sprintf("Invalid log level: %s (expected trace, debug, info, or error)", req.Level))
+		return
+	}
+
+	previous := log.GetLevel()
+	log.SetLevel(log.ParseLevel(req.Level))
+	log.Info("Log level changed from %s to %s", previous, log.GetLevel())
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"level":    strings.ToLower(log.GetLevel().String()),
+		"previous": strings.ToLower(previous.String()),
+	})
+}
+
+// pprofProfileHandler handles GET /api/admin/pprof/{profile}
+// Serves named runtime profiles (heap, goroutine, allocs, block, mutex, threadcreate).
+func (s *Server) pprofProfileHandler(w http.ResponseWriter, r *http.Request) {
+	pprof.Handler(mux.Vars(r)["profile"]).ServeHTTP(w, r)
+}
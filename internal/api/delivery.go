@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/storage"
+)
+
+// Server modes
+const (
+	ModeFull     = "full"     // editing and delivery (default)
+	ModeDelivery = "delivery" // read-only replica serving published content
+)
+
+// deliveryRoutes are the routes a delivery node serves, by path template and
+// method. Everything else (writes, admin, tenant configuration, webhooks,
+// bucket events) belongs to the editing plane.
+var deliveryRoutes = map[string][]string{
+	"/content/{tenant}/robots.txt":               {"GET"},
+	"/content/{tenant}/.well-known/security.txt": {"GET"},
+	"/content/{tenant}/{type}/{id:.+}":           {"GET"},
+	"/api":                                       {"GET"},
+	"/api/health":                                {"GET"},
+	"/api/health/live":                           {"GET"},
+	"/api/health/ready":                          {"GET"},
+	"/api/version":                               {"GET"},
+	"/api/types":                                 {"GET"},
+	"/api/content":                               {"POST"}, // bulk get
+	"/api/content/{type}":                        {"GET"},
+	"/api/content/{type}/_index":                 {"GET"},
+	"/api/content/{type}/{id:.+}":                {"GET"},
+	"/api/content/{type}/{id:.+}/metadata":       {"GET"},
+	"/api/navigation":                            {"GET"},
+	"/api/cache-manifest":                        {"GET"},
+	"/api/cluster/peers":                         {"GET"},
+	"/assets/":                                   {"GET"},
+	"/":                                          {"GET"},
+}
+
+// isDelivery reports whether the server runs as a delivery node
+func (s *Server) isDelivery() bool {
+	return s.config.Mode == ModeDelivery
+}
+
+// deliveryHandler limits a delivery node to the routes in deliveryRoutes, and
+// its API to live content (previews on the public route keep working, as they
+// need the tenant's preview token)
+func (s *Server) deliveryHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		if route == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		template, _ := route.GetPathTemplate()
+		methods, ok := deliveryRoutes[template]
+		if !ok {
+			writeError(w, http.StatusNotFound, "delivery_mode", "Not available on a delivery node")
+			return
+		}
+		if !containsString(methods, r.Method) {
+			writeError(w, http.StatusMethodNotAllowed, "delivery_mode", "Delivery nodes are read-only; send writes to an editing node")
+			return
+		}
+		if state := r.URL.Query().Get("state"); strings.HasPrefix(template, "/api/") && state != "" && state != string(storage.StateLive) {
+			writeError(w, http.StatusNotFound, "delivery_mode", "Delivery nodes serve live content only")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
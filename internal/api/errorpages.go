@@ -0,0 +1,225 @@
+package api
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const maxErrorPageHTML = 64 << 10
+
+// ErrorPage customizes one error response of the public content route
+type ErrorPage struct {
+	// Title and Message replace the default wording
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	// HTML replaces the built-in page for browsers. {{TITLE}}, {{MESSAGE}},
+	// {{PATH}}, {{STATUS}}, {{HOME}}, and {{LANG}} are replaced (escaped).
+	HTML string `json:"html,omitempty"`
+
+	// Fallback names a live content item ("type/id") browsers are shown
+	// instead, with the error status; it takes precedence over HTML
+	Fallback string `json:"fallback,omitempty"`
+}
+
+// ErrorPages brands the 404 and 410 responses of /content/{tenant}/{type}/{id}
+type ErrorPages struct {
+	NotFound *ErrorPage `json:"not_found,omitempty"`
+	Gone     *ErrorPage `json:"gone,omitempty"` // content that has been deleted
+
+	// HomeURL is linked from the pages (default /)
+	HomeURL string `json:"home_url,omitempty"`
+
+	// Language is the pages' lang attribute (default en)
+	Language string `json:"language,omitempty"`
+}
+
+// defaultErrorPageHTML is the built-in error page: a plain, accessible
+// document with a language, a single heading, and a way back
+const defaultErrorPageHTML = `<!DOCTYPE html>
+<html lang="{{LANG}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{TITLE}}</title>
+<style>
+body { font-family: system-ui, sans-serif; line-height: 1.5; color: #1a1a1a; background: #fff; margin: 0; }
+main { max-width: 40rem; margin: 4rem auto; padding: 0 1.5rem; }
+a { color: #0b57d0; }
+a:focus { outline: 3px solid #0b57d0; outline-offset: 2px; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{TITLE}}</h1>
+<p>{{MESSAGE}}</p>
+<p><a href="{{HOME}}">Go to the home page</a></p>
+</main>
+</body>
+</html>
+`
+
+// clone returns a deep copy (nil for nil)
+func (ep *ErrorPages) clone() *ErrorPages {
+	if ep == nil {
+		return nil
+	}
+	c := *ep
+	if ep.NotFound != nil {
+		page := *ep.NotFound
+		c.NotFound = &page
+	}
+	if ep.Gone != nil {
+		page := *ep.Gone
+		c.Gone = &page
+	}
+	return &c
+}
+
+// validate checks the pages before they are stored
+func (ep *ErrorPages) validate() error {
+	for _, named := range []struct {
+		name string
+		page *ErrorPage
+	}{{"not_found", ep.NotFound}, {"gone", ep.Gone}} {
+		name, page := named.name, named.page
+		if page == nil {
+			continue
+		}
+		if len(page.HTML) > maxErrorPageHTML {
+			return fmt.Errorf("%s html is limited to %d bytes", name, maxErrorPageHTML)
+		}
+		if page.Fallback != "" {
+			contentType, id, ok := strings.Cut(page.Fallback, "/")
+			if !ok || !validTypeName(contentType) || !storage.ValidKeyPath(id) {
+				return fmt.Errorf("%s fallback must name a content item as type/id", name)
+			}
+		}
+	}
+	return nil
+}
+
+// page returns the customization for a status (nil if none)
+func (ep *ErrorPages) page(status int) *ErrorPage {
+	if ep == nil {
+		return nil
+	}
+	if status == http.StatusGone {
+		return ep.Gone
+	}
+	return ep.NotFound
+}
+
+// wantsHTML reports whether the client prefers an HTML response, i.e. a
+// browser: Accept names text/html before any JSON type
+func wantsHTML(r *http.Request) bool {
+	accept := strings.ToLower(r.Header.Get("Accept"))
+	htmlAt := strings.Index(accept, "text/html")
+	if htmlAt < 0 {
+		return false
+	}
+	jsonAt := strings.Index(accept, "json")
+	return jsonAt < 0 || htmlAt < jsonAt
+}
+
+// missingContentStatus returns 410 for live content that has been deleted
+// and 404 for content that never existed (or isn't live)
+func (s *Server) missingContentStatus(r *http.Request, tenant, contentType, id string, state storage.State) int {
+	if state != storage.StateLive {
+		return http.StatusNotFound
+	}
+	deletedAt, deleted, err := s.storage.ContentDeletedAt(r.Context(), tenant, contentType, id)
+	if err == nil && deleted && !deletedAt.IsZero() {
+		return http.StatusGone
+	}
+	return http.StatusNotFound
+}
+
+// writeContentError answers a public content request for missing content:
+// browsers get the tenant's error page (or fallback item), and other
+// clients the usual JSON error with the tenant's wording
+func (s *Server) writeContentError(w http.ResponseWriter, r *http.Request, tenant string, status int, id string) {
+	pages := s.settings.get(r.Context(), tenant).ErrorPages
+	page := pages.page(status)
+
+	title, message, code := "Page not found", "The page you are looking for doesn't exist or has moved.", "not_found"
+	if status == http.StatusGone {
+		title, message, code = "Page removed", "The page you are looking for has been removed.", "gone"
+	}
+	if page != nil && page.Title != "" {
+		title = page.Title
+	}
+	if page != nil && page.Message != "" {
+		message = page.Message
+	}
+
+	// Error responses may be cached briefly, but content can appear at any time
+	w.Header().Set("Cache-Control", "public, max-age=60, must-revalidate")
+	w.Header().Add("Vary", "Accept")
+
+	if !wantsHTML(r) {
+		if page == nil || page.Message == "" {
+			message = fmt.Sprintf("Content '%s' not found", id)
+			if status == http.StatusGone {
+				message = fmt.Sprintf("Content '%s' has been removed", id)
+			}
+		}
+		writeError(w, status, code, message)
+		return
+	}
+
+	if page != nil && page.Fallback != "" && s.serveFallbackPage(w, r, tenant, status, page.Fallback) {
+		return
+	}
+
+	body := defaultErrorPageHTML
+	if page != nil && page.HTML != "" {
+		body = page.HTML
+	}
+	home, lang := "/", "en"
+	if pages != nil && pages.HomeURL != "" {
+		home = pages.HomeURL
+	}
+	if pages != nil && pages.Language != "" {
+		lang = pages.Language
+	}
+	body = strings.NewReplacer(
+		"{{TITLE}}", html.EscapeString(title),
+		"{{MESSAGE}}", html.EscapeString(message),
+		"{{PATH}}", html.EscapeString(r.URL.Path),
+		"{{STATUS}}", strconv.Itoa(status),
+		"{{HOME}}", html.EscapeString(home),
+		"{{LANG}}", html.EscapeString(lang),
+	).Replace(body)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	io.WriteString(w, body)
+}
+
+// serveFallbackPage serves a tenant's fallback item with an error status,
+// reporting false if it can't be read
+func (s *Server) serveFallbackPage(w http.ResponseWriter, r *http.Request, tenant string, status int, fallback string) bool {
+	contentType, id, _ := strings.Cut(fallback, "/")
+	stream, err := s.storage.FindContentStream(r.Context(), tenant, contentType, id, "", storage.StateLive)
+	if err != nil {
+		log.Error("Error page fallback %s for tenant %s is unavailable: %v", fallback, tenant, err)
+		return false
+	}
+	defer stream.Body.Close()
+
+	w.Header().Set("Content-Type", stream.ContentType)
+	w.Header().Set("Last-Modified", stream.LastModified.UTC().Format(time.RFC1123))
+	w.WriteHeader(status)
+	copyBuffered(w, stream.Body)
+	return true
+}
@@ -0,0 +1,255 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"velocity/internal/crypto"
+	"velocity/internal/log"
+	"velocity/internal/models"
+	"velocity/internal/storage"
+)
+
+// errNoFieldKeyring is returned when a schema encrypts fields and the server
+// has no key to encrypt them with
+var errNoFieldKeyring = errors.New("schema has encrypted fields but no encryption key is configured (--encryption-key or --secrets-key)")
+
+// errInvalidSealedField is returned when a write sends an encrypted field a
+// sealed envelope that doesn't open as that field
+var errInvalidSealedField = errors.New("sealed value doesn't open as this field; send the plaintext value or the envelope as it was read")
+
+// writeFieldEncryptionError writes the response for a write whose encrypted
+// fields couldn't be sealed
+func writeFieldEncryptionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errNoFieldKeyring):
+		writeError(w, http.StatusServiceUnavailable, "encryption_unavailable", err.Error())
+		return
+	case errors.Is(err, errInvalidSealedField):
+		writeError(w, http.StatusUnprocessableEntity, "invalid_sealed_field", err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "encryption_error", "Failed to encrypt fields")
+}
+
+// hasEncryptedFields reports whether any field, nested ones included, is encrypted
+func hasEncryptedFields(fields map[string]models.FieldDef) bool {
+	for _, def := range fields {
+		if def.Encrypted || hasEncryptedFields(def.Properties) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSealedValue reports whether a decoded JSON value is a sealed field
+func isSealedValue(value interface{}) bool {
+	obj, ok := value.(map[string]interface{})
+	return ok && obj["sealed"] != nil && obj["data"] != nil
+}
+
+// fieldAAD binds a sealed field to its tenant and path, so it can't be
+// opened after being copied into another field or tenant. The item ID isn't
+// bound, so content can be copied and renamed.
+func fieldAAD(tenant, path string) []byte {
+	return []byte("field:" + tenant + ":" + path)
+}
+
+// canDecrypt reports whether a request may read encrypted fields. Unlike
+// other permissions it isn't given to anonymous requests to open tenants.
+func canDecrypt(ctx context.Context) bool {
+	return allowed(ctx, permDecrypt)
+}
+
+// sealFields replaces the value of each encrypted field in a decoded document
+// with its sealed envelope, whatever its type, since writes aren't always
+// validated. Values that are already sealed are kept, so callers without
+// decrypt can send back what they read, but only once they're checked to
+// open as the same field of the same tenant; others fail with
+// errInvalidSealedField.
+func sealFields(ctx context.Context, keyring *crypto.Keyring, tenant string, fields map[string]models.FieldDef, doc map[string]interface{}, prefix string) error {
+	for name, def := range fields {
+		value, ok := doc[name]
+		if !ok || value == nil {
+			continue
+		}
+		path := prefix + name
+
+		if isSealedValue(value) {
+			if !def.Encrypted {
+				continue
+			}
+			sealed, _ := json.Marshal(value)
+			if !crypto.IsSealed(sealed) {
+				return fmt.Errorf("%s: %w", path, errInvalidSealedField)
+			}
+			if _, err := keyring.Open(ctx, sealed, fieldAAD(tenant, path)); err != nil {
+				log.Debug("Rejected sealed value of %s for tenant %s: %v", path, tenant, err)
+				return fmt.Errorf("%s: %w", path, errInvalidSealedField)
+			}
+			continue
+		}
+
+		if !def.Encrypted {
+			if obj, ok := value.(map[string]interface{}); ok && len(def.Properties) > 0 {
+				if err := sealFields(ctx, keyring, tenant, def.Properties, obj, path+"."); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		plaintext, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		sealed, err := keyring.Seal(ctx, plaintext, fieldAAD(tenant, path))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		doc[name] = json.RawMessage(sealed)
+	}
+	return nil
+}
+
+// openFields replaces each sealed encrypted field in a decoded document with
+// its value
+func openFields(ctx context.Context, keyring *crypto.Keyring, tenant string, fields map[string]models.FieldDef, doc map[string]interface{}, prefix string) error {
+	for name, def := range fields {
+		value, ok := doc[name]
+		if !ok || value == nil {
+			continue
+		}
+		path := prefix + name
+
+		if !isSealedValue(value) {
+			if obj, ok := value.(map[string]interface{}); ok && len(def.Properties) > 0 {
+				if err := openFields(ctx, keyring, tenant, def.Properties, obj, path+"."); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !def.Encrypted {
+			continue
+		}
+
+		sealed, _ := json.Marshal(value)
+		plaintext, err := keyring.Open(ctx, sealed, fieldAAD(tenant, path))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		var opened interface{}
+		if err := json.Unmarshal(plaintext, &opened); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		doc[name] = opened
+	}
+	return nil
+}
+
+// encryptFields seals a JSON document's encrypted fields before it's stored.
+// Documents of types without encrypted fields are returned unchanged.
+func (s *Server) encryptFields(ctx context.Context, tenant, contentType string, data []byte) ([]byte, error) {
+	schema := s.loadSchema(ctx, tenant, contentType)
+	if schema == nil || !hasEncryptedFields(schema.Fields) {
+		return data, nil
+	}
+	if s.config.FieldKeyring == nil {
+		return nil, errNoFieldKeyring
+	}
+
+	var doc map[string]interface{}
+	if json.Unmarshal(data, &doc) != nil {
+		return data, nil // Left to validation
+	}
+	if err := sealFields(ctx, s.config.FieldKeyring, tenant, schema.Fields, doc, ""); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// encryptBody seals the encrypted fields of a JSON body being written (see
+// encryptFields), returning the body to store and its length. The body is
+// buffered only when the schema encrypts fields.
+func (s *Server) encryptBody(ctx context.Context, tenant, contentType, mimeType string, body io.Reader, contentLength int64) (io.Reader, int64, error) {
+	if !isJSONContent(mimeType) {
+		return body, contentLength, nil
+	}
+	schema := s.loadSchema(ctx, tenant, contentType)
+	if schema == nil || !hasEncryptedFields(schema.Fields) {
+		return body, contentLength, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxValidatedSize+1))
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(data) > maxValidatedSize {
+		return nil, 0, fmt.Errorf("document exceeds %d bytes and cannot be encrypted", maxValidatedSize)
+	}
+	sealed, err := s.encryptFields(ctx, tenant, contentType, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(sealed), int64(len(sealed)), nil
+}
+
+// decryptFields opens a stored JSON document's encrypted fields for a caller
+// with the decrypt permission. Returns false, and the document as stored, if
+// it has none or the caller may not read them.
+func (s *Server) decryptFields(ctx context.Context, tenant, contentType string, data []byte) ([]byte, bool) {
+	if !canDecrypt(ctx) || s.config.FieldKeyring == nil || !bytes.Contains(data, []byte(`"sealed"`)) {
+		return data, false
+	}
+	schema := s.loadSchema(ctx, tenant, contentType)
+	if schema == nil || !hasEncryptedFields(schema.Fields) {
+		return data, false
+	}
+
+	var doc map[string]interface{}
+	if json.Unmarshal(data, &doc) != nil {
+		return data, false
+	}
+	if err := openFields(ctx, s.config.FieldKeyring, tenant, schema.Fields, doc, ""); err != nil {
+		log.Error("Failed to decrypt fields of %s content for tenant %s: %v", contentType, tenant, err)
+		return data, false
+	}
+	opened, err := json.Marshal(doc)
+	if err != nil {
+		return data, false
+	}
+	return opened, true
+}
+
+// decryptStream opens the encrypted fields of JSON content being served (see
+// decryptFields). A decrypted stream gets its own ETag, and should be served
+// with Cache-Control: private, no-store; returns whether it was decrypted.
+func (s *Server) decryptStream(ctx context.Context, tenant, contentType string, stream *storage.ContentStream) bool {
+	if !canDecrypt(ctx) || s.config.FieldKeyring == nil || !isJSONContent(stream.ContentType) || stream.Size > maxValidatedSize {
+		return false
+	}
+	schema := s.loadSchema(ctx, tenant, contentType)
+	if schema == nil || !hasEncryptedFields(schema.Fields) {
+		return false
+	}
+
+	stored, err := io.ReadAll(stream.Body)
+	stream.Body.Close()
+	if err != nil {
+		stream.Body = io.NopCloser(bytes.NewReader(nil))
+		return false
+	}
+	content, decrypted := s.decryptFields(ctx, tenant, contentType, stored)
+	if decrypted {
+		stream.ETag = fmt.Sprintf("\"%s-decrypted\"", strings.Trim(stream.ETag, "\""))
+	}
+	stream.Body = io.NopCloser(bytes.NewReader(content))
+	stream.Size = int64(len(content))
+	return decrypted
+}
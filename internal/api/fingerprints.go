@@ -0,0 +1,391 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"math/bits"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const (
+	fingerprintsCollection  = "fingerprints"
+	defaultSimilarityBits   = 3 // max differing simhash bits for near-duplicates
+	maxSimilarityBits       = 16
+	fingerprintKindSimhash  = "simhash"
+	fingerprintKindSHA256   = "sha256"
+	simhashShingleSize      = 2
+	fingerprintWriteTimeout = 10 * time.Second
+)
+
+// fingerprintRecord is a stored content fingerprint
+type fingerprintRecord struct {
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	State  string `json:"state"`
+	ETag   string `json:"etag"` // object ETag the fingerprint was computed from
+	Kind   string `json:"kind"` // simhash (text) or sha256 (binary)
+	Value  string `json:"value"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256,omitempty"` // of the content, whatever its kind (see checksums.go)
+}
+
+// fingerprintID returns the document ID for an item's fingerprint
+func fingerprintID(contentType, id string, state storage.State) string {
+	sum := sha256.Sum256([]byte(string(state) + "\x00" + contentType + "\x00" + id))
+	return hex.EncodeToString(sum[:16])
+}
+
+// simhash computes a 64-bit similarity hash over the words of text
+func simhash(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	var weights [64]int
+	h := fnv.New64a()
+	for _, word := range words {
+		h.Reset()
+		h.Write([]byte(word))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var result uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			result |= 1 << uint(bit)
+		}
+	}
+	return result
+}
+
+// fingerprint computes the fingerprint kind and value for content
+func fingerprint(content []byte, mimeType string) (string, string) {
+	if isJSONContent(mimeType) || isTextContent(mimeType) {
+		return fingerprintKindSimhash, fmt.Sprintf("%016x", simhash(string(content)))
+	}
+	sum := sha256.Sum256(content)
+	return fingerprintKindSHA256, hex.EncodeToString(sum[:])
+}
+
+// bodyFingerprint computes a fingerprint while a write body is stored
+type bodyFingerprint struct {
+	kind    string
+	value   string
+	hash    hash.Hash // set when hashing a streamed body
+	sum     string    // SHA-256 of a buffered text body
+	size    int64
+	content []byte // buffered text body, nil when streamed
+}
+
+// result returns the fingerprint once the body has been fully read
+func (fp *bodyFingerprint) result() (string, string) {
+	if fp.hash != nil {
+		return fingerprintKindSHA256, hex.EncodeToString(fp.hash.Sum(nil))
+	}
+	return fp.kind, fp.value
+}
+
+// checksum returns the SHA-256 of the body once it has been fully read
+func (fp *bodyFingerprint) checksum() string {
+	if fp.hash != nil {
+		return hex.EncodeToString(fp.hash.Sum(nil))
+	}
+	return fp.sum
+}
+
+// fingerprintWriter hashes and counts bytes passed through a tee
+type fingerprintWriter struct {
+	fp *bodyFingerprint
+}
+
+func (fw *fingerprintWriter) Write(p []byte) (int, error) {
+	fw.fp.hash.Write(p)
+	fw.fp.size += int64(len(p))
+	return len(p), nil
+}
+
+// fingerprintBody prepares a write body for fingerprinting. Text bodies up to 10MB
+// are buffered for simhash; other bodies are hashed as they stream to storage.
+func fingerprintBody(body io.Reader, mimeType string) (io.Reader, *bodyFingerprint, error) {
+	fp := &bodyFingerprint{}
+	if body == nil {
+		fp.sum = sha256Hex(nil)
+		return body, fp, nil
+	}
+
+	if isJSONContent(mimeType) || isTextContent(mimeType) {
+		data, err := io.ReadAll(io.LimitReader(body, maxValidatedSize+1))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(data) <= maxValidatedSize {
+			fp.kind, fp.value = fingerprint(data, mimeType)
+			fp.sum = sha256Hex(data)
+			fp.size = int64(len(data))
+			fp.content = data
+			return bytes.NewReader(data), fp, nil
+		}
+		body = io.MultiReader(bytes.NewReader(data), body)
+	}
+
+	fp.hash = sha256.New()
+	return io.TeeReader(body, &fingerprintWriter{fp: fp}), fp, nil
+}
+
+// storeFingerprint records the fingerprint of a completed write
+func (s *Server) storeFingerprint(tenant, contentType, id string, state storage.State, item *storage.ContentItem, fp *bodyFingerprint) {
+	kind, value := fp.result()
+	size := item.Size
+	if size <= 0 {
+		size = fp.size
+	}
+	record := &fingerprintRecord{Type: contentType, ID: id, State: string(state), ETag: item.ETag, Kind: kind, Value: value, Size: size, SHA256: fp.checksum()}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), fingerprintWriteTimeout)
+		defer cancel()
+		if err := s.putFingerprint(ctx, tenant, record); err != nil {
+			log.Error("Failed to store fingerprint for %s/%s: %v", contentType, id, err)
+		}
+	}()
+}
+
+func (s *Server) putFingerprint(ctx context.Context, tenant string, record *fingerprintRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal fingerprint: %w", err)
+	}
+	return s.storage.PutDocument(ctx, tenant, fingerprintsCollection, fingerprintID(record.Type, record.ID, storage.State(record.State)), data)
+}
+
+// deleteFingerprint removes an item's fingerprint
+func (s *Server) deleteFingerprint(ctx context.Context, tenant, contentType, id string, state storage.State) {
+	s.storage.DeleteDocument(ctx, tenant, fingerprintsCollection, fingerprintID(contentType, id, state))
+}
+
+// getFingerprint returns an item's stored fingerprint if it was computed from
+// the object with the given ETag
+func (s *Server) getFingerprint(ctx context.Context, tenant, contentType, id string, state storage.State, etag string) *fingerprintRecord {
+	data, err := s.storage.GetDocument(ctx, tenant, fingerprintsCollection, fingerprintID(contentType, id, state))
+	if err != nil {
+		return nil
+	}
+	var record fingerprintRecord
+	if json.Unmarshal(data, &record) != nil || record.ETag == "" || record.ETag != etag {
+		return nil
+	}
+	return &record
+}
+
+// loadFingerprint returns an item's fingerprint, recomputing it when missing or stale
+func (s *Server) loadFingerprint(ctx context.Context, tenant, contentType string, state storage.State, item *storage.ContentItem) *fingerprintRecord {
+	id, ext := extractIDAndExt(item.Key, contentType, state)
+
+	if record := s.getFingerprint(ctx, tenant, contentType, id, state, item.ETag); record != nil {
+		return record
+	}
+
+	content, err := s.storage.Get(ctx, tenant, contentType, id, ext, state)
+	if err != nil {
+		return nil
+	}
+	mimeType := content.ContentType
+	if mimeType == "" {
+		mimeType = mimeFromExt("." + ext)
+	}
+
+	kind, value := fingerprint(content.Content, mimeType)
+	record := &fingerprintRecord{Type: contentType, ID: id, State: string(state), ETag: item.ETag, Kind: kind, Value: value, Size: int64(len(content.Content)), SHA256: sha256Hex(content.Content)}
+	if err := s.putFingerprint(ctx, tenant, record); err != nil {
+		log.Error("Failed to store fingerprint for %s/%s: %v", contentType, id, err)
+	}
+	return record
+}
+
+// =============================================================================
+// Duplicate Report Handlers
+// =============================================================================
+
+// duplicateItem is a member of a duplicate group
+type duplicateItem struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Size int64  `json:"size"`
+}
+
+// duplicateGroup is a set of identical or near-identical items
+type duplicateGroup struct {
+	Kind        string          `json:"kind"`         // exact or near
+	MaxDistance int             `json:"max_distance"` // differing simhash bits within the group
+	Items       []duplicateItem `json:"items"`
+}
+
+// duplicatesReportHandler handles GET /api/reports/duplicates
+// Groups near-identical text (simhash) and identical binaries (SHA-256).
+// Supports ?type=, ?state= (default live), and ?threshold= (max differing bits, default 3).
+func (s *Server) duplicatesReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenant := s.getTenant(r)
+
+	state := storage.StateLive
+	if v := r.URL.Query().Get("state"); v != "" {
+		if !storage.ValidState(v) {
+			writeError(w, http.StatusBadRequest, "invalid_state", fmt.Sprintf("Invalid state: %s", v))
+			return
+		}
+		state = storage.State(v)
+	}
+
+	threshold := defaultSimilarityBits
+	if v := r.URL.Query().Get("threshold"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxSimilarityBits {
+			writeError(w, http.StatusBadRequest, "invalid_threshold", fmt.Sprintf("threshold must be between 0 and %d", maxSimilarityBits))
+			return
+		}
+		threshold = n
+	}
+
+	types := splitList(r.URL.Query().Get("type"))
+	if len(types) == 0 {
+		var err error
+		if types, err = s.storage.ListContentTypes(ctx, tenant); err != nil {
+			writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+			return
+		}
+	}
+
+	var text []*fingerprintRecord
+	binaries := make(map[string][]*fingerprintRecord)
+	scanned := 0
+	for _, contentType := range types {
+		items, err := s.storage.List(ctx, tenant, contentType, state)
+		if err != nil {
+			continue
+		}
+		for _, item := range items {
+			record := s.loadFingerprint(ctx, tenant, contentType, state, item)
+			if record == nil {
+				continue
+			}
+			scanned++
+			if record.Kind == fingerprintKindSimhash {
+				text = append(text, record)
+			} else {
+				binaries[record.Value] = append(binaries[record.Value], record)
+			}
+		}
+	}
+
+	groups := []duplicateGroup{}
+
+	// Identical binaries
+	for _, records := range binaries {
+		if len(records) > 1 {
+			groups = append(groups, newDuplicateGroup("exact", 0, records))
+		}
+	}
+
+	// Near-identical text: cluster items within the threshold (union-find)
+	hashes := make([]uint64, len(text))
+	for i, record := range text {
+		hashes[i], _ = strconv.ParseUint(record.Value, 16, 64)
+	}
+	parent := make([]int, len(text))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range text {
+		for j := i + 1; j < len(text); j++ {
+			if bits.OnesCount64(hashes[i]^hashes[j]) <= threshold {
+				parent[find(i)] = find(j)
+			}
+		}
+	}
+	clusters := make(map[int][]int)
+	for i := range text {
+		clusters[find(i)] = append(clusters[find(i)], i)
+	}
+	for _, members := range clusters {
+		if len(members) < 2 {
+			continue
+		}
+		maxDistance := 0
+		records := make([]*fingerprintRecord, len(members))
+		for a, i := range members {
+			records[a] = text[i]
+			for _, j := range members[a+1:] {
+				if d := bits.OnesCount64(hashes[i] ^ hashes[j]); d > maxDistance {
+					maxDistance = d
+				}
+			}
+		}
+		kind := "near"
+		if maxDistance == 0 {
+			kind = "exact"
+		}
+		groups = append(groups, newDuplicateGroup(kind, maxDistance, records))
+	}
+
+	// Largest groups first
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i].Items) != len(groups[j].Items) {
+			return len(groups[i].Items) > len(groups[j].Items)
+		}
+		return groups[i].Items[0].Type+"/"+groups[i].Items[0].ID < groups[j].Items[0].Type+"/"+groups[j].Items[0].ID
+	})
+
+	duplicates := 0
+	for _, group := range groups {
+		duplicates += len(group.Items) - 1
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"state":      state,
+		"threshold":  threshold,
+		"scanned":    scanned,
+		"groups":     groups,
+		"count":      len(groups),
+		"duplicates": duplicates,
+	})
+}
+
+func newDuplicateGroup(kind string, maxDistance int, records []*fingerprintRecord) duplicateGroup {
+	group := duplicateGroup{Kind: kind, MaxDistance: maxDistance}
+	for _, record := range records {
+		group.Items = append(group.Items, duplicateItem{Type: record.Type, ID: record.ID, Size: record.Size})
+	}
+	sort.Slice(group.Items, func(i, j int) bool {
+		return group.Items[i].Type+"/"+group.Items[i].ID < group.Items[j].Type+"/"+group.Items[j].ID
+	})
+	return group
+}
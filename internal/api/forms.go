@@ -0,0 +1,306 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/models"
+	"velocity/internal/storage"
+)
+
+const (
+	submissionsType       = "submissions"
+	maxSubmissionSize     = 64 << 10
+	defaultFormRateLimit  = 10 // Submissions per client per minute
+	formRateWindow        = time.Minute
+	maxTrackedFormClients = 10000
+	submissionState       = storage.StatePending // Never served by the public content route
+)
+
+// Form accepts public submissions at /submit/{tenant}/{form}
+type Form struct {
+	// Schema validates submissions (default: the schema named like the form)
+	Schema string `json:"schema,omitempty"`
+
+	// Redirect is where browsers posting an HTML form are sent afterwards
+	Redirect string `json:"redirect,omitempty"`
+
+	// RateLimit caps submissions per client per minute (default 10)
+	RateLimit int `json:"rate_limit,omitempty"`
+}
+
+// validateForms checks forms before they are stored
+func validateForms(forms map[string]*Form) error {
+	for name, form := range forms {
+		if !validTypeName(name) {
+			return fmt.Errorf("form name %q must be letters, digits, and '-'", name)
+		}
+		if form == nil {
+			return fmt.Errorf("form %q needs a definition (e.g. {\"schema\": \"contact\"})", name)
+		}
+		if form.Schema != "" && !validTypeName(form.Schema) {
+			return fmt.Errorf("form %q: schema must be a schema name", name)
+		}
+		if form.Redirect != "" {
+			u, err := url.Parse(form.Redirect)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("form %q: redirect must be an http or https URL", name)
+			}
+		}
+		if form.RateLimit < 0 {
+			return fmt.Errorf("form %q: rate_limit must be 0 (default) or more", name)
+		}
+	}
+	return nil
+}
+
+// schemaName returns the name of the schema that validates a form's submissions
+func (f *Form) schemaName(name string) string {
+	if f.Schema != "" {
+		return f.Schema
+	}
+	return name
+}
+
+// rateLimit returns a form's submissions per client per minute
+func (f *Form) rateLimit() int {
+	if f.RateLimit > 0 {
+		return f.RateLimit
+	}
+	return defaultFormRateLimit
+}
+
+// formWindow counts a client's submissions since the window started
+type formWindow struct {
+	start time.Time
+	count int
+}
+
+// formLimiter rate limits submissions per tenant, form, and client address
+// (see clientIP) in fixed one-minute windows. Counts are kept in memory on
+// each node and aren't shared, so the limit applies per node: a client spread
+// across N nodes by a load balancer may submit up to N times the limit.
+type formLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*formWindow
+}
+
+func newFormLimiter() *formLimiter {
+	return &formLimiter{windows: make(map[string]*formWindow)}
+}
+
+// allow counts a submission, and returns false with how long until the
+// client may submit again once it's over the limit
+func (l *formLimiter) allow(key string, limit int) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if len(l.windows) >= maxTrackedFormClients {
+		for k, window := range l.windows {
+			if now.Sub(window.start) >= formRateWindow {
+				delete(l.windows, k)
+			}
+		}
+	}
+
+	window, ok := l.windows[key]
+	if !ok || now.Sub(window.start) >= formRateWindow {
+		window = &formWindow{start: now}
+		l.windows[key] = window
+	}
+	if window.count >= limit {
+		return false, formRateWindow - now.Sub(window.start)
+	}
+	window.count++
+	return true, 0
+}
+
+// newSubmissionID returns an ID for a form's submission that sorts by time
+func newSubmissionID(form string) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return form + "/" + time.Now().UTC().Format("20060102T150405.000000Z") + "-" + hex.EncodeToString(b), nil
+}
+
+// isHTMLForm reports whether a request is a browser posting an HTML form
+func isHTMLForm(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data"
+}
+
+// parseSubmission decodes a submission sent as JSON or as an HTML form. Form
+// values are converted to the schema's number, integer, and boolean types,
+// and fields given more than once become arrays.
+func parseSubmission(r *http.Request, schema *models.Schema) (map[string]interface{}, error) {
+	if !isHTMLForm(r) {
+		var doc map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+			return nil, fmt.Errorf("submission must be a JSON object or an HTML form")
+		}
+		return doc, nil
+	}
+
+	if err := r.ParseMultipartForm(maxSubmissionSize); err != nil && err != http.ErrNotMultipart {
+		return nil, fmt.Errorf("invalid form data")
+	}
+	doc := make(map[string]interface{})
+	for name, values := range r.PostForm {
+		def := schema.Fields[name]
+		if len(values) == 1 && def.Type != "array" {
+			doc[name] = formValue(def.Type, values[0])
+			continue
+		}
+		items := make([]interface{}, len(values))
+		for i, value := range values {
+			items[i] = formValue(def.Items, value)
+		}
+		doc[name] = items
+	}
+	return doc, nil
+}
+
+// formValue converts an HTML form value to a schema field type, leaving it a
+// string when it doesn't parse so validation reports it
+func formValue(fieldType, value string) interface{} {
+	switch fieldType {
+	case "number", "integer":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		switch value {
+		case "on", "true", "1":
+			return true
+		case "false", "0":
+			return false
+		}
+	}
+	return value
+}
+
+// triggerSubmitted fires form.submitted webhooks for a stored submission
+func (s *Server) triggerSubmitted(tenant, form, id, name string) {
+	s.recentWrites.mark(tenant, submissionsType, id)
+	s.dispatchEvent(storage.WebhookEvent{
+		Event:       "form.submitted",
+		Tenant:      tenant,
+		Type:        submissionsType,
+		ID:          id,
+		Name:        name,
+		ContentType: "application/json",
+		State:       string(submissionState),
+		Metadata:    map[string]string{"form": form},
+	})
+}
+
+// storeSubmission keeps a submission's fields the schema defines, seals its
+// encrypted fields (by the submissions schema), and stores it
+func (s *Server) storeSubmission(ctx context.Context, tenant, form string, schema *models.Schema, doc map[string]interface{}) (*storage.ContentItem, error) {
+	for name := range doc {
+		if _, ok := schema.Fields[name]; !ok {
+			delete(doc, name)
+		}
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = s.encryptFields(ctx, tenant, submissionsType, data); err != nil {
+		return nil, err
+	}
+
+	id, err := newSubmissionID(form)
+	if err != nil {
+		return nil, err
+	}
+	metadata := map[string]string{"form": form}
+	return s.storage.PutStream(ctx, tenant, submissionsType, id, "json", bytes.NewReader(data), int64(len(data)), "application/json", submissionState, metadata)
+}
+
+// submitFormHandler handles POST /submit/{tenant}/{form}
+// Public and rate limited. Accepts JSON or an HTML form; see Form. Answers
+// CORS preflights (OPTIONS) too.
+func (s *Server) submitFormHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenant, name := vars["tenant"], vars["form"]
+
+	// Static sites on any origin may submit with fetch
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "POST")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	form := s.settings.get(r.Context(), tenant).Forms[name]
+	if form == nil {
+		writeError(w, http.StatusNotFound, "form_not_found", fmt.Sprintf("Form '%s' not found", name))
+		return
+	}
+	if ok, retry := s.forms.allow(tenant+"/"+name+"/"+s.clientIP(r), form.rateLimit()); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())+1))
+		writeError(w, http.StatusTooManyRequests, "rate_limited", "Too many submissions; try again later")
+		return
+	}
+
+	schema := s.loadSchema(r.Context(), tenant, form.schemaName(name))
+	if schema == nil {
+		log.Error("Form %s of tenant %s has no schema %s", name, tenant, form.schemaName(name))
+		writeError(w, http.StatusServiceUnavailable, "form_unavailable", fmt.Sprintf("Form '%s' isn't accepting submissions", name))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSubmissionSize)
+	doc, err := parseSubmission(r, schema)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_submission", err.Error())
+		return
+	}
+	if errs := validateFields(schema.Fields, doc, ""); len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	item, err := s.storeSubmission(r.Context(), tenant, name, schema, doc)
+	if errors.Is(err, errInvalidSealedField) {
+		writeError(w, http.StatusBadRequest, "invalid_submission", err.Error())
+		return
+	}
+	if err != nil {
+		log.Error("Failed to store submission to form %s of tenant %s: %v", name, tenant, err)
+		writeError(w, http.StatusInternalServerError, "storage_error", "Failed to store the submission")
+		return
+	}
+	id, _ := extractIDAndExt(item.Key, submissionsType, submissionState)
+	log.Debug("Received submission %s to form %s of tenant %s", id, name, tenant)
+	s.triggerSubmitted(tenant, name, id, filepath.Base(item.Key))
+
+	if form.Redirect != "" && isHTMLForm(r) {
+		http.Redirect(w, r, form.Redirect, http.StatusSeeOther)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"id":      id,
+		"form":    name,
+		"message": "Submission received",
+	})
+}
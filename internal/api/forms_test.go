@@ -0,0 +1,40 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFormSubmissionsAreRateLimitedByClient(t *testing.T) {
+	s := newTestServer(t)
+	requireStatus(t, serve(s, "acme", "", "PUT", "/api/tenant/schemas/contact", `{"name":"contact","fields":{"name":{"type":"string"}}}`), http.StatusOK, "PUT schema")
+	requireStatus(t, serve(s, "acme", "", "PUT", "/api/tenant/settings", `{"forms":{"contact":{"rate_limit":2}}}`), http.StatusOK, "PUT settings")
+
+	submit := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/submit/acme/contact", strings.NewReader(`{"name":"Alice"}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		s.Handler().ServeHTTP(w, r)
+		return w
+	}
+
+	// A client can't get past the limit by making up X-Forwarded-For addresses
+	for i := 1; i <= 2; i++ {
+		requireStatus(t, submit("203.0.113.7:1234", fmt.Sprintf("198.51.100.%d", i)), http.StatusCreated, fmt.Sprintf("submission %d", i))
+	}
+	w := submit("203.0.113.7:1234", "198.51.100.3")
+	requireStatus(t, w, http.StatusTooManyRequests, "submission over the limit")
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("rate limited response has no Retry-After")
+	}
+
+	// Other clients have their own limit
+	requireStatus(t, submit("203.0.113.8:1234", ""), http.StatusCreated, "another client's submission")
+}
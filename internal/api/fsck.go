@@ -0,0 +1,335 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"velocity/internal/storage"
+)
+
+const (
+	fsckJobKind  = "fsck"
+	fsckReportID = "fsck"
+)
+
+// fsck issue kinds
+const (
+	fsckOrphanedComments  = "orphaned_comments"  // comments on content that does not exist in that state
+	fsckOrphanedHistory   = "orphaned_history"   // history record whose version no longer exists
+	fsckStaleIndexEntry   = "stale_index_entry"  // _index.json entry with no matching item or folder
+	fsckExtensionConflict = "extension_conflict" // one ID stored under several extensions
+	fsckExtensionMismatch = "extension_mismatch" // stored Content-Type does not match the extension
+)
+
+// fsckIssue is one inconsistency found by a consistency check
+type fsckIssue struct {
+	Kind     string `json:"kind"`
+	Type     string `json:"type"`
+	ID       string `json:"id,omitempty"`
+	State    string `json:"state,omitempty"`
+	Detail   string `json:"detail"`
+	Repaired bool   `json:"repaired"`
+}
+
+// fsckReport is the stored result of a consistency check
+type fsckReport struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Root        string         `json:"root"`
+	Repair      bool           `json:"repair"`
+	Checked     int            `json:"checked"` // content items checked
+	Issues      []fsckIssue    `json:"issues"`
+	Counts      map[string]int `json:"counts"`
+	Repaired    int            `json:"repaired"`
+}
+
+func (r *fsckReport) add(issue fsckIssue) {
+	r.Issues = append(r.Issues, issue)
+	r.Counts[issue.Kind]++
+	if issue.Repaired {
+		r.Repaired++
+	}
+}
+
+// isSystemPath reports whether an ID refers to a system object (_index.json, _comments, ...)
+func isSystemPath(id string) bool {
+	for _, segment := range strings.Split(id, "/") {
+		if strings.HasPrefix(segment, "_") {
+			return true
+		}
+	}
+	return false
+}
+
+// baseMimeType strips parameters such as charset from a MIME type
+func baseMimeType(value string) string {
+	if mediaType, _, err := mime.ParseMediaType(value); err == nil {
+		return mediaType
+	}
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// runFsck checks a tenant's content root for drift, repairing what it can
+func (s *Server) runFsck(ctx context.Context, j *job, tenant, root string, repair bool) (*fsckReport, error) {
+	ctx = storage.WithRoot(ctx, root)
+	report := &fsckReport{Root: root, Repair: repair, Issues: []fsckIssue{}, Counts: make(map[string]int)}
+
+	types, err := s.storage.ListContentTypes(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list content types: %w", err)
+	}
+
+	for i, contentType := range types {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		j.setProgress(i, len(types))
+		j.setMessage(fmt.Sprintf("checking %s", contentType))
+
+		// Index items per state: id -> extensions
+		exts := make(map[storage.State]map[string][]string)
+		for _, state := range contentStates {
+			exts[state] = make(map[string][]string)
+			items, err := s.storage.List(ctx, tenant, contentType, state)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %s (%s): %w", contentType, state, err)
+			}
+			for _, item := range items {
+				id, ext := extractIDAndExt(item.Key, contentType, state)
+				if isSystemPath(id) {
+					continue
+				}
+				exts[state][id] = append(exts[state][id], ext)
+				report.Checked++
+			}
+		}
+
+		// Extension conflicts and Content-Type mismatches (report only)
+		for _, state := range contentStates {
+			for id, list := range exts[state] {
+				if len(list) > 1 {
+					sort.Strings(list)
+					report.add(fsckIssue{Kind: fsckExtensionConflict, Type: contentType, ID: id, State: string(state),
+						Detail: fmt.Sprintf("stored as .%s", strings.Join(list, ", ."))})
+				}
+				for _, ext := range list {
+					expected := mimeFromExt("." + strings.ToLower(ext))
+					if expected == "application/octet-stream" {
+						continue
+					}
+					stream, err := s.storage.GetStream(ctx, tenant, contentType, id, ext, state)
+					if err != nil {
+						continue
+					}
+					stream.Body.Close()
+					actual := baseMimeType(stream.ContentType)
+					if actual != "" && actual != "application/octet-stream" && actual != expected {
+						report.add(fsckIssue{Kind: fsckExtensionMismatch, Type: contentType, ID: id + "." + ext, State: string(state),
+							Detail: fmt.Sprintf("Content-Type is %s, extension implies %s", actual, expected)})
+					}
+				}
+			}
+		}
+
+		// Comments on content that no longer exists in that state
+		for _, state := range contentStates {
+			ids, err := s.storage.ListCommentedIDs(ctx, tenant, contentType, state)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list comments for %s: %w", contentType, err)
+			}
+			for _, id := range ids {
+				if _, ok := exts[state][id]; ok {
+					continue
+				}
+				issue := fsckIssue{Kind: fsckOrphanedComments, Type: contentType, ID: id, State: string(state),
+					Detail: fmt.Sprintf("comments on missing %s content", state)}
+				if repair {
+					issue.Repaired = s.storage.DeleteAllComments(ctx, tenant, contentType, id, state) == nil
+				}
+				report.add(issue)
+			}
+		}
+
+		// History records whose version no longer exists. History of deleted
+		// content is left to garbage collection.
+		historyIDs, err := s.storage.ListHistoryIDs(ctx, tenant, contentType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list history for %s: %w", contentType, err)
+		}
+		for _, id := range historyIDs {
+			list, ok := exts[storage.StateLive][id]
+			if !ok {
+				continue
+			}
+			versions, err := s.storage.ListVersions(ctx, tenant, contentType, id, list[0])
+			if err != nil {
+				continue
+			}
+			existing := make(map[string]bool, len(versions))
+			for _, v := range versions {
+				existing[v.VersionID] = true
+			}
+			records, err := s.storage.ListHistoryRecords(ctx, tenant, contentType, id)
+			if err != nil {
+				continue
+			}
+			for _, record := range records {
+				if existing[record.Version] {
+					continue
+				}
+				issue := fsckIssue{Kind: fsckOrphanedHistory, Type: contentType, ID: id,
+					Detail: fmt.Sprintf("history record for missing version %s", record.Version)}
+				if repair {
+					issue.Repaired = s.storage.DeleteHistoryRecord(ctx, tenant, contentType, id, record.Version) == nil
+				}
+				report.add(issue)
+			}
+		}
+
+		// Directory index entries without an item or folder
+		for _, state := range contentStates {
+			if err := s.checkIndexes(ctx, tenant, contentType, state, "", repair, report); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	report.GeneratedAt = time.Now().UTC()
+	return report, nil
+}
+
+// checkIndexes walks a folder tree and removes _index.json entries that name nothing
+func (s *Server) checkIndexes(ctx context.Context, tenant, contentType string, state storage.State, prefix string, repair bool, report *fsckReport) error {
+	result, err := s.storage.Browse(ctx, tenant, contentType, prefix, state)
+	if err != nil {
+		return fmt.Errorf("failed to browse %s/%s: %w", contentType, prefix, err)
+	}
+
+	if index, err := s.storage.GetDirectoryIndex(ctx, tenant, contentType, prefix, state); err == nil && index != nil {
+		names := make(map[string]bool)
+		for _, folder := range result.Folders {
+			names[folder] = true
+		}
+		for _, item := range result.Items {
+			names[storageItemName(item.Key)] = true
+		}
+
+		var kept []string
+		for _, entry := range index.Order {
+			if names[entry] {
+				kept = append(kept, entry)
+				continue
+			}
+			report.add(fsckIssue{Kind: fsckStaleIndexEntry, Type: contentType, ID: strings.TrimSuffix(prefix, "/"), State: string(state),
+				Detail: fmt.Sprintf("index entry '%s' has no item or folder", entry), Repaired: repair})
+		}
+		if repair && len(kept) != len(index.Order) {
+			index.Order = kept
+			if err := s.storage.PutDirectoryIndex(ctx, tenant, contentType, prefix, state, index); err != nil {
+				return fmt.Errorf("failed to repair index for %s/%s: %w", contentType, prefix, err)
+			}
+		}
+	}
+
+	for _, folder := range result.Folders {
+		child := folder
+		if prefix != "" {
+			child = strings.TrimSuffix(prefix, "/") + "/" + folder
+		}
+		if err := s.checkIndexes(ctx, tenant, contentType, state, child, repair, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storageItemName returns the base name of a key without its extension
+func storageItemName(key string) string {
+	name := key[strings.LastIndex(key, "/")+1:]
+	if dot := strings.LastIndex(name, "."); dot != -1 {
+		name = name[:dot]
+	}
+	return name
+}
+
+// =============================================================================
+// Consistency Check Handlers
+// =============================================================================
+
+// startFsckHandler handles POST /api/admin/fsck
+// Checks the active root (or X-Content-Root) in the background; ?repair=true fixes what it can.
+func (s *Server) startFsckHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	repair := r.URL.Query().Get("repair") == "true"
+
+	root := storage.RootFromContext(r.Context())
+	if root == "" {
+		root = s.roots.ActiveRoot(r.Context(), tenant).Active
+	}
+
+	j, err := s.jobs.start(tenant, fsckJobKind, func(ctx context.Context, j *job) (interface{}, error) {
+		report, err := s.runFsck(ctx, j, tenant, root, repair)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(report)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal report: %w", err)
+		}
+		if err := s.storage.PutDocument(ctx, tenant, reportsCollection, fsckReportID, data); err != nil {
+			return nil, err
+		}
+		return map[string]int{"checked": report.Checked, "issues": len(report.Issues), "repaired": report.Repaired}, nil
+	})
+	if err == errJobRunning {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":   "job_running",
+			"message": "A consistency check is already running",
+			"job":     j.info(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"job":     j.info(),
+		"root":    root,
+		"message": "Consistency check started",
+	})
+}
+
+// fsckReportHandler handles GET /api/admin/fsck
+// Returns the most recent report, plus the status of a check in progress.
+func (s *Server) fsckReportHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+
+	var running *jobInfo
+	if j, ok := s.jobs.latest(tenant, fsckJobKind); ok && j.running() {
+		running = j.info()
+	}
+
+	data, err := s.storage.GetDocument(r.Context(), tenant, reportsCollection, fsckReportID)
+	if err != nil {
+		if running != nil {
+			writeJSON(w, http.StatusAccepted, map[string]interface{}{"job": running})
+			return
+		}
+		writeError(w, http.StatusNotFound, "not_found", "No consistency report yet; POST to /api/admin/fsck to generate one")
+		return
+	}
+
+	var report fsckReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", "Failed to parse stored report")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"report": &report,
+		"job":    running,
+	})
+}
@@ -0,0 +1,390 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const (
+	gcJobKind          = "gc"
+	gcReportID         = "gc"
+	defaultGCRetention = 30 * 24 * time.Hour
+)
+
+// gc categories
+const (
+	gcHistory      = "history"      // history records of deleted content
+	gcFingerprints = "fingerprints" // fingerprints of content that no longer exists
+	gcSearch       = "search"       // search records of content that no longer exists
+	gcTombstones   = "tombstones"   // deletion records for the cache manifest
+	gcChanges      = "changes"      // entries of the changes feed
+	gcVersions     = "versions"     // versions of live content past the retention policy
+)
+
+// gcCategory counts what was removed for one kind of derived data
+type gcCategory struct {
+	Removed int   `json:"removed"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// gcReport is the stored result of a garbage collection run
+type gcReport struct {
+	GeneratedAt    time.Time              `json:"generated_at"`
+	Retention      string                 `json:"retention"`
+	DryRun         bool                   `json:"dry_run"`
+	Categories     map[string]*gcCategory `json:"categories"`
+	Removed        int                    `json:"removed"`
+	ReclaimedBytes int64                  `json:"reclaimed_bytes"`
+}
+
+func (r *gcReport) add(category string, bytes int64) {
+	c, ok := r.Categories[category]
+	if !ok {
+		c = &gcCategory{}
+		r.Categories[category] = c
+	}
+	c.Removed++
+	c.Bytes += bytes
+	r.Removed++
+	r.ReclaimedBytes += bytes
+}
+
+// gcItemKey identifies a content item in a state
+func gcItemKey(contentType, id string, state storage.State) string {
+	return string(state) + "\x00" + contentType + "\x00" + id
+}
+
+// runGC removes derived data whose parent content has been deleted for longer
+// than retention, across both content roots. With dryRun set nothing is deleted.
+func (s *Server) runGC(ctx context.Context, j *job, tenant string, retention time.Duration, dryRun bool) (*gcReport, error) {
+	report := &gcReport{Retention: retention.String(), DryRun: dryRun, Categories: map[string]*gcCategory{
+		gcHistory:      {},
+		gcFingerprints: {},
+		gcSearch:       {},
+		gcTombstones:   {},
+		gcVersions:     {},
+	}}
+	cutoff := time.Now().Add(-retention)
+	settings := s.settings.get(ctx, tenant)
+
+	// Items that exist in any root, for fingerprints
+	existing := make(map[string]bool)
+
+	roots := []string{storage.RootBlue, storage.RootGreen}
+	for i, root := range roots {
+		rctx := storage.WithRoot(ctx, root)
+		j.setProgress(i, len(roots)+1)
+		j.setMessage(fmt.Sprintf("collecting %s root", root))
+
+		types, err := s.storage.ListContentTypes(rctx, tenant)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list content types: %w", err)
+		}
+
+		for _, contentType := range types {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			// Versions expire by age between writes, so the retention
+			// policy is applied to every live item
+			vctx := rctx
+			if retention, ok := settings.versionRetention(contentType); ok {
+				vctx = storage.WithVersionRetention(rctx, retention)
+			}
+
+			present := make(map[string]bool)
+			for _, state := range contentStates {
+				items, err := s.storage.List(rctx, tenant, contentType, state)
+				if err != nil {
+					return nil, fmt.Errorf("failed to list %s (%s): %w", contentType, state, err)
+				}
+				for _, item := range items {
+					id, ext := extractIDAndExt(item.Key, contentType, state)
+					present[id] = true
+					existing[gcItemKey(contentType, id, state)] = true
+
+					if state != storage.StateLive {
+						continue
+					}
+					pruned, err := s.storage.PruneVersions(vctx, tenant, contentType, id, ext, dryRun)
+					if err != nil {
+						log.Error("GC failed to prune versions of %s/%s: %v", contentType, id, err)
+						continue
+					}
+					for _, v := range pruned {
+						report.add(gcVersions, v.Size)
+					}
+				}
+			}
+
+			// History of content deleted before the cutoff
+			historyIDs, err := s.storage.ListHistoryIDs(rctx, tenant, contentType)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list history for %s: %w", contentType, err)
+			}
+			for _, id := range historyIDs {
+				if present[id] {
+					continue
+				}
+				deletedAt, deleted, err := s.storage.ContentDeletedAt(rctx, tenant, contentType, id)
+				if err != nil || !deleted || deletedAt.After(cutoff) {
+					continue
+				}
+				records, err := s.storage.ListHistoryRecords(rctx, tenant, contentType, id)
+				if err != nil {
+					continue
+				}
+				for _, record := range records {
+					data, _ := json.Marshal(record)
+					if !dryRun {
+						if err := s.storage.DeleteHistoryRecord(rctx, tenant, contentType, id, record.Version); err != nil {
+							log.Error("GC failed to delete history %s/%s@%s: %v", contentType, id, record.Version, err)
+							continue
+						}
+					}
+					report.add(gcHistory, int64(len(data)))
+				}
+			}
+		}
+	}
+
+	// Fingerprints are recomputed on demand, so orphans are removed as soon as
+	// their content is gone from every root
+	j.setProgress(len(roots), len(roots)+1)
+	j.setMessage("collecting fingerprints")
+	ids, err := s.storage.ListDocuments(ctx, tenant, fingerprintsCollection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fingerprints: %w", err)
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		data, err := s.storage.GetDocument(ctx, tenant, fingerprintsCollection, id)
+		if err != nil {
+			continue
+		}
+		var record fingerprintRecord
+		if json.Unmarshal(data, &record) == nil && existing[gcItemKey(record.Type, record.ID, storage.State(record.State))] {
+			continue
+		}
+		if !dryRun {
+			if err := s.storage.DeleteDocument(ctx, tenant, fingerprintsCollection, id); err != nil {
+				log.Error("GC failed to delete fingerprint %s: %v", id, err)
+				continue
+			}
+		}
+		report.add(gcFingerprints, int64(len(data)))
+	}
+
+	// Search records name their item, so orphans are found without reading them
+	j.setMessage("collecting search records")
+	searchIDs, err := s.storage.ListDocuments(ctx, tenant, searchCollection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list search records: %w", err)
+	}
+	for _, id := range searchIDs {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		contentType, itemID, state, ok := parseSearchDocID(id)
+		if ok && existing[gcItemKey(contentType, itemID, state)] {
+			continue
+		}
+		var size int64
+		if data, err := s.storage.GetDocument(ctx, tenant, searchCollection, id); err == nil {
+			size = int64(len(data))
+		}
+		if !dryRun {
+			if err := s.storage.DeleteDocument(ctx, tenant, searchCollection, id); err != nil {
+				log.Error("GC failed to delete search record %s: %v", id, err)
+				continue
+			}
+		}
+		report.add(gcSearch, size)
+	}
+
+	// Deletion records only matter to manifests requested since their day
+	tombstones, err := s.storage.ListDocuments(ctx, tenant, tombstonesCollection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deletion records: %w", err)
+	}
+	cutoffDay := cutoff.UTC().Format("2006-01-02")
+	for _, id := range tombstones {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if day, _, _ := strings.Cut(id, "/"); day >= cutoffDay {
+			continue
+		}
+		if !dryRun {
+			if err := s.storage.DeleteDocument(ctx, tenant, tombstonesCollection, id); err != nil {
+				log.Error("GC failed to delete deletion record %s: %v", id, err)
+				continue
+			}
+		}
+		report.add(gcTombstones, 0)
+	}
+
+	// Changes feed entries older than the retention can't be read with a valid token
+	changes, err := s.storage.ListDocuments(ctx, tenant, changesCollection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changes: %w", err)
+	}
+	for _, id := range changes {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if at, ok := changeTime(id); ok && !at.Before(cutoff) {
+			continue
+		}
+		if !dryRun {
+			if err := s.storage.DeleteDocument(ctx, tenant, changesCollection, id); err != nil {
+				log.Error("GC failed to delete change %s: %v", id, err)
+				continue
+			}
+		}
+		report.add(gcChanges, 0)
+	}
+
+	report.GeneratedAt = time.Now().UTC()
+	return report, nil
+}
+
+// startGC starts a garbage collection job for a tenant
+func (s *Server) startGC(tenant string, retention time.Duration, dryRun bool) (*job, error) {
+	return s.jobs.start(tenant, gcJobKind, func(ctx context.Context, j *job) (interface{}, error) {
+		report, err := s.runGC(ctx, j, tenant, retention, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(report)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal report: %w", err)
+		}
+		if err := s.storage.PutDocument(ctx, tenant, reportsCollection, gcReportID, data); err != nil {
+			return nil, err
+		}
+		if report.Removed > 0 {
+			log.Info("GC for tenant %s removed %d objects (%d bytes)", tenant, report.Removed, report.ReclaimedBytes)
+		}
+		return map[string]interface{}{"removed": report.Removed, "reclaimed_bytes": report.ReclaimedBytes, "dry_run": dryRun}, nil
+	})
+}
+
+// gcRetention returns the configured retention period
+func (s *Server) gcRetention() time.Duration {
+	if s.config.GCRetention > 0 {
+		return s.config.GCRetention
+	}
+	return defaultGCRetention
+}
+
+// gcLoop runs garbage collection for every tenant on a fixed interval. Only
+// the cluster leader starts runs.
+func (s *Server) gcLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.scheduleGC()
+	}
+}
+
+func (s *Server) scheduleGC() {
+	if !s.leader.isLeader() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	tenants, err := s.storage.ListTenants(ctx)
+	if err != nil {
+		log.Error("GC schedule error: %v", err)
+		return
+	}
+	for _, tenant := range tenants {
+		if _, err := s.startGC(tenant, s.gcRetention(), false); err != nil && err != errJobRunning {
+			log.Error("Failed to start GC for tenant %s: %v", tenant, err)
+		}
+	}
+}
+
+// =============================================================================
+// Garbage Collection Handlers
+// =============================================================================
+
+// startGCHandler handles POST /api/admin/gc
+// ?dry_run=true reports what would be removed; ?retention= overrides the
+// configured retention period (e.g. 168h).
+func (s *Server) startGCHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	retention := s.gcRetention()
+	if value := r.URL.Query().Get("retention"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			writeError(w, http.StatusBadRequest, "invalid_retention", fmt.Sprintf("Invalid retention: %s (expected a duration such as 168h)", value))
+			return
+		}
+		retention = d
+	}
+
+	j, err := s.startGC(tenant, retention, dryRun)
+	if err == errJobRunning {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":   "job_running",
+			"message": "Garbage collection is already running",
+			"job":     j.info(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"job":       j.info(),
+		"retention": retention.String(),
+		"message":   "Garbage collection started",
+	})
+}
+
+// gcReportHandler handles GET /api/admin/gc
+// Returns the most recent report, plus the status of a run in progress.
+func (s *Server) gcReportHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+
+	var running *jobInfo
+	if j, ok := s.jobs.latest(tenant, gcJobKind); ok && j.running() {
+		running = j.info()
+	}
+
+	data, err := s.storage.GetDocument(r.Context(), tenant, reportsCollection, gcReportID)
+	if err != nil {
+		if running != nil {
+			writeJSON(w, http.StatusAccepted, map[string]interface{}{"job": running})
+			return
+		}
+		writeError(w, http.StatusNotFound, "not_found", "No garbage collection report yet; POST to /api/admin/gc to generate one")
+		return
+	}
+
+	var report gcReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", "Failed to parse stored report")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"report": &report,
+		"job":    running,
+	})
+}
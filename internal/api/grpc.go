@@ -0,0 +1,452 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"velocity/internal/models"
+	"velocity/internal/storage"
+	"velocity/velocitypb"
+)
+
+// grpcChunkSize is the size of the body chunks streamed by Get
+const grpcChunkSize = 64 << 10
+
+// grpcForwardedMetadata is the request metadata passed on as headers to the
+// API routes that serve writes, credentials included so they're checked there
+// as they are over HTTP
+var grpcForwardedMetadata = []string{"x-tenant", "x-api-key", "authorization", "idempotency-key", "x-content-root"}
+
+// grpcServer implements the Content gRPC service. Reads go to storage
+// directly; writes run the HTTP API's handlers in process, so plugin hooks,
+// schema validation, publish policies, and webhooks apply exactly as they do
+// over HTTP.
+type grpcServer struct {
+	velocitypb.UnimplementedContentServer
+	s *Server
+}
+
+// GRPCServer returns a gRPC server exposing the core content operations.
+// Every call is authenticated like an API request (see authenticate) and
+// needs the read permission; writes are checked further by the API routes.
+func (s *Server) GRPCServer() *grpc.Server {
+	gs := grpc.NewServer(
+		grpc.UnaryInterceptor(s.grpcUnaryAuth),
+		grpc.StreamInterceptor(s.grpcStreamAuth),
+	)
+	velocitypb.RegisterContentServer(gs, &grpcServer{s: s})
+	return gs
+}
+
+// grpcTenant reads the tenant from x-tenant metadata (default as getTenant)
+func grpcTenant(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("x-tenant"); len(values) > 0 && values[0] != "" {
+		if !storage.ValidTenant(values[0]) {
+			return "", status.Errorf(codes.InvalidArgument, "invalid tenant: %q", values[0])
+		}
+		return values[0], nil
+	}
+	return "demo", nil
+}
+
+// grpcAuthenticate resolves the role a call acts with from its x-api-key or
+// authorization metadata, as apiKeyHandler does for HTTP requests, and
+// requires the read permission
+func (s *Server) grpcAuthenticate(ctx context.Context) (context.Context, error) {
+	tenant, err := grpcTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	for _, key := range []string{"x-api-key", "authorization"} {
+		if values := md.Get(key); len(values) > 0 {
+			r.Header.Set(key, values[0])
+		}
+	}
+
+	ctx, authErr := s.authenticate(ctx, tenant, extractAPIKey(r), extractToken(r))
+	if authErr != nil {
+		return nil, status.Error(grpcCode(authErr.status), authErr.message)
+	}
+	if !allowed(ctx, permRead) {
+		return nil, status.Errorf(codes.PermissionDenied, "The %s role doesn't have the %s permission", roleFromContext(ctx), permRead)
+	}
+	return ctx, nil
+}
+
+func (s *Server) grpcUnaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.grpcAuthenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) grpcStreamAuth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.grpcAuthenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream is a server stream carrying the role it acts with
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a *authenticatedStream) Context() context.Context {
+	return a.ctx
+}
+
+// readState parses the state of a read, refusing non-live content on delivery nodes
+func (g *grpcServer) readState(value string) (storage.State, error) {
+	if value == "" {
+		return storage.StateLive, nil
+	}
+	if !storage.ValidState(value) {
+		return "", status.Errorf(codes.InvalidArgument, "invalid state: %s", value)
+	}
+	if g.s.isDelivery() && value != string(storage.StateLive) {
+		return "", status.Error(codes.PermissionDenied, "delivery nodes serve live content only")
+	}
+	return storage.State(value), nil
+}
+
+// grpcItem converts a content stream to an Item
+func grpcItem(contentType, id string, state storage.State, stream *storage.ContentStream) *velocitypb.Item {
+	return &velocitypb.Item{
+		Type:         contentType,
+		Id:           id,
+		State:        string(state),
+		ContentType:  stream.ContentType,
+		Size:         stream.Size,
+		Etag:         stream.ETag,
+		Version:      stream.VersionID,
+		LastModified: timestamppb.New(stream.LastModified),
+		Metadata:     stream.Metadata,
+	}
+}
+
+// findStream opens an item's content, mapping errors to gRPC statuses
+func (g *grpcServer) findStream(ctx context.Context, tenant, contentType, id string, state storage.State) (*storage.ContentStream, error) {
+	stream, err := g.s.storage.FindContentStream(ctx, tenant, contentType, id, "", state)
+	if err != nil {
+		if strings.Contains(err.Error(), "NoSuchKey") || strings.Contains(err.Error(), "not found") {
+			return nil, status.Errorf(codes.NotFound, "content '%s' not found", id)
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return stream, nil
+}
+
+// Get streams an item, with the tenant's render plugins applied
+func (g *grpcServer) Get(req *velocitypb.GetRequest, srv velocitypb.Content_GetServer) error {
+	ctx := srv.Context()
+	tenant, err := grpcTenant(ctx)
+	if err != nil {
+		return err
+	}
+	state, err := g.readState(req.State)
+	if err != nil {
+		return err
+	}
+
+	stream, err := g.findStream(ctx, tenant, req.Type, req.Id, state)
+	if err != nil {
+		return err
+	}
+	defer stream.Body.Close()
+	g.s.applyRenderPlugins(ctx, tenant, req.Type, req.Id, stream)
+
+	if err := srv.Send(&velocitypb.GetResponse{Part: &velocitypb.GetResponse_Item{Item: grpcItem(req.Type, req.Id, state, stream)}}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, grpcChunkSize)
+	for {
+		n, err := stream.Body.Read(buf)
+		if n > 0 {
+			chunk := append([]byte(nil), buf[:n]...)
+			if err := srv.Send(&velocitypb.GetResponse{Part: &velocitypb.GetResponse_Chunk{Chunk: chunk}}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+}
+
+// List returns the items of a content type in a state
+func (g *grpcServer) List(ctx context.Context, req *velocitypb.ListRequest) (*velocitypb.ListResponse, error) {
+	tenant, err := grpcTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state, err := g.readState(req.State)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := g.s.storage.List(ctx, tenant, req.Type, state)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &velocitypb.ListResponse{Items: make([]*velocitypb.Item, 0, len(items))}
+	for _, item := range items {
+		id, _ := extractIDAndExt(item.Key, req.Type, state)
+		resp.Items = append(resp.Items, &velocitypb.Item{
+			Type:         req.Type,
+			Id:           id,
+			State:        string(state),
+			ContentType:  mimeFromExt(filepath.Ext(item.Key)),
+			Size:         item.Size,
+			Etag:         item.ETag,
+			LastModified: timestamppb.New(item.LastModified),
+		})
+	}
+	return resp, nil
+}
+
+// BulkGet fetches several live items in parallel
+func (g *grpcServer) BulkGet(ctx context.Context, req *velocitypb.BulkGetRequest) (*velocitypb.BulkGetResponse, error) {
+	tenant, err := grpcTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Items) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no items requested")
+	}
+
+	results := make([]*velocitypb.BulkGetResult, len(req.Items))
+	var wg sync.WaitGroup
+	for i, ref := range req.Items {
+		wg.Add(1)
+		go func(i int, ref *velocitypb.ItemRef) {
+			defer wg.Done()
+			result := &velocitypb.BulkGetResult{Ref: ref}
+			results[i] = result
+
+			stream, err := g.findStream(ctx, tenant, ref.Type, ref.Id, storage.StateLive)
+			if err != nil {
+				result.Error = status.Convert(err).Message()
+				return
+			}
+			defer stream.Body.Close()
+			result.Item = grpcItem(ref.Type, ref.Id, storage.StateLive, stream)
+			if req.MetadataOnly {
+				return
+			}
+			if result.Content, err = io.ReadAll(stream.Body); err != nil {
+				result.Item, result.Content = nil, nil
+				result.Error = "failed to read content"
+			}
+		}(i, ref)
+	}
+	wg.Wait()
+
+	return &velocitypb.BulkGetResponse{Results: results}, nil
+}
+
+// Put stores an item through the HTTP API's update route, streaming the body
+// from the client
+func (g *grpcServer) Put(srv velocitypb.Content_PutServer) error {
+	first, err := srv.Recv()
+	if err != nil {
+		return err
+	}
+	header := first.GetHeader()
+	if header == nil {
+		return status.Error(codes.InvalidArgument, "the first message must carry the header")
+	}
+	if header.Type == "" || header.Id == "" {
+		return status.Error(codes.InvalidArgument, "type and id are required")
+	}
+
+	path := "/api/content/" + header.Type + "/" + header.Id
+	if header.State != "" {
+		if !storage.ValidState(header.State) {
+			return status.Errorf(codes.InvalidArgument, "invalid state: %s", header.State)
+		}
+		path += "/" + header.State
+	}
+
+	// Feed the remaining messages to the handler as the request body
+	body, pw := io.Pipe()
+	defer body.Close()
+	go func() {
+		for {
+			msg, err := srv.Recv()
+			if err == io.EOF {
+				pw.Close()
+				return
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := pw.Write(msg.GetChunk()); err != nil {
+				return
+			}
+		}
+	}()
+
+	r := newInProcessRequest(srv.Context(), http.MethodPut, path, body)
+	r.ContentLength = -1
+	if header.Size > 0 {
+		r.ContentLength = header.Size
+	}
+	r.Header.Set("Content-Type", "application/json")
+	if header.ContentType != "" {
+		r.Header.Set("Content-Type", header.ContentType)
+	}
+	if header.IfMatch != "" {
+		r.Header.Set("If-Match", header.IfMatch)
+	}
+	for key, value := range header.Metadata {
+		r.Header.Set("X-Meta-"+key, value)
+	}
+
+	var result struct {
+		ID      string `json:"id"`
+		State   string `json:"state"`
+		Version string `json:"version"`
+		ETag    string `json:"etag"`
+	}
+	if err := g.s.serveInProcess(r, &result); err != nil {
+		return err
+	}
+	return srv.SendAndClose(&velocitypb.PutResponse{Id: result.ID, State: result.State, Version: result.Version, Etag: result.ETag})
+}
+
+// Transition moves an item between states through the HTTP API's transition route
+func (g *grpcServer) Transition(ctx context.Context, req *velocitypb.TransitionRequest) (*velocitypb.TransitionResponse, error) {
+	if req.Type == "" || req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "type and id are required")
+	}
+	data, _ := json.Marshal(map[string]string{
+		"from":    req.From,
+		"to":      req.To,
+		"author":  req.Author,
+		"message": req.Message,
+	})
+
+	r := newInProcessRequest(ctx, http.MethodPost, "/api/content/"+req.Type+"/"+req.Id+"/transition", bytes.NewReader(data))
+	r.Header.Set("Content-Type", "application/json")
+
+	var result struct {
+		ID      string `json:"id"`
+		From    string `json:"from"`
+		To      string `json:"to"`
+		Version string `json:"version"`
+	}
+	if err := g.s.serveInProcess(r, &result); err != nil {
+		return nil, err
+	}
+	return &velocitypb.TransitionResponse{Id: result.ID, From: result.From, To: result.To, Version: result.Version}, nil
+}
+
+// =============================================================================
+// In-Process Requests
+// =============================================================================
+
+// newInProcessRequest builds an API request carrying a gRPC call's metadata
+func newInProcessRequest(ctx context.Context, method, path string, body io.Reader) *http.Request {
+	r, _ := http.NewRequestWithContext(ctx, method, "/", body)
+	r.URL.Path = path
+	r.RequestURI = path
+	r.RemoteAddr = "grpc"
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, key := range grpcForwardedMetadata {
+		if values := md.Get(key); len(values) > 0 {
+			r.Header.Set(key, values[0])
+		}
+	}
+	return r
+}
+
+// inProcessResponse records a response written by an API handler
+type inProcessResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rr *inProcessResponse) Header() http.Header         { return rr.header }
+func (rr *inProcessResponse) Write(p []byte) (int, error) { return rr.body.Write(p) }
+func (rr *inProcessResponse) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+}
+
+// serveInProcess runs a request through the API routes, decoding a successful
+// JSON response into result or returning the error as a gRPC status
+func (s *Server) serveInProcess(r *http.Request, result interface{}) error {
+	rr := &inProcessResponse{header: make(http.Header)}
+	s.router.ServeHTTP(rr, r)
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+
+	if rr.status >= 400 {
+		var apiErr models.ErrorResponse
+		message := strings.TrimSpace(rr.body.String())
+		if json.Unmarshal(rr.body.Bytes(), &apiErr) == nil && apiErr.Message != "" {
+			message = apiErr.Message
+			if len(apiErr.Details) > 0 {
+				message += ": " + strings.Join(apiErr.Details, "; ")
+			}
+		}
+		return status.Error(grpcCode(rr.status), message)
+	}
+	if err := json.Unmarshal(rr.body.Bytes(), result); err != nil {
+		return status.Error(codes.Internal, fmt.Sprintf("unexpected response from %s %s: %v", r.Method, r.URL.Path, err))
+	}
+	return nil
+}
+
+// grpcCode maps an HTTP status to the closest gRPC code
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden, http.StatusMethodNotAllowed:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusPreconditionFailed, http.StatusUnprocessableEntity:
+		return codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
@@ -1,8 +1,6 @@
 package api
 
 import (
-	"bytes"
-	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -18,6 +16,7 @@ import (
 
 	"velocity/internal/log"
 	"velocity/internal/models"
+	"velocity/internal/plugin"
 	"velocity/internal/storage"
 )
 
@@ -34,6 +33,9 @@ var mimeToExt = map[string]string{
 	"image/gif":         "gif",
 	"image/webp":        "webp",
 	"image/svg+xml":     "svg",
+	"video/mp4":         "mp4",
+	"video/webm":        "webm",
+	"video/quicktime":   "mov",
 	"application/pdf":   "pdf",
 	"text/markdown":     "md",
 	"text/plain":        "txt",
@@ -153,10 +155,39 @@ func (s *Server) listTypesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	sort.Strings(types)
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
 		"types": types,
 		"count": len(types),
-	})
+	}
+
+	// Pagination (?offset=, ?limit=); the detailed catalog is paged by default
+	details := r.URL.Query().Get("details") == "true"
+	page, msg := parseTypesPage(r, details)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, "invalid_page", msg)
+		return
+	}
+	if page.offset > 0 || page.limit > 0 {
+		names, more := page.apply(types)
+		response["types"] = names
+		response["count"] = len(names)
+		response["total"] = len(types)
+		response["offset"] = page.offset
+		if page.limit > 0 {
+			response["limit"] = page.limit
+		}
+		if more {
+			response["next_offset"] = page.offset + len(names)
+		}
+		types = names
+	}
+
+	// Schema details, item counts, and last-modified times (?details=true)
+	if details {
+		response["types"] = s.describeTypes(r.Context(), tenant, types)
+	}
+
+	writeJSON(w, http.StatusOK, response)
 }
 
 // =============================================================================
@@ -230,11 +261,9 @@ func (s *Server) createContentTypeHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	// Validate name: alphanumeric and hyphens only
-	for _, c := range name {
-		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-') {
-			writeError(w, http.StatusBadRequest, "invalid_name", "Content type name must contain only letters, numbers, and hyphens")
-			return
-		}
+	if !validTypeName(name) {
+		writeError(w, http.StatusBadRequest, "invalid_name", "Content type name must contain only letters, numbers, and hyphens")
+		return
 	}
 
 	tenant := s.getTenant(r)
@@ -584,8 +613,10 @@ func (s *Server) bulkGetHandler(w http.ResponseWriter, r *http.Request) {
 
 			// Handle content
 			if needContent {
-				content, err := io.ReadAll(stream.Body)
+				// Content is copied out (decoded or encoded) before the buffer is released
+				content, release, err := readPooled(stream.Body, stream.Size)
 				stream.Body.Close()
+				defer release()
 				if err != nil {
 					data["error"] = "read_error"
 					data["message"] = "Failed to read content"
@@ -601,19 +632,7 @@ func (s *Server) bulkGetHandler(w http.ResponseWriter, r *http.Request) {
 					data["metadata"] = stream.Metadata
 				}
 
-				// Handle content based on type
-				if strings.HasPrefix(stream.ContentType, "application/json") {
-					var jsonContent interface{}
-					if err := json.Unmarshal(content, &jsonContent); err == nil {
-						data["content"] = jsonContent
-					} else {
-						data["content"] = string(content)
-					}
-				} else if strings.HasPrefix(stream.ContentType, "text/") {
-					data["content"] = string(content)
-				} else {
-					data["content"] = "base64:" + base64.StdEncoding.EncodeToString(content)
-				}
+				data["content"] = bulkContent(stream.ContentType, content)
 			} else {
 				stream.Body.Close()
 			}
@@ -644,6 +663,22 @@ func (s *Server) bulkGetHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// bulkContent returns content as it appears in a JSON response: parsed JSON,
+// text as a string, and anything else base64-encoded with a "base64:" prefix
+func bulkContent(mimeType string, content []byte) interface{} {
+	if strings.HasPrefix(mimeType, "application/json") {
+		var jsonContent interface{}
+		if err := json.Unmarshal(content, &jsonContent); err == nil {
+			return jsonContent
+		}
+		return string(content)
+	}
+	if strings.HasPrefix(mimeType, "text/") {
+		return string(content)
+	}
+	return "base64:" + base64.StdEncoding.EncodeToString(content)
+}
+
 // mimeFromExt returns the MIME type for a file extension (including the dot)
 func mimeFromExt(ext string) string {
 	switch ext {
@@ -675,6 +710,10 @@ func mimeFromExt(ext string) string {
 		return "application/pdf"
 	case ".mp4":
 		return "video/mp4"
+	case ".webm":
+		return "video/webm"
+	case ".mov":
+		return "video/quicktime"
 	case ".mp3":
 		return "audio/mpeg"
 	default:
@@ -683,7 +722,9 @@ func mimeFromExt(ext string) string {
 }
 
 // listContentHandler lists all content of a type for a tenant.
-// Supports ?prefix= for folder-level browsing and ?state= for state filtering.
+// Supports ?prefix= for folder-level browsing and ?state= for state filtering,
+// and ?ids= or ?id= (which may be a glob) to fetch a set of items instead.
+// ?include=content adds the content of small JSON items (see hydrate.go).
 func (s *Server) listContentHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	contentType := vars["type"]
@@ -696,11 +737,24 @@ func (s *Server) listContentHandler(w http.ResponseWriter, r *http.Request) {
 	if stateOrID != "" && storage.ValidState(stateOrID) {
 		state = storage.State(stateOrID)
 	}
+	// Explicit state route (/content/{type}/states/{state})
+	if stateVar := vars["state"]; storage.ValidState(stateVar) {
+		state = storage.State(stateVar)
+	}
 	// Also check query param
 	if stateParam := r.URL.Query().Get("state"); stateParam != "" && storage.ValidState(stateParam) {
 		state = storage.State(stateParam)
 	}
 
+	// A known set of items (?ids=home,about or ?id=press-*) is fetched, not listed
+	if query := r.URL.Query(); query.Has("ids") || query.Has("id") {
+		s.contentSetHandler(w, r, tenant, contentType, state)
+		return
+	}
+
+	// Optional language filter (matches detected or explicit language metadata)
+	language := r.URL.Query().Get("language")
+
 	// Check for prefix-based browsing (present even if empty = browse root level)
 	prefix := r.URL.Query().Get("prefix")
 	_, hasPrefixParam := r.URL.Query()["prefix"]
@@ -710,21 +764,32 @@ func (s *Server) listContentHandler(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
 			return
 		}
+		browseItems := browseResult.Items
+		if language != "" {
+			browseItems = s.filterByLanguage(r.Context(), tenant, contentType, state, browseItems, language)
+		}
 
 		// Convert items to response format
-		responseItems := make([]map[string]interface{}, 0, len(browseResult.Items))
-		for _, item := range browseResult.Items {
-			id, _ := extractIDAndExt(item.Key, contentType, state)
+		responseItems := make([]map[string]interface{}, 0, len(browseItems))
+		for _, item := range browseItems {
+			id, itemExt := extractIDAndExt(item.Key, contentType, state)
 			ext := filepath.Ext(item.Key)
 			mimeType := mimeFromExt(ext)
 
 			responseItems = append(responseItems, map[string]interface{}{
 				"id":            id,
+				"ext":           itemExt,
 				"content_type":  mimeType,
 				"last_modified": item.LastModified,
 				"size":          item.Size,
 			})
 		}
+		if wantsContent(r) {
+			if err := s.hydrateItems(r.Context(), tenant, contentType, state, browseItems, responseItems); err != nil {
+				writeError(w, http.StatusBadRequest, "too_many_items", err.Error())
+				return
+			}
+		}
 
 		writeJSON(w, http.StatusOK, map[string]interface{}{
 			"items":   responseItems,
@@ -745,21 +810,31 @@ func (s *Server) listContentHandler(w http.ResponseWriter, r *http.Request) {
 	if items == nil {
 		items = []*storage.ContentItem{}
 	}
+	if language != "" {
+		items = s.filterByLanguage(r.Context(), tenant, contentType, state, items, language)
+	}
 
 	// Convert to response format, extracting full nested IDs
 	responseItems := make([]map[string]interface{}, 0, len(items))
 	for _, item := range items {
-		id, _ := extractIDAndExt(item.Key, contentType, state)
+		id, itemExt := extractIDAndExt(item.Key, contentType, state)
 		ext := filepath.Ext(item.Key)
 		mimeType := mimeFromExt(ext)
 
 		responseItems = append(responseItems, map[string]interface{}{
 			"id":            id,
+			"ext":           itemExt,
 			"content_type":  mimeType,
 			"last_modified": item.LastModified,
 			"size":          item.Size,
 		})
 	}
+	if wantsContent(r) {
+		if err := s.hydrateItems(r.Context(), tenant, contentType, state, items, responseItems); err != nil {
+			writeError(w, http.StatusBadRequest, "too_many_items", err.Error())
+			return
+		}
+	}
 
 	writeJSON(w, http.StatusOK, models.ListResponse{
 		Items: func() []interface{} {
@@ -785,75 +860,54 @@ func (s *Server) createContentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	tenant := s.getTenant(r)
-	state := getState(r)
+	state := s.writeState(r, tenant)
+	policy := s.settings.get(r.Context(), tenant).IDPolicy
+	id = policy.normalize(id)
 
 	// Extract metadata from X-Meta-* headers
 	metadata := extractMetadata(r)
 
-	// Check if ID already has an extension
-	if idx := strings.LastIndex(id, "."); idx != -1 && idx < len(id)-1 {
-		ext := id[idx+1:]
-		id = id[:idx]
-		// Use extension from ID, get mime type from that
-		mimeType := r.Header.Get("Content-Type")
-		if mimeType == "" {
-			mimeType = "application/octet-stream"
-		}
-		contentLength := r.ContentLength
-		body := r.Body
-		defer r.Body.Close()
-
-		item, err := s.storage.PutStream(r.Context(), tenant, contentType, id, ext, body, contentLength, mimeType, state, metadata)
-		if err != nil {
-			writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
-			return
-		}
-
-		log.Debug("Created content: %s (%s, %d bytes)", item.Key, mimeType, item.Size)
-		s.triggerWebhooks(tenant, "create", contentType, id, filepath.Base(item.Key), mimeType)
-
-		writeJSON(w, http.StatusCreated, map[string]interface{}{
-			"id":      id,
-			"state":   string(state),
-			"version": item.VersionID,
-			"message": "Content created successfully",
-		})
-		return
-	}
-
 	var body io.Reader
 	var contentLength int64
 	var mimeType string
 	var ext string
 
-	// Check if this is a multipart form upload
-	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
-		// For multipart, we need to parse to get the file
-		// Use MaxBytesReader to limit memory usage
-		r.Body = http.MaxBytesReader(w, r.Body, 10<<30) // 10GB max
-
-		mr, err := r.MultipartReader()
+	// Check if ID already has an extension
+	if isRelated(r) {
+		// JSON metadata part plus content part (see related.go)
+		defer r.Body.Close()
+		relatedMetadata, part, err := readRelated(r)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid_form", "Failed to parse multipart form")
+			writeError(w, http.StatusBadRequest, "invalid_multipart", err.Error())
 			return
 		}
+		defer part.Close()
+		metadata = mergeMetadata(metadata, relatedMetadata)
 
-		part, err := mr.NextPart()
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "missing_file", "No file provided")
-			return
+		mimeType = partType(part)
+		if idx := strings.LastIndex(id, "."); idx != -1 && idx < len(id)-1 {
+			ext = id[idx+1:]
+			id = id[:idx]
+		} else {
+			ext = getExtensionFromMime(mimeType)
 		}
-
-		mimeType = part.Header.Get("Content-Type")
+		contentLength = -1
+		body = part
+	} else if idx := strings.LastIndex(id, "."); idx != -1 && idx < len(id)-1 {
+		// Use extension from ID, stream the body as-is
+		ext = id[idx+1:]
+		id = id[:idx]
+		mimeType = r.Header.Get("Content-Type")
 		if mimeType == "" {
 			mimeType = "application/octet-stream"
 		}
-		ext = getExtensionFromMime(mimeType)
-
-		// Content-Length not available for multipart parts, use -1 for unknown
-		contentLength = -1
-		body = part
-		defer part.Close()
+		contentLength = r.ContentLength
+		body = r.Body
+		defer r.Body.Close()
+	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		// Files and form fields, each file stored as its own item (see uploads.go)
+		s.createFromForm(w, r, tenant, contentType, id, state, metadata)
+		return
 	} else {
 		// Stream body directly
 		contentLength = r.ContentLength
@@ -866,27 +920,101 @@ func (s *Server) createContentHandler(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
 	}
 
+	if msg := policy.check(id); msg != "" {
+		writeError(w, http.StatusBadRequest, "invalid_id", msg)
+		return
+	}
+
+	// Create-only: refuse to replace an item that already exists
+	if s.createOnly(r, tenant) {
+		unlock := lockWrite(tenant + "/" + contentType + "/" + id + "/" + string(state))
+		defer unlock()
+		if !s.checkIfNoneMatch(w, r, tenant, contentType, id, ext, state) {
+			return
+		}
+	}
+
+	// Run plugin hooks (may modify or reject the write)
+	hookReq := &plugin.Request{
+		Operation:   plugin.OpCreate,
+		Tenant:      tenant,
+		Type:        contentType,
+		ID:          id,
+		State:       string(state),
+		ContentType: mimeType,
+		Metadata:    metadata,
+	}
+	body, contentLength, err := s.applyBeforeHooks(r.Context(), hookReq, body, contentLength)
+	if err != nil {
+		writeHookRejection(w, err)
+		return
+	}
+	metadata = hookReq.Metadata
+
+	// Validate JSON content against the type's schema (dry runs, or validate_writes)
+	dryRun := isDryRun(r)
+	body, contentLength, validationErrors, err := s.validateBody(r.Context(), tenant, contentType, mimeType, body, contentLength, dryRun)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if dryRun {
+		s.writeDryRun(w, r, tenant, contentType, id, ext, state, "create", validationErrors)
+		return
+	}
+	if len(validationErrors) > 0 {
+		writeValidationError(w, validationErrors)
+		return
+	}
+
+	// Read-only and role-restricted fields
+	body, violations, err := s.checkFieldPermissions(r.Context(), tenant, contentType, id, ext, state, mimeType, body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "Failed to read request body")
+		return
+	}
+	if len(violations) > 0 {
+		writeFieldPermissionError(w, violations)
+		return
+	}
+
+	// Encrypted fields
+	body, contentLength, err = s.encryptBody(r.Context(), tenant, contentType, mimeType, body, contentLength)
+	if err != nil {
+		writeFieldEncryptionError(w, err)
+		return
+	}
+
+	// Fingerprint content for duplicate detection
+	body, fp, err := fingerprintBody(body, mimeType)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "Failed to read request body")
+		return
+	}
+	metadata = tagLanguage(metadata, fp.content, mimeType)
+
 	// Store content via streaming
 	item, err := s.storage.PutStream(r.Context(), tenant, contentType, id, ext, body, contentLength, mimeType, state, metadata)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
 		return
 	}
+	s.storeFingerprint(tenant, contentType, id, state, item, fp)
 
 	log.Debug("Created content: %s (%s, %d bytes)", item.Key, mimeType, item.Size)
 
 	// Trigger webhooks
 	s.triggerWebhooks(tenant, "create", contentType, id, filepath.Base(item.Key), mimeType)
+	plugin.RunAfter(hookReq, &plugin.Result{Key: item.Key, Version: item.VersionID})
+	s.processVideo(r.Context(), tenant, contentType, id, ext, state, mimeType)
+	s.indexContent(r.Context(), tenant, contentType, id, ext, state, mimeType)
 
-	writeJSON(w, http.StatusCreated, map[string]interface{}{
-		"id":      id,
-		"state":   string(state),
-		"version": item.VersionID,
-		"message": "Content created successfully",
-	})
+	s.writeWriteResult(w, r, http.StatusCreated, tenant, contentType, id, ext, state, item, "Content created successfully")
 }
 
-// getOrListContentHandler routes to list or get based on whether id is a state
+// getOrListContentHandler routes to list or get based on whether id is a state.
+// It serves the legacy /content/{type}/{id} route only; the explicit
+// /content/{type}/items/{id} and /content/{type}/states/{state} routes don't guess.
 func (s *Server) getOrListContentHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -906,6 +1034,18 @@ func (s *Server) getOrListContentHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// The version live at a past time (see asof.go)
+	if r.URL.Query().Has("as-of") {
+		s.asOfHandler(w, r, tenant, contentType, id, state)
+		return
+	}
+
+	// A/B variants are served by name (see variants.go)
+	if variant := r.URL.Query().Get("variant"); variant != "" && variant != controlVariant {
+		s.serveVariant(w, r, tenant, contentType, id, variant)
+		return
+	}
+
 	// Try to get as a content item first
 	extHint := ""
 	if accept := r.Header.Get("Accept"); accept != "" {
@@ -919,6 +1059,20 @@ func (s *Server) getOrListContentHandler(w http.ResponseWriter, r *http.Request)
 	if err == nil {
 		// Found a content item — serve it
 		defer stream.Body.Close()
+		foundID, _ := extractIDAndExt(stream.Key, contentType, state)
+		if s.decryptStream(r.Context(), tenant, contentType, stream) {
+			w.Header().Set("Cache-Control", "private, no-store")
+		}
+		s.applyRenderPlugins(r.Context(), tenant, contentType, foundID, stream)
+		if wantsExpand(r) && isJSONContent(stream.ContentType) {
+			s.writeExpanded(w, r, tenant, contentType, state, stream)
+			return
+		}
+		if stream.ETag != "" && checkNotModified(r, stream.ETag, stream.LastModified) {
+			w.Header().Set("ETag", stream.ETag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
 		w.Header().Set("Content-Type", stream.ContentType)
 		if stream.ETag != "" {
 			w.Header().Set("ETag", stream.ETag)
@@ -926,7 +1080,8 @@ func (s *Server) getOrListContentHandler(w http.ResponseWriter, r *http.Request)
 		if stream.VersionID != "" {
 			w.Header().Set("X-Version-Id", stream.VersionID)
 		}
-		io.Copy(w, stream.Body)
+		s.setReprDigest(w, r, tenant, contentType, foundID, state, stream.ETag)
+		copyBuffered(w, stream.Body)
 		return
 	}
 
@@ -973,6 +1128,18 @@ func (s *Server) getContentHandler(w http.ResponseWriter, r *http.Request) {
 	tenant := s.getTenant(r)
 	state := getState(r)
 
+	// The version live at a past time (see asof.go)
+	if r.URL.Query().Has("as-of") {
+		s.asOfHandler(w, r, tenant, contentType, id, state)
+		return
+	}
+
+	// A/B variants are served by name (see variants.go)
+	if variant := r.URL.Query().Get("variant"); variant != "" && variant != controlVariant {
+		s.serveVariant(w, r, tenant, contentType, id, variant)
+		return
+	}
+
 	// Check for attribute query param: "content" (default), "metadata", or "url"
 	attribute := r.URL.Query().Get("attribute")
 
@@ -1002,17 +1169,20 @@ func (s *Server) getContentHandler(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Content '%s' not found", id))
 			return
 		}
-		stream.Body.Close()
+		defer stream.Body.Close()
 
-		metadata := stream.Metadata
-		if metadata == nil {
-			metadata = make(map[string]string)
+		foundID, _ := extractIDAndExt(stream.Key, contentType, state)
+		metadata := s.withChecksum(r.Context(), tenant, contentType, foundID, state, stream)
+		attachments, err := s.listAttachments(r.Context(), tenant, contentType, id)
+		if err != nil {
+			log.Error("Failed to list attachments of %s/%s: %v", contentType, id, err)
 		}
 		writeJSON(w, http.StatusOK, map[string]interface{}{
 			"id":           id,
 			"attribute":    "metadata",
 			"content-type": stream.ContentType,
 			"metadata":     metadata,
+			"attachments":  attachments,
 		})
 		return
 	}
@@ -1029,6 +1199,21 @@ func (s *Server) getContentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer stream.Body.Close()
 
+	// Open encrypted fields for callers that may read them
+	decrypted := s.decryptStream(r.Context(), tenant, contentType, stream)
+	if decrypted {
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+
+	// Apply the tenant's render plugins
+	s.applyRenderPlugins(r.Context(), tenant, contentType, id, stream)
+
+	// Resolve reference fields (see references.go)
+	if wantsExpand(r) && isJSONContent(stream.ContentType) {
+		s.writeExpanded(w, r, tenant, contentType, state, stream)
+		return
+	}
+
 	// Check conditional request headers for caching
 	if checkNotModified(r, stream.ETag, stream.LastModified) {
 		w.WriteHeader(http.StatusNotModified)
@@ -1038,7 +1223,9 @@ func (s *Server) getContentHandler(w http.ResponseWriter, r *http.Request) {
 	// Set caching headers
 	w.Header().Set("ETag", stream.ETag)
 	w.Header().Set("Last-Modified", stream.LastModified.UTC().Format(time.RFC1123))
-	w.Header().Set("Cache-Control", "public, max-age=60, must-revalidate")
+	if !decrypted {
+		w.Header().Set("Cache-Control", "public, max-age=60, must-revalidate")
+	}
 
 	// Set content headers
 	if stream.VersionID != "" {
@@ -1049,10 +1236,12 @@ func (s *Server) getContentHandler(w http.ResponseWriter, r *http.Request) {
 	if stream.Size > 0 {
 		w.Header().Set("Content-Length", fmt.Sprintf("%d", stream.Size))
 	}
+	foundID, _ := extractIDAndExt(stream.Key, contentType, state)
+	s.setReprDigest(w, r, tenant, contentType, foundID, state, stream.ETag)
 
 	// Stream content directly to response
 	w.WriteHeader(http.StatusOK)
-	io.Copy(w, stream.Body)
+	copyBuffered(w, stream.Body)
 }
 
 // directContentHandler serves content directly via /content/{tenant}/{type}/{id}
@@ -1062,6 +1251,11 @@ func (s *Server) directContentHandler(w http.ResponseWriter, r *http.Request) {
 	contentType := vars["type"]
 	id := vars["id"]
 
+	// Moved content is redirected (previews see content as stored)
+	if r.URL.Query().Get("state") == "" && s.serveRedirect(w, r, tenant, contentType+"/"+id) {
+		return
+	}
+
 	// Get extension hint from Accept header
 	extHint := ""
 	if accept := r.Header.Get("Accept"); accept != "" {
@@ -1071,23 +1265,65 @@ func (s *Server) directContentHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	stream, err := s.storage.FindContentStream(r.Context(), tenant, contentType, id, extHint, storage.StateLive)
-	if err != nil {
-		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Content '%s' not found", id))
-		return
+	// Draft and pending content can be previewed with the tenant's preview token
+	state := storage.StateLive
+	if value := r.URL.Query().Get("state"); value != "" && value != string(storage.StateLive) {
+		if !storage.ValidState(value) {
+			writeError(w, http.StatusBadRequest, "invalid_state", fmt.Sprintf("Invalid state: %s", value))
+			return
+		}
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			token = r.Header.Get("X-Preview-Token")
+		}
+		if !s.validPreviewToken(r.Context(), tenant, token) {
+			writeError(w, http.StatusForbidden, "preview_forbidden", "A valid preview token is required to view non-live content")
+			return
+		}
+		state = storage.State(value)
+	}
+
+	// Live content may be swapped for one of its A/B variants
+	var stream *storage.ContentStream
+	var err error
+	personalized := false
+	if state == storage.StateLive {
+		stream, personalized, err = s.selectVariant(w, r, tenant, contentType, id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "not_found", err.Error())
+			return
+		}
+	}
+	if stream == nil {
+		stream, err = s.storage.FindContentStream(r.Context(), tenant, contentType, id, extHint, state)
+		if err != nil {
+			s.writeContentError(w, r, tenant, s.missingContentStatus(r, tenant, contentType, id, state), id)
+			return
+		}
 	}
 	defer stream.Body.Close()
 
+	// Apply the tenant's render plugins
+	s.applyRenderPlugins(r.Context(), tenant, contentType, id, stream)
+
 	// Check conditional request headers for caching
 	if checkNotModified(r, stream.ETag, stream.LastModified) {
 		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	// Set caching headers
+	// Set caching headers (previews are never cached by shared caches, and
+	// variant picks only by the visitor)
 	w.Header().Set("ETag", stream.ETag)
 	w.Header().Set("Last-Modified", stream.LastModified.UTC().Format(time.RFC1123))
-	w.Header().Set("Cache-Control", "public, max-age=60, must-revalidate")
+	switch {
+	case state != storage.StateLive:
+		w.Header().Set("Cache-Control", "private, no-store")
+	case personalized:
+		w.Header().Set("Cache-Control", "private, max-age=60, must-revalidate")
+	default:
+		w.Header().Set("Cache-Control", "public, max-age=60, must-revalidate")
+	}
 
 	// Set content headers
 	w.Header().Set("Content-Type", stream.ContentType)
@@ -1097,7 +1333,7 @@ func (s *Server) directContentHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Stream content directly to response
 	w.WriteHeader(http.StatusOK)
-	io.Copy(w, stream.Body)
+	copyBuffered(w, stream.Body)
 }
 
 // checkNotModified checks If-None-Match and If-Modified-Since headers
@@ -1127,45 +1363,142 @@ func (s *Server) updateContentHandler(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 
 	tenant := s.getTenant(r)
-	state := getState(r)
+	state := s.writeState(r, tenant)
 
 	// Extract metadata from X-Meta-* headers (nil if not provided means keep existing)
 	metadata := extractMetadata(r)
 
+	var body io.Reader = r.Body
+	contentLength := r.ContentLength
+	mimeType := r.Header.Get("Content-Type")
+	defer r.Body.Close()
+
+	// JSON metadata part plus content part (see related.go)
+	if isRelated(r) {
+		relatedMetadata, part, err := readRelated(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_multipart", err.Error())
+			return
+		}
+		defer part.Close()
+		metadata = mergeMetadata(metadata, relatedMetadata)
+		mimeType = partType(part)
+		contentLength = -1
+		body = part
+	}
+
 	// Check if ID already has an extension
+	id = s.settings.get(r.Context(), tenant).IDPolicy.normalize(id)
 	var ext string
 	if idx := strings.LastIndex(id, "."); idx != -1 && idx < len(id)-1 {
 		ext = id[idx+1:]
 		id = id[:idx]
 	} else {
-		ext = getExtensionFromMime(r.Header.Get("Content-Type"))
+		ext = getExtensionFromMime(mimeType)
+	}
+
+	// New items must follow the ID rules; existing ones stay writable
+	if !s.checkNewID(w, r, tenant, contentType, id, ext, state) {
+		return
 	}
 
-	contentLength := r.ContentLength
-	mimeType := r.Header.Get("Content-Type")
 	if mimeType == "" {
 		mimeType = "application/json"
 	}
 
+	// Conditional update: if the content changed since the caller read it,
+	// reject with the current version and a proposed merge
+	if r.Header.Get("If-Match") != "" {
+		unlock := lockWrite(tenant + "/" + contentType + "/" + id + "/" + string(state))
+		defer unlock()
+		if !s.checkIfMatch(w, r, tenant, contentType, id, ext, state, mimeType, body) {
+			return
+		}
+	} else if strings.TrimSpace(r.Header.Get("If-None-Match")) == "*" {
+		// PUT as a create: only write if the item doesn't exist yet
+		unlock := lockWrite(tenant + "/" + contentType + "/" + id + "/" + string(state))
+		defer unlock()
+		if !s.checkIfNoneMatch(w, r, tenant, contentType, id, ext, state) {
+			return
+		}
+	}
+
+	// Run plugin hooks (may modify or reject the write)
+	hookReq := &plugin.Request{
+		Operation:   plugin.OpUpdate,
+		Tenant:      tenant,
+		Type:        contentType,
+		ID:          id,
+		State:       string(state),
+		ContentType: mimeType,
+		Metadata:    metadata,
+	}
+	body, contentLength, err := s.applyBeforeHooks(r.Context(), hookReq, body, contentLength)
+	if err != nil {
+		writeHookRejection(w, err)
+		return
+	}
+	metadata = hookReq.Metadata
+
+	// Validate JSON content against the type's schema (dry runs, or validate_writes)
+	dryRun := isDryRun(r)
+	body, contentLength, validationErrors, err := s.validateBody(r.Context(), tenant, contentType, mimeType, body, contentLength, dryRun)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if dryRun {
+		s.writeDryRun(w, r, tenant, contentType, id, ext, state, "update", validationErrors)
+		return
+	}
+	if len(validationErrors) > 0 {
+		writeValidationError(w, validationErrors)
+		return
+	}
+
+	// Read-only and role-restricted fields
+	body, violations, err := s.checkFieldPermissions(r.Context(), tenant, contentType, id, ext, state, mimeType, body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "Failed to read request body")
+		return
+	}
+	if len(violations) > 0 {
+		writeFieldPermissionError(w, violations)
+		return
+	}
+
+	// Encrypted fields
+	body, contentLength, err = s.encryptBody(r.Context(), tenant, contentType, mimeType, body, contentLength)
+	if err != nil {
+		writeFieldEncryptionError(w, err)
+		return
+	}
+
+	// Fingerprint content for duplicate detection
+	body, fp, err := fingerprintBody(body, mimeType)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "Failed to read request body")
+		return
+	}
+	metadata = tagLanguage(metadata, fp.content, mimeType)
+
 	// Store content via streaming (S3 versioning handles the update for live content)
-	item, err := s.storage.PutStream(r.Context(), tenant, contentType, id, ext, r.Body, contentLength, mimeType, state, metadata)
+	item, err := s.storage.PutStream(r.Context(), tenant, contentType, id, ext, body, contentLength, mimeType, state, metadata)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
 		return
 	}
-	defer r.Body.Close()
+	s.storeFingerprint(tenant, contentType, id, state, item, fp)
 
 	log.Debug("Updated content: %s (%d bytes)", item.Key, item.Size)
 
 	// Trigger webhooks
 	s.triggerWebhooks(tenant, "update", contentType, id, filepath.Base(item.Key), mimeType)
+	plugin.RunAfter(hookReq, &plugin.Result{Key: item.Key, Version: item.VersionID})
+	s.processVideo(r.Context(), tenant, contentType, id, ext, state, mimeType)
+	s.indexContent(r.Context(), tenant, contentType, id, ext, state, mimeType)
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"id":      id,
-		"state":   string(state),
-		"version": item.VersionID,
-		"message": "Content updated successfully",
-	})
+	s.writeWriteResult(w, r, http.StatusOK, tenant, contentType, id, ext, state, item, "Content updated successfully")
 }
 
 // deleteContentHandler deletes content (creates delete marker with versioning for live)
@@ -1178,12 +1511,27 @@ func (s *Server) deleteContentHandler(w http.ResponseWriter, r *http.Request) {
 	state := getState(r)
 
 	ext := s.getExtensionFromSchema(r.Context(), contentType)
+	if idx := strings.LastIndex(id, "."); idx != -1 && mimeFromExt(id[idx:]) != "application/octet-stream" {
+		// ID names the file (e.g. "logo.png")
+		ext = id[idx+1:]
+		id = id[:idx]
+	}
+
+	hookReq := &plugin.Request{Operation: plugin.OpDelete, Tenant: tenant, Type: contentType, ID: id, State: string(state)}
+	if err := plugin.RunBefore(r.Context(), hookReq); err != nil {
+		writeHookRejection(w, err)
+		return
+	}
 
 	err := s.storage.Delete(r.Context(), tenant, contentType, id, ext, state)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
 		return
 	}
+	plugin.RunAfter(hookReq, &plugin.Result{})
+	s.deleteFingerprint(r.Context(), tenant, contentType, id, state)
+	s.deleteSearchRecord(r.Context(), tenant, contentType, id, state)
+	s.deleteOrphans(r.Context(), tenant, contentType, id)
 
 	log.Debug("Deleted content: %s/%s/%s.%s (state: %s)", tenant, contentType, id, ext, state)
 
@@ -1237,15 +1585,75 @@ func (s *Server) transitionHandler(w http.ResponseWriter, r *http.Request) {
 
 	ext := s.getExtensionFromSchema(r.Context(), contentType)
 
+	if isDryRun(r) {
+		s.transitionDryRun(w, r, tenant, contentType, id, ext, fromState, toState, req.Author, req.Message)
+		return
+	}
+
+	// Moving content into or out of live publishes it
+	if (toState == storage.StateLive || fromState == storage.StateLive) && !requirePermission(w, r, permPublish) {
+		return
+	}
+
+	if toState == storage.StateLive {
+		if violation := s.checkPublishPolicy(r.Context(), tenant, req.Author, req.Message); violation != "" {
+			s.triggerTransitionRejected(tenant, contentType, id, req.From, req.To, req.Author, violation)
+			writeError(w, http.StatusUnprocessableEntity, "publish_message_required", violation)
+			return
+		}
+		if violation := s.checkImageMetadata(r.Context(), tenant, contentType, id, fromState); violation != "" {
+			s.triggerTransitionRejected(tenant, contentType, id, req.From, req.To, req.Author, violation)
+			writeError(w, http.StatusUnprocessableEntity, "accessibility_metadata_required", violation)
+			return
+		}
+	}
+
+	hookReq := &plugin.Request{
+		Operation: plugin.OpTransition,
+		Tenant:    tenant,
+		Type:      contentType,
+		ID:        id,
+		From:      req.From,
+		To:        req.To,
+		Author:    req.Author,
+		Message:   req.Message,
+	}
+	if err := plugin.RunBefore(r.Context(), hookReq); err != nil {
+		s.triggerTransitionRejected(tenant, contentType, id, req.From, req.To, req.Author, err.Error())
+		writeHookRejection(w, err)
+		return
+	}
+
 	// Get parent version before transition (for history)
 	parentVersion, _ := s.storage.GetLatestHistoryVersion(r.Context(), tenant, contentType, id)
 
 	item, err := s.storage.Transition(r.Context(), tenant, contentType, id, ext, fromState, toState)
 	if err != nil {
+		s.triggerTransitionRejected(tenant, contentType, id, req.From, req.To, req.Author, err.Error())
 		writeError(w, http.StatusInternalServerError, "transition_error", err.Error())
 		return
 	}
 
+	if toState == storage.StatePending {
+		s.dispatchEvent(storage.WebhookEvent{
+			Event:   "approval.requested",
+			Tenant:  tenant,
+			Type:    contentType,
+			ID:      id,
+			State:   req.To,
+			From:    req.From,
+			To:      req.To,
+			Author:  req.Author,
+			Message: req.Message,
+		})
+	}
+
+	plugin.RunAfter(hookReq, &plugin.Result{Key: item.Key, Version: item.VersionID})
+
+	// The content moved, so its search record moves with it
+	s.deleteSearchRecord(r.Context(), tenant, contentType, id, fromState)
+	s.indexContent(r.Context(), tenant, contentType, id, strings.TrimPrefix(filepath.Ext(item.Key), "."), toState, item.ContentType)
+
 	msg := fmt.Sprintf("Content transitioned from %s to %s", req.From, req.To)
 	if toState == storage.StateLive {
 		msg = fmt.Sprintf("Content published (transitioned from %s to live)", req.From)
@@ -1269,7 +1677,7 @@ func (s *Server) transitionHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Trigger publish webhook
-		s.triggerWebhooks(tenant, "publish", contentType, id, filepath.Base(item.Key), item.ContentType)
+		s.triggerPublishWebhooks(tenant, contentType, id, filepath.Base(item.Key), item.ContentType, req.Author, req.Message)
 	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
@@ -1363,7 +1771,7 @@ func (s *Server) getVersionHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Stream content directly to response
 	w.WriteHeader(http.StatusOK)
-	io.Copy(w, stream.Body)
+	copyBuffered(w, stream.Body)
 }
 
 // restoreVersionHandler restores a specific version as the current version
@@ -1646,9 +2054,7 @@ func (s *Server) getGlobalSchemaHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(schema.Content)
+	s.writeSchema(w, r, s.getTenant(r), schema)
 }
 
 // putGlobalSchemaHandler creates or updates a global schema
@@ -1663,10 +2069,8 @@ func (s *Server) putGlobalSchemaHandler(w http.ResponseWriter, r *http.Request)
 	}
 	defer r.Body.Close()
 
-	// Validate JSON
-	var schema models.Schema
-	if err := json.Unmarshal(body, &schema); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON schema")
+	// Validate JSON and inheritance
+	if !s.checkSchema(w, r, s.getTenant(r), name, true, body) {
 		return
 	}
 
@@ -1724,9 +2128,7 @@ func (s *Server) getTenantSchemaHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(schema.Content)
+	s.writeSchema(w, r, tenant, schema)
 }
 
 // putTenantSchemaHandler creates or updates a tenant-specific schema
@@ -1742,10 +2144,8 @@ func (s *Server) putTenantSchemaHandler(w http.ResponseWriter, r *http.Request)
 	}
 	defer r.Body.Close()
 
-	// Validate JSON
-	var schema models.Schema
-	if err := json.Unmarshal(body, &schema); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON schema")
+	// Validate JSON and inheritance
+	if !s.checkSchema(w, r, tenant, name, false, body) {
 		return
 	}
 
@@ -1852,6 +2252,8 @@ func (s *Server) createCommentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.triggerCommentEvent(tenant, "comment.created", contentType, contentID, state, comment)
+
 	writeJSON(w, http.StatusCreated, comment)
 }
 
@@ -1902,6 +2304,7 @@ func (s *Server) updateCommentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update resolved status
+	wasResolved := comment.Resolved
 	if req.Resolved != nil {
 		comment.Resolved = *req.Resolved
 		if *req.Resolved {
@@ -1918,6 +2321,10 @@ func (s *Server) updateCommentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if comment.Resolved && !wasResolved {
+		s.triggerCommentEvent(tenant, "comment.resolved", contentType, contentID, state, comment)
+	}
+
 	writeJSON(w, http.StatusOK, comment)
 }
 
@@ -1968,23 +2375,30 @@ func (s *Server) uploadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	// Read file content
-	content, err := io.ReadAll(file)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "read_error", "Failed to read file")
-		return
-	}
-
-	// Determine MIME type and extension
+	// Determine MIME type and extension, sniffing the start of the file if needed
 	mimeType := header.Header.Get("Content-Type")
 	if mimeType == "" {
-		mimeType = http.DetectContentType(content)
+		head := make([]byte, 512)
+		n, err := io.ReadFull(file, head)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			writeError(w, http.StatusInternalServerError, "read_error", "Failed to read file")
+			return
+		}
+		mimeType = http.DetectContentType(head[:n])
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			writeError(w, http.StatusInternalServerError, "read_error", "Failed to read file")
+			return
+		}
 	}
 
 	ext := getExtensionFromMime(mimeType)
+	id = s.settings.get(r.Context(), tenant).IDPolicy.normalize(id)
+	if !s.checkNewID(w, r, tenant, contentType, id, ext, state) {
+		return
+	}
 
-	// Store content
-	item, err := s.storage.Put(r.Context(), tenant, contentType, id, ext, content, mimeType, state)
+	// Stream the file to storage rather than reading it into memory
+	item, err := s.storage.PutStream(r.Context(), tenant, contentType, id, ext, file, header.Size, mimeType, state, nil)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
 		return
@@ -1997,7 +2411,7 @@ func (s *Server) uploadHandler(w http.ResponseWriter, r *http.Request) {
 		"key":        item.Key,
 		"filename":   header.Filename,
 		"mime_type":  mimeType,
-		"size":       len(content),
+		"size":       header.Size,
 		"message":    "File uploaded successfully",
 	})
 }
@@ -2006,60 +2420,106 @@ func (s *Server) uploadHandler(w http.ResponseWriter, r *http.Request) {
 // Webhook Trigger
 // =============================================================================
 
-// triggerWebhooks fires webhooks asynchronously for a content event
+// triggerWebhooks fires webhooks asynchronously for a content event written through the API
 func (s *Server) triggerWebhooks(tenant, event, contentType, id, name, mimeType string) {
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
+	s.recentWrites.mark(tenant, contentType, id)
+	s.dispatchWebhooks(tenant, event, contentType, id, name, mimeType)
+}
 
-		webhooks, err := s.storage.ListWebhooks(ctx, tenant)
-		if err != nil || len(webhooks) == 0 {
-			return
-		}
+// triggerPublishWebhooks fires publish webhooks carrying the publish author and message
+func (s *Server) triggerPublishWebhooks(tenant, contentType, id, name, mimeType, author, message string) {
+	s.recentWrites.mark(tenant, contentType, id)
+	s.dispatchEvent(storage.WebhookEvent{
+		Event:       "publish",
+		Tenant:      tenant,
+		Type:        contentType,
+		ID:          id,
+		Name:        name,
+		ContentType: mimeType,
+		Author:      author,
+		Message:     message,
+	})
+}
 
-		payload := storage.WebhookEvent{
-			Event:       event,
-			Tenant:      tenant,
-			Type:        contentType,
-			ID:          id,
-			Name:        name,
-			ContentType: mimeType,
-			Timestamp:   time.Now().UTC().Format(time.RFC3339),
-		}
+// triggerCommentEvent fires comment.created / comment.resolved webhooks
+func (s *Server) triggerCommentEvent(tenant, event, contentType, id string, state storage.State, comment *storage.Comment) {
+	s.dispatchEvent(storage.WebhookEvent{
+		Event:   event,
+		Tenant:  tenant,
+		Type:    contentType,
+		ID:      id,
+		State:   string(state),
+		Author:  comment.Author,
+		Comment: comment,
+	})
+}
 
-		jsonPayload, err := json.Marshal(payload)
-		if err != nil {
-			log.Error("Failed to marshal webhook payload: %v", err)
-			return
-		}
+// triggerMetadataUpdated fires metadata.updated webhooks with the resulting metadata
+func (s *Server) triggerMetadataUpdated(tenant, contentType, id string, state storage.State, metadata map[string]string) {
+	// Metadata changes rewrite the object, so suppress the matching bucket event
+	s.recentWrites.mark(tenant, contentType, id)
+	s.dispatchEvent(storage.WebhookEvent{
+		Event:    "metadata.updated",
+		Tenant:   tenant,
+		Type:     contentType,
+		ID:       id,
+		State:    string(state),
+		Metadata: metadata,
+	})
+}
 
-		client := &http.Client{Timeout: 10 * time.Second}
+// triggerTransitionRejected fires transition.rejected webhooks with the reason
+func (s *Server) triggerTransitionRejected(tenant, contentType, id, from, to, author, reason string) {
+	s.dispatchEvent(storage.WebhookEvent{
+		Event:   "transition.rejected",
+		Tenant:  tenant,
+		Type:    contentType,
+		ID:      id,
+		State:   from,
+		From:    from,
+		To:      to,
+		Author:  author,
+		Message: reason,
+	})
+}
 
-		for _, webhook := range webhooks {
-			// Check if webhook is subscribed to this event
-			subscribed := false
-			for _, e := range webhook.Events {
-				if e == event {
-					subscribed = true
-					break
-				}
-			}
-			if !subscribed {
-				continue
-			}
+// dispatchWebhooks delivers a content event to all subscribed webhooks
+func (s *Server) dispatchWebhooks(tenant, event, contentType, id, name, mimeType string) {
+	s.dispatchEvent(storage.WebhookEvent{
+		Event:       event,
+		Tenant:      tenant,
+		Type:        contentType,
+		ID:          id,
+		Name:        name,
+		ContentType: mimeType,
+	})
+}
 
-			// Fire webhook
-			go func(url string) {
-				resp, err := client.Post(url, "application/json", bytes.NewReader(jsonPayload))
-				if err != nil {
-					log.Debug("Webhook failed for %s: %v", url, err)
-					return
-				}
-				defer resp.Body.Close()
-				log.Debug("Webhook sent to %s: %d", url, resp.StatusCode)
-			}(webhook.URL)
-		}
-	}()
+// dispatchEvent delivers an event payload to all webhooks subscribed to its event
+func (s *Server) dispatchEvent(payload storage.WebhookEvent) {
+	tenant, event := payload.Tenant, payload.Event
+	if payload.Timestamp == "" {
+		payload.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	// Content changed (written, published, or deleted), so rebuild navigation
+	if !strings.HasPrefix(event, "comment.") && event != "transition.rejected" {
+		s.navigation.invalidate(tenant, payload.Type)
+	}
+
+	// Deletions are remembered for the cache manifest
+	if event == "delete" {
+		go s.recordDeletion(tenant, payload.Type, payload.ID)
+	}
+
+	// Content changes are recorded for the changes feed before the write
+	// returns (see changesSettle)
+	if !strings.HasPrefix(event, "comment.") && event != "transition.rejected" {
+		s.recordChange(payload)
+	}
+
+	// Delivered in order per item (see webhookqueue.go)
+	s.webhookQueue.enqueue(payload)
 }
 
 // =============================================================================
@@ -2177,14 +2637,15 @@ func (s *Server) getMetadataHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Content '%s' not found", id))
 		return
 	}
-	stream.Body.Close()
+	defer stream.Body.Close()
 
-	if stream.Metadata == nil {
-		writeJSON(w, http.StatusOK, map[string]string{})
-		return
+	// The ETag names the object the checksum is of
+	foundID, _ := extractIDAndExt(stream.Key, contentType, state)
+	metadata := s.withChecksum(r.Context(), tenant, contentType, foundID, state, stream)
+	if stream.ETag != "" {
+		w.Header().Set("ETag", stream.ETag)
 	}
-
-	writeJSON(w, http.StatusOK, stream.Metadata)
+	writeJSON(w, http.StatusOK, metadata)
 }
 
 // setMetadataHandler replaces all metadata on a content item
@@ -2201,6 +2662,10 @@ func (s *Server) setMetadataHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
 		return
 	}
+	if _, ok := metadata[checksumMetadata]; ok {
+		writeError(w, http.StatusBadRequest, "reserved_metadata", fmt.Sprintf("Metadata key '%s' is set by the server", checksumMetadata))
+		return
+	}
 
 	// Find the actual content file
 	stream, err := s.storage.FindContentStream(r.Context(), tenant, contentType, id, "", state)
@@ -2218,6 +2683,8 @@ func (s *Server) setMetadataHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.triggerMetadataUpdated(tenant, contentType, foundID, state, metadata)
+
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"id":       id,
 		"metadata": metadata,
@@ -2239,6 +2706,10 @@ func (s *Server) updateMetadataHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
 		return
 	}
+	if _, ok := updates[checksumMetadata]; ok {
+		writeError(w, http.StatusBadRequest, "reserved_metadata", fmt.Sprintf("Metadata key '%s' is set by the server", checksumMetadata))
+		return
+	}
 
 	// Find the actual content file
 	stream, err := s.storage.FindContentStream(r.Context(), tenant, contentType, id, "", state)
@@ -2258,6 +2729,7 @@ func (s *Server) updateMetadataHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Get updated metadata to return
 	metadata, _ := s.storage.GetMetadata(r.Context(), tenant, contentType, foundID, ext, state)
+	s.triggerMetadataUpdated(tenant, contentType, foundID, state, metadata)
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"id":       id,
@@ -2306,6 +2778,7 @@ func (s *Server) deleteMetadataHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Get updated metadata to return
 	metadata, _ := s.storage.GetMetadata(r.Context(), tenant, contentType, foundID, ext, state)
+	s.triggerMetadataUpdated(tenant, contentType, foundID, state, metadata)
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"id":       id,
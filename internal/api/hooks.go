@@ -0,0 +1,62 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"velocity/internal/plugin"
+)
+
+// applyBeforeHooks runs plugin before-hooks for a content write. JSON and text bodies
+// are buffered so hooks can inspect and modify them; large or binary bodies stream
+// through untouched (hooks see nil Content). Returns the body to store.
+func (s *Server) applyBeforeHooks(ctx context.Context, req *plugin.Request, body io.Reader, contentLength int64) (io.Reader, int64, error) {
+	if !plugin.Enabled() && len(s.wasm.forPhase(ctx, req.Tenant, req.Type, phaseWrite)) == 0 {
+		return body, contentLength, nil
+	}
+
+	if body != nil && (isJSONContent(req.ContentType) || isTextContent(req.ContentType)) {
+		data, err := io.ReadAll(io.LimitReader(body, maxValidatedSize+1))
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(data) > maxValidatedSize {
+			// Too large to hand to hooks; stitch the buffered prefix back on
+			body = io.MultiReader(bytes.NewReader(data), body)
+		} else {
+			req.Content = data
+		}
+	}
+
+	if err := s.runWriteHooks(ctx, req); err != nil {
+		return nil, 0, err
+	}
+
+	// Store the (possibly modified) buffered content
+	if req.Content != nil {
+		return bytes.NewReader(req.Content), int64(len(req.Content)), nil
+	}
+	return body, contentLength, nil
+}
+
+// runWriteHooks runs plugin before-hooks followed by the tenant's WASM write plugins
+func (s *Server) runWriteHooks(ctx context.Context, req *plugin.Request) error {
+	if err := plugin.RunBefore(ctx, req); err != nil {
+		return err
+	}
+	if req.Operation == plugin.OpCreate || req.Operation == plugin.OpUpdate {
+		return s.applyWritePlugins(ctx, req)
+	}
+	return nil
+}
+
+// writeHookRejection writes the response for a request refused by a plugin hook
+func writeHookRejection(w http.ResponseWriter, err error) {
+	if rejection, ok := err.(*plugin.Rejection); ok {
+		writeError(w, http.StatusUnprocessableEntity, "rejected_by_policy", rejection.Error())
+		return
+	}
+	writeError(w, http.StatusBadRequest, "invalid_body", "Failed to read request body")
+}
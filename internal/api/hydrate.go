@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"velocity/internal/storage"
+)
+
+const (
+	// maxHydratedItems bounds the items a list with ?include=content may have
+	maxHydratedItems = 200
+
+	// maxHydratedItemSize is the largest item whose content a list includes
+	maxHydratedItemSize = 256 << 10
+
+	// maxHydratedBytes bounds the content one list includes in all
+	maxHydratedBytes = 4 << 20
+
+	// hydrateWorkers bounds how many items of a list are read at once
+	hydrateWorkers = 8
+
+	// hydrateTimeout bounds the reads of one list
+	hydrateTimeout = 10 * time.Second
+)
+
+// Why a listed item's content was left out of a list with ?include=content
+const (
+	omittedNotJSON    = "not_json"   // only JSON items are included
+	omittedTooLarge   = "too_large"  // larger than maxHydratedItemSize
+	omittedLimit      = "limit"      // the list reached maxHydratedBytes first
+	omittedUnreadable = "unreadable" // the read failed or timed out
+)
+
+// wantsContent reports whether a list asks for its items' content with
+// ?include=content
+func wantsContent(r *http.Request) bool {
+	for _, include := range r.URL.Query()["include"] {
+		for _, field := range strings.Split(include, ",") {
+			if strings.TrimSpace(field) == "content" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hydrateItems adds the content of a listing's JSON items to their response
+// items, so a small type can be rendered from one list call. Items are read
+// a few at a time. An item left out (not JSON, too large, past the list's
+// budget, or unreadable) has content_omitted set to why, and can be fetched
+// on its own. Lists longer than maxHydratedItems are refused.
+func (s *Server) hydrateItems(ctx context.Context, tenant, contentType string, state storage.State, items []*storage.ContentItem, responseItems []map[string]interface{}) error {
+	if len(items) > maxHydratedItems {
+		return fmt.Errorf("Lists with include=content have at most %d items (this one has %d); narrow it with ?prefix= or fetch a set with ?ids=", maxHydratedItems, len(items))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, hydrateTimeout)
+	defer cancel()
+
+	// The budget is spent in list order by listed size, so the same items are
+	// included every time
+	var budget int64 = maxHydratedBytes
+	sem := make(chan struct{}, hydrateWorkers)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		id, ext := extractIDAndExt(item.Key, contentType, state)
+		switch {
+		case ext != "json":
+			responseItems[i]["content_omitted"] = omittedNotJSON
+			continue
+		case item.Size > maxHydratedItemSize:
+			responseItems[i]["content_omitted"] = omittedTooLarge
+			continue
+		case item.Size > budget:
+			responseItems[i]["content_omitted"] = omittedLimit
+			continue
+		}
+		budget -= item.Size
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(data map[string]interface{}, id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			content, reason := s.hydrateItem(ctx, tenant, contentType, id, state)
+			if reason != "" {
+				data["content_omitted"] = reason
+				return
+			}
+			data["content"] = content
+		}(responseItems[i], id)
+	}
+	wg.Wait()
+	return nil
+}
+
+// hydrateItem reads a listed JSON item's content, or returns why it can't be
+// included
+func (s *Server) hydrateItem(ctx context.Context, tenant, contentType, id string, state storage.State) (interface{}, string) {
+	stream, err := s.storage.GetStream(ctx, tenant, contentType, id, "json", state)
+	if err != nil {
+		return nil, omittedUnreadable
+	}
+	defer stream.Body.Close()
+
+	// The item may have grown since it was listed
+	content, release, err := readPooled(io.LimitReader(stream.Body, maxHydratedItemSize+1), stream.Size)
+	defer release()
+	if err != nil {
+		return nil, omittedUnreadable
+	}
+	if len(content) > maxHydratedItemSize {
+		return nil, omittedTooLarge
+	}
+	content, _ = s.decryptFields(ctx, tenant, contentType, content)
+	return bulkContent("application/json", content), ""
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const (
+	idempotencyHeader     = "Idempotency-Key"
+	idempotencyCollection = "idempotency"
+	idempotencyTTL        = 24 * time.Hour
+	maxReplayBodySize     = 1 << 20 // 1MB
+)
+
+// replayedHeaders are response headers stored and replayed with the original response
+var replayedHeaders = []string{"Content-Type", "Location", "ETag", "Last-Modified"}
+
+// idempotencyRecord is the stored outcome of a request made with an Idempotency-Key
+type idempotencyRecord struct {
+	Key         string            `json:"key"`
+	Method      string            `json:"method"`
+	Path        string            `json:"path"`
+	Fingerprint string            `json:"fingerprint"`
+	Status      int               `json:"status"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+}
+
+// idempotencyStore persists request outcomes and tracks in-flight keys on this node
+type idempotencyStore struct {
+	mu       sync.Mutex
+	inflight map[string]bool
+	storage  storage.Storage
+	leader   *leaderElector // only the leader sweeps expired records
+}
+
+func newIdempotencyStore(s storage.Storage, leader *leaderElector) *idempotencyStore {
+	is := &idempotencyStore{
+		inflight: make(map[string]bool),
+		storage:  s,
+		leader:   leader,
+	}
+	go is.sweepLoop()
+	return is
+}
+
+// documentID scopes a client key to a tenant
+func (is *idempotencyStore) documentID(tenant, key string) string {
+	sum := sha256.Sum256([]byte(tenant + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (is *idempotencyStore) acquire(id string) bool {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	if is.inflight[id] {
+		return false
+	}
+	is.inflight[id] = true
+	return true
+}
+
+func (is *idempotencyStore) release(id string) {
+	is.mu.Lock()
+	delete(is.inflight, id)
+	is.mu.Unlock()
+}
+
+func (is *idempotencyStore) get(ctx context.Context, id string) *idempotencyRecord {
+	data, err := is.storage.GetDocument(ctx, "", idempotencyCollection, id)
+	if err != nil {
+		return nil
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil
+	}
+	return &record
+}
+
+func (is *idempotencyStore) put(ctx context.Context, id string, record *idempotencyRecord) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		log.Error("Failed to marshal idempotency record: %v", err)
+		return
+	}
+	if err := is.storage.PutDocument(ctx, "", idempotencyCollection, id, data); err != nil {
+		log.Error("Failed to store idempotency record: %v", err)
+	}
+}
+
+// sweepLoop runs periodic cleanup of expired idempotency records
+func (is *idempotencyStore) sweepLoop() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		is.sweep()
+	}
+}
+
+func (is *idempotencyStore) sweep() {
+	if !is.leader.isLeader() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	ids, err := is.storage.ListDocuments(ctx, "", idempotencyCollection)
+	if err != nil {
+		log.Error("Idempotency sweep error: %v", err)
+		return
+	}
+
+	deleted := 0
+	for _, id := range ids {
+		if is.get(ctx, id) != nil {
+			continue
+		}
+		if err := is.storage.DeleteDocument(ctx, "", idempotencyCollection, id); err == nil {
+			deleted++
+		}
+	}
+
+	if deleted > 0 {
+		log.Info("Idempotency sweep: deleted %d expired records", deleted)
+	}
+}
+
+// hashingBody hashes everything read through it so the request body can be
+// fingerprinted while it streams to the handler
+type hashingBody struct {
+	io.ReadCloser
+	hash hash.Hash
+}
+
+func (hb *hashingBody) Read(p []byte) (int, error) {
+	n, err := hb.ReadCloser.Read(p)
+	hb.hash.Write(p[:n])
+	return n, err
+}
+
+// Close is deferred until the fingerprint is taken; the server closes the real body
+func (hb *hashingBody) Close() error {
+	return nil
+}
+
+// fingerprint drains any unread body and returns the request fingerprint
+func (hb *hashingBody) fingerprint() string {
+	io.Copy(io.Discard, hb)
+	return hex.EncodeToString(hb.hash.Sum(nil))
+}
+
+func newHashingBody(r *http.Request) *hashingBody {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery + "\n"))
+	return &hashingBody{ReadCloser: r.Body, hash: h}
+}
+
+// recordingWriter captures the response so it can be replayed
+type recordingWriter struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(p []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	if !rw.overflow {
+		if rw.body.Len()+len(p) > maxReplayBodySize {
+			rw.overflow = true
+			rw.body.Reset()
+		} else {
+			rw.body.Write(p)
+		}
+	}
+	return rw.ResponseWriter.Write(p)
+}
+
+// idempotencyHandler replays the original response for POST/PUT requests that
+// repeat an Idempotency-Key, so client retries don't create duplicate versions
+// or fire webhooks twice
+func (s *Server) idempotencyHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyHeader)
+		if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPut) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id := s.idempotency.documentID(s.getTenant(r), key)
+		if !s.idempotency.acquire(id) {
+			writeError(w, http.StatusConflict, "idempotency_in_progress", "A request with this Idempotency-Key is still in progress")
+			return
+		}
+		defer s.idempotency.release(id)
+
+		body := newHashingBody(r)
+		r.Body = body
+
+		// Replay a previous response if this key has been seen
+		if record := s.idempotency.get(r.Context(), id); record != nil {
+			if record.Fingerprint != body.fingerprint() {
+				writeError(w, http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency-Key was already used for a different request")
+				return
+			}
+			for name, value := range record.Headers {
+				w.Header().Set(name, value)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.Status)
+			w.Write(record.Body)
+			return
+		}
+
+		recorder := &recordingWriter{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		// Server errors are not stored so the request can be retried
+		if recorder.status == 0 || recorder.status >= 500 || recorder.overflow {
+			return
+		}
+
+		now := time.Now()
+		record := &idempotencyRecord{
+			Key:         key,
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			Fingerprint: body.fingerprint(),
+			Status:      recorder.status,
+			Headers:     make(map[string]string),
+			Body:        recorder.body.Bytes(),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(idempotencyTTL),
+		}
+		for _, name := range replayedHeaders {
+			if value := w.Header().Get(name); value != "" {
+				record.Headers[name] = value
+			}
+		}
+
+		s.idempotency.put(context.Background(), id, record)
+	})
+}
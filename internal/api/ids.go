@@ -0,0 +1,123 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"velocity/internal/storage"
+)
+
+// reservedIDSegments can't be a segment of a new content ID: state names and
+// route suffixes would route requests for the item somewhere else on the
+// legacy /content/{type}/{id} routes
+var reservedIDSegments = []string{
+	"draft", "pending", "live", "items", "states",
+	"metadata", "versions", "history", "diff", "transition",
+	"variants", "seo-report", "comments", "from-template", "attachments",
+	"renditions", "import-zip", "export.zip", "lineage-export", "branch",
+	"events",
+}
+
+// defaultMaxIDLength keeps storage keys well under the S3 limit
+const defaultMaxIDLength = 256
+
+// IDPolicy constrains the IDs of new content (tenant settings id_policy).
+// The built-in rules apply with or without one: no reserved segments, no
+// segments starting with "_" (used for state and history folders in storage),
+// and at most 256 characters.
+type IDPolicy struct {
+	// Pattern is a regular expression every path segment must match, e.g. ^[a-z0-9-]+$
+	Pattern string `json:"pattern,omitempty"`
+
+	// MaxLength is the maximum ID length in characters (default 256)
+	MaxLength int `json:"max_length,omitempty"`
+
+	// Lowercase lowercases IDs on every write, so "About" and "about" are one item
+	Lowercase bool `json:"lowercase,omitempty"`
+
+	// Reserved lists segments refused in addition to the built-in ones
+	Reserved []string `json:"reserved,omitempty"`
+}
+
+// clone returns a copy of the policy (nil-safe)
+func (p *IDPolicy) clone() *IDPolicy {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.Reserved = append([]string(nil), p.Reserved...)
+	return &c
+}
+
+// validate checks a policy before it is stored
+func (p *IDPolicy) validate() error {
+	if p.MaxLength < 0 {
+		return fmt.Errorf("max_length must not be negative")
+	}
+	if p.Pattern != "" {
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %v", err)
+		}
+	}
+	return nil
+}
+
+// normalize applies the policy's case normalization to an ID
+func (p *IDPolicy) normalize(id string) string {
+	if p != nil && p.Lowercase {
+		return strings.ToLower(id)
+	}
+	return id
+}
+
+// check returns why an ID can't be used for new content, or "" if it can
+func (p *IDPolicy) check(id string) string {
+	maxLength := defaultMaxIDLength
+	if p != nil && p.MaxLength > 0 {
+		maxLength = p.MaxLength
+	}
+	if n := utf8.RuneCountInString(id); n > maxLength {
+		return fmt.Sprintf("ID is %d characters long (max %d)", n, maxLength)
+	}
+
+	reserved := reservedIDSegments
+	var pattern *regexp.Regexp
+	if p != nil {
+		reserved = append(append([]string(nil), reserved...), p.Reserved...)
+		if p.Pattern != "" {
+			pattern, _ = regexp.Compile(p.Pattern) // validated when stored
+		}
+	}
+
+	for _, segment := range strings.Split(id, "/") {
+		if strings.HasPrefix(segment, "_") {
+			return fmt.Sprintf("ID segment '%s' starts with '_', which is reserved", segment)
+		}
+		for _, word := range reserved {
+			if strings.EqualFold(segment, word) {
+				return fmt.Sprintf("ID segment '%s' is reserved", segment)
+			}
+		}
+		if pattern != nil && !pattern.MatchString(segment) {
+			return fmt.Sprintf("ID segment '%s' doesn't match the tenant's ID pattern %s", segment, p.Pattern)
+		}
+	}
+	return ""
+}
+
+// checkNewID rejects a write that would create an item whose ID breaks the
+// tenant's ID rules. Items that already exist can still be written.
+func (s *Server) checkNewID(w http.ResponseWriter, r *http.Request, tenant, contentType, id, ext string, state storage.State) bool {
+	msg := s.settings.get(r.Context(), tenant).IDPolicy.check(id)
+	if msg == "" {
+		return true
+	}
+	if exists, err := s.storage.Exists(r.Context(), tenant, contentType, id, ext, state); err == nil && exists {
+		return true
+	}
+	writeError(w, http.StatusBadRequest, "invalid_id", msg)
+	return false
+}
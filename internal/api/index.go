@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+
+	"velocity/internal/storage"
+)
+
+// =============================================================================
+// Index Handlers
+// =============================================================================
+
+// rebuildIndexHandler handles POST /api/admin/index/rebuild
+// Forgets which of the tenant's types are indexed, in both content roots, so
+// each is built again from storage the next time it's listed. Lists read the
+// bucket until then.
+func (s *Server) rebuildIndexHandler(w http.ResponseWriter, r *http.Request) {
+	if s.config.Index == nil {
+		writeError(w, http.StatusNotFound, "index_disabled", "No content index is configured (see --index)")
+		return
+	}
+	tenant := s.getTenant(r)
+	for _, root := range []string{storage.RootBlue, storage.RootGreen} {
+		if err := s.config.Index.Rebuild(r.Context(), storage.RootTenant(tenant, root)); err != nil {
+			writeError(w, http.StatusInternalServerError, "index_error", err.Error())
+			return
+		}
+	}
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"tenant":  tenant,
+		"message": "Index will be rebuilt as each type is listed",
+	})
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+// tenantHandler rejects requests whose tenant (X-Tenant header or {tenant} in
+// the path) isn't a valid identifier, and route variables that would step
+// outside their place in a storage key (e.g. "../production")
+func (s *Server) tenantHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		tenant := vars["tenant"]
+		if tenant == "" {
+			tenant = r.Header.Get("X-Tenant")
+		}
+		if tenant != "" && !storage.ValidTenant(tenant) {
+			log.Info("Rejected request for invalid tenant %q from %s", tenant, r.RemoteAddr)
+			writeError(w, http.StatusBadRequest, "invalid_tenant", fmt.Sprintf("Invalid tenant: %q (expected 1-64 letters, digits, '.', '_', or '-', starting with a letter or digit)", tenant))
+			return
+		}
+
+		for name, value := range vars {
+			if name != "tenant" && !storage.ValidKeyPath(value) {
+				log.Info("Rejected request with invalid %s %q from %s", name, value, r.RemoteAddr)
+				writeError(w, http.StatusBadRequest, "invalid_path", fmt.Sprintf("Invalid %s: %q", name, value))
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
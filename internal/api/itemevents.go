@@ -0,0 +1,160 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/storage"
+)
+
+// Kinds of item events
+const (
+	itemEventVersion         = "version"          // live content changed
+	itemEventPublish         = "publish"          // a version was published with a history record
+	itemEventMetadata        = "metadata"         // live metadata changed, content didn't
+	itemEventDeleted         = "deleted"          // live content was deleted
+	itemEventComment         = "comment"          // a review comment was left on a draft or pending copy
+	itemEventCommentResolved = "comment_resolved" // a review comment was resolved
+	itemEventRelease         = "release"          // a release that includes the item changed
+	itemEventTransaction     = "transaction"      // a transaction touched the item
+)
+
+// itemEvent is one entry of an item's activity timeline
+type itemEvent struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	State   string    `json:"state,omitempty"`
+	Version string    `json:"version,omitempty"`
+	Size    int64     `json:"size,omitempty"`
+	Author  string    `json:"author,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Ref     string    `json:"ref,omitempty"`    // comment, release, or transaction ID
+	Action  string    `json:"action,omitempty"` // release action or transaction status
+}
+
+// =============================================================================
+// Item Event Handlers
+// =============================================================================
+
+// itemEventsHandler handles GET /api/content/{type}/{id}/events
+// Returns one chronological timeline of an item's activity, merged from what
+// is already recorded: stored versions of its live content (a version whose
+// content matches the one before is a metadata change), history records,
+// review comments, the releases and transactions that included it, and its
+// deletion. ?since= (RFC 3339 or Unix seconds) drops earlier events.
+func (s *Server) itemEventsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenant := s.getTenant(r)
+	vars := mux.Vars(r)
+	contentType := vars["type"]
+	id := vars["id"]
+
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		t, err := parseSince(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_since", err.Error())
+			return
+		}
+		since = t
+	}
+
+	// The live copy names the item's extension; deleted items fall back to the schema's
+	ext := s.getExtensionFromSchema(ctx, contentType)
+	live := false
+	if stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, "", storage.StateLive); err == nil {
+		stream.Body.Close()
+		_, ext = extractIDAndExt(stream.Key, contentType, storage.StateLive)
+		live = true
+	}
+
+	versions, err := s.storage.ListVersions(ctx, tenant, contentType, id, ext)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].LastModified.Before(versions[j].LastModified) })
+
+	history, _ := s.storage.ListHistoryRecords(ctx, tenant, contentType, id)
+	records := make(map[string]*storage.HistoryRecord, len(history))
+	for _, record := range history {
+		records[record.Version] = record
+	}
+
+	events := []*itemEvent{}
+	for i, v := range versions {
+		event := &itemEvent{Time: v.LastModified, Kind: itemEventVersion, State: string(storage.StateLive), Version: v.VersionID, Size: v.Size}
+		if i > 0 && v.ETag != "" && v.ETag == versions[i-1].ETag {
+			event.Kind = itemEventMetadata
+		}
+		if record, ok := records[v.VersionID]; ok {
+			event.Kind, event.Author, event.Message = itemEventPublish, record.Author, record.Message
+			delete(records, v.VersionID)
+		}
+		events = append(events, event)
+	}
+	// History outlives versions pruned by retention
+	for _, record := range records {
+		events = append(events, &itemEvent{Time: record.Timestamp, Kind: itemEventPublish, State: string(storage.StateLive), Version: record.Version, Size: record.Size, Author: record.Author, Message: record.Message})
+	}
+
+	if !live {
+		if deletedAt, ok, err := s.storage.ContentDeletedAt(ctx, tenant, contentType, id); err == nil && ok && !deletedAt.IsZero() {
+			events = append(events, &itemEvent{Time: deletedAt, Kind: itemEventDeleted, State: string(storage.StateLive)})
+		}
+	}
+
+	for _, state := range []storage.State{storage.StateDraft, storage.StatePending} {
+		comments, _ := s.storage.ListComments(ctx, tenant, contentType, id, state)
+		for _, comment := range comments {
+			events = append(events, &itemEvent{Time: comment.CreatedAt, Kind: itemEventComment, State: string(state), Author: comment.Author, Message: comment.Message, Ref: comment.ID})
+			if comment.Resolved && !comment.ResolvedAt.IsZero() {
+				events = append(events, &itemEvent{Time: comment.ResolvedAt, Kind: itemEventCommentResolved, State: string(state), Author: comment.ResolvedBy, Ref: comment.ID})
+			}
+		}
+	}
+
+	for _, entry := range s.lineageAudit(ctx, tenant, contentType, id) {
+		if entry.Kind == "release" {
+			for _, e := range entry.Events {
+				events = append(events, &itemEvent{Time: e.Timestamp, Kind: itemEventRelease, Author: e.Author, Message: e.Message, Ref: entry.ID, Action: e.Action})
+			}
+			continue
+		}
+		at := entry.CreatedAt
+		if entry.CommittedAt != nil {
+			at = *entry.CommittedAt
+		}
+		events = append(events, &itemEvent{Time: at, Kind: itemEventTransaction, Author: entry.Author, Message: entry.Message, Ref: entry.ID, Action: entry.Status})
+	}
+
+	if len(events) == 0 {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Content '%s' not found", id))
+		return
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	if !since.IsZero() {
+		kept := events[:0]
+		for _, event := range events {
+			if !event.Time.Before(since) {
+				kept = append(kept, event)
+			}
+		}
+		events = kept
+	}
+	for _, event := range events {
+		event.Time = event.Time.UTC()
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":     id,
+		"type":   contentType,
+		"events": events,
+		"count":  len(events),
+	})
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/storage"
+)
+
+// itemState describes an item's copy in one state
+type itemState struct {
+	State        string    `json:"state"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type"`
+	Version      string    `json:"version,omitempty"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// itemDeletion describes live content that has been deleted; its versions
+// remain and can be restored
+type itemDeletion struct {
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// =============================================================================
+// Item State Handlers
+// =============================================================================
+
+// itemStatesHandler handles GET /api/content/{type}/{id}/states
+// Returns the states that currently hold an item (live, draft, pending) with
+// each copy's size, type, version, and modification time, plus the deletion
+// of its live content when it was deleted and not written since, so a UI can
+// show the whole lifecycle without probing each state.
+func (s *Server) itemStatesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenant := s.getTenant(r)
+	vars := mux.Vars(r)
+	contentType := vars["type"]
+	id := vars["id"]
+
+	states := []*itemState{}
+	liveExt := ""
+	for _, state := range contentStates {
+		stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, "", state)
+		if err != nil {
+			continue
+		}
+		stream.Body.Close()
+		if state == storage.StateLive {
+			_, liveExt = extractIDAndExt(stream.Key, contentType, state)
+		}
+		states = append(states, &itemState{
+			State:        string(state),
+			Size:         stream.Size,
+			ContentType:  stream.ContentType,
+			Version:      stream.VersionID,
+			ETag:         stream.ETag,
+			LastModified: stream.LastModified.UTC(),
+		})
+	}
+
+	var deleted *itemDeletion
+	if liveExt == "" {
+		if deletedAt, ok, err := s.storage.ContentDeletedAt(ctx, tenant, contentType, id); err == nil && ok && !deletedAt.IsZero() {
+			deleted = &itemDeletion{DeletedAt: deletedAt.UTC()}
+		}
+	}
+
+	if len(states) == 0 && deleted == nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Content '%s' not found", id))
+		return
+	}
+
+	response := map[string]interface{}{
+		"id":     id,
+		"type":   contentType,
+		"states": states,
+	}
+	if deleted != nil {
+		response["deleted"] = deleted
+	}
+	writeJSON(w, http.StatusOK, response)
+}
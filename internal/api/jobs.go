@@ -0,0 +1,301 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+)
+
+// Job statuses
+const (
+	jobRunning   = "running"
+	jobCompleted = "completed"
+	jobFailed    = "failed"
+	jobCancelled = "cancelled"
+)
+
+// maxFinishedJobs is how many finished jobs each node remembers
+const maxFinishedJobs = 100
+
+// errJobRunning is returned when a job of the same kind (and subject) is already running for a tenant
+var errJobRunning = errors.New("a job of this kind is already running")
+
+// jobFunc performs a job's work, reporting progress on the job. The context is
+// cancelled when the job is cancelled; the returned value becomes the job's result.
+type jobFunc func(ctx context.Context, j *job) (interface{}, error)
+
+// job is a background task running on this node
+type job struct {
+	mu         sync.Mutex
+	id         string
+	kind       string
+	subject    string // what the job works on, for jobs that run once per item
+	tenant     string
+	status     string
+	progress   float64
+	message    string
+	err        string
+	result     interface{}
+	createdAt  time.Time
+	finishedAt time.Time
+	cancel     context.CancelFunc
+}
+
+// jobInfo is the JSON view of a job
+type jobInfo struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"`
+	Subject    string      `json:"subject,omitempty"`
+	Tenant     string      `json:"tenant,omitempty"`
+	Status     string      `json:"status"`
+	Progress   float64     `json:"progress"` // percent complete (0-100)
+	Message    string      `json:"message,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+}
+
+// setProgress records how many of total units of work are done
+func (j *job) setProgress(done, total int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if total > 0 {
+		j.progress = float64(done) * 100 / float64(total)
+	}
+}
+
+// setMessage records a human-readable description of the current step
+func (j *job) setMessage(message string) {
+	j.mu.Lock()
+	j.message = message
+	j.mu.Unlock()
+}
+
+// info returns a snapshot of the job
+func (j *job) info() *jobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	info := &jobInfo{
+		ID:        j.id,
+		Kind:      j.kind,
+		Subject:   j.subject,
+		Tenant:    j.tenant,
+		Status:    j.status,
+		Progress:  j.progress,
+		Message:   j.message,
+		Error:     j.err,
+		Result:    j.result,
+		CreatedAt: j.createdAt,
+	}
+	if !j.finishedAt.IsZero() {
+		finished := j.finishedAt
+		info.FinishedAt = &finished
+	}
+	return info
+}
+
+func (j *job) running() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status == jobRunning
+}
+
+// jobManager runs background jobs and keeps their status in memory
+type jobManager struct {
+	mu    sync.Mutex
+	jobs  map[string]*job
+	order []string // job IDs, oldest first
+}
+
+func newJobManager() *jobManager {
+	return &jobManager{jobs: make(map[string]*job)}
+}
+
+// start runs fn in the background as a new job. Only one job of a kind runs per tenant.
+func (jm *jobManager) start(tenant, kind string, fn jobFunc) (*job, error) {
+	return jm.startFor(tenant, kind, "", fn)
+}
+
+// startFor runs fn in the background as a new job working on subject (e.g.
+// a content item). Only one job of a kind runs per tenant and subject.
+func (jm *jobManager) startFor(tenant, kind, subject string, fn jobFunc) (*job, error) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	for _, existing := range jm.jobs {
+		if existing.tenant == tenant && existing.kind == kind && existing.subject == subject && existing.running() {
+			return existing, errJobRunning
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		id:        uuid.New().String(),
+		kind:      kind,
+		subject:   subject,
+		tenant:    tenant,
+		status:    jobRunning,
+		createdAt: time.Now().UTC(),
+		cancel:    cancel,
+	}
+	jm.jobs[j.id] = j
+	jm.order = append(jm.order, j.id)
+	jm.trim()
+
+	go jm.run(ctx, j, fn)
+	return j, nil
+}
+
+func (jm *jobManager) run(ctx context.Context, j *job, fn jobFunc) {
+	defer j.cancel()
+	log.Info("Started %s job %s for tenant %s", j.kind, j.id, j.tenant)
+
+	result, err := fn(ctx, j)
+
+	j.mu.Lock()
+	j.finishedAt = time.Now().UTC()
+	j.result = result
+	switch {
+	case ctx.Err() != nil:
+		j.status = jobCancelled
+	case err != nil:
+		j.status = jobFailed
+		j.err = err.Error()
+	default:
+		j.status = jobCompleted
+		j.progress = 100
+	}
+	status := j.status
+	j.mu.Unlock()
+
+	if err != nil && status == jobFailed {
+		log.Error("%s job %s failed: %v", j.kind, j.id, err)
+	} else {
+		log.Info("%s job %s %s", j.kind, j.id, status)
+	}
+}
+
+// trim forgets the oldest finished jobs beyond maxFinishedJobs (caller holds jm.mu)
+func (jm *jobManager) trim() {
+	finished := 0
+	for _, id := range jm.order {
+		if !jm.jobs[id].running() {
+			finished++
+		}
+	}
+
+	kept := jm.order[:0]
+	for _, id := range jm.order {
+		if finished > maxFinishedJobs && !jm.jobs[id].running() {
+			delete(jm.jobs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	jm.order = kept
+}
+
+// get returns a job by ID
+func (jm *jobManager) get(id string) (*job, bool) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	j, ok := jm.jobs[id]
+	return j, ok
+}
+
+// latest returns the most recent job of a kind for a tenant
+func (jm *jobManager) latest(tenant, kind string) (*job, bool) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	for i := len(jm.order) - 1; i >= 0; i-- {
+		if j := jm.jobs[jm.order[i]]; j.tenant == tenant && j.kind == kind {
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+// list returns every job this node remembers, newest first
+func (jm *jobManager) list() []*job {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jobs := make([]*job, 0, len(jm.order))
+	for i := len(jm.order) - 1; i >= 0; i-- {
+		jobs = append(jobs, jm.jobs[jm.order[i]])
+	}
+	return jobs
+}
+
+// =============================================================================
+// Job Handlers
+// =============================================================================
+
+// listJobsHandler handles GET /api/admin/jobs
+// Lists the jobs known to this node; ?tenant=, ?kind=, and ?status= filter the list.
+func (s *Server) listJobsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	tenant, kind, status := query.Get("tenant"), query.Get("kind"), query.Get("status")
+
+	jobs := []*jobInfo{}
+	for _, j := range s.jobs.list() {
+		info := j.info()
+		if (tenant != "" && info.Tenant != tenant) || (kind != "" && info.Kind != kind) || (status != "" && info.Status != status) {
+			continue
+		}
+		jobs = append(jobs, info)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"jobs":  jobs,
+		"count": len(jobs),
+	})
+}
+
+// getJobHandler handles GET /api/admin/jobs/{id}
+func (s *Server) getJobHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	j, ok := s.jobs.get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Job '%s' not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, j.info())
+}
+
+// cancelJobHandler handles POST /api/admin/jobs/{id}/cancel
+func (s *Server) cancelJobHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	j, ok := s.jobs.get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Job '%s' not found", id))
+		return
+	}
+	if !j.running() {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":   "job_finished",
+			"message": fmt.Sprintf("Job '%s' is no longer running", id),
+			"job":     j.info(),
+		})
+		return
+	}
+
+	// The job stops at its next cancellation check and is then reported as cancelled
+	j.cancel()
+	log.Info("Cancellation requested for %s job %s", j.kind, j.id)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"job":     j.info(),
+		"message": "Cancellation requested",
+	})
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const (
+	apiKeyPrefix      = "vk_"
+	apiKeyPrefixLen   = len(apiKeyPrefix) + 8
+	apiKeysCacheTTL   = 30 * time.Second
+	maxAPIKeyNameSize = 128
+)
+
+// publicRoutes answer without an API key even when the tenant has keys
+var publicRoutes = map[string]bool{
+	"/api":              true,
+	"/api/login":        true,
+	"/api/logout":       true,
+	"/api/session":      true,
+	"/api/health":       true,
+	"/api/health/live":  true,
+	"/api/health/ready": true,
+	"/api/version":      true,
+	"/api/openapi.json": true,
+	"/api/events/s3":    true, // Checked against the events token
+}
+
+// apiKeyContextKey is the context key for the API key a request was made with
+type apiKeyContextKey struct{}
+
+// apiKeyFromContext returns the API key a request authenticated with, or nil
+func apiKeyFromContext(ctx context.Context) *storage.APIKey {
+	key, _ := ctx.Value(apiKeyContextKey{}).(*storage.APIKey)
+	return key
+}
+
+// hashAPIKey returns the hex SHA-256 an API key's secret is stored as
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// newAPIKeySecret returns a new random secret, vk_ followed by 64 hex digits
+func newAPIKeySecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(b), nil
+}
+
+// newAPIKeyID returns a new random key ID
+func newAPIKeyID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type cachedAPIKeys struct {
+	keys      map[string]*storage.APIKey // by hash
+	expiresAt time.Time
+}
+
+// apiKeyStore loads tenant API keys with a short-lived per-node cache, so
+// every API request can be checked against them. A revoked key may be
+// accepted by other nodes until their cache expires.
+type apiKeyStore struct {
+	mu      sync.RWMutex
+	cache   map[string]*cachedAPIKeys
+	storage storage.Storage
+}
+
+func newAPIKeyStore(s storage.Storage) *apiKeyStore {
+	return &apiKeyStore{
+		cache:   make(map[string]*cachedAPIKeys),
+		storage: s,
+	}
+}
+
+// all returns a tenant's API keys keyed by hash
+func (ks *apiKeyStore) all(ctx context.Context, tenant string) (map[string]*storage.APIKey, error) {
+	ks.mu.RLock()
+	cached, ok := ks.cache[tenant]
+	ks.mu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.keys, nil
+	}
+
+	list, err := ks.storage.ListAPIKeys(ctx, tenant)
+	if err != nil && err != storage.ErrStorageNotConfigured {
+		return nil, err
+	}
+	keys := make(map[string]*storage.APIKey, len(list))
+	for _, key := range list {
+		keys[key.Hash] = key
+	}
+
+	ks.mu.Lock()
+	ks.cache[tenant] = &cachedAPIKeys{keys: keys, expiresAt: time.Now().Add(apiKeysCacheTTL)}
+	ks.mu.Unlock()
+	return keys, nil
+}
+
+// invalidate drops a tenant's cached API keys
+func (ks *apiKeyStore) invalidate(tenant string) {
+	ks.mu.Lock()
+	delete(ks.cache, tenant)
+	ks.mu.Unlock()
+}
+
+// extractAPIKey gets an API key from the X-API-Key header, or from an
+// Authorization bearer token that is an API key rather than a session
+func extractAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && strings.HasPrefix(token, apiKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// authError is why a request couldn't be authenticated, as an API error
+type authError struct {
+	status  int
+	code    string
+	message string
+}
+
+// authenticate resolves the role a request to a tenant acts with from the
+// API key and operator session token it carries (either may be empty). A key
+// that's presented must be one of the tenant's, and the request acts with its
+// role; an operator session acts as an admin. Once a tenant has keys,
+// requests with neither are refused; until then they act with roleOpen.
+func (s *Server) authenticate(ctx context.Context, tenant, secret, token string) (context.Context, *authError) {
+	keys, err := s.apiKeys.all(ctx, tenant)
+	if err != nil {
+		log.Error("Failed to load API keys for tenant %s: %v", tenant, err)
+		return nil, &authError{http.StatusServiceUnavailable, "storage_error", "Failed to check the API key"}
+	}
+
+	if secret != "" {
+		key, ok := keys[hashAPIKey(secret)]
+		if !ok {
+			return nil, &authError{http.StatusUnauthorized, "invalid_api_key", fmt.Sprintf("Invalid API key for tenant '%s'", tenant)}
+		}
+		return withRole(context.WithValue(ctx, apiKeyContextKey{}, key), keyRole(key)), nil
+	}
+
+	// An operator session acts as an admin
+	if token != "" && s.sessions.validate(token) {
+		return withRole(context.WithValue(ctx, operatorContextKey{}, true), roleAdmin), nil
+	}
+
+	if len(keys) > 0 {
+		return nil, &authError{http.StatusUnauthorized, "api_key_required", fmt.Sprintf("Tenant '%s' requires an API key", tenant)}
+	}
+	return withRole(ctx, roleOpen), nil
+}
+
+// apiKeyHandler authenticates API requests by the tenant's API keys and
+// operator sessions (see authenticate). Public routes aren't checked.
+func (s *Server) apiKeyHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			if tmpl, err := route.GetPathTemplate(); err == nil && publicRoutes[tmpl] {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		ctx, authErr := s.authenticate(r.Context(), s.getTenant(r), extractAPIKey(r), extractToken(r))
+		if authErr != nil {
+			writeError(w, authErr.status, authErr.code, authErr.message)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// apiKeyView is an API key as the API shows it, without its hash
+func apiKeyView(key *storage.APIKey) map[string]interface{} {
+	view := map[string]interface{}{
+		"id":         key.ID,
+		"role":       keyRole(key),
+		"prefix":     key.Prefix,
+		"created_at": key.CreatedAt,
+	}
+	if key.Name != "" {
+		view["name"] = key.Name
+	}
+	if len(key.Permissions) > 0 {
+		view["permissions"] = key.Permissions
+	}
+	if key.RotatedAt != nil {
+		view["rotated_at"] = key.RotatedAt
+	}
+	return view
+}
+
+// issueAPIKey gives a key a new secret, storing its hash, and returns the secret
+func (s *Server) issueAPIKey(ctx context.Context, tenant string, key *storage.APIKey) (string, error) {
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		return "", err
+	}
+	key.Prefix = secret[:apiKeyPrefixLen]
+	key.Hash = hashAPIKey(secret)
+	if err := s.storage.PutAPIKey(ctx, tenant, key); err != nil {
+		return "", err
+	}
+	s.apiKeys.invalidate(tenant)
+	return secret, nil
+}
+
+// =============================================================================
+// API Key Handlers
+// =============================================================================
+
+// listAPIKeysHandler handles GET /api/tenant/keys
+func (s *Server) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.storage.ListAPIKeys(r.Context(), s.getTenant(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+
+	views := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		views = append(views, apiKeyView(key))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys":  views,
+		"count": len(views),
+	})
+}
+
+// createAPIKeyHandler handles POST /api/tenant/keys
+// Body: {"name": "ci", "role": "editor", "permissions": ["decrypt"]} (optional;
+// role defaults to viewer; admin must be asked for). The secret is in the response only.
+func (s *Server) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+
+	var req struct {
+		Name        string   `json:"name"`
+		Role        string   `json:"role"`
+		Permissions []string `json:"permissions"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+			return
+		}
+	}
+	if len(req.Name) > maxAPIKeyNameSize {
+		writeError(w, http.StatusBadRequest, "invalid_name", fmt.Sprintf("Key name is longer than %d characters", maxAPIKeyNameSize))
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = roleViewer
+	}
+	if !validRole(req.Role) {
+		writeError(w, http.StatusBadRequest, "invalid_role", fmt.Sprintf("Unknown role: %s (expected admin, editor, or viewer)", req.Role))
+		return
+	}
+	for _, permission := range req.Permissions {
+		if !containsString(grantablePermissions, permission) {
+			writeError(w, http.StatusBadRequest, "invalid_permission", fmt.Sprintf("Permission %s can't be granted to a key (expected %s)", permission, strings.Join(grantablePermissions, ", ")))
+			return
+		}
+	}
+
+	id, err := newAPIKeyID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "key_error", "Failed to create API key")
+		return
+	}
+	key := &storage.APIKey{ID: id, Name: req.Name, Role: req.Role, Permissions: req.Permissions, CreatedAt: time.Now().UTC()}
+	secret, err := s.issueAPIKey(r.Context(), tenant, key)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	log.Info("Created %s API key %s (%s) for tenant %s", key.Role, key.ID, key.Prefix, tenant)
+	response := apiKeyView(key)
+	response["key"] = secret
+	response["message"] = "API key created; store the key now, it can't be shown again"
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// rotateAPIKeyHandler handles POST /api/tenant/keys/{id}/rotate
+// The key keeps its ID and name; the old secret stops working.
+func (s *Server) rotateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	keyID := mux.Vars(r)["id"]
+
+	key, err := s.storage.GetAPIKey(r.Context(), tenant, keyID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("API key '%s' not found", keyID))
+		return
+	}
+
+	now := time.Now().UTC()
+	key.RotatedAt = &now
+	secret, err := s.issueAPIKey(r.Context(), tenant, key)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	log.Info("Rotated API key %s (%s) for tenant %s", key.ID, key.Prefix, tenant)
+	response := apiKeyView(key)
+	response["key"] = secret
+	response["message"] = "API key rotated; store the key now, it can't be shown again"
+	writeJSON(w, http.StatusOK, response)
+}
+
+// revokeAPIKeyHandler handles DELETE /api/tenant/keys/{id}
+func (s *Server) revokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	keyID := mux.Vars(r)["id"]
+
+	if _, err := s.storage.GetAPIKey(r.Context(), tenant, keyID); err != nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("API key '%s' not found", keyID))
+		return
+	}
+	if err := s.storage.DeleteAPIKey(r.Context(), tenant, keyID); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	s.apiKeys.invalidate(tenant)
+
+	log.Info("Revoked API key %s for tenant %s", keyID, tenant)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      keyID,
+		"message": "API key revoked",
+	})
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"velocity/internal/storage"
+)
+
+const (
+	// languageKey is the metadata key holding an item's detected language
+	languageKey = "language"
+
+	// minLanguageWords is how many words a text needs before stopword detection is trusted
+	minLanguageWords = 5
+
+	// languageLookupWorkers bounds concurrent metadata lookups when filtering lists
+	languageLookupWorkers = 8
+)
+
+var htmlTagPattern = regexp.MustCompile(`(?s)<script.*?</script>|<style.*?</style>|<[^>]*>`)
+
+// languageStopwords are frequent function words per ISO 639-1 language code
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "on", "this", "are", "was", "you", "be"},
+	"es": {"el", "la", "de", "que", "y", "en", "los", "las", "del", "por", "con", "una", "para", "es", "se", "su"},
+	"fr": {"le", "la", "les", "de", "des", "et", "est", "un", "une", "du", "en", "que", "pour", "dans", "pas", "sur"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "den", "mit", "von", "sie", "auf", "für", "sich"},
+	"it": {"il", "di", "che", "la", "e", "per", "un", "una", "non", "sono", "del", "della", "gli", "le", "con", "è"},
+	"pt": {"o", "a", "de", "que", "e", "do", "da", "em", "um", "uma", "para", "com", "não", "os", "no", "na"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "zijn", "voor", "met", "die", "ook", "aan"},
+	"sv": {"och", "att", "det", "som", "en", "på", "är", "av", "för", "med", "till", "den", "inte", "har", "om", "ett"},
+	"pl": {"i", "w", "nie", "na", "się", "z", "do", "jest", "że", "to", "jak", "od", "po", "za", "dla", "przez"},
+}
+
+// scriptLanguages maps writing systems to the language they most likely indicate
+var scriptLanguages = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+}
+
+// contentText returns the human-readable text of JSON, HTML, or plain text content
+func contentText(content []byte, mimeType string) string {
+	switch {
+	case isJSONContent(mimeType):
+		var doc interface{}
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return ""
+		}
+		var parts []string
+		var collect func(v interface{})
+		collect = func(v interface{}) {
+			switch value := v.(type) {
+			case string:
+				parts = append(parts, value)
+			case []interface{}:
+				for _, item := range value {
+					collect(item)
+				}
+			case map[string]interface{}:
+				for _, item := range value {
+					collect(item)
+				}
+			}
+		}
+		collect(doc)
+		return htmlTagPattern.ReplaceAllString(strings.Join(parts, " "), " ")
+	case strings.Contains(mimeType, "html") || strings.Contains(mimeType, "xml"):
+		return htmlTagPattern.ReplaceAllString(string(content), " ")
+	case strings.HasPrefix(mimeType, "text/"):
+		return string(content)
+	}
+	return ""
+}
+
+// detectLanguage returns the ISO 639-1 code of the text's language, or "" if unknown.
+// Non-Latin scripts are identified by script; Latin-script languages by stopword frequency.
+func detectLanguage(text string) string {
+	// Script detection: count letters per script
+	letters := 0
+	scripts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, script := range scriptLanguages {
+			if unicode.Is(script.table, r) {
+				scripts[script.language]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	// Japanese text mixes kana with Han; any kana means Japanese
+	if scripts["ja"] > 0 && scripts["ja"]+scripts["zh"] > letters/2 {
+		return "ja"
+	}
+	for _, script := range scriptLanguages {
+		if scripts[script.language] > letters/2 {
+			return script.language
+		}
+	}
+
+	// Stopword detection for Latin scripts
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) < minLanguageWords {
+		return ""
+	}
+
+	best, bestScore, secondScore := "", 0, 0
+	for language, stopwords := range languageStopwords {
+		set := make(map[string]bool, len(stopwords))
+		for _, word := range stopwords {
+			set[word] = true
+		}
+		score := 0
+		for _, word := range words {
+			if set[word] {
+				score++
+			}
+		}
+		if score > bestScore || (score == bestScore && language < best) {
+			best, bestScore, secondScore = language, score, bestScore
+		} else if score > secondScore {
+			secondScore = score
+		}
+	}
+
+	// Require a clear winner
+	if bestScore < 2 || bestScore == secondScore {
+		return ""
+	}
+	return best
+}
+
+// tagLanguage adds detected language metadata for buffered text content.
+// An explicit X-Meta-Language header is left untouched.
+func tagLanguage(metadata map[string]string, content []byte, mimeType string) map[string]string {
+	if content == nil || metadata[languageKey] != "" {
+		return metadata
+	}
+	language := detectLanguage(contentText(content, mimeType))
+	if language == "" {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	metadata[languageKey] = language
+	return metadata
+}
+
+// filterByLanguage keeps the items whose language metadata matches
+func (s *Server) filterByLanguage(ctx context.Context, tenant, contentType string, state storage.State, items []*storage.ContentItem, language string) []*storage.ContentItem {
+	matches := make([]bool, len(items))
+
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < languageLookupWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				// Items listed from the content index carry their metadata
+				if items[i].Metadata != nil {
+					matches[i] = strings.EqualFold(items[i].Metadata[languageKey], language)
+					continue
+				}
+				id, ext := extractIDAndExt(items[i].Key, contentType, state)
+				metadata, err := s.storage.GetMetadata(ctx, tenant, contentType, id, ext, state)
+				matches[i] = err == nil && strings.EqualFold(metadata[languageKey], language)
+			}
+		}()
+	}
+	for i := range items {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	filtered := make([]*storage.ContentItem, 0, len(items))
+	for i, item := range items {
+		if matches[i] {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const (
+	leaderLeaseKey   = "cluster/leader"
+	leaderLeaseTTL   = 30 * time.Second
+	leaderRenewEvery = 10 * time.Second
+)
+
+// leaderElector elects one node across all replicas sharing a bucket to run
+// singleton background work (garbage collection, session and idempotency sweeps).
+// Leadership is a storage lease renewed by its holder; other nodes take over
+// once it expires.
+type leaderElector struct {
+	mu      sync.RWMutex
+	storage storage.Storage
+	passive bool           // never takes leadership (delivery nodes)
+	lease   *storage.Lease // held by this node, nil when not leader
+	seen    *storage.Lease // last lease observed, held by any node
+}
+
+func newLeaderElector(s storage.Storage, passive bool) *leaderElector {
+	le := &leaderElector{storage: s, passive: passive}
+	if passive {
+		return le
+	}
+	go le.run()
+	return le
+}
+
+// isLeader reports whether this node currently holds leadership. A node stops
+// acting as leader a renewal period before its lease runs out, so an expired
+// lease is never relied on.
+func (le *leaderElector) isLeader() bool {
+	le.mu.RLock()
+	defer le.mu.RUnlock()
+	return le.lease != nil && time.Now().Before(le.lease.ExpiresAt.Add(-leaderRenewEvery))
+}
+
+// current returns the last lease this node has seen
+func (le *leaderElector) current() *storage.Lease {
+	le.mu.RLock()
+	defer le.mu.RUnlock()
+	return le.seen
+}
+
+func (le *leaderElector) run() {
+	le.elect()
+
+	ticker := time.NewTicker(leaderRenewEvery)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		le.elect()
+	}
+}
+
+// elect renews the lease this node holds, or tries to acquire a free one
+func (le *leaderElector) elect() {
+	ctx, cancel := context.WithTimeout(context.Background(), leaderRenewEvery)
+	defer cancel()
+
+	le.mu.RLock()
+	held := le.lease
+	le.mu.RUnlock()
+
+	if held != nil {
+		lease, err := le.storage.RenewLease(ctx, held, leaderLeaseTTL)
+		if err != nil {
+			log.Error("Lost leadership: %v", err)
+			le.set(nil, nil)
+			return
+		}
+		le.set(lease, lease)
+		return
+	}
+
+	lease, err := le.storage.AcquireLease(ctx, leaderLeaseKey, leaderLeaseTTL)
+	switch {
+	case err == storage.ErrLeaseHeld:
+		le.set(nil, lease)
+	case err != nil:
+		log.Debug("Failed to acquire leader lease: %v", err)
+		le.set(nil, nil)
+	default:
+		log.Info("Acquired leadership as %s", storage.NodeID)
+		le.set(lease, lease)
+	}
+}
+
+func (le *leaderElector) set(lease, seen *storage.Lease) {
+	le.mu.Lock()
+	le.lease = lease
+	le.seen = seen
+	le.mu.Unlock()
+}
+
+// =============================================================================
+// Cluster Handlers
+// =============================================================================
+
+// leaderHandler handles GET /api/admin/cluster/leader
+func (s *Server) leaderHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node":   storage.NodeID,
+		"leader": s.leader.isLeader(),
+		"lease":  s.leader.current(),
+	})
+}
@@ -0,0 +1,341 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+// lineageFile describes one file of a lineage archive in its manifest
+type lineageFile struct {
+	Path         string     `json:"path"`
+	Size         int64      `json:"size"`
+	SHA256       string     `json:"sha256"`
+	ContentType  string     `json:"content_type,omitempty"`
+	Version      string     `json:"version,omitempty"`
+	State        string     `json:"state,omitempty"`
+	Latest       bool       `json:"latest,omitempty"`
+	LastModified *time.Time `json:"last_modified,omitempty"`
+}
+
+// lineageManifest is the last entry of a lineage archive: what it holds, with
+// checksums, so the archive can be shown to be complete and unaltered
+type lineageManifest struct {
+	Tenant     string            `json:"tenant"`
+	Type       string            `json:"type"`
+	ID         string            `json:"id"`
+	Root       string            `json:"root"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Versions   int               `json:"versions"`
+	History    int               `json:"history"`
+	Comments   int               `json:"comments"`
+	Audit      int               `json:"audit"`
+	Files      []*lineageFile    `json:"files"`
+	Errors     map[string]string `json:"errors,omitempty"` // parts that couldn't be read, by path
+}
+
+// lineageAuditEntry is a release or transaction that included the item;
+// both are kept after they finish, as the record of who changed what
+type lineageAuditEntry struct {
+	Kind        string         `json:"kind"` // release or transaction
+	ID          string         `json:"id"`
+	Name        string         `json:"name,omitempty"`
+	Status      string         `json:"status"`
+	Author      string         `json:"author,omitempty"`
+	Message     string         `json:"message,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	CommittedAt *time.Time     `json:"committed_at,omitempty"`
+	Item        interface{}    `json:"item"`              // the release item or transaction operations for this item
+	Results     []txResult     `json:"results,omitempty"` // transaction results for this item
+	Events      []releaseEvent `json:"events,omitempty"`  // release history
+}
+
+// lineageAudit returns the releases and transactions that included an item, oldest first
+func (s *Server) lineageAudit(ctx context.Context, tenant, contentType, id string) []*lineageAuditEntry {
+	var entries []*lineageAuditEntry
+
+	releaseIDs, _ := s.storage.ListDocuments(ctx, tenant, releasesCollection)
+	for _, releaseID := range releaseIDs {
+		rel, err := s.getRelease(ctx, tenant, releaseID)
+		if err != nil {
+			continue
+		}
+		for _, item := range rel.Items {
+			if item.Type == contentType && item.ID == id {
+				entries = append(entries, &lineageAuditEntry{
+					Kind:      "release",
+					ID:        rel.ID,
+					Name:      rel.Name,
+					Status:    rel.Status,
+					CreatedAt: rel.CreatedAt,
+					Item:      item,
+					Events:    rel.History,
+				})
+				break
+			}
+		}
+	}
+
+	txIDs, _ := s.storage.ListDocuments(ctx, tenant, transactionsCollection)
+	for _, txID := range txIDs {
+		tx, err := s.getTransaction(ctx, tenant, txID)
+		if err != nil {
+			continue
+		}
+		var ops []txOperation
+		for _, op := range tx.Operations {
+			if op.Type == contentType && op.ID == id {
+				ops = append(ops, op)
+			}
+		}
+		if len(ops) == 0 {
+			continue
+		}
+		entry := &lineageAuditEntry{
+			Kind:        "transaction",
+			ID:          tx.ID,
+			Status:      tx.Status,
+			Author:      tx.Author,
+			Message:     tx.Message,
+			CreatedAt:   tx.CreatedAt,
+			CommittedAt: tx.CommittedAt,
+			Item:        ops,
+		}
+		for _, result := range tx.Results {
+			if result.Type == contentType && result.ID == id {
+				entry.Results = append(entry.Results, result)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+	return entries
+}
+
+// lineageWriter writes the files of a lineage archive, recording each in the manifest
+type lineageWriter struct {
+	archive  *zip.Writer
+	manifest *lineageManifest
+}
+
+// copy writes body as a file of the archive
+func (lw *lineageWriter) copy(file *lineageFile, body io.Reader, modified time.Time) error {
+	header := &zip.FileHeader{Name: file.Path, Modified: modified, Method: zip.Store}
+	if compressible(file.ContentType) {
+		header.Method = zip.Deflate
+	}
+	entry, err := lw.archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if file.Size, err = copyBuffered(io.MultiWriter(entry, hash), body); err != nil {
+		return err
+	}
+	file.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	lw.manifest.Files = append(lw.manifest.Files, file)
+	return nil
+}
+
+// json writes v as an indented JSON file of the archive
+func (lw *lineageWriter) json(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return lw.copy(&lineageFile{Path: name, ContentType: "application/json"}, bytes.NewReader(data), time.Now().UTC())
+}
+
+// stream writes a stored body as a file of the archive. A body that can't be
+// opened is noted in the manifest; a failure while copying ends the export.
+func (lw *lineageWriter) stream(file *lineageFile, open func() (*storage.ContentStream, error)) error {
+	stream, err := open()
+	if err != nil {
+		if lw.manifest.Errors == nil {
+			lw.manifest.Errors = make(map[string]string)
+		}
+		lw.manifest.Errors[file.Path] = err.Error()
+		return nil
+	}
+	defer stream.Body.Close()
+
+	file.ContentType = stream.ContentType
+	modified := stream.LastModified.UTC()
+	file.LastModified = &modified
+	return lw.copy(file, stream.Body, modified)
+}
+
+// =============================================================================
+// Lineage Export Handlers
+// =============================================================================
+
+// lineageExportHandler handles GET /api/content/{type}/{id}/lineage-export
+// Streams a zip of an item's full lineage, for legal discovery: every stored
+// version of its live content (versions/), its current draft, pending, and
+// live copies (states/), history records (history.json), review comments
+// (comments.json), the releases and transactions that included it
+// (audit.json), and _manifest.json listing every file with its SHA-256.
+// Items that have since been deleted are exported from what remains.
+func (s *Server) lineageExportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenant := s.getTenant(r)
+	vars := mux.Vars(r)
+	contentType := vars["type"]
+	id := vars["id"]
+
+	// Current copies name the item's extension; deleted items fall back to the schema's
+	ext := ""
+	current := make(map[storage.State]string)
+	for _, state := range contentStates {
+		stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, "", state)
+		if err != nil {
+			continue
+		}
+		stream.Body.Close()
+		_, stateExt := extractIDAndExt(stream.Key, contentType, state)
+		current[state] = stateExt
+		if ext == "" || state == storage.StateLive {
+			ext = stateExt
+		}
+	}
+	if ext == "" {
+		ext = s.getExtensionFromSchema(ctx, contentType)
+	}
+
+	versions, err := s.storage.ListVersions(ctx, tenant, contentType, id, ext)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].LastModified.Before(versions[j].LastModified) })
+
+	history, _ := s.storage.ListHistoryRecords(ctx, tenant, contentType, id)
+	if len(current) == 0 && len(versions) == 0 && len(history) == 0 {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Content '%s' not found", id))
+		return
+	}
+	if history == nil {
+		history = []*storage.HistoryRecord{}
+	}
+
+	comments := make(map[string][]*storage.Comment)
+	commentCount := 0
+	for _, state := range []storage.State{storage.StateDraft, storage.StatePending} {
+		list, err := s.storage.ListComments(ctx, tenant, contentType, id, state)
+		if err != nil || list == nil {
+			list = []*storage.Comment{}
+		}
+		comments[string(state)] = list
+		commentCount += len(list)
+	}
+	audit := s.lineageAudit(ctx, tenant, contentType, id)
+	if audit == nil {
+		audit = []*lineageAuditEntry{}
+	}
+
+	root := storage.RootFromContext(ctx)
+	if root == "" {
+		root = s.roots.ActiveRoot(ctx, tenant).Active
+	}
+	lw := &lineageWriter{manifest: &lineageManifest{
+		Tenant:     tenant,
+		Type:       contentType,
+		ID:         id,
+		Root:       root,
+		ExportedAt: time.Now().UTC(),
+		Versions:   len(versions),
+		History:    len(history),
+		Comments:   commentCount,
+		Audit:      len(audit),
+		Files:      []*lineageFile{},
+	}}
+
+	log.Info("Lineage export of %s/%s for tenant %s: %d versions, %d history records", contentType, id, tenant, len(versions), len(history))
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", contentType+"-"+path.Base(id)+"-lineage.zip"))
+	w.WriteHeader(http.StatusOK)
+
+	// The status is sent, so failures from here on can only cut the archive short
+	lw.archive = zip.NewWriter(w)
+	fail := func(err error) {
+		log.Error("Lineage export of %s/%s stopped: %v", contentType, id, err)
+	}
+
+	for _, v := range versions {
+		if ctx.Err() != nil {
+			return
+		}
+		file := &lineageFile{Path: "versions/" + v.VersionID, Version: v.VersionID, Latest: v.IsLatest}
+		if ext != "" {
+			file.Path += "." + ext
+		}
+		versionID := v.VersionID
+		if err := lw.stream(file, func() (*storage.ContentStream, error) {
+			return s.storage.GetVersionStream(ctx, tenant, contentType, id, ext, versionID)
+		}); err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	for _, state := range contentStates {
+		stateExt, ok := current[state]
+		if !ok {
+			continue
+		}
+		file := &lineageFile{Path: "states/" + string(state), State: string(state)}
+		if stateExt != "" {
+			file.Path += "." + stateExt
+		}
+		state := state
+		if err := lw.stream(file, func() (*storage.ContentStream, error) {
+			stream, err := s.storage.GetStream(ctx, tenant, contentType, id, stateExt, state)
+			if err == nil {
+				file.Version = stream.VersionID
+			}
+			return stream, err
+		}); err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	for _, file := range []struct {
+		name string
+		v    interface{}
+	}{{"history.json", history}, {"comments.json", comments}, {"audit.json", audit}} {
+		if err := lw.json(file.name, file.v); err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	entry, err := lw.archive.CreateHeader(&zip.FileHeader{Name: zipManifestName, Modified: lw.manifest.ExportedAt, Method: zip.Deflate})
+	if err == nil {
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(lw.manifest)
+	}
+	if err == nil {
+		err = lw.archive.Close()
+	}
+	if err != nil {
+		fail(err)
+	}
+}
@@ -0,0 +1,316 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"velocity/internal/outbound"
+	"velocity/internal/storage"
+)
+
+const (
+	reportsCollection   = "reports"
+	brokenLinksReportID = "broken-links"
+	brokenLinksJobKind  = "broken-links"
+	maxExternalLinks    = 500
+	externalLinkTimeout = 5 * time.Second
+)
+
+var (
+	// internalLinkPattern matches references to content: /content/{tenant}/{type}/{id}
+	// (public URLs, optionally absolute) and /api/content/{type}/{id}
+	internalLinkPattern = regexp.MustCompile(`(/api)?/content/([A-Za-z0-9_-]+)/([^\s"'<>?#)\\]+)`)
+
+	// externalLinkPattern matches absolute http(s) URLs
+	externalLinkPattern = regexp.MustCompile(`https?://[^\s"'<>)\\]+`)
+)
+
+// contentRef is an internal reference extracted from content
+type contentRef struct {
+	Link   string
+	Tenant string
+	Type   string
+	ID     string
+}
+
+// brokenLink is a dead reference found in live content
+type brokenLink struct {
+	Type   string `json:"type"`             // content type containing the link
+	ID     string `json:"id"`               // content item containing the link
+	Link   string `json:"link"`             // the link as written
+	Reason string `json:"reason"`           // why the link is broken
+	Status int    `json:"status,omitempty"` // HTTP status for external links
+}
+
+// brokenLinksReport is the stored result of a broken link scan
+type brokenLinksReport struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	External    bool         `json:"external"`
+	Scanned     int          `json:"scanned"` // content items scanned
+	Links       int          `json:"links"`   // links checked
+	Broken      []brokenLink `json:"broken"`
+	Count       int          `json:"count"`
+}
+
+// extractLinks returns the internal references and external URLs found in text
+func extractLinks(text string) ([]contentRef, []string) {
+	// JSON encoders may escape slashes
+	text = strings.ReplaceAll(text, `\/`, "/")
+
+	var refs []contentRef
+	for _, m := range internalLinkPattern.FindAllStringSubmatch(text, -1) {
+		path := strings.TrimRight(m[3], ".,;:!")
+		ref := contentRef{Link: strings.TrimRight(m[0], ".,;:!")}
+		if m[1] != "" {
+			// /api/content/{type}/{id}
+			ref.Type, ref.ID = m[2], path
+		} else {
+			// /content/{tenant}/{type}/{id}
+			parts := strings.SplitN(path, "/", 2)
+			if len(parts) != 2 || parts[1] == "" {
+				continue
+			}
+			ref.Tenant, ref.Type, ref.ID = m[2], parts[0], parts[1]
+		}
+		refs = append(refs, ref)
+	}
+
+	var external []string
+	for _, link := range externalLinkPattern.FindAllString(text, -1) {
+		if !internalLinkPattern.MatchString(link) {
+			external = append(external, strings.TrimRight(link, ".,;:!"))
+		}
+	}
+	return refs, external
+}
+
+// liveIndex tracks which live content exists, loading each content type on demand
+type liveIndex struct {
+	storage storage.Storage
+	tenant  string
+	types   map[string]map[string]bool // type -> set of "id" and "id.ext"
+}
+
+func (li *liveIndex) exists(ctx context.Context, contentType, id string) bool {
+	ids, ok := li.types[contentType]
+	if !ok {
+		ids = make(map[string]bool)
+		items, _ := li.storage.List(ctx, li.tenant, contentType, storage.StateLive)
+		for _, item := range items {
+			itemID, ext := extractIDAndExt(item.Key, contentType, storage.StateLive)
+			ids[itemID] = true
+			if ext != "" {
+				ids[itemID+"."+ext] = true
+			}
+		}
+		li.types[contentType] = ids
+	}
+	return ids[strings.TrimSuffix(id, "/")]
+}
+
+// scanBrokenLinks scans a tenant's live HTML/JSON/text content for dead links
+func (s *Server) scanBrokenLinks(ctx context.Context, j *job, tenant string, checkExternal bool) (*brokenLinksReport, error) {
+	report := &brokenLinksReport{External: checkExternal, Broken: []brokenLink{}}
+	index := &liveIndex{storage: s.storage, tenant: tenant, types: make(map[string]map[string]bool)}
+
+	types, err := s.storage.ListContentTypes(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list content types: %w", err)
+	}
+
+	// Collect scannable items up front so progress is meaningful
+	type scanItem struct {
+		contentType, id, ext string
+	}
+	var items []scanItem
+	for _, contentType := range types {
+		list, err := s.storage.List(ctx, tenant, contentType, storage.StateLive)
+		if err != nil {
+			continue
+		}
+		for _, item := range list {
+			mimeType := mimeFromExt(strings.ToLower(filepath.Ext(item.Key)))
+			if !isJSONContent(mimeType) && !isTextContent(mimeType) {
+				continue
+			}
+			id, ext := extractIDAndExt(item.Key, contentType, storage.StateLive)
+			items = append(items, scanItem{contentType, id, ext})
+		}
+	}
+
+	// External URLs are checked once each, after the scan
+	externalRefs := make(map[string][]scanItem)
+	var externalOrder []string
+
+	for i, item := range items {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		j.setProgress(i, len(items))
+
+		content, err := s.storage.Get(ctx, tenant, item.contentType, item.id, item.ext, storage.StateLive)
+		if err != nil {
+			continue
+		}
+		report.Scanned++
+
+		refs, external := extractLinks(string(content.Content))
+		for _, ref := range refs {
+			if ref.Tenant != "" && ref.Tenant != tenant {
+				continue // other tenants' content is not checked
+			}
+			report.Links++
+			if !index.exists(ctx, ref.Type, ref.ID) {
+				report.Broken = append(report.Broken, brokenLink{
+					Type:   item.contentType,
+					ID:     item.id,
+					Link:   ref.Link,
+					Reason: fmt.Sprintf("%s/%s does not exist", ref.Type, ref.ID),
+				})
+			}
+		}
+
+		if checkExternal {
+			for _, link := range external {
+				if _, seen := externalRefs[link]; !seen {
+					externalOrder = append(externalOrder, link)
+				}
+				externalRefs[link] = append(externalRefs[link], item)
+			}
+		}
+	}
+
+	if checkExternal {
+		if len(externalOrder) > maxExternalLinks {
+			externalOrder = externalOrder[:maxExternalLinks]
+		}
+		client := outbound.Client(externalLinkTimeout)
+		for i, link := range externalOrder {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			j.setMessage(fmt.Sprintf("checking external link %d of %d", i+1, len(externalOrder)))
+			report.Links++
+
+			status, reason := checkExternalLink(ctx, client, link)
+			if reason == "" {
+				continue
+			}
+			for _, item := range externalRefs[link] {
+				report.Broken = append(report.Broken, brokenLink{
+					Type:   item.contentType,
+					ID:     item.id,
+					Link:   link,
+					Reason: reason,
+					Status: status,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(report.Broken, func(a, b int) bool {
+		if report.Broken[a].Type != report.Broken[b].Type {
+			return report.Broken[a].Type < report.Broken[b].Type
+		}
+		return report.Broken[a].ID < report.Broken[b].ID
+	})
+	report.Count = len(report.Broken)
+	report.GeneratedAt = time.Now().UTC()
+	return report, nil
+}
+
+// checkExternalLink returns a reason if an external URL is dead (404/410 or unreachable)
+func checkExternalLink(ctx context.Context, client *http.Client, link string) (int, string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, link, nil)
+	if err != nil {
+		return 0, "invalid URL"
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Sprintf("unreachable: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return resp.StatusCode, fmt.Sprintf("returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, ""
+}
+
+// =============================================================================
+// Broken Link Report Handlers
+// =============================================================================
+
+// startBrokenLinksHandler handles POST /api/reports/broken-links
+// Starts a background scan; ?external=true also checks external URLs.
+func (s *Server) startBrokenLinksHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	checkExternal := r.URL.Query().Get("external") == "true"
+
+	j, err := s.jobs.start(tenant, brokenLinksJobKind, func(ctx context.Context, j *job) (interface{}, error) {
+		report, err := s.scanBrokenLinks(ctx, j, tenant, checkExternal)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(report)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal report: %w", err)
+		}
+		if err := s.storage.PutDocument(ctx, tenant, reportsCollection, brokenLinksReportID, data); err != nil {
+			return nil, err
+		}
+		return map[string]int{"scanned": report.Scanned, "links": report.Links, "broken": report.Count}, nil
+	})
+	if err == errJobRunning {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":   "job_running",
+			"message": "A broken link scan is already running",
+			"job":     j.info(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"job":     j.info(),
+		"message": "Broken link scan started",
+	})
+}
+
+// brokenLinksReportHandler handles GET /api/reports/broken-links
+// Returns the most recent report, plus the status of a scan in progress.
+func (s *Server) brokenLinksReportHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+
+	var running *jobInfo
+	if j, ok := s.jobs.latest(tenant, brokenLinksJobKind); ok && j.running() {
+		running = j.info()
+	}
+
+	data, err := s.storage.GetDocument(r.Context(), tenant, reportsCollection, brokenLinksReportID)
+	if err != nil {
+		if running != nil {
+			writeJSON(w, http.StatusAccepted, map[string]interface{}{"job": running})
+			return
+		}
+		writeError(w, http.StatusNotFound, "not_found", "No broken link report yet; POST to /api/reports/broken-links to generate one")
+		return
+	}
+
+	var report brokenLinksReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", "Failed to parse stored report")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"report": &report,
+		"job":    running,
+	})
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+// tombstonesCollection records deletions for the cache manifest, as
+// documents named {yyyy-mm-dd}/{type}/{id} so a manifest only reads recent days
+const tombstonesCollection = "tombstones"
+
+// manifestEntry is a public content URL that changed or was deleted
+type manifestEntry struct {
+	URL          string     `json:"url"`
+	Type         string     `json:"type"`
+	ID           string     `json:"id"`
+	ETag         string     `json:"etag,omitempty"`
+	LastModified *time.Time `json:"last_modified,omitempty"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+}
+
+// recordDeletion notes that content was deleted. Deletes of content that was
+// never live are recorded too; the manifest checks live versions before
+// reporting them.
+func (s *Server) recordDeletion(tenant, contentType, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	data, _ := json.Marshal(map[string]interface{}{"type": contentType, "id": id, "deleted_at": now})
+	docID := now.Format("2006-01-02") + "/" + contentType + "/" + id
+	if err := s.storage.PutDocument(ctx, tenant, tombstonesCollection, docID, data); err != nil {
+		log.Error("Failed to record deletion of %s/%s: %v", contentType, id, err)
+	}
+}
+
+// parseSince reads a timestamp given as RFC 3339 or Unix seconds
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q (expected RFC 3339 or Unix seconds)", value)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// buildCacheManifest lists live content of the given types changed or deleted
+// at or after since (zero for everything)
+func (s *Server) buildCacheManifest(ctx context.Context, tenant string, types []string, since time.Time) ([]manifestEntry, []manifestEntry, error) {
+	changed := []manifestEntry{}
+	deleted := []manifestEntry{}
+	present := make(map[string]bool)
+
+	for _, contentType := range types {
+		items, err := s.storage.List(ctx, tenant, contentType, storage.StateLive)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list %s: %w", contentType, err)
+		}
+
+		for _, item := range items {
+			id, _ := extractIDAndExt(item.Key, contentType, storage.StateLive)
+			present[contentType+"/"+id] = true
+			if item.LastModified.Before(since) {
+				continue
+			}
+			lastModified := item.LastModified.UTC()
+			changed = append(changed, manifestEntry{
+				URL:          fmt.Sprintf("/content/%s/%s/%s", tenant, contentType, id),
+				Type:         contentType,
+				ID:           id,
+				ETag:         item.ETag,
+				LastModified: &lastModified,
+			})
+		}
+	}
+
+	// A full manifest (no since) has nothing to invalidate for deleted content
+	if since.IsZero() {
+		return sortManifest(changed), deleted, nil
+	}
+
+	tombstones, err := s.storage.ListDocuments(ctx, tenant, tombstonesCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list deletions: %w", err)
+	}
+	reported := make(map[string]bool)
+	sinceDay := since.UTC().Format("2006-01-02")
+	for _, tombstone := range tombstones {
+		day, path, ok := strings.Cut(tombstone, "/")
+		if !ok || day < sinceDay || present[path] || reported[path] {
+			continue
+		}
+		contentType, id, ok := strings.Cut(path, "/")
+		if !ok || !containsString(types, contentType) {
+			continue
+		}
+
+		// Confirm against live versions: the item may have been re-created, or
+		// only ever existed as a draft
+		deletedAt, isDeleted, err := s.storage.ContentDeletedAt(ctx, tenant, contentType, id)
+		if err != nil || !isDeleted || deletedAt.Before(since) {
+			continue
+		}
+		reported[path] = true
+		deletedAt = deletedAt.UTC()
+		deleted = append(deleted, manifestEntry{
+			URL:       fmt.Sprintf("/content/%s/%s/%s", tenant, contentType, id),
+			Type:      contentType,
+			ID:        id,
+			DeletedAt: &deletedAt,
+		})
+	}
+
+	return sortManifest(changed), sortManifest(deleted), nil
+}
+
+// sortManifest orders entries by URL
+func sortManifest(entries []manifestEntry) []manifestEntry {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].URL < entries[j].URL })
+	return entries
+}
+
+// =============================================================================
+// Cache Manifest Handlers
+// =============================================================================
+
+// cacheManifestHandler handles GET /api/cache-manifest
+// ?since= (RFC 3339 or Unix seconds) limits the manifest to changes at or after
+// that time; ?type= limits it to one content type. Pass the response's
+// next_since as ?since= on the following call.
+func (s *Server) cacheManifestHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	query := r.URL.Query()
+	started := time.Now().UTC()
+
+	var since time.Time
+	if value := query.Get("since"); value != "" {
+		t, err := parseSince(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_since", err.Error())
+			return
+		}
+		since = t
+	}
+
+	var types []string
+	if contentType := query.Get("type"); contentType != "" {
+		types = []string{contentType}
+	} else {
+		var err error
+		types, err = s.storage.ListContentTypes(r.Context(), tenant)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+			return
+		}
+	}
+
+	changed, deleted, err := s.buildCacheManifest(r.Context(), tenant, types, since)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	result := map[string]interface{}{
+		"tenant":     tenant,
+		"changed":    changed,
+		"deleted":    deleted,
+		"count":      len(changed) + len(deleted),
+		"next_since": started.Format(time.RFC3339),
+	}
+	if !since.IsZero() {
+		result["since"] = since.UTC().Format(time.RFC3339)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+// maxMergeSize is the largest document a merge is proposed for
+const maxMergeSize = 10 << 20
+
+// writeLocks serializes conditional writes of the same item on this node, so
+// an If-Match check and the write that follows it can't interleave with another.
+// Items share a fixed set of locks by hash.
+var writeLocks [256]sync.Mutex
+
+func lockWrite(key string) func() {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	lock := &writeLocks[h.Sum32()%uint32(len(writeLocks))]
+	lock.Lock()
+	return lock.Unlock
+}
+
+// etagsMatch compares an If-Match value (possibly a list, weak, or "*") with
+// a stored ETag
+func etagsMatch(ifMatch, etag string) bool {
+	normalize := func(tag string) string {
+		return strings.Trim(strings.TrimPrefix(strings.TrimSpace(tag), "W/"), `"`)
+	}
+	for _, tag := range strings.Split(ifMatch, ",") {
+		if strings.TrimSpace(tag) == "*" || normalize(tag) == normalize(etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeConflict is a field both sides changed differently
+type mergeConflict struct {
+	Path    string      `json:"path"`
+	Base    interface{} `json:"base"`
+	Current interface{} `json:"current"`
+	Yours   interface{} `json:"yours"`
+}
+
+// missing marks a key absent from one side of a merge
+type missingValue struct{}
+
+var missing = missingValue{}
+
+// merge3 merges two JSON values edited from a common base. Objects merge key by
+// key; anything else (including arrays) is replaced whole. Where both sides
+// changed a value differently the current value is kept and a conflict recorded.
+func merge3(base, current, yours interface{}, path string) (interface{}, []mergeConflict) {
+	switch {
+	case reflect.DeepEqual(current, yours):
+		return current, nil
+	case reflect.DeepEqual(base, current):
+		return yours, nil
+	case reflect.DeepEqual(base, yours):
+		return current, nil
+	}
+
+	currentObj, ok1 := current.(map[string]interface{})
+	yoursObj, ok2 := yours.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return current, []mergeConflict{{Path: path, Base: nullIfMissing(base), Current: nullIfMissing(current), Yours: nullIfMissing(yours)}}
+	}
+	baseObj, _ := base.(map[string]interface{})
+
+	keys := make(map[string]bool)
+	for _, obj := range []map[string]interface{}{baseObj, currentObj, yoursObj} {
+		for key := range obj {
+			keys[key] = true
+		}
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	merged := make(map[string]interface{})
+	var conflicts []mergeConflict
+	for _, key := range sorted {
+		childPath := key
+		if path != "" {
+			childPath = path + "." + key
+		}
+		value, childConflicts := merge3(lookup(baseObj, key), lookup(currentObj, key), lookup(yoursObj, key), childPath)
+		conflicts = append(conflicts, childConflicts...)
+		if value != missing {
+			merged[key] = value
+		}
+	}
+	return merged, conflicts
+}
+
+func lookup(obj map[string]interface{}, key string) interface{} {
+	if value, ok := obj[key]; ok {
+		return value
+	}
+	return missing
+}
+
+func nullIfMissing(value interface{}) interface{} {
+	if value == missing {
+		return nil
+	}
+	return value
+}
+
+// checkIfMatch enforces an If-Match header on an update. It returns false after
+// writing a 412 response when the stored content has changed; for JSON the
+// response carries the current and base versions and a proposed merge.
+func (s *Server) checkIfMatch(w http.ResponseWriter, r *http.Request, tenant, contentType, id, ext string, state storage.State, mimeType string, body io.Reader) bool {
+	ifMatch := r.Header.Get("If-Match")
+	ctx := r.Context()
+
+	stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, ext, state)
+	if err != nil {
+		writeJSON(w, http.StatusPreconditionFailed, map[string]interface{}{
+			"error":   "precondition_failed",
+			"message": fmt.Sprintf("Content '%s' does not exist", id),
+		})
+		return false
+	}
+	stream.Body.Close()
+	if etagsMatch(ifMatch, stream.ETag) {
+		return true
+	}
+
+	result := map[string]interface{}{
+		"error":   "edit_conflict",
+		"message": "Content was modified since it was read",
+		"current": map[string]interface{}{"etag": stream.ETag, "version": stream.VersionID},
+	}
+
+	if !isJSONContent(mimeType) || !isJSONContent(stream.ContentType) {
+		writeJSON(w, http.StatusPreconditionFailed, result)
+		return false
+	}
+
+	yoursData, err := io.ReadAll(io.LimitReader(body, maxMergeSize+1))
+	if err != nil || len(yoursData) > maxMergeSize {
+		writeJSON(w, http.StatusPreconditionFailed, result)
+		return false
+	}
+
+	current, err := s.storage.Get(ctx, tenant, contentType, id, ext, state)
+	if err != nil {
+		writeJSON(w, http.StatusPreconditionFailed, result)
+		return false
+	}
+
+	var currentDoc, yoursDoc interface{}
+	if json.Unmarshal(current.Content, &currentDoc) != nil || json.Unmarshal(yoursData, &yoursDoc) != nil {
+		writeJSON(w, http.StatusPreconditionFailed, result)
+		return false
+	}
+	result["current"] = map[string]interface{}{"etag": stream.ETag, "version": stream.VersionID, "content": currentDoc}
+
+	// Without the base version (e.g. pruned), fields are merged as if both sides
+	// had added them, so only fields both sides set differently conflict
+	var baseDoc interface{} = map[string]interface{}{}
+	if base, err := s.storage.FindVersionByETag(ctx, tenant, contentType, id, ext, state, ifMatch); err == nil {
+		var doc interface{}
+		if json.Unmarshal(base.Content, &doc) == nil {
+			baseDoc = doc
+			result["base"] = map[string]interface{}{"etag": ifMatch, "version": base.VersionID, "content": doc}
+		}
+	} else {
+		log.Debug("Base version %s of %s/%s not found: %v", ifMatch, contentType, id, err)
+		result["base"] = nil
+	}
+
+	merged, conflicts := merge3(baseDoc, currentDoc, yoursDoc, "")
+	if conflicts == nil {
+		conflicts = []mergeConflict{}
+	}
+	result["merge"] = map[string]interface{}{
+		"content":   nullIfMissing(merged),
+		"clean":     len(conflicts) == 0,
+		"conflicts": conflicts,
+	}
+
+	writeJSON(w, http.StatusPreconditionFailed, result)
+	return false
+}
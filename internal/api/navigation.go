@@ -0,0 +1,339 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"velocity/internal/log"
+	"velocity/internal/models"
+	"velocity/internal/storage"
+)
+
+const (
+	defaultNavigationType = "pages"
+	menusContentType      = "menus"
+
+	// Trees are rebuilt on this node whenever their content changes here;
+	// the TTL bounds how long writes made through other nodes take to show
+	navigationCacheTTL = 60 * time.Second
+)
+
+var errMenuNotFound = errors.New("menu not found")
+
+// navigationNode is one entry of a navigation tree
+type navigationNode struct {
+	ID       string            `json:"id,omitempty"`
+	Title    string            `json:"title"`
+	Slug     string            `json:"slug,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Order    int               `json:"order"`
+	Children []*navigationNode `json:"children"`
+}
+
+// navigationTree is a built tree along with the content types it was built from
+type navigationTree struct {
+	tenant    string
+	types     []string
+	nodes     []*navigationNode
+	builtAt   time.Time
+	expiresAt time.Time
+}
+
+// navigationCache holds built trees per node until their content changes
+type navigationCache struct {
+	mu         sync.Mutex
+	trees      map[string]*navigationTree
+	generation uint64 // bumped by every invalidation
+}
+
+func newNavigationCache() *navigationCache {
+	return &navigationCache{trees: make(map[string]*navigationTree)}
+}
+
+// get returns a cached tree, or nil along with the generation to pass to put
+// once the tree is built
+func (nc *navigationCache) get(key string) (*navigationTree, uint64) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	if tree, ok := nc.trees[key]; ok && time.Now().Before(tree.expiresAt) {
+		return tree, nc.generation
+	}
+	return nil, nc.generation
+}
+
+// put caches a tree unless content was invalidated while it was being built
+func (nc *navigationCache) put(key string, tree *navigationTree, generation uint64) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	if nc.generation == generation {
+		nc.trees[key] = tree
+	}
+}
+
+// invalidate drops a tenant's trees built from a content type
+func (nc *navigationCache) invalidate(tenant, contentType string) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	nc.generation++
+	for key, tree := range nc.trees {
+		if tree.tenant == tenant && containsString(tree.types, contentType) {
+			delete(nc.trees, key)
+		}
+	}
+}
+
+// loadPages reads every JSON item of a type in a state as a page, keyed by ID
+func (s *Server) loadPages(ctx context.Context, tenant, contentType string, state storage.State) (map[string]*models.Page, error) {
+	items, err := s.storage.List(ctx, tenant, contentType, state)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make(map[string]*models.Page)
+	for _, item := range items {
+		id, ext := extractIDAndExt(item.Key, contentType, state)
+		if ext != "json" {
+			continue
+		}
+		content, err := s.storage.Get(ctx, tenant, contentType, id, ext, state)
+		if err != nil {
+			log.Error("Failed to load %s/%s for navigation: %v", contentType, id, err)
+			continue
+		}
+		var page models.Page
+		if err := json.Unmarshal(content.Content, &page); err != nil {
+			log.Debug("Skipping %s/%s in navigation: %v", contentType, id, err)
+			continue
+		}
+		page.ID = id
+		pages[id] = &page
+	}
+	return pages, nil
+}
+
+// pageSlug returns a page's slug, defaulting to the last segment of its ID
+func pageSlug(page *models.Page) string {
+	if page.Slug != "" {
+		return page.Slug
+	}
+	return path.Base(page.ID)
+}
+
+// buildPageTree nests pages under their parents. Pages whose parent doesn't
+// exist (or that sit in a parent cycle) are placed at the top level.
+func buildPageTree(pages map[string]*models.Page) []*navigationNode {
+	children := make(map[string][]*models.Page)
+	var roots []*models.Page
+	for _, page := range pages {
+		if _, ok := pages[page.Parent]; ok && page.Parent != page.ID {
+			children[page.Parent] = append(children[page.Parent], page)
+		} else {
+			roots = append(roots, page)
+		}
+	}
+
+	visited := make(map[string]bool)
+	var build func(page *models.Page, parentURL string) *navigationNode
+	build = func(page *models.Page, parentURL string) *navigationNode {
+		visited[page.ID] = true
+		node := &navigationNode{
+			ID:       page.ID,
+			Title:    page.Title,
+			Slug:     page.Slug,
+			URL:      parentURL + "/" + pageSlug(page),
+			Order:    page.Order,
+			Children: []*navigationNode{},
+		}
+		for _, child := range children[page.ID] {
+			if !visited[child.ID] {
+				node.Children = append(node.Children, build(child, node.URL))
+			}
+		}
+		sortNavigation(node.Children)
+		return node
+	}
+
+	nodes := []*navigationNode{}
+	for _, page := range roots {
+		nodes = append(nodes, build(page, ""))
+	}
+
+	// Pages only reachable through a cycle are never visited from a root
+	var orphans []string
+	for id := range pages {
+		if !visited[id] {
+			orphans = append(orphans, id)
+		}
+	}
+	sort.Strings(orphans)
+	for _, id := range orphans {
+		if !visited[id] {
+			nodes = append(nodes, build(pages[id], ""))
+		}
+	}
+
+	sortNavigation(nodes)
+	return nodes
+}
+
+// sortNavigation orders siblings by order, then title, then ID
+func sortNavigation(nodes []*navigationNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Order != nodes[j].Order {
+			return nodes[i].Order < nodes[j].Order
+		}
+		if nodes[i].Title != nodes[j].Title {
+			return nodes[i].Title < nodes[j].Title
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+}
+
+// menuItem is an entry of a menu document. Items that name a page take its
+// title and URL unless they set their own, and are dropped if the page isn't
+// in the requested state.
+type menuItem struct {
+	Title    string     `json:"title"`
+	URL      string     `json:"url"`
+	Page     string     `json:"page"`
+	Order    int        `json:"order"`
+	Children []menuItem `json:"children"`
+}
+
+// buildMenuTree resolves a menu document's items against pages
+func buildMenuTree(items []menuItem, pages map[string]*models.Page, pageURLs map[string]string) []*navigationNode {
+	nodes := []*navigationNode{}
+	for _, item := range items {
+		node := &navigationNode{
+			ID:    item.Page,
+			Title: item.Title,
+			URL:   item.URL,
+			Order: item.Order,
+		}
+		if item.Page != "" {
+			page, ok := pages[item.Page]
+			if !ok {
+				continue
+			}
+			node.Slug = page.Slug
+			if node.Title == "" {
+				node.Title = page.Title
+			}
+			if node.URL == "" {
+				node.URL = pageURLs[item.Page]
+			}
+		}
+		node.Children = buildMenuTree(item.Children, pages, pageURLs)
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// pageURLs maps each page to its URL in the page tree
+func pageURLs(nodes []*navigationNode, urls map[string]string) map[string]string {
+	for _, node := range nodes {
+		urls[node.ID] = node.URL
+		pageURLs(node.Children, urls)
+	}
+	return urls
+}
+
+// buildNavigation builds the page tree for a type, or a menu when one is named
+func (s *Server) buildNavigation(ctx context.Context, tenant, contentType, menu string, state storage.State) (*navigationTree, error) {
+	pages, err := s.loadPages(ctx, tenant, contentType, state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", contentType, err)
+	}
+
+	now := time.Now()
+	tree := &navigationTree{
+		tenant:    tenant,
+		types:     []string{contentType},
+		nodes:     buildPageTree(pages),
+		builtAt:   now.UTC(),
+		expiresAt: now.Add(navigationCacheTTL),
+	}
+	if menu == "" {
+		return tree, nil
+	}
+
+	content, err := s.storage.Get(ctx, tenant, menusContentType, menu, "json", state)
+	if err != nil {
+		log.Debug("Menu %s not loaded: %v", menu, err)
+		return nil, errMenuNotFound
+	}
+	var doc struct {
+		Items []menuItem `json:"items"`
+	}
+	if err := json.Unmarshal(content.Content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse menu: %w", err)
+	}
+
+	tree.types = append(tree.types, menusContentType)
+	tree.nodes = buildMenuTree(doc.Items, pages, pageURLs(tree.nodes, make(map[string]string)))
+	return tree, nil
+}
+
+// =============================================================================
+// Navigation Handlers
+// =============================================================================
+
+// navigationHandler handles GET /api/navigation
+// ?type= names the page content type (default pages), ?menu= builds from a
+// menus/{menu} document instead, and ?state= picks the state (default live).
+func (s *Server) navigationHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	query := r.URL.Query()
+
+	contentType := query.Get("type")
+	if contentType == "" {
+		contentType = defaultNavigationType
+	}
+	menu := strings.Trim(query.Get("menu"), "/")
+
+	state := storage.StateLive
+	if stateParam := query.Get("state"); stateParam != "" {
+		if !storage.ValidState(stateParam) {
+			writeError(w, http.StatusBadRequest, "invalid_state", fmt.Sprintf("Invalid state: %s", stateParam))
+			return
+		}
+		state = storage.State(stateParam)
+	}
+
+	key := strings.Join([]string{tenant, contentType, menu, string(state)}, "\x00")
+	tree, generation := s.navigation.get(key)
+	cached := tree != nil
+	if !cached {
+		var err error
+		tree, err = s.buildNavigation(r.Context(), tenant, contentType, menu, state)
+		if err != nil {
+			if errors.Is(err, errMenuNotFound) {
+				writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Menu '%s' not found", menu))
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+			return
+		}
+		s.navigation.put(key, tree, generation)
+	}
+
+	result := map[string]interface{}{
+		"type":     contentType,
+		"state":    state,
+		"items":    tree.nodes,
+		"built_at": tree.builtAt,
+		"cached":   cached,
+	}
+	if menu != "" {
+		result["menu"] = menu
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,418 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"velocity/internal/log"
+	"velocity/internal/outbound"
+)
+
+const (
+	oidcCookieName   = "velocity_oidc"
+	oidcCallbackPath = "/auth/oidc/callback"
+	oidcFlowDuration = 10 * time.Minute
+	oidcDiscoveryTTL = time.Hour
+	oidcTimeout      = 10 * time.Second
+	defaultLoginNext = "/admin/"
+)
+
+// OIDCConfig configures single sign-on with an OpenID Connect provider.
+// Logins start operator sessions, which reach every tenant, so single sign-on
+// is only enabled with AllowedDomains or AllowedSubjects.
+type OIDCConfig struct {
+	Issuer          string
+	ClientID        string
+	ClientSecret    string   // Empty for public clients, which rely on PKCE
+	RedirectURL     string   // Callback registered with the provider (default: this server's /auth/oidc/callback)
+	AllowedDomains  []string // Domains of verified email addresses that may log in
+	AllowedSubjects []string // Subjects (sub claims) that may log in, whatever their email
+}
+
+// restricted reports whether the config limits who may log in
+func (c *OIDCConfig) restricted() bool {
+	return len(c.AllowedDomains) > 0 || len(c.AllowedSubjects) > 0
+}
+
+// oidcProvider is the part of a provider's discovery document the flow uses
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// oidcFlow is a login in progress, kept in a short-lived cookie so the
+// callback can land on any node
+type oidcFlow struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"` // PKCE code verifier
+	Next     string `json:"next"`
+}
+
+// oidcClaims are the ID token claims checked at login
+type oidcClaims struct {
+	Issuer        string          `json:"iss"`
+	Subject       string          `json:"sub"`
+	Audience      json.RawMessage `json:"aud"` // a string or a list of them
+	Expiry        int64           `json:"exp"`
+	Nonce         string          `json:"nonce"`
+	Email         string          `json:"email"`
+	EmailVerified *bool           `json:"email_verified"`
+}
+
+// audiences returns the token's audiences
+func (c *oidcClaims) audiences() []string {
+	var one string
+	if json.Unmarshal(c.Audience, &one) == nil {
+		return []string{one}
+	}
+	var many []string
+	json.Unmarshal(c.Audience, &many)
+	return many
+}
+
+// oidcClient runs the authorization code flow (with PKCE) against the
+// configured provider, caching its discovery document
+type oidcClient struct {
+	config *OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	provider  *oidcProvider
+	fetchedAt time.Time
+}
+
+func newOIDCClient(config *OIDCConfig) *oidcClient {
+	if config == nil || config.Issuer == "" {
+		return nil
+	}
+	if !config.restricted() {
+		log.Error("Single sign-on is disabled: it needs allowed domains or subjects")
+		return nil
+	}
+	return &oidcClient{config: config, client: outbound.Client(oidcTimeout)}
+}
+
+// discover returns the provider's endpoints from its discovery document
+func (c *oidcClient) discover(ctx context.Context) (*oidcProvider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.provider != nil && time.Since(c.fetchedAt) < oidcDiscoveryTTL {
+		return c.provider, nil
+	}
+
+	endpoint := strings.TrimSuffix(c.config.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery document returned %d", resp.StatusCode)
+	}
+
+	var provider oidcProvider
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&provider); err != nil {
+		return nil, fmt.Errorf("invalid discovery document: %v", err)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" {
+		return nil, fmt.Errorf("discovery document has no authorization or token endpoint")
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != strings.TrimSuffix(c.config.Issuer, "/") {
+		return nil, fmt.Errorf("discovery document is for issuer %s", provider.Issuer)
+	}
+	c.provider, c.fetchedAt = &provider, time.Now()
+	return c.provider, nil
+}
+
+// exchange trades an authorization code for the user's ID token claims
+func (c *oidcClient) exchange(ctx context.Context, provider *oidcProvider, code, redirectURL string, flow *oidcFlow) (*oidcClaims, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"code_verifier": {flow.Verifier},
+	}
+	if c.config.ClientSecret == "" {
+		form.Set("client_id", c.config.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("invalid token response (%d)", resp.StatusCode)
+	}
+	if tokens.Error != "" {
+		return nil, fmt.Errorf("token request failed: %s %s", tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+
+	// The ID token came straight from the token endpoint over TLS, which
+	// OpenID Connect Core (3.1.3.7) accepts in place of checking its signature
+	claims, err := parseIDToken(tokens.IDToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkClaims(claims, provider, flow); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkClaims checks an ID token is for this client and login, and that the
+// user may log in
+func (c *oidcClient) checkClaims(claims *oidcClaims, provider *oidcProvider, flow *oidcFlow) error {
+	switch {
+	case claims.Issuer != provider.Issuer:
+		return fmt.Errorf("ID token is from issuer %s", claims.Issuer)
+	case !containsString(claims.audiences(), c.config.ClientID):
+		return fmt.Errorf("ID token isn't for this client")
+	case time.Now().Unix() >= claims.Expiry:
+		return fmt.Errorf("ID token has expired")
+	case claims.Nonce != flow.Nonce:
+		return fmt.Errorf("ID token is for another login")
+	}
+
+	if claims.Subject != "" && containsString(c.config.AllowedSubjects, claims.Subject) {
+		return nil
+	}
+	if len(c.config.AllowedDomains) == 0 {
+		return fmt.Errorf("%s isn't allowed to log in", claims.Subject)
+	}
+	_, domain, ok := strings.Cut(claims.Email, "@")
+	if !ok || claims.EmailVerified == nil || !*claims.EmailVerified {
+		return fmt.Errorf("a verified email address is required")
+	}
+	for _, allowed := range c.config.AllowedDomains {
+		if strings.EqualFold(domain, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s isn't allowed to log in", claims.Email)
+}
+
+// parseIDToken decodes the claims of a JWT
+func parseIDToken(token string) (*oidcClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("ID token isn't a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("ID token isn't a JWT")
+	}
+	var claims oidcClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("ID token has invalid claims")
+	}
+	return &claims, nil
+}
+
+// randomToken returns n random bytes, base64url encoded
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// isSecureRequest reports whether a request reached the server (or the
+// proxy in front of it) over HTTPS
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// callbackURL returns the URL the provider sends users back to
+func (c *oidcClient) callbackURL(r *http.Request) string {
+	if c.config.RedirectURL != "" {
+		return c.config.RedirectURL
+	}
+	scheme := "http"
+	if isSecureRequest(r) {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + oidcCallbackPath
+}
+
+// localPath returns next if it's a path on this server, so logins can't be
+// used to redirect elsewhere
+func localPath(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return defaultLoginNext
+	}
+	return next
+}
+
+// setFlowCookie stores a login in progress, or clears it when flow is nil
+func setFlowCookie(w http.ResponseWriter, r *http.Request, flow *oidcFlow) {
+	cookie := &http.Cookie{
+		Name:     oidcCookieName,
+		Path:     "/auth/oidc/",
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode, // Sent on the provider's redirect back
+		MaxAge:   -1,
+	}
+	if flow != nil {
+		data, _ := json.Marshal(flow)
+		cookie.Value = base64.RawURLEncoding.EncodeToString(data)
+		cookie.MaxAge = int(oidcFlowDuration.Seconds())
+	}
+	http.SetCookie(w, cookie)
+}
+
+// flowFromCookie reads the login in progress
+func flowFromCookie(r *http.Request) *oidcFlow {
+	cookie, err := r.Cookie(oidcCookieName)
+	if err != nil {
+		return nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil
+	}
+	var flow oidcFlow
+	if json.Unmarshal(data, &flow) != nil || flow.State == "" {
+		return nil
+	}
+	return &flow
+}
+
+// loginFailed sends the user back to the login page with why SSO failed
+func loginFailed(w http.ResponseWriter, r *http.Request, message string) {
+	http.Redirect(w, r, "/admin/login?error="+url.QueryEscape(message), http.StatusFound)
+}
+
+// =============================================================================
+// OIDC Handlers
+// =============================================================================
+
+// oidcLoginHandler handles GET /auth/oidc/login?next=/admin/...
+// Sends the user to the provider to log in.
+func (s *Server) oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		writeError(w, http.StatusNotFound, "oidc_disabled", "Single sign-on isn't configured")
+		return
+	}
+
+	provider, err := s.oidc.discover(r.Context())
+	if err != nil {
+		log.Error("OIDC discovery for %s failed: %v", s.oidc.config.Issuer, err)
+		loginFailed(w, r, "The identity provider can't be reached")
+		return
+	}
+
+	flow := &oidcFlow{Next: localPath(r.URL.Query().Get("next"))}
+	for _, value := range []*string{&flow.State, &flow.Nonce, &flow.Verifier} {
+		if *value, err = randomToken(32); err != nil {
+			writeError(w, http.StatusInternalServerError, "session_error", "Failed to start login")
+			return
+		}
+	}
+	setFlowCookie(w, r, flow)
+
+	challenge := sha256.Sum256([]byte(flow.Verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {s.oidc.config.ClientID},
+		"redirect_uri":          {s.oidc.callbackURL(r)},
+		"scope":                 {"openid email profile"},
+		"state":                 {flow.State},
+		"nonce":                 {flow.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	http.Redirect(w, r, provider.AuthorizationEndpoint+separator+query.Encode(), http.StatusFound)
+}
+
+// oidcCallbackHandler handles GET /auth/oidc/callback
+// Completes a login: exchanges the code, checks the ID token, and starts a
+// session (the same session as a password login).
+func (s *Server) oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		writeError(w, http.StatusNotFound, "oidc_disabled", "Single sign-on isn't configured")
+		return
+	}
+
+	flow := flowFromCookie(r)
+	setFlowCookie(w, r, nil)
+	query := r.URL.Query()
+	switch {
+	case query.Get("error") != "":
+		log.Info("OIDC login refused by the provider: %s %s", query.Get("error"), query.Get("error_description"))
+		loginFailed(w, r, "The identity provider refused the login")
+		return
+	case flow == nil || query.Get("state") != flow.State:
+		loginFailed(w, r, "The login expired or was started elsewhere; try again")
+		return
+	case query.Get("code") == "":
+		loginFailed(w, r, "The identity provider sent no authorization code")
+		return
+	}
+
+	provider, err := s.oidc.discover(r.Context())
+	if err != nil {
+		log.Error("OIDC discovery for %s failed: %v", s.oidc.config.Issuer, err)
+		loginFailed(w, r, "The identity provider can't be reached")
+		return
+	}
+	claims, err := s.oidc.exchange(r.Context(), provider, query.Get("code"), s.oidc.callbackURL(r), flow)
+	if err != nil {
+		log.Info("OIDC login failed: %v", err)
+		loginFailed(w, r, "Single sign-on failed: "+err.Error())
+		return
+	}
+
+	token, err := s.sessions.create()
+	if err != nil {
+		loginFailed(w, r, "Failed to create session")
+		return
+	}
+	setSessionCookie(w, r, token)
+
+	user := claims.Email
+	if user == "" {
+		user = claims.Subject
+	}
+	log.Info("OIDC login: %s", user)
+	http.Redirect(w, r, flow.Next, http.StatusFound)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestOIDCRequiresAnAllowlist(t *testing.T) {
+	if newOIDCClient(&OIDCConfig{Issuer: "https://id.example.com", ClientID: "velocity"}) != nil {
+		t.Error("single sign-on enabled without allowed domains or subjects")
+	}
+	if newOIDCClient(&OIDCConfig{Issuer: "https://id.example.com", ClientID: "velocity", AllowedDomains: []string{"example.com"}}) == nil {
+		t.Error("single sign-on disabled with allowed domains")
+	}
+}
+
+func TestOIDCCheckClaims(t *testing.T) {
+	c := &oidcClient{config: &OIDCConfig{
+		ClientID:        "velocity",
+		AllowedDomains:  []string{"example.com"},
+		AllowedSubjects: []string{"service-account"},
+	}}
+	provider := &oidcProvider{Issuer: "https://id.example.com"}
+	flow := &oidcFlow{Nonce: "nonce"}
+	verified, unverified := true, false
+
+	claims := func(subject, email string, emailVerified *bool) *oidcClaims {
+		return &oidcClaims{
+			Issuer:        provider.Issuer,
+			Subject:       subject,
+			Audience:      json.RawMessage(`"velocity"`),
+			Expiry:        time.Now().Add(time.Hour).Unix(),
+			Nonce:         flow.Nonce,
+			Email:         email,
+			EmailVerified: emailVerified,
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		claims *oidcClaims
+		ok     bool
+	}{
+		{"verified email in an allowed domain", claims("alice", "alice@example.com", &verified), true},
+		{"domain in another case", claims("alice", "alice@EXAMPLE.com", &verified), true},
+		{"allowed subject", claims("service-account", "", nil), true},
+		{"unverified email", claims("alice", "alice@example.com", &unverified), false},
+		{"email_verified missing", claims("alice", "alice@example.com", nil), false},
+		{"email in another domain", claims("mallory", "mallory@example.org", &verified), false},
+		{"lookalike domain", claims("mallory", "mallory@evil-example.com", &verified), false},
+		{"no email", claims("mallory", "", nil), false},
+	} {
+		if err := c.checkClaims(tc.claims, provider, flow); (err == nil) != tc.ok {
+			t.Errorf("%s: checkClaims = %v, want ok %v", tc.name, err, tc.ok)
+		}
+	}
+
+	// Subjects alone must be allowed by name
+	bySubject := &oidcClient{config: &OIDCConfig{ClientID: "velocity", AllowedSubjects: []string{"service-account"}}}
+	if err := bySubject.checkClaims(claims("alice", "alice@example.com", &verified), provider, flow); err == nil {
+		t.Error("checkClaims allowed a subject that isn't listed")
+	}
+
+	// Tokens for another client or login are refused whoever they're for
+	other := claims("service-account", "", nil)
+	other.Nonce = "other"
+	if err := c.checkClaims(other, provider, flow); err == nil {
+		t.Error("checkClaims allowed a token for another login")
+	}
+}
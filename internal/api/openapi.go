@@ -0,0 +1,262 @@
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/version"
+)
+
+// routeSource is this package's route table, whose comments document each
+// route ("// GET /api/types - List available content types"); the OpenAPI
+// spec takes its summaries from them, so they stay the one place routes are
+// described
+//
+//go:embed server.go
+var routeSource string
+
+var (
+	// routeComment matches a route's comment: method, path, and summary
+	routeComment = regexp.MustCompile(`^\s*//\s+(GET|POST|PUT|PATCH|DELETE|HEAD)\s+(/\S+)\s+-\s+(.+)$`)
+
+	// routeParam matches a path variable, with its pattern if it has one
+	routeParam = regexp.MustCompile(`\{([^{}:]+)(?::([^{}]+))?\}`)
+
+	// routeEnum matches a variable pattern that lists its values (draft|pending|live)
+	routeEnum = regexp.MustCompile(`^[\w.-]+(\|[\w.-]+)+$`)
+
+	routeDocsOnce sync.Once
+	routeDocs     map[string]string // summary by method and path shape
+)
+
+// routeShape reduces a path template to its shape, with variables unnamed,
+// so comments match routes whatever they call their variables
+func routeShape(method, template string) string {
+	return method + " " + routeParam.ReplaceAllString(template, "{}")
+}
+
+// routeSummary returns the summary a route's comment gives it
+func routeSummary(method, template string) string {
+	routeDocsOnce.Do(func() {
+		routeDocs = make(map[string]string)
+		for _, line := range strings.Split(routeSource, "\n") {
+			m := routeComment.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			key := routeShape(m[1], m[2])
+			if _, ok := routeDocs[key]; !ok {
+				routeDocs[key] = strings.TrimSpace(m[3])
+			}
+		}
+	})
+	if summary, ok := routeDocs[routeShape(method, template)]; ok {
+		return summary
+	}
+	// Routes under items/{id} mirror the ones without it
+	return routeDocs[routeShape(method, strings.Replace(template, "/items/", "/", 1))]
+}
+
+// routeTags groups small route families in the reference
+var routeTags = map[string]string{
+	"login":        "auth",
+	"logout":       "auth",
+	"session":      "auth",
+	"health":       "info",
+	"version":      "info",
+	"openapi.json": "info",
+	"types":        "info",
+	"tenants":      "info",
+}
+
+// routeTag names the group a route is listed under: its first segment
+// after /api, or public for the public content routes
+func routeTag(template string) string {
+	if !strings.HasPrefix(template, "/api/") {
+		return "public"
+	}
+	tag := strings.SplitN(strings.TrimPrefix(template, "/api/"), "/", 2)[0]
+	if group, ok := routeTags[tag]; ok {
+		return group
+	}
+	return tag
+}
+
+// openAPIOperation is one method of a path in the spec
+type openAPIOperation struct {
+	Summary     string                   `json:"summary,omitempty"`
+	OperationID string                   `json:"operationId"`
+	Tags        []string                 `json:"tags"`
+	Parameters  []map[string]interface{} `json:"parameters,omitempty"`
+	RequestBody map[string]interface{}   `json:"requestBody,omitempty"`
+	Responses   map[string]interface{}   `json:"responses"`
+	Security    []map[string][]string    `json:"security,omitempty"`
+}
+
+// openAPISpec describes every API route the router serves as an OpenAPI 3
+// document. Routes come from the router itself, so the spec can't miss one;
+// summaries come from the route comments in server.go.
+func (s *Server) openAPISpec() map[string]interface{} {
+	paths := make(map[string]map[string]*openAPIOperation)
+	tags := make(map[string]bool)
+
+	s.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil || !(strings.HasPrefix(template, "/api/") || strings.HasPrefix(template, "/content/")) {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		path, params := openAPIPath(template)
+		public := !strings.HasPrefix(template, "/api/")
+		tag := routeTag(template)
+		tags[tag] = true
+
+		for _, method := range methods {
+			op := &openAPIOperation{
+				Summary:     routeSummary(method, template),
+				OperationID: openAPIOperationID(method, path),
+				Tags:        []string{tag},
+				Parameters:  params,
+				Responses: map[string]interface{}{
+					"default": map[string]interface{}{"description": "JSON, or the stored content for reads; errors are {\"error\", \"message\"}"},
+				},
+			}
+			if !public {
+				op.Parameters = append(op.Parameters, map[string]interface{}{"$ref": "#/components/parameters/tenant"})
+			} else {
+				op.Security = []map[string][]string{{}}
+			}
+			if method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch {
+				op.RequestBody = map[string]interface{}{
+					"content": map[string]interface{}{
+						"application/json":         map[string]interface{}{"schema": map[string]interface{}{"type": "object"}},
+						"application/octet-stream": map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}},
+					},
+				}
+			}
+			if paths[path] == nil {
+				paths[path] = make(map[string]*openAPIOperation)
+			}
+			if _, ok := paths[path][strings.ToLower(method)]; !ok {
+				paths[path][strings.ToLower(method)] = op
+			}
+		}
+		return nil
+	})
+
+	tagList := make([]map[string]string, 0, len(tags))
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tagList = append(tagList, map[string]string{"name": name})
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Velocity API",
+			"version":     version.GetVersion(),
+			"description": "Headless CMS API. Requests are scoped to the tenant in X-Tenant, or in the path as /api/t/{tenant}/...; admin routes need a session token from POST /api/login.",
+		},
+		"servers": []map[string]string{{"url": "/"}},
+		"tags":    tagList,
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"session": map[string]string{"type": "http", "scheme": "bearer", "description": "Session token from POST /api/login"},
+				"apiKey":  map[string]string{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+			"parameters": map[string]interface{}{
+				"tenant": map[string]interface{}{
+					"name":        "X-Tenant",
+					"in":          "header",
+					"description": "Tenant the request is for (default: demo)",
+					"schema":      map[string]string{"type": "string"},
+				},
+			},
+		},
+		"security": []map[string][]string{{"session": {}}, {"apiKey": {}}, {}},
+	}
+}
+
+// openAPIPath converts a mux path template to an OpenAPI path and its
+// parameters: {id:.+} becomes {id}, noted as possibly holding slashes, and
+// {state:draft|pending|live} an enum
+func openAPIPath(template string) (string, []map[string]interface{}) {
+	var params []map[string]interface{}
+	path := routeParam.ReplaceAllStringFunc(template, func(v string) string {
+		m := routeParam.FindStringSubmatch(v)
+		name, pattern := m[1], m[2]
+		schema := map[string]interface{}{"type": "string"}
+		param := map[string]interface{}{"name": name, "in": "path", "required": true, "schema": schema}
+		switch {
+		case pattern == ".+":
+			param["description"] = "May contain slashes (nested IDs)"
+		case routeEnum.MatchString(pattern):
+			schema["enum"] = strings.Split(pattern, "|")
+		case pattern != "":
+			schema["pattern"] = "^(?:" + pattern + ")$"
+		}
+		params = append(params, param)
+		return "{" + name + "}"
+	})
+	return path, params
+}
+
+// openAPIOperationID names an operation after its method and path:
+// GET /api/content/{type} is getContentByType
+func openAPIOperationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "/api"), "/") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, "{") {
+			segment = "by-" + strings.Trim(segment, "{}")
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '.' || r == '_' }) {
+			id += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return id
+}
+
+// =============================================================================
+// API Documentation Handlers
+// =============================================================================
+
+// openAPIHandler handles GET /api/openapi.json
+// Returns the OpenAPI 3 description of the API
+func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.openAPISpec())
+}
+
+// docsHandler handles GET /docs
+// Serves the interactive API reference, which loads /api/openapi.json
+func (s *Server) docsHandler(wwwContent fs.FS) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := fs.ReadFile(wwwContent, "docs/index.html")
+		if err != nil {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		html := strings.ReplaceAll(string(data), "{{VERSION}}", version.GetVersion())
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, html)
+	}
+}
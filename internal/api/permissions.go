@@ -0,0 +1,255 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/storage"
+)
+
+// Roles an API key can have. Operator sessions act as admins.
+const (
+	roleAdmin  = "admin"  // Everything: publishing, schemas, webhooks, keys, settings
+	roleEditor = "editor" // Reads, and writes to draft and pending content
+	roleViewer = "viewer" // Reads only
+
+	// roleOpen is the role of anonymous requests to a tenant without API keys.
+	// It can't be given to a key.
+	roleOpen = "open"
+)
+
+// Permissions a route can require
+const (
+	permRead    = "read"    // Read content and configuration
+	permWrite   = "write"   // Change content outside the live state
+	permPublish = "publish" // Change live content
+	permManage  = "manage"  // Change schemas, webhooks, keys, settings, and operations
+	permDecrypt = "decrypt" // Read encrypted fields (see fieldencryption.go)
+)
+
+// rolePermissions lists what each role may do
+var rolePermissions = map[string][]string{
+	roleAdmin:  {permRead, permWrite, permPublish, permManage, permDecrypt},
+	roleEditor: {permRead, permWrite},
+	roleViewer: {permRead},
+}
+
+// openPermissions are what anonymous requests to a tenant without API keys
+// may do: everything but read encrypted fields
+var openPermissions = []string{permRead, permWrite, permPublish, permManage}
+
+// grantablePermissions can be given to an API key on top of its role's
+var grantablePermissions = []string{permDecrypt}
+
+// validRole reports whether a role exists
+func validRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// operatorContextKey marks requests that carry an operator session
+type operatorContextKey struct{}
+
+// isOperator reports whether a request carries an operator session
+func isOperator(ctx context.Context) bool {
+	operator, _ := ctx.Value(operatorContextKey{}).(bool)
+	return operator
+}
+
+// withRequestCaller returns ctx acting as the caller of a request, for
+// background work that checks permissions after the request has returned
+func withRequestCaller(ctx, request context.Context) context.Context {
+	if key := apiKeyFromContext(request); key != nil {
+		ctx = context.WithValue(ctx, apiKeyContextKey{}, key)
+	}
+	if isOperator(request) {
+		ctx = context.WithValue(ctx, operatorContextKey{}, true)
+	}
+	return withRole(ctx, roleFromContext(request))
+}
+
+// roleContextKey is the context key for the role a request acts with
+type roleContextKey struct{}
+
+// withRole returns a context acting with a role
+func withRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleContextKey{}, role)
+}
+
+// roleFromContext returns the role a request acts with, or "" when no
+// role was set (public routes)
+func roleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(roleContextKey{}).(string)
+	return role
+}
+
+// keyRole returns an API key's role. Keys from before roles are viewers;
+// replace them with a key created with the role they need.
+func keyRole(key *storage.APIKey) string {
+	if key.Role == "" {
+		return roleViewer
+	}
+	return key.Role
+}
+
+// allowed reports whether a request may do something, by its role or the
+// permissions granted to its API key. Requests without a role may do nothing.
+func allowed(ctx context.Context, permission string) bool {
+	role := roleFromContext(ctx)
+	permissions := rolePermissions[role]
+	if role == roleOpen {
+		permissions = openPermissions
+	}
+	if containsString(permissions, permission) {
+		return true
+	}
+	key := apiKeyFromContext(ctx)
+	return key != nil && containsString(key.Permissions, permission)
+}
+
+// requirePermission writes a 403 and returns false if a request may not do something
+func requirePermission(w http.ResponseWriter, r *http.Request, permission string) bool {
+	if allowed(r.Context(), permission) {
+		return true
+	}
+	writeError(w, http.StatusForbidden, "forbidden", fmt.Sprintf("The %s role doesn't have the %s permission", roleFromContext(r.Context()), permission))
+	return false
+}
+
+// operatorRoutes are route prefixes that act on every tenant, so only
+// operator sessions may use them, whatever a tenant key's role
+var operatorRoutes = []string{
+	"/api/admin/",
+	"/api/tenants",
+}
+
+// operatorWriteRoutes are route prefixes whose writes change what every
+// tenant shares, so only operator sessions may write them; reads need read
+var operatorWriteRoutes = []string{
+	"/api/schemas",
+	"/api/types",
+}
+
+// operatorOnly reports whether a request to a route needs an operator session
+func operatorOnly(r *http.Request, template string) bool {
+	for _, prefix := range operatorRoutes {
+		if strings.HasPrefix(template, prefix) {
+			return true
+		}
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+		return false
+	}
+	for _, prefix := range operatorWriteRoutes {
+		if strings.HasPrefix(template, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// privateRoutes are route prefixes that need manage even to read
+var privateRoutes = []string{
+	"/api/admin/",
+	"/api/tenant/keys",
+}
+
+// manageRoutes are route prefixes whose writes change configuration
+var manageRoutes = []string{
+	"/api/admin/",
+	"/api/tenant/",
+	"/api/webhooks",
+	"/api/redirects",
+	"/api/stats/",
+}
+
+// publishRoutes are routes whose writes change live content whatever the
+// request's state
+var publishRoutes = map[string]bool{
+	"/api/releases/{id}/publish":                                             true,
+	"/api/releases/{id}/rollback":                                            true,
+	"/api/transactions/{id}/commit":                                          true,
+	"/api/content/{type}/{id:.+}/versions/{version}/restore":                 true,
+	"/api/content/{type}/{id:.+}/variants/{name}/{action:publish|unpublish}": true,
+}
+
+// stateRoutes are content routes whose writes publish when they're to live
+// content; creates and updates go to the tenant's default state unless the
+// route names one, and deletes and metadata changes to live
+var stateRoutes = map[string]bool{
+	"/api/content/{type}/{id:.+}":                                         true,
+	"/api/content/{type}/{id:.+}/{state:draft|pending|live}":              true,
+	"/api/content/{type}/items/{id:.+}":                                   true,
+	"/api/content/{type}/items/{id:.+}/states/{state:draft|pending|live}": true,
+	"/api/content/{type}/{id:.+}/metadata":                                true,
+	"/api/content/{type}/{id:.+}/{state:draft|pending|live}/metadata":     true,
+}
+
+// readRoutes are POST routes that only read
+var readRoutes = map[string]bool{
+	"/api/content": true, // Bulk fetch
+}
+
+// requiredPermission returns the permission a request to a route needs.
+// Transitions need write here; transitions to live are checked by the handler.
+func (s *Server) requiredPermission(r *http.Request, template string) string {
+	for _, prefix := range privateRoutes {
+		if strings.HasPrefix(template, prefix) {
+			return permManage
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions:
+		return permRead
+	case readRoutes[template]:
+		return permRead
+	case publishRoutes[template]:
+		return permPublish
+	case stateRoutes[template]:
+		state := getState(r)
+		if (r.Method == http.MethodPost || r.Method == http.MethodPut) && !strings.HasSuffix(template, "/metadata") {
+			state = s.writeState(r, s.getTenant(r))
+		}
+		if state == storage.StateLive {
+			return permPublish
+		}
+		return permWrite
+	}
+	for _, prefix := range manageRoutes {
+		if strings.HasPrefix(template, prefix) {
+			return permManage
+		}
+	}
+	return permWrite
+}
+
+// permissionHandler enforces the permission each route needs against the
+// role of the request's API key (see apiKeyHandler). Public routes are
+// skipped; everything else needs a role that has the permission.
+func (s *Server) permissionHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		if route == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		template, _ := route.GetPathTemplate()
+		if publicRoutes[template] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if operatorOnly(r, template) && !isOperator(r.Context()) {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "A valid session is required")
+			return
+		}
+		if !requirePermission(w, r, s.requiredPermission(r, template)) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
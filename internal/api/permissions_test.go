@@ -0,0 +1,203 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"velocity/internal/storage"
+	"velocity/internal/storage/storagetest"
+)
+
+// newTestServer returns a server backed by an in-memory bucket
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	s, _ := storagetest.NewS3Storage(10)
+	return NewServer(s, &ServerConfig{}, embed.FS{})
+}
+
+// newTestKey issues a tenant an API key with a role and returns its secret
+func newTestKey(t *testing.T, s *Server, tenant, role string) string {
+	t.Helper()
+	id, err := newAPIKeyID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := s.issueAPIKey(context.Background(), tenant, &storage.APIKey{ID: id, Role: role})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return secret
+}
+
+// serve sends a request through the API as a tenant, with an API key unless
+// key is empty, and returns the response
+func serve(s *Server, tenant, key, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	return serveRequest(s, tenant, key, r)
+}
+
+// serveRequest sends a request built by the caller (see serve)
+func serveRequest(s *Server, tenant, key string, r *http.Request) *httptest.ResponseRecorder {
+	r.Header.Set("X-Tenant", tenant)
+	if key != "" {
+		r.Header.Set("X-API-Key", key)
+	}
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, r)
+	return w
+}
+
+// requireStatus fails the test unless a response has the status
+func requireStatus(t *testing.T, w *httptest.ResponseRecorder, status int, what string) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("%s = %d, want %d: %s", what, w.Code, status, w.Body)
+	}
+}
+
+func TestTransactionsRequirePublish(t *testing.T) {
+	s := newTestServer(t)
+	editor := newTestKey(t, s, "acme", roleEditor)
+
+	draft := `{"operations":[{"op":"update","type":"pages","id":"home","state":"draft","content":{"title":"Home"}}]`
+	requireStatus(t, serve(s, "acme", editor, "POST", "/api/transactions", draft+`,"commit":true}`), http.StatusForbidden, "editor commit: true")
+
+	live := `{"operations":[{"op":"update","type":"pages","id":"home","content":{"title":"Home"}}]}`
+	requireStatus(t, serve(s, "acme", editor, "POST", "/api/transactions", live), http.StatusForbidden, "editor staging a live update")
+
+	// Staging drafts is fine; committing them isn't
+	w := serve(s, "acme", editor, "POST", "/api/transactions", draft+`}`)
+	requireStatus(t, w, http.StatusCreated, "editor staging a draft update")
+	var tx transaction
+	if err := json.Unmarshal(w.Body.Bytes(), &tx); err != nil {
+		t.Fatal(err)
+	}
+	requireStatus(t, serve(s, "acme", editor, "POST", "/api/transactions/"+tx.ID+"/commit", ""), http.StatusForbidden, "editor commit")
+}
+
+func TestTemplateCreateIntoLiveRequiresPublish(t *testing.T) {
+	s := newTestServer(t)
+	admin := newTestKey(t, s, "acme", roleAdmin)
+	editor := newTestKey(t, s, "acme", roleEditor)
+
+	requireStatus(t, serve(s, "acme", admin, "PUT", "/api/templates/pages/basic", `{"content":{"title":"Untitled"}}`), http.StatusOK, "PUT template")
+
+	requireStatus(t, serve(s, "acme", editor, "POST", "/api/content/pages/home/from-template/basic", ""), http.StatusForbidden, "editor create into live")
+	requireStatus(t, serve(s, "acme", editor, "POST", "/api/content/pages/home/from-template/basic?state=draft", ""), http.StatusCreated, "editor create into draft")
+}
+
+func TestZipImportIntoLiveRequiresPublish(t *testing.T) {
+	s := newTestServer(t)
+	editor := newTestKey(t, s, "acme", roleEditor)
+
+	requireStatus(t, serve(s, "acme", editor, "POST", "/api/content/pages/import-zip", "PK"), http.StatusForbidden, "editor import into live")
+}
+
+func TestOperatorRoutes(t *testing.T) {
+	s := newTestServer(t)
+	admin := newTestKey(t, s, "acme", roleAdmin)
+
+	// A tenant's admin key can't reach routes that act on every tenant
+	requireStatus(t, serve(s, "acme", admin, "GET", "/api/tenants", ""), http.StatusUnauthorized, "admin key GET /api/tenants")
+	requireStatus(t, serve(s, "acme", admin, "POST", "/api/tenants", `{"name":"other"}`), http.StatusUnauthorized, "admin key POST /api/tenants")
+	requireStatus(t, serve(s, "acme", admin, "PUT", "/api/schemas/pages", `{"name":"pages","fields":{}}`), http.StatusUnauthorized, "admin key PUT /api/schemas")
+	requireStatus(t, serve(s, "acme", admin, "POST", "/api/types", `{"name":"pages"}`), http.StatusUnauthorized, "admin key POST /api/types")
+
+	// Nor can anonymous requests to a tenant without keys
+	requireStatus(t, serve(s, "open", "", "GET", "/api/tenants", ""), http.StatusUnauthorized, "anonymous GET /api/tenants")
+
+	// Global schemas and types can still be read with a key
+	requireStatus(t, serve(s, "acme", admin, "GET", "/api/schemas", ""), http.StatusOK, "admin key GET /api/schemas")
+	requireStatus(t, serve(s, "acme", admin, "GET", "/api/types", ""), http.StatusOK, "admin key GET /api/types")
+
+	token, err := s.sessions.create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("GET", "/api/tenants", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	requireStatus(t, serveRequest(s, "acme", "", r), http.StatusOK, "operator GET /api/tenants")
+}
+
+// fieldSchema protects a field by role and one from changes
+const fieldSchema = `{"name":"pages","fields":{
+	"title":{"type":"string"},
+	"price":{"type":"number","roles":["admin"]},
+	"sku":{"type":"string","read_only":true}
+}}`
+
+func TestUploadChecksFieldPermissions(t *testing.T) {
+	s := newTestServer(t)
+	admin := newTestKey(t, s, "acme", roleAdmin)
+	editor := newTestKey(t, s, "acme", roleEditor)
+	requireStatus(t, serve(s, "acme", admin, "PUT", "/api/tenant/schemas/pages", fieldSchema), http.StatusOK, "PUT schema")
+
+	upload := func(content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="file"; filename="home.json"`)
+		header.Set("Content-Type", "application/json")
+		part, _ := form.CreatePart(header)
+		io.WriteString(part, content)
+		form.Close()
+
+		r := httptest.NewRequest("POST", "/api/content/pages/items/home/states/draft", &body)
+		r.Header.Set("Content-Type", form.FormDataContentType())
+		return serveRequest(s, "acme", editor, r)
+	}
+
+	w := upload(`{"title":"Home","price":5}`)
+	requireStatus(t, w, http.StatusForbidden, "editor upload setting an admin field")
+	if !strings.Contains(w.Body.String(), "field_permission_denied") {
+		t.Errorf("upload response = %s, want field_permission_denied", w.Body)
+	}
+	requireStatus(t, upload(`{"title":"Home"}`), http.StatusCreated, "editor upload of unprotected fields")
+}
+
+func TestTransactionChecksFieldPermissions(t *testing.T) {
+	s := newTestServer(t)
+	admin := newTestKey(t, s, "acme", roleAdmin)
+	requireStatus(t, serve(s, "acme", admin, "PUT", "/api/tenant/schemas/pages", fieldSchema), http.StatusOK, "PUT schema")
+	requireStatus(t, serve(s, "acme", admin, "PUT", "/api/content/pages/home", `{"title":"Home","sku":"A1"}`), http.StatusOK, "PUT content")
+
+	// Read-only fields can't be changed even by admins
+	tx := `{"commit":true,"operations":[{"op":"update","type":"pages","id":"home","content":{"title":"Home","sku":"B2"}}]}`
+	w := serve(s, "acme", admin, "POST", "/api/transactions", tx)
+	requireStatus(t, w, http.StatusUnprocessableEntity, "commit changing a read-only field")
+	if !strings.Contains(w.Body.String(), "sku: field is read-only") {
+		t.Errorf("commit response = %s, want a read-only violation", w.Body)
+	}
+
+	w = serve(s, "acme", admin, "GET", "/api/content/pages/home", "")
+	requireStatus(t, w, http.StatusOK, "GET content")
+	if !strings.Contains(w.Body.String(), `"A1"`) {
+		t.Errorf("content after the refused commit = %s", w.Body)
+	}
+}
+
+func TestTransactionChecksFieldRoles(t *testing.T) {
+	s := newTestServer(t)
+
+	// Anonymous requests to a tenant without keys may publish, but aren't admins
+	requireStatus(t, serve(s, "open", "", "PUT", "/api/tenant/schemas/pages", fieldSchema), http.StatusOK, "PUT schema")
+	tx := `{"commit":true,"operations":[{"op":"update","type":"pages","id":"home","content":{"title":"Home","price":5}}]}`
+	w := serve(s, "open", "", "POST", "/api/transactions", tx)
+	requireStatus(t, w, http.StatusUnprocessableEntity, "open commit setting an admin field")
+	if !strings.Contains(w.Body.String(), "price: only [admin] may change this field") {
+		t.Errorf("commit response = %s, want a role violation", w.Body)
+	}
+	requireStatus(t, serve(s, "open", "", "GET", "/api/content/pages/home", ""), http.StatusNotFound, "GET content")
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Lifecycle phases reported by the readiness probe
+const (
+	phaseStarting int32 = iota
+	phaseReady
+	phaseDraining
+)
+
+// readinessCheckTTL is how long a storage check is reused, so frequent probes
+// from several orchestrators don't each reach the bucket
+const readinessCheckTTL = 5 * time.Second
+
+// readiness tracks whether the server should receive traffic
+type readiness struct {
+	phase atomic.Int32
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	checkErr  error
+}
+
+// MarkReady reports the server ready for traffic once its listeners are up
+func (s *Server) MarkReady() {
+	s.readiness.phase.Store(phaseReady)
+}
+
+// MarkDraining fails the readiness probe so load balancers stop sending new
+// requests while in-flight ones finish
+func (s *Server) MarkDraining() {
+	s.readiness.phase.Store(phaseDraining)
+}
+
+// checkStorage verifies storage is reachable, reusing a recent result
+func (s *Server) checkStorage(ctx context.Context) error {
+	s.readiness.mu.Lock()
+	defer s.readiness.mu.Unlock()
+
+	if time.Since(s.readiness.checkedAt) < readinessCheckTTL {
+		return s.readiness.checkErr
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	s.readiness.checkErr = s.storage.CheckConnection(ctx)
+	s.readiness.checkedAt = time.Now()
+	return s.readiness.checkErr
+}
+
+// =============================================================================
+// Probe Handlers
+// =============================================================================
+
+// livenessHandler handles GET /api/health/live
+// Succeeds while the process can serve requests at all; it doesn't depend on
+// storage, so an outage doesn't get every pod restarted.
+func (s *Server) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "alive"})
+}
+
+// readinessHandler handles GET /api/health/ready
+// Fails while the server is starting, while it drains for shutdown, and while
+// storage is unreachable.
+func (s *Server) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	switch s.readiness.phase.Load() {
+	case phaseStarting:
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "starting"})
+		return
+	case phaseDraining:
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "draining"})
+		return
+	}
+
+	if err := s.checkStorage(r.Context()); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"status": "storage_unavailable",
+			"error":  err.Error(),
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const (
+	redirectsCollection = "redirects"
+	redirectsCacheTTL   = 30 * time.Second
+)
+
+// redirect sends requests for a public content path elsewhere
+type redirect struct {
+	From      string    `json:"from"`   // {type}/{id}, relative to /content/{tenant}/
+	To        string    `json:"to"`     // URL, absolute path, or {type}/{id} of other content
+	Status    int       `json:"status"` // 301 or 302
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// location returns the URL a redirect sends a tenant's requests to
+func (rd *redirect) location(tenant string) string {
+	if strings.HasPrefix(rd.To, "/") || strings.Contains(rd.To, "://") {
+		return rd.To
+	}
+	return "/content/" + tenant + "/" + rd.To
+}
+
+// normalizeRedirectPath trims slashes and a leading /content/{tenant}/ so
+// paths can be given either way
+func normalizeRedirectPath(tenant, p string) string {
+	p = strings.Trim(p, "/")
+	if rest, ok := strings.CutPrefix(p, "content/"+tenant+"/"); ok {
+		return rest
+	}
+	return p
+}
+
+type cachedRedirects struct {
+	redirects map[string]*redirect
+	expiresAt time.Time
+}
+
+// redirectStore loads tenant redirects with a short-lived per-node cache, so
+// the public content route can check them on every request
+type redirectStore struct {
+	mu      sync.RWMutex
+	cache   map[string]*cachedRedirects
+	storage storage.Storage
+}
+
+func newRedirectStore(s storage.Storage) *redirectStore {
+	return &redirectStore{
+		cache:   make(map[string]*cachedRedirects),
+		storage: s,
+	}
+}
+
+// all returns a tenant's redirects keyed by from path
+func (rs *redirectStore) all(ctx context.Context, tenant string) map[string]*redirect {
+	rs.mu.RLock()
+	cached, ok := rs.cache[tenant]
+	rs.mu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.redirects
+	}
+
+	redirects := make(map[string]*redirect)
+	ids, _ := rs.storage.ListDocuments(ctx, tenant, redirectsCollection)
+	for _, id := range ids {
+		data, err := rs.storage.GetDocument(ctx, tenant, redirectsCollection, id)
+		if err != nil {
+			continue
+		}
+		var rd redirect
+		if err := json.Unmarshal(data, &rd); err != nil {
+			log.Error("Failed to parse redirect %s for tenant %s: %v", id, tenant, err)
+			continue
+		}
+		redirects[rd.From] = &rd
+	}
+
+	rs.mu.Lock()
+	rs.cache[tenant] = &cachedRedirects{redirects: redirects, expiresAt: time.Now().Add(redirectsCacheTTL)}
+	rs.mu.Unlock()
+	return redirects
+}
+
+// invalidate drops a tenant's cached redirects
+func (rs *redirectStore) invalidate(tenant string) {
+	rs.mu.Lock()
+	delete(rs.cache, tenant)
+	rs.mu.Unlock()
+}
+
+// sortedRedirects returns a tenant's redirects ordered by from path
+func (s *Server) sortedRedirects(ctx context.Context, tenant string) []*redirect {
+	redirects := []*redirect{}
+	for _, rd := range s.redirects.all(ctx, tenant) {
+		redirects = append(redirects, rd)
+	}
+	sort.Slice(redirects, func(i, j int) bool { return redirects[i].From < redirects[j].From })
+	return redirects
+}
+
+// =============================================================================
+// Redirect Handlers
+// =============================================================================
+
+// listRedirectsHandler handles GET /api/redirects
+func (s *Server) listRedirectsHandler(w http.ResponseWriter, r *http.Request) {
+	redirects := s.sortedRedirects(r.Context(), s.getTenant(r))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"redirects": redirects,
+		"count":     len(redirects),
+	})
+}
+
+// exportRedirectsHandler handles GET /api/redirects/export
+// ?format=json (default) returns a map of public path to target for edge
+// workers; ?format=redirects returns a _redirects file (Netlify, Cloudflare Pages).
+func (s *Server) exportRedirectsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	redirects := s.sortedRedirects(r.Context(), tenant)
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		rules := make(map[string]interface{}, len(redirects))
+		for _, rd := range redirects {
+			rules["/content/"+tenant+"/"+rd.From] = map[string]interface{}{
+				"to":     rd.location(tenant),
+				"status": rd.Status,
+			}
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "redirects-"+tenant+".json"))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"tenant":    tenant,
+			"redirects": rules,
+		})
+	case "redirects":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="_redirects"`)
+		w.WriteHeader(http.StatusOK)
+		for _, rd := range redirects {
+			fmt.Fprintf(w, "/content/%s/%s %s %d\n", tenant, rd.From, rd.location(tenant), rd.Status)
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_format", fmt.Sprintf("Unknown format: %s (expected json or redirects)", format))
+	}
+}
+
+// getRedirectHandler handles GET /api/redirects/{from}
+func (s *Server) getRedirectHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	from := normalizeRedirectPath(tenant, mux.Vars(r)["from"])
+
+	rd, ok := s.redirects.all(r.Context(), tenant)[from]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Redirect '%s' not found", from))
+		return
+	}
+	writeJSON(w, http.StatusOK, rd)
+}
+
+// putRedirectHandler handles PUT /api/redirects/{from}
+// Body: {"to": "pages/new-home", "status": 301}
+func (s *Server) putRedirectHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	from := normalizeRedirectPath(tenant, mux.Vars(r)["from"])
+
+	var req struct {
+		To     string `json:"to"`
+		Status int    `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	if !strings.Contains(from, "/") {
+		writeError(w, http.StatusBadRequest, "invalid_path", "Redirects are from a content path ({type}/{id})")
+		return
+	}
+	if req.To == "" {
+		writeError(w, http.StatusBadRequest, "missing_target", "Redirect target 'to' is required")
+		return
+	}
+	if req.Status == 0 {
+		req.Status = http.StatusMovedPermanently
+	}
+	if req.Status != http.StatusMovedPermanently && req.Status != http.StatusFound {
+		writeError(w, http.StatusBadRequest, "invalid_status", fmt.Sprintf("Invalid status: %d (expected 301 or 302)", req.Status))
+		return
+	}
+
+	now := time.Now().UTC()
+	rd := &redirect{
+		From:      from,
+		To:        req.To,
+		Status:    req.Status,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if rd.location(tenant) == "/content/"+tenant+"/"+from {
+		writeError(w, http.StatusBadRequest, "redirect_loop", "A redirect can't point to itself")
+		return
+	}
+	if existing, ok := s.redirects.all(r.Context(), tenant)[from]; ok {
+		rd.CreatedAt = existing.CreatedAt
+	}
+
+	data, err := json.Marshal(rd)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "marshal_error", err.Error())
+		return
+	}
+	if err := s.storage.PutDocument(r.Context(), tenant, redirectsCollection, from, data); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	s.redirects.invalidate(tenant)
+
+	log.Info("Saved redirect %s -> %s (%d) for tenant %s", from, req.To, req.Status, tenant)
+	writeJSON(w, http.StatusOK, rd)
+}
+
+// deleteRedirectHandler handles DELETE /api/redirects/{from}
+func (s *Server) deleteRedirectHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	from := normalizeRedirectPath(tenant, mux.Vars(r)["from"])
+
+	if _, ok := s.redirects.all(r.Context(), tenant)[from]; !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Redirect '%s' not found", from))
+		return
+	}
+	if err := s.storage.DeleteDocument(r.Context(), tenant, redirectsCollection, from); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	s.redirects.invalidate(tenant)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"from":    from,
+		"message": "Redirect deleted",
+	})
+}
+
+// serveRedirect answers a public content request with the tenant's redirect
+// for the path, if one exists. The request's query string is carried over.
+func (s *Server) serveRedirect(w http.ResponseWriter, r *http.Request, tenant, contentPath string) bool {
+	rd, ok := s.redirects.all(r.Context(), tenant)[contentPath]
+	if !ok {
+		return false
+	}
+
+	location := rd.location(tenant)
+	if r.URL.RawQuery != "" && !strings.Contains(location, "?") {
+		location += "?" + r.URL.RawQuery
+	}
+	if rd.Status == http.StatusMovedPermanently {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+	}
+	http.Redirect(w, r, location, rd.Status)
+	return true
+}
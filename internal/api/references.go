@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"velocity/internal/models"
+	"velocity/internal/storage"
+)
+
+// contentReference is the value of a reference field: type/id, or
+// type/id@version to pin the referenced item to one of its versions
+type contentReference struct {
+	Type    string
+	ID      string
+	Version string // empty follows the live content
+}
+
+// parseReference parses a reference field's value
+func parseReference(value string) (contentReference, bool) {
+	var ref contentReference
+	if at := strings.LastIndex(value, "@"); at != -1 {
+		value, ref.Version = value[:at], value[at+1:]
+		if ref.Version == "" {
+			return ref, false
+		}
+	}
+	contentType, id, ok := strings.Cut(value, "/")
+	if !ok || contentType == "" || id == "" || !storage.ValidKeyPath(contentType) || !storage.ValidKeyPath(id) {
+		return ref, false
+	}
+	ref.Type, ref.ID = contentType, id
+	return ref, true
+}
+
+// wantsExpand reports whether a read asked for its reference fields resolved
+func wantsExpand(r *http.Request) bool {
+	return r.URL.Query().Get("expand") == "true"
+}
+
+// writeExpanded answers a read of a JSON item with ?expand=true: the item with
+// each reference field its schema declares replaced by the item it refers to.
+// Expanded items aren't expanded in turn, so references can't loop.
+func (s *Server) writeExpanded(w http.ResponseWriter, r *http.Request, tenant, contentType string, state storage.State, stream *storage.ContentStream) {
+	var doc interface{}
+	if err := json.NewDecoder(stream.Body).Decode(&doc); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "invalid_json", "Content is not valid JSON and can't be expanded")
+		return
+	}
+	if obj, ok := doc.(map[string]interface{}); ok {
+		if schema := s.loadSchema(r.Context(), tenant, contentType); schema != nil {
+			s.expandFields(r.Context(), tenant, schema.Fields, obj)
+		}
+	}
+
+	// The expanded document changes with the live items it refers to, so it
+	// has no ETag of its own
+	if stream.VersionID != "" {
+		w.Header().Set("X-Version-ID", stream.VersionID)
+	}
+	w.Header().Set("X-Content-State", string(state))
+	if w.Header().Get("Cache-Control") == "" { // Decrypted content is already no-store
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// expandFields resolves the reference fields of an object in place, recursing
+// into object fields and arrays of references
+func (s *Server) expandFields(ctx context.Context, tenant string, fields map[string]models.FieldDef, obj map[string]interface{}) {
+	for name, def := range fields {
+		value, ok := obj[name]
+		if !ok || value == nil {
+			continue
+		}
+		switch def.Type {
+		case "reference":
+			if ref, ok := value.(string); ok {
+				obj[name] = s.expandReference(ctx, tenant, ref)
+			}
+		case "object":
+			if child, ok := value.(map[string]interface{}); ok && len(def.Properties) > 0 {
+				s.expandFields(ctx, tenant, def.Properties, child)
+			}
+		case "array":
+			if items, ok := value.([]interface{}); ok && def.Items == "reference" {
+				for i, item := range items {
+					if ref, ok := item.(string); ok {
+						items[i] = s.expandReference(ctx, tenant, ref)
+					}
+				}
+			}
+		}
+	}
+}
+
+// expandReference reads the item a reference names: the pinned version, or
+// the live content. A pinned version that no longer exists is reported as an
+// error rather than replaced by a newer one.
+func (s *Server) expandReference(ctx context.Context, tenant, value string) map[string]interface{} {
+	result := map[string]interface{}{"ref": value}
+	ref, ok := parseReference(value)
+	if !ok {
+		result["error"] = "invalid_reference"
+		result["message"] = fmt.Sprintf("Invalid reference: %q (expected type/id or type/id@version)", value)
+		return result
+	}
+	result["type"], result["id"], result["pinned"] = ref.Type, ref.ID, ref.Version != ""
+
+	// The live item names the extension its versions are stored under
+	stream, err := s.storage.FindContentStream(ctx, tenant, ref.Type, ref.ID, "", storage.StateLive)
+	if ref.Version != "" {
+		ext := s.getExtensionFromSchema(ctx, ref.Type)
+		if err == nil {
+			stream.Body.Close()
+			_, ext = extractIDAndExt(stream.Key, ref.Type, storage.StateLive)
+		}
+		stream, err = s.storage.GetVersionStream(ctx, tenant, ref.Type, ref.ID, ext, ref.Version)
+		if err != nil {
+			result["error"] = "version_not_found"
+			result["message"] = fmt.Sprintf("Version '%s' of %s/%s not found", ref.Version, ref.Type, ref.ID)
+			return result
+		}
+	} else if err != nil {
+		result["error"] = "not_found"
+		result["message"] = fmt.Sprintf("Content '%s/%s' not found", ref.Type, ref.ID)
+		return result
+	}
+	defer stream.Body.Close()
+
+	content, err := io.ReadAll(stream.Body)
+	if err != nil {
+		result["error"] = "read_error"
+		result["message"] = "Failed to read content"
+		return result
+	}
+	result["version"] = stream.VersionID
+	result["content-type"] = stream.ContentType
+	result["last_modified"] = stream.LastModified.UTC().Format(time.RFC3339)
+	result["content"] = bulkContent(stream.ContentType, content)
+	return result
+}
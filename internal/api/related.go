@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// maxRelatedMetadata limits the size of the metadata part of a multipart/related write
+const maxRelatedMetadata = 1 << 20
+
+// isRelated reports whether a write carries a multipart/related body: a JSON
+// metadata part followed by the content part
+func isRelated(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/related"
+}
+
+// readRelated reads the metadata part of a multipart/related body and returns
+// it with the content part, positioned at the start of the content. The
+// metadata part is a JSON object; values that aren't strings (lists, numbers)
+// are stored as their JSON encoding, so multi-value metadata survives the trip.
+func readRelated(r *http.Request) (map[string]string, *multipart.Part, error) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || params["boundary"] == "" {
+		return nil, nil, errors.New("multipart/related body has no boundary")
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+
+	metaPart, err := mr.NextPart()
+	if err != nil {
+		return nil, nil, errors.New("multipart/related body has no metadata part")
+	}
+	if mediaType, _, _ := mime.ParseMediaType(metaPart.Header.Get("Content-Type")); mediaType != "" && mediaType != "application/json" {
+		return nil, nil, fmt.Errorf("metadata part must be application/json, got %s", mediaType)
+	}
+	var fields map[string]interface{}
+	if err := json.NewDecoder(io.LimitReader(metaPart, maxRelatedMetadata)).Decode(&fields); err != nil {
+		return nil, nil, fmt.Errorf("metadata part is not a JSON object: %v", err)
+	}
+
+	metadata := make(map[string]string, len(fields))
+	for key, value := range fields {
+		switch v := value.(type) {
+		case string:
+			metadata[strings.ToLower(key)] = v
+		case nil:
+			// Skip nulls
+		default:
+			encoded, _ := json.Marshal(v)
+			metadata[strings.ToLower(key)] = string(encoded)
+		}
+	}
+
+	part, err := mr.NextPart()
+	if err != nil {
+		return nil, nil, errors.New("multipart/related body has no content part")
+	}
+	return metadata, part, nil
+}
+
+// partType returns the media type of a content part
+func partType(part *multipart.Part) string {
+	if mimeType := part.Header.Get("Content-Type"); mimeType != "" {
+		return mimeType
+	}
+	return "application/octet-stream"
+}
+
+// mergeMetadata adds metadata from the body to the X-Meta-* headers; the
+// body wins for keys given in both
+func mergeMetadata(headers, body map[string]string) map[string]string {
+	if len(body) == 0 {
+		return headers
+	}
+	merged := make(map[string]string, len(headers)+len(body))
+	for key, value := range headers {
+		merged[key] = value
+	}
+	for key, value := range body {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,491 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const releasesCollection = "releases"
+
+// Release statuses
+const (
+	releaseOpen       = "open"
+	releasePublished  = "published"
+	releaseRolledBack = "rolled_back"
+)
+
+// releaseItem is a content item included in a release
+type releaseItem struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	From string `json:"from,omitempty"` // source state (default draft)
+
+	// Set on publish, used for rollback
+	Ext              string `json:"ext,omitempty"` // extension the item is stored with
+	PreviousVersion  string `json:"previous_version,omitempty"`
+	PublishedVersion string `json:"published_version,omitempty"`
+}
+
+// releaseEvent is an entry in a release's history
+type releaseEvent struct {
+	Action    string    `json:"action"`
+	Author    string    `json:"author,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// release groups draft items that are published (and rolled back) together
+type release struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Status      string         `json:"status"`
+	Items       []releaseItem  `json:"items"`
+	History     []releaseEvent `json:"history"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// releaseReadiness reports whether each item can be published
+type releaseReadiness struct {
+	Ready bool                `json:"ready"`
+	Items []releaseItemStatus `json:"items"`
+}
+
+type releaseItemStatus struct {
+	Type   string   `json:"type"`
+	ID     string   `json:"id"`
+	Ready  bool     `json:"ready"`
+	Issues []string `json:"issues,omitempty"`
+}
+
+func (item *releaseItem) fromState() storage.State {
+	if item.From == "" {
+		return storage.StateDraft
+	}
+	return storage.State(item.From)
+}
+
+// ext returns the extension a published item is stored with (releases
+// published before it was recorded hold JSON)
+func (item *releaseItem) ext() string {
+	if item.Ext == "" {
+		return "json"
+	}
+	return item.Ext
+}
+
+func (rel *release) addEvent(action, author, message string, err error) {
+	event := releaseEvent{Action: action, Author: author, Message: message, Timestamp: time.Now().UTC()}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	rel.History = append(rel.History, event)
+	rel.UpdatedAt = event.Timestamp
+}
+
+func validateReleaseItems(items []releaseItem) error {
+	seen := make(map[string]bool)
+	for i, item := range items {
+		if item.Type == "" || item.ID == "" {
+			return fmt.Errorf("items[%d]: type and id are required", i)
+		}
+		if item.From != "" && (item.From == string(storage.StateLive) || !storage.ValidState(item.From)) {
+			return fmt.Errorf("items[%d]: invalid source state: %s", i, item.From)
+		}
+		key := item.Type + "/" + item.ID
+		if seen[key] {
+			return fmt.Errorf("items[%d]: %s is listed twice", i, key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+func (s *Server) getRelease(ctx context.Context, tenant, id string) (*release, error) {
+	data, err := s.storage.GetDocument(ctx, tenant, releasesCollection, id)
+	if err != nil {
+		return nil, err
+	}
+	var rel release
+	if err := json.Unmarshal(data, &rel); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+	return &rel, nil
+}
+
+func (s *Server) putRelease(ctx context.Context, tenant string, rel *release) error {
+	data, err := json.Marshal(rel)
+	if err != nil {
+		return fmt.Errorf("failed to marshal release: %w", err)
+	}
+	return s.storage.PutDocument(ctx, tenant, releasesCollection, rel.ID, data)
+}
+
+// releaseOperations converts release items to transition operations
+func releaseOperations(rel *release) []txOperation {
+	ops := make([]txOperation, len(rel.Items))
+	for i, item := range rel.Items {
+		ops[i] = txOperation{Op: "transition", Type: item.Type, ID: item.ID, From: string(item.fromState()), To: string(storage.StateLive)}
+	}
+	return ops
+}
+
+// checkReadiness verifies every item exists in its source state and passes publish guards
+func (s *Server) checkReadiness(ctx context.Context, tenant string, rel *release) *releaseReadiness {
+	readiness := &releaseReadiness{Ready: len(rel.Items) > 0, Items: []releaseItemStatus{}}
+
+	for _, item := range rel.Items {
+		status := releaseItemStatus{Type: item.Type, ID: item.ID}
+		from := item.fromState()
+
+		ext, ok := s.storedExt(ctx, tenant, item.Type, item.ID, from)
+		if !ok {
+			status.Issues = append(status.Issues, fmt.Sprintf("not found in %s state", from))
+		} else if content, err := s.storage.Get(ctx, tenant, item.Type, item.ID, ext, from); err != nil {
+			status.Issues = append(status.Issues, fmt.Sprintf("failed to read %s content: %v", from, err))
+		} else if isJSONContent(content.ContentType) {
+			status.Issues = append(status.Issues, validateDocument(s.loadSchema(ctx, tenant, item.Type), content.Content)...)
+		}
+
+		if unresolved, err := s.storage.HasUnresolvedComments(ctx, tenant, item.Type, item.ID, from); err == nil && unresolved {
+			status.Issues = append(status.Issues, fmt.Sprintf("unresolved comments on %s content", from))
+		}
+
+		if violation := s.checkImageMetadata(ctx, tenant, item.Type, item.ID, from); violation != "" {
+			status.Issues = append(status.Issues, violation)
+		}
+
+		status.Ready = len(status.Issues) == 0
+		readiness.Ready = readiness.Ready && status.Ready
+		readiness.Items = append(readiness.Items, status)
+	}
+
+	return readiness
+}
+
+// =============================================================================
+// Release Handlers
+// =============================================================================
+
+// listReleasesHandler handles GET /api/releases
+func (s *Server) listReleasesHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+
+	ids, _ := s.storage.ListDocuments(r.Context(), tenant, releasesCollection)
+	releases := []*release{}
+	for _, id := range ids {
+		if rel, err := s.getRelease(r.Context(), tenant, id); err == nil {
+			releases = append(releases, rel)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"releases": releases,
+		"count":    len(releases),
+	})
+}
+
+// createReleaseHandler handles POST /api/releases
+func (s *Server) createReleaseHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+
+	var req struct {
+		Name        string        `json:"name"`
+		Description string        `json:"description,omitempty"`
+		Items       []releaseItem `json:"items"`
+		Author      string        `json:"author,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "missing_name", "Release name is required")
+		return
+	}
+	if err := validateReleaseItems(req.Items); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_items", err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	rel := &release{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		Description: req.Description,
+		Status:      releaseOpen,
+		Items:       req.Items,
+		CreatedAt:   now,
+	}
+	if rel.Items == nil {
+		rel.Items = []releaseItem{}
+	}
+	rel.addEvent("created", req.Author, "", nil)
+
+	if err := s.putRelease(r.Context(), tenant, rel); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, rel)
+}
+
+// getReleaseHandler handles GET /api/releases/{id}
+// Includes readiness for open releases.
+func (s *Server) getReleaseHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	rel, err := s.getRelease(r.Context(), tenant, mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", "Release not found")
+		return
+	}
+
+	response := map[string]interface{}{"release": rel}
+	if rel.Status == releaseOpen {
+		response["readiness"] = s.checkReadiness(r.Context(), tenant, rel)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// updateReleaseHandler handles PUT /api/releases/{id}
+// Replaces name, description, and items of an open release.
+func (s *Server) updateReleaseHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	rel, err := s.getRelease(r.Context(), tenant, mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", "Release not found")
+		return
+	}
+	if rel.Status != releaseOpen {
+		writeError(w, http.StatusConflict, "invalid_status", fmt.Sprintf("Release is %s", rel.Status))
+		return
+	}
+
+	var req struct {
+		Name        string        `json:"name,omitempty"`
+		Description *string       `json:"description,omitempty"`
+		Items       []releaseItem `json:"items"`
+		Author      string        `json:"author,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+	if err := validateReleaseItems(req.Items); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_items", err.Error())
+		return
+	}
+
+	if req.Name != "" {
+		rel.Name = req.Name
+	}
+	if req.Description != nil {
+		rel.Description = *req.Description
+	}
+	if req.Items != nil {
+		rel.Items = req.Items
+	}
+	rel.addEvent("updated", req.Author, "", nil)
+
+	if err := s.putRelease(r.Context(), tenant, rel); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rel)
+}
+
+// deleteReleaseHandler handles DELETE /api/releases/{id}
+// Only open releases can be deleted; published releases are kept for rollback and audit.
+func (s *Server) deleteReleaseHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	id := mux.Vars(r)["id"]
+	rel, err := s.getRelease(r.Context(), tenant, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", "Release not found")
+		return
+	}
+	if rel.Status != releaseOpen {
+		writeError(w, http.StatusConflict, "invalid_status", fmt.Sprintf("Release is %s", rel.Status))
+		return
+	}
+
+	if err := s.storage.DeleteDocument(r.Context(), tenant, releasesCollection, id); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      id,
+		"message": "Release deleted successfully",
+	})
+}
+
+// publishReleaseHandler handles POST /api/releases/{id}/publish
+// Publishes every item in the release atomically.
+func (s *Server) publishReleaseHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	rel, err := s.getRelease(r.Context(), tenant, mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", "Release not found")
+		return
+	}
+	if rel.Status != releaseOpen {
+		writeError(w, http.StatusConflict, "invalid_status", fmt.Sprintf("Release is %s", rel.Status))
+		return
+	}
+
+	var req struct {
+		Author  string `json:"author,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	// Tenants that require a publish message get the caller's, not a default
+	if req.Message == "" && !s.settings.get(r.Context(), tenant).RequirePublishMessage {
+		req.Message = fmt.Sprintf("Release: %s", rel.Name)
+	}
+
+	if violation := s.checkPublishPolicy(r.Context(), tenant, req.Author, req.Message); violation != "" {
+		writeError(w, http.StatusUnprocessableEntity, "publish_message_required", violation)
+		return
+	}
+
+	readiness := s.checkReadiness(r.Context(), tenant, rel)
+	if !readiness.Ready {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":     "release_not_ready",
+			"message":   "Release has items that cannot be published",
+			"readiness": readiness,
+		})
+		return
+	}
+
+	// Remember extensions and current live versions for rollback
+	for i := range rel.Items {
+		item := &rel.Items[i]
+		item.Ext, _ = s.storedExt(r.Context(), tenant, item.Type, item.ID, item.fromState())
+		item.PreviousVersion = ""
+		if live, err := s.storage.Get(r.Context(), tenant, item.Type, item.ID, item.ext(), storage.StateLive); err == nil {
+			item.PreviousVersion = live.VersionID
+		}
+	}
+
+	ops := releaseOperations(rel)
+	if errs := s.checkOperations(r.Context(), tenant, ops, req.Author, req.Message); len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	results, err := s.commitOperations(r.Context(), tenant, ops, req.Author, req.Message)
+	if err != nil {
+		rel.addEvent("publish_failed", req.Author, req.Message, err)
+		if perr := s.putRelease(r.Context(), tenant, rel); perr != nil {
+			log.Error("Failed to save release %s: %v", rel.ID, perr)
+		}
+		writeError(w, http.StatusConflict, "publish_failed", err.Error())
+		return
+	}
+
+	for i := range rel.Items {
+		rel.Items[i].PublishedVersion = results[i].Version
+	}
+	rel.Status = releasePublished
+	rel.addEvent("published", req.Author, req.Message, nil)
+
+	if err := s.putRelease(r.Context(), tenant, rel); err != nil {
+		log.Error("Failed to save release %s: %v", rel.ID, err)
+	}
+
+	log.Info("Published release %s (%d items)", rel.Name, len(rel.Items))
+	writeJSON(w, http.StatusOK, rel)
+}
+
+// rollbackReleaseHandler handles POST /api/releases/{id}/rollback
+// Restores every item to the live version it had before the release was published
+// (items that were new are removed from live).
+func (s *Server) rollbackReleaseHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	ctx := r.Context()
+	rel, err := s.getRelease(ctx, tenant, mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", "Release not found")
+		return
+	}
+	if rel.Status != releasePublished {
+		writeError(w, http.StatusConflict, "invalid_status", fmt.Sprintf("Release is %s", rel.Status))
+		return
+	}
+
+	var req struct {
+		Author  string `json:"author,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	if req.Message == "" {
+		req.Message = fmt.Sprintf("Rollback of release: %s", rel.Name)
+	}
+
+	var failures []string
+	for _, item := range rel.Items {
+		if item.PreviousVersion == "" {
+			if err := s.storage.Delete(ctx, tenant, item.Type, item.ID, item.ext(), storage.StateLive); err != nil {
+				failures = append(failures, fmt.Sprintf("%s/%s: %v", item.Type, item.ID, err))
+				continue
+			}
+			s.triggerWebhooks(tenant, "delete", item.Type, item.ID, filepath.Base(item.ID)+"."+item.ext(), "")
+			continue
+		}
+
+		restored, err := s.storage.RestoreVersion(ctx, tenant, item.Type, item.ID, item.ext(), item.PreviousVersion)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s/%s: %v", item.Type, item.ID, err))
+			continue
+		}
+
+		record := &storage.HistoryRecord{
+			Version:   restored.VersionID,
+			Parent:    item.PublishedVersion,
+			Author:    req.Author,
+			Message:   req.Message,
+			Timestamp: time.Now(),
+			Size:      restored.Size,
+		}
+		if err := s.storage.PutHistoryRecord(ctx, tenant, item.Type, item.ID, record); err != nil {
+			log.Error("Failed to create history record: %v", err)
+		}
+		s.triggerWebhooks(tenant, "update", item.Type, item.ID, filepath.Base(restored.Key), restored.ContentType)
+	}
+
+	if len(failures) > 0 {
+		rel.addEvent("rollback_failed", req.Author, req.Message, fmt.Errorf("%v", failures))
+		s.putRelease(ctx, tenant, rel)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error":    "rollback_failed",
+			"message":  "Some items could not be rolled back",
+			"failures": failures,
+		})
+		return
+	}
+
+	rel.Status = releaseRolledBack
+	rel.addEvent("rolled_back", req.Author, req.Message, nil)
+	if err := s.putRelease(ctx, tenant, rel); err != nil {
+		log.Error("Failed to save release %s: %v", rel.ID, err)
+	}
+
+	log.Info("Rolled back release %s", rel.Name)
+	writeJSON(w, http.StatusOK, rel)
+}
@@ -0,0 +1,404 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const (
+	renditionsCollection      = "renditions"
+	renditionBodiesCollection = "rendition-bodies"
+	maxRenditionSize          = 32 << 20 // 32MB
+
+	// defaultRenditionMaxAge is how long clients may cache a rendition (1 day)
+	defaultRenditionMaxAge = 24 * 60 * 60
+)
+
+var renditionNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// errNoRenditionSource is returned when storing a rendition of content that doesn't exist
+var errNoRenditionSource = errors.New("content not found")
+
+// rendition is a file generated from a content item (a thumbnail, a WebP
+// conversion, a PDF preview). It records the content it was generated from,
+// so it shows as stale once the item changes.
+type rendition struct {
+	Name        string            `json:"name"`
+	Kind        string            `json:"kind,omitempty"` // e.g. thumbnail, webp, preview, poster
+	ContentType string            `json:"content_type"`
+	Size        int64             `json:"size"`
+	Width       int               `json:"width,omitempty"`
+	Height      int               `json:"height,omitempty"`
+	ETag        string            `json:"etag"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Source      renditionSource   `json:"source"`
+	MaxAge      int               `json:"max_age"` // seconds clients and CDNs may cache it
+	CreatedAt   time.Time         `json:"created_at"`
+	Stale       bool              `json:"stale"` // set when read: the item changed since it was generated
+}
+
+// renditionSource identifies the content a rendition was generated from
+type renditionSource struct {
+	State   string `json:"state"`
+	ETag    string `json:"etag"`
+	Version string `json:"version,omitempty"`
+}
+
+// renditionSet is the renditions of one content item, stored as a document
+type renditionSet struct {
+	Type       string                `json:"type"`
+	ID         string                `json:"id"`
+	Renditions map[string]*rendition `json:"renditions"`
+}
+
+func renditionSetID(contentType, id string) string {
+	return contentType + "/" + id
+}
+
+func renditionBodyID(contentType, id, name string) string {
+	return contentType + "/" + id + "/" + name
+}
+
+// getRenditions returns an item's renditions in name order, marking those
+// generated from content that has since changed
+func (s *Server) getRenditions(ctx context.Context, tenant, contentType, id string) ([]*rendition, error) {
+	set, err := s.loadRenditions(ctx, tenant, contentType, id)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]string) // state -> ETag
+	renditions := make([]*rendition, 0, len(set.Renditions))
+	for _, r := range set.Renditions {
+		etag, ok := current[r.Source.State]
+		if !ok {
+			if stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, "", storage.State(r.Source.State)); err == nil {
+				stream.Body.Close()
+				etag = stream.ETag
+			}
+			current[r.Source.State] = etag
+		}
+		r.Stale = etag != r.Source.ETag
+		renditions = append(renditions, r)
+	}
+	sort.Slice(renditions, func(i, j int) bool { return renditions[i].Name < renditions[j].Name })
+	return renditions, nil
+}
+
+// loadRenditions reads an item's rendition set (empty if it has none)
+func (s *Server) loadRenditions(ctx context.Context, tenant, contentType, id string) (*renditionSet, error) {
+	set := &renditionSet{Type: contentType, ID: id, Renditions: make(map[string]*rendition)}
+	data, err := s.storage.GetDocument(ctx, tenant, renditionsCollection, renditionSetID(contentType, id))
+	if err != nil {
+		return set, nil
+	}
+	if err := json.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("failed to parse renditions: %w", err)
+	}
+	if set.Renditions == nil {
+		set.Renditions = make(map[string]*rendition)
+	}
+	return set, nil
+}
+
+// updateRenditions applies fn to an item's rendition set and stores the
+// result, deleting it once empty
+func (s *Server) updateRenditions(ctx context.Context, tenant, contentType, id string, fn func(set *renditionSet) error) (*renditionSet, error) {
+	unlock := lockWrite(tenant + "/" + renditionsCollection + "/" + contentType + "/" + id)
+	defer unlock()
+
+	set, err := s.loadRenditions(ctx, tenant, contentType, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(set); err != nil {
+		return nil, err
+	}
+
+	if len(set.Renditions) == 0 {
+		err = s.storage.DeleteDocument(ctx, tenant, renditionsCollection, renditionSetID(contentType, id))
+	} else {
+		var data []byte
+		if data, err = json.Marshal(set); err == nil {
+			err = s.storage.PutDocument(ctx, tenant, renditionsCollection, renditionSetID(contentType, id), data)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save renditions: %w", err)
+	}
+	return set, nil
+}
+
+// putRendition stores a rendition generated from an item's content in a
+// state, replacing any rendition of the same name
+func (s *Server) putRendition(ctx context.Context, tenant, contentType, id string, state storage.State, r *rendition, body []byte) error {
+	stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, "", state)
+	if err != nil {
+		return fmt.Errorf("%w: %s (%s)", errNoRenditionSource, id, state)
+	}
+	stream.Body.Close()
+
+	if err := s.storage.PutDocument(ctx, tenant, renditionBodiesCollection, renditionBodyID(contentType, id, r.Name), body); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(body)
+	r.Size = int64(len(body))
+	r.ETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	r.Source = renditionSource{State: string(state), ETag: stream.ETag, Version: stream.VersionID}
+	r.CreatedAt = time.Now().UTC()
+	if r.MaxAge == 0 {
+		r.MaxAge = defaultRenditionMaxAge
+	}
+
+	_, err = s.updateRenditions(ctx, tenant, contentType, id, func(set *renditionSet) error {
+		set.Renditions[r.Name] = r
+		return nil
+	})
+	return err
+}
+
+// deleteRenditions removes renditions of an item (all of them, or only the
+// stale ones) with their bodies, returning the names removed
+func (s *Server) deleteRenditions(ctx context.Context, tenant, contentType, id string, staleOnly bool) ([]string, error) {
+	var remove []string
+	if staleOnly {
+		renditions, err := s.getRenditions(ctx, tenant, contentType, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range renditions {
+			if r.Stale {
+				remove = append(remove, r.Name)
+			}
+		}
+	}
+
+	removed := []string{}
+	_, err := s.updateRenditions(ctx, tenant, contentType, id, func(set *renditionSet) error {
+		if !staleOnly {
+			for name := range set.Renditions {
+				remove = append(remove, name)
+			}
+		}
+		for _, name := range remove {
+			if _, ok := set.Renditions[name]; ok {
+				delete(set.Renditions, name)
+				removed = append(removed, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range removed {
+		if err := s.storage.DeleteDocument(ctx, tenant, renditionBodiesCollection, renditionBodyID(contentType, id, name)); err != nil {
+			log.Error("Failed to delete body of rendition %s of %s/%s: %v", name, contentType, id, err)
+		}
+	}
+	sort.Strings(removed)
+	return removed, nil
+}
+
+// =============================================================================
+// Rendition Handlers
+// =============================================================================
+
+// listRenditionsHandler handles GET /api/content/{type}/{id}/renditions
+func (s *Server) listRenditionsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id := vars["type"], vars["id"]
+
+	renditions, err := s.getRenditions(r.Context(), s.getTenant(r), contentType, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"type":       contentType,
+		"id":         id,
+		"renditions": renditions,
+		"count":      len(renditions),
+	})
+}
+
+// getRenditionHandler handles GET /api/content/{type}/{id}/renditions/{name},
+// serving the rendition's file
+func (s *Server) getRenditionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id, name := vars["type"], vars["id"], vars["name"]
+	tenant := s.getTenant(r)
+
+	renditions, err := s.getRenditions(r.Context(), tenant, contentType, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	var found *rendition
+	for _, rd := range renditions {
+		if rd.Name == name {
+			found = rd
+		}
+	}
+	if found == nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Rendition '%s' not found", name))
+		return
+	}
+
+	if match := r.Header.Get("If-None-Match"); match != "" && etagsMatch(match, found.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	body, err := s.storage.GetDocument(r.Context(), tenant, renditionBodiesCollection, renditionBodyID(contentType, id, name))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Rendition '%s' not found", name))
+		return
+	}
+
+	w.Header().Set("Content-Type", found.ContentType)
+	w.Header().Set("ETag", found.ETag)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if found.Stale {
+		// Still served so pages don't break, but not cached until regenerated
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Rendition-Stale", "true")
+	} else {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", found.MaxAge))
+	}
+	w.Write(body)
+}
+
+// putRenditionHandler handles PUT /api/content/{type}/{id}/renditions/{name}
+// The body is the rendition's file; ?kind=, ?width=, ?height=, and ?max_age=
+// describe it, X-Meta-* headers set its metadata, and ?state= names the
+// content it was generated from (default live).
+func (s *Server) putRenditionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id, name := vars["type"], vars["id"], vars["name"]
+	tenant := s.getTenant(r)
+	query := r.URL.Query()
+
+	if !renditionNamePattern.MatchString(name) {
+		writeError(w, http.StatusBadRequest, "invalid_name", "Rendition names are lowercase letters, digits, '.', '-' and '_'")
+		return
+	}
+	state := storage.StateLive
+	if value := query.Get("state"); value != "" {
+		if !storage.ValidState(value) {
+			writeError(w, http.StatusBadRequest, "invalid_state", fmt.Sprintf("Invalid state: %s", value))
+			return
+		}
+		state = storage.State(value)
+	}
+
+	rd := &rendition{
+		Name:        name,
+		Kind:        query.Get("kind"),
+		ContentType: r.Header.Get("Content-Type"),
+		Metadata:    extractMetadata(r),
+	}
+	for param, field := range map[string]*int{"width": &rd.Width, "height": &rd.Height, "max_age": &rd.MaxAge} {
+		if value := query.Get(param); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				writeError(w, http.StatusBadRequest, "invalid_"+param, fmt.Sprintf("%s must be a non-negative integer", param))
+				return
+			}
+			*field = n
+		}
+	}
+	if rd.ContentType == "" {
+		rd.ContentType = "application/octet-stream"
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRenditionSize+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "Failed to read body")
+		return
+	}
+	if len(body) > maxRenditionSize {
+		writeError(w, http.StatusRequestEntityTooLarge, "too_large", fmt.Sprintf("Renditions are limited to %d bytes", maxRenditionSize))
+		return
+	}
+
+	if err := s.putRendition(r.Context(), tenant, contentType, id, state, rd, body); err != nil {
+		if errors.Is(err, errNoRenditionSource) {
+			writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Content '%s' not found in %s", id, state))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	log.Debug("Stored rendition %s of %s/%s (%s, %d bytes)", name, contentType, id, rd.ContentType, rd.Size)
+	writeJSON(w, http.StatusOK, rd)
+}
+
+// deleteRenditionHandler handles DELETE /api/content/{type}/{id}/renditions/{name}
+func (s *Server) deleteRenditionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id, name := vars["type"], vars["id"], vars["name"]
+	tenant := s.getTenant(r)
+
+	found := false
+	_, err := s.updateRenditions(r.Context(), tenant, contentType, id, func(set *renditionSet) error {
+		_, found = set.Renditions[name]
+		delete(set.Renditions, name)
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Rendition '%s' not found", name))
+		return
+	}
+	if err := s.storage.DeleteDocument(r.Context(), tenant, renditionBodiesCollection, renditionBodyID(contentType, id, name)); err != nil {
+		log.Error("Failed to delete body of rendition %s of %s/%s: %v", name, contentType, id, err)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"type":    contentType,
+		"id":      id,
+		"name":    name,
+		"message": "Rendition deleted",
+	})
+}
+
+// deleteStaleRenditionsHandler handles DELETE /api/content/{type}/{id}/renditions,
+// removing the renditions generated from content that has since changed
+// (?all=true removes every rendition)
+func (s *Server) deleteStaleRenditionsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id := vars["type"], vars["id"]
+
+	removed, err := s.deleteRenditions(r.Context(), s.getTenant(r), contentType, id, r.URL.Query().Get("all") != "true")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"type":    contentType,
+		"id":      id,
+		"deleted": removed,
+		"count":   len(removed),
+	})
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+// contentLocation returns the API path an item is read from in a state
+func contentLocation(contentType, id string, state storage.State) string {
+	if state == storage.StateLive {
+		return fmt.Sprintf("/api/content/%s/items/%s", contentType, id)
+	}
+	return fmt.Sprintf("/api/content/%s/items/%s/states/%s", contentType, id, state)
+}
+
+// wantsRepresentation reports whether a write should answer with the stored
+// content rather than a summary: ?return=representation, or
+// Prefer: return=representation (RFC 7240)
+func wantsRepresentation(r *http.Request) bool {
+	if r.URL.Query().Get("return") == "representation" {
+		return true
+	}
+	for _, prefer := range r.Header.Values("Prefer") {
+		for _, token := range strings.Split(prefer, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "return=representation") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeWriteResult answers a successful create or update. Creates carry a
+// Location header. The summary names the stored version, content type, and
+// metadata; with return=representation the stored content itself is sent,
+// with the same headers a read would have and the metadata as X-Meta-*.
+func (s *Server) writeWriteResult(w http.ResponseWriter, r *http.Request, status int, tenant, contentType, id, ext string, state storage.State, item *storage.ContentItem, message string) {
+	location := apiPath(r, contentLocation(contentType, id, state))
+	if status == http.StatusCreated {
+		w.Header().Set("Location", location)
+	}
+	w.Header().Set("ETag", item.ETag)
+
+	if wantsRepresentation(r) {
+		stream, err := s.storage.GetStream(r.Context(), tenant, contentType, id, ext, state)
+		if err == nil {
+			defer stream.Body.Close()
+
+			w.Header().Set("Content-Location", location)
+			w.Header().Set("Preference-Applied", "return=representation")
+			w.Header().Set("ETag", stream.ETag)
+			w.Header().Set("Last-Modified", stream.LastModified.UTC().Format(time.RFC1123))
+			if stream.VersionID != "" {
+				w.Header().Set("X-Version-ID", stream.VersionID)
+			}
+			w.Header().Set("X-Content-State", string(state))
+			for key, value := range stream.Metadata {
+				w.Header().Set("X-Meta-"+key, value)
+			}
+			w.Header().Set("Content-Type", stream.ContentType)
+			if stream.Size > 0 {
+				w.Header().Set("Content-Length", fmt.Sprintf("%d", stream.Size))
+			}
+			w.WriteHeader(status)
+			copyBuffered(w, stream.Body)
+			return
+		}
+		// The write succeeded, so fall back to the summary rather than fail it
+		log.Error("Failed to read back %s/%s after write: %v", contentType, id, err)
+	}
+
+	metadata := item.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"id":           id,
+		"state":        string(state),
+		"version":      item.VersionID,
+		"etag":         item.ETag,
+		"content-type": item.ContentType,
+		"metadata":     metadata,
+		"location":     location,
+		"message":      message,
+	})
+}
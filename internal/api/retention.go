@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/storage"
+)
+
+// retentionDefault keys the version retention of every type without its own
+const retentionDefault = "*"
+
+// RetentionPolicy keeps versions of live content by count and by age,
+// whichever keeps more: e.g. the newest 10, plus any from the last 90 days
+type RetentionPolicy struct {
+	// MaxVersions is how many of the newest versions are always kept
+	// (-1 keeps every version; 0 or unset uses the server's --max-versions)
+	MaxVersions int `json:"max_versions,omitempty"`
+
+	// MaxAge keeps versions younger than this past MaxVersions, as a
+	// duration or in days (e.g. "2160h" or "90d"; "0" keeps by count only,
+	// unset uses the server's --max-version-age)
+	MaxAge string `json:"max_age,omitempty"`
+}
+
+// parseRetentionAge parses a retention age: a duration, or a number of days ("90d")
+func parseRetentionAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	return d, nil
+}
+
+// apply overlays the policy on a storage retention; fields it leaves unset
+// keep their values
+func (p *RetentionPolicy) apply(retention storage.VersionRetention) storage.VersionRetention {
+	if p.MaxVersions != 0 {
+		retention.MaxVersions = p.MaxVersions
+	}
+	if p.MaxAge != "" {
+		if age, err := parseRetentionAge(p.MaxAge); err == nil {
+			retention.MaxAge = age
+			if age == 0 {
+				retention.MaxAge = -1 // keep by count only, whatever the server default
+			}
+		}
+	}
+	return retention
+}
+
+// validateVersionRetention checks retention policies before they are stored
+func validateVersionRetention(policies map[string]*RetentionPolicy) error {
+	for contentType, p := range policies {
+		if contentType != retentionDefault && !validTypeName(contentType) {
+			return fmt.Errorf("version retention key %q must be a content type name or %q", contentType, retentionDefault)
+		}
+		if p == nil {
+			return fmt.Errorf("version retention for %q needs a policy (e.g. {\"max_versions\": 10})", contentType)
+		}
+		if p.MaxVersions < -1 {
+			return fmt.Errorf("version retention for %q: max_versions must be -1 (unlimited) or more", contentType)
+		}
+		if p.MaxAge != "" {
+			if _, err := parseRetentionAge(p.MaxAge); err != nil {
+				return fmt.Errorf("version retention for %q: %v (expected e.g. 90d or 2160h)", contentType, err)
+			}
+		}
+	}
+	return nil
+}
+
+// versionRetention returns a content type's retention policy: its own
+// policy over the tenant's "*" policy. ok is false when neither is set, so
+// the server defaults apply.
+func (ts *TenantSettings) versionRetention(contentType string) (retention storage.VersionRetention, ok bool) {
+	if p := ts.VersionRetention[retentionDefault]; p != nil {
+		retention, ok = p.apply(retention), true
+	}
+	if p := ts.VersionRetention[contentType]; p != nil {
+		retention, ok = p.apply(retention), true
+	}
+	return retention, ok
+}
+
+// versionRetentionHandler attaches the tenant's retention policy for the
+// route's content type to the request, so the versions pruned after a write
+// follow it. The scheduled garbage collection applies it too, as versions
+// also expire by age between writes.
+func (s *Server) versionRetentionHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		contentType := vars["type"]
+		if contentType == "" || !isWriteMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tenant := vars["tenant"]
+		if tenant == "" {
+			tenant = s.getTenant(r)
+		}
+		if retention, ok := s.settings.get(r.Context(), tenant).versionRetention(contentType); ok {
+			r = r.WithContext(storage.WithVersionRetention(r.Context(), retention))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
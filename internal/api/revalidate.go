@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/models"
+	"velocity/internal/storage"
+)
+
+const validateJobKind = "validate"
+
+// invalidItem is a content item that no longer matches its schema
+type invalidItem struct {
+	ID     string   `json:"id"`
+	Errors []string `json:"errors"`
+}
+
+// validationReport is the stored result of re-validating a content type
+type validationReport struct {
+	Type        string        `json:"type"`
+	State       string        `json:"state"`
+	GeneratedAt time.Time     `json:"generated_at"`
+	Checked     int           `json:"checked"`
+	Valid       int           `json:"valid"`
+	Invalid     int           `json:"invalid"`
+	Skipped     int           `json:"skipped"` // non-JSON or unreadable items
+	Items       []invalidItem `json:"items"`
+}
+
+// validationReportID returns the document ID of a content type's latest report
+func validationReportID(contentType string, state storage.State) string {
+	return fmt.Sprintf("validation-%s-%s", contentType, state)
+}
+
+// revalidate checks every item of a content type in a state against the current schema
+func (s *Server) revalidate(ctx context.Context, j *job, tenant, contentType string, state storage.State, schema *models.Schema) (*validationReport, error) {
+	report := &validationReport{Type: contentType, State: string(state), Items: []invalidItem{}}
+
+	items, err := s.storage.List(ctx, tenant, contentType, state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list content: %w", err)
+	}
+
+	for i, item := range items {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		j.setProgress(i, len(items))
+
+		id, ext := extractIDAndExt(item.Key, contentType, state)
+		if !isJSONContent(mimeFromExt(strings.ToLower(filepath.Ext(item.Key)))) {
+			report.Skipped++
+			continue
+		}
+		content, err := s.storage.Get(ctx, tenant, contentType, id, ext, state)
+		if err != nil {
+			report.Skipped++
+			continue
+		}
+
+		report.Checked++
+		if errs := validateDocument(schema, content.Content); len(errs) > 0 {
+			report.Invalid++
+			report.Items = append(report.Items, invalidItem{ID: id, Errors: errs})
+		} else {
+			report.Valid++
+		}
+	}
+
+	report.GeneratedAt = time.Now().UTC()
+	return report, nil
+}
+
+// =============================================================================
+// Validation Job Handlers
+// =============================================================================
+
+// startValidationHandler handles POST /api/validate
+// Body: {"type": "pages", "state": "live"}. Re-validates in the background.
+func (s *Server) startValidationHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+
+	var req struct {
+		Type  string `json:"type"`
+		State string `json:"state,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+	if req.Type == "" {
+		writeError(w, http.StatusBadRequest, "missing_type", "Field 'type' is required")
+		return
+	}
+
+	state := storage.StateLive
+	if req.State != "" {
+		if !storage.ValidState(req.State) {
+			writeError(w, http.StatusBadRequest, "invalid_state", fmt.Sprintf("Invalid state: %s", req.State))
+			return
+		}
+		state = storage.State(req.State)
+	}
+
+	schema := s.loadSchema(r.Context(), tenant, req.Type)
+	if schema == nil {
+		writeError(w, http.StatusUnprocessableEntity, "no_schema", fmt.Sprintf("No schema defined for '%s'", req.Type))
+		return
+	}
+
+	j, err := s.jobs.start(tenant, validateJobKind, func(ctx context.Context, j *job) (interface{}, error) {
+		report, err := s.revalidate(ctx, j, tenant, req.Type, state, schema)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(report)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal report: %w", err)
+		}
+		if err := s.storage.PutDocument(ctx, tenant, reportsCollection, validationReportID(req.Type, state), data); err != nil {
+			return nil, err
+		}
+		return map[string]int{"checked": report.Checked, "valid": report.Valid, "invalid": report.Invalid}, nil
+	})
+	if err == errJobRunning {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":   "job_running",
+			"message": "A validation job is already running",
+			"job":     j.info(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"job":     j.info(),
+		"report":  apiPath(r, fmt.Sprintf("/api/validate/%s/report?state=%s", req.Type, state)),
+		"message": "Validation started",
+	})
+}
+
+// validationReportHandler handles GET /api/validate/{type}/report
+// Returns the latest report for ?state= (default live); ?format=csv downloads it as CSV.
+func (s *Server) validationReportHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	contentType := mux.Vars(r)["type"]
+
+	state := storage.StateLive
+	if v := r.URL.Query().Get("state"); v != "" && storage.ValidState(v) {
+		state = storage.State(v)
+	}
+
+	data, err := s.storage.GetDocument(r.Context(), tenant, reportsCollection, validationReportID(contentType, state))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("No validation report for '%s' (%s)", contentType, state))
+		return
+	}
+
+	var report validationReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", "Failed to parse stored report")
+		return
+	}
+
+	filename := fmt.Sprintf("validation-%s-%s", contentType, state)
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+		w.WriteHeader(http.StatusOK)
+
+		out := csv.NewWriter(w)
+		out.Write([]string{"id", "error"})
+		for _, item := range report.Items {
+			for _, e := range item.Errors {
+				out.Write([]string{item.ID, e})
+			}
+		}
+		out.Flush()
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+	writeJSON(w, http.StatusOK, &report)
+}
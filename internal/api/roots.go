@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+// contentRootHeader selects a content root explicitly, e.g. to stage an import
+// in the inactive root
+const contentRootHeader = "X-Content-Root"
+
+// contentRootHandler applies an explicit X-Content-Root to the request context.
+// Requests without the header use the tenant's active root.
+func (s *Server) contentRootHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		root := r.Header.Get(contentRootHeader)
+		if root == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !storage.ValidRoot(root) {
+			writeError(w, http.StatusBadRequest, "invalid_root", fmt.Sprintf("Invalid content root: %s (expected blue or green)", root))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(storage.WithRoot(r.Context(), root)))
+	})
+}
+
+// detachedContext returns a context for background work started by a
+// request: it outlives the request but addresses the same content root
+func detachedContext(ctx context.Context) context.Context {
+	return withRequestRoot(context.Background(), ctx)
+}
+
+// withRequestRoot returns ctx addressing the content root of request (a job
+// context, say, for work started by the request)
+func withRequestRoot(ctx, request context.Context) context.Context {
+	if root := storage.RootFromContext(request); root != "" {
+		return storage.WithRoot(ctx, root)
+	}
+	return ctx
+}
+
+// inactiveRoot returns the root that is not active
+func inactiveRoot(active string) string {
+	if active == storage.RootGreen {
+		return storage.RootBlue
+	}
+	return storage.RootGreen
+}
+
+// =============================================================================
+// Content Root Handlers
+// =============================================================================
+
+// getRootsHandler handles GET /api/tenant/roots
+func (s *Server) getRootsHandler(w http.ResponseWriter, r *http.Request) {
+	pointer := s.roots.ActiveRoot(r.Context(), s.getTenant(r))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"active":   pointer,
+		"inactive": inactiveRoot(pointer.Active),
+	})
+}
+
+// activateRootHandler handles POST /api/tenant/activate-root
+// Body: {"root": "green", "author": "..."} switches roots; {"rollback": true}
+// switches back to the previous root. Activating an empty root requires "force".
+func (s *Server) activateRootHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenant := s.getTenant(r)
+
+	var req struct {
+		Root     string `json:"root"`
+		Rollback bool   `json:"rollback,omitempty"`
+		Author   string `json:"author,omitempty"`
+		Force    bool   `json:"force,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	current := s.roots.ActiveRoot(ctx, tenant)
+	if req.Rollback {
+		if current.Previous == "" {
+			writeError(w, http.StatusConflict, "no_previous_root", "No previous root to roll back to")
+			return
+		}
+		req.Root = current.Previous
+	}
+	if !storage.ValidRoot(req.Root) {
+		writeError(w, http.StatusBadRequest, "invalid_root", fmt.Sprintf("Invalid content root: %s (expected blue or green)", req.Root))
+		return
+	}
+	if req.Root == current.Active {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"active":   current,
+			"inactive": inactiveRoot(current.Active),
+			"message":  fmt.Sprintf("Root '%s' is already active", req.Root),
+		})
+		return
+	}
+
+	// Guard against flipping the site to an empty root by mistake
+	if !req.Force {
+		types, err := s.storage.ListContentTypes(storage.WithRoot(ctx, req.Root), tenant)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+			return
+		}
+		if len(types) == 0 {
+			writeError(w, http.StatusConflict, "root_empty", fmt.Sprintf("Root '%s' has no content; set \"force\": true to activate it anyway", req.Root))
+			return
+		}
+	}
+
+	pointer, err := s.roots.ActivateRoot(ctx, tenant, req.Root, req.Author)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	log.Info("Activated content root %s for tenant %s (previous: %s)", pointer.Active, tenant, pointer.Previous)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"active":   pointer,
+		"inactive": inactiveRoot(pointer.Active),
+		"message":  fmt.Sprintf("Root '%s' is now live", pointer.Active),
+	})
+}
@@ -0,0 +1,510 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const (
+	s3Namespace   = "http://s3.amazonaws.com/doc/2006-03-01/"
+	s3MaxKeys     = 1000
+	s3TimeFormat  = "2006-01-02T15:04:05.000Z"
+	s3GatewayName = "velocity"
+)
+
+// s3Object is an object in a bucket listing
+type s3Object struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type s3CommonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// s3ListBucketResult answers ListObjects (v1) and ListObjectsV2
+type s3ListBucketResult struct {
+	XMLName               xml.Name         `xml:"ListBucketResult"`
+	Namespace             string           `xml:"xmlns,attr"`
+	Name                  string           `xml:"Name"`
+	Prefix                string           `xml:"Prefix"`
+	Marker                *string          `xml:"Marker"`               // v1
+	NextMarker            string           `xml:"NextMarker,omitempty"` // v1
+	StartAfter            string           `xml:"StartAfter,omitempty"`
+	ContinuationToken     string           `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string           `xml:"NextContinuationToken,omitempty"`
+	KeyCount              *int             `xml:"KeyCount"` // v2
+	MaxKeys               int              `xml:"MaxKeys"`
+	Delimiter             string           `xml:"Delimiter,omitempty"`
+	EncodingType          string           `xml:"EncodingType,omitempty"`
+	IsTruncated           bool             `xml:"IsTruncated"`
+	Contents              []s3Object       `xml:"Contents"`
+	CommonPrefixes        []s3CommonPrefix `xml:"CommonPrefixes"`
+}
+
+type s3Bucket struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+}
+
+type s3Owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+type s3ListAllMyBucketsResult struct {
+	XMLName   xml.Name   `xml:"ListAllMyBucketsResult"`
+	Namespace string     `xml:"xmlns,attr"`
+	Owner     s3Owner    `xml:"Owner"`
+	Buckets   []s3Bucket `xml:"Buckets>Bucket"`
+}
+
+type s3Error struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource"`
+}
+
+// writeXML writes an S3 XML response
+func writeXML(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(data)
+}
+
+// writeS3Error writes an S3 error response
+func writeS3Error(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return
+	}
+	writeXML(w, status, s3Error{Code: code, Message: message, Resource: r.URL.Path})
+}
+
+// s3EncodeKey applies encoding-type=url to a key or prefix
+func s3EncodeKey(value string, encode bool) string {
+	if !encode {
+		return value
+	}
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+// S3Gateway returns a read-only, S3-compatible view of live content. Each
+// tenant is a bucket, addressed path-style; object keys are {type}/{id}.{ext}.
+// Requests authenticate with one of the bucket's API keys as the access key ID
+// and need the read permission, as on the HTTP API.
+func (s *Server) S3Gateway() http.Handler {
+	router := mux.NewRouter()
+	router.SkipClean(true)
+
+	router.HandleFunc("/", s.s3ListBucketsHandler).Methods("GET")
+	router.HandleFunc("/{bucket}", s.s3Authenticated(s.s3BucketHandler)).Methods("GET", "HEAD")
+	router.HandleFunc("/{bucket}/", s.s3Authenticated(s.s3BucketHandler)).Methods("GET", "HEAD")
+	router.HandleFunc("/{bucket}/{key:.+}", s.s3Authenticated(s.s3ObjectHandler)).Methods("GET", "HEAD")
+
+	// Everything else would modify content
+	router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeS3Error(w, r, http.StatusForbidden, "AccessDenied", "The Velocity S3 gateway is read-only")
+	})
+	return router
+}
+
+// s3AccessKey returns the API key an S3 request carries: the access key ID of
+// a SigV4 Authorization header or presigned URL, or an X-API-Key header.
+// Signatures aren't checked, since only a hash of each key is stored; the key
+// itself is the credential, as on the HTTP API.
+func s3AccessKey(r *http.Request) string {
+	if key := extractAPIKey(r); key != "" {
+		return key
+	}
+	if fields, ok := strings.CutPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 "); ok {
+		for _, field := range strings.Split(fields, ",") {
+			if credential, ok := strings.CutPrefix(strings.TrimSpace(field), "Credential="); ok {
+				key, _, _ := strings.Cut(credential, "/")
+				return key
+			}
+		}
+	}
+	key, _, _ := strings.Cut(r.URL.Query().Get("X-Amz-Credential"), "/")
+	return key
+}
+
+// s3Authenticated wraps a bucket handler to require one of the bucket's API
+// keys with the read permission. Unknown buckets are refused the same way as
+// unknown keys, so buckets can't be discovered without a key.
+func (s *Server) s3Authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		secret := s3AccessKey(r)
+		if secret == "" {
+			writeS3Error(w, r, http.StatusForbidden, "AccessDenied", "An API key is required as the access key ID")
+			return
+		}
+
+		ctx, authErr := s.authenticate(r.Context(), mux.Vars(r)["bucket"], secret, "")
+		if authErr != nil {
+			if authErr.status == http.StatusServiceUnavailable {
+				writeS3Error(w, r, http.StatusServiceUnavailable, "ServiceUnavailable", authErr.message)
+				return
+			}
+			writeS3Error(w, r, http.StatusForbidden, "InvalidAccessKeyId", "The access key ID is not an API key of this bucket")
+			return
+		}
+		if !allowed(ctx, permRead) {
+			writeS3Error(w, r, http.StatusForbidden, "AccessDenied", fmt.Sprintf("The %s role doesn't have the %s permission", roleFromContext(ctx), permRead))
+			return
+		}
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// s3KeyTenant returns the tenant an API key belongs to, or "" when it's none's
+func (s *Server) s3KeyTenant(ctx context.Context, secret string) (string, error) {
+	tenants, err := s.storage.ListTenants(ctx)
+	if err != nil {
+		return "", err
+	}
+	hash := hashAPIKey(secret)
+	for _, tenant := range tenants {
+		keys, err := s.apiKeys.all(ctx, tenant)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := keys[hash]; ok {
+			return tenant, nil
+		}
+	}
+	return "", nil
+}
+
+// s3Objects lists a tenant's live objects with keys starting with prefix, sorted by key
+func (s *Server) s3Objects(ctx context.Context, tenant, prefix string) ([]s3Object, error) {
+	types, err := s.storage.ListContentTypes(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []s3Object{}
+	for _, contentType := range types {
+		// Only list types the prefix can match
+		if !strings.HasPrefix(contentType+"/", prefix) && !strings.HasPrefix(prefix, contentType+"/") {
+			continue
+		}
+
+		items, err := s.storage.List(ctx, tenant, contentType, storage.StateLive)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			id, ext := extractIDAndExt(item.Key, contentType, storage.StateLive)
+			key := contentType + "/" + id + "." + ext
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			objects = append(objects, s3Object{
+				Key:          key,
+				LastModified: item.LastModified.UTC().Format(s3TimeFormat),
+				ETag:         item.ETag,
+				Size:         item.Size,
+				StorageClass: "STANDARD",
+			})
+		}
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// s3TenantExists reports whether a bucket names a tenant
+func (s *Server) s3TenantExists(ctx context.Context, tenant string) (bool, error) {
+	tenants, err := s.storage.ListTenants(ctx)
+	if err != nil {
+		return false, err
+	}
+	return containsString(tenants, tenant), nil
+}
+
+// =============================================================================
+// S3 Gateway Handlers
+// =============================================================================
+
+// s3ListBucketsHandler handles GET / (ListBuckets), listing the one bucket
+// the request's API key belongs to
+func (s *Server) s3ListBucketsHandler(w http.ResponseWriter, r *http.Request) {
+	secret := s3AccessKey(r)
+	if secret == "" {
+		writeS3Error(w, r, http.StatusForbidden, "AccessDenied", "An API key is required as the access key ID")
+		return
+	}
+	tenant, err := s.s3KeyTenant(r.Context(), secret)
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	if tenant == "" {
+		writeS3Error(w, r, http.StatusForbidden, "InvalidAccessKeyId", "The access key ID is not an API key")
+		return
+	}
+
+	result := s3ListAllMyBucketsResult{
+		Namespace: s3Namespace,
+		Owner:     s3Owner{ID: s3GatewayName, DisplayName: s3GatewayName},
+		Buckets:   []s3Bucket{},
+	}
+	result.Buckets = append(result.Buckets, s3Bucket{Name: tenant, CreationDate: time.Unix(0, 0).UTC().Format(s3TimeFormat)})
+	writeXML(w, http.StatusOK, result)
+}
+
+// s3BucketHandler handles HEAD /{bucket} (HeadBucket), GET /{bucket}?location
+// (GetBucketLocation), and GET /{bucket} (ListObjects, or ListObjectsV2 with
+// list-type=2)
+func (s *Server) s3BucketHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := mux.Vars(r)["bucket"]
+	query := r.URL.Query()
+
+	exists, err := s.s3TenantExists(r.Context(), tenant)
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	if !exists {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchBucket", fmt.Sprintf("Bucket '%s' does not exist", tenant))
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+		return
+	case query.Has("location"):
+		writeXML(w, http.StatusOK, struct {
+			XMLName   xml.Name `xml:"LocationConstraint"`
+			Namespace string   `xml:"xmlns,attr"`
+		}{Namespace: s3Namespace})
+		return
+	}
+
+	v2 := query.Get("list-type") == "2"
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	encode := query.Get("encoding-type") == "url"
+
+	maxKeys := s3MaxKeys
+	if value := query.Get("max-keys"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", "max-keys must be a non-negative integer")
+			return
+		}
+		if n < maxKeys {
+			maxKeys = n
+		}
+	}
+
+	// Listing resumes after the marker, start-after, or continuation token
+	after := query.Get("marker")
+	if v2 {
+		after = query.Get("start-after")
+		if token := query.Get("continuation-token"); token != "" {
+			decoded, err := base64.StdEncoding.DecodeString(token)
+			if err != nil {
+				writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", "The continuation token provided is incorrect")
+				return
+			}
+			after = string(decoded)
+		}
+	}
+
+	objects, err := s.s3Objects(r.Context(), tenant, prefix)
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	result := s3ListBucketResult{
+		Namespace:      s3Namespace,
+		Name:           tenant,
+		Prefix:         s3EncodeKey(prefix, encode),
+		MaxKeys:        maxKeys,
+		Delimiter:      s3EncodeKey(delimiter, encode),
+		Contents:       []s3Object{},
+		CommonPrefixes: []s3CommonPrefix{},
+	}
+	if encode {
+		result.EncodingType = "url"
+	}
+
+	seen := make(map[string]bool)
+	count := 0
+	last := ""
+	for _, object := range objects {
+		// Keys below a delimiter are rolled up into a common prefix
+		entry := object.Key
+		rolledUp := false
+		if delimiter != "" {
+			if idx := strings.Index(object.Key[len(prefix):], delimiter); idx != -1 {
+				entry = object.Key[:len(prefix)+idx+len(delimiter)]
+				rolledUp = true
+			}
+		}
+		if entry <= after || seen[entry] {
+			continue
+		}
+		if count == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		seen[entry] = true
+		count++
+		last = entry
+
+		if rolledUp {
+			result.CommonPrefixes = append(result.CommonPrefixes, s3CommonPrefix{Prefix: s3EncodeKey(entry, encode)})
+			continue
+		}
+		object.Key = s3EncodeKey(object.Key, encode)
+		result.Contents = append(result.Contents, object)
+	}
+
+	if v2 {
+		result.KeyCount = &count
+		result.StartAfter = s3EncodeKey(query.Get("start-after"), encode)
+		result.ContinuationToken = query.Get("continuation-token")
+		if result.IsTruncated {
+			result.NextContinuationToken = base64.StdEncoding.EncodeToString([]byte(last))
+		}
+	} else {
+		marker := s3EncodeKey(query.Get("marker"), encode)
+		result.Marker = &marker
+		if result.IsTruncated {
+			result.NextMarker = s3EncodeKey(last, encode)
+		}
+	}
+
+	log.Debug("S3 gateway listed %d entries of bucket %s (prefix %q)", count, tenant, prefix)
+	writeXML(w, http.StatusOK, result)
+}
+
+// s3ObjectHandler handles GET and HEAD /{bucket}/{key} (GetObject, HeadObject).
+// Objects are served as stored: render plugins and variants aren't applied, so
+// ETags and sizes match the listing.
+func (s *Server) s3ObjectHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenant, key := vars["bucket"], vars["key"]
+
+	contentType, rest, ok := strings.Cut(key, "/")
+	dot := strings.LastIndex(rest, ".")
+	if !ok || dot <= 0 || strings.Contains(rest[dot:], "/") {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist")
+		return
+	}
+
+	stream, err := s.storage.GetStream(r.Context(), tenant, contentType, rest[:dot], rest[dot+1:], storage.StateLive)
+	if err != nil {
+		if strings.Contains(err.Error(), "NoSuchKey") || strings.Contains(err.Error(), "not found") {
+			writeS3Error(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist")
+			return
+		}
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	defer stream.Body.Close()
+
+	if checkNotModified(r, stream.ETag, stream.LastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", stream.ContentType)
+	w.Header().Set("ETag", stream.ETag)
+	w.Header().Set("Last-Modified", stream.LastModified.UTC().Format(http.TimeFormat))
+	w.Header().Set("Accept-Ranges", "bytes")
+	for name, value := range stream.Metadata {
+		w.Header().Set("X-Amz-Meta-"+name, value)
+	}
+
+	start, length := int64(0), stream.Size
+	status := http.StatusOK
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && stream.Size > 0 {
+		first, last, ok := parseByteRange(rangeHeader, stream.Size)
+		if !ok {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", stream.Size))
+			writeS3Error(w, r, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range is not satisfiable")
+			return
+		}
+		start, length = first, last-first+1
+		status = http.StatusPartialContent
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", first, last, stream.Size))
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	w.WriteHeader(status)
+
+	if r.Method == http.MethodHead {
+		return
+	}
+	if start > 0 {
+		if _, err := io.CopyN(io.Discard, stream.Body, start); err != nil {
+			return
+		}
+	}
+	io.CopyN(w, stream.Body, length)
+}
+
+// parseByteRange parses a single-range Range header ("bytes=0-99", "bytes=100-",
+// or "bytes=-100") against an object's size, returning the first and last byte
+func parseByteRange(header string, size int64) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	startText, endText, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, false
+	}
+
+	if startText == "" {
+		// Suffix range: the last n bytes
+		n, err := strconv.ParseInt(endText, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true
+	}
+
+	first, err := strconv.ParseInt(startText, 10, 64)
+	if err != nil || first < 0 || first >= size {
+		return 0, 0, false
+	}
+	last := size - 1
+	if endText != "" {
+		end, err := strconv.ParseInt(endText, 10, 64)
+		if err != nil || end < first {
+			return 0, 0, false
+		}
+		if end < last {
+			last = end
+		}
+	}
+	return first, last, true
+}
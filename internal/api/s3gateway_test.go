@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestS3GatewayRequiresTheBucketsKey(t *testing.T) {
+	s := newTestServer(t)
+	admin := newTestKey(t, s, "acme", roleAdmin)
+	viewer := newTestKey(t, s, "acme", roleViewer)
+	other := newTestKey(t, s, "other", roleAdmin)
+	requireStatus(t, serve(s, "acme", admin, "PUT", "/api/content/pages/home", `{"title":"Home"}`), http.StatusOK, "PUT acme content")
+	requireStatus(t, serve(s, "other", other, "PUT", "/api/content/pages/home", `{"title":"Other"}`), http.StatusOK, "PUT other content")
+	gateway := s.S3Gateway()
+
+	get := func(path, authorization string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		gateway.ServeHTTP(w, r)
+		return w
+	}
+	sigV4 := func(key string) string {
+		return "AWS4-HMAC-SHA256 Credential=" + key + "/20261016/us-east-1/s3/aws4_request, SignedHeaders=host, Signature=0"
+	}
+
+	requireStatus(t, get("/acme/pages/home.json", ""), http.StatusForbidden, "GetObject without a key")
+	requireStatus(t, get("/acme/pages/home.json", sigV4("vk_invalid")), http.StatusForbidden, "GetObject with an unknown key")
+	requireStatus(t, get("/acme/pages/home.json", sigV4(other)), http.StatusForbidden, "GetObject with another tenant's key")
+	requireStatus(t, get("/acme?list-type=2", sigV4(other)), http.StatusForbidden, "ListObjectsV2 with another tenant's key")
+	requireStatus(t, get("/acme/pages/home.json", sigV4(viewer)), http.StatusOK, "GetObject with a viewer key")
+
+	// Presigned URLs carry the key in the query
+	requireStatus(t, get("/acme/pages/home.json?X-Amz-Credential="+viewer+"%2F20261016%2Fus-east-1%2Fs3%2Faws4_request", ""), http.StatusOK, "presigned GetObject")
+
+	requireStatus(t, get("/", ""), http.StatusForbidden, "ListBuckets without a key")
+	w := get("/", sigV4(viewer))
+	requireStatus(t, w, http.StatusOK, "ListBuckets")
+	if body := w.Body.String(); !strings.Contains(body, "<Name>acme</Name>") || strings.Contains(body, "<Name>other</Name>") {
+		t.Errorf("ListBuckets = %s, want only the key's bucket", body)
+	}
+}
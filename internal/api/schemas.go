@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"velocity/internal/models"
+	"velocity/internal/storage"
+)
+
+// maxSchemaDepth caps how deep extends and mixins may nest
+const maxSchemaDepth = 8
+
+// resolveSchema flattens a schema's extends and mixins into one document:
+// fields from the schema it extends, then from each mixin in order, then its
+// own, a later definition of a field replacing an earlier one. Other keys
+// (storage, settings, title, ...) are inherited from the extended schema
+// unless the schema sets them; mixins contribute fields only.
+//
+// A tenant schema's parents are looked up among the tenant's schemas, then
+// the global ones, so a tenant schema can extend a global schema of the same
+// name. A global schema's parents are global.
+func (s *Server) resolveSchema(ctx context.Context, tenant, name string, global bool, content []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if doc["extends"] == nil && doc["mixins"] == nil {
+		return content, nil
+	}
+
+	resolved, err := s.resolveSchemaDoc(ctx, tenant, name, global, doc, nil)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resolved)
+}
+
+// resolveSchemaDoc resolves a decoded schema; chain is the schemas being
+// resolved that led to it, to catch cycles
+func (s *Server) resolveSchemaDoc(ctx context.Context, tenant, name string, global bool, doc map[string]interface{}, chain []string) (map[string]interface{}, error) {
+	key := name
+	if global {
+		key = "global:" + name
+	}
+	for _, seen := range chain {
+		if seen == key {
+			return nil, fmt.Errorf("schema inheritance cycle: %s", strings.Join(append(chain, key), " -> "))
+		}
+	}
+	if len(chain) >= maxSchemaDepth {
+		return nil, fmt.Errorf("schema %s nests extends and mixins more than %d deep", name, maxSchemaDepth)
+	}
+	chain = append(chain, key)
+
+	var decl struct {
+		Extends string   `json:"extends"`
+		Mixins  []string `json:"mixins"`
+	}
+	data, _ := json.Marshal(doc)
+	if err := json.Unmarshal(data, &decl); err != nil {
+		return nil, fmt.Errorf("schema %s: extends must be a schema name and mixins a list of them", name)
+	}
+
+	resolved := make(map[string]interface{})
+	fields := make(map[string]interface{})
+
+	if decl.Extends != "" {
+		parent, err := s.loadParentSchema(ctx, tenant, name, global, decl.Extends, chain)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range parent {
+			resolved[k] = v
+		}
+		mergeSchemaFields(fields, parent)
+	}
+	for _, mixin := range decl.Mixins {
+		parent, err := s.loadParentSchema(ctx, tenant, name, global, mixin, chain)
+		if err != nil {
+			return nil, err
+		}
+		mergeSchemaFields(fields, parent)
+	}
+
+	for k, v := range doc {
+		resolved[k] = v
+	}
+	mergeSchemaFields(fields, doc)
+	delete(resolved, "extends")
+	delete(resolved, "mixins")
+	if len(fields) > 0 {
+		resolved["fields"] = fields
+	}
+	return resolved, nil
+}
+
+// loadParentSchema reads and resolves a schema that another extends or mixes in
+func (s *Server) loadParentSchema(ctx context.Context, tenant, child string, global bool, name string, chain []string) (map[string]interface{}, error) {
+	var stored *storage.Schema
+	var err error
+	if global || name == child {
+		stored, err = s.storage.GetGlobalSchema(ctx, name)
+	} else {
+		stored, err = s.storage.GetSchema(ctx, tenant, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: parent schema %s not found", child, name)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(stored.Content, &doc); err != nil {
+		return nil, fmt.Errorf("schema %s: parent schema %s is not valid JSON", child, name)
+	}
+	return s.resolveSchemaDoc(ctx, tenant, name, stored.IsGlobal, doc, chain)
+}
+
+// mergeSchemaFields copies a schema document's fields into fields, replacing
+// fields of the same name
+func mergeSchemaFields(fields map[string]interface{}, doc map[string]interface{}) {
+	if own, ok := doc["fields"].(map[string]interface{}); ok {
+		for k, v := range own {
+			fields[k] = v
+		}
+	}
+}
+
+// checkSchema parses a schema being saved and checks its extends and mixins
+// resolve, and that its encrypted fields can be, writing a 400 if not. Returns false if the schema was rejected.
+func (s *Server) checkSchema(w http.ResponseWriter, r *http.Request, tenant, name string, global bool, body []byte) bool {
+	var schema models.Schema
+	if err := json.Unmarshal(body, &schema); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON schema")
+		return false
+	}
+	if _, err := s.resolveSchema(r.Context(), tenant, name, global, body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_schema", err.Error())
+		return false
+	}
+	if hasEncryptedFields(schema.Fields) && s.config.FieldKeyring == nil {
+		writeError(w, http.StatusBadRequest, "encryption_unavailable", errNoFieldKeyring.Error())
+		return false
+	}
+	return true
+}
+
+// writeSchema writes a stored schema, flattened when ?resolve=true
+func (s *Server) writeSchema(w http.ResponseWriter, r *http.Request, tenant string, schema *storage.Schema) {
+	content := schema.Content
+	if r.URL.Query().Get("resolve") == "true" {
+		resolved, err := s.resolveSchema(r.Context(), tenant, schema.Name, schema.IsGlobal, content)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "invalid_schema", err.Error())
+			return
+		}
+		content = resolved
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
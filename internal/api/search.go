@@ -0,0 +1,522 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"velocity/internal/log"
+	"velocity/internal/media"
+	"velocity/internal/storage"
+)
+
+const (
+	searchCollection = "search"
+
+	// pageCountKey is the metadata key holding a PDF's page count
+	pageCountKey = "page_count"
+
+	maxSearchText      = 256 << 10 // text kept per item
+	maxSearchSource    = 64 << 20  // largest PDF read for its text
+	searchIndexTimeout = 2 * time.Minute
+	searchWorkers      = 8
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	searchSnippetWidth = 160
+
+	// adminSearchTenants bounds how many tenants an operator search reads at once
+	adminSearchTenants = 4
+)
+
+// searchRecord is the text of a content item in one state, as searched
+type searchRecord struct {
+	Type        string    `json:"type"`
+	ID          string    `json:"id"`
+	State       string    `json:"state"`
+	ContentType string    `json:"content_type"`
+	Text        string    `json:"text"`
+	Pages       int       `json:"pages,omitempty"`
+	IndexedAt   time.Time `json:"indexed_at"`
+}
+
+// searchResult is one match of a search
+type searchResult struct {
+	Type        string  `json:"type"`
+	ID          string  `json:"id"`
+	State       string  `json:"state"`
+	ContentType string  `json:"content_type"`
+	Pages       int     `json:"pages,omitempty"`
+	Score       float64 `json:"score"`
+	Snippet     string  `json:"snippet"`
+	Hash        string  `json:"hash,omitempty"` // fingerprint that matched, for hash searches
+}
+
+// searchDocID returns the document ID of an item's search record
+func searchDocID(contentType, id string, state storage.State) string {
+	return string(state) + "/" + contentType + "/" + id
+}
+
+// parseSearchDocID splits a search record's document ID
+func parseSearchDocID(docID string) (contentType, id string, state storage.State, ok bool) {
+	parts := strings.SplitN(docID, "/", 3)
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+	return parts[1], parts[2], storage.State(parts[0]), true
+}
+
+// isPDFContent reports whether a MIME type is a PDF
+func isPDFContent(mimeType string) bool {
+	return strings.HasPrefix(strings.ToLower(mimeType), "application/pdf")
+}
+
+// indexContent records the text of written content for search, in the
+// background. PDF text is extracted server-side, and the PDF's page count is
+// stored in its page_count metadata.
+func (s *Server) indexContent(ctx context.Context, tenant, contentType, id, ext string, state storage.State, mimeType string) {
+	if !isPDFContent(mimeType) && !isJSONContent(mimeType) && !isTextContent(mimeType) {
+		return
+	}
+	// A search record would store encrypted content's text in plaintext
+	if s.config.Encryption != nil && s.config.Encryption.Encrypts(contentType) {
+		return
+	}
+
+	background := detachedContext(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(background, searchIndexTimeout)
+		defer cancel()
+		if err := s.indexItem(ctx, tenant, contentType, id, ext, state); err != nil {
+			log.Error("Failed to index %s/%s for search: %v", contentType, id, err)
+		}
+	}()
+}
+
+// indexItem reads an item and stores its search record
+func (s *Server) indexItem(ctx context.Context, tenant, contentType, id, ext string, state storage.State) error {
+	stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, ext, state)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(stream.Body, maxSearchSource+1))
+	stream.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read content: %w", err)
+	}
+
+	record := &searchRecord{Type: contentType, ID: id, State: string(state), ContentType: stream.ContentType, IndexedAt: time.Now().UTC()}
+	if isPDFContent(stream.ContentType) {
+		if len(data) > maxSearchSource {
+			return fmt.Errorf("PDF is larger than %d bytes", maxSearchSource)
+		}
+		pdf, err := media.ExtractPDF(data)
+		if err != nil {
+			return fmt.Errorf("failed to read PDF: %w", err)
+		}
+		record.Text, record.Pages = pdf.Text, pdf.Pages
+
+		// Transitions carry the metadata along, so only changed counts are written
+		pages := strconv.Itoa(pdf.Pages)
+		if pdf.Pages > 0 && stream.Metadata[pageCountKey] != pages {
+			ext := strings.TrimPrefix(filepath.Ext(stream.Key), ".")
+			if err := s.storage.UpdateMetadata(ctx, tenant, contentType, id, ext, state, map[string]string{pageCountKey: pages}); err != nil {
+				return fmt.Errorf("failed to store page count: %w", err)
+			}
+		}
+		log.Debug("Extracted %d characters from %d pages of %s/%s", len(record.Text), pdf.Pages, contentType, id)
+	} else {
+		if len(data) > maxSearchSource {
+			data = data[:maxSearchSource]
+		}
+		record.Text = contentText(data, stream.ContentType)
+	}
+	if len(record.Text) > maxSearchText {
+		record.Text = strings.ToValidUTF8(record.Text[:maxSearchText], "")
+	}
+
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.storage.PutDocument(ctx, tenant, searchCollection, searchDocID(contentType, id, state), encoded)
+}
+
+// deleteSearchRecord removes an item's search record in a state
+func (s *Server) deleteSearchRecord(ctx context.Context, tenant, contentType, id string, state storage.State) {
+	s.storage.DeleteDocument(ctx, tenant, searchCollection, searchDocID(contentType, id, state))
+}
+
+// searchTerms splits a query into lowercase words
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// matchSearch scores a record against the terms: every term must appear in
+// its text or ID. Returns false when one doesn't.
+func matchSearch(record *searchRecord, terms []string) (*searchResult, bool) {
+	text := strings.ToLower(record.Text)
+	id := strings.ToLower(record.ID)
+
+	score := 0.0
+	first := -1
+	for _, term := range terms {
+		count := strings.Count(text, term)
+		if strings.Contains(id, term) {
+			count += 10 // matches in the ID weigh most
+		}
+		if count == 0 {
+			return nil, false
+		}
+		score += float64(count)
+		if i := strings.Index(text, term); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+
+	return &searchResult{
+		Type:        record.Type,
+		ID:          record.ID,
+		State:       record.State,
+		ContentType: record.ContentType,
+		Pages:       record.Pages,
+		Score:       score,
+		Snippet:     searchSnippet(record.Text, first),
+	}, true
+}
+
+// searchSnippet returns the text around a match, on whole words
+func searchSnippet(text string, at int) string {
+	if at < 0 {
+		at = 0
+	}
+	start := at - searchSnippetWidth/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + searchSnippetWidth
+	if end > len(text) {
+		end = len(text)
+	}
+
+	// Widen to word boundaries (text without spaces is cut as is)
+	for i := 0; i < 20 && start > 0 && !unicode.IsSpace(rune(text[start-1])); i++ {
+		start--
+	}
+	for i := 0; i < 20 && end < len(text) && !unicode.IsSpace(rune(text[end])); i++ {
+		end++
+	}
+
+	snippet := strings.Join(strings.Fields(strings.ToValidUTF8(text[start:end], "")), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// searchTenant returns the tenant's items in a state matching the terms, best first
+func (s *Server) searchTenant(ctx context.Context, tenant, contentType string, state storage.State, terms []string) ([]*searchResult, error) {
+	docIDs, err := s.storage.ListDocuments(ctx, tenant, searchCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []string
+	for _, docID := range docIDs {
+		recordType, _, recordState, ok := parseSearchDocID(docID)
+		if ok && recordState == state && (contentType == "" || recordType == contentType) {
+			candidates = append(candidates, docID)
+		}
+	}
+
+	matches := make([]*searchResult, len(candidates))
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < searchWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				data, err := s.storage.GetDocument(ctx, tenant, searchCollection, candidates[i])
+				if err != nil {
+					continue
+				}
+				var record searchRecord
+				if json.Unmarshal(data, &record) != nil {
+					continue
+				}
+				if result, ok := matchSearch(&record, terms); ok {
+					matches[i] = result
+				}
+			}
+		}()
+	}
+	for i := range candidates {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	results := []*searchResult{}
+	for _, result := range matches {
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Type != results[j].Type {
+			return results[i].Type < results[j].Type
+		}
+		return results[i].ID < results[j].ID
+	})
+	return results, nil
+}
+
+// normalizeHash returns a fingerprint or ETag as lowercase hex, without the
+// quotes and algorithm prefix it may be given with
+func normalizeHash(hash string) string {
+	hash = strings.ToLower(strings.Trim(strings.TrimSpace(hash), `"`))
+	return strings.TrimPrefix(hash, "sha256:")
+}
+
+// findByHash returns the tenant's items whose fingerprint or ETag is hash, in
+// any state unless one is given
+func (s *Server) findByHash(ctx context.Context, tenant, contentType string, state storage.State, hash string) ([]*searchResult, error) {
+	docIDs, err := s.storage.ListDocuments(ctx, tenant, fingerprintsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []*searchResult{}
+	for _, docID := range docIDs {
+		data, err := s.storage.GetDocument(ctx, tenant, fingerprintsCollection, docID)
+		if err != nil {
+			continue
+		}
+		var record fingerprintRecord
+		if json.Unmarshal(data, &record) != nil {
+			continue
+		}
+		if (contentType != "" && record.Type != contentType) || (state != "" && storage.State(record.State) != state) {
+			continue
+		}
+		if record.Value != hash && normalizeHash(record.ETag) != hash {
+			continue
+		}
+		results = append(results, &searchResult{
+			Type:  record.Type,
+			ID:    record.ID,
+			State: record.State,
+			Score: 1,
+			Hash:  record.Value,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Type != results[j].Type {
+			return results[i].Type < results[j].Type
+		}
+		if results[i].ID != results[j].ID {
+			return results[i].ID < results[j].ID
+		}
+		return results[i].State < results[j].State
+	})
+	return results, nil
+}
+
+// tenantSearchResults are the matches of an operator search in one tenant
+type tenantSearchResults struct {
+	Tenant  string          `json:"tenant"`
+	Results []*searchResult `json:"results"`
+	Count   int             `json:"count"`
+	Total   int             `json:"total"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// =============================================================================
+// Search Handlers
+// =============================================================================
+
+// searchHandler handles GET /api/search?q=...
+// Searches the text of the tenant's content in a state (live by default);
+// ?type= limits the search to one content type, ?limit= caps the results.
+func (s *Server) searchHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	query := r.URL.Query()
+
+	terms := searchTerms(query.Get("q"))
+	if len(terms) == 0 {
+		writeError(w, http.StatusBadRequest, "missing_query", "Query parameter 'q' is required")
+		return
+	}
+
+	state := storage.StateLive
+	if v := query.Get("state"); v != "" {
+		if !storage.ValidState(v) {
+			writeError(w, http.StatusBadRequest, "invalid_state", fmt.Sprintf("Invalid state: %s", v))
+			return
+		}
+		state = storage.State(v)
+	}
+
+	limit := defaultSearchLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			writeError(w, http.StatusBadRequest, "invalid_limit", fmt.Sprintf("Limit must be between 1 and %d", maxSearchLimit))
+			return
+		}
+		limit = n
+	}
+
+	results, err := s.searchTenant(r.Context(), tenant, query.Get("type"), state, terms)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	total := len(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"query":   query.Get("q"),
+		"state":   string(state),
+		"results": results,
+		"count":   len(results),
+		"total":   total,
+	})
+}
+
+// adminSearchHandler handles GET /api/admin/search?q=... or ?hash=...
+// Searches every tenant (or ?tenant=, comma-separated) for operators: ?q=
+// matches text as /api/search does, ?hash= finds items by content
+// fingerprint or ETag. Results are grouped by tenant; ?limit= caps each
+// tenant's results. Requires an operator session.
+func (s *Server) adminSearchHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	terms := searchTerms(query.Get("q"))
+	hash := normalizeHash(query.Get("hash"))
+	if len(terms) == 0 && hash == "" {
+		writeError(w, http.StatusBadRequest, "missing_query", "Query parameter 'q' or 'hash' is required")
+		return
+	}
+
+	// Text searches default to live content; hash searches cover every state
+	var state storage.State
+	if v := query.Get("state"); v != "" {
+		if !storage.ValidState(v) {
+			writeError(w, http.StatusBadRequest, "invalid_state", fmt.Sprintf("Invalid state: %s", v))
+			return
+		}
+		state = storage.State(v)
+	} else if hash == "" {
+		state = storage.StateLive
+	}
+
+	limit := defaultSearchLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			writeError(w, http.StatusBadRequest, "invalid_limit", fmt.Sprintf("Limit must be between 1 and %d", maxSearchLimit))
+			return
+		}
+		limit = n
+	}
+
+	tenants := splitList(query.Get("tenant"))
+	for _, tenant := range tenants {
+		if !storage.ValidTenant(tenant) {
+			writeError(w, http.StatusBadRequest, "invalid_tenant", fmt.Sprintf("Invalid tenant: %q", tenant))
+			return
+		}
+	}
+	if len(tenants) == 0 {
+		var err error
+		if tenants, err = s.storage.ListTenants(ctx); err != nil {
+			writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+			return
+		}
+	}
+	sort.Strings(tenants)
+	contentType := query.Get("type")
+
+	log.Info("Operator search across %d tenants (q=%q, hash=%q)", len(tenants), query.Get("q"), hash)
+
+	groups := make([]*tenantSearchResults, len(tenants))
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < adminSearchTenants; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				group := &tenantSearchResults{Tenant: tenants[i]}
+				var results []*searchResult
+				var err error
+				if hash != "" {
+					results, err = s.findByHash(ctx, tenants[i], contentType, state, hash)
+				} else {
+					results, err = s.searchTenant(ctx, tenants[i], contentType, state, terms)
+				}
+				if err != nil {
+					group.Error = err.Error()
+					results = []*searchResult{}
+				}
+				group.Total = len(results)
+				if len(results) > limit {
+					results = results[:limit]
+				}
+				group.Results, group.Count = results, len(results)
+				groups[i] = group
+			}
+		}()
+	}
+	for i := range tenants {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	// Only tenants with matches (or errors) are listed
+	matched := []*tenantSearchResults{}
+	total := 0
+	for _, group := range groups {
+		if group.Total > 0 || group.Error != "" {
+			matched = append(matched, group)
+			total += group.Total
+		}
+	}
+
+	response := map[string]interface{}{
+		"tenants":  matched,
+		"searched": len(tenants),
+		"total":    total,
+	}
+	if hash != "" {
+		response["hash"] = hash
+	} else {
+		response["query"] = query.Get("q")
+	}
+	if state != "" {
+		response["state"] = string(state)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,338 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/models"
+)
+
+// Recommended lengths for search result snippets
+const (
+	seoTitleMin       = 30
+	seoTitleMax       = 60
+	seoDescriptionMin = 70
+	seoDescriptionMax = 160
+)
+
+// SEO check statuses
+const (
+	seoPass = "pass"
+	seoWarn = "warn"
+	seoFail = "fail"
+)
+
+var (
+	htmlTitlePattern     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlHeadingPattern   = regexp.MustCompile(`(?i)<h([1-6])[\s>]`)
+	htmlImagePattern     = regexp.MustCompile(`(?is)<img\b[^>]*>`)
+	htmlMetaPattern      = regexp.MustCompile(`(?is)<meta\b[^>]*>`)
+	htmlLinkPattern      = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	htmlAttributePattern = regexp.MustCompile(`(?is)([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// seoCheck is one item of an SEO checklist
+type seoCheck struct {
+	ID      string `json:"id"`
+	Label   string `json:"label"`
+	Status  string `json:"status"` // pass, warn, fail
+	Message string `json:"message"`
+	Weight  int    `json:"weight"`
+}
+
+// seoDocument is the SEO-relevant view of a content item
+type seoDocument struct {
+	seo  models.SEO
+	body string // HTML/text used for heading and image checks
+}
+
+// htmlAttributes parses the attributes of a single tag
+func htmlAttributes(tag string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range htmlAttributePattern.FindAllStringSubmatch(tag, -1) {
+		value := m[2]
+		if value == "" {
+			value = m[3]
+		}
+		attrs[strings.ToLower(m[1])] = html.UnescapeString(value)
+	}
+	return attrs
+}
+
+// seoFromHTML reads SEO fields from an HTML document's head
+func seoFromHTML(doc string) models.SEO {
+	var seo models.SEO
+	if m := htmlTitlePattern.FindStringSubmatch(doc); m != nil {
+		seo.Title = strings.TrimSpace(html.UnescapeString(m[1]))
+	}
+	for _, tag := range htmlMetaPattern.FindAllString(doc, -1) {
+		attrs := htmlAttributes(tag)
+		switch strings.ToLower(attrs["name"]) {
+		case "description":
+			seo.Description = attrs["content"]
+		case "keywords":
+			seo.Keywords = attrs["content"]
+		case "robots":
+			robots := strings.ToLower(attrs["content"])
+			seo.NoIndex = strings.Contains(robots, "noindex")
+			seo.NoFollow = strings.Contains(robots, "nofollow")
+		}
+		if strings.ToLower(attrs["property"]) == "og:image" {
+			seo.OGImage = attrs["content"]
+		}
+	}
+	for _, tag := range htmlLinkPattern.FindAllString(doc, -1) {
+		if attrs := htmlAttributes(tag); strings.ToLower(attrs["rel"]) == "canonical" {
+			seo.Canonical = attrs["href"]
+		}
+	}
+	return seo
+}
+
+// seoFromJSON reads the "seo" object of a JSON document (falling back to its
+// top-level title) and gathers its string fields as the body to analyze
+func seoFromJSON(data []byte) (*seoDocument, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	result := &seoDocument{}
+	if raw, ok := doc["seo"]; ok {
+		encoded, _ := json.Marshal(raw)
+		json.Unmarshal(encoded, &result.seo)
+	}
+	if result.seo.Title == "" {
+		result.seo.Title, _ = doc["title"].(string)
+	}
+
+	var parts []string
+	var collect func(v interface{})
+	collect = func(v interface{}) {
+		switch value := v.(type) {
+		case string:
+			parts = append(parts, value)
+		case []interface{}:
+			for _, item := range value {
+				collect(item)
+			}
+		case map[string]interface{}:
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				collect(value[key])
+			}
+		}
+	}
+	delete(doc, "seo")
+	collect(doc)
+	result.body = strings.Join(parts, "\n")
+	return result, nil
+}
+
+// lengthCheck grades a text field against a recommended length range
+func lengthCheck(id, label, value string, min, max, weight int) seoCheck {
+	check := seoCheck{ID: id, Label: label, Weight: weight}
+	length := len([]rune(strings.TrimSpace(value)))
+	switch {
+	case length == 0:
+		check.Status = seoFail
+		check.Message = fmt.Sprintf("%s is missing", label)
+	case length < min:
+		check.Status = seoWarn
+		check.Message = fmt.Sprintf("%s is %d characters; aim for %d-%d", label, length, min, max)
+	case length > max:
+		check.Status = seoWarn
+		check.Message = fmt.Sprintf("%s is %d characters and may be truncated; aim for %d-%d", label, length, min, max)
+	default:
+		check.Status = seoPass
+		check.Message = fmt.Sprintf("%s is %d characters", label, length)
+	}
+	return check
+}
+
+// headingCheck requires a single h1 and no skipped heading levels
+func headingCheck(body string) seoCheck {
+	check := seoCheck{ID: "headings", Label: "Heading structure", Weight: 20}
+
+	matches := htmlHeadingPattern.FindAllStringSubmatch(body, -1)
+	if len(matches) == 0 {
+		check.Status = seoWarn
+		check.Message = "No headings found"
+		return check
+	}
+
+	h1s, previous := 0, 0
+	var skipped []string
+	for _, m := range matches {
+		level := int(m[1][0] - '0')
+		if level == 1 {
+			h1s++
+		}
+		if previous > 0 && level > previous+1 {
+			skipped = append(skipped, fmt.Sprintf("h%d follows h%d", level, previous))
+		}
+		previous = level
+	}
+
+	switch {
+	case h1s == 0:
+		check.Status = seoFail
+		check.Message = "No h1 heading"
+	case h1s > 1:
+		check.Status = seoWarn
+		check.Message = fmt.Sprintf("%d h1 headings; use exactly one", h1s)
+	case len(skipped) > 0:
+		check.Status = seoWarn
+		check.Message = "Skipped heading levels: " + strings.Join(skipped, ", ")
+	default:
+		check.Status = seoPass
+		check.Message = fmt.Sprintf("One h1 and %d headings in order", len(matches))
+	}
+	return check
+}
+
+// imageAltCheck measures how many images carry alt text
+func imageAltCheck(body string) seoCheck {
+	check := seoCheck{ID: "image_alt", Label: "Image alt text", Weight: 20}
+
+	images := htmlImagePattern.FindAllString(body, -1)
+	if len(images) == 0 {
+		check.Status = seoPass
+		check.Message = "No images"
+		return check
+	}
+
+	withAlt := 0
+	for _, tag := range images {
+		if strings.TrimSpace(htmlAttributes(tag)["alt"]) != "" {
+			withAlt++
+		}
+	}
+
+	check.Message = fmt.Sprintf("%d of %d images have alt text", withAlt, len(images))
+	switch {
+	case withAlt == len(images):
+		check.Status = seoPass
+	case withAlt*2 >= len(images):
+		check.Status = seoWarn
+	default:
+		check.Status = seoFail
+	}
+	return check
+}
+
+// canonicalCheck verifies the canonical URL and indexing settings
+func canonicalCheck(seo models.SEO) seoCheck {
+	check := seoCheck{ID: "canonical", Label: "Canonical & indexing", Weight: 15}
+
+	if seo.NoIndex {
+		check.Status = seoWarn
+		check.Message = "Page is excluded from search engines (no_index)"
+		return check
+	}
+	if seo.Canonical == "" {
+		check.Status = seoWarn
+		check.Message = "No canonical URL set"
+		return check
+	}
+	if u, err := url.Parse(seo.Canonical); err != nil || !u.IsAbs() {
+		check.Status = seoFail
+		check.Message = "Canonical URL must be absolute"
+		return check
+	}
+
+	check.Status = seoPass
+	check.Message = "Canonical URL set"
+	return check
+}
+
+// seoReport evaluates a document and returns its checklist and score (0-100)
+func seoReport(doc *seoDocument) ([]seoCheck, int) {
+	checks := []seoCheck{
+		lengthCheck("title", "Title", doc.seo.Title, seoTitleMin, seoTitleMax, 25),
+		lengthCheck("description", "Meta description", doc.seo.Description, seoDescriptionMin, seoDescriptionMax, 20),
+		headingCheck(doc.body),
+		imageAltCheck(doc.body),
+		canonicalCheck(doc.seo),
+	}
+
+	earned, total := 0, 0
+	for _, check := range checks {
+		total += check.Weight
+		switch check.Status {
+		case seoPass:
+			earned += check.Weight * 2
+		case seoWarn:
+			earned += check.Weight
+		}
+	}
+	return checks, earned * 100 / (total * 2)
+}
+
+// =============================================================================
+// SEO Handlers
+// =============================================================================
+
+// seoReportHandler handles GET /api/content/{type}/{id}/seo-report
+// Scores title, description, headings, image alt text, and canonical settings.
+func (s *Server) seoReportHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType := vars["type"]
+	id := vars["id"]
+
+	tenant := s.getTenant(r)
+	state := getState(r)
+
+	item, err := s.storage.FindContentStream(r.Context(), tenant, contentType, id, "", state)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Content '%s' not found", id))
+		return
+	}
+	defer item.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(item.Body, maxValidatedSize+1))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	if len(data) > maxValidatedSize {
+		writeError(w, http.StatusRequestEntityTooLarge, "too_large", "Content is too large to analyze")
+		return
+	}
+
+	var doc *seoDocument
+	switch {
+	case isJSONContent(item.ContentType):
+		if doc, err = seoFromJSON(data); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "invalid_json", "Content is not valid JSON")
+			return
+		}
+	case strings.Contains(item.ContentType, "html"):
+		doc = &seoDocument{seo: seoFromHTML(string(data)), body: string(data)}
+	default:
+		writeError(w, http.StatusUnprocessableEntity, "unsupported_content", "SEO reports are available for JSON and HTML content")
+		return
+	}
+
+	checks, score := seoReport(doc)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":     id,
+		"state":  state,
+		"score":  score,
+		"checks": checks,
+		"seo":    doc.seo,
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"embed"
 	"io/fs"
 	"net/http"
+	"net/http/pprof"
+	"net/netip"
 	"strconv"
 	"strings"
 	"time"
@@ -11,55 +13,159 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 
+	"velocity/internal/crypto"
+	"velocity/internal/index"
 	"velocity/internal/log"
+	"velocity/internal/media"
+	"velocity/internal/plugin"
 	"velocity/internal/storage"
 	"velocity/internal/version"
 )
 
 // Server represents the API server
 type Server struct {
-	router   *mux.Router
-	storage  storage.Storage
-	sessions *sessionStore
-	config   *ServerConfig
-	wwwFS    embed.FS
+	router       *mux.Router
+	storage      storage.Storage
+	roots        *storage.RootedStorage
+	leader       *leaderElector
+	sessions     *sessionStore
+	config       *ServerConfig
+	wwwFS        embed.FS
+	recentWrites *recentWrites
+	webhookQueue *webhookQueue
+	idempotency  *idempotencyStore
+	settings     *settingsStore
+	wasm         *wasmPlugins
+	jobs         *jobManager
+	navigation   *navigationCache
+	redirects    *redirectStore
+	variants     *variantStore
+	apiKeys      *apiKeyStore
+	forms        *formLimiter
+	oidc         *oidcClient
+	readiness    readiness
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	Port string
+	Port          string
+	Mode          string                    // ModeFull (default) or ModeDelivery
+	S3EventsToken string                    // Shared token required by the bucket event endpoint (ingestion is off without it)
+	WASM          plugin.WASMConfig         // Sandbox limits for tenant WASM plugins
+	GCInterval    time.Duration             // How often garbage collection runs for every tenant (0 disables)
+	GCRetention   time.Duration             // How long derived data of deleted content is kept
+	StatsInterval time.Duration             // How often storage usage is scanned for every tenant (0 disables)
+	Keyring       *crypto.Keyring           // Encrypts tenant settings at rest (optional)
+	FieldKeyring  *crypto.Keyring           // Encrypts schema fields marked encrypted (optional)
+	Video         media.VideoProcessor      // Reads video details and generates posters (optional)
+	Transcoder    media.Transcoder          // Transcodes uploaded videos in background jobs (optional)
+	TenantRouting string                    // TenantRoutingBoth (default), TenantRoutingHeader, or TenantRoutingPath
+	Encryption    *storage.EncryptedStorage // Encrypts content at rest; its types aren't indexed for search (optional)
+	Index         *index.IndexedStorage     // Records content in a database that lists read from (optional)
+	OIDC          *OIDCConfig               // Single sign-on for the web UI (optional)
+
+	// TrustedProxies are the load balancers and proxies whose X-Forwarded-For
+	// is believed for client addresses (see clientIP); without them the
+	// connection's address is used
+	TrustedProxies []netip.Prefix
 }
 
 // NewServer creates a new API server
 func NewServer(storageClient storage.Storage, config *ServerConfig, wwwFS embed.FS) *Server {
+	roots := storage.NewRootedStorage(storageClient)
+	leader := newLeaderElector(storageClient, config.Mode == ModeDelivery)
 	s := &Server{
-		router:   mux.NewRouter(),
-		storage:  storageClient,
-		sessions: newSessionStore(storageClient),
-		config:   config,
-		wwwFS:    wwwFS,
+		router:       mux.NewRouter(),
+		storage:      roots,
+		roots:        roots,
+		leader:       leader,
+		sessions:     newSessionStore(storageClient, leader),
+		config:       config,
+		wwwFS:        wwwFS,
+		recentWrites: newRecentWrites(),
+		webhookQueue: newWebhookQueue(roots),
+		idempotency:  newIdempotencyStore(storageClient, leader),
+		settings:     newSettingsStore(storageClient, config.Keyring),
+		wasm:         newWASMPlugins(storageClient, config.WASM),
+		jobs:         newJobManager(),
+		navigation:   newNavigationCache(),
+		redirects:    newRedirectStore(storageClient),
+		variants:     newVariantStore(storageClient),
+		apiKeys:      newAPIKeyStore(storageClient),
+		forms:        newFormLimiter(),
+		oidc:         newOIDCClient(config.OIDC),
 	}
 
 	s.setupRoutes()
+
+	// Delivery nodes serve published content only; background work stays
+	// with the editing plane
+	if s.isDelivery() {
+		s.router.Use(s.deliveryHandler)
+		return s
+	}
+
+	if config.GCInterval > 0 {
+		go s.gcLoop(config.GCInterval)
+	}
+	if config.StatsInterval > 0 {
+		go s.storageStatsLoop(config.StatsInterval)
+	}
 	return s
 }
 
 // setupRoutes configures all API routes
 func (s *Server) setupRoutes() {
+	// Reject invalid tenants and path traversal in route variables
+	s.router.Use(s.tenantHandler)
+
+	// Resolve type aliases and apply type deprecations (tenant settings)
+	s.router.Use(s.typeAliasHandler)
+	s.router.Use(s.versionRetentionHandler)
+
+	// Per-tenant well-known files (from tenant settings; registered before direct content)
+	// GET /content/{tenant}/robots.txt               - robots_txt setting
+	// GET /content/{tenant}/.well-known/security.txt - security_txt setting
+	s.router.HandleFunc("/content/{tenant}/robots.txt", s.robotsHandler).Methods("GET")
+	s.router.HandleFunc("/content/{tenant}/.well-known/security.txt", s.securityTxtHandler).Methods("GET")
+
 	// Direct content URL (outside /api, no tenant header needed)
 	// GET /content/{tenant}/{type}/{id} - Direct content access with correct mime type
 	// NOTE: This route is intentionally outside /api and should remain:
 	//   - Read-only (GET only)
-	//   - Public (no authentication required)
+	//   - Public (no authentication required); non-live states need the tenant's preview token
 	//   - Used for embeddable URLs (images, CSS, etc.)
+	//   - Answers with the tenant's redirect when one is set for the path
+	//   - Picks among live A/B variants by targeting rules, then by weight, sticky per visitor (?variant= to choose)
 	// {id:.+} allows nested IDs with slashes (e.g., /content/demo/images/hero/banner)
 	s.router.HandleFunc("/content/{tenant}/{type}/{id:.+}", s.directContentHandler).Methods("GET")
 
+	// Form submissions (outside /api, no tenant header needed)
+	// POST /submit/{tenant}/{form} - Submit a form defined in the tenant's settings
+	// NOTE: Public (no authentication or API key) and rate limited per client; only
+	// forms in the tenant's settings accept submissions, stored as pending submissions
+	s.router.HandleFunc("/submit/{tenant}/{form}", s.submitFormHandler).Methods("POST", "OPTIONS")
+
 	api := s.router.PathPrefix("/api").Subrouter()
 
 	// Add request logging
 	api.Use(s.loggingHandler)
 
+	// Advertise supported client versions and mark deprecated routes
+	api.Use(s.compatibilityHandler)
+
+	// Replay responses for retried writes carrying an Idempotency-Key
+	api.Use(s.idempotencyHandler)
+
+	// Address an explicit content root (X-Content-Root: blue|green)
+	api.Use(s.contentRootHandler)
+
+	// Authenticate by the tenant's API keys (X-API-Key or a vk_ bearer token)
+	api.Use(s.apiKeyHandler)
+
+	// Enforce the permissions of the key's role on each route (see permissions.go)
+	api.Use(s.permissionHandler)
+
 	// Auth endpoints (public)
 	// POST   /api/login             - Login and get session token
 	// POST   /api/logout            - Logout and clear session
@@ -71,17 +177,117 @@ func (s *Server) setupRoutes() {
 	// Utility endpoints
 	// GET    /api                   - API info (name, version)
 	// GET    /api/health            - Health check
-	// GET    /api/version           - Server version
+	// GET    /api/health/live       - Liveness probe (process is serving)
+	// GET    /api/health/ready      - Readiness probe (started, not draining, storage reachable)
+	// GET    /api/version           - Server version and supported client versions
+	// GET    /api/openapi.json      - OpenAPI description of the API (browse it at /docs)
 	// GET    /api/types             - List available content types
+	// POST   /api/types             - Create a content type
 	// GET    /api/tenants           - List all tenants
+	// POST   /api/tenants           - Create a tenant
 	api.HandleFunc("", s.infoHandler).Methods("GET")
 	api.HandleFunc("/health", s.healthHandler).Methods("GET")
+	api.HandleFunc("/health/live", s.livenessHandler).Methods("GET")
+	api.HandleFunc("/health/ready", s.readinessHandler).Methods("GET")
 	api.HandleFunc("/version", s.versionHandler).Methods("GET")
+	api.HandleFunc("/openapi.json", s.openAPIHandler).Methods("GET")
 	api.HandleFunc("/types", s.listTypesHandler).Methods("GET")
 	api.HandleFunc("/types", s.createContentTypeHandler).Methods("POST")
 	api.HandleFunc("/tenants", s.listTenantsHandler).Methods("GET")
 	api.HandleFunc("/tenants", s.createTenantHandler).Methods("POST")
 
+	// Bucket event ingestion
+	// POST   /api/events/s3         - S3 event notifications for out-of-band writes (raw, SNS, or SQS)
+	api.HandleFunc("/events/s3", s.s3EventsHandler).Methods("POST")
+
+	// Report routes
+	// GET    /api/reports/alt-text   - Images missing alt_text/caption metadata (?type=, ?state=)
+	// GET    /api/reports/broken-links - Latest broken link report
+	// POST   /api/reports/broken-links - Start a broken link scan (?external=true)
+	// GET    /api/reports/duplicates - Duplicate and near-duplicate content (?type=, ?state=, ?threshold=)
+	api.HandleFunc("/reports/alt-text", s.altTextReportHandler).Methods("GET")
+	api.HandleFunc("/reports/broken-links", s.brokenLinksReportHandler).Methods("GET")
+	api.HandleFunc("/reports/broken-links", s.startBrokenLinksHandler).Methods("POST")
+	api.HandleFunc("/reports/duplicates", s.duplicatesReportHandler).Methods("GET")
+
+	// Storage usage (scanned by a scheduled job; the latest scan is served)
+	// GET    /api/stats/storage      - Bytes and objects by type, state, versions, and derived assets
+	// POST   /api/stats/storage      - Start a storage scan
+	api.HandleFunc("/stats/storage", s.storageStatsHandler).Methods("GET")
+	api.HandleFunc("/stats/storage", s.startStorageStatsHandler).Methods("POST")
+
+	// Search
+	// GET    /api/search            - Search the text of content, PDFs included (?q=, ?type=, ?state=, ?limit=)
+	// GET    /api/admin/search      - Search every tenant, grouped by tenant (operator session; ?q= or ?hash=)
+	api.HandleFunc("/search", s.searchHandler).Methods("GET")
+	api.HandleFunc("/admin/search", s.requireSession(s.adminSearchHandler)).Methods("GET")
+
+	// Validation jobs
+	// POST   /api/validate                 - Re-validate all content of a type against its schema
+	// GET    /api/validate/{type}/report   - Latest validation report (?state=, ?format=csv)
+	api.HandleFunc("/validate", s.startValidationHandler).Methods("POST")
+	api.HandleFunc("/validate/{type}/report", s.validationReportHandler).Methods("GET")
+
+	// Admin debug routes (require a session)
+	// GET    /api/admin/log-level             - Current log level
+	// PUT    /api/admin/log-level             - Change the log level at runtime
+	// GET    /api/admin/pprof/                - Profile index
+	// GET    /api/admin/pprof/cmdline         - Command line of the running server
+	// GET    /api/admin/pprof/profile         - CPU profile (?seconds=)
+	// GET    /api/admin/pprof/symbol          - Look up program counters
+	// POST   /api/admin/pprof/symbol          - Look up program counters
+	// GET    /api/admin/pprof/trace           - Execution trace (?seconds=)
+	// GET    /api/admin/pprof/{profile}       - Named profile (heap, goroutine, allocs, ...)
+	api.HandleFunc("/admin/log-level", s.requireSession(s.getLogLevelHandler)).Methods("GET")
+	api.HandleFunc("/admin/log-level", s.requireSession(s.setLogLevelHandler)).Methods("PUT")
+	api.HandleFunc("/admin/pprof/", s.requireSession(pprof.Index)).Methods("GET")
+	api.HandleFunc("/admin/pprof/cmdline", s.requireSession(pprof.Cmdline)).Methods("GET")
+	api.HandleFunc("/admin/pprof/profile", s.requireSession(pprof.Profile)).Methods("GET")
+	api.HandleFunc("/admin/pprof/symbol", s.requireSession(pprof.Symbol)).Methods("GET", "POST")
+	api.HandleFunc("/admin/pprof/trace", s.requireSession(pprof.Trace)).Methods("GET")
+	api.HandleFunc("/admin/pprof/{profile}", s.requireSession(s.pprofProfileHandler)).Methods("GET")
+
+	// Admin routes below act on every tenant, so they require an operator
+	// session (POST /api/login); tenant API keys aren't enough
+	//
+	// Admin cluster routes
+	// GET    /api/admin/cluster/leader        - This node's ID and the current leader lease
+	api.HandleFunc("/admin/cluster/leader", s.requireSession(s.leaderHandler)).Methods("GET")
+
+	// Admin job routes (jobs run in memory on the node that started them)
+	// GET    /api/admin/jobs                  - List jobs (?tenant=, ?kind=, ?status=)
+	// GET    /api/admin/jobs/{id}             - Get a job's status and progress
+	// POST   /api/admin/jobs/{id}/cancel      - Cancel a running job
+	api.HandleFunc("/admin/jobs", s.requireSession(s.listJobsHandler)).Methods("GET")
+	api.HandleFunc("/admin/jobs/{id}", s.requireSession(s.getJobHandler)).Methods("GET")
+	api.HandleFunc("/admin/jobs/{id}/cancel", s.requireSession(s.cancelJobHandler)).Methods("POST")
+
+	// Admin backup routes (manifests stored in the bucket)
+	// GET    /api/admin/backups               - List backup manifests
+	// POST   /api/admin/backups               - Start a backup job (?incremental=true)
+	// GET    /api/admin/backups/{id}          - Get a backup manifest
+	// POST   /api/admin/backups/{id}/restore  - Start a restore job (?prune=true)
+	api.HandleFunc("/admin/backups", s.requireSession(s.listBackupsHandler)).Methods("GET")
+	api.HandleFunc("/admin/backups", s.requireSession(s.createBackupHandler)).Methods("POST")
+	api.HandleFunc("/admin/backups/{id}", s.requireSession(s.getBackupHandler)).Methods("GET")
+	api.HandleFunc("/admin/backups/{id}/restore", s.requireSession(s.restoreBackupHandler)).Methods("POST")
+
+	// Admin consistency check routes
+	// GET    /api/admin/fsck                  - Latest consistency report
+	// POST   /api/admin/fsck                  - Start a consistency check (?repair=true)
+	api.HandleFunc("/admin/fsck", s.requireSession(s.fsckReportHandler)).Methods("GET")
+	api.HandleFunc("/admin/fsck", s.requireSession(s.startFsckHandler)).Methods("POST")
+
+	// Admin garbage collection routes
+	// GET    /api/admin/gc                    - Latest garbage collection report
+	// POST   /api/admin/gc                    - Start garbage collection (?dry_run=true, ?retention=)
+	api.HandleFunc("/admin/gc", s.requireSession(s.gcReportHandler)).Methods("GET")
+	api.HandleFunc("/admin/gc", s.requireSession(s.startGCHandler)).Methods("POST")
+
+	// Admin index routes
+	// POST   /api/admin/index/rebuild         - Rebuild the tenant's content index from storage
+	api.HandleFunc("/admin/index/rebuild", s.requireSession(s.rebuildIndexHandler)).Methods("POST")
+
 	// Content routes
 	// IDs can contain slashes for nested/hierarchical content (e.g., "parent/child")
 	// {id:.+} matches one or more path segments including slashes
@@ -90,10 +296,11 @@ func (s *Server) setupRoutes() {
 	// ROUTE REGISTRATION ORDER MATTERS (gorilla mux matches in registration order):
 	// 1. Bulk get (no {id})
 	// 2. List by type (no {id})
-	// 3. Literal suffix routes ({id:.+}/transition, /versions, /history, /diff, /metadata)
-	// 4. State-specific routes with literal suffixes ({state}/metadata, {state}/comments)
-	// 5. State-specific content routes ({state} only)
-	// 6. Catch-all content routes ({id:.+} only) — MUST BE LAST
+	// 3. Explicit routes (states/{state}, items/{id}, items/{id}/states/{state})
+	// 4. Literal suffix routes ({id:.+}/transition, /versions, /history, /diff, /metadata)
+	// 5. State-specific routes with literal suffixes ({state}/metadata, {state}/comments)
+	// 6. State-specific content routes ({state} only)
+	// 7. Catch-all content routes ({id:.+} only) — MUST BE LAST
 
 	// Bulk get
 	// POST   /api/content                        - Bulk get multiple items
@@ -113,27 +320,144 @@ func (s *Server) setupRoutes() {
 	api.HandleFunc("/content/{type}/_index", s.getDirectoryIndexHandler).Methods("GET")
 	api.HandleFunc("/content/{type}/_index", s.putDirectoryIndexHandler).Methods("PUT")
 
+	// Zip import
+	// POST   /api/content/{type}/import-zip      - Create an item per file of a zip archive (job)
+	api.HandleFunc("/content/{type}/import-zip", s.importZipHandler).Methods("POST")
+
+	// Zip export
+	// GET    /api/content/{type}/export.zip      - Download matching items and a manifest as a zip
+	api.HandleFunc("/content/{type}/export.zip", s.exportZipHandler).Methods("GET")
+
+	// Explicit routes (the ID is taken literally, so items named "draft" or
+	// "metadata" are addressable; registered before every {id:.+} route)
+	// GET    /api/content/{type}/states/{state}                - List items in state
+	// GET    /api/content/{type}/items/{id}/states/{state}     - Get content in state
+	// POST   /api/content/{type}/items/{id}/states/{state}     - Create content in state
+	// PUT    /api/content/{type}/items/{id}/states/{state}     - Update content in state
+	// DELETE /api/content/{type}/items/{id}/states/{state}     - Delete content in state
+	// GET    /api/content/{type}/items/{id}/states             - States that hold the item
+	// GET    /api/content/{type}/items/{id}                    - Get live content
+	// POST   /api/content/{type}/items/{id}                    - Create live content
+	// PUT    /api/content/{type}/items/{id}                    - Update live content
+	// DELETE /api/content/{type}/items/{id}                    - Delete live content
+	// (attachment and rendition routes below are also available under items/{id})
+	api.HandleFunc("/content/{type}/states/{state:draft|pending|live}", s.listContentHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/items/{id:.+}/states/{state:draft|pending|live}", s.getContentHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/items/{id:.+}/states/{state:draft|pending|live}", s.createContentHandler).Methods("POST")
+	api.HandleFunc("/content/{type}/items/{id:.+}/states/{state:draft|pending|live}", s.updateContentHandler).Methods("PUT")
+	api.HandleFunc("/content/{type}/items/{id:.+}/states/{state:draft|pending|live}", s.deleteContentHandler).Methods("DELETE")
+	api.HandleFunc("/content/{type}/items/{id:.+}/renditions", s.listRenditionsHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/items/{id:.+}/renditions", s.deleteStaleRenditionsHandler).Methods("DELETE")
+	api.HandleFunc("/content/{type}/items/{id:.+}/renditions/{name}", s.getRenditionHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/items/{id:.+}/renditions/{name}", s.putRenditionHandler).Methods("PUT")
+	api.HandleFunc("/content/{type}/items/{id:.+}/renditions/{name}", s.deleteRenditionHandler).Methods("DELETE")
+	api.HandleFunc("/content/{type}/items/{id:.+}/attachments", s.listAttachmentsHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/items/{id:.+}/attachments/{name}", s.getAttachmentHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/items/{id:.+}/attachments/{name}", s.putAttachmentHandler).Methods("PUT")
+	api.HandleFunc("/content/{type}/items/{id:.+}/attachments/{name}", s.deleteAttachmentHandler).Methods("DELETE")
+	api.HandleFunc("/content/{type}/items/{id:.+}/states", s.itemStatesHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/items/{id:.+}", s.getContentHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/items/{id:.+}", s.createContentHandler).Methods("POST")
+	api.HandleFunc("/content/{type}/items/{id:.+}", s.updateContentHandler).Methods("PUT")
+	api.HandleFunc("/content/{type}/items/{id:.+}", s.deleteContentHandler).Methods("DELETE")
+
 	// Literal suffix routes (registered FIRST so they match before catch-all)
 	// POST   /api/content/{type}/{id}/transition - Move content between states
+	// POST   /api/content/{type}/{id}/from-template/{template} - Create content from a template
 	api.HandleFunc("/content/{type}/{id:.+}/transition", s.transitionHandler).Methods("POST")
+	api.HandleFunc("/content/{type}/{id:.+}/from-template/{template}", s.fromTemplateHandler).Methods("POST")
+
+	// Attachment routes (named files stored under an item, shared by its states)
+	// GET    /api/content/{type}/{id}/attachments         - List attachments
+	// GET    /api/content/{type}/{id}/attachments/{name}  - Get attachment
+	// PUT    /api/content/{type}/{id}/attachments/{name}  - Create/replace attachment
+	// DELETE /api/content/{type}/{id}/attachments/{name}  - Delete attachment
+	api.HandleFunc("/content/{type}/{id:.+}/attachments", s.listAttachmentsHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/{id:.+}/attachments/{name}", s.getAttachmentHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/{id:.+}/attachments/{name}", s.putAttachmentHandler).Methods("PUT")
+	api.HandleFunc("/content/{type}/{id:.+}/attachments/{name}", s.deleteAttachmentHandler).Methods("DELETE")
+
+	// Rendition routes (files generated from an item: thumbnails, conversions, previews)
+	// GET    /api/content/{type}/{id}/renditions          - List renditions (with staleness)
+	// DELETE /api/content/{type}/{id}/renditions          - Delete stale renditions (?all=true for all)
+	// GET    /api/content/{type}/{id}/renditions/{name}   - Get rendition file
+	// PUT    /api/content/{type}/{id}/renditions/{name}   - Store rendition (?kind=&width=&height=&max_age=&state=)
+	// DELETE /api/content/{type}/{id}/renditions/{name}   - Delete rendition
+	api.HandleFunc("/content/{type}/{id:.+}/renditions", s.listRenditionsHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/{id:.+}/renditions", s.deleteStaleRenditionsHandler).Methods("DELETE")
+	api.HandleFunc("/content/{type}/{id:.+}/renditions/{name}", s.getRenditionHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/{id:.+}/renditions/{name}", s.putRenditionHandler).Methods("PUT")
+	api.HandleFunc("/content/{type}/{id:.+}/renditions/{name}", s.deleteRenditionHandler).Methods("DELETE")
 
 	// Version routes
+	// GET    /api/content/{type}/{id}/versions                   - List stored versions of live content
+	// GET    /api/content/{type}/{id}/versions/{version}         - Get a version's content
+	// POST   /api/content/{type}/{id}/versions/{version}/restore - Make a version the live content again
 	api.HandleFunc("/content/{type}/{id:.+}/versions", s.listVersionsHandler).Methods("GET")
 	api.HandleFunc("/content/{type}/{id:.+}/versions/{version}", s.getVersionHandler).Methods("GET")
 	api.HandleFunc("/content/{type}/{id:.+}/versions/{version}/restore", s.restoreVersionHandler).Methods("POST")
 
 	// History routes
+	// GET    /api/content/{type}/{id}/history           - List publish records (author, message, time)
+	// GET    /api/content/{type}/{id}/history/{version} - Get a version's publish record
+	// GET    /api/content/{type}/{id}/diff              - Diff two versions (?from=&to=)
 	api.HandleFunc("/content/{type}/{id:.+}/history", s.listHistoryHandler).Methods("GET")
 	api.HandleFunc("/content/{type}/{id:.+}/history/{version}", s.getHistoryHandler).Methods("GET")
 	api.HandleFunc("/content/{type}/{id:.+}/diff", s.diffHandler).Methods("GET")
 
+	// Branch (copy live into draft to start an edit)
+	// POST   /api/content/{type}/{id}/branch - Copy live content into draft (?overwrite=true replaces a draft)
+	api.HandleFunc("/content/{type}/{id:.+}/branch", s.branchHandler).Methods("POST")
+
+	// Item states
+	// GET    /api/content/{type}/{id}/states - Which states hold the item, with size, version, and modified time
+	api.HandleFunc("/content/{type}/{id:.+}/states", s.itemStatesHandler).Methods("GET")
+
+	// Item activity
+	// GET    /api/content/{type}/{id}/events - Versions, publishes, metadata changes, comments, releases, and transactions, oldest first
+	api.HandleFunc("/content/{type}/{id:.+}/events", s.itemEventsHandler).Methods("GET")
+
+	// Lineage export (legal discovery)
+	// GET    /api/content/{type}/{id}/lineage-export - Zip of every version, history, comments, and audit entries
+	api.HandleFunc("/content/{type}/{id:.+}/lineage-export", s.lineageExportHandler).Methods("GET")
+
+	// SEO report
+	// GET    /api/content/{type}/{id}/seo-report         - Scored SEO checklist (live)
+	// GET    /api/content/{type}/{id}/{state}/seo-report - Scored SEO checklist for a state
+	api.HandleFunc("/content/{type}/{id:.+}/{state:draft|pending|live}/seo-report", s.seoReportHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/{id:.+}/seo-report", s.seoReportHandler).Methods("GET")
+
+	// Variant routes (A/B alternatives of an item's content)
+	// GET    /api/content/{type}/{id}/variants                  - List variants
+	// GET    /api/content/{type}/{id}/variants/{name}           - Get variant details
+	// PUT    /api/content/{type}/{id}/variants/{name}           - Create/update variant content (?weight=)
+	// DELETE /api/content/{type}/{id}/variants/{name}           - Delete variant
+	// POST   /api/content/{type}/{id}/variants/{name}/publish   - Serve the variant on the public route
+	// POST   /api/content/{type}/{id}/variants/{name}/unpublish - Stop serving the variant
+	// POST   /api/content/{type}/{id}/variants/{name}/{action}  - Publish or unpublish the variant
+	// PUT    /api/content/{type}/{id}/variants/{name}/targeting - Set targeting rules (country, device, headers)
+	api.HandleFunc("/content/{type}/{id:.+}/variants", s.listVariantsHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/{id:.+}/variants/{name}", s.getVariantHandler).Methods("GET")
+	api.HandleFunc("/content/{type}/{id:.+}/variants/{name}", s.putVariantHandler).Methods("PUT")
+	api.HandleFunc("/content/{type}/{id:.+}/variants/{name}", s.deleteVariantHandler).Methods("DELETE")
+	api.HandleFunc("/content/{type}/{id:.+}/variants/{name}/{action:publish|unpublish}", s.publishVariantHandler).Methods("POST")
+	api.HandleFunc("/content/{type}/{id:.+}/variants/{name}/targeting", s.putTargetingHandler).Methods("PUT")
+
 	// Metadata routes (live content)
+	// GET    /api/content/{type}/{id}/metadata   - Get metadata
+	// PUT    /api/content/{type}/{id}/metadata   - Replace metadata
+	// PATCH  /api/content/{type}/{id}/metadata   - Merge keys into metadata
+	// DELETE /api/content/{type}/{id}/metadata   - Remove metadata keys ({"keys": [...]})
 	api.HandleFunc("/content/{type}/{id:.+}/metadata", s.getMetadataHandler).Methods("GET")
 	api.HandleFunc("/content/{type}/{id:.+}/metadata", s.setMetadataHandler).Methods("PUT")
 	api.HandleFunc("/content/{type}/{id:.+}/metadata", s.updateMetadataHandler).Methods("PATCH")
 	api.HandleFunc("/content/{type}/{id:.+}/metadata", s.deleteMetadataHandler).Methods("DELETE")
 
 	// State-specific metadata routes (explicit state names)
+	// GET    /api/content/{type}/{id}/{state}/metadata - Get metadata in a state
+	// PUT    /api/content/{type}/{id}/{state}/metadata - Replace metadata in a state
+	// PATCH  /api/content/{type}/{id}/{state}/metadata - Merge keys into metadata in a state
+	// DELETE /api/content/{type}/{id}/{state}/metadata - Remove metadata keys in a state
 	api.HandleFunc("/content/{type}/{id:.+}/{state:draft|pending|live}/metadata", s.getMetadataHandler).Methods("GET")
 	api.HandleFunc("/content/{type}/{id:.+}/{state:draft|pending|live}/metadata", s.setMetadataHandler).Methods("PUT")
 	api.HandleFunc("/content/{type}/{id:.+}/{state:draft|pending|live}/metadata", s.updateMetadataHandler).Methods("PATCH")
@@ -150,6 +474,40 @@ func (s *Server) setupRoutes() {
 	api.HandleFunc("/schemas/{name}", s.putGlobalSchemaHandler).Methods("PUT")
 	api.HandleFunc("/schemas/{name}", s.deleteGlobalSchemaHandler).Methods("DELETE")
 
+	// Tenant settings routes (per-tenant policy)
+	// GET    /api/tenant/settings             - Get tenant settings
+	// PUT    /api/tenant/settings             - Update tenant settings (partial)
+
+	api.HandleFunc("/tenant/settings", s.getSettingsHandler).Methods("GET")
+	api.HandleFunc("/tenant/settings", s.putSettingsHandler).Methods("PUT")
+
+	// Tenant content root routes (blue/green)
+	// GET    /api/tenant/roots                - Active and inactive content roots
+	// POST   /api/tenant/activate-root        - Switch the active root ({"root": "green"} or {"rollback": true})
+
+	api.HandleFunc("/tenant/roots", s.getRootsHandler).Methods("GET")
+	api.HandleFunc("/tenant/activate-root", s.activateRootHandler).Methods("POST")
+
+	// Tenant API key routes (only a hash is stored; secrets are shown once)
+	// GET    /api/tenant/keys                 - List API keys
+	// POST   /api/tenant/keys                 - Create an API key ({"name": "ci", "role": "admin|editor|viewer"}, default viewer)
+	// POST   /api/tenant/keys/{id}/rotate     - Replace a key's secret
+	// DELETE /api/tenant/keys/{id}            - Revoke an API key
+
+	api.HandleFunc("/tenant/keys", s.listAPIKeysHandler).Methods("GET")
+	api.HandleFunc("/tenant/keys", s.createAPIKeyHandler).Methods("POST")
+	api.HandleFunc("/tenant/keys/{id}/rotate", s.rotateAPIKeyHandler).Methods("POST")
+	api.HandleFunc("/tenant/keys/{id}", s.revokeAPIKeyHandler).Methods("DELETE")
+
+	// Tenant plugin routes (sandboxed WASM content transforms)
+	// GET    /api/tenant/plugins              - List tenant plugins
+	// PUT    /api/tenant/plugins/{name}       - Upload a .wasm plugin (?phases=write,render&types=...)
+	// DELETE /api/tenant/plugins/{name}       - Delete plugin
+
+	api.HandleFunc("/tenant/plugins", s.listPluginsHandler).Methods("GET")
+	api.HandleFunc("/tenant/plugins/{name}", s.putPluginHandler).Methods("PUT")
+	api.HandleFunc("/tenant/plugins/{name}", s.deletePluginHandler).Methods("DELETE")
+
 	// Tenant schema routes (tenant-specific overrides)
 	// GET    /api/tenant/schemas              - List tenant schemas
 	// GET    /api/tenant/schemas/{name}       - Get tenant schema
@@ -162,6 +520,11 @@ func (s *Server) setupRoutes() {
 	api.HandleFunc("/tenant/schemas/{name}", s.deleteTenantSchemaHandler).Methods("DELETE")
 
 	// State-specific comment routes (explicit state names + literal /comments suffix)
+	// GET    /api/content/{type}/{id}/{state}/comments              - List review comments
+	// POST   /api/content/{type}/{id}/{state}/comments              - Add a review comment
+	// GET    /api/content/{type}/{id}/{state}/comments/{comment_id} - Get a comment
+	// PUT    /api/content/{type}/{id}/{state}/comments/{comment_id} - Edit or resolve a comment
+	// DELETE /api/content/{type}/{id}/{state}/comments/{comment_id} - Delete a comment
 	api.HandleFunc("/content/{type}/{id:.+}/{state:draft|pending|live}/comments", s.listCommentsHandler).Methods("GET")
 	api.HandleFunc("/content/{type}/{id:.+}/{state:draft|pending|live}/comments", s.createCommentHandler).Methods("POST")
 	api.HandleFunc("/content/{type}/{id:.+}/{state:draft|pending|live}/comments/{comment_id}", s.getCommentHandler).Methods("GET")
@@ -169,12 +532,20 @@ func (s *Server) setupRoutes() {
 	api.HandleFunc("/content/{type}/{id:.+}/{state:draft|pending|live}/comments/{comment_id}", s.deleteCommentHandler).Methods("DELETE")
 
 	// State-specific content routes (explicit state names, after all literal suffix routes)
+	// GET    /api/content/{type}/{id}/{state} - Get content in a state
+	// POST   /api/content/{type}/{id}/{state} - Create content in a state
+	// PUT    /api/content/{type}/{id}/{state} - Update content in a state
+	// DELETE /api/content/{type}/{id}/{state} - Delete content in a state
 	api.HandleFunc("/content/{type}/{id:.+}/{state:draft|pending|live}", s.getContentHandler).Methods("GET")
 	api.HandleFunc("/content/{type}/{id:.+}/{state:draft|pending|live}", s.createContentHandler).Methods("POST")
 	api.HandleFunc("/content/{type}/{id:.+}/{state:draft|pending|live}", s.updateContentHandler).Methods("PUT")
 	api.HandleFunc("/content/{type}/{id:.+}/{state:draft|pending|live}", s.deleteContentHandler).Methods("DELETE")
 
 	// Catch-all content routes (MUST BE LAST — {id:.+} matches anything)
+	// GET    /api/content/{type}/{id} - Get live content, or list a folder of items
+	// POST   /api/content/{type}/{id} - Create content (in the tenant's default state)
+	// PUT    /api/content/{type}/{id} - Update content
+	// DELETE /api/content/{type}/{id} - Delete live content
 	api.HandleFunc("/content/{type}/{id:.+}", s.getOrListContentHandler).Methods("GET")
 	api.HandleFunc("/content/{type}/{id:.+}", s.createContentHandler).Methods("POST")
 	api.HandleFunc("/content/{type}/{id:.+}", s.updateContentHandler).Methods("PUT")
@@ -191,6 +562,71 @@ func (s *Server) setupRoutes() {
 	api.HandleFunc("/webhooks/{id}", s.putWebhookHandler).Methods("PUT")
 	api.HandleFunc("/webhooks/{id}", s.deleteWebhookHandler).Methods("DELETE")
 
+	// Transaction routes
+	// GET    /api/transactions              - List transactions for tenant
+	// POST   /api/transactions              - Stage a transaction (optionally commit immediately)
+	// GET    /api/transactions/{id}         - Get transaction
+	// POST   /api/transactions/{id}/commit  - Commit atomically (all succeed or all roll back)
+	// DELETE /api/transactions/{id}         - Abort a staged transaction
+
+	api.HandleFunc("/transactions", s.listTransactionsHandler).Methods("GET")
+	api.HandleFunc("/transactions", s.createTransactionHandler).Methods("POST")
+	api.HandleFunc("/transactions/{id}", s.getTransactionHandler).Methods("GET")
+	api.HandleFunc("/transactions/{id}/commit", s.commitTransactionHandler).Methods("POST")
+	api.HandleFunc("/transactions/{id}", s.abortTransactionHandler).Methods("DELETE")
+
+	// Release routes
+	// GET    /api/releases                  - List releases for tenant
+	// POST   /api/releases                  - Create a release
+	// GET    /api/releases/{id}             - Get release (with readiness while open)
+	// PUT    /api/releases/{id}             - Update an open release
+	// DELETE /api/releases/{id}             - Delete an open release
+	// POST   /api/releases/{id}/publish     - Publish all items atomically
+	// POST   /api/releases/{id}/rollback    - Restore items to their pre-release versions
+
+	api.HandleFunc("/releases", s.listReleasesHandler).Methods("GET")
+	api.HandleFunc("/releases", s.createReleaseHandler).Methods("POST")
+	api.HandleFunc("/releases/{id}", s.getReleaseHandler).Methods("GET")
+	api.HandleFunc("/releases/{id}", s.updateReleaseHandler).Methods("PUT")
+	api.HandleFunc("/releases/{id}", s.deleteReleaseHandler).Methods("DELETE")
+	api.HandleFunc("/releases/{id}/publish", s.publishReleaseHandler).Methods("POST")
+	api.HandleFunc("/releases/{id}/rollback", s.rollbackReleaseHandler).Methods("POST")
+
+	// Template routes
+	// GET    /api/templates/{type}          - List templates for a content type
+	// GET    /api/templates/{type}/{name}   - Get template
+	// PUT    /api/templates/{type}/{name}   - Create/update template
+	// DELETE /api/templates/{type}/{name}   - Delete template
+
+	api.HandleFunc("/templates/{type}", s.listTemplatesHandler).Methods("GET")
+	api.HandleFunc("/templates/{type}/{name}", s.getTemplateHandler).Methods("GET")
+	api.HandleFunc("/templates/{type}/{name}", s.putTemplateHandler).Methods("PUT")
+	api.HandleFunc("/templates/{type}/{name}", s.deleteTemplateHandler).Methods("DELETE")
+
+	// Navigation routes
+	// GET    /api/navigation                - Page tree from parent/order (?type=, ?menu=, ?state=)
+	api.HandleFunc("/navigation", s.navigationHandler).Methods("GET")
+
+	// Cache manifest routes (for targeted CDN invalidation and static rebuilds)
+	// GET    /api/cache-manifest            - Public URLs changed or deleted since a time (?since=, ?type=)
+	api.HandleFunc("/cache-manifest", s.cacheManifestHandler).Methods("GET")
+
+	// Changes feed routes (for incremental sync clients)
+	// GET    /api/changes                   - Content changes in any state after a sync token (?since=, ?type=, ?limit=)
+	api.HandleFunc("/changes", s.changesHandler).Methods("GET")
+
+	// Redirect routes ({from} is a public content path, {type}/{id})
+	// GET    /api/redirects                 - List redirects
+	// GET    /api/redirects/export          - Export for edge workers (?format=json|redirects)
+	// GET    /api/redirects/{from}          - Get redirect
+	// PUT    /api/redirects/{from}          - Create/update redirect
+	// DELETE /api/redirects/{from}          - Delete redirect
+	api.HandleFunc("/redirects", s.listRedirectsHandler).Methods("GET")
+	api.HandleFunc("/redirects/export", s.exportRedirectsHandler).Methods("GET")
+	api.HandleFunc("/redirects/{from:.+}", s.getRedirectHandler).Methods("GET")
+	api.HandleFunc("/redirects/{from:.+}", s.putRedirectHandler).Methods("PUT")
+	api.HandleFunc("/redirects/{from:.+}", s.deleteRedirectHandler).Methods("DELETE")
+
 	// Serve static website files at root
 	// Strip the "www" prefix from the embedded filesystem
 	wwwContent, err := fs.Sub(s.wwwFS, "www")
@@ -217,6 +653,12 @@ func (s *Server) setupRoutes() {
 		w.Write(data)
 	}).Methods("GET")
 
+	// Single sign-on (OIDC authorization code flow with PKCE) for the web UI
+	// GET /auth/oidc/login    - Redirect to the identity provider (?next= is where to land after)
+	// GET /auth/oidc/callback - Check the login and start a session
+	s.router.HandleFunc("/auth/oidc/login", s.oidcLoginHandler).Methods("GET")
+	s.router.HandleFunc(oidcCallbackPath, s.oidcCallbackHandler).Methods("GET")
+
 	// Admin static assets (CSS, JS) - unprotected so login page can load them
 	s.router.PathPrefix("/admin/css/").Handler(http.StripPrefix("/admin/", http.FileServer(http.FS(func() fs.FS {
 		sub, _ := fs.Sub(wwwContent, "admin")
@@ -239,6 +681,9 @@ func (s *Server) setupRoutes() {
 	})
 	s.router.PathPrefix("/admin").Handler(s.adminAuthMiddleware(adminHandler))
 
+	// Interactive API reference, built from /api/openapi.json
+	s.router.HandleFunc("/docs", s.docsHandler(wwwContent)).Methods("GET")
+
 	// Handle root path - serve index.html with dynamic values
 	s.router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		// Read and serve index.html
@@ -258,22 +703,25 @@ func (s *Server) setupRoutes() {
 	}).Methods("GET")
 }
 
-// Handler returns the HTTP handler with CORS support
-// RegisterRoute adds an additional route to the API router.
+// Routes added with RegisterRoute, described here for the API reference:
+// GET    /api/cluster/peers - Gossip cluster members (with --cluster)
+
+// RegisterRoute adds an additional route to the API router
 func (s *Server) RegisterRoute(path string, handler http.HandlerFunc, methods ...string) {
 	s.router.PathPrefix("/api").Subrouter().HandleFunc(path, handler).Methods(methods...)
 }
 
+// Handler returns the HTTP handler with CORS support
 func (s *Server) Handler() http.Handler {
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Tenant"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Tenant", "X-API-Key", "If-Match", "If-None-Match", "Idempotency-Key", "X-Content-Root"},
 		AllowCredentials: true,
 		MaxAge:           86400,
 	})
 
-	return c.Handler(s.router)
+	return c.Handler(s.pathTenantHandler(s.router))
 }
 
 // responseWriter wraps http.ResponseWriter to capture status code
@@ -326,7 +774,9 @@ func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 // versionHandler returns the server version
 func (s *Server) versionHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{
-		"version": version.GetVersion(),
-		"service": "velocity",
+		"version":            version.GetVersion(),
+		"service":            "velocity",
+		"min_client_version": minClientVersion,
+		"max_client_version": maxClientVersion(),
 	})
 }
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/crypto"
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const (
+	configCollection   = "config"
+	settingsDocumentID = "settings"
+	settingsCacheTTL   = 30 * time.Second
+)
+
+// TenantSettings holds per-tenant policy
+type TenantSettings struct {
+	// RequirePublishMessage requires a non-empty author and message on every transition to live
+	RequirePublishMessage bool `json:"require_publish_message"`
+
+	// RequireAltText requires image content to carry alt_text metadata before going live
+	RequireAltText bool `json:"require_alt_text"`
+
+	// DefaultState is the state writes land in when the route doesn't name one (default live)
+	DefaultState storage.State `json:"default_state,omitempty"`
+
+	// FailIfExists makes POST creates fail with 409 when the item already
+	// exists, as if sent with If-None-Match: * (?fail-if-exists=false overrides)
+	FailIfExists bool `json:"fail_if_exists"`
+
+	// ValidateWrites rejects JSON creates, updates, uploads, and transaction
+	// updates that don't match their type's schema (dry runs always validate)
+	ValidateWrites bool `json:"validate_writes"`
+
+	// PreviewToken lets the direct content route serve draft and pending content
+	// (?state=draft&token=...). Preview is disabled while it is empty.
+	PreviewToken string `json:"preview_token,omitempty"`
+
+	// RobotsTxt is served at /content/{tenant}/robots.txt (default allows everything)
+	RobotsTxt string `json:"robots_txt,omitempty"`
+
+	// SecurityTxt is served at /content/{tenant}/.well-known/security.txt when it has a contact
+	SecurityTxt *SecurityTxt `json:"security_txt,omitempty"`
+
+	// IDPolicy adds rules for the IDs of new content (the built-in rules always apply)
+	IDPolicy *IDPolicy `json:"id_policy,omitempty"`
+
+	// TypeAliases resolve content type names to other types in every route
+	// (e.g. "article": "blog" while clients move to a renamed type)
+	TypeAliases map[string]string `json:"type_aliases,omitempty"`
+
+	// DeprecatedTypes marks content types as deprecated, by name
+	DeprecatedTypes map[string]*TypeDeprecation `json:"deprecated_types,omitempty"`
+
+	// VersionRetention keeps versions of live content by count and age, per
+	// content type ("*" for every type without its own policy)
+	VersionRetention map[string]*RetentionPolicy `json:"version_retention,omitempty"`
+
+	// ErrorPages brands the 404 and 410 responses of the public content route
+	ErrorPages *ErrorPages `json:"error_pages,omitempty"`
+
+	// Forms accept public submissions at /submit/{tenant}/{form}, by form name
+	Forms map[string]*Form `json:"forms,omitempty"`
+}
+
+// cachedSettings is an in-memory cache entry for tenant settings
+type cachedSettings struct {
+	settings  *TenantSettings
+	expiresAt time.Time
+}
+
+// settingsStore loads tenant settings with a short-lived per-node cache
+type settingsStore struct {
+	mu      sync.RWMutex
+	cache   map[string]*cachedSettings
+	storage storage.Storage
+	keyring *crypto.Keyring // Seals stored settings, which hold the preview token
+}
+
+func newSettingsStore(s storage.Storage, keyring *crypto.Keyring) *settingsStore {
+	return &settingsStore{
+		cache:   make(map[string]*cachedSettings),
+		storage: s,
+		keyring: keyring,
+	}
+}
+
+// settingsAAD binds encrypted settings to their tenant
+func settingsAAD(tenant string) []byte {
+	return []byte("settings:" + tenant)
+}
+
+// get returns a tenant's settings (defaults if none are stored)
+func (ss *settingsStore) get(ctx context.Context, tenant string) *TenantSettings {
+	ss.mu.RLock()
+	cached, ok := ss.cache[tenant]
+	ss.mu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.settings
+	}
+
+	settings := &TenantSettings{}
+	if data, err := ss.storage.GetDocument(ctx, tenant, configCollection, settingsDocumentID); err == nil {
+		if data, err = ss.keyring.Open(ctx, data, settingsAAD(tenant)); err != nil {
+			log.Error("Failed to decrypt settings for tenant %s: %v", tenant, err)
+		} else if err := json.Unmarshal(data, settings); err != nil {
+			log.Error("Failed to parse settings for tenant %s: %v", tenant, err)
+		}
+	}
+
+	ss.mu.Lock()
+	ss.cache[tenant] = &cachedSettings{settings: settings, expiresAt: time.Now().Add(settingsCacheTTL)}
+	ss.mu.Unlock()
+
+	return settings
+}
+
+// put stores a tenant's settings and refreshes the local cache
+func (ss *settingsStore) put(ctx context.Context, tenant string, settings *TenantSettings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	if ss.keyring != nil {
+		if data, err = ss.keyring.Seal(ctx, data, settingsAAD(tenant)); err != nil {
+			return err
+		}
+	}
+	if err := ss.storage.PutDocument(ctx, tenant, configCollection, settingsDocumentID, data); err != nil {
+		return err
+	}
+
+	ss.mu.Lock()
+	ss.cache[tenant] = &cachedSettings{settings: settings, expiresAt: time.Now().Add(settingsCacheTTL)}
+	ss.mu.Unlock()
+	return nil
+}
+
+// checkPublishPolicy returns an error message if a transition to live violates tenant policy
+func (s *Server) checkPublishPolicy(ctx context.Context, tenant, author, message string) string {
+	if !s.settings.get(ctx, tenant).RequirePublishMessage {
+		return ""
+	}
+	if author == "" || message == "" {
+		return "Tenant policy requires an author and message when publishing to live"
+	}
+	return ""
+}
+
+// writeState returns the state a create or update lands in: the state named in
+// the route, or the tenant's default state
+func (s *Server) writeState(r *http.Request, tenant string) storage.State {
+	if mux.Vars(r)["state"] != "" {
+		return getState(r)
+	}
+	if state := s.settings.get(r.Context(), tenant).DefaultState; state != "" {
+		return state
+	}
+	return storage.StateLive
+}
+
+// validPreviewToken reports whether token matches the tenant's preview token
+func (s *Server) validPreviewToken(ctx context.Context, tenant, token string) bool {
+	expected := s.settings.get(ctx, tenant).PreviewToken
+	return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// =============================================================================
+// Settings Handlers
+// =============================================================================
+
+// getSettingsHandler handles GET /api/tenant/settings
+func (s *Server) getSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.settings.get(r.Context(), s.getTenant(r)))
+}
+
+// putSettingsHandler handles PUT /api/tenant/settings
+// Fields omitted from the body keep their current values.
+func (s *Server) putSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+
+	current := *s.settings.get(r.Context(), tenant)
+	current.SecurityTxt = current.SecurityTxt.clone() // decoding fills it in place
+	current.IDPolicy = current.IDPolicy.clone()
+	current.ErrorPages = current.ErrorPages.clone()
+
+	// Maps are replaced rather than merged, so entries can be removed
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "Failed to read body")
+		return
+	}
+	var given map[string]json.RawMessage
+	if err := json.Unmarshal(body, &given); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+	if _, ok := given["type_aliases"]; ok {
+		current.TypeAliases = nil
+	}
+	if _, ok := given["deprecated_types"]; ok {
+		current.DeprecatedTypes = nil
+	}
+	if _, ok := given["version_retention"]; ok {
+		current.VersionRetention = nil
+	}
+	if _, ok := given["forms"]; ok {
+		current.Forms = nil
+	}
+	if err := json.Unmarshal(body, &current); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+	if current.DefaultState != "" && !storage.ValidState(string(current.DefaultState)) {
+		writeError(w, http.StatusBadRequest, "invalid_state", fmt.Sprintf("Invalid default_state: %s (expected draft, pending, or live)", current.DefaultState))
+		return
+	}
+	if current.SecurityTxt != nil {
+		if err := current.SecurityTxt.validate(); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_security_txt", err.Error())
+			return
+		}
+	}
+	if current.IDPolicy != nil {
+		if err := current.IDPolicy.validate(); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_id_policy", err.Error())
+			return
+		}
+	}
+	if current.ErrorPages != nil {
+		if err := current.ErrorPages.validate(); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_error_pages", err.Error())
+			return
+		}
+	}
+	if err := validateTypeAliases(current.TypeAliases); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_type_aliases", err.Error())
+		return
+	}
+	if err := validateDeprecatedTypes(current.DeprecatedTypes); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_deprecated_types", err.Error())
+		return
+	}
+	if err := validateVersionRetention(current.VersionRetention); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_version_retention", err.Error())
+		return
+	}
+	if err := validateForms(current.Forms); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_forms", err.Error())
+		return
+	}
+
+	if err := s.settings.put(r.Context(), tenant, &current); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	log.Info("Updated settings for tenant %s", tenant)
+	writeJSON(w, http.StatusOK, &current)
+}
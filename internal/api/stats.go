@@ -0,0 +1,365 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const (
+	storageStatsJobKind  = "storage-stats"
+	storageStatsReportID = "storage-stats"
+	storageStatsWorkers  = 8
+)
+
+// Derived asset categories of the storage breakdown
+const (
+	derivedAttachments = "attachments"
+	derivedRenditions  = "renditions"
+	derivedVariants    = "variants"
+)
+
+// usage counts stored objects and their bytes
+type usage struct {
+	Objects int64 `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+}
+
+func (u *usage) add(objects, bytes int64) {
+	u.Objects += objects
+	u.Bytes += bytes
+}
+
+// typeUsage is the storage used by one content type
+type typeUsage struct {
+	States   map[string]*usage `json:"states"`   // current objects, by state
+	Versions *usage            `json:"versions"` // older versions of live content
+	Derived  map[string]*usage `json:"derived"`  // attachments, renditions, variants
+	Total    *usage            `json:"total"`
+}
+
+func newTypeUsage() *typeUsage {
+	return &typeUsage{
+		States:   map[string]*usage{},
+		Versions: &usage{},
+		Derived:  map[string]*usage{},
+		Total:    &usage{},
+	}
+}
+
+// storageStats is the stored result of a storage usage scan of a tenant
+type storageStats struct {
+	GeneratedAt time.Time             `json:"generated_at"`
+	Duration    string                `json:"duration"`
+	Root        string                `json:"root"`
+	Total       *usage                `json:"total"`
+	Current     *usage                `json:"current"`  // latest objects in every state
+	Versions    *usage                `json:"versions"` // older versions of live content
+	States      map[string]*usage     `json:"states"`
+	Derived     map[string]*usage     `json:"derived"`
+	Types       map[string]*typeUsage `json:"types"`
+}
+
+// typeUsage returns the usage of a content type, adding it when missing
+func (st *storageStats) typeUsage(contentType string) *typeUsage {
+	t, ok := st.Types[contentType]
+	if !ok {
+		t = newTypeUsage()
+		st.Types[contentType] = t
+	}
+	return t
+}
+
+// addCurrent records the latest object of an item in a state
+func (st *storageStats) addCurrent(contentType string, state storage.State, bytes int64) {
+	t := st.typeUsage(contentType)
+	for _, states := range []map[string]*usage{t.States, st.States} {
+		if states[string(state)] == nil {
+			states[string(state)] = &usage{}
+		}
+		states[string(state)].add(1, bytes)
+	}
+	t.Total.add(1, bytes)
+	st.Current.add(1, bytes)
+	st.Total.add(1, bytes)
+}
+
+// addVersions records older versions of an item
+func (st *storageStats) addVersions(contentType string, objects, bytes int64) {
+	t := st.typeUsage(contentType)
+	t.Versions.add(objects, bytes)
+	t.Total.add(objects, bytes)
+	st.Versions.add(objects, bytes)
+	st.Total.add(objects, bytes)
+}
+
+// addDerived records derived assets of an item
+func (st *storageStats) addDerived(contentType, category string, objects, bytes int64) {
+	t := st.typeUsage(contentType)
+	for _, derived := range []map[string]*usage{t.Derived, st.Derived} {
+		if derived[category] == nil {
+			derived[category] = &usage{}
+		}
+		derived[category].add(objects, bytes)
+	}
+	t.Total.add(objects, bytes)
+	st.Total.add(objects, bytes)
+}
+
+// scanStorage measures the storage used by a tenant's active root: the
+// current objects of every state, older versions of live content, and the
+// attachments, renditions, and variants derived from content
+func (s *Server) scanStorage(ctx context.Context, j *job, tenant string) (*storageStats, error) {
+	started := time.Now()
+	stats := &storageStats{
+		Root:     s.roots.ActiveRoot(ctx, tenant).Active,
+		Total:    &usage{},
+		Current:  &usage{},
+		Versions: &usage{},
+		States:   map[string]*usage{},
+		Derived:  map[string]*usage{},
+		Types:    map[string]*typeUsage{},
+	}
+
+	types, err := s.storage.ListContentTypes(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list content types: %w", err)
+	}
+
+	for i, contentType := range types {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		j.setMessage("scanning " + contentType)
+		j.setProgress(i, len(types)+1)
+		stats.typeUsage(contentType)
+
+		// Current objects; live ones are checked for older versions, and every
+		// item (attachments are shared by its states) for attachments
+		type liveItem struct{ id, ext string }
+		var live []liveItem
+		ids := make(map[string]bool)
+		for _, state := range contentStates {
+			items, err := s.storage.List(ctx, tenant, contentType, state)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %s (%s): %w", contentType, state, err)
+			}
+			for _, item := range items {
+				stats.addCurrent(contentType, state, item.Size)
+				id, ext := extractIDAndExt(item.Key, contentType, state)
+				ids[id] = true
+				if state == storage.StateLive {
+					live = append(live, liveItem{id, ext})
+				}
+			}
+		}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		next := make(chan func())
+		for w := 0; w < storageStatsWorkers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for task := range next {
+					task()
+				}
+			}()
+		}
+		for _, item := range live {
+			next <- func() {
+				versions, err := s.storage.ListVersions(ctx, tenant, contentType, item.id, item.ext)
+				if err != nil {
+					return
+				}
+				var objects, bytes int64
+				for _, v := range versions {
+					if !v.IsLatest {
+						objects++
+						bytes += v.Size
+					}
+				}
+				mu.Lock()
+				stats.addVersions(contentType, objects, bytes)
+				mu.Unlock()
+			}
+		}
+		for id := range ids {
+			next <- func() {
+				attachments, err := s.storage.ListAttachments(ctx, tenant, contentType, id)
+				if err != nil || len(attachments) == 0 {
+					return
+				}
+				var bytes int64
+				for _, a := range attachments {
+					bytes += a.Size
+				}
+				mu.Lock()
+				stats.addDerived(contentType, derivedAttachments, int64(len(attachments)), bytes)
+				mu.Unlock()
+			}
+		}
+		close(next)
+		wg.Wait()
+	}
+
+	// Renditions and variants are recorded in per-item documents
+	j.setMessage("scanning renditions and variants")
+	j.setProgress(len(types), len(types)+1)
+	if err := s.scanDerivedSets(ctx, tenant, renditionsCollection, func(data []byte) {
+		var set renditionSet
+		if json.Unmarshal(data, &set) != nil {
+			return
+		}
+		var bytes int64
+		for _, r := range set.Renditions {
+			bytes += r.Size
+		}
+		stats.addDerived(set.Type, derivedRenditions, int64(len(set.Renditions)), bytes)
+	}); err != nil {
+		return nil, err
+	}
+	if err := s.scanDerivedSets(ctx, tenant, variantsCollection, func(data []byte) {
+		var set variantSet
+		if json.Unmarshal(data, &set) != nil {
+			return
+		}
+		var bytes int64
+		for _, v := range set.Variants {
+			bytes += v.Size
+		}
+		stats.addDerived(set.Type, derivedVariants, int64(len(set.Variants)), bytes)
+	}); err != nil {
+		return nil, err
+	}
+
+	stats.GeneratedAt = time.Now().UTC()
+	stats.Duration = time.Since(started).Round(time.Millisecond).String()
+	return stats, nil
+}
+
+// scanDerivedSets passes every document of a collection to fn
+func (s *Server) scanDerivedSets(ctx context.Context, tenant, collection string, fn func(data []byte)) error {
+	ids, err := s.storage.ListDocuments(ctx, tenant, collection)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", collection, err)
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if data, err := s.storage.GetDocument(ctx, tenant, collection, id); err == nil {
+			fn(data)
+		}
+	}
+	return nil
+}
+
+// startStorageStats starts a storage usage scan of a tenant
+func (s *Server) startStorageStats(tenant string) (*job, error) {
+	return s.jobs.start(tenant, storageStatsJobKind, func(ctx context.Context, j *job) (interface{}, error) {
+		stats, err := s.scanStorage(ctx, j, tenant)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(stats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal report: %w", err)
+		}
+		if err := s.storage.PutDocument(ctx, tenant, reportsCollection, storageStatsReportID, data); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"objects": stats.Total.Objects, "bytes": stats.Total.Bytes}, nil
+	})
+}
+
+// storageStatsLoop scans every tenant's storage usage on a fixed interval.
+// Only the cluster leader starts scans.
+func (s *Server) storageStatsLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.scheduleStorageStats()
+	}
+}
+
+func (s *Server) scheduleStorageStats() {
+	if !s.leader.isLeader() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	tenants, err := s.storage.ListTenants(ctx)
+	if err != nil {
+		log.Error("Storage stats schedule error: %v", err)
+		return
+	}
+	for _, tenant := range tenants {
+		if _, err := s.startStorageStats(tenant); err != nil && err != errJobRunning {
+			log.Error("Failed to start storage stats for tenant %s: %v", tenant, err)
+		}
+	}
+}
+
+// =============================================================================
+// Storage Stats Handlers
+// =============================================================================
+
+// startStorageStatsHandler handles POST /api/stats/storage
+func (s *Server) startStorageStatsHandler(w http.ResponseWriter, r *http.Request) {
+	j, err := s.startStorageStats(s.getTenant(r))
+	if err == errJobRunning {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":   "job_running",
+			"message": "A storage scan is already running",
+			"job":     j.info(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"job":     j.info(),
+		"message": "Storage scan started",
+	})
+}
+
+// storageStatsHandler handles GET /api/stats/storage
+// Returns the latest storage usage breakdown, plus the status of a scan in progress.
+func (s *Server) storageStatsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+
+	var running *jobInfo
+	if j, ok := s.jobs.latest(tenant, storageStatsJobKind); ok && j.running() {
+		running = j.info()
+	}
+
+	data, err := s.storage.GetDocument(r.Context(), tenant, reportsCollection, storageStatsReportID)
+	if err != nil {
+		if running != nil {
+			writeJSON(w, http.StatusAccepted, map[string]interface{}{"job": running})
+			return
+		}
+		writeError(w, http.StatusNotFound, "not_found", "No storage stats yet; POST to /api/stats/storage to scan")
+		return
+	}
+
+	var stats storageStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", "Failed to parse stored report")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"report": &stats,
+		"job":    running,
+	})
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+)
+
+// Devices a targeting rule can match
+const (
+	deviceMobile  = "mobile"
+	deviceTablet  = "tablet"
+	deviceDesktop = "desktop"
+)
+
+// countryHeaders are the CDN headers a visitor's country is read from, in order
+var countryHeaders = []string{"CF-IPCountry", "CloudFront-Viewer-Country", "X-Vercel-IP-Country", "X-Country-Code"}
+
+// deviceHeaders are the headers a visitor's device is inferred from
+var deviceHeaders = []string{"User-Agent", "Sec-CH-UA-Mobile", "CloudFront-Is-Mobile-Viewer", "CloudFront-Is-Tablet-Viewer"}
+
+// targetingRule matches visitors on the public content route. Every condition
+// set must match; a variant with several rules matches if any rule does.
+type targetingRule struct {
+	Country []string          `json:"country,omitempty"` // ISO 3166-1 alpha-2 codes
+	Device  []string          `json:"device,omitempty"`  // mobile, tablet, desktop
+	Headers map[string]string `json:"headers,omitempty"` // value, "prefix*", or "*" for present
+}
+
+// visitor is what targeting rules are evaluated against
+type visitor struct {
+	country string
+	device  string
+	header  http.Header
+}
+
+func newVisitor(r *http.Request) *visitor {
+	v := &visitor{header: r.Header, device: detectDevice(r.Header)}
+	for _, name := range countryHeaders {
+		if country := r.Header.Get(name); country != "" {
+			v.country = strings.ToUpper(country)
+			break
+		}
+	}
+	return v
+}
+
+// detectDevice classifies a request from CDN device headers, client hints, or
+// the User-Agent
+func detectDevice(h http.Header) string {
+	switch {
+	case h.Get("CloudFront-Is-Tablet-Viewer") == "true":
+		return deviceTablet
+	case h.Get("CloudFront-Is-Mobile-Viewer") == "true", h.Get("Sec-CH-UA-Mobile") == "?1":
+		return deviceMobile
+	}
+
+	ua := h.Get("User-Agent")
+	switch {
+	case strings.Contains(ua, "iPad"), strings.Contains(ua, "Tablet"),
+		strings.Contains(ua, "Android") && !strings.Contains(ua, "Mobile"):
+		return deviceTablet
+	case strings.Contains(ua, "Mobi"), strings.Contains(ua, "iPhone"), strings.Contains(ua, "Android"):
+		return deviceMobile
+	}
+	return deviceDesktop
+}
+
+// matches reports whether a visitor satisfies every condition of the rule
+func (rule *targetingRule) matches(v *visitor) bool {
+	if len(rule.Country) > 0 && !containsString(rule.Country, v.country) {
+		return false
+	}
+	if len(rule.Device) > 0 && !containsString(rule.Device, v.device) {
+		return false
+	}
+	for name, pattern := range rule.Headers {
+		if !headerMatches(v.header.Values(name), pattern) {
+			return false
+		}
+	}
+	return true
+}
+
+// headerMatches compares header values (case-insensitively) with a pattern
+func headerMatches(values []string, pattern string) bool {
+	pattern = strings.ToLower(pattern)
+	for _, value := range values {
+		value = strings.ToLower(value)
+		switch {
+		case pattern == "*":
+			return true
+		case strings.HasSuffix(pattern, "*"):
+			if strings.HasPrefix(value, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		case value == pattern:
+			return true
+		}
+	}
+	return false
+}
+
+// matchesTargeting reports whether any of a variant's rules match the visitor
+func (cv *contentVariant) matchesTargeting(v *visitor) bool {
+	for i := range cv.Targeting {
+		if cv.Targeting[i].matches(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// varyHeaders lists the request headers the rules of some variants read
+func varyHeaders(variants []*contentVariant) []string {
+	seen := make(map[string]bool)
+	var headers []string
+	add := func(names ...string) {
+		for _, name := range names {
+			name = http.CanonicalHeaderKey(name)
+			if !seen[name] {
+				seen[name] = true
+				headers = append(headers, name)
+			}
+		}
+	}
+	for _, cv := range variants {
+		for _, rule := range cv.Targeting {
+			if len(rule.Country) > 0 {
+				add(countryHeaders...)
+			}
+			if len(rule.Device) > 0 {
+				add(deviceHeaders...)
+			}
+			names := make([]string, 0, len(rule.Headers))
+			for name := range rule.Headers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			add(names...)
+		}
+	}
+	return headers
+}
+
+// normalizeTargeting validates rules and normalizes their values
+func normalizeTargeting(rules []targetingRule) error {
+	for i := range rules {
+		rule := &rules[i]
+		if len(rule.Country) == 0 && len(rule.Device) == 0 && len(rule.Headers) == 0 {
+			return fmt.Errorf("rule %d has no conditions", i+1)
+		}
+		for j, country := range rule.Country {
+			if len(country) != 2 {
+				return fmt.Errorf("rule %d: invalid country code %q (expected ISO 3166-1 alpha-2)", i+1, country)
+			}
+			rule.Country[j] = strings.ToUpper(country)
+		}
+		for j, device := range rule.Device {
+			device = strings.ToLower(device)
+			if device != deviceMobile && device != deviceTablet && device != deviceDesktop {
+				return fmt.Errorf("rule %d: invalid device %q (expected mobile, tablet, or desktop)", i+1, device)
+			}
+			rule.Device[j] = device
+		}
+		headers := make(map[string]string, len(rule.Headers))
+		for name, pattern := range rule.Headers {
+			if name == "" || pattern == "" {
+				return fmt.Errorf("rule %d: header matchers need a name and value", i+1)
+			}
+			headers[http.CanonicalHeaderKey(name)] = pattern
+		}
+		if len(rule.Headers) > 0 {
+			rule.Headers = headers
+		}
+	}
+	return nil
+}
+
+// =============================================================================
+// Targeting Handlers
+// =============================================================================
+
+// putTargetingHandler handles PUT /api/content/{type}/{id}/variants/{name}/targeting
+// Body: {"rules": [{"country": ["DE", "AT"], "device": ["mobile"]}, {"headers": {"X-Beta": "1"}}]}
+// An empty list removes targeting, returning the variant to weighted selection.
+func (s *Server) putTargetingHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id, name := vars["type"], vars["id"], vars["name"]
+	tenant := s.getTenant(r)
+
+	var req struct {
+		Rules []targetingRule `json:"rules"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+	if err := normalizeTargeting(req.Rules); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_targeting", err.Error())
+		return
+	}
+
+	var status int
+	set, err := s.variants.update(r.Context(), tenant, contentType, id, func(set *variantSet) error {
+		v, ok := set.Variants[name]
+		if !ok {
+			status = http.StatusNotFound
+			return fmt.Errorf("variant '%s' not found", name)
+		}
+
+		// A live variant losing its rules joins weighted selection
+		if len(req.Rules) == 0 && len(v.Targeting) > 0 && v.Status == variantLive {
+			total := v.Weight
+			for _, other := range set.weighted() {
+				total += other.Weight
+			}
+			if total > 100 {
+				status = http.StatusUnprocessableEntity
+				return fmt.Errorf("live variant weights would total %d%% (at most 100%%)", total)
+			}
+		}
+
+		v.Targeting = req.Rules
+		if len(v.Targeting) == 0 {
+			v.Targeting = nil
+		}
+		return nil
+	})
+	if err != nil {
+		switch status {
+		case http.StatusNotFound:
+			writeError(w, status, "not_found", err.Error())
+		case http.StatusUnprocessableEntity:
+			writeError(w, status, "invalid_weights", err.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		}
+		return
+	}
+
+	log.Info("Set %d targeting rules on variant %s of %s/%s for tenant %s", len(req.Rules), name, contentType, id, tenant)
+	writeJSON(w, http.StatusOK, set.Variants[name])
+}
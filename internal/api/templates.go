@@ -0,0 +1,333 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const templatesCollection = "templates"
+
+// placeholderPattern matches {{name}} tokens in template strings
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// Placeholders filled in automatically when not supplied
+var builtinPlaceholders = map[string]func(contentType, id string) interface{}{
+	"id":   func(contentType, id string) interface{} { return id },
+	"type": func(contentType, id string) interface{} { return contentType },
+	"date": func(contentType, id string) interface{} { return time.Now().UTC().Format("2006-01-02") },
+	"now":  func(contentType, id string) interface{} { return time.Now().UTC().Format(time.RFC3339) },
+}
+
+// contentTemplate is a reusable JSON document for a content type. String values
+// may contain {{name}} placeholders that are filled in on instantiation.
+type contentTemplate struct {
+	Name         string                 `json:"name"`
+	Type         string                 `json:"type"`
+	Description  string                 `json:"description,omitempty"`
+	Content      interface{}            `json:"content"`
+	Defaults     map[string]interface{} `json:"defaults,omitempty"`
+	Placeholders []string               `json:"placeholders"`
+	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+}
+
+func templateID(contentType, name string) string {
+	return contentType + "/" + name
+}
+
+func (s *Server) getTemplate(ctx context.Context, tenant, contentType, name string) (*contentTemplate, error) {
+	data, err := s.storage.GetDocument(ctx, tenant, templatesCollection, templateID(contentType, name))
+	if err != nil {
+		return nil, err
+	}
+	var tmpl contentTemplate
+	if err := json.Unmarshal(data, &tmpl); err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return &tmpl, nil
+}
+
+// findPlaceholders returns the sorted, distinct placeholder names in a value
+func findPlaceholders(value interface{}) []string {
+	seen := make(map[string]bool)
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			for _, match := range placeholderPattern.FindAllStringSubmatch(v, -1) {
+				seen[match[1]] = true
+			}
+		case map[string]interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(value)
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fillPlaceholders substitutes values into a template. A string that is exactly
+// one placeholder takes the value as-is (so numbers, booleans, and objects keep
+// their type); placeholders inside longer strings are formatted as text.
+func fillPlaceholders(value interface{}, values map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if match := placeholderPattern.FindStringSubmatch(v); match != nil && match[0] == v {
+			return values[match[1]]
+		}
+		return placeholderPattern.ReplaceAllStringFunc(v, func(token string) string {
+			name := placeholderPattern.FindStringSubmatch(token)[1]
+			if s, ok := values[name].(string); ok {
+				return s
+			}
+			data, _ := json.Marshal(values[name])
+			return string(data)
+		})
+	case map[string]interface{}:
+		filled := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			filled[key] = fillPlaceholders(child, values)
+		}
+		return filled
+	case []interface{}:
+		filled := make([]interface{}, len(v))
+		for i, child := range v {
+			filled[i] = fillPlaceholders(child, values)
+		}
+		return filled
+	}
+	return value
+}
+
+// =============================================================================
+// Template Handlers
+// =============================================================================
+
+// listTemplatesHandler handles GET /api/templates/{type}
+func (s *Server) listTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	contentType := mux.Vars(r)["type"]
+	tenant := s.getTenant(r)
+
+	ids, err := s.storage.ListDocuments(r.Context(), tenant, templatesCollection)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	templates := []*contentTemplate{}
+	for _, id := range ids {
+		name, ok := strings.CutPrefix(id, contentType+"/")
+		if !ok {
+			continue
+		}
+		tmpl, err := s.getTemplate(r.Context(), tenant, contentType, name)
+		if err != nil {
+			log.Error("Failed to load template %s: %v", id, err)
+			continue
+		}
+		templates = append(templates, tmpl)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"type":      contentType,
+		"templates": templates,
+		"count":     len(templates),
+	})
+}
+
+// getTemplateHandler handles GET /api/templates/{type}/{name}
+func (s *Server) getTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tmpl, err := s.getTemplate(r.Context(), s.getTenant(r), vars["type"], vars["name"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Template '%s' not found", vars["name"]))
+		return
+	}
+	writeJSON(w, http.StatusOK, tmpl)
+}
+
+// putTemplateHandler handles PUT /api/templates/{type}/{name}
+// Body: {"description": "...", "content": {...}, "defaults": {"name": "value"}}
+func (s *Server) putTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, name := vars["type"], vars["name"]
+	tenant := s.getTenant(r)
+
+	var req struct {
+		Description string                 `json:"description"`
+		Content     interface{}            `json:"content"`
+		Defaults    map[string]interface{} `json:"defaults"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+	if req.Content == nil {
+		writeError(w, http.StatusBadRequest, "missing_content", "Template content is required")
+		return
+	}
+
+	now := time.Now().UTC()
+	tmpl := &contentTemplate{
+		Name:         name,
+		Type:         contentType,
+		Description:  req.Description,
+		Content:      req.Content,
+		Defaults:     req.Defaults,
+		Placeholders: findPlaceholders(req.Content),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if existing, err := s.getTemplate(r.Context(), tenant, contentType, name); err == nil {
+		tmpl.CreatedAt = existing.CreatedAt
+	}
+
+	data, err := json.Marshal(tmpl)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "marshal_error", err.Error())
+		return
+	}
+	if err := s.storage.PutDocument(r.Context(), tenant, templatesCollection, templateID(contentType, name), data); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	log.Info("Saved template %s/%s for tenant %s", contentType, name, tenant)
+	writeJSON(w, http.StatusOK, tmpl)
+}
+
+// deleteTemplateHandler handles DELETE /api/templates/{type}/{name}
+func (s *Server) deleteTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenant := s.getTenant(r)
+
+	if _, err := s.getTemplate(r.Context(), tenant, vars["type"], vars["name"]); err != nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Template '%s' not found", vars["name"]))
+		return
+	}
+	if err := s.storage.DeleteDocument(r.Context(), tenant, templatesCollection, templateID(vars["type"], vars["name"])); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"type":    vars["type"],
+		"name":    vars["name"],
+		"message": "Template deleted",
+	})
+}
+
+// fromTemplateHandler handles POST /api/content/{type}/{id}/from-template/{template}
+// Body: {"values": {"name": "value"}}. ?state= chooses the state (default: the
+// tenant's default state); existing content is only replaced with ?overwrite=true.
+func (s *Server) fromTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id, name := vars["type"], vars["id"], vars["template"]
+	tenant := s.getTenant(r)
+
+	tmpl, err := s.getTemplate(r.Context(), tenant, contentType, name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Template '%s' not found for type '%s'", name, contentType))
+		return
+	}
+
+	var req struct {
+		Values map[string]interface{} `json:"values"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+			return
+		}
+	}
+
+	// Supplied values win over template defaults, which win over built-ins
+	values := make(map[string]interface{})
+	var missing []string
+	for _, placeholder := range findPlaceholders(tmpl.Content) {
+		if value, ok := req.Values[placeholder]; ok {
+			values[placeholder] = value
+		} else if value, ok := tmpl.Defaults[placeholder]; ok {
+			values[placeholder] = value
+		} else if builtin, ok := builtinPlaceholders[placeholder]; ok {
+			values[placeholder] = builtin(contentType, id)
+		} else {
+			missing = append(missing, placeholder)
+		}
+	}
+	if len(missing) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "missing_values",
+			"message": fmt.Sprintf("Template '%s' needs values for: %s", name, strings.Join(missing, ", ")),
+			"missing": missing,
+		})
+		return
+	}
+
+	content, err := json.Marshal(fillPlaceholders(tmpl.Content, values))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "marshal_error", err.Error())
+		return
+	}
+
+	createVars := map[string]string{"type": contentType, "id": id}
+	if state := r.URL.Query().Get("state"); state != "" {
+		if !storage.ValidState(state) {
+			writeError(w, http.StatusBadRequest, "invalid_state", fmt.Sprintf("Invalid state: %s", state))
+			return
+		}
+		createVars["state"] = state
+	}
+
+	// Creating live content publishes it
+	state := s.writeState(mux.SetURLVars(r, createVars), tenant)
+	if state == storage.StateLive && !requirePermission(w, r, permPublish) {
+		return
+	}
+
+	if r.URL.Query().Get("overwrite") != "true" {
+		if stream, err := s.storage.FindContentStream(r.Context(), tenant, contentType, id, "json", state); err == nil {
+			stream.Body.Close()
+			writeError(w, http.StatusConflict, "already_exists", fmt.Sprintf("Content '%s' already exists in %s; pass ?overwrite=true to replace it", id, state))
+			return
+		}
+	}
+
+	// Write through the create handler so hooks, validation, fingerprints, and
+	// webhooks all apply as for any other write
+	create := mux.SetURLVars(r, createVars)
+	if r.URL.Query().Get("overwrite") == "true" {
+		// Replacing was asked for, whatever the tenant's fail_if_exists default
+		query := create.URL.Query()
+		query.Set("fail-if-exists", "false")
+		create.URL.RawQuery = query.Encode()
+	}
+	create.Body = io.NopCloser(bytes.NewReader(content))
+	create.ContentLength = int64(len(content))
+	create.Header.Set("Content-Type", "application/json")
+	s.createContentHandler(w, create)
+}
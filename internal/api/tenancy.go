@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+// Tenant routing: where API requests name their tenant
+const (
+	TenantRoutingBoth   = "both"   // X-Tenant header or /api/t/{tenant}/... (default)
+	TenantRoutingHeader = "header" // X-Tenant header only
+	TenantRoutingPath   = "path"   // /api/t/{tenant}/... only
+)
+
+// tenantPathPrefix starts API paths that carry their tenant
+// (/api/t/acme/content/pages is /api/content/pages for acme)
+const tenantPathPrefix = "/api/t/"
+
+// pathTenantKey is the context key of the tenant taken from the path
+type pathTenantKey struct{}
+
+// tenantRouting returns the configured tenant routing
+func (s *Server) tenantRouting() string {
+	if s.config.TenantRouting == "" {
+		return TenantRoutingBoth
+	}
+	return s.config.TenantRouting
+}
+
+// pathTenantHandler serves /api/t/{tenant}/... as the same route without the
+// tenant segment, for gateways and caches that key on the URL but not on
+// headers. The tenant in the path wins: a different X-Tenant is rejected
+// rather than ignored. With path routing, API requests must name their tenant
+// in the path and X-Tenant is refused; with header routing, /api/t/ isn't
+// served.
+func (s *Server) pathTenantHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routing := s.tenantRouting()
+
+		rest, ok := strings.CutPrefix(r.URL.Path, tenantPathPrefix)
+		if !ok {
+			if routing == TenantRoutingPath && r.Header.Get("X-Tenant") != "" && (r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/api/")) {
+				writeError(w, http.StatusBadRequest, "tenant_in_path", "This server takes the tenant in the path: /api/t/{tenant}/...")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if routing == TenantRoutingHeader {
+			writeError(w, http.StatusNotFound, "not_found", "Tenants in the path are disabled on this server; send X-Tenant")
+			return
+		}
+
+		tenant, rest, _ := strings.Cut(rest, "/")
+		if !storage.ValidTenant(tenant) {
+			log.Info("Rejected request for invalid tenant %q from %s", tenant, r.RemoteAddr)
+			writeError(w, http.StatusBadRequest, "invalid_tenant", fmt.Sprintf("Invalid tenant: %q (expected 1-64 letters, digits, '.', '_', or '-', starting with a letter or digit)", tenant))
+			return
+		}
+		if header := r.Header.Get("X-Tenant"); header != "" && header != tenant {
+			writeError(w, http.StatusBadRequest, "tenant_mismatch", fmt.Sprintf("X-Tenant %q doesn't match tenant %q in the path", header, tenant))
+			return
+		}
+
+		r = r.Clone(context.WithValue(r.Context(), pathTenantKey{}, tenant))
+		r.Header.Set("X-Tenant", tenant)
+		r.URL.Path = "/api/" + rest
+		if rest == "" {
+			r.URL.Path = "/api"
+		}
+		if r.URL.RawPath != "" {
+			if raw, ok := strings.CutPrefix(r.URL.RawPath, tenantPathPrefix+tenant+"/"); ok {
+				r.URL.RawPath = "/api/" + raw
+			} else {
+				r.URL.RawPath = ""
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiPath returns an API path as the client would request it: under
+// /api/t/{tenant} when the request named its tenant in the path
+func apiPath(r *http.Request, path string) string {
+	if tenant, ok := r.Context().Value(pathTenantKey{}).(string); ok {
+		if rest, ok := strings.CutPrefix(path, "/api/"); ok {
+			return tenantPathPrefix + tenant + "/" + rest
+		}
+	}
+	return path
+}
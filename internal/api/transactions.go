@@ -0,0 +1,672 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/plugin"
+	"velocity/internal/storage"
+)
+
+const (
+	transactionsCollection = "transactions"
+	txCommitLeaseTTL       = 5 * time.Minute // upper bound on a commit; the lease is released when it finishes
+)
+
+// Transaction statuses
+const (
+	txStaged     = "staged"
+	txCommitted  = "committed"
+	txRolledBack = "rolled_back"
+	txAborted    = "aborted"
+)
+
+// txOperation is a single staged change within a transaction
+type txOperation struct {
+	Op          string          `json:"op"` // "update" or "transition"
+	Type        string          `json:"type"`
+	ID          string          `json:"id"`
+	State       string          `json:"state,omitempty"`        // update: target state (default live)
+	Content     json.RawMessage `json:"content,omitempty"`      // update: JSON content, or a string for text types
+	ContentType string          `json:"content_type,omitempty"` // update: MIME type (default application/json)
+	From        string          `json:"from,omitempty"`         // transition
+	To          string          `json:"to,omitempty"`           // transition
+}
+
+// txResult records the outcome of an applied operation
+type txResult struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	State   string `json:"state"`
+	Version string `json:"version,omitempty"`
+}
+
+// transaction is a set of operations committed together
+type transaction struct {
+	ID          string        `json:"id"`
+	Status      string        `json:"status"`
+	Author      string        `json:"author,omitempty"`
+	Message     string        `json:"message,omitempty"`
+	Operations  []txOperation `json:"operations"`
+	Results     []txResult    `json:"results,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	CommittedAt *time.Time    `json:"committed_at,omitempty"`
+}
+
+// txSnapshot captures an item's state before an operation so it can be rolled back
+type txSnapshot struct {
+	Type     string
+	ID       string
+	Ext      string
+	State    storage.State
+	Existed  bool
+	Version  string
+	Content  []byte
+	MimeType string
+}
+
+// keyedLocks hands out a mutex per key, dropping it once nobody holds or
+// waits for it
+type keyedLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks key and returns the function that unlocks it
+func (kl *keyedLocks) lock(key string) func() {
+	kl.mu.Lock()
+	l := kl.locks[key]
+	if l == nil {
+		l = &keyedLock{}
+		kl.locks[key] = l
+	}
+	l.refs++
+	kl.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		kl.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(kl.locks, key)
+		}
+		kl.mu.Unlock()
+	}
+}
+
+// txLocks serializes commits of the same transaction on this node
+var txLocks = &keyedLocks{locks: make(map[string]*keyedLock)}
+
+// ext returns the storage extension an update writes, by its MIME type
+// (transitions move whatever is stored; see storedExt)
+func (op *txOperation) ext() string {
+	return getExtensionFromMime(op.mimeType())
+}
+
+// storedExt returns the extension an item is stored under in a state, or
+// false if the state doesn't hold it
+func (s *Server) storedExt(ctx context.Context, tenant, contentType, id string, state storage.State) (string, bool) {
+	stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, "", state)
+	if err != nil {
+		return "", false
+	}
+	stream.Body.Close()
+	_, ext := extractIDAndExt(stream.Key, contentType, state)
+	return ext, true
+}
+
+func (op *txOperation) mimeType() string {
+	if op.ContentType == "" {
+		return "application/json"
+	}
+	return op.ContentType
+}
+
+// body returns the bytes to store for an update operation
+func (op *txOperation) body() []byte {
+	if !isJSONContent(op.mimeType()) {
+		var text string
+		if err := json.Unmarshal(op.Content, &text); err == nil {
+			return []byte(text)
+		}
+	}
+	return op.Content
+}
+
+// setBody replaces update content with bytes returned from a plugin hook
+func (op *txOperation) setBody(content []byte) {
+	if isJSONContent(op.mimeType()) {
+		op.Content = content
+		return
+	}
+	op.Content, _ = json.Marshal(string(content))
+}
+
+// hookRequest describes the operation to plugin hooks
+func (op *txOperation) hookRequest(tenant string) *plugin.Request {
+	req := &plugin.Request{Tenant: tenant, Type: op.Type, ID: op.ID}
+	if op.Op == "update" {
+		req.Operation = plugin.OpUpdate
+		req.State = string(op.state())
+		req.ContentType = op.mimeType()
+		req.Content = op.body()
+	} else {
+		req.Operation = plugin.OpTransition
+		req.From, req.To = op.From, op.To
+	}
+	return req
+}
+
+func (op *txOperation) state() storage.State {
+	if op.State == "" {
+		return storage.StateLive
+	}
+	return storage.State(op.State)
+}
+
+// validate checks an operation is well-formed
+func (op *txOperation) validate() error {
+	if op.Type == "" || op.ID == "" {
+		return fmt.Errorf("type and id are required")
+	}
+	switch op.Op {
+	case "update":
+		if op.State != "" && !storage.ValidState(op.State) {
+			return fmt.Errorf("invalid state: %s", op.State)
+		}
+		if len(op.Content) == 0 {
+			return fmt.Errorf("content is required for update")
+		}
+	case "transition":
+		if !storage.ValidState(op.From) || !storage.ValidState(op.To) {
+			return fmt.Errorf("invalid transition: %s -> %s", op.From, op.To)
+		}
+		if op.From == op.To {
+			return fmt.Errorf("source and target states are the same")
+		}
+	default:
+		return fmt.Errorf("unknown op: %s", op.Op)
+	}
+	return nil
+}
+
+// snapshot records the current state of an item in the given state
+func (s *Server) snapshot(ctx context.Context, tenant, contentType, id, ext string, state storage.State) *txSnapshot {
+	snap := &txSnapshot{Type: contentType, ID: id, Ext: ext, State: state}
+	if item, err := s.storage.Get(ctx, tenant, contentType, id, ext, state); err == nil {
+		snap.Existed = true
+		snap.Version = item.VersionID
+		snap.Content = item.Content
+		snap.MimeType = item.ContentType
+	}
+	return snap
+}
+
+// restoreSnapshot puts an item back the way it was before the transaction
+func (s *Server) restoreSnapshot(ctx context.Context, tenant string, snap *txSnapshot) error {
+	if !snap.Existed {
+		return s.storage.Delete(ctx, tenant, snap.Type, snap.ID, snap.Ext, snap.State)
+	}
+	if snap.State == storage.StateLive && snap.Version != "" {
+		if _, err := s.storage.RestoreVersion(ctx, tenant, snap.Type, snap.ID, snap.Ext, snap.Version); err == nil {
+			return nil
+		}
+	}
+	_, err := s.storage.Put(ctx, tenant, snap.Type, snap.ID, snap.Ext, snap.Content, snap.MimeType, snap.State)
+	return err
+}
+
+// publishesToLive reports whether any operation transitions content to live
+// or writes it there directly
+func publishesToLive(ops []txOperation) bool {
+	for _, op := range ops {
+		switch op.Op {
+		case "transition":
+			if op.To == string(storage.StateLive) {
+				return true
+			}
+		case "update":
+			if op.state() == storage.StateLive {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// changesLive reports whether any operation writes live content or moves
+// content into or out of live
+func changesLive(ops []txOperation) bool {
+	for _, op := range ops {
+		if op.From == string(storage.StateLive) || publishesToLive([]txOperation{op}) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOperations runs validation and guards for all operations before anything is written
+func (s *Server) checkOperations(ctx context.Context, tenant string, ops []txOperation, author, message string) []string {
+	var errs []string
+	policy := s.settings.get(ctx, tenant).IDPolicy
+	for i, op := range ops {
+		if op.Op == "update" {
+			ops[i].ID = policy.normalize(op.ID)
+			op = ops[i]
+		}
+		prefix := fmt.Sprintf("operations[%d] %s/%s", i, op.Type, op.ID)
+		if err := op.validate(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", prefix, err))
+			continue
+		}
+
+		// Plugin hooks may modify update content or reject the operation
+		hookReq := op.hookRequest(tenant)
+		hookReq.Author, hookReq.Message = author, message
+		if err := s.runWriteHooks(ctx, hookReq); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", prefix, err))
+			continue
+		}
+		if op.Op == "update" && hookReq.Content != nil {
+			ops[i].setBody(hookReq.Content)
+			op = ops[i]
+		}
+
+		switch op.Op {
+		case "update":
+			if msg := policy.check(op.ID); msg != "" {
+				if exists, err := s.storage.Exists(ctx, tenant, op.Type, op.ID, op.ext(), op.state()); err != nil || !exists {
+					errs = append(errs, fmt.Sprintf("%s: %s", prefix, msg))
+				}
+			}
+			if isJSONContent(op.mimeType()) {
+				schema := s.loadSchema(ctx, tenant, op.Type)
+				if s.validatesWrites(ctx, tenant) {
+					for _, e := range validateDocument(schema, op.body()) {
+						errs = append(errs, fmt.Sprintf("%s: %s", prefix, e))
+					}
+				}
+				if schema != nil && hasProtectedFields(schema.Fields) {
+					for _, e := range s.fieldViolations(ctx, schema, tenant, op.Type, op.ID, op.ext(), op.state(), op.body()) {
+						errs = append(errs, fmt.Sprintf("%s: %s", prefix, e))
+					}
+				}
+			}
+		case "transition":
+			from := storage.State(op.From)
+			if from != storage.StateLive {
+				if unresolved, err := s.storage.HasUnresolvedComments(ctx, tenant, op.Type, op.ID, from); err == nil && unresolved {
+					errs = append(errs, fmt.Sprintf("%s: unresolved comments on %s content", prefix, from))
+				}
+			}
+			if storage.State(op.To) == storage.StateLive {
+				if violation := s.checkImageMetadata(ctx, tenant, op.Type, op.ID, from); violation != "" {
+					errs = append(errs, fmt.Sprintf("%s: %s", prefix, violation))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// commitOperations applies operations in order. If any fails, every applied
+// operation is rolled back (live content via version restore) and an error is returned.
+// History records and webhooks are only emitted once all operations succeed.
+func (s *Server) commitOperations(ctx context.Context, tenant string, ops []txOperation, author, message string) ([]txResult, error) {
+	type applied struct {
+		item  *storage.ContentItem
+		state storage.State
+		event string
+	}
+
+	var snapshots []*txSnapshot
+	var results []txResult
+	var changes []applied
+	parents := make(map[string]string)
+
+	rollback := func() {
+		for i := len(snapshots) - 1; i >= 0; i-- {
+			if err := s.restoreSnapshot(ctx, tenant, snapshots[i]); err != nil {
+				log.Error("Rollback failed for %s/%s (%s): %v", snapshots[i].Type, snapshots[i].ID, snapshots[i].State, err)
+			}
+		}
+	}
+
+	for i, op := range ops {
+		ext := op.ext()
+		if op.Op == "transition" {
+			if stored, ok := s.storedExt(ctx, tenant, op.Type, op.ID, storage.State(op.From)); ok {
+				ext = stored
+			}
+		}
+		if _, ok := parents[op.Type+"/"+op.ID]; !ok {
+			parents[op.Type+"/"+op.ID], _ = s.storage.GetLatestHistoryVersion(ctx, tenant, op.Type, op.ID)
+		}
+
+		var change applied
+		var err error
+
+		switch op.Op {
+		case "update":
+			snap := s.snapshot(ctx, tenant, op.Type, op.ID, ext, op.state())
+			snapshots = append(snapshots, snap)
+			change.state, change.event = op.state(), "create"
+			if snap.Existed {
+				change.event = "update"
+			}
+			content := op.body()
+			if isJSONContent(op.mimeType()) {
+				// Checked again against the item as it is now, which may have
+				// changed since checkOperations
+				if schema := s.loadSchema(ctx, tenant, op.Type); schema != nil && hasProtectedFields(schema.Fields) {
+					if violations := s.fieldViolations(ctx, schema, tenant, op.Type, op.ID, ext, op.state(), content); len(violations) > 0 {
+						err = fmt.Errorf("%s/%s: %s", op.Type, op.ID, strings.Join(violations, "; "))
+					}
+				}
+			}
+			if err == nil && isJSONContent(op.mimeType()) {
+				// Plugin hooks may have changed the content since its fields were sealed
+				content, err = s.encryptFields(ctx, tenant, op.Type, content)
+			}
+			if err == nil {
+				change.item, err = s.storage.Put(ctx, tenant, op.Type, op.ID, ext, content, op.mimeType(), op.state())
+			}
+		case "transition":
+			from, to := storage.State(op.From), storage.State(op.To)
+			snapshots = append(snapshots,
+				s.snapshot(ctx, tenant, op.Type, op.ID, ext, to),
+				s.snapshot(ctx, tenant, op.Type, op.ID, ext, from),
+			)
+			change.state = to
+			if to == storage.StateLive {
+				change.event = "publish"
+			}
+			change.item, err = s.storage.Transition(ctx, tenant, op.Type, op.ID, ext, from, to)
+		}
+
+		if err != nil {
+			rollback()
+			return nil, fmt.Errorf("operations[%d] %s %s/%s failed: %w", i, op.Op, op.Type, op.ID, err)
+		}
+
+		changes = append(changes, change)
+		results = append(results, txResult{Type: op.Type, ID: op.ID, State: string(change.state), Version: change.item.VersionID})
+	}
+
+	// Everything applied; record history and notify
+	for i, op := range ops {
+		change := changes[i]
+		if change.state == storage.StateLive {
+			record := &storage.HistoryRecord{
+				Version:   change.item.VersionID,
+				Parent:    parents[op.Type+"/"+op.ID],
+				Author:    author,
+				Message:   message,
+				Timestamp: time.Now(),
+				Size:      change.item.Size,
+			}
+			if err := s.storage.PutHistoryRecord(ctx, tenant, op.Type, op.ID, record); err != nil {
+				log.Error("Failed to create history record: %v", err)
+			}
+			parents[op.Type+"/"+op.ID] = change.item.VersionID
+		}
+
+		hookReq := op.hookRequest(tenant)
+		hookReq.Author, hookReq.Message = author, message
+		plugin.RunAfter(hookReq, &plugin.Result{Key: change.item.Key, Version: change.item.VersionID})
+
+		switch change.event {
+		case "":
+		case "publish":
+			s.triggerPublishWebhooks(tenant, op.Type, op.ID, filepath.Base(change.item.Key), change.item.ContentType, author, message)
+		default:
+			s.triggerWebhooks(tenant, change.event, op.Type, op.ID, filepath.Base(change.item.Key), change.item.ContentType)
+		}
+	}
+
+	return results, nil
+}
+
+// =============================================================================
+// Transaction Handlers
+// =============================================================================
+
+func (s *Server) getTransaction(ctx context.Context, tenant, id string) (*transaction, error) {
+	data, err := s.storage.GetDocument(ctx, tenant, transactionsCollection, id)
+	if err != nil {
+		return nil, err
+	}
+	var tx transaction
+	if err := json.Unmarshal(data, &tx); err != nil {
+		return nil, fmt.Errorf("failed to parse transaction: %w", err)
+	}
+	return &tx, nil
+}
+
+func (s *Server) putTransaction(ctx context.Context, tenant string, tx *transaction) error {
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transaction: %w", err)
+	}
+	return s.storage.PutDocument(ctx, tenant, transactionsCollection, tx.ID, data)
+}
+
+// createTransactionHandler handles POST /api/transactions
+// Stages a set of operations. With "commit": true the transaction is committed immediately.
+func (s *Server) createTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+
+	var req struct {
+		Operations []txOperation `json:"operations"`
+		Author     string        `json:"author,omitempty"`
+		Message    string        `json:"message,omitempty"`
+		Commit     bool          `json:"commit,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+	if len(req.Operations) == 0 {
+		writeError(w, http.StatusBadRequest, "missing_operations", "At least one operation is required")
+		return
+	}
+	for i, op := range req.Operations {
+		if err := op.validate(); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_operation", fmt.Sprintf("operations[%d]: %v", i, err))
+			return
+		}
+	}
+
+	// Committing, like POST /api/transactions/{id}/commit, and staging
+	// changes to live content need publish
+	if (req.Commit || changesLive(req.Operations)) && !requirePermission(w, r, permPublish) {
+		return
+	}
+
+	// Encrypted fields are sealed before the transaction is stored
+	for i := range req.Operations {
+		op := &req.Operations[i]
+		if op.Op != "update" || !isJSONContent(op.mimeType()) {
+			continue
+		}
+		sealed, err := s.encryptFields(r.Context(), tenant, op.Type, op.body())
+		if err != nil {
+			writeFieldEncryptionError(w, err)
+			return
+		}
+		op.setBody(sealed)
+	}
+
+	tx := &transaction{
+		ID:         uuid.New().String(),
+		Status:     txStaged,
+		Author:     req.Author,
+		Message:    req.Message,
+		Operations: req.Operations,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	if err := s.putTransaction(r.Context(), tenant, tx); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	if req.Commit {
+		s.commitTransaction(w, r, tenant, tx.ID)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, tx)
+}
+
+// listTransactionsHandler handles GET /api/transactions
+func (s *Server) listTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+
+	ids, _ := s.storage.ListDocuments(r.Context(), tenant, transactionsCollection)
+	transactions := []*transaction{}
+	for _, id := range ids {
+		if tx, err := s.getTransaction(r.Context(), tenant, id); err == nil {
+			transactions = append(transactions, tx)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"transactions": transactions,
+		"count":        len(transactions),
+	})
+}
+
+// getTransactionHandler handles GET /api/transactions/{id}
+func (s *Server) getTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	tx, err := s.getTransaction(r.Context(), s.getTenant(r), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", "Transaction not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, tx)
+}
+
+// commitTransactionHandler handles POST /api/transactions/{id}/commit
+func (s *Server) commitTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	s.commitTransaction(w, r, s.getTenant(r), mux.Vars(r)["id"])
+}
+
+// lockTransaction locks a staged transaction on this node and across the
+// cluster, then reads it, so a commit or abort that finished while this
+// request waited is seen. Writes the error response and returns nil if the
+// transaction can't be locked or is no longer staged.
+func (s *Server) lockTransaction(w http.ResponseWriter, r *http.Request, tenant, id string) (*transaction, func()) {
+	unlock := txLocks.lock(tenant + "/" + id)
+
+	// Without the lease there's no telling whether another node is committing
+	lease, err := s.storage.AcquireLease(r.Context(), "tenants/"+tenant+"/transactions/"+id, txCommitLeaseTTL)
+	switch {
+	case err == storage.ErrLeaseHeld:
+		unlock()
+		writeError(w, http.StatusConflict, "commit_in_progress", "Transaction is being committed by another request")
+		return nil, nil
+	case err != nil:
+		unlock()
+		log.Error("Failed to acquire commit lease for transaction %s: %v", id, err)
+		writeError(w, http.StatusServiceUnavailable, "lease_unavailable", "Failed to lock the transaction")
+		return nil, nil
+	}
+	release := func() {
+		s.storage.ReleaseLease(context.Background(), lease)
+		unlock()
+	}
+
+	tx, err := s.getTransaction(r.Context(), tenant, id)
+	if err != nil {
+		release()
+		writeError(w, http.StatusNotFound, "not_found", "Transaction not found")
+		return nil, nil
+	}
+	if tx.Status != txStaged {
+		release()
+		writeError(w, http.StatusConflict, "invalid_status", fmt.Sprintf("Transaction is %s", tx.Status))
+		return nil, nil
+	}
+	return tx, release
+}
+
+// commitTransaction validates and commits a staged transaction, writing the outcome
+func (s *Server) commitTransaction(w http.ResponseWriter, r *http.Request, tenant, id string) {
+	if !requirePermission(w, r, permPublish) {
+		return
+	}
+	tx, unlock := s.lockTransaction(w, r, tenant, id)
+	if tx == nil {
+		return
+	}
+	defer unlock()
+
+	errs := s.checkOperations(r.Context(), tenant, tx.Operations, tx.Author, tx.Message)
+	if publishesToLive(tx.Operations) {
+		if violation := s.checkPublishPolicy(r.Context(), tenant, tx.Author, tx.Message); violation != "" {
+			errs = append(errs, violation)
+		}
+	}
+	if len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	results, err := s.commitOperations(r.Context(), tenant, tx.Operations, tx.Author, tx.Message)
+	now := time.Now().UTC()
+	if err != nil {
+		tx.Status = txRolledBack
+		tx.Error = err.Error()
+	} else {
+		tx.Status = txCommitted
+		tx.Results = results
+		tx.CommittedAt = &now
+	}
+
+	if perr := s.putTransaction(r.Context(), tenant, tx); perr != nil {
+		log.Error("Failed to save transaction %s: %v", tx.ID, perr)
+	}
+
+	if err != nil {
+		writeJSON(w, http.StatusConflict, tx)
+		return
+	}
+
+	log.Info("Committed transaction %s (%d operations)", tx.ID, len(tx.Operations))
+	writeJSON(w, http.StatusOK, tx)
+}
+
+// abortTransactionHandler handles DELETE /api/transactions/{id}
+// Discards a staged transaction; committed transactions are kept for audit.
+func (s *Server) abortTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	tx, unlock := s.lockTransaction(w, r, tenant, mux.Vars(r)["id"])
+	if tx == nil {
+		return
+	}
+	defer unlock()
+
+	tx.Status = txAborted
+	if err := s.putTransaction(r.Context(), tenant, tx); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, tx)
+}
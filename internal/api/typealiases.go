@@ -0,0 +1,172 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+)
+
+// Deprecated type write policies
+const (
+	deprecatedWritesWarn  = "warn"
+	deprecatedWritesBlock = "block"
+)
+
+// resolvedTypeHeader names the type a request for an alias was served from
+const resolvedTypeHeader = "X-Resolved-Type"
+
+// TypeDeprecation marks a content type as deprecated
+type TypeDeprecation struct {
+	// Message explains the deprecation to clients (e.g. where to move)
+	Message string `json:"message,omitempty"`
+
+	// ReplacedBy names the type that replaces this one
+	ReplacedBy string `json:"replaced_by,omitempty"`
+
+	// Writes is "warn" (default: writes succeed with a Warning header) or
+	// "block" (writes are rejected); reads are always served
+	Writes string `json:"writes,omitempty"`
+}
+
+// blocksWrites reports whether writes to the type are rejected
+func (d *TypeDeprecation) blocksWrites() bool {
+	return d.Writes == deprecatedWritesBlock
+}
+
+// notice returns the text clients are warned with
+func (d *TypeDeprecation) notice(contentType string) string {
+	notice := fmt.Sprintf("Content type '%s' is deprecated", contentType)
+	if d.ReplacedBy != "" {
+		notice += fmt.Sprintf("; use '%s' instead", d.ReplacedBy)
+	}
+	if d.Message != "" {
+		notice += ": " + d.Message
+	}
+	return notice
+}
+
+// validTypeName reports whether name can name a content type: letters,
+// digits, and hyphens
+func validTypeName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateTypeAliases checks aliases before they are stored. Aliases point
+// at real type names, never at other aliases, so resolving takes one step.
+func validateTypeAliases(aliases map[string]string) error {
+	for alias, target := range aliases {
+		if !validTypeName(alias) || !validTypeName(target) {
+			return fmt.Errorf("type alias %q -> %q must name content types (letters, numbers, and hyphens)", alias, target)
+		}
+		if alias == target {
+			return fmt.Errorf("type alias %q points at itself", alias)
+		}
+		if _, ok := aliases[target]; ok {
+			return fmt.Errorf("type alias %q points at another alias (%q)", alias, target)
+		}
+	}
+	return nil
+}
+
+// validateDeprecatedTypes checks deprecations before they are stored
+func validateDeprecatedTypes(deprecated map[string]*TypeDeprecation) error {
+	for contentType, d := range deprecated {
+		if !validTypeName(contentType) {
+			return fmt.Errorf("deprecated type %q must be a content type name", contentType)
+		}
+		if d == nil {
+			return fmt.Errorf("deprecated type %q needs a deprecation (e.g. {})", contentType)
+		}
+		if d.Writes != "" && d.Writes != deprecatedWritesWarn && d.Writes != deprecatedWritesBlock {
+			return fmt.Errorf("deprecated type %q: writes must be %q or %q", contentType, deprecatedWritesWarn, deprecatedWritesBlock)
+		}
+		if d.ReplacedBy != "" && !validTypeName(d.ReplacedBy) {
+			return fmt.Errorf("deprecated type %q: replaced_by must be a content type name", contentType)
+		}
+	}
+	return nil
+}
+
+// typeAliasesOf returns the aliases that resolve to contentType, sorted
+func typeAliasesOf(aliases map[string]string, contentType string) []string {
+	var names []string
+	for alias, target := range aliases {
+		if target == contentType {
+			names = append(names, alias)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isWriteMethod reports whether a request method changes content
+func isWriteMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// typeAliasHandler resolves type aliases in route variables, so requests for
+// an alias are served from its target type, and applies type deprecations:
+// responses carry a Deprecation header, and writes are warned about or
+// blocked per the tenant's policy
+func (s *Server) typeAliasHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		contentType := vars["type"]
+		if contentType == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tenant := vars["tenant"]
+		if tenant == "" {
+			tenant = s.getTenant(r)
+		}
+		settings := s.settings.get(r.Context(), tenant)
+		if len(settings.TypeAliases) == 0 && len(settings.DeprecatedTypes) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if target, ok := settings.TypeAliases[contentType]; ok {
+			vars["type"] = target
+			r = mux.SetURLVars(r, vars)
+			w.Header().Set(resolvedTypeHeader, target)
+			log.Debug("Resolved type alias %s to %s for tenant %s", contentType, target, tenant)
+			contentType = target
+		}
+
+		if d, ok := settings.DeprecatedTypes[contentType]; ok {
+			notice := d.notice(contentType)
+			w.Header().Set("Deprecation", "true")
+			if d.ReplacedBy != "" {
+				w.Header().Set("Link", fmt.Sprintf(`</api/content/%s>; rel="successor-version"`, d.ReplacedBy))
+			}
+			if isWriteMethod(r.Method) {
+				if d.blocksWrites() {
+					writeError(w, http.StatusGone, "type_deprecated", notice)
+					return
+				}
+				w.Header().Set("Warning", fmt.Sprintf("299 velocity %q", notice))
+				log.Info("Write to deprecated type %s for tenant %s: %s %s", contentType, tenant, r.Method, r.URL.Path)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
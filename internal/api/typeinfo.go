@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"velocity/internal/storage"
+)
+
+const (
+	defaultTypeDetailsLimit = 50 // page size of ?details=true when no limit is given
+	maxTypesLimit           = 500
+	typeDetailWorkers       = 8
+)
+
+// typeInfo describes a content type for catalogs (?details=true on /api/types)
+type typeInfo struct {
+	Name         string           `json:"name"`
+	Title        string           `json:"title,omitempty"`
+	Description  string           `json:"description,omitempty"`
+	Schema       string           `json:"schema,omitempty"` // "tenant" or "global" when the type has a schema
+	Extension    string           `json:"extension,omitempty"`
+	Counts       map[string]int   `json:"counts"`
+	Size         int64            `json:"size"`
+	LastModified *time.Time       `json:"last_modified,omitempty"`
+	Aliases      []string         `json:"aliases,omitempty"`
+	Deprecated   *TypeDeprecation `json:"deprecated,omitempty"`
+}
+
+// typesPage is the offset and limit of a page of /api/types
+type typesPage struct {
+	offset, limit int // limit 0 means every type
+}
+
+// parseTypesPage reads ?offset= and ?limit= (?details=true pages by default),
+// returning why they are invalid, or "" if they aren't
+func parseTypesPage(r *http.Request, details bool) (typesPage, string) {
+	page := typesPage{}
+	if details {
+		page.limit = defaultTypeDetailsLimit
+	}
+	query := r.URL.Query()
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxTypesLimit {
+			return page, fmt.Sprintf("Limit must be between 1 and %d", maxTypesLimit)
+		}
+		page.limit = n
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return page, "Offset must be a non-negative number"
+		}
+		page.offset = n
+	}
+	return page, ""
+}
+
+// apply returns the page of names, and whether more follow it
+func (p typesPage) apply(names []string) ([]string, bool) {
+	if p.offset >= len(names) {
+		return []string{}, false
+	}
+	names = names[p.offset:]
+	if p.limit > 0 && len(names) > p.limit {
+		return names[:p.limit], true
+	}
+	return names, false
+}
+
+// describeTypes returns the details of each type, in order
+func (s *Server) describeTypes(ctx context.Context, tenant string, names []string) []*typeInfo {
+	settings := s.settings.get(ctx, tenant)
+	infos := make([]*typeInfo, len(names))
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < typeDetailWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				infos[i] = s.describeType(ctx, tenant, names[i])
+				infos[i].Aliases = typeAliasesOf(settings.TypeAliases, names[i])
+				infos[i].Deprecated = settings.DeprecatedTypes[names[i]]
+			}
+		}()
+	}
+	for i := range names {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return infos
+}
+
+// describeType reads a type's schema and counts its items in each state
+func (s *Server) describeType(ctx context.Context, tenant, name string) *typeInfo {
+	info := &typeInfo{Name: name, Counts: make(map[string]int)}
+
+	if schema, err := s.storage.GetSchema(ctx, tenant, name); err == nil && schema != nil {
+		info.Schema = "tenant"
+		if schema.IsGlobal {
+			info.Schema = "global"
+		}
+		info.Extension = s.getExtensionFromSchema(ctx, name)
+
+		var doc struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+		}
+		if json.Unmarshal(schema.Content, &doc) == nil {
+			info.Title, info.Description = doc.Title, doc.Description
+		}
+	}
+
+	// Types without a schema report the extension most of their items use
+	extensions := make(map[string]int)
+	var lastModified time.Time
+	for _, state := range []storage.State{storage.StateDraft, storage.StatePending, storage.StateLive} {
+		items, err := s.storage.List(ctx, tenant, name, state)
+		if err != nil {
+			continue
+		}
+		info.Counts[string(state)] = len(items)
+		for _, item := range items {
+			info.Size += item.Size
+			if item.LastModified.After(lastModified) {
+				lastModified = item.LastModified
+			}
+			extensions[strings.TrimPrefix(filepath.Ext(item.Key), ".")]++
+		}
+	}
+	if info.Extension == "" {
+		best := 0
+		for ext, n := range extensions {
+			if ext != "" && (n > best || (n == best && ext < info.Extension)) {
+				info.Extension, best = ext, n
+			}
+		}
+	}
+	if !lastModified.IsZero() {
+		info.LastModified = &lastModified
+	}
+	return info
+}
@@ -0,0 +1,335 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"velocity/internal/log"
+	"velocity/internal/plugin"
+	"velocity/internal/storage"
+)
+
+// maxFormField limits the size of a form field of a multipart upload
+const maxFormField = 64 << 10
+
+// uploadResult reports what happened to one file of a multipart upload or
+// zip import
+type uploadResult struct {
+	Part     string   `json:"part,omitempty"`
+	Filename string   `json:"filename,omitempty"`
+	ID       string   `json:"id"`
+	MimeType string   `json:"mime_type"`
+	Size     int64    `json:"size"`
+	Version  string   `json:"version,omitempty"`
+	Status   string   `json:"status"` // created, valid (dry run), skipped, or failed
+	Error    string   `json:"error,omitempty"`
+	Message  string   `json:"message,omitempty"`
+	Details  []string `json:"details,omitempty"`
+
+	code   int               // HTTP status of a failure
+	ext    string            // extension the file was stored with
+	fields map[string]string // form fields the file was stored with
+}
+
+// fail marks a file as not stored
+func (u *uploadResult) fail(code int, errCode, message string) {
+	u.Status = "failed"
+	u.code = code
+	u.Error = errCode
+	u.Message = message
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// uploadName returns the item name for an uploaded file: its base name
+// without the extension
+func uploadName(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// createFromForm stores the files of a multipart/form-data create. Parts
+// are streamed one at a time, never buffered whole. The first file becomes
+// the item {id}; each further file becomes {id}/{file name}. Form fields
+// (alt text, captions) are stored as metadata on every file, on top of the
+// X-Meta-* headers; fields sent after a file are added to it afterwards.
+func (s *Server) createFromForm(w http.ResponseWriter, r *http.Request, tenant, contentType, id string, state storage.State, headers map[string]string) {
+	ctx := r.Context()
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<30) // 10GB max
+	defer r.Body.Close()
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_form", "Failed to parse multipart form")
+		return
+	}
+
+	policy := s.settings.get(ctx, tenant).IDPolicy
+	dryRun := isDryRun(r)
+	createOnly := s.createOnly(r, tenant)
+	fields := make(map[string]string)
+	var results []*uploadResult
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_form", fmt.Sprintf("Failed to read multipart form: %v", err))
+			return
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFormField+1))
+			part.Close()
+			if err != nil || len(value) > maxFormField {
+				writeError(w, http.StatusBadRequest, "invalid_form", fmt.Sprintf("Form field '%s' is too large (max %d bytes)", part.FormName(), maxFormField))
+				return
+			}
+			fields[strings.ToLower(part.FormName())] = string(value)
+			continue
+		}
+
+		itemID := id
+		if len(results) > 0 {
+			itemID = policy.normalize(path.Join(id, uploadName(part.FileName())))
+		}
+		results = append(results, s.storeUpload(r, tenant, contentType, itemID, state, part, headers, fields, policy, dryRun, createOnly))
+		part.Close()
+	}
+
+	if len(results) == 0 {
+		writeError(w, http.StatusBadRequest, "missing_file", "No file provided")
+		return
+	}
+
+	// Add fields that arrived after a file was stored
+	for _, result := range results {
+		if result.Status != "created" || dryRun {
+			continue
+		}
+		late := make(map[string]string)
+		for key, value := range fields {
+			if stored, ok := result.fields[key]; !ok || stored != value {
+				late[key] = value
+			}
+		}
+		if len(late) == 0 {
+			continue
+		}
+		if err := s.storage.UpdateMetadata(ctx, tenant, contentType, result.ID, result.ext, state, late); err != nil {
+			log.Error("Failed to add form fields to %s/%s: %v", contentType, result.ID, err)
+			result.Error = "storage_error"
+			result.Message = fmt.Sprintf("Stored, but form fields sent after the file weren't added: %v", err)
+		}
+	}
+
+	s.writeUploadResults(w, id, state, results, dryRun)
+}
+
+// storeUpload stores one file of a multipart upload, running the same hooks,
+// validation, and webhooks as a single-item create. A create-only upload
+// fails the file when its item already exists.
+func (s *Server) storeUpload(r *http.Request, tenant, contentType, id string, state storage.State, part *multipart.Part, headers, fields map[string]string, policy *IDPolicy, dryRun, createOnly bool) *uploadResult {
+	result := &uploadResult{
+		Part:     part.FormName(),
+		Filename: part.FileName(),
+		ID:       id,
+		MimeType: partType(part),
+	}
+	result.ext = getExtensionFromMime(result.MimeType)
+	if createOnly {
+		unlock := lockWrite(tenant + "/" + contentType + "/" + id + "/" + string(state))
+		defer unlock()
+		if _, version, exists := s.existingItem(r.Context(), tenant, contentType, id, result.ext, state); exists {
+			result.fail(http.StatusConflict, "already_exists", fmt.Sprintf("Content '%s' already exists", id))
+			result.Version = version
+			return result
+		}
+	}
+	return s.storeFile(r.Context(), tenant, contentType, state, result, part, headers, fields, policy, dryRun)
+}
+
+// storeFile stores a file read from an upload or archive as the item
+// result.ID, recording the outcome on result
+func (s *Server) storeFile(ctx context.Context, tenant, contentType string, state storage.State, result *uploadResult, file io.Reader, headers, fields map[string]string, policy *IDPolicy, dryRun bool) *uploadResult {
+	id := result.ID
+	if !storage.ValidKeyPath(id) {
+		result.fail(http.StatusBadRequest, "invalid_id", fmt.Sprintf("Invalid file name: %q", result.Filename))
+		return result
+	}
+	if msg := policy.check(id); msg != "" {
+		result.fail(http.StatusBadRequest, "invalid_id", msg)
+		return result
+	}
+
+	result.fields = make(map[string]string, len(fields))
+	for key, value := range fields {
+		result.fields[key] = value
+	}
+	metadata := mergeMetadata(headers, result.fields)
+
+	// Run plugin hooks (may modify or reject the write)
+	hookReq := &plugin.Request{
+		Operation:   plugin.OpCreate,
+		Tenant:      tenant,
+		Type:        contentType,
+		ID:          id,
+		State:       string(state),
+		ContentType: result.MimeType,
+		Metadata:    metadata,
+	}
+	counter := &countingReader{r: file}
+	body, contentLength, err := s.applyBeforeHooks(ctx, hookReq, counter, -1)
+	if err != nil {
+		if rejection, ok := err.(*plugin.Rejection); ok {
+			result.fail(http.StatusUnprocessableEntity, "rejected_by_policy", rejection.Error())
+		} else {
+			result.fail(http.StatusBadRequest, "invalid_body", "Failed to read file")
+		}
+		return result
+	}
+	metadata = hookReq.Metadata
+
+	// Validate JSON content against the type's schema (dry runs, or validate_writes)
+	body, contentLength, validationErrors, err := s.validateBody(ctx, tenant, contentType, result.MimeType, body, contentLength, dryRun)
+	if errors.Is(err, errDocumentTooLarge) {
+		result.fail(http.StatusRequestEntityTooLarge, "document_too_large", err.Error())
+		return result
+	}
+	if err != nil {
+		result.fail(http.StatusBadRequest, "invalid_body", "Failed to read file")
+		return result
+	}
+	if len(validationErrors) > 0 {
+		result.fail(http.StatusUnprocessableEntity, "validation_failed", "Content does not match schema")
+		result.Details = validationErrors
+		return result
+	}
+	if dryRun {
+		result.Status = "valid"
+		return result
+	}
+
+	// Read-only and role-restricted fields
+	body, violations, err := s.checkFieldPermissions(ctx, tenant, contentType, id, result.ext, state, result.MimeType, body)
+	if err != nil {
+		result.fail(http.StatusBadRequest, "invalid_body", "Failed to read file")
+		return result
+	}
+	if len(violations) > 0 {
+		result.fail(http.StatusForbidden, "field_permission_denied", "Content changes fields the caller may not change")
+		result.Details = violations
+		return result
+	}
+
+	// Encrypted fields
+	body, contentLength, err = s.encryptBody(ctx, tenant, contentType, result.MimeType, body, contentLength)
+	switch {
+	case errors.Is(err, errNoFieldKeyring):
+		result.fail(http.StatusServiceUnavailable, "encryption_unavailable", err.Error())
+		return result
+	case errors.Is(err, errInvalidSealedField):
+		result.fail(http.StatusUnprocessableEntity, "invalid_sealed_field", err.Error())
+		return result
+	case err != nil:
+		result.fail(http.StatusInternalServerError, "encryption_error", "Failed to encrypt fields")
+		return result
+	}
+
+	// Fingerprint content for duplicate detection
+	body, fp, err := fingerprintBody(body, result.MimeType)
+	if err != nil {
+		result.fail(http.StatusBadRequest, "invalid_body", "Failed to read file")
+		return result
+	}
+	metadata = tagLanguage(metadata, fp.content, result.MimeType)
+
+	item, err := s.storage.PutStream(ctx, tenant, contentType, id, result.ext, body, contentLength, result.MimeType, state, metadata)
+	if err != nil {
+		result.fail(http.StatusInternalServerError, "storage_error", err.Error())
+		return result
+	}
+	s.storeFingerprint(tenant, contentType, id, state, item, fp)
+
+	log.Debug("Created content: %s (%s, %d bytes)", item.Key, result.MimeType, item.Size)
+
+	s.triggerWebhooks(tenant, "create", contentType, id, filepath.Base(item.Key), result.MimeType)
+	plugin.RunAfter(hookReq, &plugin.Result{Key: item.Key, Version: item.VersionID})
+	s.processVideo(ctx, tenant, contentType, id, result.ext, state, result.MimeType)
+	s.indexContent(ctx, tenant, contentType, id, result.ext, state, result.MimeType)
+
+	result.Status = "created"
+	result.Size = item.Size
+	if result.Size < 0 {
+		result.Size = counter.n
+	}
+	result.Version = item.VersionID
+	return result
+}
+
+// writeUploadResults responds to a multipart upload: 201 when every file was
+// stored, the failure's status when none was, and 207 when some were
+func (s *Server) writeUploadResults(w http.ResponseWriter, id string, state storage.State, results []*uploadResult, dryRun bool) {
+	var stored int
+	var failed *uploadResult
+	for _, result := range results {
+		if result.Status == "failed" {
+			if failed == nil {
+				failed = result
+			}
+		} else {
+			stored++
+		}
+	}
+
+	response := map[string]interface{}{
+		"id":    id,
+		"state": string(state),
+		"parts": results,
+	}
+	if results[0].Version != "" {
+		response["version"] = results[0].Version
+	}
+
+	done := "created"
+	status := http.StatusCreated
+	if dryRun {
+		done = "valid"
+		status = http.StatusOK
+		response["dry_run"] = true
+	}
+	switch {
+	case failed == nil && dryRun:
+		response["message"] = "Content is valid"
+	case failed == nil:
+		response["message"] = "Content created successfully"
+	case stored == 0:
+		status = failed.code
+		response["error"] = failed.Error
+		response["message"] = failed.Message
+		response["code"] = failed.code
+	default:
+		status = http.StatusMultiStatus
+		response["message"] = fmt.Sprintf("%d of %d files %s", stored, len(results), done)
+	}
+	writeJSON(w, status, response)
+}
@@ -0,0 +1,322 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"velocity/internal/log"
+	"velocity/internal/models"
+	"velocity/internal/storage"
+)
+
+// maxValidatedSize caps how much of a JSON body is buffered for schema validation
+const maxValidatedSize = 10 << 20 // 10MB
+
+// isDryRun reports whether the request asked to validate without persisting
+func isDryRun(r *http.Request) bool {
+	return r.URL.Query().Get("dry-run") == "true"
+}
+
+// writeValidationError writes a 422 response listing validation failures
+func writeValidationError(w http.ResponseWriter, errs []string) {
+	writeJSON(w, http.StatusUnprocessableEntity, models.ErrorResponse{
+		Error:   "validation_failed",
+		Message: "Content does not match schema",
+		Code:    http.StatusUnprocessableEntity,
+		Details: errs,
+	})
+}
+
+// loadSchema returns the parsed schema for a content type, with its extends
+// and mixins resolved, or nil if none is defined
+func (s *Server) loadSchema(ctx context.Context, tenant, contentType string) *models.Schema {
+	stored, err := s.storage.GetSchema(ctx, tenant, contentType)
+	if err != nil {
+		return nil
+	}
+
+	var schema models.Schema
+	if err := json.Unmarshal(stored.Content, &schema); err != nil {
+		return nil
+	}
+	if schema.Extends == "" && len(schema.Mixins) == 0 {
+		return &schema
+	}
+
+	// A parent that has since gone missing leaves the schema's own fields
+	resolved, err := s.resolveSchema(ctx, tenant, contentType, stored.IsGlobal, stored.Content)
+	if err != nil {
+		log.Error("Failed to resolve schema %s for tenant %s: %v", contentType, tenant, err)
+		return &schema
+	}
+	var flat models.Schema
+	if err := json.Unmarshal(resolved, &flat); err != nil {
+		return &schema
+	}
+	return &flat
+}
+
+// errDocumentTooLarge is returned for JSON bodies too large to buffer for validation
+var errDocumentTooLarge = fmt.Errorf("document exceeds %d bytes and cannot be validated", maxValidatedSize)
+
+// writeBodyError writes the response for a body that couldn't be read for validation
+func writeBodyError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errDocumentTooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, "document_too_large", err.Error())
+		return
+	}
+	writeError(w, http.StatusBadRequest, "invalid_body", "Failed to read request body")
+}
+
+// validatesWrites reports whether a tenant's writes are validated against schemas
+func (s *Server) validatesWrites(ctx context.Context, tenant string) bool {
+	return s.settings.get(ctx, tenant).ValidateWrites
+}
+
+// validateBody buffers a JSON body and validates it against the content type's
+// schema, on dry runs and for tenants with validate_writes set. Other bodies
+// (and JSON bodies without a schema, unless dryRun) are passed through
+// untouched so they keep streaming. Returns the body to store and any
+// validation errors; errDocumentTooLarge if it's too large to validate.
+func (s *Server) validateBody(ctx context.Context, tenant, contentType, mimeType string, body io.Reader, contentLength int64, dryRun bool) (io.Reader, int64, []string, error) {
+	if !isJSONContent(mimeType) || (!dryRun && !s.validatesWrites(ctx, tenant)) {
+		return body, contentLength, nil, nil
+	}
+
+	schema := s.loadSchema(ctx, tenant, contentType)
+	if schema == nil && !dryRun {
+		return body, contentLength, nil, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxValidatedSize+1))
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if len(data) > maxValidatedSize {
+		return nil, 0, nil, errDocumentTooLarge
+	}
+
+	return bytes.NewReader(data), int64(len(data)), validateDocument(schema, data), nil
+}
+
+// validateDocument checks a JSON document against a schema (syntax only if schema is nil)
+func validateDocument(schema *models.Schema, data []byte) []string {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+
+	if schema == nil || len(schema.Fields) == 0 {
+		return nil
+	}
+
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return []string{"document must be a JSON object"}
+	}
+
+	return validateFields(schema.Fields, obj, "")
+}
+
+// validateFields checks required fields and field types, recursing into objects
+func validateFields(fields map[string]models.FieldDef, obj map[string]interface{}, prefix string) []string {
+	var errs []string
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		def := fields[name]
+		path := prefix + name
+
+		value, present := obj[name]
+		if !present || value == nil {
+			if def.Required {
+				errs = append(errs, fmt.Sprintf("%s: required field is missing", path))
+			}
+			continue
+		}
+
+		// Sealed values were checked before they were encrypted
+		if def.Encrypted && isSealedValue(value) {
+			continue
+		}
+
+		if !matchesFieldType(def.Type, value) {
+			errs = append(errs, fmt.Sprintf("%s: expected %s", path, def.Type))
+			continue
+		}
+
+		switch def.Type {
+		case "object":
+			if len(def.Properties) > 0 {
+				errs = append(errs, validateFields(def.Properties, value.(map[string]interface{}), path+".")...)
+			}
+		case "array":
+			if def.Items != "" {
+				for i, item := range value.([]interface{}) {
+					if !matchesFieldType(def.Items, item) {
+						errs = append(errs, fmt.Sprintf("%s[%d]: expected %s", path, i, def.Items))
+					}
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+// matchesFieldType reports whether a decoded JSON value matches a schema field type.
+// Unknown types are accepted so schemas can carry UI-only types.
+func matchesFieldType(fieldType string, value interface{}) bool {
+	switch fieldType {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == float64(int64(n))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "reference":
+		// type/id, or type/id@version to pin a version
+		ref, ok := value.(string)
+		if !ok {
+			return false
+		}
+		_, ok = parseReference(ref)
+		return ok
+	case "image", "file":
+		// References are stored as a URL/ID string or an object
+		switch value.(type) {
+		case string, map[string]interface{}:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// subscribedWebhooks returns the IDs of webhooks that would fire for an event
+func (s *Server) subscribedWebhooks(ctx context.Context, tenant, event string) []string {
+	ids := []string{}
+	webhooks, err := s.storage.ListWebhooks(ctx, tenant)
+	if err != nil {
+		return ids
+	}
+	for _, webhook := range webhooks {
+		for _, e := range webhook.Events {
+			if e == event {
+				ids = append(ids, webhook.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// writeDryRun reports what a create/update would do without persisting anything
+func (s *Server) writeDryRun(w http.ResponseWriter, r *http.Request, tenant, contentType, id, ext string, state storage.State, event string, errs []string) {
+	ctx := r.Context()
+
+	result := map[string]interface{}{
+		"dry_run":  true,
+		"valid":    len(errs) == 0,
+		"id":       id,
+		"state":    string(state),
+		"event":    event,
+		"exists":   false,
+		"webhooks": s.subscribedWebhooks(ctx, tenant, event),
+	}
+	if len(errs) > 0 {
+		result["errors"] = errs
+	}
+
+	if stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, ext, state); err == nil {
+		stream.Body.Close()
+		result["exists"] = true
+		result["current_version"] = stream.VersionID
+	}
+	result["creates_version"] = state == storage.StateLive
+
+	status := http.StatusOK
+	if len(errs) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, result)
+}
+
+// transitionDryRun reports whether a transition would succeed without performing it.
+// Runs the same guards as the storage transition plus schema validation when publishing.
+func (s *Server) transitionDryRun(w http.ResponseWriter, r *http.Request, tenant, contentType, id, ext string, fromState, toState storage.State, author, message string) {
+	ctx := r.Context()
+	var errs []string
+
+	if fromState == toState {
+		errs = append(errs, "source and target states are the same")
+	}
+
+	if toState == storage.StateLive {
+		if violation := s.checkPublishPolicy(ctx, tenant, author, message); violation != "" {
+			errs = append(errs, violation)
+		}
+		if violation := s.checkImageMetadata(ctx, tenant, contentType, id, fromState); violation != "" {
+			errs = append(errs, violation)
+		}
+	}
+
+	if fromState != storage.StateLive {
+		if unresolved, err := s.storage.HasUnresolvedComments(ctx, tenant, contentType, id, fromState); err == nil && unresolved {
+			errs = append(errs, fmt.Sprintf("unresolved comments on %s content", fromState))
+		}
+	}
+
+	item, err := s.storage.Get(ctx, tenant, contentType, id, ext, fromState)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("content not found in %s state", fromState))
+	} else if toState == storage.StateLive && isJSONContent(item.ContentType) {
+		errs = append(errs, validateDocument(s.loadSchema(ctx, tenant, contentType), item.Content)...)
+	}
+
+	result := map[string]interface{}{
+		"dry_run":         true,
+		"valid":           len(errs) == 0,
+		"id":              id,
+		"from":            string(fromState),
+		"to":              string(toState),
+		"creates_version": toState == storage.StateLive,
+		"webhooks":        []string{},
+	}
+	if toState == storage.StateLive {
+		result["event"] = "publish"
+		result["webhooks"] = s.subscribedWebhooks(ctx, tenant, "publish")
+	}
+	if len(errs) > 0 {
+		result["errors"] = errs
+	}
+
+	status := http.StatusOK
+	if len(errs) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, result)
+}
@@ -0,0 +1,605 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const (
+	variantsCollection      = "variants"
+	variantBodiesCollection = "variant-bodies"
+	variantsCacheTTL        = 30 * time.Second
+	maxVariantSize          = 16 << 20 // 16MB
+
+	// controlVariant names the item's own content in selection and cookies
+	controlVariant = "control"
+
+	variantCookieMaxAge = 30 * 24 * 60 * 60
+)
+
+// Variant statuses
+const (
+	variantDraft = "draft"
+	variantLive  = "live"
+)
+
+var variantNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// contentVariant is an alternative body for a content item. Live variants are
+// served to a share of visitors on the public content route.
+type contentVariant struct {
+	Name        string            `json:"name"`
+	Status      string            `json:"status"` // draft or live
+	Weight      int               `json:"weight"` // percent of visitors while live
+	ContentType string            `json:"content_type"`
+	Size        int64             `json:"size"`
+	ETag        string            `json:"etag"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	PublishedAt *time.Time        `json:"published_at,omitempty"`
+	Targeting   []targetingRule   `json:"targeting,omitempty"` // serve to matching visitors instead of by weight
+}
+
+// variantSet is the variants of one content item, stored as a document
+type variantSet struct {
+	Type     string                     `json:"type"`
+	ID       string                     `json:"id"`
+	Variants map[string]*contentVariant `json:"variants"`
+}
+
+func variantSetID(contentType, id string) string {
+	return contentType + "/" + id
+}
+
+func variantBodyID(contentType, id, name string) string {
+	return contentType + "/" + id + "/" + name
+}
+
+// live returns the live variants in name order
+func (vs *variantSet) live() []*contentVariant {
+	var live []*contentVariant
+	for _, v := range vs.Variants {
+		if v.Status == variantLive {
+			live = append(live, v)
+		}
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].Name < live[j].Name })
+	return live
+}
+
+// weighted returns the live variants picked by weight (those without targeting)
+func (vs *variantSet) weighted() []*contentVariant {
+	var weighted []*contentVariant
+	for _, v := range vs.live() {
+		if len(v.Targeting) == 0 {
+			weighted = append(weighted, v)
+		}
+	}
+	return weighted
+}
+
+// targeted returns the live variants served by targeting rules
+func (vs *variantSet) targeted() []*contentVariant {
+	var targeted []*contentVariant
+	for _, v := range vs.live() {
+		if len(v.Targeting) > 0 {
+			targeted = append(targeted, v)
+		}
+	}
+	return targeted
+}
+
+// controlWeight is the percent of untargeted visitors that get the item's own content
+func (vs *variantSet) controlWeight() int {
+	weight := 100
+	for _, v := range vs.weighted() {
+		weight -= v.Weight
+	}
+	if weight < 0 {
+		return 0
+	}
+	return weight
+}
+
+// sorted returns all variants in name order
+func (vs *variantSet) sorted() []*contentVariant {
+	variants := make([]*contentVariant, 0, len(vs.Variants))
+	for _, v := range vs.Variants {
+		variants = append(variants, v)
+	}
+	sort.Slice(variants, func(i, j int) bool { return variants[i].Name < variants[j].Name })
+	return variants
+}
+
+type cachedVariants struct {
+	sets      map[string]*variantSet
+	expiresAt time.Time
+}
+
+// variantStore loads a tenant's variant sets with a short-lived per-node
+// cache, so the public content route can check for variants on every request
+type variantStore struct {
+	mu      sync.RWMutex
+	cache   map[string]*cachedVariants
+	storage storage.Storage
+}
+
+func newVariantStore(s storage.Storage) *variantStore {
+	return &variantStore{
+		cache:   make(map[string]*cachedVariants),
+		storage: s,
+	}
+}
+
+// all returns a tenant's variant sets keyed by {type}/{id}
+func (vst *variantStore) all(ctx context.Context, tenant string) map[string]*variantSet {
+	vst.mu.RLock()
+	cached, ok := vst.cache[tenant]
+	vst.mu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.sets
+	}
+
+	sets := make(map[string]*variantSet)
+	ids, _ := vst.storage.ListDocuments(ctx, tenant, variantsCollection)
+	for _, id := range ids {
+		data, err := vst.storage.GetDocument(ctx, tenant, variantsCollection, id)
+		if err != nil {
+			continue
+		}
+		var set variantSet
+		if err := json.Unmarshal(data, &set); err != nil {
+			log.Error("Failed to parse variants %s for tenant %s: %v", id, tenant, err)
+			continue
+		}
+		sets[id] = &set
+	}
+
+	vst.mu.Lock()
+	vst.cache[tenant] = &cachedVariants{sets: sets, expiresAt: time.Now().Add(variantsCacheTTL)}
+	vst.mu.Unlock()
+	return sets
+}
+
+// get returns an item's variant set, or nil if it has none
+func (vst *variantStore) get(ctx context.Context, tenant, contentType, id string) *variantSet {
+	return vst.all(ctx, tenant)[variantSetID(contentType, id)]
+}
+
+// update applies fn to an item's variant set and stores the result (deleting
+// it once empty), dropping the tenant's cache. The set is read from storage
+// rather than the cache so edits made through other nodes aren't lost.
+func (vst *variantStore) update(ctx context.Context, tenant, contentType, id string, fn func(set *variantSet) error) (*variantSet, error) {
+	unlock := lockWrite(tenant + "/" + variantsCollection + "/" + contentType + "/" + id)
+	defer unlock()
+
+	set := &variantSet{Type: contentType, ID: id, Variants: make(map[string]*contentVariant)}
+	if data, err := vst.storage.GetDocument(ctx, tenant, variantsCollection, variantSetID(contentType, id)); err == nil {
+		if err := json.Unmarshal(data, set); err != nil {
+			return nil, fmt.Errorf("failed to parse variants: %w", err)
+		}
+		if set.Variants == nil {
+			set.Variants = make(map[string]*contentVariant)
+		}
+	}
+
+	if err := fn(set); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if len(set.Variants) == 0 {
+		err = vst.storage.DeleteDocument(ctx, tenant, variantsCollection, variantSetID(contentType, id))
+	} else {
+		var data []byte
+		if data, err = json.Marshal(set); err == nil {
+			err = vst.storage.PutDocument(ctx, tenant, variantsCollection, variantSetID(contentType, id), data)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save variants: %w", err)
+	}
+
+	vst.mu.Lock()
+	delete(vst.cache, tenant)
+	vst.mu.Unlock()
+	return set, nil
+}
+
+// variantStream opens a variant's body as a content stream
+func (s *Server) variantStream(ctx context.Context, tenant, contentType, id string, v *contentVariant) (*storage.ContentStream, error) {
+	data, err := s.storage.GetDocument(ctx, tenant, variantBodiesCollection, variantBodyID(contentType, id, v.Name))
+	if err != nil {
+		return nil, err
+	}
+	return &storage.ContentStream{
+		Body:         io.NopCloser(bytes.NewReader(data)),
+		ContentType:  v.ContentType,
+		LastModified: v.UpdatedAt,
+		Size:         int64(len(data)),
+		ETag:         v.ETag,
+		Metadata:     v.Metadata,
+	}, nil
+}
+
+// variantCookie names the cookie that keeps a visitor on one variant of an item
+func variantCookie(contentType, id string) string {
+	h := fnv.New32a()
+	h.Write([]byte(contentType + "/" + id))
+	return fmt.Sprintf("velocity_variant_%08x", h.Sum32())
+}
+
+// pickVariant chooses a variant by weight, the rest of visitors getting control
+func pickVariant(set *variantSet) string {
+	n := rand.Intn(100)
+	for _, v := range set.weighted() {
+		if n < v.Weight {
+			return v.Name
+		}
+		n -= v.Weight
+	}
+	return controlVariant
+}
+
+// selectVariant picks the variant of a live item to serve on the public route:
+// the one named by ?variant=, the first targeted variant whose rules match, the
+// visitor's sticky choice, or a weighted random choice. It returns a nil stream
+// for the item's own content, and reports whether the response depends on the
+// visitor's cookie (so it mustn't be shared).
+func (s *Server) selectVariant(w http.ResponseWriter, r *http.Request, tenant, contentType, id string) (*storage.ContentStream, bool, error) {
+	set := s.variants.get(r.Context(), tenant, contentType, id)
+	if set == nil {
+		return nil, false, nil
+	}
+
+	if name := r.URL.Query().Get("variant"); name != "" {
+		if name == controlVariant {
+			w.Header().Set("X-Variant", controlVariant)
+			return nil, false, nil
+		}
+		v, ok := set.Variants[name]
+		if !ok || v.Status != variantLive {
+			return nil, false, fmt.Errorf("variant '%s' not found", name)
+		}
+		w.Header().Set("X-Variant", name)
+		stream, err := s.variantStream(r.Context(), tenant, contentType, id, v)
+		return stream, false, err
+	}
+
+	// Targeted variants depend only on request headers, so shared caches can
+	// keep a copy per combination named in Vary
+	if targeted := set.targeted(); len(targeted) > 0 {
+		for _, header := range varyHeaders(targeted) {
+			w.Header().Add("Vary", header)
+		}
+		visitor := newVisitor(r)
+		for _, v := range targeted {
+			if v.matchesTargeting(visitor) {
+				w.Header().Set("X-Variant", v.Name)
+				stream, err := s.variantStream(r.Context(), tenant, contentType, id, v)
+				return stream, false, err
+			}
+		}
+	}
+
+	weighted := set.weighted()
+	if len(weighted) == 0 {
+		if len(set.live()) > 0 {
+			w.Header().Set("X-Variant", controlVariant)
+		}
+		return nil, false, nil
+	}
+
+	cookie := variantCookie(contentType, id)
+	name := ""
+	if c, err := r.Cookie(cookie); err == nil {
+		if c.Value == controlVariant {
+			name = c.Value
+		}
+		for _, v := range weighted {
+			if v.Name == c.Value {
+				name = c.Value
+			}
+		}
+	}
+	if name == "" {
+		name = pickVariant(set)
+		http.SetCookie(w, &http.Cookie{
+			Name:     cookie,
+			Value:    name,
+			Path:     "/content/" + tenant + "/",
+			MaxAge:   variantCookieMaxAge,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	w.Header().Add("Vary", "Cookie")
+	w.Header().Set("X-Variant", name)
+	if name == controlVariant {
+		return nil, true, nil
+	}
+	stream, err := s.variantStream(r.Context(), tenant, contentType, id, set.Variants[name])
+	return stream, true, err
+}
+
+// =============================================================================
+// Variant Handlers
+// =============================================================================
+
+// listVariantsHandler handles GET /api/content/{type}/{id}/variants
+func (s *Server) listVariantsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id := vars["type"], vars["id"]
+
+	set := s.variants.get(r.Context(), s.getTenant(r), contentType, id)
+	if set == nil {
+		set = &variantSet{Type: contentType, ID: id}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"type":           contentType,
+		"id":             id,
+		"variants":       set.sorted(),
+		"control_weight": set.controlWeight(),
+	})
+}
+
+// getVariantHandler handles GET /api/content/{type}/{id}/variants/{name}
+// The variant's body is served by GET /api/content/{type}/{id}?variant={name}.
+func (s *Server) getVariantHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	set := s.variants.get(r.Context(), s.getTenant(r), vars["type"], vars["id"])
+	if set == nil || set.Variants[vars["name"]] == nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Variant '%s' not found", vars["name"]))
+		return
+	}
+	writeJSON(w, http.StatusOK, set.Variants[vars["name"]])
+}
+
+// putVariantHandler handles PUT /api/content/{type}/{id}/variants/{name}
+// The body is the variant's content; X-Meta-* headers set its metadata and
+// ?weight= its share of visitors once live. New variants start as drafts.
+func (s *Server) putVariantHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id, name := vars["type"], vars["id"], vars["name"]
+	tenant := s.getTenant(r)
+
+	if !variantNamePattern.MatchString(name) || name == controlVariant {
+		writeError(w, http.StatusBadRequest, "invalid_name", "Variant names are lowercase letters, digits, '-' and '_' (and not 'control')")
+		return
+	}
+	if !s.contentExists(r.Context(), tenant, contentType, id) {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Content '%s' not found", id))
+		return
+	}
+
+	weight := -1
+	if value := r.URL.Query().Get("weight"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > 100 {
+			writeError(w, http.StatusBadRequest, "invalid_weight", "Weight must be between 0 and 100")
+			return
+		}
+		weight = n
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxVariantSize+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "Failed to read body")
+		return
+	}
+	if len(body) > maxVariantSize {
+		writeError(w, http.StatusRequestEntityTooLarge, "too_large", fmt.Sprintf("Variants are limited to %d bytes", maxVariantSize))
+		return
+	}
+	mimeType := r.Header.Get("Content-Type")
+	if mimeType == "" {
+		mimeType = "application/json"
+	}
+	if isJSONContent(mimeType) && !json.Valid(body) {
+		writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	if err := s.storage.PutDocument(r.Context(), tenant, variantBodiesCollection, variantBodyID(contentType, id, name), body); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	set, err := s.variants.update(r.Context(), tenant, contentType, id, func(set *variantSet) error {
+		now := time.Now().UTC()
+		v, ok := set.Variants[name]
+		if !ok {
+			v = &contentVariant{Name: name, Status: variantDraft, Weight: 50, CreatedAt: now}
+			set.Variants[name] = v
+		}
+		if weight >= 0 {
+			v.Weight = weight
+		}
+		if metadata := extractMetadata(r); metadata != nil {
+			v.Metadata = metadata
+		}
+		v.ContentType = mimeType
+		v.Size = int64(len(body))
+		v.ETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+		v.UpdatedAt = now
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	log.Info("Saved variant %s of %s/%s for tenant %s", name, contentType, id, tenant)
+	writeJSON(w, http.StatusOK, set.Variants[name])
+}
+
+// publishVariantHandler handles POST /api/content/{type}/{id}/variants/{name}/publish
+// and /unpublish. Publishing takes an optional body {"weight": 20}; live
+// weights may not add up to more than 100.
+func (s *Server) publishVariantHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id, name := vars["type"], vars["id"], vars["name"]
+	tenant := s.getTenant(r)
+	publish := vars["action"] == "publish"
+
+	var req struct {
+		Weight *int `json:"weight"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+			return
+		}
+	}
+	if req.Weight != nil && (*req.Weight < 0 || *req.Weight > 100) {
+		writeError(w, http.StatusBadRequest, "invalid_weight", "Weight must be between 0 and 100")
+		return
+	}
+
+	var status int
+	set, err := s.variants.update(r.Context(), tenant, contentType, id, func(set *variantSet) error {
+		v, ok := set.Variants[name]
+		if !ok {
+			status = http.StatusNotFound
+			return fmt.Errorf("variant '%s' not found", name)
+		}
+		if req.Weight != nil {
+			v.Weight = *req.Weight
+		}
+		if !publish {
+			v.Status = variantDraft
+			return nil
+		}
+
+		total := 0
+		for _, other := range set.weighted() {
+			if other.Name != name {
+				total += other.Weight
+			}
+		}
+		if len(v.Targeting) == 0 && total+v.Weight > 100 {
+			status = http.StatusUnprocessableEntity
+			return fmt.Errorf("live variant weights would total %d%% (at most 100%%)", total+v.Weight)
+		}
+		now := time.Now().UTC()
+		v.Status = variantLive
+		v.PublishedAt = &now
+		return nil
+	})
+	if err != nil {
+		switch status {
+		case http.StatusNotFound:
+			writeError(w, status, "not_found", err.Error())
+		case http.StatusUnprocessableEntity:
+			writeError(w, status, "invalid_weights", err.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		}
+		return
+	}
+
+	log.Info("Variant %s of %s/%s is now %s for tenant %s", name, contentType, id, set.Variants[name].Status, tenant)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"variant":        set.Variants[name],
+		"control_weight": set.controlWeight(),
+	})
+}
+
+// deleteVariantHandler handles DELETE /api/content/{type}/{id}/variants/{name}
+func (s *Server) deleteVariantHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contentType, id, name := vars["type"], vars["id"], vars["name"]
+	tenant := s.getTenant(r)
+
+	found := false
+	_, err := s.variants.update(r.Context(), tenant, contentType, id, func(set *variantSet) error {
+		_, found = set.Variants[name]
+		delete(set.Variants, name)
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Variant '%s' not found", name))
+		return
+	}
+	if err := s.storage.DeleteDocument(r.Context(), tenant, variantBodiesCollection, variantBodyID(contentType, id, name)); err != nil {
+		log.Error("Failed to delete body of variant %s of %s/%s: %v", name, contentType, id, err)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"type":    contentType,
+		"id":      id,
+		"name":    name,
+		"message": "Variant deleted",
+	})
+}
+
+// contentExists reports whether an item exists in any state
+func (s *Server) contentExists(ctx context.Context, tenant, contentType, id string) bool {
+	for _, state := range []storage.State{storage.StateLive, storage.StateDraft, storage.StatePending} {
+		if stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, "", state); err == nil {
+			stream.Body.Close()
+			return true
+		}
+	}
+	return false
+}
+
+// serveVariant writes a variant's body for GET /api/content/{type}/{id}?variant={name}
+// (any status, so drafts can be reviewed before publishing)
+func (s *Server) serveVariant(w http.ResponseWriter, r *http.Request, tenant, contentType, id, name string) {
+	set := s.variants.get(r.Context(), tenant, contentType, id)
+	if set == nil || set.Variants[name] == nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Variant '%s' not found", name))
+		return
+	}
+	v := set.Variants[name]
+
+	stream, err := s.variantStream(r.Context(), tenant, contentType, id, v)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Variant '%s' not found", name))
+		return
+	}
+	defer stream.Body.Close()
+
+	s.applyRenderPlugins(r.Context(), tenant, contentType, id, stream)
+
+	if checkNotModified(r, stream.ETag, stream.LastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("ETag", stream.ETag)
+	w.Header().Set("Last-Modified", stream.LastModified.UTC().Format(time.RFC1123))
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("X-Variant", name)
+	w.Header().Set("X-Variant-Status", v.Status)
+	w.Header().Set("Content-Type", stream.ContentType)
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", stream.Size))
+	w.WriteHeader(http.StatusOK)
+	copyBuffered(w, stream.Body)
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"velocity/internal/log"
+	"velocity/internal/media"
+	"velocity/internal/storage"
+)
+
+const (
+	// transcodeJobKind is the job kind of video transcodes
+	transcodeJobKind = "transcode"
+
+	// videoProcessTimeout bounds probing a video and generating its poster
+	videoProcessTimeout = 5 * time.Minute
+
+	// transcodeCancelWait bounds waiting for a superseded transcode to stop
+	transcodeCancelWait = time.Minute
+)
+
+// Metadata keys set on uploaded videos
+const (
+	videoDurationKey = "duration" // seconds
+	videoWidthKey    = "width"
+	videoHeightKey   = "height"
+)
+
+// processVideo reads an uploaded video's duration and frame size into its
+// metadata and generates its poster rendition, in the background. With a
+// transcoder configured, it then starts a transcode job for the video.
+// Without a video processor, only MP4 and QuickTime headers are read.
+func (s *Server) processVideo(ctx context.Context, tenant, contentType, id, ext string, state storage.State, mimeType string) {
+	if !media.IsVideo(mimeType) || (s.config.Video == nil && !media.IsMP4(mimeType)) {
+		return
+	}
+
+	background := detachedContext(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(background, videoProcessTimeout)
+		defer cancel()
+
+		info, err := s.probeVideo(ctx, tenant, contentType, id, ext, state)
+		if err != nil {
+			log.Error("Failed to process video %s/%s: %v", contentType, id, err)
+			return
+		}
+		if s.config.Transcoder != nil {
+			s.startTranscode(background, tenant, contentType, id, ext, state, info)
+		}
+	}()
+}
+
+// probeVideo stores a video's duration and frame size as metadata and, with
+// a video processor configured, generates its poster
+func (s *Server) probeVideo(ctx context.Context, tenant, contentType, id, ext string, state storage.State) (*media.VideoInfo, error) {
+	dir, err := os.MkdirTemp("", "velocity-video-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	file, _, err := s.downloadContent(ctx, tenant, contentType, id, ext, state, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var info *media.VideoInfo
+	if s.config.Video != nil {
+		info, err = s.config.Video.Probe(ctx, file)
+	} else {
+		info, err = probeMP4File(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read video details: %w", err)
+	}
+
+	metadata := map[string]string{videoDurationKey: strconv.FormatFloat(info.Duration, 'f', 3, 64)}
+	if info.Width > 0 && info.Height > 0 {
+		metadata[videoWidthKey] = strconv.Itoa(info.Width)
+		metadata[videoHeightKey] = strconv.Itoa(info.Height)
+	}
+	if err := s.storage.UpdateMetadata(ctx, tenant, contentType, id, ext, state, metadata); err != nil {
+		return nil, fmt.Errorf("failed to store video details: %w", err)
+	}
+	log.Debug("Read video details of %s/%s: %.3fs, %dx%d", contentType, id, info.Duration, info.Width, info.Height)
+
+	if s.config.Video == nil {
+		return info, nil
+	}
+	poster, err := s.config.Video.Poster(ctx, file, dir, info)
+	if err != nil {
+		return info, fmt.Errorf("failed to generate poster: %w", err)
+	}
+	if err := s.storeVideoOutput(ctx, tenant, contentType, id, ext, state, "", s.config.Video.Name(), poster); err != nil {
+		return info, fmt.Errorf("failed to store poster: %w", err)
+	}
+	return info, nil
+}
+
+// startTranscode starts a job that transcodes a video and stores the
+// results as renditions. A transcode still running for an earlier upload of
+// the item is cancelled first.
+func (s *Server) startTranscode(ctx context.Context, tenant, contentType, id, ext string, state storage.State, info *media.VideoInfo) {
+	subject := contentType + "/" + id
+	transcode := func(jobCtx context.Context, j *job) (interface{}, error) {
+		// Address the same content root as the write
+		jobCtx = withRequestRoot(jobCtx, ctx)
+
+		dir, err := os.MkdirTemp("", "velocity-transcode-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		j.setMessage("Downloading " + subject)
+		file, stream, err := s.downloadContent(jobCtx, tenant, contentType, id, ext, state, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		j.setMessage("Transcoding " + subject)
+		outputs, err := s.config.Transcoder.Transcode(jobCtx, file, dir, info)
+		if err != nil {
+			return nil, err
+		}
+
+		stored := []string{}
+		for i, output := range outputs {
+			if err := s.storeVideoOutput(jobCtx, tenant, contentType, id, ext, state, stream.ETag, s.config.Transcoder.Name(), output); err != nil {
+				return map[string]interface{}{"renditions": stored}, fmt.Errorf("failed to store %s: %w", output.Name, err)
+			}
+			stored = append(stored, output.Name)
+			j.setProgress(i+1, len(outputs))
+		}
+		return map[string]interface{}{
+			"type":       contentType,
+			"id":         id,
+			"state":      string(state),
+			"renditions": stored,
+		}, nil
+	}
+
+	j, err := s.jobs.startFor(tenant, transcodeJobKind, subject, transcode)
+	if errors.Is(err, errJobRunning) {
+		log.Info("Cancelling transcode job %s of %s for a newer upload", j.id, subject)
+		j.cancel()
+		for deadline := time.Now().Add(transcodeCancelWait); j.running() && time.Now().Before(deadline); {
+			time.Sleep(100 * time.Millisecond)
+		}
+		_, err = s.jobs.startFor(tenant, transcodeJobKind, subject, transcode)
+	}
+	if err != nil {
+		log.Error("Failed to start transcode of %s: %v", subject, err)
+	}
+}
+
+// storeVideoOutput stores a file generated from a video as a rendition. When
+// etag is set, the output is dropped if the video changed after it was read.
+func (s *Server) storeVideoOutput(ctx context.Context, tenant, contentType, id, ext string, state storage.State, etag, generator string, output *media.Output) error {
+	defer os.Remove(output.Path)
+
+	file, err := os.Open(output.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	body, err := io.ReadAll(io.LimitReader(file, maxRenditionSize+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > maxRenditionSize {
+		return fmt.Errorf("%s is larger than %d bytes", output.Name, maxRenditionSize)
+	}
+
+	if etag != "" {
+		current, err := s.storage.FindContentStream(ctx, tenant, contentType, id, ext, state)
+		if err != nil {
+			return err
+		}
+		current.Body.Close()
+		if current.ETag != etag {
+			log.Info("Dropped %s of %s/%s: the video changed while it was generated", output.Name, contentType, id)
+			return nil
+		}
+	}
+
+	r := &rendition{
+		Name:        output.Name,
+		Kind:        output.Kind,
+		ContentType: output.ContentType,
+		Width:       output.Width,
+		Height:      output.Height,
+		Metadata:    map[string]string{"generator": generator},
+	}
+	if err := s.putRendition(ctx, tenant, contentType, id, state, r, body); err != nil {
+		return err
+	}
+	log.Debug("Stored %s rendition %s of %s/%s (%d bytes)", output.Kind, output.Name, contentType, id, len(body))
+	return nil
+}
+
+// downloadContent copies an item's content in a state to a file in dir,
+// returning the file's path and the stream it was read from
+func (s *Server) downloadContent(ctx context.Context, tenant, contentType, id, ext string, state storage.State, dir string) (string, *storage.ContentStream, error) {
+	stream, err := s.storage.FindContentStream(ctx, tenant, contentType, id, ext, state)
+	if err != nil {
+		return "", nil, err
+	}
+	defer stream.Body.Close()
+
+	file, err := os.CreateTemp(dir, "source-*"+path.Ext(stream.Key))
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, stream.Body); err != nil {
+		return "", nil, fmt.Errorf("failed to download content: %w", err)
+	}
+	return file.Name(), stream, nil
+}
+
+// probeMP4File reads video details from the headers of an MP4 or QuickTime file
+func probeMP4File(name string) (*media.VideoInfo, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return media.ProbeMP4(file, stat.Size())
+}
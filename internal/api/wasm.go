@@ -0,0 +1,418 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/sync/singleflight"
+
+	"velocity/internal/log"
+	"velocity/internal/plugin"
+	"velocity/internal/storage"
+)
+
+const (
+	pluginsCollection = "plugins"
+	maxPluginSize     = 16 << 20 // 16MB
+	pluginsCacheTTL   = 30 * time.Second
+)
+
+// Plugin phases
+const (
+	phaseWrite  = "write"
+	phaseRender = "render"
+)
+
+// wasmPluginRecord is a tenant's WASM plugin as stored
+type wasmPluginRecord struct {
+	Name      string    `json:"name"`
+	Phases    []string  `json:"phases"`
+	Types     []string  `json:"types,omitempty"` // empty means all content types
+	SHA256    string    `json:"sha256"`
+	Size      int       `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	Module    []byte    `json:"module,omitempty"`
+}
+
+func (rec *wasmPluginRecord) applies(contentType, phase string) bool {
+	if !containsString(rec.Phases, phase) {
+		return false
+	}
+	return len(rec.Types) == 0 || containsString(rec.Types, contentType)
+}
+
+// loadedPlugin is a compiled plugin ready to run
+type loadedPlugin struct {
+	record *wasmPluginRecord
+	module *plugin.WASMModule
+}
+
+type tenantPlugins struct {
+	plugins   []*loadedPlugin
+	expiresAt time.Time
+}
+
+// wasmPlugins loads per-tenant WASM plugins and caches compiled modules.
+// Storage reads and compiles run outside the lock, once per tenant and per
+// module at a time, so one tenant's slow compile doesn't hold up the others.
+type wasmPlugins struct {
+	runtime    *plugin.WASMRuntime
+	storage    storage.Storage
+	closeDelay time.Duration // how long a dropped module stays open for calls already running it
+	loads      singleflight.Group
+	compiles   singleflight.Group
+
+	mu          sync.Mutex
+	tenants     map[string]*tenantPlugins
+	generations map[string]int                   // bumped by invalidate, so loads in flight aren't cached
+	compiled    map[string]*plugin.WASMModule    // by sha256, while a cached plugin list uses it
+	retired     map[*plugin.WASMModule]time.Time // dropped modules, closed once closeDelay passes
+}
+
+func newWASMPlugins(s storage.Storage, cfg plugin.WASMConfig) *wasmPlugins {
+	return &wasmPlugins{
+		runtime:     plugin.NewWASMRuntime(context.Background(), cfg),
+		storage:     s,
+		closeDelay:  pluginsCacheTTL + cfg.Timeout,
+		tenants:     make(map[string]*tenantPlugins),
+		generations: make(map[string]int),
+		compiled:    make(map[string]*plugin.WASMModule),
+		retired:     make(map[*plugin.WASMModule]time.Time),
+	}
+}
+
+// load returns a tenant's compiled plugins (cached briefly per node)
+func (wp *wasmPlugins) load(ctx context.Context, tenant string) []*loadedPlugin {
+	wp.mu.Lock()
+	cached, ok := wp.tenants[tenant]
+	generation := wp.generations[tenant]
+	wp.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.plugins
+	}
+
+	// Shared by every request waiting on the load, so one canceling doesn't
+	// leave the others (and the cache) without plugins
+	ctx = context.WithoutCancel(ctx)
+	plugins, _, _ := wp.loads.Do(fmt.Sprintf("%s/%d", tenant, generation), func() (interface{}, error) {
+		plugins := wp.read(ctx, tenant)
+
+		wp.mu.Lock()
+		defer wp.mu.Unlock()
+		for _, p := range plugins {
+			if existing, ok := wp.compiled[p.record.SHA256]; ok && existing != p.module {
+				wp.retired[p.module] = time.Now()
+				p.module = existing
+			}
+			wp.compiled[p.record.SHA256] = p.module
+			delete(wp.retired, p.module)
+		}
+		if wp.generations[tenant] == generation {
+			wp.tenants[tenant] = &tenantPlugins{plugins: plugins, expiresAt: time.Now().Add(pluginsCacheTTL)}
+		}
+		wp.dropUnused()
+		return plugins, nil
+	})
+	return plugins.([]*loadedPlugin)
+}
+
+// read loads a tenant's plugin records and compiles their modules
+func (wp *wasmPlugins) read(ctx context.Context, tenant string) []*loadedPlugin {
+	var plugins []*loadedPlugin
+	names, _ := wp.storage.ListDocuments(ctx, tenant, pluginsCollection)
+	sort.Strings(names)
+	for _, name := range names {
+		data, err := wp.storage.GetDocument(ctx, tenant, pluginsCollection, name)
+		if err != nil {
+			continue
+		}
+		var record wasmPluginRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			log.Error("Failed to parse plugin %s for tenant %s: %v", name, tenant, err)
+			continue
+		}
+
+		module, err := wp.module(ctx, &record)
+		if err != nil {
+			log.Error("Failed to compile plugin %s for tenant %s: %v", name, tenant, err)
+			continue
+		}
+		record.Module = nil
+		plugins = append(plugins, &loadedPlugin{record: &record, module: module})
+	}
+	return plugins
+}
+
+// module returns the compiled module of a plugin record, compiling it once
+// however many tenants load it at the same time
+func (wp *wasmPlugins) module(ctx context.Context, record *wasmPluginRecord) (*plugin.WASMModule, error) {
+	wp.mu.Lock()
+	module, ok := wp.compiled[record.SHA256]
+	wp.mu.Unlock()
+	if ok {
+		return module, nil
+	}
+
+	compiled, err, _ := wp.compiles.Do(record.SHA256, func() (interface{}, error) {
+		return wp.runtime.Compile(ctx, record.Module)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return compiled.(*plugin.WASMModule), nil
+}
+
+// dropUnused drops compiled modules no cached plugin list uses, and closes
+// dropped modules once calls that were running them have finished. Callers
+// hold wp.mu.
+func (wp *wasmPlugins) dropUnused() {
+	used := make(map[*plugin.WASMModule]bool)
+	for _, cached := range wp.tenants {
+		for _, p := range cached.plugins {
+			used[p.module] = true
+		}
+	}
+	for sha, module := range wp.compiled {
+		if !used[module] {
+			delete(wp.compiled, sha)
+			wp.retired[module] = time.Now()
+		}
+	}
+	for module, at := range wp.retired {
+		if time.Since(at) >= wp.closeDelay {
+			module.Close(context.Background())
+			delete(wp.retired, module)
+		}
+	}
+}
+
+// invalidate drops a tenant's cached plugin list
+func (wp *wasmPlugins) invalidate(tenant string) {
+	wp.mu.Lock()
+	delete(wp.tenants, tenant)
+	wp.generations[tenant]++
+	wp.dropUnused()
+	wp.mu.Unlock()
+}
+
+// forPhase returns the plugins that apply to a content type in a phase
+func (wp *wasmPlugins) forPhase(ctx context.Context, tenant, contentType, phase string) []*loadedPlugin {
+	var matched []*loadedPlugin
+	for _, p := range wp.load(ctx, tenant) {
+		if p.record.applies(contentType, phase) {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+// transform runs content through each plugin in order
+func (wp *wasmPlugins) transform(ctx context.Context, plugins []*loadedPlugin, phase, tenant, contentType, id, mimeType string, content []byte) ([]byte, error) {
+	for _, p := range plugins {
+		out, err := p.module.Transform(ctx, &plugin.TransformInput{
+			Phase:       phase,
+			Tenant:      tenant,
+			Type:        contentType,
+			ID:          id,
+			ContentType: mimeType,
+			Content:     string(content),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", p.record.Name, err)
+		}
+		content = out
+	}
+	return content, nil
+}
+
+// pluginsDigest identifies a set of plugins (used to vary ETags of rendered content)
+func pluginsDigest(plugins []*loadedPlugin) string {
+	h := sha256.New()
+	for _, p := range plugins {
+		h.Write([]byte(p.record.SHA256))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// applyWritePlugins transforms buffered write content with the tenant's write plugins
+func (s *Server) applyWritePlugins(ctx context.Context, req *plugin.Request) error {
+	if req.Content == nil {
+		return nil
+	}
+	plugins := s.wasm.forPhase(ctx, req.Tenant, req.Type, phaseWrite)
+	if len(plugins) == 0 {
+		return nil
+	}
+
+	out, err := s.wasm.transform(ctx, plugins, phaseWrite, req.Tenant, req.Type, req.ID, req.ContentType, req.Content)
+	if err != nil {
+		return &plugin.Rejection{Hook: "wasm", Reason: err.Error()}
+	}
+	req.Content = out
+	return nil
+}
+
+// applyRenderPlugins transforms content being served with the tenant's render plugins.
+// The stream's body, size, and ETag are replaced when plugins apply; on plugin failure
+// the original content is served.
+func (s *Server) applyRenderPlugins(ctx context.Context, tenant, contentType, id string, stream *storage.ContentStream) {
+	if !isJSONContent(stream.ContentType) && !isTextContent(stream.ContentType) {
+		return
+	}
+	if stream.Size > maxValidatedSize {
+		return
+	}
+	plugins := s.wasm.forPhase(ctx, tenant, contentType, phaseRender)
+	if len(plugins) == 0 {
+		return
+	}
+
+	original, err := io.ReadAll(stream.Body)
+	stream.Body.Close()
+	if err != nil {
+		stream.Body = io.NopCloser(bytes.NewReader(nil))
+		return
+	}
+
+	content, err := s.wasm.transform(ctx, plugins, phaseRender, tenant, contentType, id, stream.ContentType, original)
+	if err != nil {
+		log.Error("Render plugin failed for %s/%s: %v", contentType, id, err)
+		content = original
+	} else {
+		stream.ETag = fmt.Sprintf("\"%s-%s\"", strings.Trim(stream.ETag, "\""), pluginsDigest(plugins))
+	}
+
+	stream.Body = io.NopCloser(bytes.NewReader(content))
+	stream.Size = int64(len(content))
+}
+
+// =============================================================================
+// Plugin Handlers
+// =============================================================================
+
+// listPluginsHandler handles GET /api/tenant/plugins
+func (s *Server) listPluginsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+
+	records := []*wasmPluginRecord{}
+	for _, p := range s.wasm.load(r.Context(), tenant) {
+		records = append(records, p.record)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"plugins": records,
+		"count":   len(records),
+	})
+}
+
+// putPluginHandler handles PUT /api/tenant/plugins/{name}
+// Body is the .wasm module; ?phases=write,render and ?types=pages,articles select where it runs.
+func (s *Server) putPluginHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	name := mux.Vars(r)["name"]
+
+	phases := splitList(r.URL.Query().Get("phases"))
+	if len(phases) == 0 {
+		phases = []string{phaseWrite}
+	}
+	for _, phase := range phases {
+		if phase != phaseWrite && phase != phaseRender {
+			writeError(w, http.StatusBadRequest, "invalid_phase", fmt.Sprintf("Invalid phase: %s", phase))
+			return
+		}
+	}
+
+	module, err := io.ReadAll(io.LimitReader(r.Body, maxPluginSize+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "Failed to read request body")
+		return
+	}
+	if len(module) > maxPluginSize {
+		writeError(w, http.StatusRequestEntityTooLarge, "too_large", "Plugin module is too large")
+		return
+	}
+
+	// Validate the module compiles and exports the plugin ABI
+	compiled, err := s.wasm.runtime.Compile(r.Context(), module)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_module", err.Error())
+		return
+	}
+	compiled.Close(r.Context())
+
+	sum := sha256.Sum256(module)
+	record := &wasmPluginRecord{
+		Name:      name,
+		Phases:    phases,
+		Types:     splitList(r.URL.Query().Get("types")),
+		SHA256:    hex.EncodeToString(sum[:]),
+		Size:      len(module),
+		CreatedAt: time.Now().UTC(),
+		Module:    module,
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "marshal_error", err.Error())
+		return
+	}
+	if err := s.storage.PutDocument(r.Context(), tenant, pluginsCollection, name, data); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	s.wasm.invalidate(tenant)
+
+	log.Info("Installed plugin %s for tenant %s (%d bytes)", name, tenant, len(module))
+
+	record.Module = nil
+	writeJSON(w, http.StatusOK, record)
+}
+
+// deletePluginHandler handles DELETE /api/tenant/plugins/{name}
+func (s *Server) deletePluginHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	name := mux.Vars(r)["name"]
+
+	if err := s.storage.DeleteDocument(r.Context(), tenant, pluginsCollection, name); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+	s.wasm.invalidate(tenant)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"name":    name,
+		"message": "Plugin deleted successfully",
+	})
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
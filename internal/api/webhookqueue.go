@@ -0,0 +1,143 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"velocity/internal/log"
+	"velocity/internal/outbound"
+	"velocity/internal/storage"
+)
+
+// maxQueuedEvents bounds the events waiting for one item, so a webhook that
+// is down can't hold an unbounded backlog; the oldest are dropped
+const maxQueuedEvents = 1000
+
+// webhookQueue delivers webhook events one item at a time: events for the
+// same content item are sent in the order they happened, each only after
+// every webhook has answered (or timed out) for the one before. Items are
+// independent, so a slow item doesn't hold up the others.
+type webhookQueue struct {
+	mu       sync.Mutex
+	items    map[string][]*storage.WebhookEvent // pending events by tenant/type/id
+	sequence int64                              // last sequence number handed out
+	client   *http.Client
+	storage  storage.Storage
+}
+
+func newWebhookQueue(s storage.Storage) *webhookQueue {
+	return &webhookQueue{
+		items:   make(map[string][]*storage.WebhookEvent),
+		client:  outbound.Client(10 * time.Second),
+		storage: s,
+	}
+}
+
+// nextSequence returns a sequence number above every earlier one: the clock in
+// microseconds, so numbers from different nodes interleave by time, bumped
+// past the last one when the clock hasn't moved (or moved back)
+func (q *webhookQueue) nextSequence() int64 {
+	seq := time.Now().UnixMicro()
+	if seq <= q.sequence {
+		seq = q.sequence + 1
+	}
+	q.sequence = seq
+	return seq
+}
+
+// enqueue numbers an event and queues it behind the item's earlier events,
+// starting the item's delivery loop if it isn't running
+func (q *webhookQueue) enqueue(payload storage.WebhookEvent) {
+	key := payload.Tenant + "/" + payload.Type + "/" + payload.ID
+
+	q.mu.Lock()
+	payload.Sequence = q.nextSequence()
+	pending, running := q.items[key]
+	if len(pending) >= maxQueuedEvents {
+		log.Error("Webhook queue for %s is full; dropping event %d (%s)", key, pending[0].Sequence, pending[0].Event)
+		pending = pending[1:]
+	}
+	q.items[key] = append(pending, &payload)
+	q.mu.Unlock()
+
+	if !running {
+		go q.run(key)
+	}
+}
+
+// run delivers an item's events in order until its queue is empty
+func (q *webhookQueue) run(key string) {
+	for {
+		q.mu.Lock()
+		pending := q.items[key]
+		if len(pending) == 0 {
+			delete(q.items, key)
+			q.mu.Unlock()
+			return
+		}
+		event := pending[0]
+		q.mu.Unlock()
+
+		q.deliver(event)
+
+		q.mu.Lock()
+		if pending := q.items[key]; len(pending) > 0 && pending[0] == event {
+			q.items[key] = pending[1:]
+		}
+		q.mu.Unlock()
+	}
+}
+
+// deliver sends one event to every webhook subscribed to it, waiting for all
+// of them
+func (q *webhookQueue) deliver(event *storage.WebhookEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	webhooks, err := q.storage.ListWebhooks(ctx, event.Tenant)
+	if err != nil || len(webhooks) == 0 {
+		return
+	}
+
+	jsonPayload, err := json.Marshal(event)
+	if err != nil {
+		log.Error("Failed to marshal webhook payload: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, webhook := range webhooks {
+		// Check if webhook is subscribed to this event
+		subscribed := false
+		for _, e := range webhook.Events {
+			if e == event.Event {
+				subscribed = true
+				break
+			}
+		}
+		if !subscribed {
+			continue
+		}
+
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			resp, err := q.client.Post(url, "application/json", bytes.NewReader(jsonPayload))
+			if err != nil {
+				log.Error("Webhook delivery to %s failed: %v", url, err)
+				return
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				log.Error("Webhook delivery to %s returned %d (sequence %d)", url, resp.StatusCode, event.Sequence)
+				return
+			}
+			log.Debug("Webhook sent to %s: %d (sequence %d)", url, resp.StatusCode, event.Sequence)
+		}(webhook.URL)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultRobotsTxt is served when a tenant hasn't set robots_txt
+const defaultRobotsTxt = "User-agent: *\nAllow: /\n"
+
+// SecurityTxt holds the fields of a tenant's security.txt (RFC 9116)
+type SecurityTxt struct {
+	Contact            []string  `json:"contact,omitempty"` // mailto:, https:, or tel: URIs (required)
+	Expires            time.Time `json:"expires,omitempty"` // default one year from the time of the request
+	Encryption         []string  `json:"encryption,omitempty"`
+	Acknowledgments    []string  `json:"acknowledgments,omitempty"`
+	PreferredLanguages string    `json:"preferred_languages,omitempty"`
+	Canonical          []string  `json:"canonical,omitempty"`
+	Policy             []string  `json:"policy,omitempty"`
+	Hiring             []string  `json:"hiring,omitempty"`
+}
+
+// clone returns a deep copy (nil for nil)
+func (st *SecurityTxt) clone() *SecurityTxt {
+	if st == nil {
+		return nil
+	}
+	c := *st
+	c.Contact = append([]string(nil), st.Contact...)
+	c.Encryption = append([]string(nil), st.Encryption...)
+	c.Acknowledgments = append([]string(nil), st.Acknowledgments...)
+	c.Canonical = append([]string(nil), st.Canonical...)
+	c.Policy = append([]string(nil), st.Policy...)
+	c.Hiring = append([]string(nil), st.Hiring...)
+	return &c
+}
+
+// render formats the fields in security.txt syntax
+func (st *SecurityTxt) render(now time.Time) string {
+	var b strings.Builder
+	field := func(name string, values ...string) {
+		for _, value := range values {
+			if value != "" {
+				fmt.Fprintf(&b, "%s: %s\n", name, value)
+			}
+		}
+	}
+
+	expires := st.Expires
+	if expires.IsZero() {
+		expires = now.AddDate(1, 0, 0).Truncate(24 * time.Hour)
+	}
+
+	field("Contact", st.Contact...)
+	field("Expires", expires.UTC().Format(time.RFC3339))
+	field("Encryption", st.Encryption...)
+	field("Acknowledgments", st.Acknowledgments...)
+	field("Preferred-Languages", st.PreferredLanguages)
+	field("Canonical", st.Canonical...)
+	field("Policy", st.Policy...)
+	field("Hiring", st.Hiring...)
+	return b.String()
+}
+
+// validate checks the fields RFC 9116 requires
+func (st *SecurityTxt) validate() error {
+	if len(st.Contact) == 0 {
+		return fmt.Errorf("security_txt needs at least one contact")
+	}
+	for _, contact := range st.Contact {
+		if !strings.HasPrefix(contact, "mailto:") && !strings.HasPrefix(contact, "https://") && !strings.HasPrefix(contact, "tel:") {
+			return fmt.Errorf("security_txt contact %q must be a mailto:, https://, or tel: URI", contact)
+		}
+	}
+	return nil
+}
+
+// =============================================================================
+// Well-Known File Handlers
+// =============================================================================
+
+// robotsHandler handles GET /content/{tenant}/robots.txt
+func (s *Server) robotsHandler(w http.ResponseWriter, r *http.Request) {
+	robots := s.settings.get(r.Context(), mux.Vars(r)["tenant"]).RobotsTxt
+	if robots == "" {
+		robots = defaultRobotsTxt
+	}
+	if !strings.HasSuffix(robots, "\n") {
+		robots += "\n"
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(robots))
+}
+
+// securityTxtHandler handles GET /content/{tenant}/.well-known/security.txt
+func (s *Server) securityTxtHandler(w http.ResponseWriter, r *http.Request) {
+	securityTxt := s.settings.get(r.Context(), mux.Vars(r)["tenant"]).SecurityTxt
+	if securityTxt == nil || len(securityTxt.Contact) == 0 {
+		writeError(w, http.StatusNotFound, "not_found", "No security.txt is configured")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(securityTxt.render(time.Now())))
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+// zipManifestName is the archive entry describing the exported items; item
+// IDs can't start with "_", so it never collides with one
+const zipManifestName = "_manifest.json"
+
+// zipExportItem describes one exported item in the archive's manifest
+type zipExportItem struct {
+	ID           string            `json:"id"`
+	Path         string            `json:"path"`
+	ContentType  string            `json:"content_type"`
+	Size         int64             `json:"size"`
+	Version      string            `json:"version,omitempty"`
+	LastModified time.Time         `json:"last_modified"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// zipExportManifest is the last entry of an export archive
+type zipExportManifest struct {
+	Type       string            `json:"type"`
+	State      string            `json:"state"`
+	Prefix     string            `json:"prefix,omitempty"`
+	Filters    map[string]string `json:"filters,omitempty"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Count      int               `json:"count"`
+	Items      []*zipExportItem  `json:"items"`
+	Errors     map[string]string `json:"errors,omitempty"` // items that couldn't be read, by ID
+}
+
+// parseExportFilters parses ?filter=key:value parameters into metadata
+// values that exported items must have
+func parseExportFilters(values []string) (map[string]string, error) {
+	filters := make(map[string]string)
+	for _, value := range values {
+		key, want, ok := strings.Cut(value, ":")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid filter %q (expected key:value)", value)
+		}
+		filters[key] = strings.TrimSpace(want)
+	}
+	return filters, nil
+}
+
+// matchExportFilters reports whether metadata has every filtered value
+func matchExportFilters(metadata, filters map[string]string) bool {
+	for key, want := range filters {
+		if !strings.EqualFold(metadata[key], want) {
+			return false
+		}
+	}
+	return true
+}
+
+// compressible reports whether a MIME type is worth deflating; images,
+// video, audio, and archives are stored as is
+func compressible(mimeType string) bool {
+	mimeType = strings.ToLower(mimeType)
+	switch {
+	case strings.HasPrefix(mimeType, "image/svg"):
+		return true
+	case strings.HasPrefix(mimeType, "image/"), strings.HasPrefix(mimeType, "video/"), strings.HasPrefix(mimeType, "audio/"):
+		return false
+	case strings.HasPrefix(mimeType, "application/zip"), strings.HasPrefix(mimeType, "application/gzip"), strings.HasPrefix(mimeType, "application/pdf"):
+		return false
+	}
+	return true
+}
+
+// exportZipItem copies an item into the archive, returning its manifest
+// entry, or nil when it doesn't match the filters. started reports whether
+// the archive was written to: after that, a failure leaves it unusable.
+func (s *Server) exportZipItem(ctx context.Context, archive *zip.Writer, tenant, contentType, id, ext string, state storage.State, filters map[string]string) (item *zipExportItem, started bool, err error) {
+	if len(filters) > 0 {
+		metadata, err := s.storage.GetMetadata(ctx, tenant, contentType, id, ext, state)
+		if err != nil {
+			return nil, false, err
+		}
+		if !matchExportFilters(metadata, filters) {
+			return nil, false, nil
+		}
+	}
+
+	stream, err := s.storage.GetStream(ctx, tenant, contentType, id, ext, state)
+	if err != nil {
+		return nil, false, err
+	}
+	defer stream.Body.Close()
+
+	item = &zipExportItem{
+		ID:           id,
+		Path:         id,
+		ContentType:  stream.ContentType,
+		Version:      stream.VersionID,
+		LastModified: stream.LastModified,
+		Metadata:     stream.Metadata,
+	}
+	if ext != "" {
+		item.Path += "." + ext
+	}
+
+	header := &zip.FileHeader{Name: item.Path, Modified: stream.LastModified, Method: zip.Store}
+	if compressible(stream.ContentType) {
+		header.Method = zip.Deflate
+	}
+	entry, err := archive.CreateHeader(header)
+	if err != nil {
+		return nil, true, err
+	}
+	if item.Size, err = copyBuffered(entry, stream.Body); err != nil {
+		return nil, true, err
+	}
+	return item, true, nil
+}
+
+// =============================================================================
+// Zip Export Handlers
+// =============================================================================
+
+// exportZipHandler handles GET /api/content/{type}/export.zip
+// Streams a zip of the items of a type in a state (live by default), each as
+// {id}.{ext}, followed by _manifest.json listing them with their metadata.
+// ?prefix= limits the export to a folder, ?language= to a language, and
+// ?filter=key:value (repeatable) to items with those metadata values. The
+// archive is written as items are read, never held in memory.
+func (s *Server) exportZipHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenant := s.getTenant(r)
+	contentType := mux.Vars(r)["type"]
+	query := r.URL.Query()
+
+	state := storage.StateLive
+	if v := query.Get("state"); v != "" {
+		if !storage.ValidState(v) {
+			writeError(w, http.StatusBadRequest, "invalid_state", fmt.Sprintf("Invalid state: %s", v))
+			return
+		}
+		state = storage.State(v)
+	}
+
+	filters, err := parseExportFilters(query["filter"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_filter", err.Error())
+		return
+	}
+	if language := query.Get("language"); language != "" {
+		filters[languageKey] = language
+	}
+
+	prefix := strings.Trim(query.Get("prefix"), "/")
+	items, err := s.storage.List(ctx, tenant, contentType, state)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "storage_error", err.Error())
+		return
+	}
+
+	type listed struct{ id, ext string }
+	var matches []listed
+	for _, item := range items {
+		id, ext := extractIDAndExt(item.Key, contentType, state)
+		if prefix == "" || id == prefix || strings.HasPrefix(id, prefix+"/") {
+			matches = append(matches, listed{id, ext})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].id < matches[j].id })
+
+	manifest := &zipExportManifest{
+		Type:       contentType,
+		State:      string(state),
+		Prefix:     prefix,
+		ExportedAt: time.Now().UTC(),
+		Items:      []*zipExportItem{},
+	}
+	if len(filters) > 0 {
+		manifest.Filters = filters
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", contentType+".zip"))
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	// The status is sent, so failures from here on can only cut the archive short
+	archive := zip.NewWriter(w)
+	for _, match := range matches {
+		if ctx.Err() != nil {
+			log.Info("Export of %s cancelled by the client", contentType)
+			return
+		}
+		item, started, err := s.exportZipItem(ctx, archive, tenant, contentType, match.id, match.ext, state, filters)
+		if err != nil {
+			if started || ctx.Err() != nil {
+				log.Error("Export of %s stopped at %s: %v", contentType, match.id, err)
+				return
+			}
+			log.Error("Failed to export %s/%s: %v", contentType, match.id, err)
+			if manifest.Errors == nil {
+				manifest.Errors = make(map[string]string)
+			}
+			manifest.Errors[match.id] = err.Error()
+			continue
+		}
+		if item == nil {
+			continue
+		}
+		manifest.Items = append(manifest.Items, item)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	manifest.Count = len(manifest.Items)
+
+	entry, err := archive.Create(zipManifestName)
+	if err == nil {
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(manifest)
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		log.Error("Failed to finish export of %s: %v", contentType, err)
+		return
+	}
+	log.Debug("Exported %d items of %s (%s) as zip", manifest.Count, contentType, state)
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+const (
+	zipImportJobKind = "zip-import"
+
+	maxZipImportSize    = 10 << 30 // largest archive accepted
+	maxZipImportEntries = 10000
+)
+
+// zipImportReport is the result of a zip import job
+type zipImportReport struct {
+	Type    string          `json:"type"`
+	Prefix  string          `json:"prefix,omitempty"`
+	State   string          `json:"state"`
+	DryRun  bool            `json:"dry_run,omitempty"`
+	Entries int             `json:"entries"`
+	Created int             `json:"created"` // valid files, on a dry run
+	Skipped int             `json:"skipped"`
+	Failed  int             `json:"failed"`
+	Items   []*uploadResult `json:"items"`
+}
+
+// zipEntryIgnored reports whether an archive entry is not content: folders,
+// hidden files or the metadata archivers add (__MACOSX, .DS_Store), and the
+// manifest of an export
+func zipEntryIgnored(file *zip.File) bool {
+	if file.FileInfo().IsDir() || zipEntryName(file) == zipManifestName {
+		return true
+	}
+	for _, segment := range strings.Split(zipEntryName(file), "/") {
+		if strings.HasPrefix(segment, ".") || strings.HasPrefix(segment, "__MACOSX") {
+			return true
+		}
+	}
+	return false
+}
+
+// zipEntryName returns an entry's path with forward slashes
+func zipEntryName(file *zip.File) string {
+	return strings.Trim(strings.ReplaceAll(file.Name, `\`, "/"), "/")
+}
+
+// zipEntryID returns the item ID for an archive entry: its path under the
+// prefix, without the extension
+func zipEntryID(prefix, name string) string {
+	id := strings.TrimSuffix(name, path.Ext(name))
+	if prefix != "" {
+		id = prefix + "/" + id
+	}
+	return id
+}
+
+// zipEntryType detects the MIME type of an archive entry from its extension,
+// falling back to sniffing its first bytes
+func zipEntryType(name string, head []byte) string {
+	ext := strings.ToLower(path.Ext(name))
+	if mimeType := mimeFromExt(ext); mimeType != "application/octet-stream" {
+		return mimeType
+	}
+	if mimeType := mime.TypeByExtension(ext); mimeType != "" {
+		return mimeType
+	}
+	return http.DetectContentType(head)
+}
+
+// importZip stores every file of an archive as an item of a content type
+func (s *Server) importZip(ctx context.Context, j *job, tenant, contentType string, state storage.State, archive *zip.Reader, prefix string, headers map[string]string, skipExisting, dryRun bool) (*zipImportReport, error) {
+	report := &zipImportReport{Type: contentType, Prefix: prefix, State: string(state), DryRun: dryRun, Items: []*uploadResult{}}
+	policy := s.settings.get(ctx, tenant).IDPolicy
+
+	var files []*zip.File
+	for _, file := range archive.File {
+		if !zipEntryIgnored(file) {
+			files = append(files, file)
+		}
+	}
+	report.Entries = len(files)
+
+	for i, file := range files {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		name := zipEntryName(file)
+		j.setMessage("Importing " + name)
+
+		result := s.importZipEntry(ctx, tenant, contentType, state, file, name, prefix, headers, policy, skipExisting, dryRun)
+		switch result.Status {
+		case "failed":
+			report.Failed++
+			log.Debug("Failed to import %s from zip: %s", name, result.Message)
+		case "skipped":
+			report.Skipped++
+		default:
+			report.Created++
+		}
+		report.Items = append(report.Items, result)
+		j.setProgress(i+1, len(files))
+	}
+
+	j.setMessage(fmt.Sprintf("Imported %d of %d files", report.Created, report.Entries))
+	return report, nil
+}
+
+// importZipEntry stores one file of an archive
+func (s *Server) importZipEntry(ctx context.Context, tenant, contentType string, state storage.State, file *zip.File, name, prefix string, headers map[string]string, policy *IDPolicy, skipExisting, dryRun bool) *uploadResult {
+	result := &uploadResult{Filename: name, ID: policy.normalize(zipEntryID(prefix, name))}
+
+	entry, err := file.Open()
+	if err != nil {
+		result.fail(http.StatusBadRequest, "invalid_zip", fmt.Sprintf("Failed to read %s: %v", name, err))
+		return result
+	}
+	defer entry.Close()
+
+	body := bufio.NewReaderSize(entry, 512)
+	head, _ := body.Peek(512)
+	result.MimeType = zipEntryType(name, head)
+	result.ext = getExtensionFromMime(result.MimeType)
+	if ext := strings.ToLower(strings.TrimPrefix(path.Ext(name), ".")); result.ext == "bin" && ext != "" {
+		result.ext = ext
+	}
+
+	if skipExisting && storage.ValidKeyPath(result.ID) {
+		exists, err := s.storage.Exists(ctx, tenant, contentType, result.ID, result.ext, state)
+		if err == nil && exists {
+			result.Status = "skipped"
+			result.Message = "Content already exists"
+			return result
+		}
+	}
+
+	return s.storeFile(ctx, tenant, contentType, state, result, body, headers, nil, policy, dryRun)
+}
+
+// =============================================================================
+// Zip Import Handlers
+// =============================================================================
+
+// importZipHandler handles POST /api/content/{type}/import-zip
+// Stores every file of the zip archive in the body as an item in the
+// background: folders become ID prefixes and the MIME type is detected per
+// file. ?prefix= nests the items under a folder, ?state= picks their state,
+// ?skip-existing=true leaves items that already exist alone, and
+// ?dry-run=true only validates. X-Meta-* headers apply to every item.
+func (s *Server) importZipHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := s.getTenant(r)
+	contentType := mux.Vars(r)["type"]
+	query := r.URL.Query()
+
+	state := s.writeState(r, tenant)
+	if v := query.Get("state"); v != "" {
+		if !storage.ValidState(v) {
+			writeError(w, http.StatusBadRequest, "invalid_state", fmt.Sprintf("Invalid state: %s", v))
+			return
+		}
+		state = storage.State(v)
+	}
+
+	// Importing into live publishes every item
+	if state == storage.StateLive && !requirePermission(w, r, permPublish) {
+		return
+	}
+
+	prefix := strings.Trim(query.Get("prefix"), "/")
+	if prefix != "" && !storage.ValidKeyPath(prefix) {
+		writeError(w, http.StatusBadRequest, "invalid_prefix", fmt.Sprintf("Invalid prefix: %q", prefix))
+		return
+	}
+
+	// Spool the archive to disk: zip's directory is at the end of the file
+	tmp, err := os.CreateTemp("", "velocity-import-*.zip")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Failed to buffer archive")
+		return
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+
+	size, err := io.Copy(tmp, http.MaxBytesReader(w, r.Body, maxZipImportSize))
+	if err != nil {
+		cleanup()
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "too_large", fmt.Sprintf("Archives are limited to %d bytes", int64(maxZipImportSize)))
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid_body", "Failed to read archive")
+		return
+	}
+
+	archive, err := zip.NewReader(tmp, size)
+	if err != nil {
+		cleanup()
+		writeError(w, http.StatusBadRequest, "invalid_zip", fmt.Sprintf("Body is not a zip archive: %v", err))
+		return
+	}
+	if len(archive.File) > maxZipImportEntries {
+		cleanup()
+		writeError(w, http.StatusBadRequest, "too_many_entries", fmt.Sprintf("Archives are limited to %d entries", maxZipImportEntries))
+		return
+	}
+
+	headers := extractMetadata(r)
+	skipExisting := query.Get("skip-existing") == "true"
+	dryRun := isDryRun(r)
+	request := r.Context()
+
+	j, err := s.jobs.start(tenant, zipImportJobKind, func(ctx context.Context, j *job) (interface{}, error) {
+		defer cleanup()
+		return s.importZip(withRequestCaller(withRequestRoot(ctx, request), request), j, tenant, contentType, state, archive, prefix, headers, skipExisting, dryRun)
+	})
+	if err == errJobRunning {
+		cleanup()
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":   "job_running",
+			"message": "A zip import is already running",
+			"job":     j.info(),
+		})
+		return
+	}
+
+	log.Info("Importing %d entries of a zip archive into %s (%s)", len(archive.File), contentType, state)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"job":     j.info(),
+		"type":    contentType,
+		"state":   string(state),
+		"message": "Zip import started",
+	})
+}
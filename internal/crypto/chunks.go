@@ -0,0 +1,223 @@
+package crypto
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ChunkSize is how much plaintext each chunk of a chunked stream holds
+const ChunkSize = 64 << 10
+
+// ChunkOverhead is what each chunk adds to its plaintext: a flag byte, the
+// sealed length, the nonce, and the GCM tag
+const ChunkOverhead = 1 + 4 + gcmNonceSize + 16
+
+// Chunk flags
+const (
+	chunkMore  = 0
+	chunkFinal = 1
+)
+
+// ErrTruncated is returned when a chunked stream ends before its final chunk
+var ErrTruncated = errors.New("encrypted stream is truncated")
+
+// SealedSize returns the size of a chunked stream holding size bytes of
+// plaintext, or -1 when the size isn't known
+func SealedSize(size int64) int64 {
+	if size < 0 {
+		return -1
+	}
+	chunks := (size + ChunkSize - 1) / ChunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return size + chunks*ChunkOverhead
+}
+
+// OpenedSize returns the plaintext size of a chunked stream of size bytes,
+// or -1 when the size isn't known
+func OpenedSize(size int64) int64 {
+	if size < 0 {
+		return -1
+	}
+	chunks := (size + ChunkSize + ChunkOverhead - 1) / (ChunkSize + ChunkOverhead)
+	if chunks == 0 {
+		chunks = 1
+	}
+	return max(size-chunks*ChunkOverhead, 0)
+}
+
+// chunkAAD binds a chunk to its stream, its position, and whether it's the
+// last, so chunks can't be reordered, dropped, or cut short
+func chunkAAD(aad []byte, index uint64, flag byte) []byte {
+	data := make([]byte, 0, len(aad)+9)
+	data = append(data, aad...)
+	data = binary.BigEndian.AppendUint64(data, index)
+	return append(data, flag)
+}
+
+// ChunkSealer encrypts a stream in chunks of ChunkSize as it's read, so
+// content of any size can be encrypted without holding it in memory. Each
+// chunk is sealed on its own with the keyring's primary key; Key and DEK are
+// what's needed to open them.
+type ChunkSealer struct {
+	Key string // ID of the key the chunks are sealed with
+	DEK []byte // wrapped data key (KMS keys only)
+
+	ctx       context.Context
+	key       Key
+	plaintext *bufio.Reader
+	aad       []byte
+	index     uint64
+	buf       []byte // sealed chunk not yet read
+	done      bool
+	read      int64
+}
+
+// SealChunks returns a reader of plaintext sealed in chunks. The first chunk
+// is sealed before it returns, so Key and DEK are set.
+func (k *Keyring) SealChunks(ctx context.Context, plaintext io.Reader, aad []byte) (*ChunkSealer, error) {
+	s := &ChunkSealer{
+		Key:       k.primary.ID(),
+		ctx:       ctx,
+		key:       k.primary,
+		plaintext: bufio.NewReaderSize(plaintext, ChunkSize),
+		aad:       aad,
+	}
+	if err := s.next(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Size returns how much plaintext has been sealed so far
+func (s *ChunkSealer) Size() int64 {
+	return s.read
+}
+
+// next seals the next chunk of plaintext
+func (s *ChunkSealer) next() error {
+	chunk := make([]byte, ChunkSize)
+	n, err := io.ReadFull(s.plaintext, chunk)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("failed to read content: %w", err)
+	}
+	s.read += int64(n)
+
+	flag := byte(chunkMore)
+	if _, err := s.plaintext.Peek(1); err != nil {
+		if err != io.EOF {
+			return fmt.Errorf("failed to read content: %w", err)
+		}
+		flag = chunkFinal
+		s.done = true
+	}
+
+	env, err := s.key.seal(s.ctx, chunk[:n], chunkAAD(s.aad, s.index, flag))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if s.index == 0 {
+		s.DEK = env.DEK
+	}
+	s.index++
+
+	s.buf = make([]byte, 0, 5+len(env.Data))
+	s.buf = append(s.buf, flag)
+	s.buf = binary.BigEndian.AppendUint32(s.buf, uint32(len(env.Data)))
+	s.buf = append(s.buf, env.Data...)
+	return nil
+}
+
+func (s *ChunkSealer) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// chunkOpener decrypts a stream sealed with SealChunks as it's read
+type chunkOpener struct {
+	ctx    context.Context
+	key    Key
+	dek    []byte
+	sealed io.ReadCloser
+	aad    []byte
+	index  uint64
+	buf    []byte // opened chunk not yet read
+	done   bool
+}
+
+// OpenChunks returns a reader of a stream sealed with SealChunks by the given
+// key. Each chunk is verified before any of it is returned; a stream that
+// was cut short fails with ErrTruncated once its last chunk is read.
+func (k *Keyring) OpenChunks(ctx context.Context, keyID string, dek []byte, sealed io.ReadCloser, aad []byte) (io.ReadCloser, error) {
+	if k == nil {
+		return nil, ErrNoKey
+	}
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoKey, keyID)
+	}
+	return &chunkOpener{ctx: ctx, key: key, dek: dek, sealed: sealed, aad: aad}, nil
+}
+
+// next opens the next chunk
+func (o *chunkOpener) next() error {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(o.sealed, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrTruncated
+		}
+		return err
+	}
+	flag, size := header[0], binary.BigEndian.Uint32(header[1:])
+	if (flag != chunkMore && flag != chunkFinal) || size < gcmNonceSize+16 || size > ChunkSize+gcmNonceSize+16 {
+		return errors.New("failed to decrypt: invalid chunk")
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(o.sealed, data); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrTruncated
+		}
+		return err
+	}
+
+	plaintext, err := o.key.open(o.ctx, &envelope{DEK: o.dek, Data: data}, chunkAAD(o.aad, o.index, flag))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt: %w", err)
+	}
+	o.index++
+	o.buf = plaintext
+	o.done = flag == chunkFinal
+	return nil
+}
+
+func (o *chunkOpener) Read(p []byte) (int, error) {
+	for len(o.buf) == 0 {
+		if o.done {
+			return 0, io.EOF
+		}
+		if err := o.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, o.buf)
+	o.buf = o.buf[n:]
+	return n, nil
+}
+
+func (o *chunkOpener) Close() error {
+	return o.sealed.Close()
+}
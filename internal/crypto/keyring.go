@@ -0,0 +1,266 @@
+// Package crypto encrypts sensitive documents before they are written to the
+// bucket, so webhook URLs (which often carry tokens) and tenant secrets aren't
+// stored as plaintext JSON.
+package crypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealedVersion marks a sealed document and its format
+const sealedVersion = "v1"
+
+// gcmNonceSize is the nonce size of every key's AES-GCM cipher
+const gcmNonceSize = 12
+
+// Passphrase derivation parameters. The salt is fixed so every node derives
+// the same key from the same passphrase.
+const (
+	passphraseIterations = 600000
+	passphraseSalt       = "velocity-keyring"
+)
+
+// ErrNoKey is returned when opening a sealed document without its key
+var ErrNoKey = errors.New("document is encrypted with a key that isn't configured")
+
+// envelope is the stored form of a sealed document
+type envelope struct {
+	Sealed string `json:"sealed"`
+	Key    string `json:"key"`
+	DEK    []byte `json:"dek,omitempty"` // wrapped data key (KMS keys only)
+	Data   []byte `json:"data"`          // nonce followed by ciphertext
+}
+
+// Key encrypts and decrypts documents
+type Key interface {
+	// ID identifies the key in sealed documents
+	ID() string
+
+	seal(ctx context.Context, plaintext, aad []byte) (*envelope, error)
+	open(ctx context.Context, env *envelope, aad []byte) ([]byte, error)
+}
+
+// Keyring seals documents with its primary key and opens documents sealed with
+// any of its keys, so keys can be rotated: make the new key primary and keep
+// the old one until everything has been rewritten.
+type Keyring struct {
+	primary Key
+	keys    map[string]Key
+}
+
+// NewKeyring creates a keyring; the first key is the primary
+func NewKeyring(primary Key, previous ...Key) *Keyring {
+	k := &Keyring{primary: primary, keys: make(map[string]Key)}
+	for _, key := range append([]Key{primary}, previous...) {
+		k.keys[key.ID()] = key
+	}
+	return k
+}
+
+// ParseKey parses a key reference: passphrase:{text} or kms:{key id or ARN}
+func ParseKey(ctx context.Context, ref string) (Key, error) {
+	kind, value, ok := strings.Cut(ref, ":")
+	if !ok || value == "" {
+		return nil, fmt.Errorf("invalid key %q (expected passphrase:... or kms:...)", redact(ref))
+	}
+	switch kind {
+	case "passphrase":
+		return NewPassphraseKey(value)
+	case "kms":
+		return NewKMSKey(ctx, nil, value)
+	default:
+		return nil, fmt.Errorf("unknown key type %q (expected passphrase or kms)", kind)
+	}
+}
+
+// PrimaryID returns the ID of the key new documents are sealed with
+func (k *Keyring) PrimaryID() string {
+	return k.primary.ID()
+}
+
+// Seal encrypts a document. aad binds it to where it is stored (e.g. its
+// key), so a sealed document can't be copied elsewhere and still open.
+func (k *Keyring) Seal(ctx context.Context, plaintext, aad []byte) ([]byte, error) {
+	env, err := k.primary.seal(ctx, plaintext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	env.Sealed = sealedVersion
+	env.Key = k.primary.ID()
+	return json.Marshal(env)
+}
+
+// Open decrypts a sealed document. Documents that aren't sealed (written
+// before encryption was enabled) are returned unchanged.
+func (k *Keyring) Open(ctx context.Context, data, aad []byte) ([]byte, error) {
+	env, ok := parseEnvelope(data)
+	if !ok {
+		return data, nil
+	}
+	if k == nil {
+		return nil, ErrNoKey
+	}
+	key, ok := k.keys[env.Key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoKey, env.Key)
+	}
+	plaintext, err := key.open(ctx, env, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Detached is a document sealed with SealDetached: its ciphertext apart from
+// what's needed to open it, so the ciphertext can be stored as an object's
+// body and the rest as its metadata
+type Detached struct {
+	Key        string // ID of the key it was sealed with
+	DEK        []byte // wrapped data key (KMS keys only)
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// SealDetached encrypts a document like Seal, returning its parts
+func (k *Keyring) SealDetached(ctx context.Context, plaintext, aad []byte) (*Detached, error) {
+	env, err := k.primary.seal(ctx, plaintext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	return &Detached{
+		Key:        k.primary.ID(),
+		DEK:        env.DEK,
+		Nonce:      env.Data[:gcmNonceSize],
+		Ciphertext: env.Data[gcmNonceSize:],
+	}, nil
+}
+
+// OpenDetached decrypts a document sealed with SealDetached
+func (k *Keyring) OpenDetached(ctx context.Context, d *Detached, aad []byte) ([]byte, error) {
+	if k == nil {
+		return nil, ErrNoKey
+	}
+	key, ok := k.keys[d.Key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoKey, d.Key)
+	}
+	if len(d.Nonce) != gcmNonceSize {
+		return nil, errors.New("failed to decrypt: invalid nonce")
+	}
+	data := make([]byte, 0, len(d.Nonce)+len(d.Ciphertext))
+	data = append(append(data, d.Nonce...), d.Ciphertext...)
+	plaintext, err := key.open(ctx, &envelope{Key: d.Key, DEK: d.DEK, Data: data}, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// IsSealed reports whether data is a sealed document
+func IsSealed(data []byte) bool {
+	_, ok := parseEnvelope(data)
+	return ok
+}
+
+func parseEnvelope(data []byte) (*envelope, bool) {
+	if len(data) == 0 || data[0] != '{' {
+		return nil, false
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Sealed != sealedVersion {
+		return nil, false
+	}
+	return &env, true
+}
+
+// =============================================================================
+// Passphrase Keys
+// =============================================================================
+
+// passphraseKey is an AES-256-GCM key derived from a passphrase
+type passphraseKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// NewPassphraseKey derives a key from a passphrase with PBKDF2-SHA256
+func NewPassphraseKey(passphrase string) (Key, error) {
+	if len(passphrase) < 16 {
+		return nil, errors.New("passphrase must be at least 16 characters")
+	}
+	secret, err := pbkdf2.Key(sha256.New, passphrase, []byte(passphraseSalt), passphraseIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &passphraseKey{id: "passphrase:" + fingerprint(secret), aead: aead}, nil
+}
+
+func (k *passphraseKey) ID() string { return k.id }
+
+func (k *passphraseKey) seal(ctx context.Context, plaintext, aad []byte) (*envelope, error) {
+	data, err := sealAEAD(k.aead, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+	return &envelope{Data: data}, nil
+}
+
+func (k *passphraseKey) open(ctx context.Context, env *envelope, aad []byte) ([]byte, error) {
+	return openAEAD(k.aead, env.Data, aad)
+}
+
+// =============================================================================
+// Helpers
+// =============================================================================
+
+func newAEAD(secret []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealAEAD encrypts with a random nonce, returning the nonce and ciphertext
+func sealAEAD(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func openAEAD(aead cipher.AEAD, data, aad []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], aad)
+}
+
+// fingerprint identifies a secret without revealing it
+func fingerprint(secret []byte) string {
+	sum := sha256.Sum256(append([]byte("velocity-key-id:"), secret...))
+	return hex.EncodeToString(sum[:4])
+}
+
+// redact hides all but the type of a key reference in error messages
+func redact(ref string) string {
+	if kind, _, ok := strings.Cut(ref, ":"); ok {
+		return kind + ":..."
+	}
+	return "..."
+}
@@ -0,0 +1,189 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+func newTestKey(t *testing.T, passphrase string) Key {
+	key, err := NewPassphraseKey(passphrase)
+	if err != nil {
+		t.Fatalf("NewPassphraseKey: %v", err)
+	}
+	return key
+}
+
+func TestKeyringSealOpen(t *testing.T) {
+	ctx := context.Background()
+	keyring := NewKeyring(newTestKey(t, "correct horse battery staple"))
+	plaintext := []byte(`{"url":"https://hooks.example.com/T000/B000/secret"}`)
+
+	sealed, err := keyring.Seal(ctx, plaintext, []byte("webhook:acme/slack"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if !IsSealed(sealed) || bytes.Contains(sealed, []byte("secret")) {
+		t.Fatalf("sealed document exposes its plaintext: %s", sealed)
+	}
+
+	opened, err := keyring.Open(ctx, sealed, []byte("webhook:acme/slack"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open = %s, want %s", opened, plaintext)
+	}
+
+	// Bound to where it was stored
+	if _, err := keyring.Open(ctx, sealed, []byte("webhook:other/slack")); err == nil {
+		t.Error("opened a document with the wrong context")
+	}
+
+	// Documents written before encryption are read as they are
+	opened, err = keyring.Open(ctx, plaintext, nil)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open(plaintext) = %s, %v", opened, err)
+	}
+}
+
+func TestKeyringSealDetached(t *testing.T) {
+	ctx := context.Background()
+	keyring := NewKeyring(newTestKey(t, "correct horse battery staple"))
+	plaintext := []byte(`{"email":"jane@example.com"}`)
+
+	sealed, err := keyring.SealDetached(ctx, plaintext, []byte("acme/customers/jane"))
+	if err != nil {
+		t.Fatalf("SealDetached: %v", err)
+	}
+	if sealed.Key != keyring.PrimaryID() || len(sealed.Nonce) != 12 || bytes.Contains(sealed.Ciphertext, []byte("jane")) {
+		t.Fatalf("unexpected sealed parts: %+v", sealed)
+	}
+
+	opened, err := keyring.OpenDetached(ctx, sealed, []byte("acme/customers/jane"))
+	if err != nil {
+		t.Fatalf("OpenDetached: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("OpenDetached = %s, want %s", opened, plaintext)
+	}
+	if _, err := keyring.OpenDetached(ctx, sealed, []byte("acme/customers/john")); err == nil {
+		t.Error("opened a document with the wrong context")
+	}
+	if _, err := NewKeyring(newTestKey(t, "some other passphrase")).OpenDetached(ctx, sealed, nil); !errors.Is(err, ErrNoKey) {
+		t.Errorf("OpenDetached without the key = %v, want ErrNoKey", err)
+	}
+}
+
+func TestKeyringChunks(t *testing.T) {
+	ctx := context.Background()
+	keyring := NewKeyring(newTestKey(t, "correct horse battery staple"))
+	aad := []byte("acme/videos/intro")
+
+	for _, size := range []int{0, 10, ChunkSize, 2*ChunkSize + 5} {
+		plaintext := bytes.Repeat([]byte("x"), size)
+		sealer, err := keyring.SealChunks(ctx, bytes.NewReader(plaintext), aad)
+		if err != nil {
+			t.Fatalf("SealChunks(%d): %v", size, err)
+		}
+		sealed, _ := io.ReadAll(sealer)
+		if int64(len(sealed)) != SealedSize(int64(size)) || OpenedSize(int64(len(sealed))) != int64(size) || sealer.Size() != int64(size) {
+			t.Errorf("%d bytes sealed to %d, want %d", size, len(sealed), SealedSize(int64(size)))
+		}
+
+		opened, err := keyring.OpenChunks(ctx, sealer.Key, sealer.DEK, io.NopCloser(bytes.NewReader(sealed)), aad)
+		if err != nil {
+			t.Fatalf("OpenChunks(%d): %v", size, err)
+		}
+		if data, err := io.ReadAll(opened); err != nil || !bytes.Equal(data, plaintext) {
+			t.Errorf("OpenChunks(%d) = %d bytes, %v", size, len(data), err)
+		}
+
+		if size <= ChunkSize {
+			continue
+		}
+		// Cut after the first chunk, or with the first two chunks swapped
+		frame := ChunkSize + ChunkOverhead
+		swapped := append(append(append([]byte{}, sealed[frame:2*frame]...), sealed[:frame]...), sealed[2*frame:]...)
+		for name, tampered := range map[string][]byte{"truncated": sealed[:frame], "reordered": swapped} {
+			opened, _ := keyring.OpenChunks(ctx, sealer.Key, sealer.DEK, io.NopCloser(bytes.NewReader(tampered)), aad)
+			if _, err := io.ReadAll(opened); err == nil {
+				t.Errorf("opened a %s stream", name)
+			}
+		}
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	ctx := context.Background()
+	old := newTestKey(t, "the old passphrase, retired")
+	sealed, err := NewKeyring(old).Seal(ctx, []byte("{}"), nil)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	current := newTestKey(t, "the new passphrase, in use")
+	if _, err := NewKeyring(current).Open(ctx, sealed, nil); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Open without the old key = %v, want ErrNoKey", err)
+	}
+	keyring := NewKeyring(current, old)
+	if _, err := keyring.Open(ctx, sealed, nil); err != nil {
+		t.Errorf("Open with the old key as previous: %v", err)
+	}
+	if keyring.PrimaryID() != current.ID() {
+		t.Errorf("PrimaryID = %s, want %s", keyring.PrimaryID(), current.ID())
+	}
+}
+
+// fakeKMS wraps data keys by XOR with a fixed byte
+type fakeKMS struct {
+	decrypts int
+}
+
+func xor(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i, b := range data {
+		out[i] = b ^ 0x5a
+	}
+	return out
+}
+
+func (f *fakeKMS) GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+	plaintext := bytes.Repeat([]byte{7}, 32)
+	return &kms.GenerateDataKeyOutput{Plaintext: plaintext, CiphertextBlob: xor(plaintext), KeyId: params.KeyId}, nil
+}
+
+func (f *fakeKMS) Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	f.decrypts++
+	return &kms.DecryptOutput{Plaintext: xor(params.CiphertextBlob)}, nil
+}
+
+func TestKMSKey(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeKMS{}
+	key, err := NewKMSKey(ctx, fake, "alias/velocity")
+	if err != nil {
+		t.Fatalf("NewKMSKey: %v", err)
+	}
+	sealed, err := NewKeyring(key).Seal(ctx, []byte("{}"), nil)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	// Another process unwraps the data key once
+	other, _ := NewKMSKey(ctx, fake, "alias/velocity")
+	other.(*kmsKey).unwrapped = map[string]cipher.AEAD{}
+	for i := 0; i < 2; i++ {
+		if _, err := NewKeyring(other).Open(ctx, sealed, nil); err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+	}
+	if fake.decrypts != 1 {
+		t.Errorf("KMS Decrypt called %d times, want 1", fake.decrypts)
+	}
+}
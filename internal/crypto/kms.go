@@ -0,0 +1,111 @@
+package crypto
+
+import (
+	"context"
+	"crypto/cipher"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// KMSAPI is the subset of the KMS client used by KMS keys
+type KMSAPI interface {
+	GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// Ensure *kms.Client implements KMSAPI
+var _ KMSAPI = (*kms.Client)(nil)
+
+// kmsKey uses envelope encryption: documents are sealed with a data key
+// generated by KMS, stored alongside them wrapped by the KMS key. The master
+// key never leaves KMS, and opening a document costs one KMS call per data key.
+type kmsKey struct {
+	keyID  string
+	client KMSAPI
+
+	// Data key new documents are sealed with (one per process)
+	dataKey cipher.AEAD
+	wrapped []byte
+
+	mu        sync.Mutex
+	unwrapped map[string]cipher.AEAD // wrapped data key -> key
+}
+
+// NewKMSKey creates a key backed by an AWS KMS key. A nil client uses the
+// default AWS credential chain. A data key is generated up front, so missing
+// permissions are reported at startup.
+func NewKMSKey(ctx context.Context, client KMSAPI, keyID string) (Key, error) {
+	if client == nil {
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		client = kms.NewFromConfig(cfg)
+	}
+
+	result, err := client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:   aws.String(keyID),
+		KeySpec: types.DataKeySpecAes256,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key with %s: %w", keyID, err)
+	}
+	dataKey, err := newAEAD(result.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmsKey{
+		keyID:     keyID,
+		client:    client,
+		dataKey:   dataKey,
+		wrapped:   result.CiphertextBlob,
+		unwrapped: map[string]cipher.AEAD{string(result.CiphertextBlob): dataKey},
+	}, nil
+}
+
+func (k *kmsKey) ID() string { return "kms:" + k.keyID }
+
+func (k *kmsKey) seal(ctx context.Context, plaintext, aad []byte) (*envelope, error) {
+	data, err := sealAEAD(k.dataKey, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+	return &envelope{DEK: k.wrapped, Data: data}, nil
+}
+
+func (k *kmsKey) open(ctx context.Context, env *envelope, aad []byte) ([]byte, error) {
+	dataKey, err := k.unwrap(ctx, env.DEK)
+	if err != nil {
+		return nil, err
+	}
+	return openAEAD(dataKey, env.Data, aad)
+}
+
+// unwrap decrypts a wrapped data key with KMS, caching the result
+func (k *kmsKey) unwrap(ctx context.Context, wrapped []byte) (cipher.AEAD, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if dataKey, ok := k.unwrapped[string(wrapped)]; ok {
+		return dataKey, nil
+	}
+	result, err := k.client.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:          aws.String(k.keyID),
+		CiphertextBlob: wrapped,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	dataKey, err := newAEAD(result.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	k.unwrapped[string(wrapped)] = dataKey
+	return dataKey, nil
+}
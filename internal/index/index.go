@@ -0,0 +1,88 @@
+// Package index keeps a queryable record of every content item (its ID,
+// type, state, metadata, size, and timestamps) in a database beside the
+// bucket, so listing a type reads the database instead of scanning objects.
+package index
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"velocity/internal/storage"
+)
+
+// Entry is the indexed record of one content item in one state
+type Entry struct {
+	Tenant      string
+	Type        string
+	ID          string
+	Ext         string
+	State       storage.State
+	Key         string // the object's key in storage
+	ContentType string
+	Size        int64
+	ETag        string
+	VersionID   string
+	Metadata    map[string]string
+	CreatedAt   time.Time // when the item was first indexed in this state
+	UpdatedAt   time.Time
+}
+
+// Query selects entries
+type Query struct {
+	Tenant string
+	Type   string
+	State  storage.State
+}
+
+// Index stores entries. Implementations must be safe for concurrent use.
+type Index interface {
+	// Put adds or replaces an entry, keeping its CreatedAt
+	Put(ctx context.Context, entry *Entry) error
+	// Delete removes an entry; removing one that doesn't exist isn't an error
+	Delete(ctx context.Context, tenant, contentType, id, ext string, state storage.State) error
+	// List returns the entries a query selects, ordered by ID
+	List(ctx context.Context, q Query) ([]*Entry, error)
+
+	// Ready reports whether a type's entries in a state have been built from
+	// storage, so List can answer for it
+	Ready(ctx context.Context, tenant, contentType string, state storage.State) (bool, error)
+	// MarkReady records that a type's entries in a state have been built
+	MarkReady(ctx context.Context, tenant, contentType string, state storage.State) error
+	// Reset forgets that a tenant's types were built, so they're built again
+	Reset(ctx context.Context, tenant string) error
+
+	Close() error
+}
+
+// Open connects to the index a DSN names: postgres://... or
+// postgresql://..., or sqlite:{path} for a database file
+func Open(dsn string) (Index, error) {
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		return OpenPostgres(dsn)
+	case strings.HasPrefix(dsn, "sqlite:"):
+		path := strings.TrimPrefix(dsn, "sqlite:")
+		if path == "" {
+			return nil, fmt.Errorf("sqlite index needs a file, as sqlite:{path}")
+		}
+		return OpenSQLite(path)
+	default:
+		return nil, fmt.Errorf("unknown index %q (expected postgres://... or sqlite:{path})", Redact(dsn))
+	}
+}
+
+// Redact hides the password of a DSN for messages
+func Redact(dsn string) string {
+	scheme, rest, ok := strings.Cut(dsn, "://")
+	if !ok {
+		return dsn
+	}
+	at := strings.LastIndex(rest, "@")
+	if at == -1 {
+		return dsn
+	}
+	user, _, _ := strings.Cut(rest[:at], ":")
+	return scheme + "://" + user + ":xxxxx@" + rest[at+1:]
+}
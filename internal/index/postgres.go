@@ -0,0 +1,33 @@
+package index
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the pgx driver
+)
+
+// OpenPostgres connects to a Postgres index, creating its tables if needed
+func OpenPostgres(dsn string) (Index, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index: %w", err)
+	}
+	db.SetMaxOpenConns(16)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to index %s: %w", Redact(dsn), err)
+	}
+	idx, err := newSQLIndex(ctx, db, true)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return idx, nil
+}
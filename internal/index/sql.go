@@ -0,0 +1,191 @@
+package index
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"velocity/internal/storage"
+)
+
+// schema creates the index's tables. Times are Unix nanoseconds and metadata
+// is a JSON object, so the same statements work on every database.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS velocity_content (
+		tenant       TEXT   NOT NULL,
+		type         TEXT   NOT NULL,
+		state        TEXT   NOT NULL,
+		id           TEXT   NOT NULL,
+		ext          TEXT   NOT NULL,
+		object_key   TEXT   NOT NULL,
+		content_type TEXT   NOT NULL,
+		size         BIGINT NOT NULL,
+		etag         TEXT   NOT NULL,
+		version_id   TEXT   NOT NULL,
+		metadata     TEXT   NOT NULL,
+		created_at   BIGINT NOT NULL,
+		updated_at   BIGINT NOT NULL,
+		PRIMARY KEY (tenant, type, state, id, ext)
+	)`,
+	`CREATE TABLE IF NOT EXISTS velocity_content_built (
+		tenant   TEXT   NOT NULL,
+		type     TEXT   NOT NULL,
+		state    TEXT   NOT NULL,
+		built_at BIGINT NOT NULL,
+		PRIMARY KEY (tenant, type, state)
+	)`,
+}
+
+// sqlIndex is an Index in a SQL database
+type sqlIndex struct {
+	db *sql.DB
+	// numbered rewrites ? placeholders as $1, $2, ... for databases that need it
+	numbered bool
+}
+
+// newSQLIndex creates the index's tables if needed
+func newSQLIndex(ctx context.Context, db *sql.DB, numbered bool) (*sqlIndex, error) {
+	idx := &sqlIndex{db: db, numbered: numbered}
+	for _, statement := range schema {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return nil, fmt.Errorf("failed to create index tables: %w", err)
+		}
+	}
+	return idx, nil
+}
+
+// query rewrites a statement's placeholders for the database
+func (idx *sqlIndex) query(statement string) string {
+	if !idx.numbered {
+		return statement
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range statement {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (idx *sqlIndex) Put(ctx context.Context, e *Entry) error {
+	metadata, err := json.Marshal(e.Metadata)
+	if err != nil {
+		return err
+	}
+	if e.Metadata == nil {
+		metadata = []byte("{}")
+	}
+	now := time.Now()
+	updated := e.UpdatedAt
+	if updated.IsZero() {
+		updated = now
+	}
+	created := e.CreatedAt
+	if created.IsZero() {
+		created = updated
+	}
+	_, err = idx.db.ExecContext(ctx, idx.query(`
+		INSERT INTO velocity_content (tenant, type, state, id, ext, object_key, content_type, size, etag, version_id, metadata, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (tenant, type, state, id, ext) DO UPDATE SET
+			object_key = excluded.object_key, content_type = excluded.content_type, size = excluded.size,
+			etag = excluded.etag, version_id = excluded.version_id, metadata = excluded.metadata,
+			updated_at = excluded.updated_at`),
+		e.Tenant, e.Type, string(stateOf(e.State)), e.ID, e.Ext, e.Key, e.ContentType, e.Size, e.ETag, e.VersionID, string(metadata), created.UnixNano(), updated.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to index %s/%s: %w", e.Type, e.ID, err)
+	}
+	return nil
+}
+
+func (idx *sqlIndex) Delete(ctx context.Context, tenant, contentType, id, ext string, state storage.State) error {
+	_, err := idx.db.ExecContext(ctx, idx.query(`DELETE FROM velocity_content WHERE tenant = ? AND type = ? AND state = ? AND id = ? AND ext = ?`),
+		tenant, contentType, string(stateOf(state)), id, ext)
+	if err != nil {
+		return fmt.Errorf("failed to remove %s/%s from the index: %w", contentType, id, err)
+	}
+	return nil
+}
+
+func (idx *sqlIndex) List(ctx context.Context, q Query) ([]*Entry, error) {
+	rows, err := idx.db.QueryContext(ctx, idx.query(`
+		SELECT id, ext, object_key, content_type, size, etag, version_id, metadata, created_at, updated_at
+		FROM velocity_content WHERE tenant = ? AND type = ? AND state = ? ORDER BY id, ext`),
+		q.Tenant, q.Type, string(stateOf(q.State)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the index: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*Entry{}
+	for rows.Next() {
+		e := &Entry{Tenant: q.Tenant, Type: q.Type, State: stateOf(q.State)}
+		var metadata string
+		var created, updated int64
+		if err := rows.Scan(&e.ID, &e.Ext, &e.Key, &e.ContentType, &e.Size, &e.ETag, &e.VersionID, &metadata, &created, &updated); err != nil {
+			return nil, fmt.Errorf("failed to read the index: %w", err)
+		}
+		e.Metadata = map[string]string{}
+		json.Unmarshal([]byte(metadata), &e.Metadata)
+		e.CreatedAt = time.Unix(0, created).UTC()
+		e.UpdatedAt = time.Unix(0, updated).UTC()
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the index: %w", err)
+	}
+	return entries, nil
+}
+
+func (idx *sqlIndex) Ready(ctx context.Context, tenant, contentType string, state storage.State) (bool, error) {
+	var builtAt int64
+	err := idx.db.QueryRowContext(ctx, idx.query(`SELECT built_at FROM velocity_content_built WHERE tenant = ? AND type = ? AND state = ?`),
+		tenant, contentType, string(stateOf(state))).Scan(&builtAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to query the index: %w", err)
+	}
+	return true, nil
+}
+
+func (idx *sqlIndex) MarkReady(ctx context.Context, tenant, contentType string, state storage.State) error {
+	_, err := idx.db.ExecContext(ctx, idx.query(`
+		INSERT INTO velocity_content_built (tenant, type, state, built_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (tenant, type, state) DO UPDATE SET built_at = excluded.built_at`),
+		tenant, contentType, string(stateOf(state)), time.Now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to update the index: %w", err)
+	}
+	return nil
+}
+
+func (idx *sqlIndex) Reset(ctx context.Context, tenant string) error {
+	_, err := idx.db.ExecContext(ctx, idx.query(`DELETE FROM velocity_content_built WHERE tenant = ?`), tenant)
+	if err != nil {
+		return fmt.Errorf("failed to update the index: %w", err)
+	}
+	return nil
+}
+
+func (idx *sqlIndex) Close() error {
+	return idx.db.Close()
+}
+
+// stateOf defaults an empty state to live, as storage does
+func stateOf(state storage.State) storage.State {
+	if state == "" {
+		return storage.StateLive
+	}
+	return state
+}
@@ -0,0 +1,42 @@
+package index
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the sqlite driver
+)
+
+// OpenSQLite opens an index in a SQLite database file, creating the file and
+// its tables if needed. It needs no server, so suits a single node; nodes
+// sharing a bucket need a shared (Postgres) index.
+func OpenSQLite(path string) (Index, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create index directory: %w", err)
+		}
+	}
+
+	// WAL lets lists read while the index is written; the busy timeout waits
+	// out writes from other connections rather than failing
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index: %w", err)
+	}
+	db.SetMaxOpenConns(4)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	idx, err := newSQLIndex(ctx, db, false)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open index %s: %w", path, err)
+	}
+	return idx, nil
+}
@@ -0,0 +1,276 @@
+package index
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"velocity/internal/log"
+	"velocity/internal/storage"
+)
+
+// IndexedStorage wraps a Storage implementation and records every content
+// write in an Index, so List answers from the index instead of listing the
+// bucket. A type's entries in a state are built from storage the first time
+// it's listed; until they are, List lists the bucket. Content written
+// straight to the bucket is indexed when Refresh is called for it.
+type IndexedStorage struct {
+	storage.Storage
+	index    Index
+	building sync.Map // tenant/type/state being built
+}
+
+// Ensure IndexedStorage implements Storage interface
+var _ storage.Storage = (*IndexedStorage)(nil)
+
+// NewIndexedStorage wraps the given Storage with an index
+func NewIndexedStorage(inner storage.Storage, index Index) *IndexedStorage {
+	return &IndexedStorage{Storage: inner, index: index}
+}
+
+// Index returns the index content is recorded in
+func (is *IndexedStorage) Index() Index {
+	return is.index
+}
+
+// Close closes the index
+func (is *IndexedStorage) Close() error {
+	return is.index.Close()
+}
+
+// Refresh brings an item's entry in a state up to date with storage,
+// removing it if the item no longer exists there
+func (is *IndexedStorage) Refresh(ctx context.Context, tenant, contentType, id, ext string, state storage.State) error {
+	stream, err := is.Storage.GetStream(ctx, tenant, contentType, id, ext, state)
+	if storage.IsNotFound(err) {
+		return is.index.Delete(ctx, tenant, contentType, id, ext, state)
+	}
+	if err != nil {
+		return err
+	}
+	stream.Body.Close()
+	return is.index.Put(ctx, &Entry{
+		Tenant:      tenant,
+		Type:        contentType,
+		ID:          id,
+		Ext:         ext,
+		State:       state,
+		Key:         stream.Key,
+		ContentType: stream.ContentType,
+		Size:        stream.Size,
+		ETag:        stream.ETag,
+		VersionID:   stream.VersionID,
+		Metadata:    stream.Metadata,
+		UpdatedAt:   stream.LastModified,
+	})
+}
+
+// Rebuild forgets which of a tenant's types are built, so each is built
+// again from storage the next time it's listed
+func (is *IndexedStorage) Rebuild(ctx context.Context, tenant string) error {
+	return is.index.Reset(ctx, tenant)
+}
+
+// refresh updates an entry after a write, logging failures: the write has
+// succeeded, and the entry is corrected by the next write or rebuild
+func (is *IndexedStorage) refresh(ctx context.Context, tenant, contentType, id, ext string, state storage.State) {
+	if err := is.Refresh(context.WithoutCancel(ctx), tenant, contentType, id, ext, state); err != nil {
+		log.Error("Failed to index %s/%s/%s (%s): %v", tenant, contentType, id, state, err)
+	}
+}
+
+// put records a written item, reading it back when the write didn't say
+// everything the entry needs
+func (is *IndexedStorage) put(ctx context.Context, tenant, contentType, id, ext string, state storage.State, item *storage.ContentItem, metadata map[string]string) {
+	if item.Size < 0 || item.ETag == "" {
+		is.refresh(ctx, tenant, contentType, id, ext, state)
+		return
+	}
+	entry := &Entry{
+		Tenant:      tenant,
+		Type:        contentType,
+		ID:          id,
+		Ext:         ext,
+		State:       state,
+		Key:         item.Key,
+		ContentType: item.ContentType,
+		Size:        item.Size,
+		ETag:        item.ETag,
+		VersionID:   item.VersionID,
+		Metadata:    metadata,
+		UpdatedAt:   item.LastModified,
+	}
+	if item.Metadata != nil {
+		entry.Metadata = item.Metadata
+	}
+	if entry.UpdatedAt.IsZero() {
+		entry.UpdatedAt = time.Now()
+	}
+	if err := is.index.Put(context.WithoutCancel(ctx), entry); err != nil {
+		log.Error("Failed to index %s/%s/%s (%s): %v", tenant, contentType, id, state, err)
+	}
+}
+
+// build indexes a type's items in a state from a listing of storage, then
+// marks it ready. Writes during the build index themselves, and each item is
+// read back, so the entries match storage when it finishes.
+func (is *IndexedStorage) build(tenant, contentType string, state storage.State, items []*storage.ContentItem) {
+	key := tenant + "/" + contentType + "/" + string(state)
+	if _, running := is.building.LoadOrStore(key, true); running {
+		return
+	}
+	defer is.building.Delete(key)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	started := time.Now()
+	for _, item := range items {
+		id, ext := itemID(item.Key, contentType, state)
+		if err := is.Refresh(ctx, tenant, contentType, id, ext, state); err != nil {
+			log.Error("Failed to build index of %s/%s (%s): %v", tenant, contentType, state, err)
+			return
+		}
+	}
+	if err := is.index.MarkReady(ctx, tenant, contentType, state); err != nil {
+		log.Error("Failed to build index of %s/%s (%s): %v", tenant, contentType, state, err)
+		return
+	}
+	log.Info("Indexed %d %s items of %s (%s) in %s", len(items), contentType, tenant, state, time.Since(started).Round(time.Millisecond))
+}
+
+// itemID reads an item's ID and extension from its key, as
+// {...}/content/{type}/{id}.{ext} or {...}/content/{type}/_{state}/{id}.{ext}
+func itemID(key, contentType string, state storage.State) (string, string) {
+	marker := "/content/" + contentType + "/"
+	if state != storage.StateLive && state != "" {
+		marker += "_" + string(state) + "/"
+	}
+	if i := strings.Index(key, marker); i != -1 {
+		key = key[i+len(marker):]
+	}
+	if dot := strings.LastIndex(key, "."); dot != -1 && !strings.Contains(key[dot:], "/") {
+		return key[:dot], key[dot+1:]
+	}
+	return key, ""
+}
+
+// --- Reads ---
+
+// List answers from the index once the type is built in that state, and
+// otherwise lists storage and starts building it. Items listed from the index
+// carry their metadata.
+func (is *IndexedStorage) List(ctx context.Context, tenant, contentType string, state storage.State) ([]*storage.ContentItem, error) {
+	state = stateOf(state)
+	if ready, err := is.index.Ready(ctx, tenant, contentType, state); err == nil && ready {
+		entries, err := is.index.List(ctx, Query{Tenant: tenant, Type: contentType, State: state})
+		if err == nil {
+			items := make([]*storage.ContentItem, len(entries))
+			for i, e := range entries {
+				items[i] = &storage.ContentItem{
+					Key:          e.Key,
+					ContentType:  e.ContentType,
+					VersionID:    e.VersionID,
+					LastModified: e.UpdatedAt,
+					Size:         e.Size,
+					ETag:         e.ETag,
+					Metadata:     e.Metadata,
+				}
+			}
+			return items, nil
+		}
+		log.Error("Failed to list %s/%s from the index: %v", tenant, contentType, err)
+	}
+
+	items, err := is.Storage.List(ctx, tenant, contentType, state)
+	if err == nil {
+		go is.build(tenant, contentType, state, items)
+	}
+	return items, err
+}
+
+// --- Writes ---
+
+func (is *IndexedStorage) Put(ctx context.Context, tenant, contentType, id, ext string, content []byte, mimeType string, state storage.State) (*storage.ContentItem, error) {
+	item, err := is.Storage.Put(ctx, tenant, contentType, id, ext, content, mimeType, state)
+	if err == nil {
+		is.put(ctx, tenant, contentType, id, ext, stateOf(state), item, nil)
+	}
+	return item, err
+}
+
+func (is *IndexedStorage) PutStream(ctx context.Context, tenant, contentType, id, ext string, body io.Reader, contentLength int64, mimeType string, state storage.State, metadata map[string]string) (*storage.ContentItem, error) {
+	item, err := is.Storage.PutStream(ctx, tenant, contentType, id, ext, body, contentLength, mimeType, state, metadata)
+	if err == nil {
+		is.put(ctx, tenant, contentType, id, ext, stateOf(state), item, metadata)
+	}
+	return item, err
+}
+
+func (is *IndexedStorage) Delete(ctx context.Context, tenant, contentType, id, ext string, state storage.State) error {
+	err := is.Storage.Delete(ctx, tenant, contentType, id, ext, state)
+	if err == nil {
+		if err := is.index.Delete(context.WithoutCancel(ctx), tenant, contentType, id, ext, state); err != nil {
+			log.Error("Failed to remove %s/%s/%s (%s) from the index: %v", tenant, contentType, id, state, err)
+		}
+	}
+	return err
+}
+
+func (is *IndexedStorage) Transition(ctx context.Context, tenant, contentType, id, ext string, fromState, toState storage.State) (*storage.ContentItem, error) {
+	item, err := is.Storage.Transition(ctx, tenant, contentType, id, ext, fromState, toState)
+	if err == nil {
+		is.refresh(ctx, tenant, contentType, id, ext, stateOf(toState))
+		is.refresh(ctx, tenant, contentType, id, ext, stateOf(fromState))
+	}
+	return item, err
+}
+
+func (is *IndexedStorage) CopyContent(ctx context.Context, tenant, contentType, id, ext string, fromState, toState storage.State, metadata map[string]string) (*storage.ContentItem, error) {
+	item, err := is.Storage.CopyContent(ctx, tenant, contentType, id, ext, fromState, toState, metadata)
+	if err == nil {
+		is.refresh(ctx, tenant, contentType, id, ext, stateOf(toState))
+	}
+	return item, err
+}
+
+func (is *IndexedStorage) RestoreVersion(ctx context.Context, tenant, contentType, id, ext, versionID string) (*storage.ContentItem, error) {
+	item, err := is.Storage.RestoreVersion(ctx, tenant, contentType, id, ext, versionID)
+	if err == nil {
+		is.refresh(ctx, tenant, contentType, id, ext, storage.StateLive)
+	}
+	return item, err
+}
+
+func (is *IndexedStorage) RestoreObject(ctx context.Context, tenant, contentType, id, ext string, state storage.State, objectID string) (*storage.ContentItem, error) {
+	item, err := is.Storage.RestoreObject(ctx, tenant, contentType, id, ext, state, objectID)
+	if err == nil {
+		is.refresh(ctx, tenant, contentType, id, ext, stateOf(state))
+	}
+	return item, err
+}
+
+func (is *IndexedStorage) SetMetadata(ctx context.Context, tenant, contentType, id, ext string, state storage.State, metadata map[string]string) error {
+	err := is.Storage.SetMetadata(ctx, tenant, contentType, id, ext, state, metadata)
+	if err == nil {
+		is.refresh(ctx, tenant, contentType, id, ext, stateOf(state))
+	}
+	return err
+}
+
+func (is *IndexedStorage) UpdateMetadata(ctx context.Context, tenant, contentType, id, ext string, state storage.State, updates map[string]string) error {
+	err := is.Storage.UpdateMetadata(ctx, tenant, contentType, id, ext, state, updates)
+	if err == nil {
+		is.refresh(ctx, tenant, contentType, id, ext, stateOf(state))
+	}
+	return err
+}
+
+func (is *IndexedStorage) DeleteMetadataKeys(ctx context.Context, tenant, contentType, id, ext string, state storage.State, keys []string) error {
+	err := is.Storage.DeleteMetadataKeys(ctx, tenant, contentType, id, ext, state, keys)
+	if err == nil {
+		is.refresh(ctx, tenant, contentType, id, ext, stateOf(state))
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+package index_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"velocity/internal/index"
+	"velocity/internal/storage"
+	"velocity/internal/storage/storagetest"
+)
+
+// newIndexedStorage returns storage indexed in a SQLite file, and the storage
+// under it
+func newIndexedStorage(t *testing.T) (*index.IndexedStorage, storage.Storage) {
+	t.Helper()
+	inner, _ := storagetest.NewS3Storage(10)
+	idx, err := index.OpenSQLite(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	is := index.NewIndexedStorage(inner, idx)
+	t.Cleanup(func() { is.Close() })
+	return is, inner
+}
+
+// waitReady waits for a type's index to be built after it was first listed
+func waitReady(t *testing.T, is *index.IndexedStorage, tenant, contentType string, state storage.State) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ready, err := is.Index().Ready(context.Background(), tenant, contentType, state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ready {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("index of %s (%s) not built", contentType, state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func listIDs(t *testing.T, is *index.IndexedStorage, contentType string, state storage.State) []string {
+	t.Helper()
+	items, err := is.List(context.Background(), "acme", contentType, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.Key[strings.LastIndex(item.Key, "/")+1:]
+	}
+	return ids
+}
+
+func TestIndexedStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		is, _ := newIndexedStorage(t)
+		return is
+	})
+}
+
+func TestIndexedStorageListsFromIndex(t *testing.T) {
+	ctx := context.Background()
+	is, inner := newIndexedStorage(t)
+
+	if _, err := is.PutStream(ctx, "acme", "pages", "home", "json", strings.NewReader(`{"title":"Home"}`), -1, "application/json", storage.StateLive, map[string]string{"language": "en"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := is.Put(ctx, "acme", "pages", "pricing", "json", []byte(`{"title":"Pricing"}`), "application/json", storage.StateLive); err != nil {
+		t.Fatal(err)
+	}
+	if got := listIDs(t, is, "pages", storage.StateLive); strings.Join(got, ",") != "home.json,pricing.json" {
+		t.Fatalf("listed %v before the index was built", got)
+	}
+	waitReady(t, is, "acme", "pages", storage.StateLive)
+
+	// Written behind the index's back, so not listed until it's refreshed
+	if _, err := inner.Put(ctx, "acme", "pages", "about", "json", []byte(`{"title":"About"}`), "application/json", storage.StateLive); err != nil {
+		t.Fatal(err)
+	}
+	items, err := is.List(ctx, "acme", "pages", storage.StateLive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("listed %d items from the index, want 2", len(items))
+	}
+	if items[0].Metadata["language"] != "en" || items[0].Size != int64(len(`{"title":"Home"}`)) || items[0].ETag == "" {
+		t.Errorf("indexed home = %+v", items[0])
+	}
+	if err := is.Refresh(ctx, "acme", "pages", "about", "json", storage.StateLive); err != nil {
+		t.Fatal(err)
+	}
+	if got := listIDs(t, is, "pages", storage.StateLive); strings.Join(got, ",") != "about.json,home.json,pricing.json" {
+		t.Errorf("listed %v after refresh", got)
+	}
+
+	// Writes through the indexed storage keep it in sync
+	if err := is.UpdateMetadata(ctx, "acme", "pages", "pricing", "json", storage.StateLive, map[string]string{"language": "de"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := is.Delete(ctx, "acme", "pages", "home", "json", storage.StateLive); err != nil {
+		t.Fatal(err)
+	}
+	items, _ = is.List(ctx, "acme", "pages", storage.StateLive)
+	if len(items) != 2 || items[1].Metadata["language"] != "de" {
+		t.Errorf("listed %d items after delete, pricing metadata %v", len(items), items[len(items)-1].Metadata)
+	}
+
+	// Publishing moves the entry between states
+	if _, err := is.Put(ctx, "acme", "pages", "faq", "json", []byte(`{"title":"FAQ"}`), "application/json", storage.StateDraft); err != nil {
+		t.Fatal(err)
+	}
+	listIDs(t, is, "pages", storage.StateDraft)
+	waitReady(t, is, "acme", "pages", storage.StateDraft)
+	if _, err := is.Transition(ctx, "acme", "pages", "faq", "json", storage.StateDraft, storage.StateLive); err != nil {
+		t.Fatal(err)
+	}
+	if got := listIDs(t, is, "pages", storage.StateDraft); len(got) != 0 {
+		t.Errorf("drafts after publishing = %v", got)
+	}
+	if got := listIDs(t, is, "pages", storage.StateLive); strings.Join(got, ",") != "about.json,faq.json,pricing.json" {
+		t.Errorf("live after publishing = %v", got)
+	}
+
+	// A rebuild lists storage again until the type is indexed again
+	if err := is.Rebuild(ctx, "acme"); err != nil {
+		t.Fatal(err)
+	}
+	if ready, _ := is.Index().Ready(ctx, "acme", "pages", storage.StateLive); ready {
+		t.Error("index still ready after a rebuild")
+	}
+}
@@ -3,7 +3,9 @@ package log
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -51,25 +53,40 @@ func ParseLevel(s string) Level {
 
 // Logger provides structured logging
 type Logger struct {
-	level Level
+	level atomic.Int32 // may be changed at runtime
 }
 
 // global logger instance
-var defaultLogger = &Logger{level: INFO}
+var defaultLogger = newLogger(INFO)
+
+func newLogger(level Level) *Logger {
+	l := &Logger{}
+	l.level.Store(int32(level))
+	return l
+}
 
 // SetLevel sets the global log level
 func SetLevel(level Level) {
-	defaultLogger.level = level
+	defaultLogger.level.Store(int32(level))
 }
 
 // GetLevel returns the current log level
 func GetLevel() Level {
-	return defaultLogger.level
+	return Level(defaultLogger.level.Load())
+}
+
+// ValidLevel reports whether s names a log level
+func ValidLevel(s string) bool {
+	switch strings.ToLower(s) {
+	case "trace", "debug", "info", "error":
+		return true
+	}
+	return false
 }
 
 // log outputs a log message if the level is enabled
 func (l *Logger) log(level Level, format string, args ...interface{}) {
-	if level < l.level {
+	if level < Level(l.level.Load()) {
 		return
 	}
 
@@ -98,6 +115,23 @@ func Error(format string, args ...interface{}) {
 	defaultLogger.log(ERROR, format, args...)
 }
 
+// Event logs a structured event as key=value pairs at info level, for log
+// processors and orchestrators to pick out of the output. Fields alternate
+// keys and values; values containing spaces or quotes are quoted.
+func Event(name string, fields ...interface{}) {
+	var b strings.Builder
+	b.WriteString("event=")
+	b.WriteString(name)
+	for i := 0; i+1 < len(fields); i += 2 {
+		value := fmt.Sprint(fields[i+1])
+		if value == "" || strings.ContainsAny(value, " \t\"=") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&b, " %v=%s", fields[i], value)
+	}
+	defaultLogger.log(INFO, "%s", b.String())
+}
+
 // Fatal logs an error message and exits
 func Fatal(format string, args ...interface{}) {
 	defaultLogger.log(ERROR, format, args...)
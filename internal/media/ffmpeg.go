@@ -0,0 +1,128 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultPosterOffset is how far into a video the poster frame is taken (seconds)
+const defaultPosterOffset = 1.0
+
+// FFmpeg processes videos with the ffmpeg and ffprobe command-line tools
+type FFmpeg struct {
+	Path      string // ffmpeg binary
+	ProbePath string // ffprobe binary
+	Heights   []int  // heights of H.264 transcodes (e.g. 1080, 720); none disables transcoding
+}
+
+// NewFFmpeg creates a processor for the ffmpeg binary at path, using the
+// ffprobe binary next to it
+func NewFFmpeg(path string, heights []int) *FFmpeg {
+	probe := "ffprobe"
+	if strings.ContainsRune(path, filepath.Separator) {
+		probe = filepath.Join(filepath.Dir(path), "ffprobe")
+	}
+	return &FFmpeg{Path: path, ProbePath: probe, Heights: heights}
+}
+
+// Name returns "ffmpeg"
+func (f *FFmpeg) Name() string {
+	return "ffmpeg"
+}
+
+// ffprobeOutput is the part of ffprobe's JSON output that is read
+type ffprobeOutput struct {
+	Streams []struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+// Probe reads the video's duration and the frame size of its first video stream
+func (f *FFmpeg) Probe(ctx context.Context, path string) (*VideoInfo, error) {
+	out, err := f.run(ctx, f.ProbePath, "-v", "error", "-select_streams", "v:0",
+		"-show_entries", "stream=width,height:format=duration", "-of", "json", path)
+	if err != nil {
+		return nil, err
+	}
+
+	var probe ffprobeOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, fmt.Errorf("invalid ffprobe output: %w", err)
+	}
+	if len(probe.Streams) == 0 {
+		return nil, fmt.Errorf("no video stream found")
+	}
+	info := &VideoInfo{Width: probe.Streams[0].Width, Height: probe.Streams[0].Height}
+	info.Duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	return info, nil
+}
+
+// Poster writes a JPEG of the frame one second in (or halfway through
+// shorter videos)
+func (f *FFmpeg) Poster(ctx context.Context, path, dir string, info *VideoInfo) (*Output, error) {
+	offset := defaultPosterOffset
+	if info != nil && info.Duration > 0 && info.Duration < 2*offset {
+		offset = info.Duration / 2
+	}
+
+	output := &Output{Name: "poster.jpg", Kind: "poster", ContentType: "image/jpeg", Path: filepath.Join(dir, "poster.jpg")}
+	if info != nil {
+		output.Width, output.Height = info.Width, info.Height
+	}
+	_, err := f.run(ctx, f.Path, "-v", "error", "-y", "-ss", strconv.FormatFloat(offset, 'f', 3, 64),
+		"-i", path, "-frames:v", "1", "-q:v", "3", output.Path)
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// Transcode writes an H.264/AAC MP4 for each configured height below the
+// video's own (videos are never upscaled)
+func (f *FFmpeg) Transcode(ctx context.Context, path, dir string, info *VideoInfo) ([]*Output, error) {
+	var outputs []*Output
+	for _, height := range f.Heights {
+		if height <= 0 || (info != nil && info.Height > 0 && height >= info.Height) {
+			continue
+		}
+		name := fmt.Sprintf("%dp.mp4", height)
+		output := &Output{Name: name, Kind: "transcode", ContentType: "video/mp4", Height: height, Path: filepath.Join(dir, name)}
+		if info != nil && info.Height > 0 {
+			// ffmpeg rounds the width to an even number of pixels
+			output.Width = (info.Width*height/info.Height + 1) &^ 1
+		}
+		_, err := f.run(ctx, f.Path, "-v", "error", "-y", "-i", path,
+			"-vf", fmt.Sprintf("scale=-2:%d", height), "-c:v", "libx264", "-preset", "veryfast", "-crf", "23",
+			"-c:a", "aac", "-b:a", "128k", "-movflags", "+faststart", output.Path)
+		if err != nil {
+			return outputs, err
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+// run runs a tool and returns its output, with its error output on failure
+func (f *FFmpeg) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s failed: %v: %s", filepath.Base(name), err, msg)
+		}
+		return nil, fmt.Errorf("%s failed: %v", filepath.Base(name), err)
+	}
+	return stdout.Bytes(), nil
+}
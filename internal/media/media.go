@@ -0,0 +1,71 @@
+// Package media extracts details from uploaded media (video running time and
+// frame size, PDF text and page counts) and generates files from it (poster
+// frames, transcodes). Video processors work on local files; the API copies
+// content to a temporary file before handing it over.
+package media
+
+import (
+	"context"
+	"strings"
+)
+
+// VideoInfo describes a video's running time and frame size
+type VideoInfo struct {
+	Duration float64 // seconds
+	Width    int
+	Height   int
+}
+
+// Output is a file a processor generated from a video
+type Output struct {
+	Name        string // rendition name (e.g. poster.jpg, 720p.mp4)
+	Kind        string // poster or transcode
+	ContentType string
+	Width       int
+	Height      int
+	Path        string // local file holding the output, removed by the caller
+}
+
+// VideoProcessor reads video details and generates a poster image
+type VideoProcessor interface {
+	// Name identifies the processor in logs and rendition metadata
+	Name() string
+
+	// Probe returns the duration and frame size of the video at path
+	Probe(ctx context.Context, path string) (*VideoInfo, error)
+
+	// Poster writes a still frame of the video into dir
+	Poster(ctx context.Context, path, dir string, info *VideoInfo) (*Output, error)
+}
+
+// Transcoder converts videos into other sizes or formats. Transcodes run as
+// background jobs, since they can take far longer than the upload.
+type Transcoder interface {
+	// Name identifies the transcoder in logs and rendition metadata
+	Name() string
+
+	// Transcode writes the video's transcoded outputs into dir
+	Transcode(ctx context.Context, path, dir string, info *VideoInfo) ([]*Output, error)
+}
+
+// IsVideo reports whether a MIME type is a video type
+func IsVideo(mimeType string) bool {
+	return strings.HasPrefix(mediaType(mimeType), "video/")
+}
+
+// IsMP4 reports whether a MIME type is one ProbeMP4 reads
+func IsMP4(mimeType string) bool {
+	switch mediaType(mimeType) {
+	case "video/mp4", "video/quicktime", "video/x-m4v":
+		return true
+	}
+	return false
+}
+
+// mediaType strips parameters from a MIME type and lowercases it
+func mediaType(mimeType string) string {
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
@@ -0,0 +1,134 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// maxMP4Depth bounds how deep boxes are searched for the movie header
+const maxMP4Depth = 8
+
+// errNoMovieHeader is returned when a file has no readable moov box
+var errNoMovieHeader = errors.New("no movie header found")
+
+// mp4Containers are the boxes whose children are searched for headers
+var mp4Containers = map[string]bool{
+	"moov": true,
+	"trak": true,
+	"mdia": true,
+}
+
+// ProbeMP4 reads the duration and frame size of an MP4 or QuickTime file
+// from its headers, without decoding any frames. Used when no video
+// processor is configured.
+func ProbeMP4(r io.ReaderAt, size int64) (*VideoInfo, error) {
+	info := &VideoInfo{}
+	found := false
+	err := walkMP4(r, 0, size, 0, func(boxType string, offset, length int64) error {
+		switch boxType {
+		case "mvhd":
+			duration, err := readMovieHeader(r, offset, length)
+			if err != nil {
+				return err
+			}
+			info.Duration = duration
+			found = true
+		case "tkhd":
+			// Audio tracks have no frame size; the first visual track wins
+			if info.Width == 0 && length >= 84 {
+				var dims [8]byte
+				if _, err := r.ReadAt(dims[:], offset+length-8); err != nil {
+					return err
+				}
+				info.Width = int(binary.BigEndian.Uint32(dims[0:4]) >> 16)
+				info.Height = int(binary.BigEndian.Uint32(dims[4:8]) >> 16)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errNoMovieHeader
+	}
+	return info, nil
+}
+
+// walkMP4 calls fn for each box between start and end, descending into
+// container boxes. offset and length cover the box's payload.
+func walkMP4(r io.ReaderAt, start, end int64, depth int, fn func(boxType string, offset, length int64) error) error {
+	if depth > maxMP4Depth {
+		return nil
+	}
+	for pos := start; pos+8 <= end; {
+		var header [16]byte
+		if _, err := r.ReadAt(header[:8], pos); err != nil {
+			return err
+		}
+		boxSize := int64(binary.BigEndian.Uint32(header[0:4]))
+		boxType := string(header[4:8])
+		headerSize := int64(8)
+
+		switch boxSize {
+		case 0:
+			// Box extends to the end of the file
+			boxSize = end - pos
+		case 1:
+			// 64-bit size follows the type
+			if _, err := r.ReadAt(header[8:16], pos+8); err != nil {
+				return err
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if boxSize < headerSize || pos+boxSize > end {
+			return errors.New("invalid box size")
+		}
+
+		offset, length := pos+headerSize, boxSize-headerSize
+		if mp4Containers[boxType] {
+			if err := walkMP4(r, offset, offset+length, depth+1, fn); err != nil {
+				return err
+			}
+		} else if err := fn(boxType, offset, length); err != nil {
+			return err
+		}
+		pos += boxSize
+	}
+	return nil
+}
+
+// readMovieHeader returns the duration in seconds from an mvhd payload
+func readMovieHeader(r io.ReaderAt, offset, length int64) (float64, error) {
+	var buf [32]byte
+	if length < 20 {
+		return 0, errors.New("movie header too short")
+	}
+	n := int64(len(buf))
+	if length < n {
+		n = length
+	}
+	if _, err := r.ReadAt(buf[:n], offset); err != nil {
+		return 0, err
+	}
+
+	var timescale uint32
+	var duration uint64
+	if buf[0] == 1 {
+		// Version 1: 64-bit creation and modification times and duration
+		if n < 32 {
+			return 0, errors.New("movie header too short")
+		}
+		timescale = binary.BigEndian.Uint32(buf[20:24])
+		duration = binary.BigEndian.Uint64(buf[24:32])
+	} else {
+		timescale = binary.BigEndian.Uint32(buf[12:16])
+		duration = uint64(binary.BigEndian.Uint32(buf[16:20]))
+	}
+	if timescale == 0 {
+		return 0, errors.New("movie header has no timescale")
+	}
+	return float64(duration) / float64(timescale), nil
+}
@@ -0,0 +1,65 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// box encodes an MP4 box with a 32-bit size
+func box(boxType string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	out := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(out[0:4], uint32(8+len(body)))
+	copy(out[4:8], boxType)
+	return append(out, body...)
+}
+
+// movieHeader encodes a version 0 mvhd payload
+func movieHeader(timescale, duration uint32) []byte {
+	payload := make([]byte, 100)
+	binary.BigEndian.PutUint32(payload[12:16], timescale)
+	binary.BigEndian.PutUint32(payload[16:20], duration)
+	return payload
+}
+
+// trackHeader encodes a version 0 tkhd payload with a frame size
+func trackHeader(width, height int) []byte {
+	payload := make([]byte, 84)
+	binary.BigEndian.PutUint32(payload[76:80], uint32(width)<<16)
+	binary.BigEndian.PutUint32(payload[80:84], uint32(height)<<16)
+	return payload
+}
+
+func TestProbeMP4(t *testing.T) {
+	file := bytes.Join([][]byte{
+		box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2")),
+		box("mdat", make([]byte, 64)),
+		box("moov",
+			box("mvhd", movieHeader(1000, 12500)),
+			box("trak", box("tkhd", trackHeader(0, 0))), // audio track
+			box("trak", box("tkhd", trackHeader(1920, 1080)), box("mdia", box("hdlr", make([]byte, 24)))),
+		),
+	}, nil)
+
+	info, err := ProbeMP4(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatalf("ProbeMP4: %v", err)
+	}
+	if info.Duration != 12.5 || info.Width != 1920 || info.Height != 1080 {
+		t.Errorf("ProbeMP4 = %+v, want 12.5s at 1920x1080", info)
+	}
+}
+
+func TestProbeMP4Invalid(t *testing.T) {
+	tests := map[string][]byte{
+		"no movie header": box("ftyp", []byte("isom")),
+		"truncated box":   box("moov", box("mvhd", movieHeader(600, 600)))[:40],
+		"zero timescale":  box("moov", box("mvhd", movieHeader(0, 600))),
+	}
+	for name, file := range tests {
+		if _, err := ProbeMP4(bytes.NewReader(file), int64(len(file))); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
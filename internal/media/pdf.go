@@ -0,0 +1,474 @@
+package media
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// maxPDFStreamSize bounds a decompressed PDF stream
+const maxPDFStreamSize = 16 << 20
+
+// errNotPDF is returned for data that doesn't start with a PDF header
+var errNotPDF = errors.New("not a PDF file")
+
+var (
+	pdfStreamPattern    = regexp.MustCompile(`stream\r?\n`)
+	pdfLengthPattern    = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+	pdfPagesPattern     = regexp.MustCompile(`/Type\s*/Pages\b`)
+	pdfCountPattern     = regexp.MustCompile(`/Count\s+(\d+)`)
+	pdfPagePattern      = regexp.MustCompile(`/Type\s*/Page\b`)
+	pdfSkippedDictTypes = regexp.MustCompile(`/(Subtype\s*/Image|Length1|Length2|Length3|Type\s*/XRef|Type\s*/Metadata|Subtype\s*/Type1C|Subtype\s*/CIDFontType0C|Subtype\s*/OpenType)\b`)
+)
+
+// PDFText is the text and page count of a PDF
+type PDFText struct {
+	Text  string
+	Pages int
+}
+
+// ExtractPDF reads the text and page count of a PDF. Text is taken from the
+// text operators of uncompressed and Flate-compressed content streams, read
+// as PDFDocEncoding or UTF-16; text in fonts with custom encodings may come
+// out incomplete. Scanned PDFs (images only) have no text.
+func ExtractPDF(data []byte) (*PDFText, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF-")) {
+		return nil, errNotPDF
+	}
+
+	var text strings.Builder
+	pages := pageCount(data)
+	objectPages := 0
+
+	for _, loc := range pdfStreamPattern.FindAllIndex(data, -1) {
+		dict, ok := streamDict(data, loc[0])
+		if !ok || pdfSkippedDictTypes.Match(dict) {
+			continue
+		}
+		content, ok := streamContent(data, dict, loc[1])
+		if !ok {
+			continue
+		}
+
+		if bytes.Contains(dict, []byte("/ObjStm")) {
+			// Compressed object streams hold page objects of newer PDFs
+			if pages == 0 {
+				if n := pageCount(content); n > objectPages {
+					objectPages = n
+				}
+			}
+			continue
+		}
+		if bytes.Contains(content, []byte("BT")) {
+			if extracted := contentStreamText(content); extracted != "" {
+				text.WriteString(extracted)
+				text.WriteString("\n")
+			}
+		}
+	}
+
+	if pages == 0 {
+		pages = objectPages
+	}
+	return &PDFText{Text: strings.TrimSpace(text.String()), Pages: pages}, nil
+}
+
+// pageCount returns the page count of the root page tree in data (the
+// largest /Count of a /Pages node), or the number of page objects
+func pageCount(data []byte) int {
+	count := 0
+	for _, loc := range pdfPagesPattern.FindAllIndex(data, -1) {
+		start, end := dictBounds(data, loc[0])
+		if m := pdfCountPattern.FindSubmatch(data[start:end]); m != nil {
+			if n, err := strconv.Atoi(string(m[1])); err == nil && n > count {
+				count = n
+			}
+		}
+	}
+	if count == 0 {
+		count = len(pdfPagePattern.FindAllIndex(data, -1))
+	}
+	return count
+}
+
+// dictBounds returns the bounds of the innermost dictionary around pos
+func dictBounds(data []byte, pos int) (int, int) {
+	start := bytes.LastIndex(data[:pos], []byte("<<"))
+	if start < 0 {
+		start = 0
+	}
+	end := bytes.Index(data[pos:], []byte(">>"))
+	if end < 0 {
+		return start, len(data)
+	}
+	return start, pos + end
+}
+
+// streamDict returns the dictionary before the stream keyword at pos
+func streamDict(data []byte, pos int) ([]byte, bool) {
+	end := len(bytes.TrimRight(data[:pos], "\x00\t\r\n\f "))
+	if end < 2 || string(data[end-2:end]) != ">>" {
+		return nil, false
+	}
+
+	// Walk back to the matching "<<", skipping nested dictionaries
+	depth := 0
+	for i := end - 2; i >= 1; i-- {
+		switch {
+		case data[i] == '>' && data[i+1] == '>':
+			depth++
+			i--
+		case data[i-1] == '<' && data[i] == '<':
+			depth--
+			if depth == 0 {
+				return data[i-1 : end], true
+			}
+			i--
+		}
+	}
+	return nil, false
+}
+
+// streamContent returns the decoded content of a stream starting at start,
+// or false when it uses a filter other than FlateDecode
+func streamContent(data, dict []byte, start int) ([]byte, bool) {
+	end := -1
+	if m := pdfLengthPattern.FindSubmatch(dict); m != nil && len(m[2]) == 0 {
+		if n, err := strconv.Atoi(string(m[1])); err == nil && start+n <= len(data) {
+			end = start + n
+		}
+	}
+	if end < 0 {
+		// Indirect or missing length
+		i := bytes.Index(data[start:], []byte("endstream"))
+		if i < 0 {
+			return nil, false
+		}
+		end = start + i
+	}
+	raw := data[start:end]
+
+	filters := 0
+	for _, filter := range []string{"/FlateDecode", "/ASCIIHexDecode", "/ASCII85Decode", "/LZWDecode", "/RunLengthDecode", "/DCTDecode", "/JPXDecode", "/CCITTFaxDecode", "/JBIG2Decode"} {
+		if bytes.Contains(dict, []byte(filter)) {
+			filters++
+		}
+	}
+	switch {
+	case filters == 0:
+		return raw, true
+	case filters == 1 && bytes.Contains(dict, []byte("/FlateDecode")):
+		zr, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, false
+		}
+		defer zr.Close()
+		// Keep what was decoded before a truncated or corrupt end
+		content, _ := io.ReadAll(io.LimitReader(zr, maxPDFStreamSize))
+		return content, len(content) > 0
+	default:
+		return nil, false
+	}
+}
+
+// contentStreamText returns the text shown by the operators of a page
+// content stream, a line per text line
+func contentStreamText(content []byte) string {
+	var out strings.Builder
+	var operands []interface{} // strings ([]byte), numbers (float64), arrays ([]interface{})
+	var array []interface{}
+	inArray := false
+
+	push := func(v interface{}) {
+		if inArray {
+			array = append(array, v)
+		} else {
+			operands = append(operands, v)
+		}
+	}
+	newline := func() {
+		if out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+			out.WriteString("\n")
+		}
+	}
+	space := func() {
+		s := out.String()
+		if out.Len() > 0 && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+			out.WriteString(" ")
+		}
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case isPDFSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, next := readLiteralString(content, i)
+			push(s)
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			// Inline dictionary (marked content properties)
+			i = skipDict(content, i)
+		case c == '<':
+			s, next := readHexString(content, i)
+			push(s)
+			i = next
+		case c == '[':
+			inArray, array = true, nil
+			i++
+		case c == ']':
+			inArray = false
+			operands = append(operands, array)
+			i++
+		case c == '/':
+			i++
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			push(nil)
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			start := i
+			i++
+			for i < len(content) && (content[i] == '.' || (content[i] >= '0' && content[i] <= '9')) {
+				i++
+			}
+			n, _ := strconv.ParseFloat(string(content[start:i]), 64)
+			push(n)
+		default:
+			start := i
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			op := string(content[start:i])
+			if op == "BI" {
+				// Skip inline image data
+				if end := bytes.Index(content[i:], []byte("EI")); end >= 0 {
+					i += end + 2
+				} else {
+					i = len(content)
+				}
+			}
+
+			switch op {
+			case "Tj":
+				out.WriteString(lastString(operands))
+			case "'", "\"":
+				newline()
+				out.WriteString(lastString(operands))
+			case "TJ":
+				if len(operands) > 0 {
+					if parts, ok := operands[len(operands)-1].([]interface{}); ok {
+						for _, part := range parts {
+							switch v := part.(type) {
+							case []byte:
+								out.WriteString(decodePDFString(v))
+							case float64:
+								// Wide negative adjustments separate words
+								if v < -200 {
+									space()
+								}
+							}
+						}
+					}
+				}
+			case "Td", "TD":
+				if len(operands) >= 2 {
+					if ty, ok := operands[len(operands)-1].(float64); ok && ty != 0 {
+						newline()
+					} else {
+						space()
+					}
+				}
+			case "T*", "ET":
+				newline()
+			case "Tm":
+				space()
+			}
+			operands = operands[:0]
+		}
+	}
+
+	// Collapse runs of spaces within lines
+	lines := strings.Split(out.String(), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// lastString returns the decoded last operand when it is a string
+func lastString(operands []interface{}) string {
+	if len(operands) == 0 {
+		return ""
+	}
+	if s, ok := operands[len(operands)-1].([]byte); ok {
+		return decodePDFString(s)
+	}
+	return ""
+}
+
+// readLiteralString reads a (...) string starting at i, returning its bytes
+// and the position after it
+func readLiteralString(data []byte, i int) ([]byte, int) {
+	var out []byte
+	depth := 0
+	for i++; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			if depth == 0 {
+				return out, i + 1
+			}
+			depth--
+			out = append(out, c)
+		case '\\':
+			i++
+			if i >= len(data) {
+				return out, i
+			}
+			switch e := data[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				// Line continuation
+				if i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					for j := 0; j < 3 && i < len(data) && data[i] >= '0' && data[i] <= '7'; j++ {
+						n = n*8 + int(data[i]-'0')
+						i++
+					}
+					i--
+					out = append(out, byte(n))
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return out, i
+}
+
+// readHexString reads a <...> string starting at i
+func readHexString(data []byte, i int) ([]byte, int) {
+	var out []byte
+	var digits []byte
+	for i++; i < len(data) && data[i] != '>'; i++ {
+		if v, ok := hexValue(data[i]); ok {
+			digits = append(digits, v)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, 0)
+	}
+	for j := 0; j < len(digits); j += 2 {
+		out = append(out, digits[j]<<4|digits[j+1])
+	}
+	return out, i + 1
+}
+
+// skipDict returns the position after the << ... >> dictionary at i
+func skipDict(data []byte, i int) int {
+	depth := 0
+	for i < len(data)-1 {
+		switch {
+		case data[i] == '<' && data[i+1] == '<':
+			depth++
+			i += 2
+		case data[i] == '>' && data[i+1] == '>':
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return len(data)
+}
+
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// decodePDFString decodes a text string as UTF-16 (with a byte order mark)
+// or PDFDocEncoding, dropping characters that aren't printable
+func decodePDFString(s []byte) string {
+	var runes []rune
+	if len(s) >= 2 && s[0] == 0xFE && s[1] == 0xFF {
+		units := make([]uint16, 0, len(s)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		runes = utf16.Decode(units)
+	} else {
+		runes = make([]rune, len(s))
+		for i, b := range s {
+			runes[i] = rune(b) // PDFDocEncoding matches Latin-1 for printable text
+		}
+	}
+
+	var out strings.Builder
+	for _, r := range runes {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			out.WriteRune(' ')
+		case unicode.IsPrint(r):
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
@@ -0,0 +1,81 @@
+package media
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF assembles a PDF whose pages show the given content streams; the
+// second and later streams are Flate-compressed
+func buildPDF(t *testing.T, streams ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, len(streams))
+	for i := range streams {
+		kids[i] = fmt.Sprintf("%d 0 R", 3+2*i)
+	}
+	buf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	fmt.Fprintf(&buf, "2 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(streams))
+
+	for i, content := range streams {
+		page, stream := 3+2*i, 4+2*i
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents %d 0 R /Resources << /Font << /F1 << /Type /Font /Subtype /Type1 /BaseFont /Helvetica >> >> >> >>\nendobj\n", page, stream)
+
+		data, filter := []byte(content), ""
+		if i > 0 {
+			var compressed bytes.Buffer
+			zw := zlib.NewWriter(&compressed)
+			zw.Write(data)
+			zw.Close()
+			data, filter = compressed.Bytes(), " /Filter /FlateDecode"
+		}
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Length %d%s >>\nstream\n", stream, len(data), filter)
+		buf.Write(data)
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func TestExtractPDF(t *testing.T) {
+	data := buildPDF(t,
+		"BT /F1 24 Tf 72 720 Td (Annual Report) Tj 0 -30 Td (Revenue grew \\(again\\)) Tj ET",
+		"BT /F1 12 Tf 72 700 Td [(Caf) 10 (\\351 ) -300 (menu)] TJ T* <FEFF00480069> Tj ET",
+	)
+
+	result, err := ExtractPDF(data)
+	if err != nil {
+		t.Fatalf("ExtractPDF: %v", err)
+	}
+	if result.Pages != 2 {
+		t.Errorf("Pages = %d, want 2", result.Pages)
+	}
+	for _, want := range []string{"Annual Report", "Revenue grew (again)", "Café menu", "Hi"} {
+		if !strings.Contains(result.Text, want) {
+			t.Errorf("Text %q does not contain %q", result.Text, want)
+		}
+	}
+	if lines := strings.Split(result.Text, "\n"); lines[0] != "Annual Report" {
+		t.Errorf("first line = %q, want %q", lines[0], "Annual Report")
+	}
+}
+
+func TestExtractPDFInvalid(t *testing.T) {
+	if _, err := ExtractPDF([]byte("PK\x03\x04 not a pdf")); err == nil {
+		t.Error("expected an error for data without a PDF header")
+	}
+
+	// A PDF with no text still reports its pages
+	result, err := ExtractPDF(buildPDF(t, "q 100 0 0 100 0 0 cm Q"))
+	if err != nil {
+		t.Fatalf("ExtractPDF: %v", err)
+	}
+	if result.Pages != 1 || result.Text != "" {
+		t.Errorf("ExtractPDF = %+v, want 1 page without text", result)
+	}
+}
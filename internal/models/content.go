@@ -131,6 +131,8 @@ type SEO struct {
 type Schema struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description,omitempty"`
+	Extends     string                 `json:"extends,omitempty"` // Schema whose fields, storage, and settings this one inherits
+	Mixins      []string               `json:"mixins,omitempty"`  // Schemas whose fields are included, in order
 	Fields      map[string]FieldDef    `json:"fields,omitempty"`
 	Storage     StorageConfig          `json:"storage,omitempty"`
 	Settings    map[string]interface{} `json:"settings,omitempty"`
@@ -138,13 +140,16 @@ type Schema struct {
 
 // FieldDef defines a field in a schema
 type FieldDef struct {
-	Type        string                 `json:"type"`                  // string, number, boolean, array, object, image, file
+	Type        string                 `json:"type"`                  // string, number, boolean, array, object, image, file, reference
 	Required    bool                   `json:"required,omitempty"`
 	Default     interface{}            `json:"default,omitempty"`
 	Description string                 `json:"description,omitempty"`
 	Items       string                 `json:"items,omitempty"`       // For array type
 	Properties  map[string]FieldDef    `json:"properties,omitempty"`  // For object type
 	Options     map[string]interface{} `json:"options,omitempty"`     // Additional field options
+	ReadOnly    bool                   `json:"read_only,omitempty"`   // Can be set when the item is created, not changed after
+	Roles       []string               `json:"roles,omitempty"`       // Roles allowed to change the field (admins always can)
+	Encrypted   bool                   `json:"encrypted,omitempty"`   // Stored sealed; read back only with the decrypt permission
 }
 
 // StorageConfig defines how content of this type is stored
@@ -171,9 +176,10 @@ type ListResponse struct {
 
 // ErrorResponse represents an API error
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Code    int    `json:"code"`
+	Error   string   `json:"error"`
+	Message string   `json:"message"`
+	Code    int      `json:"code"`
+	Details []string `json:"details,omitempty"`
 }
 
 // ToJSON converts a model to JSON bytes
@@ -0,0 +1,93 @@
+// Package outbound provides the HTTP client for requests Velocity sends to
+// outside services: webhook deliveries, plugin hooks, external link checks,
+// and SNS subscription confirmations. By default they honor HTTPS_PROXY,
+// HTTP_PROXY, and NO_PROXY; Configure routes them through an explicit proxy
+// instead (NO_PROXY still applies).
+package outbound
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// transport is shared by every outbound client, so connections are reused
+var transport atomic.Pointer[http.Transport]
+
+func init() {
+	transport.Store(newTransport(http.ProxyFromEnvironment))
+}
+
+func newTransport(proxy func(*http.Request) (*url.URL, error)) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = proxy
+	return t
+}
+
+// ParseProxy validates a proxy URL (http, https, or socks5, with a host)
+func ParseProxy(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: scheme must be http, https, or socks5", u.Redacted())
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", u.Redacted())
+	}
+	return u, nil
+}
+
+// Configure sends outbound requests through proxy, except to hosts listed in
+// NO_PROXY. An empty proxy restores the environment's proxy settings.
+func Configure(proxy string) error {
+	if proxy == "" {
+		transport.Store(newTransport(http.ProxyFromEnvironment))
+		return nil
+	}
+	u, err := ParseProxy(proxy)
+	if err != nil {
+		return err
+	}
+
+	noProxy := os.Getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = os.Getenv("no_proxy")
+	}
+	proxyFunc := (&httpproxy.Config{
+		HTTPProxy:  u.String(),
+		HTTPSProxy: u.String(),
+		NoProxy:    noProxy,
+	}).ProxyFunc()
+
+	transport.Store(newTransport(func(r *http.Request) (*url.URL, error) {
+		return proxyFunc(r.URL)
+	}))
+	return nil
+}
+
+// Transport returns the transport outbound requests are sent with
+func Transport() http.RoundTripper {
+	return transport.Load()
+}
+
+// roundTripper sends each request with the transport configured at the time,
+// so clients made before Configure still use the proxy
+type roundTripper struct{}
+
+func (roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return transport.Load().RoundTrip(r)
+}
+
+// Client returns a client for outbound requests with the given timeout
+func Client(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: roundTripper{}, Timeout: timeout}
+}
@@ -0,0 +1,122 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"velocity/internal/log"
+	"velocity/internal/outbound"
+)
+
+// HTTPHook calls an external policy endpoint around writes.
+//
+// The endpoint receives {"phase": "before"|"after", "request": {...}, "content": "...", "result": {...}}.
+// For "before" it responds with {"allow": bool, "reason": "...", "content": ..., "metadata": {...}};
+// content and metadata, when present, replace the request's values. Before hooks fail
+// closed: an unreachable endpoint or non-2xx response rejects the request.
+type HTTPHook struct {
+	URL    string
+	client *http.Client
+}
+
+// NewHTTPHook creates a policy hook for the given URL
+func NewHTTPHook(url string, timeout time.Duration) *HTTPHook {
+	return &HTTPHook{
+		URL:    url,
+		client: outbound.Client(timeout),
+	}
+}
+
+// Name returns the hook URL
+func (h *HTTPHook) Name() string {
+	return h.URL
+}
+
+type httpHookDecision struct {
+	Allow    bool              `json:"allow"`
+	Reason   string            `json:"reason,omitempty"`
+	Content  json.RawMessage   `json:"content,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Before asks the endpoint whether to allow (and how to modify) the request
+func (h *HTTPHook) Before(ctx context.Context, req *Request) error {
+	resp, err := h.post(ctx, "before", req, nil)
+	if err != nil {
+		return &Rejection{Hook: h.Name(), Reason: fmt.Sprintf("policy hook unavailable: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &Rejection{Hook: h.Name(), Reason: fmt.Sprintf("policy hook returned %d", resp.StatusCode)}
+	}
+
+	var decision httpHookDecision
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return &Rejection{Hook: h.Name(), Reason: "invalid policy hook response"}
+	}
+
+	if !decision.Allow {
+		reason := decision.Reason
+		if reason == "" {
+			reason = "request denied by policy"
+		}
+		return &Rejection{Hook: h.Name(), Reason: reason}
+	}
+
+	if len(decision.Content) > 0 && req.Content != nil {
+		req.Content = decision.Content
+		// Non-JSON content is returned as a JSON string
+		var text string
+		if json.Unmarshal(decision.Content, &text) == nil {
+			req.Content = []byte(text)
+		}
+	}
+	if decision.Metadata != nil {
+		req.Metadata = decision.Metadata
+	}
+
+	return nil
+}
+
+// After notifies the endpoint of a completed write (errors are logged)
+func (h *HTTPHook) After(ctx context.Context, req *Request, result *Result) {
+	resp, err := h.post(ctx, "after", req, result)
+	if err != nil {
+		log.Debug("Policy hook %s failed: %v", h.URL, err)
+		return
+	}
+	resp.Body.Close()
+}
+
+func (h *HTTPHook) post(ctx context.Context, phase string, req *Request, result *Result) (*http.Response, error) {
+	payload := map[string]interface{}{
+		"phase":   phase,
+		"request": req,
+	}
+	if result != nil {
+		payload["result"] = result
+	}
+
+	// Content is sent as text (omitted for streamed bodies)
+	if req.Content != nil {
+		payload["content"] = string(req.Content)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	return h.client.Do(httpReq)
+}
@@ -0,0 +1,117 @@
+// Package plugin provides hooks invoked around content writes and transitions.
+// Hooks can be compiled in (Register from an init function) or external HTTP
+// policy endpoints (see HTTPHook).
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Operation identifies the action a hook is invoked for
+type Operation string
+
+const (
+	OpCreate     Operation = "create"
+	OpUpdate     Operation = "update"
+	OpDelete     Operation = "delete"
+	OpTransition Operation = "transition"
+)
+
+// Request describes a content write or transition.
+// Before hooks may modify Content and Metadata; changes are applied to the write.
+type Request struct {
+	Operation   Operation         `json:"operation"`
+	Tenant      string            `json:"tenant"`
+	Type        string            `json:"type"`
+	ID          string            `json:"id"`
+	State       string            `json:"state,omitempty"`
+	From        string            `json:"from,omitempty"`
+	To          string            `json:"to,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Content     []byte            `json:"-"` // nil for streamed (large or binary) bodies
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Author      string            `json:"author,omitempty"`
+	Message     string            `json:"message,omitempty"`
+}
+
+// Result describes a completed write or transition
+type Result struct {
+	Key     string `json:"key,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// Hook is invoked before and after content writes and transitions
+type Hook interface {
+	// Name identifies the hook in logs and rejections
+	Name() string
+
+	// Before runs before the write. Returning an error rejects the request.
+	Before(ctx context.Context, req *Request) error
+
+	// After runs once the write succeeded. It cannot affect the response.
+	After(ctx context.Context, req *Request, result *Result)
+}
+
+// Rejection is returned by a hook to refuse a request with a reason shown to the client
+type Rejection struct {
+	Hook   string
+	Reason string
+}
+
+func (r *Rejection) Error() string {
+	return fmt.Sprintf("rejected by %s: %s", r.Hook, r.Reason)
+}
+
+var (
+	mu    sync.RWMutex
+	hooks []Hook
+)
+
+// Register adds a hook. Hooks run in registration order.
+func Register(hook Hook) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = append(hooks, hook)
+}
+
+// Hooks returns the registered hooks
+func Hooks() []Hook {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Hook(nil), hooks...)
+}
+
+// Enabled reports whether any hooks are registered
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(hooks) > 0
+}
+
+// RunBefore runs all Before hooks in order, stopping at the first rejection
+func RunBefore(ctx context.Context, req *Request) error {
+	for _, hook := range Hooks() {
+		if err := hook.Before(ctx, req); err != nil {
+			if _, ok := err.(*Rejection); ok {
+				return err
+			}
+			return &Rejection{Hook: hook.Name(), Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+// RunAfter runs all After hooks asynchronously
+func RunAfter(req *Request, result *Result) {
+	registered := Hooks()
+	if len(registered) == 0 {
+		return
+	}
+	go func() {
+		for _, hook := range registered {
+			hook.After(context.Background(), req, result)
+		}
+	}()
+}
@@ -0,0 +1,138 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// WASM plugin ABI
+//
+// A module exports its linear memory as "memory" and two functions:
+//
+//	alloc(size i32) -> ptr i32                 reserve size bytes for the input
+//	transform(ptr i32, len i32) -> i64          transform the input, returning (out_ptr << 32 | out_len)
+//
+// The input is a JSON TransformInput; the output is the new content bytes.
+// Modules may import WASI (no filesystem, network, or clock access is granted).
+
+// TransformInput is passed to a WASM plugin's transform function
+type TransformInput struct {
+	Phase       string `json:"phase"` // "write" or "render"
+	Tenant      string `json:"tenant"`
+	Type        string `json:"type"`
+	ID          string `json:"id"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content"`
+}
+
+// WASMConfig holds sandbox limits for WASM plugins
+type WASMConfig struct {
+	MemoryLimitMB int           // Max linear memory per instance
+	Timeout       time.Duration // Max execution time per call
+}
+
+// WASMRuntime compiles and runs sandboxed WASM transform plugins
+type WASMRuntime struct {
+	runtime wazero.Runtime
+	timeout time.Duration
+}
+
+// WASMModule is a compiled plugin module
+type WASMModule struct {
+	rt       *WASMRuntime
+	compiled wazero.CompiledModule
+}
+
+// NewWASMRuntime creates a runtime enforcing the configured limits
+func NewWASMRuntime(ctx context.Context, cfg WASMConfig) *WASMRuntime {
+	if cfg.MemoryLimitMB <= 0 {
+		cfg.MemoryLimitMB = 64
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Second
+	}
+
+	// 64KiB per WASM page
+	pages := uint32(cfg.MemoryLimitMB * 16)
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(pages).
+		WithCloseOnContextDone(true))
+	wasi_snapshot_preview1.MustInstantiate(ctx, rt)
+
+	return &WASMRuntime{runtime: rt, timeout: cfg.Timeout}
+}
+
+// Compile validates and compiles a plugin module
+func (wr *WASMRuntime) Compile(ctx context.Context, wasm []byte) (*WASMModule, error) {
+	compiled, err := wr.runtime.CompileModule(ctx, wasm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile module: %w", err)
+	}
+
+	exports := compiled.ExportedFunctions()
+	for _, name := range []string{"alloc", "transform"} {
+		if _, ok := exports[name]; !ok {
+			compiled.Close(ctx)
+			return nil, fmt.Errorf("module does not export %q", name)
+		}
+	}
+	if _, ok := compiled.ExportedMemories()["memory"]; !ok {
+		compiled.Close(ctx)
+		return nil, fmt.Errorf("module does not export memory")
+	}
+
+	return &WASMModule{rt: wr, compiled: compiled}, nil
+}
+
+// Close releases the compiled module
+func (m *WASMModule) Close(ctx context.Context) {
+	m.compiled.Close(ctx)
+}
+
+// Transform runs the module on the input in a fresh instance, so no state
+// leaks between calls. Execution is aborted when the timeout elapses.
+func (m *WASMModule) Transform(ctx context.Context, input *TransformInput) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.rt.timeout)
+	defer cancel()
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	mod, err := m.rt.runtime.InstantiateModule(ctx, m.compiled, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate module: %w", err)
+	}
+	defer mod.Close(context.Background())
+
+	res, err := mod.ExportedFunction("alloc").Call(ctx, uint64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("alloc failed: %w", err)
+	}
+	ptr := uint32(res[0])
+	if !mod.Memory().Write(ptr, data) {
+		return nil, fmt.Errorf("alloc returned out-of-range pointer")
+	}
+
+	res, err = mod.ExportedFunction("transform").Call(ctx, uint64(ptr), uint64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("transform failed: %w", err)
+	}
+
+	outPtr, outLen := uint32(res[0]>>32), uint32(res[0])
+	out, ok := mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("transform returned out-of-range result")
+	}
+
+	// Copy out before the instance memory is released
+	return append([]byte(nil), out...), nil
+}
@@ -0,0 +1,205 @@
+// Package secrets resolves configuration values that refer to a secret
+// manager instead of holding the secret itself:
+//
+//	vault:{path}#{field}   HashiCorp Vault (KV v1, or KV v2 with data/ in the path)
+//	ssm:{parameter name}   AWS SSM Parameter Store (SecureStrings are decrypted)
+//
+// Any other value is used as it is.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// SSMAPI is the subset of the SSM client used to read parameters
+type SSMAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// Ensure *ssm.Client implements SSMAPI
+var _ SSMAPI = (*ssm.Client)(nil)
+
+// Resolver resolves secret references
+type Resolver struct {
+	VaultAddr      string // Vault server address (VAULT_ADDR)
+	VaultToken     string // Vault token (VAULT_TOKEN)
+	VaultTokenFile string // File holding the Vault token, re-read on every lookup (VAULT_TOKEN_FILE)
+	VaultNamespace string // Vault Enterprise namespace (VAULT_NAMESPACE, optional)
+
+	HTTPClient *http.Client // Client for Vault requests
+	SSM        SSMAPI       // SSM client (built from the default AWS config when nil)
+
+	ssmOnce sync.Once
+	ssmErr  error
+}
+
+// NewResolver creates a resolver configured from the standard Vault
+// environment variables
+func NewResolver() *Resolver {
+	return &Resolver{
+		VaultAddr:      os.Getenv("VAULT_ADDR"),
+		VaultToken:     os.Getenv("VAULT_TOKEN"),
+		VaultTokenFile: os.Getenv("VAULT_TOKEN_FILE"),
+		VaultNamespace: os.Getenv("VAULT_NAMESPACE"),
+		HTTPClient:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// IsReference reports whether a value refers to a secret manager
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, "vault:") || strings.HasPrefix(value, "ssm:")
+}
+
+// Resolve returns the secret a value refers to, or the value itself
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "vault:"):
+		return r.vault(ctx, strings.TrimPrefix(value, "vault:"))
+	case strings.HasPrefix(value, "ssm:"):
+		return r.ssm(ctx, strings.TrimPrefix(value, "ssm:"))
+	default:
+		return value, nil
+	}
+}
+
+// vault reads a field of a Vault secret: {path}#{field}
+func (r *Resolver) vault(ctx context.Context, ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("invalid vault reference %q (expected vault:{path}#{field})", ref)
+	}
+	if r.VaultAddr == "" {
+		return "", fmt.Errorf("vault:%s: VAULT_ADDR is not set", path)
+	}
+
+	token := r.VaultToken
+	if r.VaultTokenFile != "" {
+		data, err := os.ReadFile(r.VaultTokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read Vault token: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(r.VaultAddr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if r.VaultNamespace != "" {
+		req.Header.Set("X-Vault-Namespace", r.VaultNamespace)
+	}
+
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault:%s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("vault:%s: Vault returned %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("vault:%s: invalid response: %w", path, err)
+	}
+
+	// KV v2 nests the secret under data.data, next to its metadata
+	data := result.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+	value, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("vault:%s has no field %s", path, field)
+	}
+	return value, nil
+}
+
+// ssm reads a parameter from SSM Parameter Store
+func (r *Resolver) ssm(ctx context.Context, name string) (string, error) {
+	r.ssmOnce.Do(func() {
+		if r.SSM != nil {
+			return
+		}
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			r.ssmErr = fmt.Errorf("failed to load AWS config: %w", err)
+			return
+		}
+		r.SSM = ssm.NewFromConfig(cfg)
+	})
+	if r.ssmErr != nil {
+		return "", r.ssmErr
+	}
+
+	result, err := r.SSM.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", fmt.Errorf("ssm:%s: %w", name, err)
+	}
+	if result.Parameter == nil {
+		return "", fmt.Errorf("ssm:%s has no value", name)
+	}
+	return aws.ToString(result.Parameter.Value), nil
+}
+
+// =============================================================================
+// AWS Credentials
+// =============================================================================
+
+// Credentials provides an access key pair whose parts may be secret
+// references. They are resolved again after Refresh, so keys rotated in the
+// secret manager are picked up without a restart. Wrap it in
+// aws.NewCredentialsCache.
+type Credentials struct {
+	Resolver        *Resolver
+	AccessKeyID     string
+	SecretAccessKey string
+	Refresh         time.Duration
+}
+
+// Ensure Credentials implements aws.CredentialsProvider
+var _ aws.CredentialsProvider = (*Credentials)(nil)
+
+// Retrieve resolves the key pair
+func (c *Credentials) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	accessKeyID, err := c.Resolver.Resolve(ctx, c.AccessKeyID)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	secretAccessKey, err := c.Resolver.Resolve(ctx, c.SecretAccessKey)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	creds := aws.Credentials{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		Source:          "velocity/secrets",
+	}
+	if c.Refresh > 0 {
+		creds.CanExpire = true
+		creds.Expires = time.Now().Add(c.Refresh)
+	}
+	return creds, nil
+}
@@ -0,0 +1,110 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+func newVault(t *testing.T) *Resolver {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/velocity": // KV v1
+			w.Write([]byte(`{"data":{"access_key":"v1-key"}}`))
+		case "/v1/secret/data/velocity": // KV v2
+			w.Write([]byte(`{"data":{"data":{"access_key":"v2-key"},"metadata":{"version":3}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return &Resolver{VaultAddr: server.URL, VaultToken: "root", HTTPClient: server.Client()}
+}
+
+func TestResolveVault(t *testing.T) {
+	ctx := context.Background()
+	r := newVault(t)
+
+	for ref, want := range map[string]string{
+		"vault:kv/velocity#access_key":          "v1-key",
+		"vault:secret/data/velocity#access_key": "v2-key",
+		"plain-value":                           "plain-value",
+	} {
+		got, err := r.Resolve(ctx, ref)
+		if err != nil {
+			t.Errorf("Resolve(%s): %v", ref, err)
+		} else if got != want {
+			t.Errorf("Resolve(%s) = %q, want %q", ref, got, want)
+		}
+	}
+
+	for _, ref := range []string{"vault:kv/velocity#missing", "vault:kv/other#access_key", "vault:kv/velocity"} {
+		if _, err := r.Resolve(ctx, ref); err == nil {
+			t.Errorf("Resolve(%s) succeeded", ref)
+		}
+	}
+
+	// The token file is re-read on every lookup
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(tokenFile, []byte("expired\n"), 0600)
+	r.VaultToken, r.VaultTokenFile = "", tokenFile
+	if _, err := r.Resolve(ctx, "vault:kv/velocity#access_key"); err == nil {
+		t.Error("Resolve succeeded with an expired token")
+	}
+	os.WriteFile(tokenFile, []byte("root\n"), 0600)
+	if _, err := r.Resolve(ctx, "vault:kv/velocity#access_key"); err != nil {
+		t.Errorf("Resolve after the token was renewed: %v", err)
+	}
+}
+
+// fakeSSM serves parameters from a map
+type fakeSSM map[string]string
+
+func (f fakeSSM) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	value, ok := f[aws.ToString(params.Name)]
+	if !ok {
+		return nil, &types.ParameterNotFound{}
+	}
+	return &ssm.GetParameterOutput{Parameter: &types.Parameter{Value: aws.String(value)}}, nil
+}
+
+func TestCredentialsRefresh(t *testing.T) {
+	ctx := context.Background()
+	params := fakeSSM{"/velocity/access_key": "AKIA1", "/velocity/secret_key": "secret1"}
+	creds := &Credentials{
+		Resolver:        &Resolver{SSM: params},
+		AccessKeyID:     "ssm:/velocity/access_key",
+		SecretAccessKey: "ssm:/velocity/secret_key",
+	}
+	cache := aws.NewCredentialsCache(creds)
+
+	got, err := cache.Retrieve(ctx)
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if got.AccessKeyID != "AKIA1" || got.SecretAccessKey != "secret1" {
+		t.Fatalf("Retrieve = %s/%s", got.AccessKeyID, got.SecretAccessKey)
+	}
+
+	// Rotated keys are picked up once the cached pair expires
+	params["/velocity/access_key"] = "AKIA2"
+	cache.Invalidate()
+	if got, _ := cache.Retrieve(ctx); got.AccessKeyID != "AKIA2" {
+		t.Errorf("AccessKeyID after rotation = %s, want AKIA2", got.AccessKeyID)
+	}
+
+	if _, err := (&Resolver{SSM: params}).Resolve(ctx, "ssm:/velocity/missing"); err == nil {
+		t.Error("Resolve of a missing parameter succeeded")
+	}
+}
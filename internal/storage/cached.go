@@ -2,6 +2,7 @@ package storage
 
 import (
 	"bytes"
+	"container/list"
 	"context"
 	"fmt"
 	"io"
@@ -17,7 +18,7 @@ import (
 type CacheConfig struct {
 	MaxTTL         time.Duration // idle TTL before eviction (default 1h)
 	MaxContentSize int64         // max body size to cache per entry (default 1MB)
-	MaxMemory      int64         // total memory budget for cached content (default 256MB)
+	MaxMemory      int64         // total memory budget; least recently used entries are evicted past it (default 256MB)
 	SweepInterval  time.Duration // background cleanup interval (default 1m)
 }
 
@@ -49,9 +50,11 @@ func (n *noopInvalidator) Close() error                                { return
 
 // cacheEntry holds a cached value and tracking metadata.
 type cacheEntry struct {
+	key          string
 	value        interface{}
 	lastAccessed time.Time
 	size         int64
+	element      *list.Element // position in the LRU list
 }
 
 // CachedStorage wraps a Storage implementation with an in-memory cache.
@@ -60,6 +63,7 @@ type CachedStorage struct {
 	inner         Storage
 	mu            sync.RWMutex
 	cache         map[string]*cacheEntry
+	lru           *list.List // entries, most recently used first
 	config        CacheConfig
 	invalidator   CacheInvalidator
 	currentMemory int64
@@ -87,6 +91,7 @@ func NewCachedStorage(inner Storage, config CacheConfig) *CachedStorage {
 	cs := &CachedStorage{
 		inner:       inner,
 		cache:       make(map[string]*cacheEntry),
+		lru:         list.New(),
 		config:      config,
 		invalidator: &noopInvalidator{},
 		stopCh:      make(chan struct{}),
@@ -144,12 +149,13 @@ func (cs *CachedStorage) sweep() {
 }
 
 func (cs *CachedStorage) get(key string) (interface{}, bool) {
-	cs.mu.RLock()
+	cs.mu.Lock()
 	entry, ok := cs.cache[key]
 	if ok {
 		entry.lastAccessed = time.Now()
+		cs.lru.MoveToFront(entry.element)
 	}
-	cs.mu.RUnlock()
+	cs.mu.Unlock()
 	return entryValue(entry, ok)
 }
 
@@ -170,27 +176,35 @@ func (cs *CachedStorage) set(key string, value interface{}, size int64) {
 	}
 
 	// Delete existing entry if present (reclaim memory)
-	if existing, ok := cs.cache[key]; ok {
-		cs.currentMemory -= existing.size
-		delete(cs.cache, key)
-	}
+	cs.deleteEntry(key)
 
-	// Check total memory budget
-	if cs.currentMemory+size > cs.config.MaxMemory {
+	// Make room by evicting the least recently used entries
+	if size > cs.config.MaxMemory {
 		return
 	}
+	for cs.currentMemory+size > cs.config.MaxMemory {
+		oldest := cs.lru.Back()
+		if oldest == nil {
+			return
+		}
+		cs.deleteEntry(oldest.Value.(*cacheEntry).key)
+	}
 
-	cs.cache[key] = &cacheEntry{
+	entry := &cacheEntry{
+		key:          key,
 		value:        value,
 		lastAccessed: time.Now(),
 		size:         size,
 	}
+	entry.element = cs.lru.PushFront(entry)
+	cs.cache[key] = entry
 	cs.currentMemory += size
 }
 
 func (cs *CachedStorage) deleteEntry(key string) {
 	if entry, ok := cs.cache[key]; ok {
 		cs.currentMemory -= entry.size
+		cs.lru.Remove(entry.element)
 		delete(cs.cache, key)
 	}
 }
@@ -243,6 +257,10 @@ func existsKey(tenant, contentType, id, ext string, state State) string {
 	return fmt.Sprintf("exists:%s:%s:%s:%s:%s", tenant, contentType, id, ext, state)
 }
 
+func metadataKey(tenant, contentType, id, ext string, state State) string {
+	return fmt.Sprintf("metadata:%s:%s:%s:%s:%s", tenant, contentType, id, ext, state)
+}
+
 func schemaKey(scope, tenant, name string) string {
 	return fmt.Sprintf("schema:%s:%s:%s", scope, tenant, name)
 }
@@ -251,7 +269,21 @@ func schemaKey(scope, tenant, name string) string {
 func writeInvalidationKeys(tenant, contentType, id, ext string, state State) []string {
 	return []string{
 		contentKey(tenant, contentType, id, ext, state),
+		contentKey(tenant, contentType, id, "", state), // FindContentStream without an extension hint
 		existsKey(tenant, contentType, id, ext, state),
+		metadataKey(tenant, contentType, id, ext, state),
+		metadataKey(tenant, contentType, id, "", state),
+	}
+}
+
+// metadataInvalidationKeys returns the cache keys to drop when an item's
+// metadata changes in place
+func metadataInvalidationKeys(tenant, contentType, id, ext string, state State) []string {
+	return []string{
+		contentKey(tenant, contentType, id, ext, state),
+		contentKey(tenant, contentType, id, "", state), // FindContentStream without an extension hint
+		metadataKey(tenant, contentType, id, ext, state),
+		metadataKey(tenant, contentType, id, "", state),
 	}
 }
 
@@ -263,6 +295,13 @@ func (cs *CachedStorage) invalidateOnWrite(tenant, contentType, id, ext string,
 	cs.invalidatePrefix(fmt.Sprintf("list:%s:%s:", tenant, contentType))
 }
 
+// Invalidate drops cached entries for a content item that changed outside this
+// instance (e.g. written directly to the bucket) and notifies peers.
+func (cs *CachedStorage) Invalidate(tenant, contentType, id, ext string, state State) {
+	cs.invalidateOnWrite(tenant, contentType, id, ext, state)
+	cs.invalidate([]string{contentKey(tenant, contentType, id, "", state)})
+}
+
 // --- Cached read methods ---
 
 func (cs *CachedStorage) Get(ctx context.Context, tenant, contentType, id, ext string, state State) (*ContentItem, error) {
@@ -583,6 +622,15 @@ func (cs *CachedStorage) Transition(ctx context.Context, tenant, contentType, id
 	return item, nil
 }
 
+func (cs *CachedStorage) CopyContent(ctx context.Context, tenant, contentType, id, ext string, fromState, toState State, metadata map[string]string) (*ContentItem, error) {
+	item, err := cs.inner.CopyContent(ctx, tenant, contentType, id, ext, fromState, toState, metadata)
+	if err != nil {
+		return nil, err
+	}
+	cs.invalidateOnWrite(tenant, contentType, id, ext, toState)
+	return item, nil
+}
+
 func (cs *CachedStorage) PutDirectoryIndex(ctx context.Context, tenant, contentType, prefix string, state State, index *DirectoryIndex) error {
 	err := cs.inner.PutDirectoryIndex(ctx, tenant, contentType, prefix, state, index)
 	if err != nil {
@@ -614,6 +662,15 @@ func (cs *CachedStorage) RestoreVersion(ctx context.Context, tenant, contentType
 	return item, nil
 }
 
+func (cs *CachedStorage) RestoreObject(ctx context.Context, tenant, contentType, id, ext string, state State, objectID string) (*ContentItem, error) {
+	item, err := cs.inner.RestoreObject(ctx, tenant, contentType, id, ext, state, objectID)
+	if err != nil {
+		return nil, err
+	}
+	cs.invalidateOnWrite(tenant, contentType, id, ext, state)
+	return item, nil
+}
+
 func (cs *CachedStorage) PutGlobalSchema(ctx context.Context, schemaName string, content []byte) error {
 	err := cs.inner.PutGlobalSchema(ctx, schemaName, content)
 	if err != nil {
@@ -650,12 +707,32 @@ func (cs *CachedStorage) DeleteTenantSchema(ctx context.Context, tenant, schemaN
 	return nil
 }
 
+func (cs *CachedStorage) GetMetadata(ctx context.Context, tenant, contentType, id, ext string, state State) (map[string]string, error) {
+	key := metadataKey(tenant, contentType, id, ext, state)
+	if cached, ok := cs.get(key); ok {
+		log.Debug("Cache hit: %s", key)
+		return copyMetadata(cached.(map[string]string)), nil
+	}
+
+	metadata, err := cs.inner.GetMetadata(ctx, tenant, contentType, id, ext, state)
+	if err != nil {
+		return nil, err
+	}
+
+	size := int64(64)
+	for k, v := range metadata {
+		size += int64(len(k) + len(v) + 32)
+	}
+	cs.set(key, copyMetadata(metadata), size)
+	return metadata, nil
+}
+
 func (cs *CachedStorage) SetMetadata(ctx context.Context, tenant, contentType, id, ext string, state State, metadata map[string]string) error {
 	err := cs.inner.SetMetadata(ctx, tenant, contentType, id, ext, state, metadata)
 	if err != nil {
 		return err
 	}
-	cs.invalidate([]string{contentKey(tenant, contentType, id, ext, state)})
+	cs.invalidate(metadataInvalidationKeys(tenant, contentType, id, ext, state))
 	return nil
 }
 
@@ -664,7 +741,7 @@ func (cs *CachedStorage) UpdateMetadata(ctx context.Context, tenant, contentType
 	if err != nil {
 		return err
 	}
-	cs.invalidate([]string{contentKey(tenant, contentType, id, ext, state)})
+	cs.invalidate(metadataInvalidationKeys(tenant, contentType, id, ext, state))
 	return nil
 }
 
@@ -673,7 +750,7 @@ func (cs *CachedStorage) DeleteMetadataKeys(ctx context.Context, tenant, content
 	if err != nil {
 		return err
 	}
-	cs.invalidate([]string{contentKey(tenant, contentType, id, ext, state)})
+	cs.invalidate(metadataInvalidationKeys(tenant, contentType, id, ext, state))
 	return nil
 }
 
@@ -691,10 +768,18 @@ func (cs *CachedStorage) GetVersion(ctx context.Context, tenant, contentType, id
 	return cs.inner.GetVersion(ctx, tenant, contentType, id, ext, versionID)
 }
 
+func (cs *CachedStorage) FindVersionByETag(ctx context.Context, tenant, contentType, id, ext string, state State, etag string) (*ContentItem, error) {
+	return cs.inner.FindVersionByETag(ctx, tenant, contentType, id, ext, state, etag)
+}
+
 func (cs *CachedStorage) GetVersionStream(ctx context.Context, tenant, contentType, id, ext, versionID string) (*ContentStream, error) {
 	return cs.inner.GetVersionStream(ctx, tenant, contentType, id, ext, versionID)
 }
 
+func (cs *CachedStorage) PruneVersions(ctx context.Context, tenant, contentType, id, ext string, dryRun bool) ([]*ContentVersion, error) {
+	return cs.inner.PruneVersions(ctx, tenant, contentType, id, ext, dryRun)
+}
+
 func (cs *CachedStorage) PutHistoryRecord(ctx context.Context, tenant, contentType, id string, record *HistoryRecord) error {
 	return cs.inner.PutHistoryRecord(ctx, tenant, contentType, id, record)
 }
@@ -751,6 +836,26 @@ func (cs *CachedStorage) HasUnresolvedComments(ctx context.Context, tenant, cont
 	return cs.inner.HasUnresolvedComments(ctx, tenant, contentType, contentID, state)
 }
 
+func (cs *CachedStorage) PutAttachment(ctx context.Context, tenant, contentType, contentID, name string, body io.Reader, contentLength int64, mimeType string) (*Attachment, error) {
+	return cs.inner.PutAttachment(ctx, tenant, contentType, contentID, name, body, contentLength, mimeType)
+}
+
+func (cs *CachedStorage) GetAttachment(ctx context.Context, tenant, contentType, contentID, name string) (*ContentStream, error) {
+	return cs.inner.GetAttachment(ctx, tenant, contentType, contentID, name)
+}
+
+func (cs *CachedStorage) ListAttachments(ctx context.Context, tenant, contentType, contentID string) ([]*Attachment, error) {
+	return cs.inner.ListAttachments(ctx, tenant, contentType, contentID)
+}
+
+func (cs *CachedStorage) DeleteAttachment(ctx context.Context, tenant, contentType, contentID, name string) error {
+	return cs.inner.DeleteAttachment(ctx, tenant, contentType, contentID, name)
+}
+
+func (cs *CachedStorage) DeleteAllAttachments(ctx context.Context, tenant, contentType, contentID string) error {
+	return cs.inner.DeleteAllAttachments(ctx, tenant, contentType, contentID)
+}
+
 func (cs *CachedStorage) ListWebhooks(ctx context.Context, tenant string) ([]*Webhook, error) {
 	return cs.inner.ListWebhooks(ctx, tenant)
 }
@@ -767,6 +872,22 @@ func (cs *CachedStorage) DeleteWebhook(ctx context.Context, tenant, webhookID st
 	return cs.inner.DeleteWebhook(ctx, tenant, webhookID)
 }
 
+func (cs *CachedStorage) ListAPIKeys(ctx context.Context, tenant string) ([]*APIKey, error) {
+	return cs.inner.ListAPIKeys(ctx, tenant)
+}
+
+func (cs *CachedStorage) GetAPIKey(ctx context.Context, tenant, keyID string) (*APIKey, error) {
+	return cs.inner.GetAPIKey(ctx, tenant, keyID)
+}
+
+func (cs *CachedStorage) PutAPIKey(ctx context.Context, tenant string, key *APIKey) error {
+	return cs.inner.PutAPIKey(ctx, tenant, key)
+}
+
+func (cs *CachedStorage) DeleteAPIKey(ctx context.Context, tenant, keyID string) error {
+	return cs.inner.DeleteAPIKey(ctx, tenant, keyID)
+}
+
 func (cs *CachedStorage) ListTenants(ctx context.Context) ([]string, error) {
 	return cs.inner.ListTenants(ctx)
 }
@@ -799,8 +920,58 @@ func (cs *CachedStorage) DeleteExpiredSessions(ctx context.Context) (int, error)
 	return cs.inner.DeleteExpiredSessions(ctx)
 }
 
-func (cs *CachedStorage) GetMetadata(ctx context.Context, tenant, contentType, id, ext string, state State) (map[string]string, error) {
-	return cs.inner.GetMetadata(ctx, tenant, contentType, id, ext, state)
+func (cs *CachedStorage) GetDocument(ctx context.Context, tenant, collection, id string) ([]byte, error) {
+	return cs.inner.GetDocument(ctx, tenant, collection, id)
+}
+
+func (cs *CachedStorage) PutDocument(ctx context.Context, tenant, collection, id string, data []byte) error {
+	return cs.inner.PutDocument(ctx, tenant, collection, id, data)
+}
+
+func (cs *CachedStorage) DeleteDocument(ctx context.Context, tenant, collection, id string) error {
+	return cs.inner.DeleteDocument(ctx, tenant, collection, id)
+}
+
+func (cs *CachedStorage) ListDocuments(ctx context.Context, tenant, collection string) ([]string, error) {
+	return cs.inner.ListDocuments(ctx, tenant, collection)
+}
+
+
+func (cs *CachedStorage) ListCommentedIDs(ctx context.Context, tenant, contentType string, state State) ([]string, error) {
+	return cs.inner.ListCommentedIDs(ctx, tenant, contentType, state)
+}
+
+func (cs *CachedStorage) ListHistoryIDs(ctx context.Context, tenant, contentType string) ([]string, error) {
+	return cs.inner.ListHistoryIDs(ctx, tenant, contentType)
+}
+
+func (cs *CachedStorage) DeleteHistoryRecord(ctx context.Context, tenant, contentType, id, version string) error {
+	return cs.inner.DeleteHistoryRecord(ctx, tenant, contentType, id, version)
+}
+
+func (cs *CachedStorage) ContentDeletedAt(ctx context.Context, tenant, contentType, id string) (time.Time, bool, error) {
+	return cs.inner.ContentDeletedAt(ctx, tenant, contentType, id)
+}
+
+// Leases are never cached: every call must see the current holder
+func (cs *CachedStorage) AcquireLease(ctx context.Context, key string, ttl time.Duration) (*Lease, error) {
+	return cs.inner.AcquireLease(ctx, key, ttl)
+}
+
+func (cs *CachedStorage) RenewLease(ctx context.Context, lease *Lease, ttl time.Duration) (*Lease, error) {
+	return cs.inner.RenewLease(ctx, lease, ttl)
+}
+
+func (cs *CachedStorage) ReleaseLease(ctx context.Context, lease *Lease) error {
+	return cs.inner.ReleaseLease(ctx, lease)
+}
+
+func (cs *CachedStorage) GetLease(ctx context.Context, key string) (*Lease, error) {
+	return cs.inner.GetLease(ctx, key)
+}
+
+func (cs *CachedStorage) BackupObject(ctx context.Context, tenant, contentType, id, ext string, state State, objectID string) error {
+	return cs.inner.BackupObject(ctx, tenant, contentType, id, ext, state, objectID)
 }
 
 // --- cachingReader ---
@@ -0,0 +1,685 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"velocity/internal/log"
+)
+
+// Ensure EmbeddedS3 implements S3API
+var _ S3API = (*EmbeddedS3)(nil)
+
+const (
+	embeddedJournal  = "journal.log"
+	embeddedBlobs    = "blobs"
+	embeddedTemp     = "tmp"
+	maxJournalRecord = 16 << 20
+)
+
+// EmbeddedS3 is a versioned bucket kept in a local directory, for single-node
+// installs without an external object store. It implements S3API, so
+// S3Storage runs on it unchanged, with the same versioning (history, restore,
+// pruning) as on S3.
+//
+// Object bodies are files under blobs/. Versions are recorded in an
+// append-only journal that is replayed into memory (and compacted) on open.
+// Only one process may open a directory at a time.
+type EmbeddedS3 struct {
+	dir string
+
+	mu       sync.RWMutex
+	objects  map[string][]*embeddedVersion // key -> versions, oldest first
+	sequence uint64
+	clock    time.Time
+	journal  *os.File
+}
+
+// embeddedVersion is one version (or delete marker) of an object
+type embeddedVersion struct {
+	Key          string            `json:"key"`
+	ID           string            `json:"id"`
+	ContentType  string            `json:"content_type,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	ETag         string            `json:"etag,omitempty"`
+	Size         int64             `json:"size"`
+	LastModified time.Time         `json:"last_modified"`
+	DeleteMarker bool              `json:"delete_marker,omitempty"`
+}
+
+// embeddedRecord is a journal entry: a version added, or one removed for good
+type embeddedRecord struct {
+	Op      string           `json:"op"` // put or remove
+	Version *embeddedVersion `json:"version"`
+}
+
+// OpenEmbeddedS3 opens (creating if needed) a bucket in dir
+func OpenEmbeddedS3(dir string) (*EmbeddedS3, error) {
+	for _, sub := range []string{embeddedBlobs, embeddedTemp} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", sub, err)
+		}
+	}
+
+	// Uploads that never completed
+	temps, _ := os.ReadDir(filepath.Join(dir, embeddedTemp))
+	for _, temp := range temps {
+		os.Remove(filepath.Join(dir, embeddedTemp, temp.Name()))
+	}
+
+	e := &EmbeddedS3{dir: dir, objects: make(map[string][]*embeddedVersion)}
+	if err := e.replay(); err != nil {
+		return nil, err
+	}
+	if err := e.compact(); err != nil {
+		return nil, err
+	}
+	e.sweepBlobs()
+
+	journal, err := os.OpenFile(filepath.Join(dir, embeddedJournal), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	e.journal = journal
+	return e, nil
+}
+
+// Close closes the journal
+func (e *EmbeddedS3) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.journal.Close()
+}
+
+// replay rebuilds the index from the journal. A torn final record (from a
+// crash mid-write) is ignored.
+func (e *EmbeddedS3) replay() error {
+	file, err := os.Open(filepath.Join(e.dir, embeddedJournal))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), maxJournalRecord)
+	for line := 1; scanner.Scan(); line++ {
+		var record embeddedRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.Version == nil {
+			log.Error("Skipping unreadable journal record %d: %v", line, err)
+			continue
+		}
+		v := record.Version
+		switch record.Op {
+		case "put":
+			e.objects[v.Key] = append(e.objects[v.Key], v)
+			var sequence uint64
+			fmt.Sscanf(v.ID, "%x", &sequence)
+			if sequence > e.sequence {
+				e.sequence = sequence
+			}
+			if v.LastModified.After(e.clock) {
+				e.clock = v.LastModified
+			}
+		case "remove":
+			e.drop(v.Key, v.ID)
+		}
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the journal with only the versions that still exist
+func (e *EmbeddedS3) compact() error {
+	path := filepath.Join(e.dir, embeddedJournal)
+	temp, err := os.Create(path + ".compact")
+	if err != nil {
+		return fmt.Errorf("failed to compact journal: %w", err)
+	}
+	writer := bufio.NewWriter(temp)
+	encoder := json.NewEncoder(writer)
+	for _, key := range e.sortedKeys("") {
+		for _, v := range e.objects[key] {
+			if err := encoder.Encode(&embeddedRecord{Op: "put", Version: v}); err != nil {
+				temp.Close()
+				return fmt.Errorf("failed to compact journal: %w", err)
+			}
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to compact journal: %w", err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to compact journal: %w", err)
+	}
+	temp.Close()
+	return os.Rename(temp.Name(), path)
+}
+
+// sweepBlobs deletes blobs no version refers to (writes interrupted between
+// storing the body and journaling the version)
+func (e *EmbeddedS3) sweepBlobs() {
+	referenced := make(map[string]bool)
+	for _, versions := range e.objects {
+		for _, v := range versions {
+			referenced[v.ID] = true
+		}
+	}
+	filepath.WalkDir(filepath.Join(e.dir, embeddedBlobs), func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && !referenced[d.Name()] {
+			os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// blobPath returns where a version's body is stored
+func (e *EmbeddedS3) blobPath(id string) string {
+	return filepath.Join(e.dir, embeddedBlobs, id[len(id)-2:], id)
+}
+
+// record appends a journal entry and syncs it (caller holds mu)
+func (e *EmbeddedS3) record(op string, v *embeddedVersion) error {
+	data, err := json.Marshal(&embeddedRecord{Op: op, Version: v})
+	if err != nil {
+		return err
+	}
+	if _, err := e.journal.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return e.journal.Sync()
+}
+
+// next assigns a version its ID and timestamp (caller holds mu)
+func (e *EmbeddedS3) next(v *embeddedVersion) {
+	e.sequence++
+	v.ID = fmt.Sprintf("%016x", e.sequence)
+
+	// Strictly increasing timestamps keep version order stable
+	now := time.Now().UTC()
+	if !now.After(e.clock) {
+		now = e.clock.Add(time.Microsecond)
+	}
+	e.clock = now
+	v.LastModified = now
+}
+
+// commit journals a new version whose body (if any) is already at its blob
+// path, and adds it to the index (caller holds mu)
+func (e *EmbeddedS3) commit(v *embeddedVersion) error {
+	if err := e.record("put", v); err != nil {
+		if !v.DeleteMarker {
+			os.Remove(e.blobPath(v.ID))
+		}
+		return err
+	}
+	e.objects[v.Key] = append(e.objects[v.Key], v)
+	return nil
+}
+
+// drop removes a version from the index (caller holds mu)
+func (e *EmbeddedS3) drop(key, id string) *embeddedVersion {
+	versions := e.objects[key]
+	for i, v := range versions {
+		if v.ID == id {
+			e.objects[key] = append(versions[:i:i], versions[i+1:]...)
+			if len(e.objects[key]) == 0 {
+				delete(e.objects, key)
+			}
+			return v
+		}
+	}
+	return nil
+}
+
+// find returns a version of a key, or the latest when id is empty (caller holds mu)
+func (e *EmbeddedS3) find(key, id string) *embeddedVersion {
+	versions := e.objects[key]
+	if id == "" {
+		if len(versions) > 0 && !versions[len(versions)-1].DeleteMarker {
+			return versions[len(versions)-1]
+		}
+		return nil
+	}
+	for _, v := range versions {
+		if v.ID == id && !v.DeleteMarker {
+			return v
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys with a prefix, in order (caller holds mu)
+func (e *EmbeddedS3) sortedKeys(prefix string) []string {
+	var keys []string
+	for key := range e.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkConditions applies If-Match and If-None-Match to a write (caller holds mu)
+func (e *EmbeddedS3) checkConditions(key string, header http.Header) error {
+	current := e.find(key, "")
+	if header.Get("If-None-Match") == "*" && current != nil {
+		return errEmbeddedPrecondition()
+	}
+	if match := header.Get("If-Match"); match != "" && (current == nil || current.ETag != match) {
+		return errEmbeddedPrecondition()
+	}
+	return nil
+}
+
+// embeddedError is an S3 API error with an HTTP status
+type embeddedError struct {
+	status int
+	code   string
+	msg    string
+}
+
+func (e *embeddedError) Error() string        { return fmt.Sprintf("api error %s: %s", e.code, e.msg) }
+func (e *embeddedError) ErrorCode() string    { return e.code }
+func (e *embeddedError) ErrorMessage() string { return e.msg }
+func (e *embeddedError) HTTPStatusCode() int  { return e.status }
+
+func errEmbeddedNoSuchKey() error {
+	return &embeddedError{status: http.StatusNotFound, code: "NoSuchKey", msg: "The specified key does not exist."}
+}
+
+func errEmbeddedPrecondition() error {
+	return &embeddedError{status: http.StatusPreconditionFailed, code: "PreconditionFailed", msg: "At least one of the pre-conditions you specified did not hold"}
+}
+
+// optionHeaders runs the per-call options' build middleware against an empty
+// request to recover the headers they set (conditional writes, see withHeader)
+func optionHeaders(ctx context.Context, optFns []func(*s3.Options)) http.Header {
+	var opts s3.Options
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+	if len(opts.APIOptions) == 0 {
+		return http.Header{}
+	}
+
+	stack := middleware.NewStack("EmbeddedS3", smithyhttp.NewStackRequest)
+	for _, apply := range opts.APIOptions {
+		apply(stack)
+	}
+	req := smithyhttp.NewStackRequest().(*smithyhttp.Request)
+	stack.Build.HandleMiddleware(ctx, req, middleware.HandlerFunc(func(ctx context.Context, in interface{}) (interface{}, middleware.Metadata, error) {
+		return nil, middleware.Metadata{}, nil
+	}))
+	return req.Header
+}
+
+// spool writes a body to a temporary file, returning its path, size, and ETag
+func (e *EmbeddedS3) spool(body io.Reader) (string, int64, string, error) {
+	temp, err := os.CreateTemp(filepath.Join(e.dir, embeddedTemp), "upload-")
+	if err != nil {
+		return "", 0, "", err
+	}
+	hash := md5.New()
+	var size int64
+	if body != nil {
+		size, err = io.Copy(io.MultiWriter(temp, hash), body)
+	}
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return "", 0, "", err
+	}
+	return temp.Name(), size, `"` + hex.EncodeToString(hash.Sum(nil)) + `"`, nil
+}
+
+// place moves or links a body into a version's blob path
+func (e *EmbeddedS3) place(id string, link func(string) error) error {
+	path := e.blobPath(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return link(path)
+}
+
+// GetObject returns the latest (or requested) version of an object
+func (e *EmbeddedS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	v := e.find(aws.ToString(params.Key), aws.ToString(params.VersionId))
+	if v == nil {
+		return nil, errEmbeddedNoSuchKey()
+	}
+	body, err := os.Open(e.blobPath(v.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", v.Key, err)
+	}
+	return &s3.GetObjectOutput{
+		Body:          body,
+		ContentType:   aws.String(v.ContentType),
+		ContentLength: aws.Int64(v.Size),
+		ETag:          aws.String(v.ETag),
+		LastModified:  aws.Time(v.LastModified),
+		Metadata:      copyMetadata(v.Metadata),
+		VersionId:     aws.String(v.ID),
+	}, nil
+}
+
+// HeadObject returns an object's attributes without its body
+func (e *EmbeddedS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	v := e.find(aws.ToString(params.Key), aws.ToString(params.VersionId))
+	if v == nil {
+		return nil, &embeddedError{status: http.StatusNotFound, code: "NotFound", msg: "Not Found"}
+	}
+	return &s3.HeadObjectOutput{
+		ContentType:   aws.String(v.ContentType),
+		ContentLength: aws.Int64(v.Size),
+		ETag:          aws.String(v.ETag),
+		LastModified:  aws.Time(v.LastModified),
+		Metadata:      copyMetadata(v.Metadata),
+		VersionId:     aws.String(v.ID),
+	}, nil
+}
+
+// PutObject stores a new version of an object
+func (e *EmbeddedS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	temp, size, etag, err := e.spool(params.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store object: %w", err)
+	}
+	defer os.Remove(temp) // no-op once placed
+	header := optionHeaders(ctx, optFns)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := aws.ToString(params.Key)
+	if err := e.checkConditions(key, header); err != nil {
+		return nil, err
+	}
+
+	contentType := aws.ToString(params.ContentType)
+	if contentType == "" {
+		contentType = "binary/octet-stream"
+	}
+	v := &embeddedVersion{Key: key, ContentType: contentType, Metadata: lowerMetadata(params.Metadata), ETag: etag, Size: size}
+	e.next(v)
+	if err := e.place(v.ID, func(path string) error { return os.Rename(temp, path) }); err != nil {
+		return nil, fmt.Errorf("failed to store object: %w", err)
+	}
+	if err := e.commit(v); err != nil {
+		return nil, err
+	}
+	return &s3.PutObjectOutput{ETag: aws.String(v.ETag), VersionId: aws.String(v.ID)}, nil
+}
+
+// DeleteObject removes a specific version for good, or adds a delete marker
+func (e *EmbeddedS3) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := aws.ToString(params.Key)
+	if id := aws.ToString(params.VersionId); id != "" {
+		v := e.drop(key, id)
+		if v == nil {
+			return &s3.DeleteObjectOutput{VersionId: aws.String(id)}, nil
+		}
+		if err := e.record("remove", &embeddedVersion{Key: key, ID: id}); err != nil {
+			e.objects[key] = append(e.objects[key], v)
+			sort.Slice(e.objects[key], func(i, j int) bool { return e.objects[key][i].ID < e.objects[key][j].ID })
+			return nil, err
+		}
+		if !v.DeleteMarker {
+			os.Remove(e.blobPath(id))
+		}
+		return &s3.DeleteObjectOutput{VersionId: aws.String(id), DeleteMarker: aws.Bool(v.DeleteMarker)}, nil
+	}
+
+	if len(e.objects[key]) == 0 {
+		return &s3.DeleteObjectOutput{}, nil
+	}
+	v := &embeddedVersion{Key: key, DeleteMarker: true}
+	e.next(v)
+	if err := e.commit(v); err != nil {
+		return nil, err
+	}
+	return &s3.DeleteObjectOutput{DeleteMarker: aws.Bool(true), VersionId: aws.String(v.ID)}, nil
+}
+
+// CopyObject copies an object (optionally a specific version) as a new version
+func (e *EmbeddedS3) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	header := optionHeaders(ctx, optFns)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// CopySource: {bucket}/{key}[?versionId={id}]
+	source := aws.ToString(params.CopySource)
+	if slash := strings.Index(source, "/"); slash != -1 {
+		source = source[slash+1:]
+	}
+	versionID := ""
+	if q := strings.Index(source, "?versionId="); q != -1 {
+		source, versionID = source[:q], source[q+len("?versionId="):]
+	}
+
+	src := e.find(source, versionID)
+	if src == nil {
+		return nil, errEmbeddedNoSuchKey()
+	}
+
+	key := aws.ToString(params.Key)
+	if err := e.checkConditions(key, header); err != nil {
+		return nil, err
+	}
+
+	v := &embeddedVersion{Key: key, ContentType: src.ContentType, Metadata: copyMetadata(src.Metadata), ETag: src.ETag, Size: src.Size}
+	if params.MetadataDirective == types.MetadataDirectiveReplace {
+		v.Metadata = lowerMetadata(params.Metadata)
+		if params.ContentType != nil {
+			v.ContentType = aws.ToString(params.ContentType)
+		}
+	}
+	e.next(v)
+
+	// Versions share a body through a hard link where the file system allows
+	from := e.blobPath(src.ID)
+	err := e.place(v.ID, func(path string) error {
+		if err := os.Link(from, path); err == nil {
+			return nil
+		}
+		return copyFile(from, path)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy object: %w", err)
+	}
+	if err := e.commit(v); err != nil {
+		return nil, err
+	}
+	return &s3.CopyObjectOutput{
+		VersionId:        aws.String(v.ID),
+		CopyObjectResult: &types.CopyObjectResult{ETag: aws.String(v.ETag), LastModified: aws.Time(v.LastModified)},
+	}, nil
+}
+
+// HeadBucket succeeds while the directory is readable
+func (e *EmbeddedS3) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if _, err := os.Stat(filepath.Join(e.dir, embeddedBlobs)); err != nil {
+		return nil, err
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+// ListObjectsV2 lists current objects by prefix, grouping by delimiter
+func (e *EmbeddedS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	prefix := aws.ToString(params.Prefix)
+	delimiter := aws.ToString(params.Delimiter)
+	maxKeys := int(aws.ToInt32(params.MaxKeys))
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	// Entries are object keys or common prefixes, in key order
+	isPrefix := make(map[string]bool)
+	var entries []string
+	for _, key := range e.sortedKeys(prefix) {
+		if e.find(key, "") == nil {
+			continue
+		}
+		entry, common := key, false
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i != -1 {
+				entry, common = key[:len(prefix)+i+len(delimiter)], true
+			}
+		}
+		if _, ok := isPrefix[entry]; !ok {
+			isPrefix[entry] = common
+			entries = append(entries, entry)
+		}
+	}
+
+	start := 0
+	if token := aws.ToString(params.ContinuationToken); token != "" {
+		start = sort.SearchStrings(entries, token)
+		if start < len(entries) && entries[start] == token {
+			start++
+		}
+	} else if after := aws.ToString(params.StartAfter); after != "" {
+		start = sort.SearchStrings(entries, after)
+		if start < len(entries) && entries[start] == after {
+			start++
+		}
+	}
+
+	out := &s3.ListObjectsV2Output{Prefix: params.Prefix, Delimiter: params.Delimiter, IsTruncated: aws.Bool(false)}
+	count := 0
+	for i := start; i < len(entries); i++ {
+		if count == maxKeys {
+			out.IsTruncated = aws.Bool(true)
+			out.NextContinuationToken = aws.String(entries[i-1])
+			break
+		}
+		entry := entries[i]
+		if isPrefix[entry] {
+			out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(entry)})
+		} else {
+			v := e.find(entry, "")
+			out.Contents = append(out.Contents, types.Object{
+				Key:          aws.String(entry),
+				Size:         aws.Int64(v.Size),
+				ETag:         aws.String(v.ETag),
+				LastModified: aws.Time(v.LastModified),
+			})
+		}
+		count++
+	}
+	out.KeyCount = aws.Int32(int32(count))
+	return out, nil
+}
+
+// ListObjectVersions lists every version and delete marker by prefix, newest
+// first within each key. All results are returned in a single page.
+func (e *EmbeddedS3) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	out := &s3.ListObjectVersionsOutput{Prefix: params.Prefix, IsTruncated: aws.Bool(false)}
+	for _, key := range e.sortedKeys(aws.ToString(params.Prefix)) {
+		versions := e.objects[key]
+		for i := len(versions) - 1; i >= 0; i-- {
+			v := versions[i]
+			isLatest := aws.Bool(i == len(versions)-1)
+			if v.DeleteMarker {
+				out.DeleteMarkers = append(out.DeleteMarkers, types.DeleteMarkerEntry{
+					Key:          aws.String(key),
+					VersionId:    aws.String(v.ID),
+					IsLatest:     isLatest,
+					LastModified: aws.Time(v.LastModified),
+				})
+				continue
+			}
+			out.Versions = append(out.Versions, types.ObjectVersion{
+				Key:          aws.String(key),
+				VersionId:    aws.String(v.ID),
+				IsLatest:     isLatest,
+				LastModified: aws.Time(v.LastModified),
+				Size:         aws.Int64(v.Size),
+				ETag:         aws.String(v.ETag),
+			})
+		}
+	}
+	return out, nil
+}
+
+// copyFile copies a file's contents to a new file
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+func lowerMetadata(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[strings.ToLower(k)] = v
+	}
+	return out
+}
+
+func copyMetadata(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+
+	"velocity/internal/storage"
+	"velocity/internal/storage/storagetest"
+)
+
+func newEmbeddedStorage(t *testing.T, dir string) (*storage.S3Storage, *storage.EmbeddedS3) {
+	bucket, err := storage.OpenEmbeddedS3(dir)
+	if err != nil {
+		t.Fatalf("OpenEmbeddedS3: %v", err)
+	}
+	t.Cleanup(func() { bucket.Close() })
+
+	s, err := storage.NewS3Storage(storage.S3Config{Bucket: "velocity", Root: "test", MaxVersions: 10, Client: bucket})
+	if err != nil {
+		t.Fatalf("NewS3Storage: %v", err)
+	}
+	return s, bucket
+}
+
+func TestEmbeddedStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, _ := newEmbeddedStorage(t, t.TempDir())
+		return s
+	})
+}
+
+func TestEmbeddedStorageReopen(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	s, bucket := newEmbeddedStorage(t, dir)
+	for _, body := range []string{"one", "two", "three"} {
+		if _, err := s.Put(ctx, "acme", "pages", "home", "txt", []byte(body), "text/plain", storage.StateLive); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	if err := s.Delete(ctx, "acme", "pages", "home", "txt", storage.StateLive); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	bucket.Close()
+
+	s, _ = newEmbeddedStorage(t, dir)
+	if _, err := s.Get(ctx, "acme", "pages", "home", "txt", storage.StateLive); err == nil {
+		t.Fatal("deleted content is readable after reopening")
+	}
+	versions, err := s.ListVersions(ctx, "acme", "pages", "home", "txt")
+	if err != nil {
+		t.Fatalf("ListVersions: %v", err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("got %d versions after reopening, want 3", len(versions))
+	}
+
+	// Versions are listed newest first
+	item, err := s.GetVersion(ctx, "acme", "pages", "home", "txt", versions[2].VersionID)
+	if err != nil {
+		t.Fatalf("GetVersion: %v", err)
+	}
+	if string(item.Content) != "one" {
+		t.Errorf("oldest version is %q, want %q", item.Content, "one")
+	}
+
+	// Writes continue the version sequence
+	item, err = s.Put(ctx, "acme", "pages", "home", "txt", []byte("four"), "text/plain", storage.StateLive)
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if item.VersionID <= versions[0].VersionID {
+		t.Errorf("new version %s doesn't sort after %s", item.VersionID, versions[0].VersionID)
+	}
+}
@@ -0,0 +1,345 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+
+	"velocity/internal/crypto"
+)
+
+// Metadata an encrypted object's key ID, chunk size, wrapped data key (KMS
+// keys only), and nonce (content sealed whole, before chunking) are stored under
+const (
+	encryptionKeyMetadata   = "encryption-key"
+	encryptionChunkMetadata = "encryption-chunk-size"
+	encryptionNonceMetadata = "encryption-nonce"
+	encryptionDEKMetadata   = "encryption-dek"
+)
+
+// encryptionMetadata lists the metadata EncryptedStorage manages
+var encryptionMetadata = []string{encryptionKeyMetadata, encryptionChunkMetadata, encryptionNonceMetadata, encryptionDEKMetadata}
+
+// EncryptedStorage wraps a Storage implementation and encrypts the content of
+// chosen types with AES-GCM before it is written, so it is stored as
+// ciphertext whatever the bucket provider does. Content is sealed in chunks
+// (see crypto.SealChunks) as it streams, so bodies of any size are encrypted
+// and decrypted without being held in memory. What's needed to decrypt it is
+// kept in the object's metadata, which reads don't return and metadata
+// updates can't change. Content without it (written before encryption was
+// enabled) is read as it is, and content sealed whole (before chunking) is
+// still read whole. Attachments, history, comments, and documents pass
+// through unchanged.
+type EncryptedStorage struct {
+	Storage
+	keyring *crypto.Keyring
+	types   map[string]bool // nil encrypts every type
+}
+
+// Ensure EncryptedStorage implements Storage interface
+var _ Storage = (*EncryptedStorage)(nil)
+
+// NewEncryptedStorage wraps the given Storage with content encryption for
+// the given types, or every type if none are given.
+func NewEncryptedStorage(inner Storage, keyring *crypto.Keyring, types []string) *EncryptedStorage {
+	es := &EncryptedStorage{Storage: inner, keyring: keyring}
+	if len(types) > 0 {
+		es.types = make(map[string]bool, len(types))
+		for _, contentType := range types {
+			es.types[contentType] = true
+		}
+	}
+	return es
+}
+
+// Encrypts reports whether new content of a type is encrypted
+func (es *EncryptedStorage) Encrypts(contentType string) bool {
+	return es.types == nil || es.types[contentType]
+}
+
+// contentAAD binds encrypted content to its item, so it can't be copied to
+// another item or tenant and still decrypt. States share it, so transitions
+// copy the ciphertext as it is.
+func contentAAD(tenant, contentType, id string) []byte {
+	return []byte("content:" + tenant + "/" + contentType + "/" + id)
+}
+
+// seal returns a reader of content sealed in chunks, and the metadata to
+// store with it
+func (es *EncryptedStorage) seal(ctx context.Context, tenant, contentType, id string, content io.Reader, metadata map[string]string) (*crypto.ChunkSealer, map[string]string, error) {
+	sealer, err := es.keyring.SealChunks(ctx, content, contentAAD(tenant, contentType, id))
+	if err != nil {
+		return nil, nil, err
+	}
+	merged := withoutEncryptionMetadata(metadata)
+	if merged == nil {
+		merged = make(map[string]string, len(encryptionMetadata))
+	}
+	merged[encryptionKeyMetadata] = sealer.Key
+	merged[encryptionChunkMetadata] = strconv.Itoa(crypto.ChunkSize)
+	if len(sealer.DEK) > 0 {
+		merged[encryptionDEKMetadata] = base64.StdEncoding.EncodeToString(sealer.DEK)
+	}
+	return sealer, merged, nil
+}
+
+// dataKey returns the wrapped data key content was sealed with, if any
+func dataKey(contentType, id string, metadata map[string]string) ([]byte, error) {
+	value := metadata[encryptionDEKMetadata]
+	if value == "" {
+		return nil, nil
+	}
+	dek, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s/%s: invalid data key", contentType, id)
+	}
+	return dek, nil
+}
+
+// openChunks returns a reader of content sealed in chunks
+func (es *EncryptedStorage) openChunks(ctx context.Context, tenant, contentType, id string, body io.ReadCloser, metadata map[string]string) (io.ReadCloser, error) {
+	if metadata[encryptionChunkMetadata] != strconv.Itoa(crypto.ChunkSize) {
+		return nil, fmt.Errorf("failed to decrypt %s/%s: unsupported chunk size %s", contentType, id, metadata[encryptionChunkMetadata])
+	}
+	dek, err := dataKey(contentType, id, metadata)
+	if err != nil {
+		return nil, err
+	}
+	opened, err := es.keyring.OpenChunks(ctx, metadata[encryptionKeyMetadata], dek, body, contentAAD(tenant, contentType, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s/%s: %w", contentType, id, err)
+	}
+	return opened, nil
+}
+
+// open decrypts content stored with metadata, returning it unchanged if it
+// wasn't encrypted
+func (es *EncryptedStorage) open(ctx context.Context, tenant, contentType, id string, content []byte, metadata map[string]string) ([]byte, error) {
+	keyID, ok := metadata[encryptionKeyMetadata]
+	if !ok {
+		return content, nil
+	}
+	if _, ok := metadata[encryptionChunkMetadata]; ok {
+		opened, err := es.openChunks(ctx, tenant, contentType, id, io.NopCloser(bytes.NewReader(content)), metadata)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := io.ReadAll(opened)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s/%s: %w", contentType, id, err)
+		}
+		return plaintext, nil
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(metadata[encryptionNonceMetadata])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s/%s: invalid nonce", contentType, id)
+	}
+	dek, err := dataKey(contentType, id, metadata)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := es.keyring.OpenDetached(ctx, &crypto.Detached{Key: keyID, DEK: dek, Nonce: nonce, Ciphertext: content}, contentAAD(tenant, contentType, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s/%s: %w", contentType, id, err)
+	}
+	return plaintext, nil
+}
+
+// openItem decrypts a content item in place
+func (es *EncryptedStorage) openItem(ctx context.Context, tenant, contentType, id string, item *ContentItem) (*ContentItem, error) {
+	content, err := es.open(ctx, tenant, contentType, id, item.Content, item.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	item.Content = content
+	item.Size = int64(len(content))
+	item.Metadata = withoutEncryptionMetadata(item.Metadata)
+	return item, nil
+}
+
+// openStream decrypts a content stream. Chunked content is decrypted as it's
+// read, each chunk verified before it's returned; content sealed whole is
+// read whole, as it can't be verified until the end.
+func (es *EncryptedStorage) openStream(ctx context.Context, tenant, contentType, id string, stream *ContentStream) (*ContentStream, error) {
+	if _, ok := stream.Metadata[encryptionKeyMetadata]; !ok {
+		return stream, nil
+	}
+	if _, ok := stream.Metadata[encryptionChunkMetadata]; ok {
+		body, err := es.openChunks(ctx, tenant, contentType, id, stream.Body, stream.Metadata)
+		if err != nil {
+			stream.Body.Close()
+			return nil, err
+		}
+		opened := *stream
+		opened.Body = body
+		opened.Size = crypto.OpenedSize(stream.Size)
+		opened.Metadata = withoutEncryptionMetadata(stream.Metadata)
+		return &opened, nil
+	}
+
+	defer stream.Body.Close()
+	ciphertext, err := io.ReadAll(stream.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object body: %w", err)
+	}
+	content, err := es.open(ctx, tenant, contentType, id, ciphertext, stream.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	opened := *stream
+	opened.Body = io.NopCloser(bytes.NewReader(content))
+	opened.Size = int64(len(content))
+	opened.Metadata = withoutEncryptionMetadata(stream.Metadata)
+	return &opened, nil
+}
+
+// withoutEncryptionMetadata returns a copy of metadata without the entries
+// EncryptedStorage manages
+func withoutEncryptionMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+	copied := copyMetadata(metadata)
+	for _, key := range encryptionMetadata {
+		delete(copied, key)
+	}
+	return copied
+}
+
+// --- Writes ---
+
+func (es *EncryptedStorage) Put(ctx context.Context, tenant, contentType, id, ext string, content []byte, mimeType string, state State) (*ContentItem, error) {
+	if !es.Encrypts(contentType) {
+		return es.Storage.Put(ctx, tenant, contentType, id, ext, content, mimeType, state)
+	}
+	sealer, metadata, err := es.seal(ctx, tenant, contentType, id, bytes.NewReader(content), nil)
+	if err != nil {
+		return nil, err
+	}
+	item, err := es.Storage.PutStream(ctx, tenant, contentType, id, ext, sealer, crypto.SealedSize(int64(len(content))), mimeType, state, metadata)
+	if err != nil {
+		return nil, err
+	}
+	item.Content = content
+	item.Size = int64(len(content))
+	item.Metadata = nil
+	return item, nil
+}
+
+func (es *EncryptedStorage) PutStream(ctx context.Context, tenant, contentType, id, ext string, body io.Reader, contentLength int64, mimeType string, state State, metadata map[string]string) (*ContentItem, error) {
+	if !es.Encrypts(contentType) {
+		return es.Storage.PutStream(ctx, tenant, contentType, id, ext, body, contentLength, mimeType, state, withoutEncryptionMetadata(metadata))
+	}
+	sealer, sealedMetadata, err := es.seal(ctx, tenant, contentType, id, body, metadata)
+	if err != nil {
+		return nil, err
+	}
+	item, err := es.Storage.PutStream(ctx, tenant, contentType, id, ext, sealer, crypto.SealedSize(contentLength), mimeType, state, sealedMetadata)
+	if err != nil {
+		return nil, err
+	}
+	item.Size = sealer.Size()
+	item.Metadata = withoutEncryptionMetadata(item.Metadata)
+	return item, nil
+}
+
+func (es *EncryptedStorage) CopyContent(ctx context.Context, tenant, contentType, id, ext string, fromState, toState State, metadata map[string]string) (*ContentItem, error) {
+	return es.Storage.CopyContent(ctx, tenant, contentType, id, ext, fromState, toState, withoutEncryptionMetadata(metadata))
+}
+
+// --- Reads ---
+
+func (es *EncryptedStorage) Get(ctx context.Context, tenant, contentType, id, ext string, state State) (*ContentItem, error) {
+	item, err := es.Storage.Get(ctx, tenant, contentType, id, ext, state)
+	if err != nil {
+		return nil, err
+	}
+	return es.openItem(ctx, tenant, contentType, id, item)
+}
+
+func (es *EncryptedStorage) GetStream(ctx context.Context, tenant, contentType, id, ext string, state State) (*ContentStream, error) {
+	stream, err := es.Storage.GetStream(ctx, tenant, contentType, id, ext, state)
+	if err != nil {
+		return nil, err
+	}
+	return es.openStream(ctx, tenant, contentType, id, stream)
+}
+
+func (es *EncryptedStorage) FindContentStream(ctx context.Context, tenant, contentType, id, extHint string, state State) (*ContentStream, error) {
+	stream, err := es.Storage.FindContentStream(ctx, tenant, contentType, id, extHint, state)
+	if err != nil {
+		return nil, err
+	}
+	return es.openStream(ctx, tenant, contentType, id, stream)
+}
+
+func (es *EncryptedStorage) GetVersion(ctx context.Context, tenant, contentType, id, ext, versionID string) (*ContentItem, error) {
+	item, err := es.Storage.GetVersion(ctx, tenant, contentType, id, ext, versionID)
+	if err != nil {
+		return nil, err
+	}
+	return es.openItem(ctx, tenant, contentType, id, item)
+}
+
+func (es *EncryptedStorage) GetVersionStream(ctx context.Context, tenant, contentType, id, ext, versionID string) (*ContentStream, error) {
+	stream, err := es.Storage.GetVersionStream(ctx, tenant, contentType, id, ext, versionID)
+	if err != nil {
+		return nil, err
+	}
+	return es.openStream(ctx, tenant, contentType, id, stream)
+}
+
+func (es *EncryptedStorage) FindVersionByETag(ctx context.Context, tenant, contentType, id, ext string, state State, etag string) (*ContentItem, error) {
+	item, err := es.Storage.FindVersionByETag(ctx, tenant, contentType, id, ext, state, etag)
+	if err != nil {
+		return nil, err
+	}
+	return es.openItem(ctx, tenant, contentType, id, item)
+}
+
+// --- Metadata ---
+
+func (es *EncryptedStorage) GetMetadata(ctx context.Context, tenant, contentType, id, ext string, state State) (map[string]string, error) {
+	metadata, err := es.Storage.GetMetadata(ctx, tenant, contentType, id, ext, state)
+	if err != nil {
+		return nil, err
+	}
+	return withoutEncryptionMetadata(metadata), nil
+}
+
+// SetMetadata replaces an item's metadata, keeping what's needed to decrypt it
+func (es *EncryptedStorage) SetMetadata(ctx context.Context, tenant, contentType, id, ext string, state State, metadata map[string]string) error {
+	existing, err := es.Storage.GetMetadata(ctx, tenant, contentType, id, ext, state)
+	if err != nil {
+		return err
+	}
+	merged := withoutEncryptionMetadata(metadata)
+	if merged == nil {
+		merged = make(map[string]string)
+	}
+	for _, key := range encryptionMetadata {
+		if value, ok := existing[key]; ok {
+			merged[key] = value
+		}
+	}
+	return es.Storage.SetMetadata(ctx, tenant, contentType, id, ext, state, merged)
+}
+
+func (es *EncryptedStorage) UpdateMetadata(ctx context.Context, tenant, contentType, id, ext string, state State, updates map[string]string) error {
+	return es.Storage.UpdateMetadata(ctx, tenant, contentType, id, ext, state, withoutEncryptionMetadata(updates))
+}
+
+func (es *EncryptedStorage) DeleteMetadataKeys(ctx context.Context, tenant, contentType, id, ext string, state State, keys []string) error {
+	kept := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !slices.Contains(encryptionMetadata, key) {
+			kept = append(kept, key)
+		}
+	}
+	return es.Storage.DeleteMetadataKeys(ctx, tenant, contentType, id, ext, state, kept)
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"velocity/internal/crypto"
+)
+
+// Factory creates a storage backend from its settings. Settings are strings
+// so operators can give them as flags or environment variables; what can't
+// be a string comes in opts.
+type Factory func(cfg map[string]string, opts Options) (Storage, error)
+
+// Options are the settings of a backend that aren't strings
+type Options struct {
+	Keyring     *crypto.Keyring         // Encrypts webhooks at rest (optional)
+	Credentials aws.CredentialsProvider // Used instead of static S3 keys (optional)
+}
+
+// Option sets one of a backend's Options
+type Option func(*Options)
+
+// WithKeyring encrypts the backend's secrets at rest with a keyring
+func WithKeyring(keyring *crypto.Keyring) Option {
+	return func(o *Options) { o.Keyring = keyring }
+}
+
+// WithCredentials gives the backend a credential provider in place of its keys
+func WithCredentials(provider aws.CredentialsProvider) Option {
+	return func(o *Options) { o.Credentials = provider }
+}
+
+// ConnectionChecker is implemented by backends that can check they reach
+// their store before a server starts using them
+type ConnectionChecker interface {
+	CheckConnection(ctx context.Context) error
+}
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+// Register makes a backend available to New under a kind. It panics if the
+// kind is taken, as registration happens at init.
+func Register(kind string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if _, ok := factories[kind]; ok {
+		panic("storage: backend registered twice: " + kind)
+	}
+	factories[kind] = factory
+}
+
+// Kinds returns the registered backend kinds, sorted
+func Kinds() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	kinds := make([]string, 0, len(factories))
+	for kind := range factories {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// New creates a backend of a registered kind:
+//
+//   - s3: a bucket (endpoint, region, bucket, access_key_id,
+//     secret_access_key, auth, fips)
+//   - file: a local versioned store (dir); embedded is the same
+//   - memory: an in-memory bucket, lost when the process exits
+//   - noop: no storage; every call fails
+//
+// s3, file, and memory also take root, max_versions (a number, or all), and
+// max_version_age. Backends that hold resources, like file, implement
+// io.Closer.
+func New(kind string, cfg map[string]string, opts ...Option) (Storage, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[kind]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown storage %q (expected %s)", kind, strings.Join(Kinds(), ", "))
+	}
+
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return factory(cfg, options)
+}
+
+func init() {
+	Register("s3", newS3Backend)
+	Register("file", newFileBackend)
+	Register("embedded", newFileBackend)
+	Register("memory", newMemoryBackend)
+	Register("noop", func(cfg map[string]string, opts Options) (Storage, error) {
+		return NewNoopStorage(), nil
+	})
+}
+
+// s3ConfigFrom reads the settings S3Storage shares across backends
+func s3ConfigFrom(cfg map[string]string, opts Options) (S3Config, error) {
+	s3Config := S3Config{
+		Endpoint:        cfg["endpoint"],
+		Region:          cfg["region"],
+		Bucket:          cfg["bucket"],
+		AccessKeyID:     cfg["access_key_id"],
+		SecretAccessKey: cfg["secret_access_key"],
+		Root:            cfg["root"],
+		Auth:            cfg["auth"],
+		FIPS:            cfg["fips"] == "true",
+		Credentials:     opts.Credentials,
+		Keyring:         opts.Keyring,
+	}
+	if s3Config.Bucket == "" {
+		s3Config.Bucket = "velocity"
+	}
+
+	switch value := strings.ToLower(cfg["max_versions"]); value {
+	case "":
+	case "all":
+		s3Config.MaxVersions = -1
+	default:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return s3Config, fmt.Errorf("invalid max_versions: %q", cfg["max_versions"])
+		}
+		s3Config.MaxVersions = n
+	}
+	if value := cfg["max_version_age"]; value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return s3Config, fmt.Errorf("invalid max_version_age: %q", value)
+		}
+		s3Config.MaxVersionAge = d
+	}
+	return s3Config, nil
+}
+
+func newS3Backend(cfg map[string]string, opts Options) (Storage, error) {
+	s3Config, err := s3ConfigFrom(cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewS3Storage(s3Config)
+}
+
+// fileStorage is S3Storage on a local versioned store, which it closes
+type fileStorage struct {
+	*S3Storage
+	bucket *EmbeddedS3
+}
+
+// Close closes the store's journal
+func (s *fileStorage) Close() error {
+	return s.bucket.Close()
+}
+
+func newFileBackend(cfg map[string]string, opts Options) (Storage, error) {
+	s3Config, err := s3ConfigFrom(cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+	dir := cfg["dir"]
+	if dir == "" {
+		return nil, fmt.Errorf("file storage needs a dir")
+	}
+	bucket, err := OpenEmbeddedS3(dir)
+	if err != nil {
+		return nil, err
+	}
+	s3Config.Client = bucket
+	s, err := NewS3Storage(s3Config)
+	if err != nil {
+		bucket.Close()
+		return nil, err
+	}
+	return &fileStorage{S3Storage: s, bucket: bucket}, nil
+}
+
+func newMemoryBackend(cfg map[string]string, opts Options) (Storage, error) {
+	s3Config, err := s3ConfigFrom(cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+	s3Config.Client = NewMemoryS3()
+	return NewS3Storage(s3Config)
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// tenantPattern matches valid tenant identifiers
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// ValidTenant checks if a string is a valid tenant identifier: 1 to 64
+// letters, digits, dots, underscores, or hyphens, starting with a letter or
+// digit (so never "." or "..")
+func ValidTenant(tenant string) bool {
+	return tenantPattern.MatchString(tenant)
+}
+
+// ValidKeyPath checks if a content type, ID, or other name is safe to place
+// in a storage key: no "." or ".." segments, no empty segments, and no
+// control characters
+func ValidKeyPath(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// =============================================================================
+// Key Construction
+// =============================================================================
+//
+// Every storage key is built by objectKey or tenantKey. Names are validated
+// at the API edge; these escape whatever slips through, so a name can never
+// address a key outside its own place in the tree (another tenant, or the
+// shared schemas and sessions).
+
+// objectKey joins key components under the storage root
+func (s *S3Storage) objectKey(parts ...string) string {
+	segments := []string{s.root}
+	for _, part := range parts {
+		for _, segment := range strings.Split(part, "/") {
+			segments = append(segments, escapeSegment(segment))
+		}
+	}
+	return path.Join(segments...)
+}
+
+// tenantKey joins key components under a tenant's tree. The tenant is one
+// segment, or a tenant's content root as returned by RootTenant.
+func (s *S3Storage) tenantKey(tenant string, parts ...string) string {
+	return s.objectKey(append([]string{"tenants", tenantSegment(tenant)}, parts...)...)
+}
+
+// tenantSegment returns the key path of a storage tenant, escaping slashes
+// unless it is a content root ({tenant}/roots/{root})
+func tenantSegment(tenant string) string {
+	segments := strings.Split(tenant, "/")
+	if len(segments) == 3 && segments[1] == "roots" && ValidRoot(segments[2]) {
+		return tenant
+	}
+	return strings.ReplaceAll(tenant, "/", "%2F")
+}
+
+// escapeSegment keeps a key segment from moving up the tree
+func escapeSegment(segment string) string {
+	switch segment {
+	case ".":
+		return "%2E"
+	case "..":
+		return "%2E%2E"
+	default:
+		return segment
+	}
+}
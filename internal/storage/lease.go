@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"time"
+)
+
+var (
+	// ErrLeaseHeld is returned when a lease is held by someone else and has not expired
+	ErrLeaseHeld = errors.New("lease is held by another holder")
+
+	// ErrLeaseLost is returned when renewing or releasing a lease that was taken over
+	ErrLeaseLost = errors.New("lease is no longer held")
+)
+
+// NodeID identifies this process to other nodes sharing the storage, e.g. as
+// the holder of a lease: hostname plus a random suffix
+var NodeID = newNodeID()
+
+func newNodeID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "node"
+	}
+	return host + "-" + randomToken(4)
+}
+
+func randomToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Lease is an exclusive, expiring claim on a key, coordinated through storage
+// so that it holds across every node (leader election, transaction commits,
+// content locks). A lease that is not renewed before ExpiresAt may be taken
+// over by another holder.
+type Lease struct {
+	Key        string    `json:"key"`
+	Holder     string    `json:"holder"` // node that acquired the lease
+	Token      string    `json:"token"`  // identifies this claim
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+
+	version string // backend revision the lease was written at, for conditional updates
+}
+
+// Expired reports whether the lease has run out
+func (l *Lease) Expired() bool {
+	return !time.Now().Before(l.ExpiresAt)
+}
@@ -0,0 +1,418 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Ensure MemoryS3 implements S3API
+var _ S3API = (*MemoryS3)(nil)
+
+// MemoryS3 is an in-memory, versioned S3 bucket. It supports the calls and
+// conditional write headers (If-Match, If-None-Match) that S3Storage uses,
+// and is safe for concurrent use. Content is lost when the process exits, so
+// it suits tests, demos, and throwaway servers.
+type MemoryS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]*memoryVersion // key -> versions, oldest first
+	sequence int
+	clock    time.Time
+}
+
+type memoryVersion struct {
+	id           string
+	data         []byte
+	contentType  string
+	metadata     map[string]string
+	etag         string
+	lastModified time.Time
+	deleteMarker bool
+}
+
+// NewMemoryS3 creates an empty in-memory bucket
+func NewMemoryS3() *MemoryS3 {
+	return &MemoryS3{objects: make(map[string][]*memoryVersion)}
+}
+
+// memoryError is an S3 API error with an HTTP status
+type memoryError struct {
+	status int
+	code   string
+	msg    string
+}
+
+func (e *memoryError) Error() string        { return fmt.Sprintf("api error %s: %s", e.code, e.msg) }
+func (e *memoryError) ErrorCode() string    { return e.code }
+func (e *memoryError) ErrorMessage() string { return e.msg }
+func (e *memoryError) HTTPStatusCode() int  { return e.status }
+
+func errMemoryNoSuchKey() error {
+	return &memoryError{status: http.StatusNotFound, code: "NoSuchKey", msg: "The specified key does not exist."}
+}
+
+func errMemoryPrecondition() error {
+	return &memoryError{status: http.StatusPreconditionFailed, code: "PreconditionFailed", msg: "At least one of the pre-conditions you specified did not hold"}
+}
+
+// memoryETag returns the ETag S3 gives a single-part upload
+func memoryETag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// latest returns the newest version of a key, or nil (caller holds mu)
+func (m *MemoryS3) latest(key string) *memoryVersion {
+	versions := m.objects[key]
+	if len(versions) == 0 {
+		return nil
+	}
+	return versions[len(versions)-1]
+}
+
+// find returns a version of a key, or the latest when versionID is empty (caller holds mu)
+func (m *MemoryS3) find(key, versionID string) *memoryVersion {
+	if versionID == "" {
+		if v := m.latest(key); v != nil && !v.deleteMarker {
+			return v
+		}
+		return nil
+	}
+	for _, v := range m.objects[key] {
+		if v.id == versionID && !v.deleteMarker {
+			return v
+		}
+	}
+	return nil
+}
+
+// add appends a new version of a key (caller holds mu)
+func (m *MemoryS3) add(key string, v *memoryVersion) *memoryVersion {
+	m.sequence++
+	v.id = fmt.Sprintf("v%08d", m.sequence)
+
+	// Strictly increasing timestamps keep version order stable
+	now := time.Now().UTC()
+	if !now.After(m.clock) {
+		now = m.clock.Add(time.Microsecond)
+	}
+	m.clock = now
+	v.lastModified = now
+
+	m.objects[key] = append(m.objects[key], v)
+	return v
+}
+
+// checkConditions applies If-Match and If-None-Match to a write (caller holds mu)
+func (m *MemoryS3) checkConditions(key string, header http.Header) error {
+	current := m.find(key, "")
+	if header.Get("If-None-Match") == "*" && current != nil {
+		return errMemoryPrecondition()
+	}
+	if match := header.Get("If-Match"); match != "" && (current == nil || current.etag != match) {
+		return errMemoryPrecondition()
+	}
+	return nil
+}
+
+// GetObject returns the latest (or requested) version of an object
+func (m *MemoryS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v := m.find(aws.ToString(params.Key), aws.ToString(params.VersionId))
+	if v == nil {
+		return nil, errMemoryNoSuchKey()
+	}
+	return &s3.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(v.data)),
+		ContentType:   aws.String(v.contentType),
+		ContentLength: aws.Int64(int64(len(v.data))),
+		ETag:          aws.String(v.etag),
+		LastModified:  aws.Time(v.lastModified),
+		Metadata:      copyMetadata(v.metadata),
+		VersionId:     aws.String(v.id),
+	}, nil
+}
+
+// HeadObject returns an object's attributes without its body
+func (m *MemoryS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v := m.find(aws.ToString(params.Key), aws.ToString(params.VersionId))
+	if v == nil {
+		return nil, &memoryError{status: http.StatusNotFound, code: "NotFound", msg: "Not Found"}
+	}
+	return &s3.HeadObjectOutput{
+		ContentType:   aws.String(v.contentType),
+		ContentLength: aws.Int64(int64(len(v.data))),
+		ETag:          aws.String(v.etag),
+		LastModified:  aws.Time(v.lastModified),
+		Metadata:      copyMetadata(v.metadata),
+		VersionId:     aws.String(v.id),
+	}, nil
+}
+
+// PutObject stores a new version of an object
+func (m *MemoryS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	var data []byte
+	if params.Body != nil {
+		var err error
+		if data, err = io.ReadAll(params.Body); err != nil {
+			return nil, err
+		}
+	}
+	header := optionHeaders(ctx, optFns)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := aws.ToString(params.Key)
+	if err := m.checkConditions(key, header); err != nil {
+		return nil, err
+	}
+
+	contentType := aws.ToString(params.ContentType)
+	if contentType == "" {
+		contentType = "binary/octet-stream"
+	}
+	v := m.add(key, &memoryVersion{
+		data:        data,
+		contentType: contentType,
+		metadata:    lowerMetadata(params.Metadata),
+		etag:        memoryETag(data),
+	})
+	return &s3.PutObjectOutput{ETag: aws.String(v.etag), VersionId: aws.String(v.id)}, nil
+}
+
+// DeleteObject removes a specific version, or adds a delete marker
+func (m *MemoryS3) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := aws.ToString(params.Key)
+	if versionID := aws.ToString(params.VersionId); versionID != "" {
+		versions := m.objects[key]
+		for i, v := range versions {
+			if v.id == versionID {
+				m.objects[key] = append(versions[:i:i], versions[i+1:]...)
+				break
+			}
+		}
+		if len(m.objects[key]) == 0 {
+			delete(m.objects, key)
+		}
+		return &s3.DeleteObjectOutput{VersionId: aws.String(versionID)}, nil
+	}
+
+	if m.latest(key) == nil {
+		return &s3.DeleteObjectOutput{}, nil
+	}
+	v := m.add(key, &memoryVersion{deleteMarker: true})
+	return &s3.DeleteObjectOutput{DeleteMarker: aws.Bool(true), VersionId: aws.String(v.id)}, nil
+}
+
+// CopyObject copies an object (optionally a specific version) as a new version
+func (m *MemoryS3) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	header := optionHeaders(ctx, optFns)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// CopySource: {bucket}/{key}[?versionId={id}]
+	source := aws.ToString(params.CopySource)
+	if slash := strings.Index(source, "/"); slash != -1 {
+		source = source[slash+1:]
+	}
+	versionID := ""
+	if q := strings.Index(source, "?versionId="); q != -1 {
+		source, versionID = source[:q], source[q+len("?versionId="):]
+	}
+
+	src := m.find(source, versionID)
+	if src == nil {
+		return nil, errMemoryNoSuchKey()
+	}
+
+	key := aws.ToString(params.Key)
+	if err := m.checkConditions(key, header); err != nil {
+		return nil, err
+	}
+
+	v := &memoryVersion{
+		data:        src.data,
+		contentType: src.contentType,
+		metadata:    copyMetadata(src.metadata),
+		etag:        src.etag,
+	}
+	if params.MetadataDirective == types.MetadataDirectiveReplace {
+		v.metadata = lowerMetadata(params.Metadata)
+		if params.ContentType != nil {
+			v.contentType = aws.ToString(params.ContentType)
+		}
+	}
+	v = m.add(key, v)
+	return &s3.CopyObjectOutput{
+		VersionId:        aws.String(v.id),
+		CopyObjectResult: &types.CopyObjectResult{ETag: aws.String(v.etag), LastModified: aws.Time(v.lastModified)},
+	}, nil
+}
+
+// HeadBucket always succeeds
+func (m *MemoryS3) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return &s3.HeadBucketOutput{}, nil
+}
+
+// ListObjectsV2 lists current objects by prefix, grouping by delimiter
+func (m *MemoryS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prefix := aws.ToString(params.Prefix)
+	delimiter := aws.ToString(params.Delimiter)
+	maxKeys := int(aws.ToInt32(params.MaxKeys))
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	// Entries are object keys or common prefixes, in key order
+	isPrefix := make(map[string]bool)
+	var entries []string
+	for key := range m.objects {
+		if !strings.HasPrefix(key, prefix) || m.find(key, "") == nil {
+			continue
+		}
+		entry, common := key, false
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i != -1 {
+				entry, common = key[:len(prefix)+i+len(delimiter)], true
+			}
+		}
+		if _, ok := isPrefix[entry]; !ok {
+			isPrefix[entry] = common
+			entries = append(entries, entry)
+		}
+	}
+	sort.Strings(entries)
+
+	start := 0
+	if token := aws.ToString(params.ContinuationToken); token != "" {
+		start = sort.SearchStrings(entries, token)
+		if start < len(entries) && entries[start] == token {
+			start++
+		}
+	}
+
+	out := &s3.ListObjectsV2Output{Prefix: params.Prefix, Delimiter: params.Delimiter}
+	count := 0
+	for i := start; i < len(entries); i++ {
+		if count == maxKeys {
+			out.IsTruncated = aws.Bool(true)
+			out.NextContinuationToken = aws.String(entries[i-1])
+			break
+		}
+		entry := entries[i]
+		if isPrefix[entry] {
+			out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(entry)})
+		} else {
+			v := m.find(entry, "")
+			out.Contents = append(out.Contents, types.Object{
+				Key:          aws.String(entry),
+				Size:         aws.Int64(int64(len(v.data))),
+				ETag:         aws.String(v.etag),
+				LastModified: aws.Time(v.lastModified),
+			})
+		}
+		count++
+	}
+	if out.IsTruncated == nil {
+		out.IsTruncated = aws.Bool(false)
+	}
+	out.KeyCount = aws.Int32(int32(count))
+	return out, nil
+}
+
+// ListObjectVersions lists every version and delete marker by prefix, newest
+// first within each key. All results are returned in a single page.
+func (m *MemoryS3) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prefix := aws.ToString(params.Prefix)
+	var keys []string
+	for key := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	out := &s3.ListObjectVersionsOutput{Prefix: params.Prefix, IsTruncated: aws.Bool(false)}
+	for _, key := range keys {
+		versions := m.objects[key]
+		for i := len(versions) - 1; i >= 0; i-- {
+			v := versions[i]
+			isLatest := aws.Bool(i == len(versions)-1)
+			if v.deleteMarker {
+				out.DeleteMarkers = append(out.DeleteMarkers, types.DeleteMarkerEntry{
+					Key:          aws.String(key),
+					VersionId:    aws.String(v.id),
+					IsLatest:     isLatest,
+					LastModified: aws.Time(v.lastModified),
+				})
+				continue
+			}
+			out.Versions = append(out.Versions, types.ObjectVersion{
+				Key:          aws.String(key),
+				VersionId:    aws.String(v.id),
+				IsLatest:     isLatest,
+				LastModified: aws.Time(v.lastModified),
+				Size:         aws.Int64(int64(len(v.data))),
+				ETag:         aws.String(v.etag),
+			})
+		}
+	}
+	return out, nil
+}
+
+// Keys returns the keys of every current object, sorted (useful in assertions)
+func (m *MemoryS3) Keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var keys []string
+	for key := range m.objects {
+		if m.find(key, "") != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// VersionCount returns how many versions (excluding delete markers) a key has
+func (m *MemoryS3) VersionCount(key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, v := range m.objects[key] {
+		if !v.deleteMarker {
+			count++
+		}
+	}
+	return count
+}
@@ -69,6 +69,10 @@ func (s *NoopStorage) Transition(ctx context.Context, tenant, contentType, id, e
 	return nil, ErrStorageNotConfigured
 }
 
+func (s *NoopStorage) CopyContent(ctx context.Context, tenant, contentType, id, ext string, fromState, toState State, metadata map[string]string) (*ContentItem, error) {
+	return nil, ErrStorageNotConfigured
+}
+
 // Versioning - all return ErrStorageNotConfigured
 
 func (s *NoopStorage) ListVersions(ctx context.Context, tenant, contentType, id, ext string) ([]*ContentVersion, error) {
@@ -79,6 +83,10 @@ func (s *NoopStorage) GetVersion(ctx context.Context, tenant, contentType, id, e
 	return nil, ErrStorageNotConfigured
 }
 
+func (s *NoopStorage) FindVersionByETag(ctx context.Context, tenant, contentType, id, ext string, state State, etag string) (*ContentItem, error) {
+	return nil, ErrStorageNotConfigured
+}
+
 func (s *NoopStorage) GetVersionStream(ctx context.Context, tenant, contentType, id, ext, versionID string) (*ContentStream, error) {
 	return nil, ErrStorageNotConfigured
 }
@@ -87,6 +95,10 @@ func (s *NoopStorage) RestoreVersion(ctx context.Context, tenant, contentType, i
 	return nil, ErrStorageNotConfigured
 }
 
+func (s *NoopStorage) PruneVersions(ctx context.Context, tenant, contentType, id, ext string, dryRun bool) ([]*ContentVersion, error) {
+	return nil, ErrStorageNotConfigured
+}
+
 // History - all return ErrStorageNotConfigured
 
 func (s *NoopStorage) PutHistoryRecord(ctx context.Context, tenant, contentType, id string, record *HistoryRecord) error {
@@ -177,6 +189,28 @@ func (s *NoopStorage) HasUnresolvedComments(ctx context.Context, tenant, content
 	return false, ErrStorageNotConfigured
 }
 
+// Attachments - all return ErrStorageNotConfigured
+
+func (s *NoopStorage) PutAttachment(ctx context.Context, tenant, contentType, contentID, name string, body io.Reader, contentLength int64, mimeType string) (*Attachment, error) {
+	return nil, ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) GetAttachment(ctx context.Context, tenant, contentType, contentID, name string) (*ContentStream, error) {
+	return nil, ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) ListAttachments(ctx context.Context, tenant, contentType, contentID string) ([]*Attachment, error) {
+	return nil, ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) DeleteAttachment(ctx context.Context, tenant, contentType, contentID, name string) error {
+	return ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) DeleteAllAttachments(ctx context.Context, tenant, contentType, contentID string) error {
+	return ErrStorageNotConfigured
+}
+
 // Webhooks - all return ErrStorageNotConfigured
 
 func (s *NoopStorage) ListWebhooks(ctx context.Context, tenant string) ([]*Webhook, error) {
@@ -195,6 +229,24 @@ func (s *NoopStorage) DeleteWebhook(ctx context.Context, tenant, webhookID strin
 	return ErrStorageNotConfigured
 }
 
+// API keys - all return ErrStorageNotConfigured
+
+func (s *NoopStorage) ListAPIKeys(ctx context.Context, tenant string) ([]*APIKey, error) {
+	return nil, ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) GetAPIKey(ctx context.Context, tenant, keyID string) (*APIKey, error) {
+	return nil, ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) PutAPIKey(ctx context.Context, tenant string, key *APIKey) error {
+	return ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) DeleteAPIKey(ctx context.Context, tenant, keyID string) error {
+	return ErrStorageNotConfigured
+}
+
 // Tenants - returns ErrStorageNotConfigured
 
 func (s *NoopStorage) ListTenants(ctx context.Context) ([]string, error) {
@@ -247,6 +299,68 @@ func (s *NoopStorage) DeleteExpiredSessions(ctx context.Context) (int, error) {
 	return 0, ErrStorageNotConfigured
 }
 
+// Documents - all return ErrStorageNotConfigured
+
+func (s *NoopStorage) GetDocument(ctx context.Context, tenant, collection, id string) ([]byte, error) {
+	return nil, ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) PutDocument(ctx context.Context, tenant, collection, id string, data []byte) error {
+	return ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) DeleteDocument(ctx context.Context, tenant, collection, id string) error {
+	return ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) ListDocuments(ctx context.Context, tenant, collection string) ([]string, error) {
+	return nil, ErrStorageNotConfigured
+}
+
+// Consistency - all return ErrStorageNotConfigured
+
+func (s *NoopStorage) ListCommentedIDs(ctx context.Context, tenant, contentType string, state State) ([]string, error) {
+	return nil, ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) ListHistoryIDs(ctx context.Context, tenant, contentType string) ([]string, error) {
+	return nil, ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) DeleteHistoryRecord(ctx context.Context, tenant, contentType, id, version string) error {
+	return ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) ContentDeletedAt(ctx context.Context, tenant, contentType, id string) (time.Time, bool, error) {
+	return time.Time{}, false, ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) AcquireLease(ctx context.Context, key string, ttl time.Duration) (*Lease, error) {
+	return nil, ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) RenewLease(ctx context.Context, lease *Lease, ttl time.Duration) (*Lease, error) {
+	return nil, ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) ReleaseLease(ctx context.Context, lease *Lease) error {
+	return ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) GetLease(ctx context.Context, key string) (*Lease, error) {
+	return nil, ErrStorageNotConfigured
+}
+
+// Backups - all return ErrStorageNotConfigured
+
+func (s *NoopStorage) BackupObject(ctx context.Context, tenant, contentType, id, ext string, state State, objectID string) error {
+	return ErrStorageNotConfigured
+}
+
+func (s *NoopStorage) RestoreObject(ctx context.Context, tenant, contentType, id, ext string, state State, objectID string) (*ContentItem, error) {
+	return nil, ErrStorageNotConfigured
+}
+
 // Metadata - all return ErrStorageNotConfigured
 
 func (s *NoopStorage) GetMetadata(ctx context.Context, tenant, contentType, id, ext string, state State) (map[string]string, error) {
@@ -0,0 +1,431 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"velocity/internal/log"
+)
+
+// Replication settings
+const (
+	replicationQueueSize   = 4096             // writes waiting per secondary before new ones are dropped
+	replicationAttempts    = 6                // tries per write, backing off between them
+	replicationBackoff     = 1 * time.Second  // first retry delay, doubled each time
+	replicationCallTimeout = 60 * time.Second // per attempt
+)
+
+// replicationTask brings one thing on a secondary up to date with the primary
+type replicationTask struct {
+	name string // for logs
+	sync func(ctx context.Context, secondary Storage) error
+}
+
+// replica is a secondary and the queue of writes it has yet to receive
+type replica struct {
+	name    string
+	storage Storage
+	queue   chan replicationTask
+}
+
+// ReplicatedStorage wraps a primary Storage and copies content, metadata,
+// schemas, and history records to secondary backends (buckets in other
+// regions) after each write, without relying on bucket-level replication.
+// Writes return once the primary has them; each secondary is updated in the
+// background, retrying with backoff. A task copies whatever the primary holds
+// when it runs, so retries and reordering converge on the primary's state.
+// Reads that fail on the primary, other than for missing content, are retried
+// on the secondaries in order. Everything else uses the primary only.
+type ReplicatedStorage struct {
+	Storage
+	replicas []*replica
+	pending  atomic.Int64
+	dropped  atomic.Int64
+	failed   atomic.Int64
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Ensure ReplicatedStorage implements Storage interface
+var _ Storage = (*ReplicatedStorage)(nil)
+
+// NewReplicatedStorage wraps a primary with the given secondaries, named for
+// logs by their position (replica-1, replica-2, ...), and starts replicating.
+func NewReplicatedStorage(primary Storage, secondaries ...Storage) *ReplicatedStorage {
+	rs := &ReplicatedStorage{Storage: primary, stopCh: make(chan struct{})}
+	for i, secondary := range secondaries {
+		r := &replica{name: fmt.Sprintf("replica-%d", i+1), storage: secondary, queue: make(chan replicationTask, replicationQueueSize)}
+		rs.replicas = append(rs.replicas, r)
+		rs.wg.Add(1)
+		go rs.worker(r)
+	}
+	return rs
+}
+
+// ReplicationStatus reports writes waiting to reach a secondary, writes
+// dropped because a queue was full, and writes that failed every attempt
+func (rs *ReplicatedStorage) ReplicationStatus() (pending, dropped, failed int64) {
+	return rs.pending.Load(), rs.dropped.Load(), rs.failed.Load()
+}
+
+// Drain waits until every queued write has reached its secondary or failed
+func (rs *ReplicatedStorage) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for rs.pending.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d writes not replicated: %w", rs.pending.Load(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Close waits up to 10 seconds for queued writes, stops replicating, and
+// closes secondaries that hold resources
+func (rs *ReplicatedStorage) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := rs.Drain(ctx); err != nil {
+		log.Error("Stopping replication: %v", err)
+	}
+	close(rs.stopCh)
+	rs.wg.Wait()
+	for _, r := range rs.replicas {
+		if closer, ok := r.storage.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	return nil
+}
+
+// --- Replication internals ---
+
+func (rs *ReplicatedStorage) worker(r *replica) {
+	defer rs.wg.Done()
+	for {
+		select {
+		case task := <-r.queue:
+			rs.run(r, task)
+			rs.pending.Add(-1)
+		case <-rs.stopCh:
+			return
+		}
+	}
+}
+
+// run applies a task to a secondary, retrying with backoff
+func (rs *ReplicatedStorage) run(r *replica, task replicationTask) {
+	delay := replicationBackoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), replicationCallTimeout)
+		err := task.sync(ctx, r.storage)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt == replicationAttempts {
+			rs.failed.Add(1)
+			log.Error("Failed to replicate %s to %s after %d attempts: %v", task.name, r.name, attempt, err)
+			return
+		}
+		log.Info("Failed to replicate %s to %s (attempt %d): %v", task.name, r.name, attempt, err)
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-rs.stopCh:
+			return
+		}
+	}
+}
+
+// replicate queues a task for every secondary
+func (rs *ReplicatedStorage) replicate(name string, sync func(ctx context.Context, secondary Storage) error) {
+	task := replicationTask{name: name, sync: sync}
+	for _, r := range rs.replicas {
+		rs.pending.Add(1)
+		select {
+		case r.queue <- task:
+		default:
+			rs.pending.Add(-1)
+			rs.dropped.Add(1)
+			log.Error("Replication queue for %s is full; dropped %s", r.name, name)
+		}
+	}
+}
+
+// replicateContent copies an item's copy in a state from the primary to the
+// secondaries, with its metadata, or deletes it there if the primary has none
+func (rs *ReplicatedStorage) replicateContent(tenant, contentType, id, ext string, state State) {
+	if state == "" {
+		state = StateLive
+	}
+	rs.replicate(fmt.Sprintf("%s/%s/%s (%s)", tenant, contentType, id, state), func(ctx context.Context, secondary Storage) error {
+		stream, err := rs.Storage.GetStream(ctx, tenant, contentType, id, ext, state)
+		if IsNotFound(err) {
+			if err := secondary.Delete(ctx, tenant, contentType, id, ext, state); err != nil && !IsNotFound(err) {
+				return err
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		defer stream.Body.Close()
+		_, err = secondary.PutStream(ctx, tenant, contentType, id, ext, stream.Body, stream.Size, stream.ContentType, state, stream.Metadata)
+		return err
+	})
+}
+
+// replicateSchema copies a schema from the primary to the secondaries, or
+// deletes it there if the primary has none. tenant is empty for global schemas.
+func (rs *ReplicatedStorage) replicateSchema(tenant, schemaName string) {
+	name := "schema " + schemaName
+	if tenant != "" {
+		name = "schema " + tenant + "/" + schemaName
+	}
+	rs.replicate(name, func(ctx context.Context, secondary Storage) error {
+		var schema *Schema
+		var err error
+		if tenant == "" {
+			schema, err = rs.Storage.GetGlobalSchema(ctx, schemaName)
+		} else {
+			schema, err = rs.Storage.GetTenantSchema(ctx, tenant, schemaName)
+		}
+		switch {
+		case IsNotFound(err) && tenant == "":
+			return ignoreNotFound(secondary.DeleteGlobalSchema(ctx, schemaName))
+		case IsNotFound(err):
+			return ignoreNotFound(secondary.DeleteTenantSchema(ctx, tenant, schemaName))
+		case err != nil:
+			return err
+		case tenant == "":
+			return secondary.PutGlobalSchema(ctx, schemaName, schema.Content)
+		default:
+			return secondary.PutTenantSchema(ctx, tenant, schemaName, schema.Content)
+		}
+	})
+}
+
+// IsNotFound reports whether a storage error means the object doesn't exist
+func IsNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	return httpStatus(err) == 404 || strings.Contains(err.Error(), "NoSuchKey") || strings.Contains(err.Error(), "not found")
+}
+
+func ignoreNotFound(err error) error {
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// fallback runs a read on the primary, then on each secondary while the
+// failure isn't just that the object is missing
+func fallback[T any](rs *ReplicatedStorage, read func(s Storage) (T, error)) (T, error) {
+	result, err := read(rs.Storage)
+	if err == nil || IsNotFound(err) || errors.Is(err, context.Canceled) {
+		return result, err
+	}
+	for _, r := range rs.replicas {
+		replicaResult, replicaErr := read(r.storage)
+		if replicaErr == nil {
+			log.Info("Read from %s after the primary failed: %v", r.name, err)
+			return replicaResult, nil
+		}
+	}
+	return result, err
+}
+
+// --- Replicated writes ---
+
+func (rs *ReplicatedStorage) Put(ctx context.Context, tenant, contentType, id, ext string, content []byte, mimeType string, state State) (*ContentItem, error) {
+	item, err := rs.Storage.Put(ctx, tenant, contentType, id, ext, content, mimeType, state)
+	if err == nil {
+		rs.replicateContent(tenant, contentType, id, ext, state)
+	}
+	return item, err
+}
+
+func (rs *ReplicatedStorage) PutStream(ctx context.Context, tenant, contentType, id, ext string, body io.Reader, contentLength int64, mimeType string, state State, metadata map[string]string) (*ContentItem, error) {
+	item, err := rs.Storage.PutStream(ctx, tenant, contentType, id, ext, body, contentLength, mimeType, state, metadata)
+	if err == nil {
+		rs.replicateContent(tenant, contentType, id, ext, state)
+	}
+	return item, err
+}
+
+func (rs *ReplicatedStorage) Delete(ctx context.Context, tenant, contentType, id, ext string, state State) error {
+	err := rs.Storage.Delete(ctx, tenant, contentType, id, ext, state)
+	if err == nil {
+		rs.replicateContent(tenant, contentType, id, ext, state)
+	}
+	return err
+}
+
+func (rs *ReplicatedStorage) Transition(ctx context.Context, tenant, contentType, id, ext string, fromState, toState State) (*ContentItem, error) {
+	item, err := rs.Storage.Transition(ctx, tenant, contentType, id, ext, fromState, toState)
+	if err == nil {
+		rs.replicateContent(tenant, contentType, id, ext, toState)
+		rs.replicateContent(tenant, contentType, id, ext, fromState)
+	}
+	return item, err
+}
+
+func (rs *ReplicatedStorage) CopyContent(ctx context.Context, tenant, contentType, id, ext string, fromState, toState State, metadata map[string]string) (*ContentItem, error) {
+	item, err := rs.Storage.CopyContent(ctx, tenant, contentType, id, ext, fromState, toState, metadata)
+	if err == nil {
+		rs.replicateContent(tenant, contentType, id, ext, toState)
+	}
+	return item, err
+}
+
+func (rs *ReplicatedStorage) RestoreVersion(ctx context.Context, tenant, contentType, id, ext, versionID string) (*ContentItem, error) {
+	item, err := rs.Storage.RestoreVersion(ctx, tenant, contentType, id, ext, versionID)
+	if err == nil {
+		rs.replicateContent(tenant, contentType, id, ext, StateLive)
+	}
+	return item, err
+}
+
+func (rs *ReplicatedStorage) RestoreObject(ctx context.Context, tenant, contentType, id, ext string, state State, objectID string) (*ContentItem, error) {
+	item, err := rs.Storage.RestoreObject(ctx, tenant, contentType, id, ext, state, objectID)
+	if err == nil {
+		rs.replicateContent(tenant, contentType, id, ext, state)
+	}
+	return item, err
+}
+
+func (rs *ReplicatedStorage) SetMetadata(ctx context.Context, tenant, contentType, id, ext string, state State, metadata map[string]string) error {
+	err := rs.Storage.SetMetadata(ctx, tenant, contentType, id, ext, state, metadata)
+	if err == nil {
+		rs.replicateContent(tenant, contentType, id, ext, state)
+	}
+	return err
+}
+
+func (rs *ReplicatedStorage) UpdateMetadata(ctx context.Context, tenant, contentType, id, ext string, state State, updates map[string]string) error {
+	err := rs.Storage.UpdateMetadata(ctx, tenant, contentType, id, ext, state, updates)
+	if err == nil {
+		rs.replicateContent(tenant, contentType, id, ext, state)
+	}
+	return err
+}
+
+func (rs *ReplicatedStorage) DeleteMetadataKeys(ctx context.Context, tenant, contentType, id, ext string, state State, keys []string) error {
+	err := rs.Storage.DeleteMetadataKeys(ctx, tenant, contentType, id, ext, state, keys)
+	if err == nil {
+		rs.replicateContent(tenant, contentType, id, ext, state)
+	}
+	return err
+}
+
+func (rs *ReplicatedStorage) PutGlobalSchema(ctx context.Context, schemaName string, content []byte) error {
+	err := rs.Storage.PutGlobalSchema(ctx, schemaName, content)
+	if err == nil {
+		rs.replicateSchema("", schemaName)
+	}
+	return err
+}
+
+func (rs *ReplicatedStorage) PutTenantSchema(ctx context.Context, tenant, schemaName string, content []byte) error {
+	err := rs.Storage.PutTenantSchema(ctx, tenant, schemaName, content)
+	if err == nil {
+		rs.replicateSchema(tenant, schemaName)
+	}
+	return err
+}
+
+func (rs *ReplicatedStorage) DeleteGlobalSchema(ctx context.Context, schemaName string) error {
+	err := rs.Storage.DeleteGlobalSchema(ctx, schemaName)
+	if err == nil {
+		rs.replicateSchema("", schemaName)
+	}
+	return err
+}
+
+func (rs *ReplicatedStorage) DeleteTenantSchema(ctx context.Context, tenant, schemaName string) error {
+	err := rs.Storage.DeleteTenantSchema(ctx, tenant, schemaName)
+	if err == nil {
+		rs.replicateSchema(tenant, schemaName)
+	}
+	return err
+}
+
+// PutHistoryRecord stores a history record and queues a copy of it; records
+// don't change once written
+func (rs *ReplicatedStorage) PutHistoryRecord(ctx context.Context, tenant, contentType, id string, record *HistoryRecord) error {
+	err := rs.Storage.PutHistoryRecord(ctx, tenant, contentType, id, record)
+	if err == nil {
+		copied := *record
+		rs.replicate(fmt.Sprintf("history %s/%s/%s@%s", tenant, contentType, id, record.Version), func(ctx context.Context, secondary Storage) error {
+			return secondary.PutHistoryRecord(ctx, tenant, contentType, id, &copied)
+		})
+	}
+	return err
+}
+
+// --- Reads with fallback ---
+
+func (rs *ReplicatedStorage) Get(ctx context.Context, tenant, contentType, id, ext string, state State) (*ContentItem, error) {
+	return fallback(rs, func(s Storage) (*ContentItem, error) {
+		return s.Get(ctx, tenant, contentType, id, ext, state)
+	})
+}
+
+func (rs *ReplicatedStorage) GetStream(ctx context.Context, tenant, contentType, id, ext string, state State) (*ContentStream, error) {
+	return fallback(rs, func(s Storage) (*ContentStream, error) {
+		return s.GetStream(ctx, tenant, contentType, id, ext, state)
+	})
+}
+
+func (rs *ReplicatedStorage) FindContentStream(ctx context.Context, tenant, contentType, id, extHint string, state State) (*ContentStream, error) {
+	return fallback(rs, func(s Storage) (*ContentStream, error) {
+		return s.FindContentStream(ctx, tenant, contentType, id, extHint, state)
+	})
+}
+
+func (rs *ReplicatedStorage) List(ctx context.Context, tenant, contentType string, state State) ([]*ContentItem, error) {
+	return fallback(rs, func(s Storage) ([]*ContentItem, error) {
+		return s.List(ctx, tenant, contentType, state)
+	})
+}
+
+func (rs *ReplicatedStorage) Exists(ctx context.Context, tenant, contentType, id, ext string, state State) (bool, error) {
+	return fallback(rs, func(s Storage) (bool, error) {
+		return s.Exists(ctx, tenant, contentType, id, ext, state)
+	})
+}
+
+func (rs *ReplicatedStorage) GetMetadata(ctx context.Context, tenant, contentType, id, ext string, state State) (map[string]string, error) {
+	return fallback(rs, func(s Storage) (map[string]string, error) {
+		return s.GetMetadata(ctx, tenant, contentType, id, ext, state)
+	})
+}
+
+func (rs *ReplicatedStorage) GetSchema(ctx context.Context, tenant, schemaName string) (*Schema, error) {
+	return fallback(rs, func(s Storage) (*Schema, error) {
+		return s.GetSchema(ctx, tenant, schemaName)
+	})
+}
+
+func (rs *ReplicatedStorage) GetGlobalSchema(ctx context.Context, schemaName string) (*Schema, error) {
+	return fallback(rs, func(s Storage) (*Schema, error) {
+		return s.GetGlobalSchema(ctx, schemaName)
+	})
+}
+
+func (rs *ReplicatedStorage) GetTenantSchema(ctx context.Context, tenant, schemaName string) (*Schema, error) {
+	return fallback(rs, func(s Storage) (*Schema, error) {
+		return s.GetTenantSchema(ctx, tenant, schemaName)
+	})
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// VersionRetention decides which versions of live content are kept: the
+// newest MaxVersions, plus any younger than MaxAge, whichever keeps more.
+// Zero fields fall back to the storage's defaults (--max-versions and
+// --max-version-age).
+type VersionRetention struct {
+	MaxVersions int           // Versions always kept (negative means unlimited)
+	MaxAge      time.Duration // Versions younger than this are kept past MaxVersions (negative disables)
+}
+
+type retentionContextKey struct{}
+
+// WithVersionRetention returns ctx carrying a retention policy for the live
+// content written through it, overriding the storage defaults
+func WithVersionRetention(ctx context.Context, retention VersionRetention) context.Context {
+	return context.WithValue(ctx, retentionContextKey{}, retention)
+}
+
+// VersionRetentionFromContext returns the retention policy carried by ctx
+func VersionRetentionFromContext(ctx context.Context) (VersionRetention, bool) {
+	retention, ok := ctx.Value(retentionContextKey{}).(VersionRetention)
+	return retention, ok
+}
+
+// merge fills r's zero fields from defaults
+func (r VersionRetention) merge(defaults VersionRetention) VersionRetention {
+	if r.MaxVersions == 0 {
+		r.MaxVersions = defaults.MaxVersions
+	}
+	if r.MaxAge == 0 {
+		r.MaxAge = defaults.MaxAge
+	}
+	return r
+}
+
+// Unlimited reports whether every version is kept
+func (r VersionRetention) Unlimited() bool {
+	return r.MaxVersions <= 0
+}
+
+// Expired returns the versions the policy no longer keeps, given every
+// version of an item, as of now
+func (r VersionRetention) Expired(versions []*ContentVersion, now time.Time) []*ContentVersion {
+	if r.Unlimited() || len(versions) <= r.MaxVersions {
+		return nil
+	}
+
+	sorted := make([]*ContentVersion, len(versions))
+	copy(sorted, versions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LastModified.After(sorted[j].LastModified)
+	})
+
+	var expired []*ContentVersion
+	for _, v := range sorted[r.MaxVersions:] {
+		if v.IsLatest || (r.MaxAge > 0 && now.Sub(v.LastModified) < r.MaxAge) {
+			continue
+		}
+		expired = append(expired, v)
+	}
+	return expired
+}
@@ -0,0 +1,348 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+)
+
+// Content roots. Each tenant has two content roots; one is active and served
+// to readers while the other can be used to stage a full-site import.
+// The blue root is the tenant's original content prefix, so existing
+// content needs no migration.
+const (
+	RootBlue  = "blue"
+	RootGreen = "green"
+
+	rootCollection = "config"
+	rootDocumentID = "content-root"
+	rootCacheTTL   = 5 * time.Second
+)
+
+// ValidRoot checks if a string is a valid content root name
+func ValidRoot(root string) bool {
+	return root == RootBlue || root == RootGreen
+}
+
+// rootContextKey is the context key for an explicit content root
+type rootContextKey struct{}
+
+// WithRoot returns a context that addresses the given content root instead
+// of the tenant's active root
+func WithRoot(ctx context.Context, root string) context.Context {
+	return context.WithValue(ctx, rootContextKey{}, root)
+}
+
+// RootFromContext returns the explicit content root set on a context, if any
+func RootFromContext(ctx context.Context) string {
+	root, _ := ctx.Value(rootContextKey{}).(string)
+	return root
+}
+
+// RootPointer records which content root is active for a tenant
+type RootPointer struct {
+	Active      string    `json:"active"`
+	Previous    string    `json:"previous,omitempty"`
+	ActivatedAt time.Time `json:"activated_at,omitempty"`
+	ActivatedBy string    `json:"activated_by,omitempty"`
+}
+
+// cachedRoot is an in-memory cache entry for a tenant's root pointer
+type cachedRoot struct {
+	pointer   *RootPointer
+	expiresAt time.Time
+}
+
+// RootedStorage wraps a Storage implementation and maps each tenant's content
+// operations onto its active content root. Schemas, webhooks, sessions, and
+// documents are shared by both roots and pass through unchanged.
+type RootedStorage struct {
+	Storage
+	mu    sync.RWMutex
+	cache map[string]*cachedRoot
+}
+
+// Ensure RootedStorage implements Storage interface
+var _ Storage = (*RootedStorage)(nil)
+
+// NewRootedStorage wraps the given Storage with blue/green content roots.
+func NewRootedStorage(inner Storage) *RootedStorage {
+	return &RootedStorage{
+		Storage: inner,
+		cache:   make(map[string]*cachedRoot),
+	}
+}
+
+// RootTenant returns the storage tenant holding a tenant's content in a root.
+// The green root lives under /{root}/tenants/{tenant}/roots/green/.
+func RootTenant(tenant, root string) string {
+	if root == RootGreen {
+		return path.Join(tenant, "roots", RootGreen)
+	}
+	return tenant
+}
+
+// ActiveRoot returns a tenant's root pointer (blue if none is stored).
+// Pointers are cached briefly, so other instances follow a switch within seconds.
+func (rs *RootedStorage) ActiveRoot(ctx context.Context, tenant string) *RootPointer {
+	rs.mu.RLock()
+	cached, ok := rs.cache[tenant]
+	rs.mu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.pointer
+	}
+
+	pointer := &RootPointer{Active: RootBlue}
+	if data, err := rs.Storage.GetDocument(ctx, tenant, rootCollection, rootDocumentID); err == nil {
+		var stored RootPointer
+		if json.Unmarshal(data, &stored) == nil && ValidRoot(stored.Active) {
+			pointer = &stored
+		}
+	}
+
+	rs.mu.Lock()
+	rs.cache[tenant] = &cachedRoot{pointer: pointer, expiresAt: time.Now().Add(rootCacheTTL)}
+	rs.mu.Unlock()
+	return pointer
+}
+
+// ActivateRoot atomically switches a tenant's active content root
+func (rs *RootedStorage) ActivateRoot(ctx context.Context, tenant, root, author string) (*RootPointer, error) {
+	if !ValidRoot(root) {
+		return nil, fmt.Errorf("invalid content root: %s", root)
+	}
+
+	current := rs.ActiveRoot(ctx, tenant)
+	pointer := &RootPointer{
+		Active:      root,
+		Previous:    current.Active,
+		ActivatedAt: time.Now().UTC(),
+		ActivatedBy: author,
+	}
+
+	data, err := json.Marshal(pointer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal root pointer: %w", err)
+	}
+	if err := rs.Storage.PutDocument(ctx, tenant, rootCollection, rootDocumentID, data); err != nil {
+		return nil, err
+	}
+
+	rs.mu.Lock()
+	rs.cache[tenant] = &cachedRoot{pointer: pointer, expiresAt: time.Now().Add(rootCacheTTL)}
+	rs.mu.Unlock()
+	return pointer, nil
+}
+
+// resolve returns the storage tenant for a content operation: the root set on
+// the context if any, otherwise the tenant's active root
+func (rs *RootedStorage) resolve(ctx context.Context, tenant string) string {
+	root := RootFromContext(ctx)
+	if root == "" {
+		root = rs.ActiveRoot(ctx, tenant).Active
+	}
+	return RootTenant(tenant, root)
+}
+
+// Invalidate forwards out-of-band change notifications to the wrapped cache, if any
+func (rs *RootedStorage) Invalidate(tenant, contentType, id, ext string, state State) {
+	if inv, ok := rs.Storage.(interface {
+		Invalidate(tenant, contentType, id, ext string, state State)
+	}); ok {
+		inv.Invalidate(tenant, contentType, id, ext, state)
+	}
+}
+
+// --- Rooted content methods ---
+
+func (rs *RootedStorage) Put(ctx context.Context, tenant, contentType, id, ext string, content []byte, mimeType string, state State) (*ContentItem, error) {
+	return rs.Storage.Put(ctx, rs.resolve(ctx, tenant), contentType, id, ext, content, mimeType, state)
+}
+
+func (rs *RootedStorage) PutStream(ctx context.Context, tenant, contentType, id, ext string, body io.Reader, contentLength int64, mimeType string, state State, metadata map[string]string) (*ContentItem, error) {
+	return rs.Storage.PutStream(ctx, rs.resolve(ctx, tenant), contentType, id, ext, body, contentLength, mimeType, state, metadata)
+}
+
+func (rs *RootedStorage) Get(ctx context.Context, tenant, contentType, id, ext string, state State) (*ContentItem, error) {
+	return rs.Storage.Get(ctx, rs.resolve(ctx, tenant), contentType, id, ext, state)
+}
+
+func (rs *RootedStorage) GetStream(ctx context.Context, tenant, contentType, id, ext string, state State) (*ContentStream, error) {
+	return rs.Storage.GetStream(ctx, rs.resolve(ctx, tenant), contentType, id, ext, state)
+}
+
+func (rs *RootedStorage) FindContentStream(ctx context.Context, tenant, contentType, id, extHint string, state State) (*ContentStream, error) {
+	return rs.Storage.FindContentStream(ctx, rs.resolve(ctx, tenant), contentType, id, extHint, state)
+}
+
+func (rs *RootedStorage) Delete(ctx context.Context, tenant, contentType, id, ext string, state State) error {
+	return rs.Storage.Delete(ctx, rs.resolve(ctx, tenant), contentType, id, ext, state)
+}
+
+func (rs *RootedStorage) List(ctx context.Context, tenant, contentType string, state State) ([]*ContentItem, error) {
+	return rs.Storage.List(ctx, rs.resolve(ctx, tenant), contentType, state)
+}
+
+func (rs *RootedStorage) Browse(ctx context.Context, tenant, contentType, prefix string, state State) (*BrowseResult, error) {
+	return rs.Storage.Browse(ctx, rs.resolve(ctx, tenant), contentType, prefix, state)
+}
+
+func (rs *RootedStorage) Exists(ctx context.Context, tenant, contentType, id, ext string, state State) (bool, error) {
+	return rs.Storage.Exists(ctx, rs.resolve(ctx, tenant), contentType, id, ext, state)
+}
+
+func (rs *RootedStorage) Transition(ctx context.Context, tenant, contentType, id, ext string, fromState, toState State) (*ContentItem, error) {
+	return rs.Storage.Transition(ctx, rs.resolve(ctx, tenant), contentType, id, ext, fromState, toState)
+}
+
+func (rs *RootedStorage) CopyContent(ctx context.Context, tenant, contentType, id, ext string, fromState, toState State, metadata map[string]string) (*ContentItem, error) {
+	return rs.Storage.CopyContent(ctx, rs.resolve(ctx, tenant), contentType, id, ext, fromState, toState, metadata)
+}
+
+func (rs *RootedStorage) ListVersions(ctx context.Context, tenant, contentType, id, ext string) ([]*ContentVersion, error) {
+	return rs.Storage.ListVersions(ctx, rs.resolve(ctx, tenant), contentType, id, ext)
+}
+
+func (rs *RootedStorage) GetVersion(ctx context.Context, tenant, contentType, id, ext, versionID string) (*ContentItem, error) {
+	return rs.Storage.GetVersion(ctx, rs.resolve(ctx, tenant), contentType, id, ext, versionID)
+}
+
+func (rs *RootedStorage) FindVersionByETag(ctx context.Context, tenant, contentType, id, ext string, state State, etag string) (*ContentItem, error) {
+	return rs.Storage.FindVersionByETag(ctx, rs.resolve(ctx, tenant), contentType, id, ext, state, etag)
+}
+
+func (rs *RootedStorage) GetVersionStream(ctx context.Context, tenant, contentType, id, ext, versionID string) (*ContentStream, error) {
+	return rs.Storage.GetVersionStream(ctx, rs.resolve(ctx, tenant), contentType, id, ext, versionID)
+}
+
+func (rs *RootedStorage) RestoreVersion(ctx context.Context, tenant, contentType, id, ext, versionID string) (*ContentItem, error) {
+	return rs.Storage.RestoreVersion(ctx, rs.resolve(ctx, tenant), contentType, id, ext, versionID)
+}
+
+func (rs *RootedStorage) PruneVersions(ctx context.Context, tenant, contentType, id, ext string, dryRun bool) ([]*ContentVersion, error) {
+	return rs.Storage.PruneVersions(ctx, rs.resolve(ctx, tenant), contentType, id, ext, dryRun)
+}
+
+func (rs *RootedStorage) PutHistoryRecord(ctx context.Context, tenant, contentType, id string, record *HistoryRecord) error {
+	return rs.Storage.PutHistoryRecord(ctx, rs.resolve(ctx, tenant), contentType, id, record)
+}
+
+func (rs *RootedStorage) GetHistoryRecord(ctx context.Context, tenant, contentType, id, version string) (*HistoryRecord, error) {
+	return rs.Storage.GetHistoryRecord(ctx, rs.resolve(ctx, tenant), contentType, id, version)
+}
+
+func (rs *RootedStorage) ListHistoryRecords(ctx context.Context, tenant, contentType, id string) ([]*HistoryRecord, error) {
+	return rs.Storage.ListHistoryRecords(ctx, rs.resolve(ctx, tenant), contentType, id)
+}
+
+func (rs *RootedStorage) GetLatestHistoryVersion(ctx context.Context, tenant, contentType, id string) (string, error) {
+	return rs.Storage.GetLatestHistoryVersion(ctx, rs.resolve(ctx, tenant), contentType, id)
+}
+
+func (rs *RootedStorage) PutComment(ctx context.Context, tenant, contentType, contentID string, state State, comment *Comment) error {
+	return rs.Storage.PutComment(ctx, rs.resolve(ctx, tenant), contentType, contentID, state, comment)
+}
+
+func (rs *RootedStorage) GetComment(ctx context.Context, tenant, contentType, contentID string, state State, id string) (*Comment, error) {
+	return rs.Storage.GetComment(ctx, rs.resolve(ctx, tenant), contentType, contentID, state, id)
+}
+
+func (rs *RootedStorage) ListComments(ctx context.Context, tenant, contentType, contentID string, state State) ([]*Comment, error) {
+	return rs.Storage.ListComments(ctx, rs.resolve(ctx, tenant), contentType, contentID, state)
+}
+
+func (rs *RootedStorage) DeleteComment(ctx context.Context, tenant, contentType, contentID string, state State, id string) error {
+	return rs.Storage.DeleteComment(ctx, rs.resolve(ctx, tenant), contentType, contentID, state, id)
+}
+
+func (rs *RootedStorage) DeleteAllComments(ctx context.Context, tenant, contentType, contentID string, state State) error {
+	return rs.Storage.DeleteAllComments(ctx, rs.resolve(ctx, tenant), contentType, contentID, state)
+}
+
+func (rs *RootedStorage) HasUnresolvedComments(ctx context.Context, tenant, contentType, contentID string, state State) (bool, error) {
+	return rs.Storage.HasUnresolvedComments(ctx, rs.resolve(ctx, tenant), contentType, contentID, state)
+}
+
+func (rs *RootedStorage) PutAttachment(ctx context.Context, tenant, contentType, contentID, name string, body io.Reader, contentLength int64, mimeType string) (*Attachment, error) {
+	return rs.Storage.PutAttachment(ctx, rs.resolve(ctx, tenant), contentType, contentID, name, body, contentLength, mimeType)
+}
+
+func (rs *RootedStorage) GetAttachment(ctx context.Context, tenant, contentType, contentID, name string) (*ContentStream, error) {
+	return rs.Storage.GetAttachment(ctx, rs.resolve(ctx, tenant), contentType, contentID, name)
+}
+
+func (rs *RootedStorage) ListAttachments(ctx context.Context, tenant, contentType, contentID string) ([]*Attachment, error) {
+	return rs.Storage.ListAttachments(ctx, rs.resolve(ctx, tenant), contentType, contentID)
+}
+
+func (rs *RootedStorage) DeleteAttachment(ctx context.Context, tenant, contentType, contentID, name string) error {
+	return rs.Storage.DeleteAttachment(ctx, rs.resolve(ctx, tenant), contentType, contentID, name)
+}
+
+func (rs *RootedStorage) DeleteAllAttachments(ctx context.Context, tenant, contentType, contentID string) error {
+	return rs.Storage.DeleteAllAttachments(ctx, rs.resolve(ctx, tenant), contentType, contentID)
+}
+
+func (rs *RootedStorage) ListContentTypes(ctx context.Context, tenant string) ([]string, error) {
+	return rs.Storage.ListContentTypes(ctx, rs.resolve(ctx, tenant))
+}
+
+func (rs *RootedStorage) CreateContentType(ctx context.Context, tenant, contentType string) error {
+	return rs.Storage.CreateContentType(ctx, rs.resolve(ctx, tenant), contentType)
+}
+
+func (rs *RootedStorage) CreateFolder(ctx context.Context, tenant, contentType, folderPath string, state State) error {
+	return rs.Storage.CreateFolder(ctx, rs.resolve(ctx, tenant), contentType, folderPath, state)
+}
+
+func (rs *RootedStorage) GetDirectoryIndex(ctx context.Context, tenant, contentType, prefix string, state State) (*DirectoryIndex, error) {
+	return rs.Storage.GetDirectoryIndex(ctx, rs.resolve(ctx, tenant), contentType, prefix, state)
+}
+
+func (rs *RootedStorage) PutDirectoryIndex(ctx context.Context, tenant, contentType, prefix string, state State, index *DirectoryIndex) error {
+	return rs.Storage.PutDirectoryIndex(ctx, rs.resolve(ctx, tenant), contentType, prefix, state, index)
+}
+
+func (rs *RootedStorage) GetMetadata(ctx context.Context, tenant, contentType, id, ext string, state State) (map[string]string, error) {
+	return rs.Storage.GetMetadata(ctx, rs.resolve(ctx, tenant), contentType, id, ext, state)
+}
+
+func (rs *RootedStorage) SetMetadata(ctx context.Context, tenant, contentType, id, ext string, state State, metadata map[string]string) error {
+	return rs.Storage.SetMetadata(ctx, rs.resolve(ctx, tenant), contentType, id, ext, state, metadata)
+}
+
+func (rs *RootedStorage) UpdateMetadata(ctx context.Context, tenant, contentType, id, ext string, state State, updates map[string]string) error {
+	return rs.Storage.UpdateMetadata(ctx, rs.resolve(ctx, tenant), contentType, id, ext, state, updates)
+}
+
+func (rs *RootedStorage) DeleteMetadataKeys(ctx context.Context, tenant, contentType, id, ext string, state State, keys []string) error {
+	return rs.Storage.DeleteMetadataKeys(ctx, rs.resolve(ctx, tenant), contentType, id, ext, state, keys)
+}
+
+func (rs *RootedStorage) ListCommentedIDs(ctx context.Context, tenant, contentType string, state State) ([]string, error) {
+	return rs.Storage.ListCommentedIDs(ctx, rs.resolve(ctx, tenant), contentType, state)
+}
+
+func (rs *RootedStorage) ListHistoryIDs(ctx context.Context, tenant, contentType string) ([]string, error) {
+	return rs.Storage.ListHistoryIDs(ctx, rs.resolve(ctx, tenant), contentType)
+}
+
+func (rs *RootedStorage) DeleteHistoryRecord(ctx context.Context, tenant, contentType, id, version string) error {
+	return rs.Storage.DeleteHistoryRecord(ctx, rs.resolve(ctx, tenant), contentType, id, version)
+}
+
+func (rs *RootedStorage) ContentDeletedAt(ctx context.Context, tenant, contentType, id string) (time.Time, bool, error) {
+	return rs.Storage.ContentDeletedAt(ctx, rs.resolve(ctx, tenant), contentType, id)
+}
+
+func (rs *RootedStorage) BackupObject(ctx context.Context, tenant, contentType, id, ext string, state State, objectID string) error {
+	return rs.Storage.BackupObject(ctx, rs.resolve(ctx, tenant), contentType, id, ext, state, objectID)
+}
+
+func (rs *RootedStorage) RestoreObject(ctx context.Context, tenant, contentType, id, ext string, state State, objectID string) (*ContentItem, error) {
+	return rs.Storage.RestoreObject(ctx, rs.resolve(ctx, tenant), contentType, id, ext, state, objectID)
+}
@@ -4,10 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"path"
-	"sort"
 	"strings"
 	"time"
 
@@ -16,28 +16,65 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 
+	"velocity/internal/crypto"
 	"velocity/internal/log"
 )
 
+// S3 authentication modes
+const (
+	S3AuthStatic = "static" // Access key pair from the config
+	S3AuthIAM    = "iam"    // Default AWS credential chain: environment, IRSA web identity, ECS task role, or instance profile
+)
+
 // S3Config holds the S3/Wasabi configuration
 type S3Config struct {
-	Endpoint        string // S3 endpoint (e.g., s3.wasabisys.com)
+	Endpoint        string // S3 endpoint (e.g., s3.wasabisys.com, or http://localhost:9000 for MinIO)
 	Region          string // Region (e.g., us-east-1)
 	Bucket          string // Bucket name
 	AccessKeyID     string
 	SecretAccessKey string
-	Root            string // Root path prefix (e.g., "development" or "production")
-	MaxVersions     int    // Max versions to keep (0 or negative means unlimited, default 10)
+	Root            string        // Root path prefix (e.g., "development" or "production")
+	MaxVersions     int           // Max versions to keep (0 or negative means unlimited, default 10)
+	MaxVersionAge   time.Duration // Versions younger than this are kept past MaxVersions (0 keeps by count only)
+	Auth            string        // S3AuthStatic (default) or S3AuthIAM
+	FIPS            bool          // Use FIPS endpoints (AWS endpoints only, i.e. no custom Endpoint)
+
+	Client     S3API          // Client to use instead of building one (e.g. a mock); endpoint and credentials are then ignored
+	HTTPClient aws.HTTPClient // HTTP client for the built client (optional)
+
+	Credentials aws.CredentialsProvider // Provider used instead of the static keys (optional)
+
+	Keyring *crypto.Keyring // Encrypts webhooks at rest (optional; stored in plaintext without it)
+}
+
+// S3API is the subset of the S3 client used by S3Storage. *s3.Client
+// implements it; tests can supply a mock.
+type S3API interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
 }
 
+// Ensure *s3.Client implements S3API
+var _ S3API = (*s3.Client)(nil)
+
 // S3Storage provides S3-compatible storage operations.
 // Implements the Storage interface.
 type S3Storage struct {
-	s3Client    *s3.Client
+	s3Client    S3API
 	bucket      string
-	root        string // Root path prefix
-	maxVersions int    // Max versions to keep (0 or negative means unlimited)
+	root        string        // Root path prefix
+	maxVersions int           // Max versions to keep (0 or negative means unlimited)
+	maxAge      time.Duration // Versions younger than this are kept past maxVersions
+	keyring     *crypto.Keyring
 }
 
 // Ensure S3Storage implements Storage interface
@@ -45,35 +82,15 @@ var _ Storage = (*S3Storage)(nil)
 
 // NewS3Storage creates a new S3-compatible storage client
 func NewS3Storage(cfg S3Config) (*S3Storage, error) {
-	// Custom endpoint resolver for Wasabi
-	customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
-		if cfg.Endpoint != "" {
-			return aws.Endpoint{
-				URL:               fmt.Sprintf("https://%s", cfg.Endpoint),
-				SigningRegion:     cfg.Region,
-				HostnameImmutable: true,
-			}, nil
+	s3Client := cfg.Client
+	if s3Client == nil {
+		client, err := newS3Client(cfg)
+		if err != nil {
+			return nil, err
 		}
-		return aws.Endpoint{}, &aws.EndpointNotFoundError{}
-	})
-
-	awsCfg, err := config.LoadDefaultConfig(context.Background(),
-		config.WithRegion(cfg.Region),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			cfg.AccessKeyID,
-			cfg.SecretAccessKey,
-			"",
-		)),
-		config.WithEndpointResolverWithOptions(customResolver),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		s3Client = client
 	}
 
-	s3Client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
-		o.UsePathStyle = true // Required for Wasabi and many S3-compatible stores
-	})
-
 	// Clean up root path (remove leading/trailing slashes)
 	root := strings.Trim(cfg.Root, "/")
 
@@ -88,9 +105,66 @@ func NewS3Storage(cfg S3Config) (*S3Storage, error) {
 		bucket:      cfg.Bucket,
 		root:        root,
 		maxVersions: maxVersions,
+		maxAge:      cfg.MaxVersionAge,
+		keyring:     cfg.Keyring,
 	}, nil
 }
 
+// newS3Client builds an S3 client for the configured endpoint
+func newS3Client(cfg S3Config) (*s3.Client, error) {
+	// Custom endpoint resolver for Wasabi (https unless a scheme is given)
+	endpoint := cfg.Endpoint
+	if endpoint != "" && !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+		if endpoint != "" {
+			return aws.Endpoint{
+				URL:               endpoint,
+				SigningRegion:     cfg.Region,
+				HostnameImmutable: true,
+			}, nil
+		}
+		return aws.Endpoint{}, &aws.EndpointNotFoundError{}
+	})
+
+	opts := []func(*config.LoadOptions) error{
+		config.WithRegion(cfg.Region),
+		config.WithEndpointResolverWithOptions(customResolver),
+	}
+	switch cfg.Auth {
+	case "", S3AuthStatic:
+		var provider aws.CredentialsProvider = credentials.NewStaticCredentialsProvider(
+			cfg.AccessKeyID,
+			cfg.SecretAccessKey,
+			"",
+		)
+		if cfg.Credentials != nil {
+			provider = cfg.Credentials
+		}
+		opts = append(opts, config.WithCredentialsProvider(provider))
+	case S3AuthIAM:
+		// No provider: LoadDefaultConfig resolves the default credential chain
+	default:
+		return nil, fmt.Errorf("invalid S3 auth %q (expected %s or %s)", cfg.Auth, S3AuthStatic, S3AuthIAM)
+	}
+	if cfg.FIPS {
+		opts = append(opts, config.WithUseFIPSEndpoint(aws.FIPSEndpointStateEnabled))
+	}
+	if cfg.HTTPClient != nil {
+		opts = append(opts, config.WithHTTPClient(cfg.HTTPClient))
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		o.UsePathStyle = true // Required for Wasabi and many S3-compatible stores
+	}), nil
+}
+
 // CheckConnection verifies connectivity to S3/Wasabi by checking if the bucket exists
 func (s *S3Storage) CheckConnection(ctx context.Context) error {
 	_, err := s.s3Client.HeadBucket(ctx, &s3.HeadBucketInput{
@@ -112,57 +186,67 @@ func (s *S3Storage) CheckConnection(ctx context.Context) error {
 // contentKey constructs the S3 key for a content item with state
 func (s *S3Storage) contentKey(tenant string, contentType string, id string, ext string, state State) string {
 	if state == StateLive || state == "" {
-		return path.Join(s.root, "tenants", tenant, "content", contentType, fmt.Sprintf("%s.%s", id, ext))
+		return s.tenantKey(tenant, "content", contentType, fmt.Sprintf("%s.%s", id, ext))
 	}
-	return path.Join(s.root, "tenants", tenant, "content", contentType, fmt.Sprintf("_%s", state), fmt.Sprintf("%s.%s", id, ext))
+	return s.tenantKey(tenant, "content", contentType, fmt.Sprintf("_%s", state), fmt.Sprintf("%s.%s", id, ext))
 }
 
 // contentPrefix returns the prefix for listing content of a type with state
 func (s *S3Storage) contentPrefix(tenant string, contentType string, state State) string {
 	if state == StateLive || state == "" {
-		return path.Join(s.root, "tenants", tenant, "content", contentType) + "/"
+		return s.tenantKey(tenant, "content", contentType) + "/"
 	}
-	return path.Join(s.root, "tenants", tenant, "content", contentType, fmt.Sprintf("_%s", state)) + "/"
+	return s.tenantKey(tenant, "content", contentType, fmt.Sprintf("_%s", state)) + "/"
 }
 
 // historyKey constructs the S3 key for a history record
 func (s *S3Storage) historyKey(tenant string, contentType string, id string, version string) string {
-	return path.Join(s.root, "tenants", tenant, "content", contentType, "_history", id, fmt.Sprintf("%s.json", version))
+	return s.tenantKey(tenant, "content", contentType, "_history", id, fmt.Sprintf("%s.json", version))
 }
 
 // historyPrefix returns the prefix for listing history of an item
 func (s *S3Storage) historyPrefix(tenant string, contentType string, id string) string {
-	return path.Join(s.root, "tenants", tenant, "content", contentType, "_history", id) + "/"
+	return s.tenantKey(tenant, "content", contentType, "_history", id) + "/"
 }
 
 // commentKey constructs the S3 key for a comment (within a state directory)
 func (s *S3Storage) commentKey(tenant string, contentType string, contentID string, state State, id string) string {
-	return path.Join(s.root, "tenants", tenant, "content", contentType, fmt.Sprintf("_%s", state), "_comments", contentID, fmt.Sprintf("%s.json", id))
+	return s.tenantKey(tenant, "content", contentType, fmt.Sprintf("_%s", state), "_comments", contentID, fmt.Sprintf("%s.json", id))
 }
 
 // commentPrefix returns the prefix for listing comments on an item in a state
 func (s *S3Storage) commentPrefix(tenant string, contentType string, contentID string, state State) string {
-	return path.Join(s.root, "tenants", tenant, "content", contentType, fmt.Sprintf("_%s", state), "_comments", contentID) + "/"
+	return s.tenantKey(tenant, "content", contentType, fmt.Sprintf("_%s", state), "_comments", contentID) + "/"
+}
+
+// attachmentKey constructs the S3 key for an attachment of a content item
+func (s *S3Storage) attachmentKey(tenant string, contentType string, contentID string, name string) string {
+	return s.tenantKey(tenant, "content", contentType, "_attachments", contentID, name)
+}
+
+// attachmentPrefix returns the prefix for listing attachments of an item
+func (s *S3Storage) attachmentPrefix(tenant string, contentType string, contentID string) string {
+	return s.tenantKey(tenant, "content", contentType, "_attachments", contentID) + "/"
 }
 
 // globalSchemaKey constructs the S3 key for a global schema
 func (s *S3Storage) globalSchemaKey(schemaName string) string {
-	return path.Join(s.root, "schemas", fmt.Sprintf("%s.json", schemaName))
+	return s.objectKey("schemas", fmt.Sprintf("%s.json", schemaName))
 }
 
 // tenantSchemaKey constructs the S3 key for a tenant-specific schema
 func (s *S3Storage) tenantSchemaKey(tenant string, schemaName string) string {
-	return path.Join(s.root, "tenants", tenant, "schemas", fmt.Sprintf("%s.json", schemaName))
+	return s.tenantKey(tenant, "schemas", fmt.Sprintf("%s.json", schemaName))
 }
 
 // globalSchemasPrefix returns the prefix for listing global schemas
 func (s *S3Storage) globalSchemasPrefix() string {
-	return path.Join(s.root, "schemas") + "/"
+	return s.objectKey("schemas") + "/"
 }
 
 // tenantSchemasPrefix returns the prefix for listing tenant schemas
 func (s *S3Storage) tenantSchemasPrefix(tenant string) string {
-	return path.Join(s.root, "tenants", tenant, "schemas") + "/"
+	return s.tenantKey(tenant, "schemas") + "/"
 }
 
 // =============================================================================
@@ -198,6 +282,7 @@ func (s *S3Storage) Put(ctx context.Context, tenant string, contentType string,
 		Content:     content,
 		ContentType: mimeType,
 		VersionID:   versionID,
+		ETag:        aws.ToString(result.ETag),
 	}, nil
 }
 
@@ -232,8 +317,8 @@ func (s *S3Storage) PutStream(ctx context.Context, tenant string, contentType st
 	}
 
 	// Prune old versions if this is live content
-	if state == StateLive && s.maxVersions > 0 {
-		go s.pruneVersions(context.Background(), key)
+	if retention := s.retention(ctx); state == StateLive && !retention.Unlimited() {
+		go s.pruneVersions(context.Background(), key, retention)
 	}
 
 	return &ContentItem{
@@ -242,60 +327,70 @@ func (s *S3Storage) PutStream(ctx context.Context, tenant string, contentType st
 		VersionID:   versionID,
 		Metadata:    metadata,
 		Size:        contentLength,
+		ETag:        aws.ToString(result.ETag),
 	}, nil
 }
 
-// pruneVersions deletes old versions beyond maxVersions
-func (s *S3Storage) pruneVersions(ctx context.Context, key string) {
-	log.Info("Pruning versions for %s (keeping max %d)", key, s.maxVersions)
+// retention returns the version retention policy for a write: the policy in
+// ctx, with the storage defaults for what it leaves unset
+func (s *S3Storage) retention(ctx context.Context) VersionRetention {
+	defaults := VersionRetention{MaxVersions: s.maxVersions, MaxAge: s.maxAge}
+	retention, ok := VersionRetentionFromContext(ctx)
+	if !ok {
+		return defaults
+	}
+	return retention.merge(defaults)
+}
 
-	versions, err := s.s3Client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
-		Bucket: aws.String(s.bucket),
-		Prefix: aws.String(key),
-	})
+// pruneVersions deletes the old versions of key the retention policy no longer keeps
+func (s *S3Storage) pruneVersions(ctx context.Context, key string, retention VersionRetention) []*ContentVersion {
+	log.Info("Pruning versions for %s (keeping max %d, or younger than %s)", key, retention.MaxVersions, retention.MaxAge)
+
+	versions, err := s.keyVersions(ctx, key)
 	if err != nil {
 		log.Error("Failed to list versions for pruning: %v", err)
-		return
+		return nil
 	}
 
-	// Filter to only versions of this exact key (not prefix matches)
-	var objectVersions []types.ObjectVersion
-	for _, v := range versions.Versions {
-		if aws.ToString(v.Key) == key {
-			objectVersions = append(objectVersions, v)
+	log.Info("Found %d versions for %s", len(versions), key)
+
+	var pruned []*ContentVersion
+	for _, v := range retention.Expired(versions, time.Now()) {
+		_, err := s.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket:    aws.String(s.bucket),
+			Key:       aws.String(key),
+			VersionId: aws.String(v.VersionID),
+		})
+		if err != nil {
+			log.Error("Failed to delete old version %s: %v", v.VersionID, err)
+			continue
 		}
+		log.Info("Pruned old version %s of %s", v.VersionID, key)
+		pruned = append(pruned, v)
 	}
+	return pruned
+}
 
-	log.Info("Found %d versions for %s", len(objectVersions), key)
+// PruneVersions deletes the versions of live content the retention policy in
+// ctx (or the storage default) no longer keeps, returning them. Versions also
+// expire by age between writes, so this is run periodically. With dryRun set
+// nothing is deleted.
+func (s *S3Storage) PruneVersions(ctx context.Context, tenant, contentType, id, ext string, dryRun bool) ([]*ContentVersion, error) {
+	retention := s.retention(ctx)
+	if retention.Unlimited() {
+		return nil, nil
+	}
 
-	// Sort by LastModified descending (newest first)
-	sort.Slice(objectVersions, func(i, j int) bool {
-		ti := objectVersions[i].LastModified
-		tj := objectVersions[j].LastModified
-		if ti == nil || tj == nil {
-			return false
-		}
-		return ti.After(*tj)
-	})
+	key := s.contentKey(tenant, contentType, id, ext, StateLive)
+	if !dryRun {
+		return s.pruneVersions(ctx, key, retention), nil
+	}
 
-	// If we have more versions than allowed, delete the oldest ones
-	if len(objectVersions) > s.maxVersions {
-		// Skip the first maxVersions (newest), delete the rest
-		toDelete := objectVersions[s.maxVersions:]
-		log.Info("Deleting %d old versions", len(toDelete))
-		for _, v := range toDelete {
-			_, err := s.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
-				Bucket:    aws.String(s.bucket),
-				Key:       aws.String(key),
-				VersionId: v.VersionId,
-			})
-			if err != nil {
-				log.Error("Failed to delete old version %s: %v", aws.ToString(v.VersionId), err)
-			} else {
-				log.Info("Pruned old version %s of %s", aws.ToString(v.VersionId), key)
-			}
-		}
+	versions, err := s.keyVersions(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
 	}
+	return retention.Expired(versions, time.Now()), nil
 }
 
 // Get retrieves content from S3/Wasabi with a specific state (defaults to live)
@@ -496,6 +591,7 @@ func (s *S3Storage) getByKey(ctx context.Context, key string, versionID string)
 		LastModified: lastMod,
 		Size:         size,
 		ETag:         etag,
+		Metadata:     result.Metadata,
 	}, nil
 }
 
@@ -545,7 +641,7 @@ func (s *S3Storage) List(ctx context.Context, tenant string, contentType string,
 			}
 
 			// Skip state and system directories when listing live content
-			if state == StateLive && (strings.Contains(key, "/_draft/") || strings.Contains(key, "/_pending/") || strings.Contains(key, "/_history/") || strings.Contains(key, "/_comments/")) {
+			if state == StateLive && (strings.Contains(key, "/_draft/") || strings.Contains(key, "/_pending/") || strings.Contains(key, "/_history/") || strings.Contains(key, "/_comments/") || strings.Contains(key, "/_attachments/")) {
 				continue
 			}
 
@@ -675,8 +771,15 @@ func (s *S3Storage) Browse(ctx context.Context, tenant string, contentType strin
 
 // ListVersions returns all versions of a specific live content item (only live content is versioned)
 func (s *S3Storage) ListVersions(ctx context.Context, tenant string, contentType string, id string, ext string) ([]*ContentVersion, error) {
-	key := s.contentKey(tenant, contentType, id, ext, StateLive)
+	versions, err := s.keyVersions(ctx, s.contentKey(tenant, contentType, id, ext, StateLive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+	return versions, nil
+}
 
+// keyVersions returns the versions of exactly key (not of keys it prefixes)
+func (s *S3Storage) keyVersions(ctx context.Context, key string) ([]*ContentVersion, error) {
 	input := &s3.ListObjectVersionsInput{
 		Bucket: aws.String(s.bucket),
 		Prefix: aws.String(key),
@@ -684,7 +787,7 @@ func (s *S3Storage) ListVersions(ctx context.Context, tenant string, contentType
 
 	result, err := s.s3Client.ListObjectVersions(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list versions: %w", err)
+		return nil, err
 	}
 
 	var versions []*ContentVersion
@@ -709,6 +812,7 @@ func (s *S3Storage) ListVersions(ctx context.Context, tenant string, contentType
 				VersionID:    vid,
 				LastModified: lastMod,
 				Size:         size,
+				ETag:         aws.ToString(v.ETag),
 				IsLatest:     v.IsLatest != nil && *v.IsLatest,
 			})
 		}
@@ -717,6 +821,32 @@ func (s *S3Storage) ListVersions(ctx context.Context, tenant string, contentType
 	return versions, nil
 }
 
+// FindVersionByETag returns the version of content in a state whose ETag
+// matches, e.g. the version an editor started from. Any state's versions are
+// searched, since the bucket keeps versions of drafts as well as live content.
+func (s *S3Storage) FindVersionByETag(ctx context.Context, tenant string, contentType string, id string, ext string, state State, etag string) (*ContentItem, error) {
+	if state == "" {
+		state = StateLive
+	}
+	key := s.contentKey(tenant, contentType, id, ext, state)
+
+	result, err := s.s3Client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+
+	want := strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+	for _, v := range result.Versions {
+		if aws.ToString(v.Key) == key && strings.Trim(aws.ToString(v.ETag), `"`) == want {
+			return s.getByKey(ctx, key, aws.ToString(v.VersionId))
+		}
+	}
+	return nil, fmt.Errorf("no version of %s with ETag %s", key, etag)
+}
+
 // RestoreVersion restores a specific version of live content by copying it as the latest version
 func (s *S3Storage) RestoreVersion(ctx context.Context, tenant string, contentType string, id string, ext string, versionID string) (*ContentItem, error) {
 	key := s.contentKey(tenant, contentType, id, ext, StateLive)
@@ -800,8 +930,8 @@ func (s *S3Storage) Transition(ctx context.Context, tenant string, contentType s
 		return nil, fmt.Errorf("failed to get content from %s state: %w", fromState, err)
 	}
 
-	// Put it in the target state
-	targetItem, err := s.Put(ctx, tenant, contentType, id, ext, sourceItem.Content, sourceItem.ContentType, toState)
+	// Put it in the target state, with its metadata
+	targetItem, err := s.PutStream(ctx, tenant, contentType, id, ext, bytes.NewReader(sourceItem.Content), int64(len(sourceItem.Content)), sourceItem.ContentType, toState, sourceItem.Metadata)
 	if err != nil {
 		return nil, fmt.Errorf("failed to put content to %s state: %w", toState, err)
 	}
@@ -822,6 +952,67 @@ func (s *S3Storage) Transition(ctx context.Context, tenant string, contentType s
 	return targetItem, nil
 }
 
+// CopyContent copies content to another state server-side, leaving the source
+// in place. The source's MIME type and metadata are kept; metadata adds to
+// (or replaces keys of) the source's.
+func (s *S3Storage) CopyContent(ctx context.Context, tenant string, contentType string, id string, ext string, fromState State, toState State, metadata map[string]string) (*ContentItem, error) {
+	if fromState == toState {
+		return nil, fmt.Errorf("source and target states are the same")
+	}
+	source := s.contentKey(tenant, contentType, id, ext, fromState)
+	key := s.contentKey(tenant, contentType, id, ext, toState)
+
+	head, err := s.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(source),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get content from %s state: %w", fromState, err)
+	}
+
+	merged := make(map[string]string, len(head.Metadata)+len(metadata))
+	for k, v := range head.Metadata {
+		merged[k] = v
+	}
+	for k, v := range metadata {
+		merged[k] = v
+	}
+
+	// The version read is the one copied, even if the source changes meanwhile
+	copySource := fmt.Sprintf("%s/%s", s.bucket, source)
+	if head.VersionId != nil {
+		copySource += "?versionId=" + aws.ToString(head.VersionId)
+	}
+	result, err := s.s3Client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String(s.bucket),
+		CopySource:        aws.String(copySource),
+		Key:               aws.String(key),
+		ContentType:       head.ContentType,
+		Metadata:          merged,
+		MetadataDirective: types.MetadataDirectiveReplace,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy content to %s state: %w", toState, err)
+	}
+
+	if retention := s.retention(ctx); toState == StateLive && !retention.Unlimited() {
+		go s.pruneVersions(context.Background(), key, retention)
+	}
+
+	item := &ContentItem{
+		Key:         key,
+		ContentType: aws.ToString(head.ContentType),
+		VersionID:   aws.ToString(result.VersionId),
+		Size:        aws.ToInt64(head.ContentLength),
+		Metadata:    merged,
+	}
+	if result.CopyObjectResult != nil {
+		item.ETag = aws.ToString(result.CopyObjectResult.ETag)
+		item.LastModified = aws.ToTime(result.CopyObjectResult.LastModified)
+	}
+	return item, nil
+}
+
 // =============================================================================
 // History Operations
 // =============================================================================
@@ -1271,18 +1462,125 @@ func (s *S3Storage) HasUnresolvedComments(ctx context.Context, tenant string, co
 	return false, nil
 }
 
+// =============================================================================
+// Attachment Operations
+// =============================================================================
+
+// PutAttachment stores a named file under a content item
+func (s *S3Storage) PutAttachment(ctx context.Context, tenant string, contentType string, contentID string, name string, body io.Reader, contentLength int64, mimeType string) (*Attachment, error) {
+	key := s.attachmentKey(tenant, contentType, contentID, name)
+
+	result, err := s.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          body,
+		ContentLength: aws.Int64(contentLength),
+		ContentType:   aws.String(mimeType),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to put attachment: %w", err)
+	}
+
+	return &Attachment{
+		Name:         name,
+		ContentType:  mimeType,
+		Size:         contentLength,
+		ETag:         aws.ToString(result.ETag),
+		LastModified: time.Now().UTC(),
+	}, nil
+}
+
+// GetAttachment retrieves an attachment as a stream
+func (s *S3Storage) GetAttachment(ctx context.Context, tenant string, contentType string, contentID string, name string) (*ContentStream, error) {
+	return s.getStreamByKey(ctx, s.attachmentKey(tenant, contentType, contentID, name), "")
+}
+
+// ListAttachments returns the attachments of a content item (without their
+// content types, which listing doesn't return)
+func (s *S3Storage) ListAttachments(ctx context.Context, tenant string, contentType string, contentID string) ([]*Attachment, error) {
+	prefix := s.attachmentPrefix(tenant, contentType, contentID)
+
+	// The delimiter keeps attachments of nested items (parent/child) out
+	input := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(s.bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}
+
+	attachments := []*Attachment{}
+	paginator := s3.NewListObjectsV2Paginator(s.s3Client, input)
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list attachments: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			attachment := &Attachment{
+				Name: strings.TrimPrefix(aws.ToString(obj.Key), prefix),
+				ETag: aws.ToString(obj.ETag),
+			}
+			if obj.Size != nil {
+				attachment.Size = *obj.Size
+			}
+			if obj.LastModified != nil {
+				attachment.LastModified = *obj.LastModified
+			}
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	return attachments, nil
+}
+
+// DeleteAttachment removes an attachment
+func (s *S3Storage) DeleteAttachment(ctx context.Context, tenant string, contentType string, contentID string, name string) error {
+	_, err := s.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.attachmentKey(tenant, contentType, contentID, name)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	return nil
+}
+
+// DeleteAllAttachments removes every attachment of a content item
+func (s *S3Storage) DeleteAllAttachments(ctx context.Context, tenant string, contentType string, contentID string) error {
+	attachments, err := s.ListAttachments(ctx, tenant, contentType, contentID)
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range attachments {
+		if err := s.DeleteAttachment(ctx, tenant, contentType, contentID, attachment.Name); err != nil {
+			// Log but continue deleting others
+			log.Error("Failed to delete attachment %s: %v", attachment.Name, err)
+		}
+	}
+
+	return nil
+}
+
 // =============================================================================
 // Webhook Operations
 // =============================================================================
 
 // webhookKey constructs the S3 key for a webhook
 func (s *S3Storage) webhookKey(tenant string, webhookID string) string {
-	return path.Join(s.root, "tenants", tenant, "webhooks", webhookID+".json")
+	return s.tenantKey(tenant, "webhooks", webhookID+".json")
 }
 
 // webhookPrefix returns the prefix for listing webhooks
 func (s *S3Storage) webhookPrefix(tenant string) string {
-	return path.Join(s.root, "tenants", tenant, "webhooks") + "/"
+	return s.tenantKey(tenant, "webhooks") + "/"
+}
+
+// webhookAAD binds an encrypted webhook to its tenant and ID (not its key, so
+// it survives copies between roots)
+func webhookAAD(tenant, webhookID string) []byte {
+	return []byte("webhook:" + tenant + "/" + webhookID)
 }
 
 // ListWebhooks lists all webhooks for a tenant
@@ -1333,8 +1631,17 @@ func (s *S3Storage) GetWebhook(ctx context.Context, tenant string, webhookID str
 	}
 	defer result.Body.Close()
 
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook: %w", err)
+	}
+	data, err = s.keyring.Open(ctx, data, webhookAAD(tenant, webhookID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook: %w", err)
+	}
+
 	var webhook Webhook
-	if err := json.NewDecoder(result.Body).Decode(&webhook); err != nil {
+	if err := json.Unmarshal(data, &webhook); err != nil {
 		return nil, fmt.Errorf("failed to decode webhook: %w", err)
 	}
 
@@ -1350,6 +1657,11 @@ func (s *S3Storage) PutWebhook(ctx context.Context, tenant string, webhook *Webh
 	if err != nil {
 		return fmt.Errorf("failed to marshal webhook: %w", err)
 	}
+	if s.keyring != nil {
+		if data, err = s.keyring.Seal(ctx, data, webhookAAD(tenant, webhook.ID)); err != nil {
+			return fmt.Errorf("failed to encrypt webhook: %w", err)
+		}
+	}
 
 	_, err = s.s3Client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(s.bucket),
@@ -1379,13 +1691,112 @@ func (s *S3Storage) DeleteWebhook(ctx context.Context, tenant string, webhookID
 	return nil
 }
 
+// =============================================================================
+// API Key Operations
+// =============================================================================
+
+// apiKeyKey constructs the S3 key for an API key
+func (s *S3Storage) apiKeyKey(tenant string, keyID string) string {
+	return s.tenantKey(tenant, "keys", keyID+".json")
+}
+
+// apiKeyPrefix returns the prefix for listing API keys
+func (s *S3Storage) apiKeyPrefix(tenant string) string {
+	return s.tenantKey(tenant, "keys") + "/"
+}
+
+// ListAPIKeys lists all API keys for a tenant
+func (s *S3Storage) ListAPIKeys(ctx context.Context, tenant string) ([]*APIKey, error) {
+	paginator := s3.NewListObjectsV2Paginator(s.s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.apiKeyPrefix(tenant)),
+	})
+
+	var keys []*APIKey
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list API keys: %w", err)
+		}
+		for _, obj := range page.Contents {
+			filename := path.Base(aws.ToString(obj.Key))
+			if !strings.HasSuffix(filename, ".json") {
+				continue
+			}
+			keyID := strings.TrimSuffix(filename, ".json")
+
+			key, err := s.GetAPIKey(ctx, tenant, keyID)
+			if err != nil {
+				log.Error("Failed to get API key %s: %v", keyID, err)
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// GetAPIKey retrieves an API key by ID
+func (s *S3Storage) GetAPIKey(ctx context.Context, tenant string, keyID string) (*APIKey, error) {
+	result, err := s.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.apiKeyKey(tenant, keyID)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("API key not found: %w", err)
+	}
+	defer result.Body.Close()
+
+	var key APIKey
+	if err := json.NewDecoder(result.Body).Decode(&key); err != nil {
+		return nil, fmt.Errorf("failed to decode API key: %w", err)
+	}
+
+	key.ID = keyID
+	return &key, nil
+}
+
+// PutAPIKey creates or replaces an API key
+func (s *S3Storage) PutAPIKey(ctx context.Context, tenant string, key *APIKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal API key: %w", err)
+	}
+
+	_, err = s.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.apiKeyKey(tenant, key.ID)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save API key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteAPIKey removes an API key
+func (s *S3Storage) DeleteAPIKey(ctx context.Context, tenant string, keyID string) error {
+	_, err := s.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.apiKeyKey(tenant, keyID)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete API key: %w", err)
+	}
+
+	return nil
+}
+
 // =============================================================================
 // Tenant Operations
 // =============================================================================
 
 // tenantsPrefix returns the prefix for listing tenants
 func (s *S3Storage) tenantsPrefix() string {
-	return path.Join(s.root, "tenants") + "/"
+	return s.objectKey("tenants") + "/"
 }
 
 // ListTenants returns all tenant names by listing common prefixes under /{root}/tenants/
@@ -1424,7 +1835,7 @@ func (s *S3Storage) ListTenants(ctx context.Context) ([]string, error) {
 
 // ListContentTypes returns all content type names for a tenant by listing prefixes under /{root}/tenants/{tenant}/content/
 func (s *S3Storage) ListContentTypes(ctx context.Context, tenant string) ([]string, error) {
-	prefix := path.Join(s.root, "tenants", tenant, "content") + "/"
+	prefix := s.tenantKey(tenant, "content") + "/"
 
 	input := &s3.ListObjectsV2Input{
 		Bucket:    aws.String(s.bucket),
@@ -1461,12 +1872,12 @@ func (s *S3Storage) ListContentTypes(ctx context.Context, tenant string) ([]stri
 
 // sessionKey constructs the S3 key for a session
 func (s *S3Storage) sessionKey(token string) string {
-	return path.Join(s.root, "sessions", token+".json")
+	return s.objectKey("sessions", token+".json")
 }
 
 // sessionsPrefix returns the prefix for listing sessions
 func (s *S3Storage) sessionsPrefix() string {
-	return path.Join(s.root, "sessions") + "/"
+	return s.objectKey("sessions") + "/"
 }
 
 // PutSession stores a session in S3
@@ -1589,7 +2000,7 @@ func (s *S3Storage) DeleteExpiredSessions(ctx context.Context) (int, error) {
 
 // CreateTenant creates a new tenant by putting a .keep marker object
 func (s *S3Storage) CreateTenant(ctx context.Context, tenant string) error {
-	key := path.Join(s.root, "tenants", tenant, ".keep")
+	key := s.tenantKey(tenant, ".keep")
 
 	_, err := s.s3Client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(s.bucket),
@@ -1606,7 +2017,7 @@ func (s *S3Storage) CreateTenant(ctx context.Context, tenant string) error {
 
 // CreateContentType creates a new content type directory for a tenant by putting a .keep marker object
 func (s *S3Storage) CreateContentType(ctx context.Context, tenant, contentType string) error {
-	key := path.Join(s.root, "tenants", tenant, "content", contentType, ".keep")
+	key := s.tenantKey(tenant, "content", contentType, ".keep")
 
 	_, err := s.s3Client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(s.bucket),
@@ -1782,3 +2193,387 @@ func (s *S3Storage) DeleteMetadataKeys(ctx context.Context, tenant string, conte
 	// Set updated metadata
 	return s.SetMetadata(ctx, tenant, contentType, id, ext, state, existing)
 }
+
+// =============================================================================
+// Document Operations
+// =============================================================================
+
+// documentPrefix returns the prefix for a document collection
+// Tenant format: /{root}/tenants/{tenant}/{collection}/
+// Global format: /{root}/{collection}/
+func (s *S3Storage) documentPrefix(tenant, collection string) string {
+	if tenant == "" {
+		return s.objectKey(collection) + "/"
+	}
+	return s.tenantKey(tenant, collection) + "/"
+}
+
+// documentKey constructs the S3 key for a document
+func (s *S3Storage) documentKey(tenant, collection, id string) string {
+	return s.documentPrefix(tenant, collection) + id + ".json"
+}
+
+// GetDocument retrieves a document's raw JSON
+func (s *S3Storage) GetDocument(ctx context.Context, tenant, collection, id string) ([]byte, error) {
+	item, err := s.getByKey(ctx, s.documentKey(tenant, collection, id), "")
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+	return item.Content, nil
+}
+
+// PutDocument creates or replaces a document
+func (s *S3Storage) PutDocument(ctx context.Context, tenant, collection, id string, data []byte) error {
+	_, err := s.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.documentKey(tenant, collection, id)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put document: %w", err)
+	}
+	return nil
+}
+
+// DeleteDocument removes a document
+func (s *S3Storage) DeleteDocument(ctx context.Context, tenant, collection, id string) error {
+	_, err := s.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.documentKey(tenant, collection, id)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	return nil
+}
+
+// ListDocuments returns the IDs of all documents in a collection
+func (s *S3Storage) ListDocuments(ctx context.Context, tenant, collection string) ([]string, error) {
+	prefix := s.documentPrefix(tenant, collection)
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	var ids []string
+	paginator := s3.NewListObjectsV2Paginator(s.s3Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list documents: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			name := strings.TrimPrefix(aws.ToString(obj.Key), prefix)
+			if !strings.HasSuffix(name, ".json") {
+				continue
+			}
+			ids = append(ids, strings.TrimSuffix(name, ".json"))
+		}
+	}
+
+	return ids, nil
+}
+
+// =============================================================================
+// Consistency Operations
+// =============================================================================
+
+// listParentIDs returns the distinct parent paths of every object under prefix,
+// e.g. the content IDs of {prefix}{id}/{record}.json
+func (s *S3Storage) listParentIDs(ctx context.Context, prefix string) ([]string, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	seen := make(map[string]bool)
+	var ids []string
+	paginator := s3.NewListObjectsV2Paginator(s.s3Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			rel := strings.TrimPrefix(aws.ToString(obj.Key), prefix)
+			idx := strings.LastIndex(rel, "/")
+			if idx <= 0 {
+				continue
+			}
+			if id := rel[:idx]; !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// ListCommentedIDs returns the IDs of all content that has comments in a state
+func (s *S3Storage) ListCommentedIDs(ctx context.Context, tenant, contentType string, state State) ([]string, error) {
+	prefix := s.tenantKey(tenant, "content", contentType, fmt.Sprintf("_%s", state), "_comments") + "/"
+	return s.listParentIDs(ctx, prefix)
+}
+
+// ListHistoryIDs returns the IDs of all content that has history records
+func (s *S3Storage) ListHistoryIDs(ctx context.Context, tenant, contentType string) ([]string, error) {
+	prefix := s.tenantKey(tenant, "content", contentType, "_history") + "/"
+	return s.listParentIDs(ctx, prefix)
+}
+
+// DeleteHistoryRecord removes a single history record
+func (s *S3Storage) DeleteHistoryRecord(ctx context.Context, tenant, contentType, id, version string) error {
+	_, err := s.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.historyKey(tenant, contentType, id, version)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete history record: %w", err)
+	}
+	return nil
+}
+
+// ContentDeletedAt reports whether live content has been deleted (under any
+// extension) and when. Content with no remaining versions is reported as
+// deleted with a zero time.
+func (s *S3Storage) ContentDeletedAt(ctx context.Context, tenant, contentType, id string) (time.Time, bool, error) {
+	// "{id}." matches the item under any extension
+	prefix := s.contentKey(tenant, contentType, id, "", StateLive)
+	matches := func(key *string) bool {
+		rest := strings.TrimPrefix(aws.ToString(key), prefix)
+		return rest != aws.ToString(key) && !strings.ContainsAny(rest, "./")
+	}
+
+	var deletedAt time.Time
+	paginator := s3.NewListObjectVersionsPaginator(s.s3Client, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("failed to list versions: %w", err)
+		}
+		for _, v := range page.Versions {
+			if matches(v.Key) && aws.ToBool(v.IsLatest) {
+				return time.Time{}, false, nil
+			}
+		}
+		for _, marker := range page.DeleteMarkers {
+			if matches(marker.Key) && aws.ToBool(marker.IsLatest) && aws.ToTime(marker.LastModified).After(deletedAt) {
+				deletedAt = aws.ToTime(marker.LastModified)
+			}
+		}
+	}
+
+	return deletedAt, true, nil
+}
+
+// =============================================================================
+// Lease Operations
+// =============================================================================
+
+// leaseKey constructs the S3 key for a lease
+// /{root}/leases/{key}.json
+func (s *S3Storage) leaseKey(key string) string {
+	return s.objectKey("leases", key+".json")
+}
+
+// withHeader sets a request header on a single S3 call. Used for the
+// conditional write headers (If-Match, If-None-Match) that this SDK version
+// does not model.
+func withHeader(name, value string) func(*s3.Options) {
+	return func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Build.Add(middleware.BuildMiddlewareFunc("Velocity"+name, func(ctx context.Context, in middleware.BuildInput, next middleware.BuildHandler) (middleware.BuildOutput, middleware.Metadata, error) {
+				if req, ok := in.Request.(*smithyhttp.Request); ok {
+					req.Header.Set(name, value)
+				}
+				return next.HandleBuild(ctx, in)
+			}), middleware.After)
+		})
+	}
+}
+
+// httpStatus returns the HTTP status code of a failed S3 call, or 0
+func httpStatus(err error) int {
+	var re interface{ HTTPStatusCode() int }
+	if errors.As(err, &re) {
+		return re.HTTPStatusCode()
+	}
+	return 0
+}
+
+// GetLease returns the current lease on a key, or nil if it has never been leased
+func (s *S3Storage) GetLease(ctx context.Context, key string) (*Lease, error) {
+	result, err := s.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.leaseKey(key)),
+	})
+	if err != nil {
+		if httpStatus(err) == 404 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get lease: %w", err)
+	}
+	defer result.Body.Close()
+
+	var lease Lease
+	if err := json.NewDecoder(result.Body).Decode(&lease); err != nil {
+		return nil, fmt.Errorf("failed to parse lease: %w", err)
+	}
+	lease.version = aws.ToString(result.ETag)
+	return &lease, nil
+}
+
+// AcquireLease claims a key for ttl if it is free or its lease has expired.
+// The write is conditional on the lease not having changed since it was read,
+// so only one of several concurrent callers succeeds.
+func (s *S3Storage) AcquireLease(ctx context.Context, key string, ttl time.Duration) (*Lease, error) {
+	current, err := s.GetLease(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && !current.Expired() {
+		return current, ErrLeaseHeld
+	}
+
+	now := time.Now().UTC()
+	lease := &Lease{Key: key, Holder: NodeID, Token: randomToken(16), AcquiredAt: now, ExpiresAt: now.Add(ttl)}
+
+	condition := withHeader("If-None-Match", "*")
+	if current != nil {
+		condition = withHeader("If-Match", current.version)
+	}
+	if err := s.putLease(ctx, lease, condition); err != nil {
+		if err == errPreconditionFailed {
+			return nil, ErrLeaseHeld
+		}
+		return nil, err
+	}
+	return lease, nil
+}
+
+// RenewLease extends a lease this caller holds
+func (s *S3Storage) RenewLease(ctx context.Context, lease *Lease, ttl time.Duration) (*Lease, error) {
+	if lease.version == "" {
+		return nil, ErrLeaseLost
+	}
+
+	renewed := *lease
+	renewed.ExpiresAt = time.Now().UTC().Add(ttl)
+	if err := s.putLease(ctx, &renewed, withHeader("If-Match", lease.version)); err != nil {
+		if err == errPreconditionFailed {
+			return nil, ErrLeaseLost
+		}
+		return nil, err
+	}
+	return &renewed, nil
+}
+
+// ReleaseLease gives up a lease this caller holds by marking it expired
+func (s *S3Storage) ReleaseLease(ctx context.Context, lease *Lease) error {
+	if lease.version == "" {
+		return ErrLeaseLost
+	}
+
+	released := *lease
+	released.ExpiresAt = time.Now().UTC()
+	if err := s.putLease(ctx, &released, withHeader("If-Match", lease.version)); err != nil {
+		if err == errPreconditionFailed {
+			return ErrLeaseLost
+		}
+		return err
+	}
+	return nil
+}
+
+// errPreconditionFailed reports that a conditional lease write lost a race
+var errPreconditionFailed = errors.New("precondition failed")
+
+// putLease writes a lease with a conditional header and records its new version
+func (s *S3Storage) putLease(ctx context.Context, lease *Lease, condition func(*s3.Options)) error {
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lease: %w", err)
+	}
+
+	result, err := s.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.leaseKey(lease.Key)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}, condition)
+	if err != nil {
+		// 412: the lease changed since it was read; 409: a concurrent conditional write won
+		if status := httpStatus(err); status == 412 || status == 409 {
+			return errPreconditionFailed
+		}
+		return fmt.Errorf("failed to put lease: %w", err)
+	}
+
+	lease.version = aws.ToString(result.ETag)
+	return nil
+}
+
+// =============================================================================
+// Backup Operations
+// =============================================================================
+
+// backupObjectKey returns the key of a backed-up object
+// Format: /{root}/tenants/{tenant}/backup-objects/{objectID}
+func (s *S3Storage) backupObjectKey(tenant, objectID string) string {
+	return s.tenantKey(tenant, "backup-objects", objectID)
+}
+
+// BackupObject copies a content object into the tenant's backup store (server-side)
+func (s *S3Storage) BackupObject(ctx context.Context, tenant, contentType, id, ext string, state State, objectID string) error {
+	if state == "" {
+		state = StateLive
+	}
+	source := s.contentKey(tenant, contentType, id, ext, state)
+
+	_, err := s.s3Client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String(s.bucket),
+		CopySource:        aws.String(fmt.Sprintf("%s/%s", s.bucket, source)),
+		Key:               aws.String(s.backupObjectKey(tenant, objectID)),
+		MetadataDirective: types.MetadataDirectiveCopy,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to back up object: %w", err)
+	}
+	return nil
+}
+
+// RestoreObject copies a backed-up object over a content object, creating a new version
+func (s *S3Storage) RestoreObject(ctx context.Context, tenant, contentType, id, ext string, state State, objectID string) (*ContentItem, error) {
+	if state == "" {
+		state = StateLive
+	}
+	key := s.contentKey(tenant, contentType, id, ext, state)
+
+	result, err := s.s3Client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String(s.bucket),
+		CopySource:        aws.String(fmt.Sprintf("%s/%s", s.bucket, s.backupObjectKey(tenant, objectID))),
+		Key:               aws.String(key),
+		MetadataDirective: types.MetadataDirectiveCopy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore object: %w", err)
+	}
+
+	if retention := s.retention(ctx); state == StateLive && !retention.Unlimited() {
+		go s.pruneVersions(context.Background(), key, retention)
+	}
+
+	item := &ContentItem{Key: key, VersionID: aws.ToString(result.VersionId)}
+	if result.CopyObjectResult != nil {
+		item.ETag = aws.ToString(result.CopyObjectResult.ETag)
+	}
+	return item, nil
+}
@@ -0,0 +1,638 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"velocity/internal/crypto"
+	"velocity/internal/storage"
+	"velocity/internal/storage/storagetest"
+)
+
+func TestS3Storage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, _ := storagetest.NewS3Storage(10)
+		return s
+	})
+}
+
+func TestCachedStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, _ := storagetest.NewS3Storage(10)
+		return storage.NewCachedStorage(s, storage.DefaultCacheConfig())
+	})
+}
+
+func TestCachedStorageEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	s, _ := storagetest.NewS3Storage(10)
+	// Room for two small items
+	cached := storage.NewCachedStorage(s, storage.CacheConfig{MaxMemory: 400})
+	defer cached.Stop()
+
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := s.Put(ctx, "acme", "pages", id, "json", []byte(`"v1"`), "application/json", storage.StateLive); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"a", "b", "a", "c"} {
+		if _, err := cached.Get(ctx, "acme", "pages", id, "json", storage.StateLive); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Change the items behind the cache: a (used recently) is still cached,
+	// b (least recently used) was evicted to make room for c
+	for _, id := range []string{"a", "b"} {
+		if _, err := s.Put(ctx, "acme", "pages", id, "json", []byte(`"v2"`), "application/json", storage.StateLive); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tc := range []struct{ id, want string }{{"a", `"v1"`}, {"b", `"v2"`}} {
+		item, err := cached.Get(ctx, "acme", "pages", tc.id, "json", storage.StateLive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(item.Content) != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.id, item.Content, tc.want)
+		}
+	}
+}
+
+func TestCachedStorageMetadata(t *testing.T) {
+	ctx := context.Background()
+	s, _ := storagetest.NewS3Storage(10)
+	cached := storage.NewCachedStorage(s, storage.DefaultCacheConfig())
+	defer cached.Stop()
+
+	if _, err := s.PutStream(ctx, "acme", "pages", "home", "json", strings.NewReader(`{}`), 2, "application/json", storage.StateLive, map[string]string{"tag": "one"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cached.GetMetadata(ctx, "acme", "pages", "home", "json", storage.StateLive); err != nil {
+		t.Fatal(err)
+	}
+
+	// Served from the cache until it changes through it
+	if err := s.SetMetadata(ctx, "acme", "pages", "home", "json", storage.StateLive, map[string]string{"tag": "two"}); err != nil {
+		t.Fatal(err)
+	}
+	if metadata, _ := cached.GetMetadata(ctx, "acme", "pages", "home", "json", storage.StateLive); metadata["tag"] != "one" {
+		t.Errorf("got tag %q from the cache, want one", metadata["tag"])
+	}
+	if err := cached.UpdateMetadata(ctx, "acme", "pages", "home", "json", storage.StateLive, map[string]string{"tag": "three"}); err != nil {
+		t.Fatal(err)
+	}
+	if metadata, _ := cached.GetMetadata(ctx, "acme", "pages", "home", "json", storage.StateLive); metadata["tag"] != "three" {
+		t.Errorf("got tag %q after an update, want three", metadata["tag"])
+	}
+}
+
+// TestS3StorageMinIO runs the suite against a real S3-compatible server, e.g.
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	VELOCITY_TEST_S3_ENDPOINT=http://localhost:9000 VELOCITY_TEST_S3_BUCKET=velocity \
+//	VELOCITY_TEST_S3_ACCESS_KEY=minioadmin VELOCITY_TEST_S3_SECRET_KEY=minioadmin go test ./internal/storage
+//
+// The bucket must exist and have versioning enabled.
+func TestS3StorageMinIO(t *testing.T) {
+	endpoint := os.Getenv("VELOCITY_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("VELOCITY_TEST_S3_ENDPOINT not set")
+	}
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := storage.NewS3Storage(storage.S3Config{
+			Endpoint:        endpoint,
+			Region:          "us-east-1",
+			Bucket:          os.Getenv("VELOCITY_TEST_S3_BUCKET"),
+			AccessKeyID:     os.Getenv("VELOCITY_TEST_S3_ACCESS_KEY"),
+			SecretAccessKey: os.Getenv("VELOCITY_TEST_S3_SECRET_KEY"),
+			Root:            fmt.Sprintf("test-%d", time.Now().UnixNano()),
+		})
+		if err != nil {
+			t.Fatalf("NewS3Storage: %v", err)
+		}
+		return s
+	})
+}
+
+func TestS3StoragePrunesVersions(t *testing.T) {
+	s, fake := storagetest.NewS3Storage(3)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		body := fmt.Sprintf(`{"v":%d}`, i)
+		if _, err := s.PutStream(ctx, "acme", "pages", "home", "json", strings.NewReader(body), int64(len(body)), "application/json", storage.StateLive, nil); err != nil {
+			t.Fatalf("PutStream: %v", err)
+		}
+	}
+
+	var key string
+	for _, k := range fake.Keys() {
+		if strings.HasSuffix(k, "/pages/home.json") {
+			key = k
+		}
+	}
+	if key == "" {
+		t.Fatalf("no object written for pages/home: %v", fake.Keys())
+	}
+
+	// Pruning runs in the background after each write
+	deadline := time.Now().Add(time.Second)
+	for fake.VersionCount(key) > 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := fake.VersionCount(key); n != 3 {
+		t.Errorf("%s has %d versions, want 3", key, n)
+	}
+}
+
+func TestVersionRetentionExpired(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	var versions []*storage.ContentVersion
+	for i, age := range []int{200, 0, 30, 100, 10} {
+		versions = append(versions, &storage.ContentVersion{
+			VersionID:    fmt.Sprintf("v%d", age),
+			LastModified: now.Add(-time.Duration(age) * day),
+			IsLatest:     i == 1,
+		})
+	}
+
+	ids := func(vs []*storage.ContentVersion) string {
+		var out []string
+		for _, v := range vs {
+			out = append(out, v.VersionID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		name      string
+		retention storage.VersionRetention
+		want      string
+	}{
+		{"count only", storage.VersionRetention{MaxVersions: 2}, "v30,v100,v200"},
+		{"count or age", storage.VersionRetention{MaxVersions: 2, MaxAge: 90 * day}, "v100,v200"},
+		{"age disabled", storage.VersionRetention{MaxVersions: 2, MaxAge: -1}, "v30,v100,v200"},
+		{"under count", storage.VersionRetention{MaxVersions: 5}, ""},
+		{"unlimited", storage.VersionRetention{MaxVersions: -1}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(tt.retention.Expired(versions, now)); got != tt.want {
+				t.Errorf("Expired() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestS3StorageKeepsRecentVersions(t *testing.T) {
+	s, _ := storagetest.NewS3Storage(2)
+	ctx := storage.WithVersionRetention(context.Background(), storage.VersionRetention{MaxAge: time.Hour})
+
+	for i := 0; i < 4; i++ {
+		body := fmt.Sprintf(`{"v":%d}`, i)
+		if _, err := s.PutStream(ctx, "acme", "pages", "home", "json", strings.NewReader(body), int64(len(body)), "application/json", storage.StateLive, nil); err != nil {
+			t.Fatalf("PutStream: %v", err)
+		}
+	}
+
+	// Every version is younger than the max age, so none is pruned
+	pruned, err := s.PruneVersions(ctx, "acme", "pages", "home", "json", false)
+	if err != nil {
+		t.Fatalf("PruneVersions: %v", err)
+	}
+	if len(pruned) != 0 {
+		t.Errorf("pruned %d versions within the max age, want 0", len(pruned))
+	}
+
+	// By count alone, the two oldest go
+	byCount := storage.WithVersionRetention(context.Background(), storage.VersionRetention{MaxAge: -1})
+	pruned, err = s.PruneVersions(byCount, "acme", "pages", "home", "json", true)
+	if err != nil {
+		t.Fatalf("PruneVersions (dry run): %v", err)
+	}
+	if len(pruned) != 2 {
+		t.Errorf("dry run would prune %d versions, want 2", len(pruned))
+	}
+	if _, err := s.PruneVersions(byCount, "acme", "pages", "home", "json", false); err != nil {
+		t.Fatalf("PruneVersions: %v", err)
+	}
+	versions, err := s.ListVersions(ctx, "acme", "pages", "home", "json")
+	if err != nil {
+		t.Fatalf("ListVersions: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Errorf("%d versions left, want 2", len(versions))
+	}
+}
+
+func TestS3StorageContentDeletedAt(t *testing.T) {
+	s, _ := storagetest.NewS3Storage(10)
+	ctx := context.Background()
+
+	if _, err := s.Put(ctx, "acme", "pages", "home", "json", []byte(`{}`), "application/json", storage.StateLive); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, deleted, err := s.ContentDeletedAt(ctx, "acme", "pages", "home"); err != nil || deleted {
+		t.Fatalf("ContentDeletedAt before delete = %v, %v; want false", deleted, err)
+	}
+
+	if err := s.Delete(ctx, "acme", "pages", "home", "json", storage.StateLive); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	deletedAt, deleted, err := s.ContentDeletedAt(ctx, "acme", "pages", "home")
+	if err != nil || !deleted {
+		t.Fatalf("ContentDeletedAt after delete = %v, %v; want true", deleted, err)
+	}
+	if time.Since(deletedAt) > time.Minute {
+		t.Errorf("ContentDeletedAt = %v, want about now", deletedAt)
+	}
+}
+
+func TestS3StorageEncryptsWebhooks(t *testing.T) {
+	ctx := context.Background()
+	key, err := crypto.NewPassphraseKey("a passphrase for the test keyring")
+	if err != nil {
+		t.Fatalf("NewPassphraseKey: %v", err)
+	}
+	fake := storagetest.NewFakeS3()
+	plain, _ := storage.NewS3Storage(storage.S3Config{Bucket: "velocity", Root: "test", Client: fake})
+	sealed, _ := storage.NewS3Storage(storage.S3Config{Bucket: "velocity", Root: "test", Client: fake, Keyring: crypto.NewKeyring(key)})
+
+	// Written before encryption was enabled
+	if err := plain.PutWebhook(ctx, "acme", &storage.Webhook{ID: "old", URL: "https://example.com/old"}); err != nil {
+		t.Fatalf("PutWebhook: %v", err)
+	}
+	if err := sealed.PutWebhook(ctx, "acme", &storage.Webhook{ID: "slack", URL: "https://hooks.example.com/secret"}); err != nil {
+		t.Fatalf("PutWebhook: %v", err)
+	}
+
+	for _, k := range fake.Keys() {
+		if !strings.HasSuffix(k, "/webhooks/slack.json") {
+			continue
+		}
+		result, err := fake.GetObject(ctx, &s3.GetObjectInput{Key: aws.String(k)})
+		if err != nil {
+			t.Fatalf("GetObject: %v", err)
+		}
+		data, _ := io.ReadAll(result.Body)
+		if !crypto.IsSealed(data) || strings.Contains(string(data), "secret") {
+			t.Errorf("webhook stored in plaintext: %s", data)
+		}
+	}
+
+	webhooks, err := sealed.ListWebhooks(ctx, "acme")
+	if err != nil {
+		t.Fatalf("ListWebhooks: %v", err)
+	}
+	urls := make(map[string]string)
+	for _, webhook := range webhooks {
+		urls[webhook.ID] = webhook.URL
+	}
+	if urls["slack"] != "https://hooks.example.com/secret" || urls["old"] != "https://example.com/old" {
+		t.Errorf("ListWebhooks = %v", urls)
+	}
+
+	if _, err := plain.GetWebhook(ctx, "acme", "slack"); err == nil {
+		t.Error("read an encrypted webhook without the key")
+	}
+}
+
+func TestS3StorageAPIKeys(t *testing.T) {
+	s, _ := storagetest.NewS3Storage(10)
+	ctx := context.Background()
+
+	for _, key := range []*storage.APIKey{
+		{ID: "ci", Name: "CI", Prefix: "vk_0123abcd", Hash: "aa"},
+		{ID: "deploy", Prefix: "vk_4567ef01", Hash: "bb"},
+	} {
+		if err := s.PutAPIKey(ctx, "acme", key); err != nil {
+			t.Fatalf("PutAPIKey: %v", err)
+		}
+	}
+
+	keys, err := s.ListAPIKeys(ctx, "acme")
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("ListAPIKeys = %d keys, %v; want 2", len(keys), err)
+	}
+	if others, _ := s.ListAPIKeys(ctx, "other"); len(others) != 0 {
+		t.Errorf("ListAPIKeys(other) = %d keys, want 0", len(others))
+	}
+
+	key, err := s.GetAPIKey(ctx, "acme", "ci")
+	if err != nil || key.Hash != "aa" || key.Name != "CI" {
+		t.Fatalf("GetAPIKey = %+v, %v", key, err)
+	}
+
+	if err := s.DeleteAPIKey(ctx, "acme", "ci"); err != nil {
+		t.Fatalf("DeleteAPIKey: %v", err)
+	}
+	if _, err := s.GetAPIKey(ctx, "acme", "ci"); !storage.IsNotFound(err) {
+		t.Errorf("GetAPIKey after delete = %v, want not found", err)
+	}
+}
+
+func newTestKeyring(t *testing.T) *crypto.Keyring {
+	key, err := crypto.NewPassphraseKey("a passphrase for the test keyring")
+	if err != nil {
+		t.Fatalf("NewPassphraseKey: %v", err)
+	}
+	return crypto.NewKeyring(key)
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	keyring := newTestKeyring(t)
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, _ := storagetest.NewS3Storage(10)
+		return storage.NewEncryptedStorage(s, keyring, nil)
+	})
+}
+
+func TestEncryptedStorageEncryptsContent(t *testing.T) {
+	ctx := context.Background()
+	fake := storagetest.NewFakeS3()
+	plain, _ := storage.NewS3Storage(storage.S3Config{Bucket: "velocity", Root: "test", Client: fake, MaxVersions: 10})
+	encrypted := storage.NewEncryptedStorage(plain, newTestKeyring(t), []string{"customers"})
+
+	// Written before encryption was enabled
+	if _, err := plain.Put(ctx, "acme", "customers", "old", "json", []byte(`{"email":"old@example.com"}`), "application/json", storage.StateLive); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := encrypted.PutStream(ctx, "acme", "customers", "jane", "json", strings.NewReader(`{"email":"jane@example.com"}`), -1, "application/json", storage.StateLive, map[string]string{"owner": "crm"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := encrypted.Put(ctx, "acme", "pages", "home", "json", []byte(`{"title":"jane@example.com"}`), "application/json", storage.StateLive); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range fake.Keys() {
+		result, err := fake.GetObject(ctx, &s3.GetObjectInput{Key: aws.String(k)})
+		if err != nil {
+			t.Fatalf("GetObject: %v", err)
+		}
+		data, _ := io.ReadAll(result.Body)
+		// Only customers are encrypted
+		switch plaintext := strings.Contains(string(data), "@example.com"); {
+		case strings.Contains(k, "/customers/jane") && plaintext, strings.Contains(k, "/pages/home") && !plaintext:
+			t.Errorf("%s stored as %q", k, data)
+		}
+	}
+
+	// Bodies larger than a chunk are streamed through
+	large := strings.Repeat(`{"email":"bulk@example.com"}`, 5000)
+	if _, err := encrypted.PutStream(ctx, "acme", "customers", "bulk", "json", strings.NewReader(large), int64(len(large)), "application/json", storage.StateLive, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[string]string{"jane": `{"email":"jane@example.com"}`, "old": `{"email":"old@example.com"}`, "bulk": large} {
+		stream, err := encrypted.FindContentStream(ctx, "acme", "customers", id, "", storage.StateLive)
+		if err != nil {
+			t.Fatalf("FindContentStream(%s): %v", id, err)
+		}
+		data, _ := io.ReadAll(stream.Body)
+		stream.Body.Close()
+		if string(data) != want || stream.Size != int64(len(want)) {
+			t.Errorf("FindContentStream(%s) = %d bytes (size %d), want %d", id, len(data), stream.Size, len(want))
+		}
+	}
+
+	// Replacing the metadata keeps what's needed to decrypt
+	if err := encrypted.SetMetadata(ctx, "acme", "customers", "jane", "json", storage.StateLive, map[string]string{"owner": "support"}); err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := encrypted.GetMetadata(ctx, "acme", "customers", "jane", "json", storage.StateLive)
+	if err != nil || len(metadata) != 1 || metadata["owner"] != "support" {
+		t.Errorf("GetMetadata = %v, %v; want only the owner", metadata, err)
+	}
+	item, err := encrypted.Get(ctx, "acme", "customers", "jane", "json", storage.StateLive)
+	if err != nil || string(item.Content) != `{"email":"jane@example.com"}` {
+		t.Errorf("Get after SetMetadata = %v, %v", item, err)
+	}
+
+	if _, err := plain.Get(ctx, "acme", "customers", "jane", "json", storage.StateLive); err != nil {
+		t.Fatal(err)
+	}
+	other := storage.NewEncryptedStorage(plain, crypto.NewKeyring(mustKey(t, "some other passphrase entirely")), nil)
+	if _, err := other.Get(ctx, "acme", "customers", "jane", "json", storage.StateLive); !errors.Is(err, crypto.ErrNoKey) {
+		t.Errorf("Get without the key = %v, want ErrNoKey", err)
+	}
+}
+
+func TestReplicatedStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		primary, _ := storagetest.NewS3Storage(10)
+		secondary, _ := storagetest.NewS3Storage(10)
+		rs := storage.NewReplicatedStorage(primary, secondary)
+		t.Cleanup(func() { rs.Close() })
+		return rs
+	})
+}
+
+func TestReplicatedStorageCopiesWrites(t *testing.T) {
+	ctx := context.Background()
+	primary, _ := storagetest.NewS3Storage(10)
+	secondary, _ := storagetest.NewS3Storage(10)
+	rs := storage.NewReplicatedStorage(primary, secondary)
+	defer rs.Close()
+
+	if _, err := rs.PutStream(ctx, "acme", "pages", "home", "json", strings.NewReader(`{"title":"Home"}`), -1, "application/json", storage.StateDraft, map[string]string{"owner": "web"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rs.Transition(ctx, "acme", "pages", "home", "json", storage.StateDraft, storage.StateLive); err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.PutTenantSchema(ctx, "acme", "pages", []byte(`{"type":"object"}`)); err != nil {
+		t.Fatal(err)
+	}
+	drain(t, rs)
+
+	item, err := secondary.Get(ctx, "acme", "pages", "home", "json", storage.StateLive)
+	if err != nil {
+		t.Fatalf("live copy not replicated: %v", err)
+	}
+	if string(item.Content) != `{"title":"Home"}` || item.Metadata["owner"] != "web" {
+		t.Errorf("replicated copy = %q %v", item.Content, item.Metadata)
+	}
+	if exists, _ := secondary.Exists(ctx, "acme", "pages", "home", "json", storage.StateDraft); exists {
+		t.Error("draft left on the secondary after it was published")
+	}
+	if _, err := secondary.GetTenantSchema(ctx, "acme", "pages"); err != nil {
+		t.Errorf("schema not replicated: %v", err)
+	}
+
+	if err := rs.Delete(ctx, "acme", "pages", "home", "json", storage.StateLive); err != nil {
+		t.Fatal(err)
+	}
+	drain(t, rs)
+	if exists, _ := secondary.Exists(ctx, "acme", "pages", "home", "json", storage.StateLive); exists {
+		t.Error("delete not replicated")
+	}
+}
+
+// unreachableStorage fails every content read, like a primary in a region
+// that's down
+type unreachableStorage struct {
+	storage.Storage
+}
+
+func (unreachableStorage) Get(ctx context.Context, tenant, contentType, id, ext string, state storage.State) (*storage.ContentItem, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (unreachableStorage) FindContentStream(ctx context.Context, tenant, contentType, id, extHint string, state storage.State) (*storage.ContentStream, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestReplicatedStorageFallsBackOnReadFailure(t *testing.T) {
+	ctx := context.Background()
+	primary, _ := storagetest.NewS3Storage(10)
+	secondary, _ := storagetest.NewS3Storage(10)
+	rs := storage.NewReplicatedStorage(unreachableStorage{primary}, secondary)
+	defer rs.Close()
+
+	if _, err := rs.Put(ctx, "acme", "pages", "home", "json", []byte(`{"title":"Home"}`), "application/json", storage.StateLive); err != nil {
+		t.Fatal(err)
+	}
+	drain(t, rs)
+
+	item, err := rs.Get(ctx, "acme", "pages", "home", "json", storage.StateLive)
+	if err != nil || string(item.Content) != `{"title":"Home"}` {
+		t.Fatalf("Get = %v, %v; want the secondary's copy", item, err)
+	}
+	stream, err := rs.FindContentStream(ctx, "acme", "pages", "home", "", storage.StateLive)
+	if err != nil {
+		t.Fatalf("FindContentStream: %v", err)
+	}
+	stream.Body.Close()
+
+	// Missing content is missing everywhere, so it isn't looked for on secondaries
+	secondary.Put(ctx, "acme", "pages", "about", "json", []byte(`{}`), "application/json", storage.StateLive)
+	healthy := storage.NewReplicatedStorage(primary, secondary)
+	defer healthy.Close()
+	if _, err := healthy.Get(ctx, "acme", "pages", "about", "json", storage.StateLive); err == nil {
+		t.Error("read of missing content fell back to a secondary")
+	}
+}
+
+func drain(t *testing.T, rs *storage.ReplicatedStorage) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := rs.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustKey(t *testing.T, passphrase string) crypto.Key {
+	key, err := crypto.NewPassphraseKey(passphrase)
+	if err != nil {
+		t.Fatalf("NewPassphraseKey: %v", err)
+	}
+	return key
+}
+
+func TestNewS3StorageAuth(t *testing.T) {
+	for _, auth := range []string{"", storage.S3AuthStatic, storage.S3AuthIAM} {
+		if _, err := storage.NewS3Storage(storage.S3Config{Region: "us-east-1", Bucket: "velocity", Auth: auth, FIPS: auth == storage.S3AuthIAM}); err != nil {
+			t.Errorf("NewS3Storage(auth %q): %v", auth, err)
+		}
+	}
+	if _, err := storage.NewS3Storage(storage.S3Config{Region: "us-east-1", Bucket: "velocity", Auth: "password"}); err == nil {
+		t.Error("NewS3Storage accepted an unknown auth mode")
+	}
+}
+
+func TestS3StorageKeysStayInTenant(t *testing.T) {
+	s, fake := storagetest.NewS3Storage(10)
+	ctx := context.Background()
+
+	for _, tenant := range []string{"../production", "acme/../../schemas", "acme/content/pages"} {
+		if _, err := s.Put(ctx, tenant, "pages", "home", "json", []byte("{}"), "application/json", storage.StateLive); err != nil {
+			t.Fatalf("Put(%s): %v", tenant, err)
+		}
+	}
+	if _, err := s.Put(ctx, "acme", "../../../sessions", "../x", "json", []byte("{}"), "application/json", storage.StateLive); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := s.Put(ctx, storage.RootTenant("acme", storage.RootGreen), "pages", "home", "json", []byte("{}"), "application/json", storage.StateLive); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	want := map[string]bool{
+		"test/tenants/..%2Fproduction/content/pages/home.json":                  true,
+		"test/tenants/acme%2F..%2F..%2Fschemas/content/pages/home.json":         true,
+		"test/tenants/acme%2Fcontent%2Fpages/content/pages/home.json":           true,
+		"test/tenants/acme/content/%2E%2E/%2E%2E/%2E%2E/sessions/%2E%2E/x.json": true,
+		"test/tenants/acme/roots/green/content/pages/home.json":                 true,
+	}
+	for _, key := range fake.Keys() {
+		if !want[key] {
+			t.Errorf("unexpected key %s", key)
+		}
+		delete(want, key)
+	}
+	for key := range want {
+		t.Errorf("missing key %s", key)
+	}
+}
+
+func BenchmarkS3StoragePutStream(b *testing.B) {
+	s, _ := storagetest.NewS3Storage(10)
+	ctx := context.Background()
+	body := strings.Repeat(`{"title":"Page"}`, 64)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id := fmt.Sprintf("page-%d", i%100)
+		if _, err := s.PutStream(ctx, "acme", "pages", id, "json", strings.NewReader(body), int64(len(body)), "application/json", storage.StateDraft, nil); err != nil {
+			b.Fatalf("PutStream: %v", err)
+		}
+	}
+}
+
+func BenchmarkS3StorageGet(b *testing.B) {
+	s, _ := storagetest.NewS3Storage(10)
+	ctx := context.Background()
+	body := strings.Repeat(`{"title":"Page"}`, 64)
+	for i := 0; i < 100; i++ {
+		if _, err := s.PutStream(ctx, "acme", "pages", fmt.Sprintf("page-%d", i), "json", strings.NewReader(body), int64(len(body)), "application/json", storage.StateDraft, nil); err != nil {
+			b.Fatalf("PutStream: %v", err)
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Get(ctx, "acme", "pages", fmt.Sprintf("page-%d", i%100), "json", storage.StateDraft); err != nil {
+			b.Fatalf("Get: %v", err)
+		}
+	}
+}
+
+func BenchmarkS3StorageList(b *testing.B) {
+	s, _ := storagetest.NewS3Storage(10)
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		body := fmt.Sprintf(`{"title":"Page %d"}`, i)
+		if _, err := s.PutStream(ctx, "acme", "pages", fmt.Sprintf("page-%d", i), "json", strings.NewReader(body), int64(len(body)), "application/json", storage.StateDraft, nil); err != nil {
+			b.Fatalf("PutStream: %v", err)
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.List(ctx, "acme", "pages", storage.StateDraft); err != nil {
+			b.Fatalf("List: %v", err)
+		}
+	}
+}
@@ -63,6 +63,7 @@ type ContentVersion struct {
 	VersionID    string
 	LastModified time.Time
 	Size         int64
+	ETag         string // same as the previous version's when only metadata changed
 	IsLatest     bool
 }
 
@@ -94,6 +95,16 @@ type Comment struct {
 	ResolvedAt time.Time `json:"resolved_at,omitempty"`
 }
 
+// Attachment is a secondary file stored under a content item (e.g. a PDF
+// datasheet attached to a product)
+type Attachment struct {
+	Name         string    `json:"name"`
+	ContentType  string    `json:"content_type,omitempty"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+}
+
 // Webhook represents a webhook configuration for a tenant
 type Webhook struct {
 	ID     string   `json:"id"`
@@ -101,6 +112,20 @@ type Webhook struct {
 	Events []string `json:"events"` // create, update, delete, publish
 }
 
+// APIKey is a key a tenant's clients authenticate with. Only a hash of the
+// secret is stored; the secret itself is shown once, when the key is created
+// or rotated.
+type APIKey struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name,omitempty"`
+	Role        string     `json:"role,omitempty"`        // admin, editor, or viewer (admin if unset)
+	Permissions []string   `json:"permissions,omitempty"` // Granted on top of the role's (decrypt)
+	Prefix      string     `json:"prefix"`                // Start of the secret, to tell keys apart
+	Hash        string     `json:"hash"`                  // Hex SHA-256 of the secret
+	CreatedAt   time.Time  `json:"created_at"`
+	RotatedAt   *time.Time `json:"rotated_at,omitempty"`
+}
+
 // WebhookEvent represents an event payload sent to webhooks
 type WebhookEvent struct {
 	Event       string `json:"event"`
@@ -109,7 +134,16 @@ type WebhookEvent struct {
 	ID          string `json:"id"`
 	Name        string `json:"name,omitempty"`
 	ContentType string `json:"content-type,omitempty"`
+	State       string `json:"state,omitempty"`
+	From        string `json:"from,omitempty"`    // Set on transition events
+	To          string `json:"to,omitempty"`      // Set on transition events
+	Author      string `json:"author,omitempty"`  // Set on publish and workflow events
+	Message     string `json:"message,omitempty"` // Publish message or rejection reason
 	Timestamp   string `json:"timestamp"`
+	Sequence    int64  `json:"sequence"` // Increases with every event for the same item
+
+	Comment  *Comment          `json:"comment,omitempty"`  // Set on comment events
+	Metadata map[string]string `json:"metadata,omitempty"` // Set on metadata.updated
 }
 
 // BrowseResult contains folders and items at a given prefix level
@@ -198,12 +232,15 @@ type Storage interface {
 	Browse(ctx context.Context, tenant, contentType, prefix string, state State) (*BrowseResult, error)
 	Exists(ctx context.Context, tenant, contentType, id, ext string, state State) (bool, error)
 	Transition(ctx context.Context, tenant, contentType, id, ext string, fromState, toState State) (*ContentItem, error)
+	CopyContent(ctx context.Context, tenant, contentType, id, ext string, fromState, toState State, metadata map[string]string) (*ContentItem, error)
 
 	// Versioning
 	ListVersions(ctx context.Context, tenant, contentType, id, ext string) ([]*ContentVersion, error)
 	GetVersion(ctx context.Context, tenant, contentType, id, ext, versionID string) (*ContentItem, error)
 	GetVersionStream(ctx context.Context, tenant, contentType, id, ext, versionID string) (*ContentStream, error)
 	RestoreVersion(ctx context.Context, tenant, contentType, id, ext, versionID string) (*ContentItem, error)
+	FindVersionByETag(ctx context.Context, tenant, contentType, id, ext string, state State, etag string) (*ContentItem, error)
+	PruneVersions(ctx context.Context, tenant, contentType, id, ext string, dryRun bool) ([]*ContentVersion, error)
 
 	// History
 	PutHistoryRecord(ctx context.Context, tenant, contentType, id string, record *HistoryRecord) error
@@ -232,12 +269,25 @@ type Storage interface {
 	DeleteAllComments(ctx context.Context, tenant, contentType, contentID string, state State) error
 	HasUnresolvedComments(ctx context.Context, tenant, contentType, contentID string, state State) (bool, error)
 
+	// Attachments (named files under a content item, shared by all its states)
+	PutAttachment(ctx context.Context, tenant, contentType, contentID, name string, body io.Reader, contentLength int64, mimeType string) (*Attachment, error)
+	GetAttachment(ctx context.Context, tenant, contentType, contentID, name string) (*ContentStream, error)
+	ListAttachments(ctx context.Context, tenant, contentType, contentID string) ([]*Attachment, error)
+	DeleteAttachment(ctx context.Context, tenant, contentType, contentID, name string) error
+	DeleteAllAttachments(ctx context.Context, tenant, contentType, contentID string) error
+
 	// Webhooks
 	ListWebhooks(ctx context.Context, tenant string) ([]*Webhook, error)
 	GetWebhook(ctx context.Context, tenant, webhookID string) (*Webhook, error)
 	PutWebhook(ctx context.Context, tenant string, webhook *Webhook) error
 	DeleteWebhook(ctx context.Context, tenant, webhookID string) error
 
+	// API keys (stored hashed)
+	ListAPIKeys(ctx context.Context, tenant string) ([]*APIKey, error)
+	GetAPIKey(ctx context.Context, tenant, keyID string) (*APIKey, error)
+	PutAPIKey(ctx context.Context, tenant string, key *APIKey) error
+	DeleteAPIKey(ctx context.Context, tenant, keyID string) error
+
 	// Tenants
 	ListTenants(ctx context.Context) ([]string, error)
 	ListContentTypes(ctx context.Context, tenant string) ([]string, error)
@@ -250,6 +300,31 @@ type Storage interface {
 	DeleteSession(ctx context.Context, token string) error
 	DeleteExpiredSessions(ctx context.Context) (int, error)
 
+	// Documents (small JSON records keyed by collection, e.g. idempotency keys).
+	// An empty tenant stores the document globally.
+	GetDocument(ctx context.Context, tenant, collection, id string) ([]byte, error)
+	PutDocument(ctx context.Context, tenant, collection, id string, data []byte) error
+	DeleteDocument(ctx context.Context, tenant, collection, id string) error
+	ListDocuments(ctx context.Context, tenant, collection string) ([]string, error)
+
+	// Leases (exclusive, expiring claims shared by every node; keys are global).
+	// AcquireLease returns the current lease with ErrLeaseHeld when it is taken;
+	// GetLease returns nil when the key has never been leased.
+	AcquireLease(ctx context.Context, key string, ttl time.Duration) (*Lease, error)
+	RenewLease(ctx context.Context, lease *Lease, ttl time.Duration) (*Lease, error)
+	ReleaseLease(ctx context.Context, lease *Lease) error
+	GetLease(ctx context.Context, key string) (*Lease, error)
+
+	// Consistency (enumerate and clean up records kept alongside content)
+	ListCommentedIDs(ctx context.Context, tenant, contentType string, state State) ([]string, error)
+	ListHistoryIDs(ctx context.Context, tenant, contentType string) ([]string, error)
+	DeleteHistoryRecord(ctx context.Context, tenant, contentType, id, version string) error
+	ContentDeletedAt(ctx context.Context, tenant, contentType, id string) (time.Time, bool, error)
+
+	// Backups (server-side copies of content objects referenced by backup manifests)
+	BackupObject(ctx context.Context, tenant, contentType, id, ext string, state State, objectID string) error
+	RestoreObject(ctx context.Context, tenant, contentType, id, ext string, state State, objectID string) (*ContentItem, error)
+
 	// Folders
 	CreateFolder(ctx context.Context, tenant, contentType, folderPath string, state State) error
 
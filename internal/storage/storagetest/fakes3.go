@@ -0,0 +1,33 @@
+// Package storagetest provides helpers for testing storage backends: an
+// in-memory S3 client and a conformance suite every Storage implementation
+// should pass.
+package storagetest
+
+import (
+	"velocity/internal/storage"
+)
+
+// FakeS3 is an in-memory, versioned S3 bucket implementing storage.S3API
+// (see storage.MemoryS3)
+type FakeS3 = storage.MemoryS3
+
+// NewFakeS3 creates an empty in-memory bucket
+func NewFakeS3() *FakeS3 {
+	return storage.NewMemoryS3()
+}
+
+// NewS3Storage returns an S3Storage backed by a new in-memory bucket, along
+// with the bucket for inspection
+func NewS3Storage(maxVersions int) (*storage.S3Storage, *FakeS3) {
+	fake := NewFakeS3()
+	s, err := storage.NewS3Storage(storage.S3Config{
+		Bucket:      "velocity",
+		Root:        "test",
+		MaxVersions: maxVersions,
+		Client:      fake,
+	})
+	if err != nil {
+		panic(err) // cannot fail with an injected client
+	}
+	return s, fake
+}
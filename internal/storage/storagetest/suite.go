@@ -0,0 +1,523 @@
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"velocity/internal/storage"
+)
+
+// Factory returns a new, empty Storage for one test
+type Factory func(t *testing.T) storage.Storage
+
+// Run checks a Storage implementation against the behavior the API relies on.
+// Each subtest gets fresh storage from newStorage.
+//
+//	func TestMyBackend(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Storage { return newMyBackend(t) })
+//	}
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s storage.Storage)
+	}{
+		{"Content", testContent},
+		{"States", testStates},
+		{"Transition", testTransition},
+		{"CopyContent", testCopyContent},
+		{"Browse", testBrowse},
+		{"Versions", testVersions},
+		{"History", testHistory},
+		{"Comments", testComments},
+		{"Attachments", testAttachments},
+		{"Metadata", testMetadata},
+		{"Documents", testDocuments},
+		{"Tenants", testTenants},
+		{"Leases", testLeases},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStorage(t))
+		})
+	}
+}
+
+const tenant = "acme"
+
+func mustPut(t *testing.T, s storage.Storage, contentType, id, ext, content string, state storage.State) *storage.ContentItem {
+	t.Helper()
+	item, err := s.Put(context.Background(), tenant, contentType, id, ext, []byte(content), "application/json", state)
+	if err != nil {
+		t.Fatalf("Put %s/%s.%s (%s): %v", contentType, id, ext, state, err)
+	}
+	return item
+}
+
+func ids(items []*storage.ContentItem) []string {
+	var out []string
+	for _, item := range items {
+		name := item.Key[strings.LastIndex(item.Key, "/")+1:]
+		out = append(out, strings.TrimSuffix(name, ".json"))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func testContent(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	mustPut(t, s, "pages", "home", "json", `{"title":"Home"}`, storage.StateLive)
+	item, err := s.Get(ctx, tenant, "pages", "home", "json", storage.StateLive)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(item.Content) != `{"title":"Home"}` {
+		t.Errorf("Get content = %s", item.Content)
+	}
+	if !strings.HasPrefix(item.ContentType, "application/json") {
+		t.Errorf("Get content type = %q", item.ContentType)
+	}
+
+	stream, err := s.GetStream(ctx, tenant, "pages", "home", "json", storage.StateLive)
+	if err != nil {
+		t.Fatalf("GetStream: %v", err)
+	}
+	data, _ := io.ReadAll(stream.Body)
+	stream.Body.Close()
+	if string(data) != `{"title":"Home"}` {
+		t.Errorf("GetStream content = %s", data)
+	}
+
+	if _, err := s.PutStream(ctx, tenant, "pages", "about", "json", bytes.NewReader([]byte(`{}`)), 2, "application/json", storage.StateLive, map[string]string{"author": "jane"}); err != nil {
+		t.Fatalf("PutStream: %v", err)
+	}
+
+	items, err := s.List(ctx, tenant, "pages", storage.StateLive)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if got := strings.Join(ids(items), ","); got != "about,home" {
+		t.Errorf("List = %s, want about,home", got)
+	}
+
+	if exists, err := s.Exists(ctx, tenant, "pages", "home", "json", storage.StateLive); err != nil || !exists {
+		t.Errorf("Exists = %v, %v; want true", exists, err)
+	}
+	if err := s.Delete(ctx, tenant, "pages", "home", "json", storage.StateLive); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if exists, _ := s.Exists(ctx, tenant, "pages", "home", "json", storage.StateLive); exists {
+		t.Error("Exists after Delete = true")
+	}
+	if _, err := s.Get(ctx, tenant, "pages", "home", "json", storage.StateLive); err == nil {
+		t.Error("Get after Delete succeeded")
+	}
+}
+
+func testStates(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	mustPut(t, s, "pages", "home", "json", `{"v":"live"}`, storage.StateLive)
+	mustPut(t, s, "pages", "home", "json", `{"v":"draft"}`, storage.StateDraft)
+	mustPut(t, s, "pages", "next", "json", `{"v":"pending"}`, storage.StatePending)
+
+	for state, want := range map[storage.State]string{
+		storage.StateLive:    `{"v":"live"}`,
+		storage.StateDraft:   `{"v":"draft"}`,
+		storage.StatePending: "",
+	} {
+		item, err := s.Get(ctx, tenant, "pages", "home", "json", state)
+		switch {
+		case want == "" && err == nil:
+			t.Errorf("Get (%s) found content that was never written", state)
+		case want != "" && err != nil:
+			t.Errorf("Get (%s): %v", state, err)
+		case want != "" && string(item.Content) != want:
+			t.Errorf("Get (%s) = %s, want %s", state, item.Content, want)
+		}
+	}
+
+	live, _ := s.List(ctx, tenant, "pages", storage.StateLive)
+	if got := strings.Join(ids(live), ","); got != "home" {
+		t.Errorf("List (live) = %s, want home", got)
+	}
+	pending, _ := s.List(ctx, tenant, "pages", storage.StatePending)
+	if got := strings.Join(ids(pending), ","); got != "next" {
+		t.Errorf("List (pending) = %s, want next", got)
+	}
+}
+
+func testTransition(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	mustPut(t, s, "pages", "home", "json", `{"v":1}`, storage.StateDraft)
+	if _, err := s.Transition(ctx, tenant, "pages", "home", "json", storage.StateDraft, storage.StateLive); err != nil {
+		t.Fatalf("Transition: %v", err)
+	}
+
+	if exists, _ := s.Exists(ctx, tenant, "pages", "home", "json", storage.StateDraft); exists {
+		t.Error("draft still exists after transition to live")
+	}
+	item, err := s.Get(ctx, tenant, "pages", "home", "json", storage.StateLive)
+	if err != nil || string(item.Content) != `{"v":1}` {
+		t.Errorf("Get (live) after transition = %v, %v", item, err)
+	}
+}
+
+func testCopyContent(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	body := "\x00\xffbinary"
+	if _, err := s.PutStream(ctx, tenant, "files", "logo", "bin", strings.NewReader(body), int64(len(body)), "application/octet-stream", storage.StateLive, map[string]string{"author": "jane"}); err != nil {
+		t.Fatalf("PutStream: %v", err)
+	}
+	if _, err := s.CopyContent(ctx, tenant, "files", "logo", "bin", storage.StateLive, storage.StateDraft, map[string]string{"branched-from": "live"}); err != nil {
+		t.Fatalf("CopyContent: %v", err)
+	}
+
+	if exists, _ := s.Exists(ctx, tenant, "files", "logo", "bin", storage.StateLive); !exists {
+		t.Error("live removed by copy to draft")
+	}
+	item, err := s.Get(ctx, tenant, "files", "logo", "bin", storage.StateDraft)
+	if err != nil || string(item.Content) != body || item.ContentType != "application/octet-stream" {
+		t.Fatalf("Get (draft) after copy = %v, %v", item, err)
+	}
+	metadata, err := s.GetMetadata(ctx, tenant, "files", "logo", "bin", storage.StateDraft)
+	if err != nil || metadata["author"] != "jane" || metadata["branched-from"] != "live" {
+		t.Errorf("GetMetadata (draft) after copy = %v, %v; want author and branched-from", metadata, err)
+	}
+}
+
+func testBrowse(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	mustPut(t, s, "docs", "intro", "json", `{}`, storage.StateLive)
+	mustPut(t, s, "docs", "guides/setup", "json", `{}`, storage.StateLive)
+	if err := s.CreateFolder(ctx, tenant, "docs", "empty", storage.StateLive); err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+
+	result, err := s.Browse(ctx, tenant, "docs", "", storage.StateLive)
+	if err != nil {
+		t.Fatalf("Browse: %v", err)
+	}
+	sort.Strings(result.Folders)
+	if got := strings.Join(result.Folders, ","); got != "empty,guides" {
+		t.Errorf("Browse folders = %s, want empty,guides", got)
+	}
+	if got := strings.Join(ids(result.Items), ","); got != "intro" {
+		t.Errorf("Browse items = %s, want intro", got)
+	}
+
+	nested, err := s.Browse(ctx, tenant, "docs", "guides/", storage.StateLive)
+	if err != nil {
+		t.Fatalf("Browse guides/: %v", err)
+	}
+	if got := strings.Join(ids(nested.Items), ","); got != "setup" {
+		t.Errorf("Browse guides/ items = %s, want setup", got)
+	}
+
+	index := &storage.DirectoryIndex{Order: []string{"intro", "guides"}}
+	if err := s.PutDirectoryIndex(ctx, tenant, "docs", "", storage.StateLive, index); err != nil {
+		t.Fatalf("PutDirectoryIndex: %v", err)
+	}
+	got, err := s.GetDirectoryIndex(ctx, tenant, "docs", "", storage.StateLive)
+	if err != nil || got == nil || strings.Join(got.Order, ",") != "intro,guides" {
+		t.Errorf("GetDirectoryIndex = %v, %v", got, err)
+	}
+}
+
+func testVersions(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	first := mustPut(t, s, "pages", "home", "json", `{"v":1}`, storage.StateLive)
+	mustPut(t, s, "pages", "home", "json", `{"v":2}`, storage.StateLive)
+
+	versions, err := s.ListVersions(ctx, tenant, "pages", "home", "json")
+	if err != nil {
+		t.Fatalf("ListVersions: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("ListVersions returned %d versions, want 2", len(versions))
+	}
+
+	old, err := s.GetVersion(ctx, tenant, "pages", "home", "json", first.VersionID)
+	if err != nil || string(old.Content) != `{"v":1}` {
+		t.Fatalf("GetVersion = %v, %v", old, err)
+	}
+
+	if _, err := s.RestoreVersion(ctx, tenant, "pages", "home", "json", first.VersionID); err != nil {
+		t.Fatalf("RestoreVersion: %v", err)
+	}
+	current, _ := s.Get(ctx, tenant, "pages", "home", "json", storage.StateLive)
+	if current == nil || string(current.Content) != `{"v":1}` {
+		t.Errorf("Get after RestoreVersion = %v", current)
+	}
+
+	// Drafts are versioned too, and can be found by the ETag an editor read
+	draft := mustPut(t, s, "pages", "home", "json", `{"v":"draft 1"}`, storage.StateDraft)
+	mustPut(t, s, "pages", "home", "json", `{"v":"draft 2"}`, storage.StateDraft)
+	base, err := s.FindVersionByETag(ctx, tenant, "pages", "home", "json", storage.StateDraft, draft.ETag)
+	if err != nil || string(base.Content) != `{"v":"draft 1"}` {
+		t.Errorf("FindVersionByETag = %v, %v", base, err)
+	}
+	if _, err := s.FindVersionByETag(ctx, tenant, "pages", "home", "json", storage.StateDraft, `"unknown"`); err == nil {
+		t.Error("FindVersionByETag found an unknown ETag")
+	}
+}
+
+func testHistory(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	for i, version := range []string{"v1", "v2"} {
+		record := &storage.HistoryRecord{Version: version, Author: "jane", Timestamp: time.Now().Add(time.Duration(i) * time.Second)}
+		if err := s.PutHistoryRecord(ctx, tenant, "pages", "home", record); err != nil {
+			t.Fatalf("PutHistoryRecord: %v", err)
+		}
+	}
+
+	record, err := s.GetHistoryRecord(ctx, tenant, "pages", "home", "v2")
+	if err != nil || record.Author != "jane" {
+		t.Errorf("GetHistoryRecord = %v, %v", record, err)
+	}
+	records, err := s.ListHistoryRecords(ctx, tenant, "pages", "home")
+	if err != nil || len(records) != 2 {
+		t.Errorf("ListHistoryRecords returned %d records (%v), want 2", len(records), err)
+	}
+
+	if err := s.DeleteHistoryRecord(ctx, tenant, "pages", "home", "v1"); err != nil {
+		t.Fatalf("DeleteHistoryRecord: %v", err)
+	}
+	if _, err := s.GetHistoryRecord(ctx, tenant, "pages", "home", "v1"); err == nil {
+		t.Error("GetHistoryRecord after delete succeeded")
+	}
+	historyIDs, err := s.ListHistoryIDs(ctx, tenant, "pages")
+	if err != nil || strings.Join(historyIDs, ",") != "home" {
+		t.Errorf("ListHistoryIDs = %v, %v; want [home]", historyIDs, err)
+	}
+}
+
+func testComments(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	mustPut(t, s, "pages", "home", "json", `{}`, storage.StateDraft)
+	comment := &storage.Comment{ID: "c1", Author: "jane", Message: "Typo in title", CreatedAt: time.Now()}
+	if err := s.PutComment(ctx, tenant, "pages", "home", storage.StateDraft, comment); err != nil {
+		t.Fatalf("PutComment: %v", err)
+	}
+
+	got, err := s.GetComment(ctx, tenant, "pages", "home", storage.StateDraft, "c1")
+	if err != nil || got.Message != "Typo in title" {
+		t.Errorf("GetComment = %v, %v", got, err)
+	}
+	if unresolved, err := s.HasUnresolvedComments(ctx, tenant, "pages", "home", storage.StateDraft); err != nil || !unresolved {
+		t.Errorf("HasUnresolvedComments = %v, %v; want true", unresolved, err)
+	}
+
+	if err := s.DeleteAllComments(ctx, tenant, "pages", "home", storage.StateDraft); err != nil {
+		t.Fatalf("DeleteAllComments: %v", err)
+	}
+	if comments, _ := s.ListComments(ctx, tenant, "pages", "home", storage.StateDraft); len(comments) != 0 {
+		t.Errorf("ListComments after delete returned %d comments", len(comments))
+	}
+}
+
+func testAttachments(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	mustPut(t, s, "products", "widget", "json", `{}`, storage.StateLive)
+	mustPut(t, s, "products", "widget/mini", "json", `{}`, storage.StateLive)
+	put := func(id, name, content string) {
+		t.Helper()
+		if _, err := s.PutAttachment(ctx, tenant, "products", id, name, strings.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+			t.Fatalf("PutAttachment(%s, %s): %v", id, name, err)
+		}
+	}
+	put("widget", "datasheet.pdf", "%PDF-1.7")
+	put("widget", "manual.pdf", "%PDF-1.4")
+	put("widget/mini", "datasheet.pdf", "%PDF-1.5")
+
+	stream, err := s.GetAttachment(ctx, tenant, "products", "widget", "datasheet.pdf")
+	if err != nil {
+		t.Fatalf("GetAttachment: %v", err)
+	}
+	data, _ := io.ReadAll(stream.Body)
+	stream.Body.Close()
+	if string(data) != "%PDF-1.7" || stream.ContentType != "application/pdf" {
+		t.Errorf("GetAttachment = %q (%s)", data, stream.ContentType)
+	}
+
+	// Attachments of nested items aren't listed with their parent
+	attachments, err := s.ListAttachments(ctx, tenant, "products", "widget")
+	if err != nil {
+		t.Fatalf("ListAttachments: %v", err)
+	}
+	var names []string
+	for _, a := range attachments {
+		names = append(names, a.Name)
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "datasheet.pdf,manual.pdf" {
+		t.Errorf("ListAttachments = %v; want [datasheet.pdf manual.pdf]", names)
+	}
+
+	// Attachments aren't content
+	items, err := s.List(ctx, tenant, "products", storage.StateLive)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(items) != 2 {
+		t.Errorf("List returned %d items (%v); want 2", len(items), ids(items))
+	}
+
+	if err := s.DeleteAllAttachments(ctx, tenant, "products", "widget"); err != nil {
+		t.Fatalf("DeleteAllAttachments: %v", err)
+	}
+	if attachments, _ := s.ListAttachments(ctx, tenant, "products", "widget"); len(attachments) != 0 {
+		t.Errorf("ListAttachments after delete returned %d attachments", len(attachments))
+	}
+	if _, err := s.GetAttachment(ctx, tenant, "products", "widget/mini", "datasheet.pdf"); err != nil {
+		t.Errorf("DeleteAllAttachments removed a nested item's attachment: %v", err)
+	}
+}
+
+func testMetadata(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	mustPut(t, s, "pages", "home", "json", `{}`, storage.StateLive)
+	if err := s.SetMetadata(ctx, tenant, "pages", "home", "json", storage.StateLive, map[string]string{"author": "jane", "status": "new"}); err != nil {
+		t.Fatalf("SetMetadata: %v", err)
+	}
+	if err := s.UpdateMetadata(ctx, tenant, "pages", "home", "json", storage.StateLive, map[string]string{"status": "reviewed"}); err != nil {
+		t.Fatalf("UpdateMetadata: %v", err)
+	}
+	if err := s.DeleteMetadataKeys(ctx, tenant, "pages", "home", "json", storage.StateLive, []string{"author"}); err != nil {
+		t.Fatalf("DeleteMetadataKeys: %v", err)
+	}
+
+	metadata, err := s.GetMetadata(ctx, tenant, "pages", "home", "json", storage.StateLive)
+	if err != nil {
+		t.Fatalf("GetMetadata: %v", err)
+	}
+	if metadata["status"] != "reviewed" || metadata["author"] != "" {
+		t.Errorf("GetMetadata = %v, want status=reviewed without author", metadata)
+	}
+
+	item, _ := s.Get(ctx, tenant, "pages", "home", "json", storage.StateLive)
+	if item == nil || string(item.Content) != `{}` {
+		t.Errorf("content changed by metadata update: %v", item)
+	}
+}
+
+func testDocuments(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	if err := s.PutDocument(ctx, tenant, "notes", "a", []byte(`{"n":1}`)); err != nil {
+		t.Fatalf("PutDocument: %v", err)
+	}
+	if err := s.PutDocument(ctx, "", "notes", "global", []byte(`{}`)); err != nil {
+		t.Fatalf("PutDocument (global): %v", err)
+	}
+
+	data, err := s.GetDocument(ctx, tenant, "notes", "a")
+	if err != nil || string(data) != `{"n":1}` {
+		t.Errorf("GetDocument = %s, %v", data, err)
+	}
+	list, err := s.ListDocuments(ctx, tenant, "notes")
+	if err != nil || strings.Join(list, ",") != "a" {
+		t.Errorf("ListDocuments = %v, %v; want [a] (global documents are separate)", list, err)
+	}
+
+	if err := s.DeleteDocument(ctx, tenant, "notes", "a"); err != nil {
+		t.Fatalf("DeleteDocument: %v", err)
+	}
+	if _, err := s.GetDocument(ctx, tenant, "notes", "a"); err == nil {
+		t.Error("GetDocument after delete succeeded")
+	}
+}
+
+func testTenants(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	if err := s.CreateTenant(ctx, tenant); err != nil {
+		t.Fatalf("CreateTenant: %v", err)
+	}
+	if err := s.CreateContentType(ctx, tenant, "pages"); err != nil {
+		t.Fatalf("CreateContentType: %v", err)
+	}
+	mustPut(t, s, "posts", "hello", "json", `{}`, storage.StateLive)
+
+	tenants, err := s.ListTenants(ctx)
+	if err != nil || strings.Join(tenants, ",") != tenant {
+		t.Errorf("ListTenants = %v, %v; want [%s]", tenants, err, tenant)
+	}
+	types, err := s.ListContentTypes(ctx, tenant)
+	sort.Strings(types)
+	if err != nil || strings.Join(types, ",") != "pages,posts" {
+		t.Errorf("ListContentTypes = %v, %v; want [pages posts]", types, err)
+	}
+}
+
+func testLeases(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	if lease, err := s.GetLease(ctx, "jobs/nightly"); err != nil || lease != nil {
+		t.Fatalf("GetLease on a new key = %v, %v; want nil", lease, err)
+	}
+
+	lease, err := s.AcquireLease(ctx, "jobs/nightly", time.Minute)
+	if err != nil {
+		t.Fatalf("AcquireLease: %v", err)
+	}
+	if lease.Holder != storage.NodeID || lease.Expired() {
+		t.Errorf("AcquireLease = %+v", lease)
+	}
+
+	held, err := s.AcquireLease(ctx, "jobs/nightly", time.Minute)
+	if err != storage.ErrLeaseHeld {
+		t.Fatalf("second AcquireLease error = %v, want ErrLeaseHeld", err)
+	}
+	if held == nil || held.Token != lease.Token {
+		t.Errorf("second AcquireLease returned %+v, want the current lease", held)
+	}
+
+	renewed, err := s.RenewLease(ctx, lease, 2*time.Minute)
+	if err != nil {
+		t.Fatalf("RenewLease: %v", err)
+	}
+	if !renewed.ExpiresAt.After(lease.ExpiresAt) {
+		t.Errorf("RenewLease did not extend the lease: %v -> %v", lease.ExpiresAt, renewed.ExpiresAt)
+	}
+	if _, err := s.RenewLease(ctx, lease, time.Minute); err != storage.ErrLeaseLost {
+		t.Errorf("RenewLease with a stale lease error = %v, want ErrLeaseLost", err)
+	}
+
+	if err := s.ReleaseLease(ctx, renewed); err != nil {
+		t.Fatalf("ReleaseLease: %v", err)
+	}
+	next, err := s.AcquireLease(ctx, "jobs/nightly", time.Minute)
+	if err != nil {
+		t.Fatalf("AcquireLease after release: %v", err)
+	}
+	if next.Token == lease.Token {
+		t.Error("AcquireLease after release reused the old token")
+	}
+
+	// A short lease can be taken over once it expires
+	short, err := s.AcquireLease(ctx, "jobs/short", 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("AcquireLease (short): %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := s.AcquireLease(ctx, "jobs/short", time.Minute); err != nil {
+		t.Errorf("AcquireLease of an expired lease: %v", err)
+	}
+	if err := s.ReleaseLease(ctx, short); err != storage.ErrLeaseLost {
+		t.Errorf("ReleaseLease of a taken-over lease error = %v, want ErrLeaseLost", err)
+	}
+}
@@ -1,5 +1,10 @@
 package version
 
+import (
+	"strconv"
+	"strings"
+)
+
 // Version is set at build time via ldflags
 var Version = "0.1.0-dev"
 
@@ -7,3 +12,47 @@ var Version = "0.1.0-dev"
 func GetVersion() string {
 	return Version
 }
+
+// Compare orders two versions by their major, minor, and patch numbers,
+// returning -1, 0, or 1. A leading "v" and any pre-release or build suffix
+// ("-dev", "+abc") are ignored; missing or unreadable parts count as 0.
+func Compare(a, b string) int {
+	pa, pb := parse(a), parse(b)
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// SameRelease reports whether two versions share a major and minor version
+func SameRelease(a, b string) bool {
+	pa, pb := parse(a), parse(b)
+	return pa[0] == pb[0] && pa[1] == pb[1]
+}
+
+// parse reads a version's major, minor, and patch numbers
+func parse(v string) [3]int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var parts [3]int
+	for i, part := range strings.SplitN(v, ".", 3) {
+		parts[i], _ = strconv.Atoi(part)
+	}
+	return parts
+}
+
+// Within reports whether v is min or newer and from max's release or older:
+// patch releases newer than max are still within it
+func Within(v, min, max string) bool {
+	if Compare(v, min) < 0 {
+		return false
+	}
+	return Compare(v, max) <= 0 || SameRelease(v, max)
+}
@@ -0,0 +1,52 @@
+package version
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.3-dev", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.2.3", "1.2.4", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "1.99.99", 1},
+		{"0.1.0-dev", "0.2.0", -1},
+		{"", "0.0.0", 0},
+	}
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSameRelease(t *testing.T) {
+	if !SameRelease("1.4.0", "v1.4.7-dev") {
+		t.Error("1.4.0 and 1.4.7 should be the same release")
+	}
+	if SameRelease("1.4.0", "1.5.0") {
+		t.Error("1.4.0 and 1.5.0 should be different releases")
+	}
+}
+
+func TestWithin(t *testing.T) {
+	tests := []struct {
+		v    string
+		want bool
+	}{
+		{"0.9.0", false},
+		{"1.0.0", true},
+		{"1.3.0", true},
+		{"1.3.9", true},
+		{"1.4.0", false},
+	}
+	for _, tt := range tests {
+		if got := Within(tt.v, "1.0.0", "1.3.2"); got != tt.want {
+			t.Errorf("Within(%q, 1.0.0, 1.3.2) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
@@ -5,16 +5,27 @@ import (
 	"embed"
 	"flag"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+
 	"velocity/internal/api"
+	"velocity/internal/crypto"
+	"velocity/internal/index"
 	"velocity/internal/log"
+	"velocity/internal/media"
+	"velocity/internal/outbound"
+	"velocity/internal/plugin"
+	"velocity/internal/secrets"
 	"velocity/internal/storage"
 	"velocity/internal/ui"
 	"velocity/internal/version"
@@ -27,14 +38,53 @@ var wwwFS embed.FS
 func main() {
 	// CLI flags with env var fallbacks: --flag > ENV_VAR > default
 	port := flag.String("port", getEnv("PORT", "8080"), "Server port")
+	grpcPort := flag.String("grpc-port", getEnv("GRPC_PORT", ""), "gRPC port (disabled if empty)")
+	s3GatewayPort := flag.String("s3-gateway-port", getEnv("S3_GATEWAY_PORT", ""), "Port of the read-only S3-compatible gateway (disabled if empty)")
 	environment := flag.String("environment", getEnv("ENVIRONMENT", "development"), "Environment (development or production)")
+	mode := flag.String("mode", getEnv("MODE", api.ModeFull), "Server mode (full, or delivery for a read-only replica)")
+	tenantRouting := flag.String("tenant-routing", getEnv("TENANT_ROUTING", api.TenantRoutingBoth), "Where API requests name their tenant (header, path for /api/t/{tenant}/..., or both)")
+	storageMode := flag.String("storage", getEnv("STORAGE", "s3"), "Storage backend (s3, file for a local versioned store, memory, or noop)")
+	dataDir := flag.String("data-dir", getEnv("DATA_DIR", "./data"), "Directory of the local store (with --storage=file)")
+	indexDSN := flag.String("index", getEnv("INDEX", ""), "Database that indexes content so listings don't scan the bucket, as postgres://... or sqlite:{path} (disabled if empty)")
+	replicas := flag.String("replicas", getEnv("REPLICAS", ""), "Comma-separated secondaries writes are copied to, as s3:{bucket}@{region} or file:{dir} (disabled if empty)")
 	s3Endpoint := flag.String("s3-endpoint", getEnv("S3_ENDPOINT", "s3.wasabisys.com"), "S3/Wasabi endpoint")
+	s3Auth := flag.String("s3-auth", getEnv("S3_AUTH", storage.S3AuthStatic), "S3 authentication (static access keys, or iam for the default AWS credential chain)")
+	s3FIPS := flag.Bool("s3-fips", getEnv("S3_FIPS", "false") == "true", "Use AWS FIPS endpoints")
 	s3Region := flag.String("s3-region", getEnv("S3_REGION", "us-east-1"), "S3 region")
 	s3Bucket := flag.String("s3-bucket", getEnv("S3_BUCKET", "velocity"), "S3 bucket name")
 	s3AccessKeyID := flag.String("s3-access-key-id", getEnv("S3_ACCESS_KEY_ID", ""), "S3 access key ID")
 	s3SecretAccessKey := flag.String("s3-secret-access-key", getEnv("S3_SECRET_ACCESS_KEY", ""), "S3 secret access key")
 	s3Root := flag.String("s3-root", getEnv("S3_ROOT", ""), "S3 root path (default: /{environment})")
 	maxVersions := flag.String("max-versions", getEnv("MAX_VERSIONS", "10"), "Max versions to keep per content item (use 'all' for unlimited)")
+	maxVersionAge := flag.String("max-version-age", getEnv("MAX_VERSION_AGE", "0"), "Keep versions younger than this past --max-versions, e.g. 2160h (0 keeps by count only)")
+	s3EventsToken := flag.String("s3-events-token", getEnv("S3_EVENTS_TOKEN", ""), "Shared token required on the S3 bucket event endpoint (ingestion is off without it)")
+	secretsKey := flag.String("secrets-key", getEnv("SECRETS_KEY", ""), "Key that encrypts webhooks and tenant settings at rest (passphrase:... or kms:...)")
+	secretsPreviousKeys := flag.String("secrets-previous-keys", getEnv("SECRETS_PREVIOUS_KEYS", ""), "Comma-separated keys that still decrypt documents during key rotation")
+	encryptionKey := flag.String("encryption-key", getEnv("ENCRYPTION_KEY", ""), "Key that encrypts content bodies and encrypted schema fields before they are stored (passphrase:... or kms:...)")
+	encryptionPreviousKeys := flag.String("encryption-previous-keys", getEnv("ENCRYPTION_PREVIOUS_KEYS", ""), "Comma-separated keys that still decrypt content during key rotation")
+	encryptionTypes := flag.String("encryption-types", getEnv("ENCRYPTION_TYPES", ""), "Comma-separated content types encrypted with --encryption-key (default: all)")
+	secretsRefresh := flag.String("secrets-refresh", getEnv("SECRETS_REFRESH", "15m"), "How often S3 credentials given as vault: or ssm: references are fetched again")
+	outboundProxy := flag.String("outbound-proxy", getEnv("OUTBOUND_PROXY", ""), "Proxy URL for webhooks, plugin hooks, and other outbound requests (default: HTTPS_PROXY/HTTP_PROXY; NO_PROXY applies)")
+	oidcIssuer := flag.String("oidc-issuer", getEnv("OIDC_ISSUER", ""), "OpenID Connect issuer URL editors log in to the web UI with (SSO disabled if empty)")
+	oidcClientID := flag.String("oidc-client-id", getEnv("OIDC_CLIENT_ID", ""), "OIDC client ID registered with the issuer")
+	oidcClientSecret := flag.String("oidc-client-secret", getEnv("OIDC_CLIENT_SECRET", ""), "OIDC client secret (empty for public clients)")
+	oidcRedirectURL := flag.String("oidc-redirect-url", getEnv("OIDC_REDIRECT_URL", ""), "OIDC callback URL registered with the issuer (default: {request host}/auth/oidc/callback)")
+	oidcAllowedDomains := flag.String("oidc-allowed-domains", getEnv("OIDC_ALLOWED_DOMAINS", ""), "Comma-separated domains of verified email addresses allowed to log in with OIDC")
+	oidcAllowedSubjects := flag.String("oidc-allowed-subjects", getEnv("OIDC_ALLOWED_SUBJECTS", ""), "Comma-separated OIDC subjects (sub claims) allowed to log in (SSO needs these or --oidc-allowed-domains)")
+	trustedProxies := flag.String("trusted-proxies", getEnv("TRUSTED_PROXIES", ""), "Comma-separated addresses or CIDR ranges of proxies whose X-Forwarded-For gives client addresses (default: none, the connection's address is used)")
+	policyHooks := flag.String("policy-hooks", getEnv("POLICY_HOOKS", ""), "Comma-separated URLs of HTTP policy hooks called around content writes")
+	wasmMemoryLimit := flag.String("wasm-memory-limit", getEnv("WASM_MEMORY_LIMIT", "64"), "Max memory in MB per WASM plugin instance")
+	wasmTimeout := flag.String("wasm-timeout", getEnv("WASM_TIMEOUT", "1s"), "Max execution time per WASM plugin call")
+	ffmpegPath := flag.String("ffmpeg", getEnv("FFMPEG", ""), "Path of the ffmpeg binary used for video posters and transcodes (disabled if empty)")
+	videoTranscodes := flag.String("video-transcodes", getEnv("VIDEO_TRANSCODES", ""), "Comma-separated heights uploaded videos are transcoded to, e.g. 720,480 (requires --ffmpeg)")
+	gcInterval := flag.String("gc-interval", getEnv("GC_INTERVAL", "24h"), "How often garbage collection runs for every tenant (0 disables)")
+	gcRetention := flag.String("gc-retention", getEnv("GC_RETENTION", "720h"), "How long derived data of deleted content is kept before garbage collection")
+	cacheMemory := flag.String("cache-memory", getEnv("CACHE_MEMORY", ""), "Memory in MB for cached content, least recently used evicted first (default 256, or 1024 in delivery mode; 0 disables)")
+	cacheTTL := flag.String("cache-ttl", getEnv("CACHE_TTL", ""), "How long cached content may go unread before it's dropped (default 1h, or 24h in delivery mode)")
+	cacheMaxItem := flag.String("cache-max-item", getEnv("CACHE_MAX_ITEM", ""), "Largest item in MB the cache keeps (default 1, or 16 in delivery mode)")
+	statsInterval := flag.String("stats-interval", getEnv("STATS_INTERVAL", "24h"), "How often storage usage is scanned for every tenant (0 disables)")
+	shutdownGrace := flag.String("shutdown-grace", getEnv("SHUTDOWN_GRACE", "10s"), "How long in-flight requests get to finish on shutdown")
+	shutdownDelay := flag.String("shutdown-delay", getEnv("SHUTDOWN_DELAY", "0s"), "How long to keep serving after a shutdown signal while the readiness probe fails")
 	logLevel := flag.String("logging", getEnv("LOG_LEVEL", "info"), "Log level (debug, info, error)")
 	showVersion := flag.Bool("version", false, "Show version and exit")
 
@@ -48,6 +98,26 @@ func main() {
 	// Set log level
 	log.SetLevel(log.ParseLevel(*logLevel))
 
+	if *mode != api.ModeFull && *mode != api.ModeDelivery {
+		log.Fatal("Invalid mode: %s (expected full or delivery)", *mode)
+	}
+	if *tenantRouting != api.TenantRoutingBoth && *tenantRouting != api.TenantRoutingHeader && *tenantRouting != api.TenantRoutingPath {
+		log.Fatal("Invalid tenant routing: %s (expected header, path, or both)", *tenantRouting)
+	}
+	if !slices.Contains(storage.Kinds(), *storageMode) {
+		log.Fatal("Invalid storage: %s (expected %s)", *storageMode, strings.Join(storage.Kinds(), ", "))
+	}
+	if *s3Auth != storage.S3AuthStatic && *s3Auth != storage.S3AuthIAM {
+		log.Fatal("Invalid S3 auth: %s (expected static or iam)", *s3Auth)
+	}
+	if *s3Auth == storage.S3AuthIAM && !flagSet("s3-endpoint") && os.Getenv("S3_ENDPOINT") == "" {
+		// IAM roles are AWS-only, so use AWS endpoints rather than the Wasabi default
+		*s3Endpoint = ""
+	}
+	if *s3Auth == storage.S3AuthIAM && (*s3AccessKeyID != "" || *s3SecretAccessKey != "") {
+		log.Info("S3 access keys are ignored with --s3-auth=iam")
+	}
+
 	// Print styled header
 	ui.PrintHeader(version.GetVersion())
 
@@ -74,12 +144,37 @@ func main() {
 
 	// Print config info
 	ui.PrintKeyValue("Port", config.Port)
+	if *grpcPort != "" {
+		ui.PrintKeyValue("gRPC Port", *grpcPort)
+	}
+	if *s3GatewayPort != "" {
+		ui.PrintKeyValue("S3 Gateway Port", *s3GatewayPort)
+	}
+	ui.PrintKeyValue("Mode", *mode)
 	ui.PrintKeyValue("Logging", log.GetLevel().String())
 	ui.PrintKeyValue("Environment", string(config.Environment))
 	fmt.Println()
-	ui.PrintKeyValue("S3 Endpoint", config.S3Endpoint)
-	ui.PrintKeyValue("S3 Bucket", config.S3Bucket)
+	switch *storageMode {
+	case "file", "embedded":
+		ui.PrintKeyValue("Storage", "file")
+		ui.PrintKeyValue("Data Dir", *dataDir)
+	case "memory":
+		ui.PrintKeyValue("Storage", "memory (lost on exit)")
+	case "noop":
+		ui.PrintKeyValue("Storage", "none")
+	default:
+		if config.S3Endpoint != "" {
+			ui.PrintKeyValue("S3 Endpoint", config.S3Endpoint)
+		} else {
+			ui.PrintKeyValue("S3 Endpoint", "AWS ("+*s3Region+")")
+		}
+		ui.PrintKeyValue("S3 Auth", *s3Auth)
+		ui.PrintKeyValue("S3 Bucket", config.S3Bucket)
+	}
 	ui.PrintKeyValue("S3 Root", config.S3Root)
+	if *replicas != "" {
+		ui.PrintKeyValue("Replicas", *replicas)
+	}
 
 	// Parse max versions (negative means unlimited)
 	maxVer := 10
@@ -89,83 +184,349 @@ func main() {
 		maxVer = v
 	}
 
+	// Parse max version age (versions younger than it outlive the count)
+	var maxAge time.Duration
+	if d, err := time.ParseDuration(*maxVersionAge); err == nil && d > 0 {
+		maxAge = d
+	}
+
 	if maxVer < 0 {
 		ui.PrintKeyValue("Version Retention", "unlimited")
+	} else if maxAge > 0 {
+		ui.PrintKeyValue("Version Retention", fmt.Sprintf("%d, or younger than %s", maxVer, maxAge))
 	} else {
 		ui.PrintKeyValue("Version Retention", strconv.Itoa(maxVer))
 	}
+
+	// Fetch secrets given as vault: or ssm: references
+	resolver := secrets.NewResolver()
+	*s3EventsToken = resolveSecret(resolver, "s3-events-token", *s3EventsToken)
+	*secretsKey = resolveSecret(resolver, "secrets-key", *secretsKey)
+	*encryptionKey = resolveSecret(resolver, "encryption-key", *encryptionKey)
+	*outboundProxy = resolveSecret(resolver, "outbound-proxy", *outboundProxy)
+	*oidcClientSecret = resolveSecret(resolver, "oidc-client-secret", *oidcClientSecret)
+	*indexDSN = resolveSecret(resolver, "index", *indexDSN)
+	if *indexDSN != "" {
+		ui.PrintKeyValue("Index", index.Redact(*indexDSN))
+	}
+
+	// Route webhooks and other outbound requests through the proxy
+	if *outboundProxy != "" {
+		if err := outbound.Configure(*outboundProxy); err != nil {
+			log.Fatal("Invalid outbound proxy: %v", err)
+		}
+		proxyURL, _ := outbound.ParseProxy(*outboundProxy)
+		ui.PrintKeyValue("Outbound Proxy", proxyURL.Redacted())
+	}
+
+	// Build the keyring for secrets at rest
+	var keyring *crypto.Keyring
+	if *secretsKey != "" {
+		keyring = buildKeyring(resolver, "secrets", *secretsKey, *secretsPreviousKeys)
+		ui.PrintKeyValue("Secrets Key", keyring.PrimaryID())
+	} else {
+		ui.PrintKeyValue("Secrets Key", "none (stored in plaintext)")
+	}
+
+	// Build the keyring for content at rest
+	var contentKeyring *crypto.Keyring
+	var contentEncrypted []string
+	if *encryptionKey != "" {
+		contentKeyring = buildKeyring(resolver, "encryption", *encryptionKey, *encryptionPreviousKeys)
+		for _, contentType := range strings.Split(*encryptionTypes, ",") {
+			if contentType = strings.TrimSpace(contentType); contentType != "" {
+				contentEncrypted = append(contentEncrypted, contentType)
+			}
+		}
+		types := "all types"
+		if len(contentEncrypted) > 0 {
+			types = strings.Join(contentEncrypted, ", ")
+		}
+		ui.PrintKeyValue("Encryption Key", fmt.Sprintf("%s (%s)", contentKeyring.PrimaryID(), types))
+	} else if *encryptionTypes != "" {
+		log.Fatal("--encryption-types requires --encryption-key")
+	}
+
+	// Schema fields marked encrypted are sealed with the content key, or the
+	// secrets key without one
+	fieldKeyring := contentKeyring
+	if fieldKeyring == nil {
+		fieldKeyring = keyring
+	}
+
+	proxies, err := api.ParseTrustedProxies(*trustedProxies)
+	if err != nil {
+		log.Fatal("Invalid --trusted-proxies: %v", err)
+	}
+
+	// Single sign-on for the web UI
+	var oidcConfig *api.OIDCConfig
+	if *oidcIssuer != "" {
+		if *oidcClientID == "" {
+			log.Fatal("--oidc-issuer requires --oidc-client-id")
+		}
+		oidcConfig = &api.OIDCConfig{
+			Issuer:       *oidcIssuer,
+			ClientID:     *oidcClientID,
+			ClientSecret: *oidcClientSecret,
+			RedirectURL:  *oidcRedirectURL,
+		}
+		for _, domain := range strings.Split(*oidcAllowedDomains, ",") {
+			if domain = strings.TrimSpace(domain); domain != "" {
+				oidcConfig.AllowedDomains = append(oidcConfig.AllowedDomains, domain)
+			}
+		}
+		for _, subject := range strings.Split(*oidcAllowedSubjects, ",") {
+			if subject = strings.TrimSpace(subject); subject != "" {
+				oidcConfig.AllowedSubjects = append(oidcConfig.AllowedSubjects, subject)
+			}
+		}
+		if len(oidcConfig.AllowedDomains) == 0 && len(oidcConfig.AllowedSubjects) == 0 {
+			// Every login is an operator session, so don't let in anyone the issuer knows
+			log.Fatal("--oidc-issuer requires --oidc-allowed-domains or --oidc-allowed-subjects")
+		}
+		ui.PrintKeyValue("SSO", *oidcIssuer)
+	}
 	fmt.Println()
 
 	// Create storage client
 	var storageClient storage.Storage
 	var gossipInvalidator *storage.GossipInvalidator
 
-	if config.S3AccessKeyID == "" || config.S3SecretAccessKey == "" {
+	kind := *storageMode
+	if kind == "s3" && *s3Auth == storage.S3AuthStatic && (config.S3AccessKeyID == "" || config.S3SecretAccessKey == "") {
 		// No S3 credentials configured - use noop storage
 		log.Info("No S3 credentials configured, using noop storage (API endpoints will return errors)")
-		storageClient = storage.NewNoopStorage()
-	} else {
-		// Create S3/Wasabi storage client
-		s3Client, err := storage.NewS3Storage(storage.S3Config{
-			Endpoint:        config.S3Endpoint,
-			Region:          config.S3Region,
-			Bucket:          config.S3Bucket,
+		kind = "noop"
+	}
+
+	storageOpts := []storage.Option{storage.WithKeyring(keyring)}
+	if kind == "s3" && (secrets.IsReference(config.S3AccessKeyID) || secrets.IsReference(config.S3SecretAccessKey)) {
+		// Credentials are fetched from the secret manager and refreshed
+		refresh, err := time.ParseDuration(*secretsRefresh)
+		if err != nil {
+			log.Fatal("Invalid secrets refresh interval: %s", *secretsRefresh)
+		}
+		storageOpts = append(storageOpts, storage.WithCredentials(aws.NewCredentialsCache(&secrets.Credentials{
+			Resolver:        resolver,
 			AccessKeyID:     config.S3AccessKeyID,
 			SecretAccessKey: config.S3SecretAccessKey,
-			Root:            config.S3Root,
-			MaxVersions:     maxVer,
-		})
+			Refresh:         refresh,
+		})))
+	}
+
+	storageConfig := map[string]string{
+		"endpoint":          config.S3Endpoint,
+		"region":            config.S3Region,
+		"bucket":            config.S3Bucket,
+		"access_key_id":     config.S3AccessKeyID,
+		"secret_access_key": config.S3SecretAccessKey,
+		"root":              config.S3Root,
+		"auth":              *s3Auth,
+		"fips":              strconv.FormatBool(*s3FIPS),
+		"dir":               *dataDir,
+		"max_versions":      strconv.Itoa(maxVer),
+		"max_version_age":   maxAge.String(),
+	}
+	backend, err := storage.New(kind, storageConfig, storageOpts...)
+	if err != nil {
+		log.Fatal("Failed to create storage client: %v", err)
+	}
+	if closer, ok := backend.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if *replicas != "" && kind != "noop" {
+		// Secondaries sit under encryption, so they hold the same ciphertext
+		replicated := buildReplicatedStorage(backend, *replicas, storageConfig, storageOpts)
+		defer replicated.Close()
+		backend = replicated
+	}
+	var encrypted *storage.EncryptedStorage
+	if contentKeyring != nil {
+		encrypted = storage.NewEncryptedStorage(backend, contentKeyring, contentEncrypted)
+		backend = encrypted
+	}
+	var indexed *index.IndexedStorage
+	if *indexDSN != "" && kind != "noop" {
+		// The index sits over encryption, so it records plaintext sizes and metadata
+		idx, err := index.Open(*indexDSN)
 		if err != nil {
-			log.Fatal("Failed to create storage client: %v", err)
+			log.Fatal("Failed to open index: %v", err)
 		}
+		indexed = index.NewIndexedStorage(backend, idx)
+		defer indexed.Close()
+		backend = indexed
+	}
 
+	if kind == "noop" {
+		storageClient = backend
+	} else {
 		// Check storage connection
 		log.Info("Connecting to storage...")
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
-		if err := s3Client.CheckConnection(ctx); err != nil {
-			log.Fatal("Storage connection failed: %v", err)
+		if checker, ok := backend.(storage.ConnectionChecker); ok {
+			if err := checker.CheckConnection(ctx); err != nil {
+				log.Fatal("Storage connection failed: %v", err)
+			}
 		}
 		log.Info("Connected to storage.")
-		cached := storage.NewCachedStorage(s3Client, storage.CacheConfig{
+		cacheConfig := storage.CacheConfig{
 			MaxTTL:         1 * time.Hour,
 			MaxContentSize: 1 << 20,   // 1MB per entry
 			MaxMemory:      256 << 20, // 256MB total
 			SweepInterval:  1 * time.Minute,
-		})
-
-		// Start gossip-based cache invalidation (disable with GOSSIP_ENABLED=false)
-		if getEnv("GOSSIP_ENABLED", "true") != "false" {
-			gossipCfg := storage.DefaultGossipConfig()
-			if addr := getEnv("GOSSIP_BIND_ADDR", ""); addr != "" {
-				gossipCfg.BindAddr = addr
+		}
+		if *mode == api.ModeDelivery {
+			// Delivery nodes only read, relying on gossip to learn of writes
+			cacheConfig.MaxTTL = 24 * time.Hour
+			cacheConfig.MaxContentSize = 16 << 20 // 16MB per entry
+			cacheConfig.MaxMemory = 1 << 30       // 1GB total
+		}
+		if *cacheMemory != "" {
+			mb, err := strconv.Atoi(*cacheMemory)
+			if err != nil || mb < 0 {
+				log.Fatal("Invalid cache memory: %s", *cacheMemory)
+			}
+			cacheConfig.MaxMemory = int64(mb) << 20
+		}
+		if *cacheTTL != "" {
+			d, err := time.ParseDuration(*cacheTTL)
+			if err != nil || d <= 0 {
+				log.Fatal("Invalid cache TTL: %s", *cacheTTL)
+			}
+			cacheConfig.MaxTTL = d
+		}
+		if *cacheMaxItem != "" {
+			mb, err := strconv.Atoi(*cacheMaxItem)
+			if err != nil || mb <= 0 {
+				log.Fatal("Invalid cache max item: %s", *cacheMaxItem)
 			}
-			if port := getEnv("GOSSIP_BIND_PORT", ""); port != "" {
-				if p, err := strconv.Atoi(port); err == nil {
-					gossipCfg.BindPort = p
+			cacheConfig.MaxContentSize = int64(mb) << 20
+		}
+
+		if cacheConfig.MaxMemory == 0 {
+			log.Info("Content cache disabled")
+			storageClient = backend
+		} else {
+			log.Info("Content cache: %d MB, items up to %d MB, dropped after %s unread", cacheConfig.MaxMemory>>20, cacheConfig.MaxContentSize>>20, cacheConfig.MaxTTL)
+			cached := storage.NewCachedStorage(backend, cacheConfig)
+
+			// Start gossip-based cache invalidation (disable with GOSSIP_ENABLED=false)
+			if getEnv("GOSSIP_ENABLED", "true") != "false" {
+				gossipCfg := storage.DefaultGossipConfig()
+				if addr := getEnv("GOSSIP_BIND_ADDR", ""); addr != "" {
+					gossipCfg.BindAddr = addr
+				}
+				if port := getEnv("GOSSIP_BIND_PORT", ""); port != "" {
+					if p, err := strconv.Atoi(port); err == nil {
+						gossipCfg.BindPort = p
+					}
+				}
+				if peers := getEnv("GOSSIP_PEERS", ""); peers != "" {
+					gossipCfg.Peers = strings.Split(peers, ",")
+				}
+				gossipCfg.EnableMDNS = getEnv("GOSSIP_MDNS", "true") != "false"
+
+				inv, err := storage.NewGossipInvalidator(gossipCfg)
+				if err != nil {
+					log.Error("Failed to start gossip invalidator: %v", err)
+				} else {
+					cached.SetInvalidator(inv)
+					gossipInvalidator = inv
 				}
 			}
-			if peers := getEnv("GOSSIP_PEERS", ""); peers != "" {
-				gossipCfg.Peers = strings.Split(peers, ",")
+
+			storageClient = cached
+		}
+	}
+
+	// Register external policy hooks
+	for _, url := range strings.Split(*policyHooks, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			plugin.Register(plugin.NewHTTPHook(url, 5*time.Second))
+			log.Info("Registered policy hook: %s", url)
+		}
+	}
+
+	// Parse WASM plugin limits
+	wasmConfig := plugin.WASMConfig{MemoryLimitMB: 64, Timeout: time.Second}
+	if v, err := strconv.Atoi(*wasmMemoryLimit); err == nil {
+		wasmConfig.MemoryLimitMB = v
+	}
+	if d, err := time.ParseDuration(*wasmTimeout); err == nil {
+		wasmConfig.Timeout = d
+	}
+
+	// Configure video processing
+	var videoProcessor media.VideoProcessor
+	var transcoder media.Transcoder
+	if *ffmpegPath != "" {
+		var heights []int
+		for _, h := range strings.Split(*videoTranscodes, ",") {
+			if h = strings.TrimSpace(h); h == "" {
+				continue
 			}
-			gossipCfg.EnableMDNS = getEnv("GOSSIP_MDNS", "true") != "false"
-
-			inv, err := storage.NewGossipInvalidator(gossipCfg)
-			if err != nil {
-				log.Error("Failed to start gossip invalidator: %v", err)
-			} else {
-				cached.SetInvalidator(inv)
-				gossipInvalidator = inv
+			height, err := strconv.Atoi(strings.TrimSuffix(h, "p"))
+			if err != nil || height <= 0 {
+				log.Fatal("Invalid video transcode height: %s", h)
 			}
+			heights = append(heights, height)
+		}
+		ffmpeg := media.NewFFmpeg(*ffmpegPath, heights)
+		videoProcessor = ffmpeg
+		if len(heights) > 0 {
+			transcoder = ffmpeg
 		}
+		log.Info("Processing videos with %s", *ffmpegPath)
+	} else if *videoTranscodes != "" {
+		log.Info("--video-transcodes is ignored without --ffmpeg")
+	}
 
-		storageClient = cached
+	// Parse garbage collection schedule
+	gcEvery, gcKeep := 24*time.Hour, 720*time.Hour
+	if d, err := time.ParseDuration(*gcInterval); err == nil {
+		gcEvery = d
+	}
+	if d, err := time.ParseDuration(*gcRetention); err == nil {
+		gcKeep = d
+	}
+
+	// Parse storage stats schedule
+	statsEvery := 24 * time.Hour
+	if d, err := time.ParseDuration(*statsInterval); err == nil {
+		statsEvery = d
+	}
+
+	// Parse shutdown timing
+	grace, delay := 10*time.Second, time.Duration(0)
+	if d, err := time.ParseDuration(*shutdownGrace); err == nil {
+		grace = d
+	}
+	if d, err := time.ParseDuration(*shutdownDelay); err == nil {
+		delay = d
 	}
 
 	// Create the API server
 	server := api.NewServer(storageClient, &api.ServerConfig{
-		Port: config.Port,
+		Port:          config.Port,
+		Mode:          *mode,
+		S3EventsToken: *s3EventsToken,
+		WASM:          wasmConfig,
+		GCInterval:    gcEvery,
+		GCRetention:   gcKeep,
+		StatsInterval: statsEvery,
+		Keyring:       keyring,
+		FieldKeyring:  fieldKeyring,
+		Video:         videoProcessor,
+		Transcoder:    transcoder,
+		TenantRouting: *tenantRouting,
+		Encryption:    encrypted,
+		Index:         indexed,
+		OIDC:          oidcConfig,
+
+		TrustedProxies: proxies,
 	}, wwwFS)
 
 	// Register cluster peers endpoint for HTTP-based peer discovery
@@ -174,9 +535,7 @@ func main() {
 	}
 
 	// Create HTTP server with graceful shutdown
-	addr := fmt.Sprintf(":%s", config.Port)
 	httpServer := &http.Server{
-		Addr:    addr,
 		Handler: server.Handler(),
 	}
 
@@ -184,33 +543,95 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
-	// Start server in goroutine
+	// Bind listeners up front so port 0 resolves to the actual port before
+	// anything reports it
+	log.Event("server.starting", "version", version.GetVersion(), "mode", *mode)
+	httpListener := listen("http", config.Port)
+	log.Info("Starting http server on %s...", httpListener.Addr())
 	go func() {
-		log.Info("Starting http server on port %s...", config.Port)
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := httpServer.Serve(httpListener); err != nil && err != http.ErrServerClosed {
 			log.Fatal("Server failed: %v", err)
 		}
 	}()
 
-	// Give server a moment to start, then log success
-	time.Sleep(100 * time.Millisecond)
+	// Start the gRPC server if enabled
+	grpcServer := server.GRPCServer()
+	if *grpcPort != "" {
+		listener := listen("grpc", *grpcPort)
+		log.Info("Starting grpc server on %s...", listener.Addr())
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatal("gRPC server failed: %v", err)
+			}
+		}()
+	}
+
+	// Start the S3 gateway if enabled
+	s3Gateway := &http.Server{
+		Handler: server.S3Gateway(),
+	}
+	if *s3GatewayPort != "" {
+		listener := listen("s3-gateway", *s3GatewayPort)
+		log.Info("Starting s3 gateway on %s...", listener.Addr())
+		go func() {
+			if err := s3Gateway.Serve(listener); err != nil && err != http.ErrServerClosed {
+				log.Fatal("S3 gateway failed: %v", err)
+			}
+		}()
+	}
+
+	server.MarkReady()
 	log.Info("Started http server.")
+	log.Event("server.ready", "version", version.GetVersion(), "mode", *mode, "http_addr", httpListener.Addr())
 	log.Info("Velocity %s server is ready!", version.GetVersion())
 
 	// Wait for shutdown signal
-	<-stop
+	sig := <-stop
+	stopping := time.Now()
 	fmt.Println()
 	log.Info("Server stopping...")
+	log.Event("server.stopping", "signal", sig, "delay", delay, "grace", grace)
+
+	// Fail readiness first, and keep serving while load balancers catch up
+	server.MarkDraining()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
 
 	// Graceful shutdown with timeout
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), grace)
 	defer shutdownCancel()
 
-	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	s3Gateway.Shutdown(shutdownCtx)
+	err = httpServer.Shutdown(shutdownCtx)
+	select {
+	case <-grpcStopped:
+	case <-shutdownCtx.Done():
+		grpcServer.Stop()
+	}
+
+	if err != nil {
 		log.Error("Server shutdown error: %v.", err)
 	} else {
 		log.Info("Server stopped.")
 	}
+	log.Event("server.stopped", "duration", time.Since(stopping).Round(time.Millisecond), "clean", err == nil)
+}
+
+// listen binds a listener on port (0 picks a free port) and reports the
+// address it got
+func listen(name, port string) net.Listener {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
+	if err != nil {
+		log.Fatal("Failed to listen on %s port %s: %v", name, port, err)
+	}
+	log.Event("server.listening", "listener", name, "addr", listener.Addr())
+	return listener
 }
 
 // Config holds all server configuration
@@ -236,6 +657,97 @@ func parseEnvironment(env string) storage.Environment {
 	}
 }
 
+// resolveSecret fetches a flag value given as a secret reference, exiting if
+// it can't be fetched
+func resolveSecret(resolver *secrets.Resolver, flagName, value string) string {
+	if !secrets.IsReference(value) {
+		return value
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resolved, err := resolver.Resolve(ctx, value)
+	if err != nil {
+		log.Fatal("Failed to fetch --%s: %v", flagName, err)
+	}
+	return resolved
+}
+
+// buildKeyring parses a primary key and comma-separated previous keys given
+// as --{name}-key and --{name}-previous-keys
+func buildKeyring(resolver *secrets.Resolver, name, primaryRef, previousRefs string) *crypto.Keyring {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	primary, err := crypto.ParseKey(ctx, primaryRef)
+	if err != nil {
+		log.Fatal("Invalid %s key: %v", name, err)
+	}
+	var previous []crypto.Key
+	for _, ref := range strings.Split(previousRefs, ",") {
+		if ref = strings.TrimSpace(ref); ref == "" {
+			continue
+		}
+		key, err := crypto.ParseKey(ctx, resolveSecret(resolver, name+"-previous-keys", ref))
+		if err != nil {
+			log.Fatal("Invalid previous %s key: %v", name, err)
+		}
+		previous = append(previous, key)
+	}
+	return crypto.NewKeyring(primary, previous...)
+}
+
+// buildReplicatedStorage wraps the primary backend with the secondaries of
+// --replicas: s3:{bucket}@{region} shares the primary's endpoint, credentials,
+// and root, and file:{dir} is a local versioned store
+func buildReplicatedStorage(primary storage.Storage, spec string, primaryConfig map[string]string, opts []storage.Option) *storage.ReplicatedStorage {
+	var secondaries []storage.Storage
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		kind, target, ok := strings.Cut(entry, ":")
+		if !ok || target == "" {
+			log.Fatal("Invalid replica %q (expected s3:{bucket}@{region} or file:{dir})", entry)
+		}
+		cfg := make(map[string]string, len(primaryConfig))
+		for key, value := range primaryConfig {
+			cfg[key] = value
+		}
+		switch kind {
+		case "s3":
+			bucket, region, _ := strings.Cut(target, "@")
+			cfg["bucket"] = bucket
+			if region != "" {
+				cfg["region"] = region
+			}
+		case "file":
+			cfg["dir"] = target
+		default:
+			log.Fatal("Invalid replica %q (expected s3:{bucket}@{region} or file:{dir})", entry)
+		}
+		secondary, err := storage.New(kind, cfg, opts...)
+		if err != nil {
+			log.Fatal("Failed to create replica %s: %v", entry, err)
+		}
+		secondaries = append(secondaries, secondary)
+	}
+	if len(secondaries) == 0 {
+		log.Fatal("--replicas names no replicas")
+	}
+	return storage.NewReplicatedStorage(primary, secondaries...)
+}
+
+// flagSet reports whether a flag was given on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 // getEnv returns an environment variable value or a default
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
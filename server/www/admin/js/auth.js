@@ -28,6 +28,17 @@ var Auth = (function() {
         }
     }
 
+    // Whether the server offers single sign-on (OIDC)
+    async function ssoEnabled() {
+        try {
+            var resp = await fetch('/api/session');
+            var data = await resp.json();
+            return data.oidc === true;
+        } catch (e) {
+            return false;
+        }
+    }
+
     // Authenticated fetch wrapper - redirects to login on 401/302
     async function apiFetch(url, options) {
         options = options || {};
@@ -44,6 +55,7 @@ var Auth = (function() {
         login: login,
         logout: logout,
         checkSession: checkSession,
+        ssoEnabled: ssoEnabled,
         fetch: apiFetch
     };
 })();
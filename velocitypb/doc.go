@@ -0,0 +1,5 @@
+// Package velocitypb holds the gRPC client and server code for Velocity's
+// Content service, generated from content.proto.
+package velocitypb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative content.proto
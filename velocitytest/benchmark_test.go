@@ -0,0 +1,130 @@
+package velocitytest_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"velocity/internal/log"
+	"velocity/velocitytest"
+)
+
+// benchItems is how many items the list and bulk benchmarks work over
+const benchItems = 100
+
+// benchRequest sends a request outside the helpers, which keep every response
+// open until the benchmark ends, and drains the body so connections are reused
+func benchRequest(b *testing.B, v *velocitytest.Server, method, path, body string) {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, v.URL+path, reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	req.Header.Set("X-Tenant", v.Tenant)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		b.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b.Fatalf("%s %s = %d", method, path, resp.StatusCode)
+	}
+}
+
+// startBench starts a server with logging limited to errors: request logs
+// would otherwise be timed with the handlers and break up the results
+func startBench(b *testing.B) *velocitytest.Server {
+	level := log.GetLevel()
+	log.SetLevel(log.ERROR)
+	b.Cleanup(func() { log.SetLevel(level) })
+	b.ReportAllocs()
+	return velocitytest.Start(b)
+}
+
+// benchServer starts a server holding benchItems pages
+func benchServer(b *testing.B) *velocitytest.Server {
+	v := startBench(b)
+	for i := 0; i < benchItems; i++ {
+		v.PutContent(b, "pages", fmt.Sprintf("page-%03d", i), fmt.Sprintf(`{"title":"Page %d","body":"%s"}`, i, strings.Repeat("lorem ipsum ", 50)))
+	}
+	return v
+}
+
+func BenchmarkList(b *testing.B) {
+	v := benchServer(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchRequest(b, v, http.MethodGet, "/api/content/pages", "")
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	v := benchServer(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchRequest(b, v, http.MethodGet, fmt.Sprintf("/api/content/pages/page-%03d", i%benchItems), "")
+	}
+}
+
+func BenchmarkDirectGet(b *testing.B) {
+	v := benchServer(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchRequest(b, v, http.MethodGet, fmt.Sprintf("/content/%s/pages/page-%03d", v.Tenant, i%benchItems), "")
+	}
+}
+
+func BenchmarkDirectGetLarge(b *testing.B) {
+	v := startBench(b)
+	data := []byte(strings.Repeat("0123456789abcdef", 64<<10)) // 1 MiB
+	v.PutFile(b, "files", "large.bin", data)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchRequest(b, v, http.MethodGet, fmt.Sprintf("/content/%s/files/large.bin", v.Tenant), "")
+	}
+}
+
+func BenchmarkBulkGet(b *testing.B) {
+	v := benchServer(b)
+	var items []map[string]string
+	for i := 0; i < 20; i++ {
+		items = append(items, map[string]string{"type": "pages", "id": fmt.Sprintf("page-%03d", i)})
+	}
+	body, _ := json.Marshal(map[string]interface{}{"items": items})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchRequest(b, v, http.MethodPost, "/api/content", string(body))
+	}
+}
+
+func BenchmarkDiff(b *testing.B) {
+	v := startBench(b)
+	v.PutContent(b, "pages", "home", `{"title":"Home","sections":["intro","features","pricing"],"footer":"2025"}`)
+	v.PutContent(b, "pages", "home", `{"title":"Home page","sections":["intro","pricing","faq"],"footer":"2026"}`)
+
+	var versions struct {
+		Versions []struct {
+			VersionID string `json:"version_id"`
+		} `json:"versions"`
+	}
+	v.JSON(b, http.MethodGet, "/api/content/pages/home/versions", nil, &versions)
+	if len(versions.Versions) < 2 {
+		b.Fatalf("got %d versions, want 2", len(versions.Versions))
+	}
+	path := fmt.Sprintf("/api/content/pages/home/diff?from=%s&to=%s", versions.Versions[1].VersionID, versions.Versions[0].VersionID)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchRequest(b, v, http.MethodGet, path, "")
+	}
+}
@@ -0,0 +1,258 @@
+// Package velocitytest runs a Velocity API server in-process for integration
+// tests. Storage is an in-memory S3 bucket, so tests need no network access or
+// credentials:
+//
+//	func TestHomePage(t *testing.T) {
+//		v := velocitytest.Start(t)
+//		v.PutSchema(t, "pages", `{"name":"pages","fields":{"title":{"type":"string","required":true}}}`)
+//		v.PutContent(t, "pages", "home", `{"title":"Home"}`)
+//
+//		resp := v.Get(t, "/api/content/pages/home")
+//		...
+//	}
+package velocitytest
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"velocity/internal/api"
+	"velocity/internal/storage/storagetest"
+)
+
+// DefaultTenant is the tenant requests are sent as unless overridden
+const DefaultTenant = "test"
+
+// Server is a running API server backed by in-memory storage
+type Server struct {
+	URL    string       // Base URL, e.g. http://127.0.0.1:54321
+	Tenant string       // Tenant sent in the X-Tenant header
+	Client *http.Client // Client used by the request helpers
+}
+
+// Start starts a server on a random local port. It is shut down when the
+// test finishes.
+func Start(t testing.TB) *Server {
+	t.Helper()
+
+	s, _ := storagetest.NewS3Storage(10)
+	server := api.NewServer(s, &api.ServerConfig{}, embed.FS{})
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+
+	return &Server{URL: ts.URL, Tenant: DefaultTenant, Client: ts.Client()}
+}
+
+// WithTenant returns a view of the server that sends requests as another tenant
+func (s *Server) WithTenant(tenant string) *Server {
+	view := *s
+	view.Tenant = tenant
+	return &view
+}
+
+// =============================================================================
+// Requests
+// =============================================================================
+
+// Do sends a request with the tenant header set and returns the response. The
+// body is closed when the test finishes.
+func (s *Server) Do(t testing.TB, method, path string, body io.Reader, header http.Header) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, s.URL+path, body)
+	if err != nil {
+		t.Fatalf("velocitytest: failed to create request: %v", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if req.Header.Get("X-Tenant") == "" {
+		req.Header.Set("X-Tenant", s.Tenant)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		t.Fatalf("velocitytest: %s %s failed: %v", method, path, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// Get sends a GET request
+func (s *Server) Get(t testing.TB, path string) *http.Response {
+	t.Helper()
+	return s.Do(t, http.MethodGet, path, nil, nil)
+}
+
+// JSON sends a request with a JSON body (a string, []byte, or value to
+// marshal), requires a 2xx response, and decodes the response into out when
+// out is non-nil
+func (s *Server) JSON(t testing.TB, method, path string, body interface{}, out interface{}) {
+	t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(b)
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("velocitytest: failed to marshal body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	resp := s.Do(t, method, path, reader, http.Header{"Content-Type": {"application/json"}})
+	data := requireOK(t, resp, method, path)
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("velocitytest: failed to decode %s %s response: %v", method, path, err)
+		}
+	}
+}
+
+// Login logs in as the admin user and returns a session token for endpoints
+// that require one (send it as "Authorization: Bearer <token>")
+func (s *Server) Login(t testing.TB) string {
+	t.Helper()
+
+	var result struct {
+		Token string `json:"token"`
+	}
+	s.JSON(t, http.MethodPost, "/api/login", map[string]string{
+		"username": "velocity",
+		"password": "V3l0c1ty@12345",
+	}, &result)
+	return result.Token
+}
+
+func requireOK(t testing.TB, resp *http.Response, method, path string) []byte {
+	t.Helper()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("velocitytest: failed to read %s %s response: %v", method, path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		t.Fatalf("velocitytest: %s %s returned %d: %s", method, path, resp.StatusCode, data)
+	}
+	return data
+}
+
+// =============================================================================
+// Fixtures
+// =============================================================================
+
+// PutSchema saves a tenant schema
+func (s *Server) PutSchema(t testing.TB, name, schema string) {
+	t.Helper()
+	s.JSON(t, http.MethodPut, "/api/tenant/schemas/"+name, schema, nil)
+}
+
+// PutContent saves live JSON content
+func (s *Server) PutContent(t testing.TB, contentType, id, content string) {
+	t.Helper()
+	s.PutContentState(t, contentType, id, "live", content)
+}
+
+// PutDraft saves draft JSON content
+func (s *Server) PutDraft(t testing.TB, contentType, id, content string) {
+	t.Helper()
+	s.PutContentState(t, contentType, id, "draft", content)
+}
+
+// PutContentState saves JSON content in a state (draft, pending, or live)
+func (s *Server) PutContentState(t testing.TB, contentType, id, state, content string) {
+	t.Helper()
+	s.JSON(t, http.MethodPut, fmt.Sprintf("/api/content/%s/%s/%s", contentType, id, state), content, nil)
+}
+
+// PutFile saves live content of any type. The ID includes the extension
+// (e.g. "hero.png"), and the MIME type is derived from it.
+func (s *Server) PutFile(t testing.TB, contentType, id string, data []byte) {
+	t.Helper()
+
+	mimeType := mime.TypeByExtension(path.Ext(id))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	p := fmt.Sprintf("/api/content/%s/%s", contentType, id)
+	resp := s.Do(t, http.MethodPut, p, bytes.NewReader(data), http.Header{"Content-Type": {mimeType}})
+	requireOK(t, resp, http.MethodPut, p)
+}
+
+// GetContent returns live content, failing the test if it doesn't exist
+func (s *Server) GetContent(t testing.TB, contentType, id string) []byte {
+	t.Helper()
+
+	p := fmt.Sprintf("/api/content/%s/%s", contentType, id)
+	return requireOK(t, s.Get(t, p), http.MethodGet, p)
+}
+
+// LoadFixtures saves schemas and content from a directory tree:
+//
+//	schemas/{name}.json          tenant schemas
+//	content/{type}/{id}.json     live JSON content
+//	content/{type}/{id}.{ext}    live files of other types (images, CSS, ...)
+//	drafts/{type}/{id}.json      draft JSON content
+//
+// IDs may contain slashes (content/docs/guides/setup.json is docs/guides/setup).
+// Schemas are loaded first so content is validated against them.
+func (s *Server) LoadFixtures(t testing.TB, fsys fs.FS) {
+	t.Helper()
+
+	schemas, _ := fs.Glob(fsys, "schemas/*.json")
+	for _, file := range schemas {
+		s.PutSchema(t, strings.TrimSuffix(path.Base(file), ".json"), string(readFixture(t, fsys, file)))
+	}
+
+	for dir, state := range map[string]string{"content": "live", "drafts": "draft"} {
+		err := fs.WalkDir(fsys, dir, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			contentType, id, ok := strings.Cut(strings.TrimPrefix(file, dir+"/"), "/")
+			if !ok {
+				return nil
+			}
+
+			data := readFixture(t, fsys, file)
+			switch {
+			case strings.HasSuffix(id, ".json"):
+				s.PutContentState(t, contentType, strings.TrimSuffix(id, ".json"), state, string(data))
+			case state == "live":
+				s.PutFile(t, contentType, id, data)
+			default:
+				t.Fatalf("velocitytest: draft fixture %s must be JSON", file)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("velocitytest: failed to load fixtures from %s: %v", dir, err)
+		}
+	}
+}
+
+func readFixture(t testing.TB, fsys fs.FS, file string) []byte {
+	t.Helper()
+
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		t.Fatalf("velocitytest: failed to read fixture %s: %v", file, err)
+	}
+	return data
+}
@@ -0,0 +1,62 @@
+package velocitytest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"velocity/velocitytest"
+)
+
+func TestServer(t *testing.T) {
+	v := velocitytest.Start(t)
+
+	v.PutSchema(t, "pages", `{"name":"pages","fields":{"title":{"type":"string","required":true}}}`)
+	v.PutContent(t, "pages", "home", `{"title":"Home"}`)
+	v.PutDraft(t, "pages", "home", `{"title":"Home (draft)"}`)
+
+	if got := string(v.GetContent(t, "pages", "home")); got != `{"title":"Home"}` {
+		t.Errorf("GetContent = %s", got)
+	}
+
+	// Content is per tenant
+	if resp := v.WithTenant("other").Get(t, "/api/content/pages/home"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("other tenant GET = %d, want 404", resp.StatusCode)
+	}
+
+	if token := v.Login(t); token == "" {
+		t.Error("Login returned an empty token")
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	v := velocitytest.Start(t)
+
+	v.LoadFixtures(t, fstest.MapFS{
+		"schemas/posts.json":             {Data: []byte(`{"name":"posts","fields":{"title":{"type":"string"}}}`)},
+		"content/posts/hello.json":       {Data: []byte(`{"title":"Hello"}`)},
+		"content/docs/guides/setup.json": {Data: []byte(`{"title":"Setup"}`)},
+		"content/images/logo.png":        {Data: []byte("\x89PNG\r\n\x1a\n")},
+		"drafts/posts/upcoming.json":     {Data: []byte(`{"title":"Soon"}`)},
+	})
+
+	if got := string(v.GetContent(t, "docs", "guides/setup")); got != `{"title":"Setup"}` {
+		t.Errorf("nested fixture = %s", got)
+	}
+	if resp := v.Get(t, "/api/content/images/logo.png"); resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("file fixture = %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var draft map[string]interface{}
+	v.JSON(t, http.MethodGet, "/api/content/posts/upcoming/draft", nil, &draft)
+	if draft["title"] != "Soon" {
+		t.Errorf("draft fixture = %v", draft)
+	}
+
+	resp := v.Get(t, "/api/tenant/schemas/posts")
+	var schema map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&schema); err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("schema fixture = %d, %v", resp.StatusCode, err)
+	}
+}